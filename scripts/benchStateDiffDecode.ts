@@ -0,0 +1,105 @@
+import { performance } from 'perf_hooks';
+import { parseArgs } from 'node:util';
+import { decodeAbiParameters, encodeAbiParameters, Hex, zeroAddress } from 'viem';
+import {
+  decodeOverrides,
+  decodePreimages,
+  decodeStateDiff,
+  OVERRIDES_PARAMS,
+  PREIMAGES_PARAMS,
+  STATE_DIFF_PARAMS,
+} from '@/lib/state-diff-encoding';
+
+// Benchmarks repeated decodes of stateDiff.json blobs, comparing the module-level parameter
+// trees with trees rebuilt on every call (the previous behaviour).
+
+const word = (n: number) => ('0x' + n.toString(16).padStart(64, '0')) as Hex;
+
+function sampleBlobs(accounts: number) {
+  const accesses = Array.from({ length: accounts }, (_, i) => ({
+    chainInfo: { forkId: BigInt(0), chainId: BigInt(1) },
+    kind: 0,
+    account: zeroAddress,
+    accessor: zeroAddress,
+    initialized: true,
+    oldBalance: BigInt(i),
+    newBalance: BigInt(i + 1),
+    deployedCode: '0x' as Hex,
+    value: BigInt(0),
+    data: '0x' as Hex,
+    reverted: false,
+    storageAccesses: Array.from({ length: 5 }, (_, j) => ({
+      account: zeroAddress,
+      slot: word(j),
+      isWrite: true,
+      previousValue: word(i),
+      newValue: word(i + j),
+      reverted: false,
+    })),
+    depth: BigInt(1),
+    oldNonce: BigInt(0),
+    newNonce: BigInt(0),
+  }));
+  const overrides = {
+    from: zeroAddress,
+    to: zeroAddress,
+    data: '0x' as Hex,
+    stateOverrides: Array.from({ length: accounts }, (_, i) => ({
+      contractAddress: zeroAddress,
+      overrides: [{ key: word(i), value: word(i + 1) }],
+    })),
+  };
+  const preimages = Array.from({ length: accounts }, (_, i) => ({
+    slot: word(i),
+    parent: word(i + 1),
+    key: word(i + 2),
+  }));
+
+  return {
+    stateDiff: encodeAbiParameters(STATE_DIFF_PARAMS, [accesses]),
+    overrides: encodeAbiParameters(OVERRIDES_PARAMS, [overrides]),
+    preimages: encodeAbiParameters(PREIMAGES_PARAMS, [preimages]),
+  };
+}
+
+function time(label: string, iterations: number, fn: () => void): number {
+  fn(); // warm up
+  const start = performance.now();
+  for (let i = 0; i < iterations; i++) fn();
+  const perOp = (performance.now() - start) / iterations;
+  console.log(`${label.padEnd(28)} ${perOp.toFixed(3)} ms/op`);
+  return perOp;
+}
+
+function main() {
+  const { values } = parseArgs({
+    args: process.argv.slice(2),
+    options: {
+      iterations: { type: 'string', short: 'n' },
+      accounts: { type: 'string', short: 'a' },
+    },
+  });
+  const iterations = Number.parseInt(values.iterations ?? '500', 10);
+  const accounts = Number.parseInt(values.accounts ?? '50', 10);
+  const blobs = sampleBlobs(accounts);
+
+  console.log(`${iterations} iterations, ${accounts} accounts per blob\n`);
+  const cached =
+    time('decodeStateDiff', iterations, () => decodeStateDiff(blobs.stateDiff)) +
+    time('decodeOverrides', iterations, () => decodeOverrides(blobs.overrides)) +
+    time('decodePreimages', iterations, () => decodePreimages(blobs.preimages));
+  const rebuilt =
+    time('stateDiff (rebuilt params)', iterations, () =>
+      decodeAbiParameters(structuredClone(STATE_DIFF_PARAMS), blobs.stateDiff)
+    ) +
+    time('overrides (rebuilt params)', iterations, () =>
+      decodeAbiParameters(structuredClone(OVERRIDES_PARAMS), blobs.overrides)
+    ) +
+    time('preimages (rebuilt params)', iterations, () =>
+      decodeAbiParameters(structuredClone(PREIMAGES_PARAMS), blobs.preimages)
+    );
+
+  console.log(`\ncached ${cached.toFixed(3)} ms vs rebuilt ${rebuilt.toFixed(3)} ms per round`);
+}
+
+main();
@@ -18,6 +18,7 @@ import {
 } from '@/lib/task-origin-validate';
 import { TASK_ORIGIN_SIGNATURE_FILE_NAMES, TASK_ORIGIN_COMMON_NAMES } from '@/lib/constants';
 import trustedRoot from '@/lib/config/trusted-root.json';
+import { ledgerTaskName, recordSignature } from '@/lib/task-ledger';
 import type { TaskOriginRole } from '@/lib/types';
 
 const TSA_BASE_URL = 'https://timestamp.sigstore.dev';
@@ -40,11 +41,14 @@ export type FacilitatorType = 'base' | 'security-council';
 type VerificationResult = {
   role: TaskOriginRole;
   roleName: string;
+  identity: string;
   success: boolean;
   error?: string;
   signatureFile: string;
 };
 
+export type VerifiedSignature = Pick<VerificationResult, 'role' | 'identity' | 'signatureFile'>;
+
 function printUsage(): void {
   const msg = `
   Generate a task origin signature from a bundle.
@@ -65,6 +69,7 @@ function printUsage(): void {
     --signature-path, -p Directory path to store/read the signature
     --facilitator, -f    Facilitator type: "base" or "security-council" (used for 'sign' and 'verify' commands, omit this flag to sign/verify as task creator)
     --common-name, -c    Common name for task creator (required when not using --facilitator in 'verify' and 'verify-all' commands)
+    --ledger             Task ledger file to record verified signatures in (used for 'verify' and 'verify-all' commands)
     --help, -h           Show this help message
   `;
   console.log(msg);
@@ -332,7 +337,7 @@ export async function verifyTaskOrigin(
   signatureDir: string,
   facilitator: FacilitatorType | undefined,
   commonName: string | undefined
-) {
+): Promise<VerifiedSignature | undefined> {
   console.log('✅ Validating task signature...');
 
   const role = facilitatorToRole(facilitator);
@@ -371,13 +376,14 @@ export async function verifyTaskOrigin(
     process.exitCode = 1;
     return;
   }
+  return { role, identity, signatureFile };
 }
 
 export async function verifyAllSignatures(
   taskFolderPath: string,
   signatureDir: string,
   taskCreatorCommonName: string
-) {
+): Promise<VerifiedSignature[]> {
   console.log('📋 Validating all task signatures...');
   console.log(`  Task folder: ${taskFolderPath}`);
   console.log(`  Signature directory: ${signatureDir}`);
@@ -419,6 +425,7 @@ export async function verifyAllSignatures(
       results.push({
         role,
         roleName,
+        identity,
         success: true,
         signatureFile,
       });
@@ -427,6 +434,7 @@ export async function verifyAllSignatures(
       results.push({
         role,
         roleName,
+        identity,
         success: false,
         error: errorMessage,
         signatureFile,
@@ -467,6 +475,20 @@ export async function verifyAllSignatures(
   if (failures.length > 0) {
     process.exitCode = 1;
   }
+  return successes.map(({ role, identity, signatureFile }) => ({ role, identity, signatureFile }));
+}
+
+async function recordVerifiedSignatures(
+  ledger: string,
+  verified: VerifiedSignature[]
+): Promise<void> {
+  for (const { role, identity, signatureFile } of verified) {
+    const task = ledgerTaskName(signatureFile);
+    await recordSignature(ledger, { task, role, signer: identity, signatureFile });
+  }
+  if (verified.length > 0) {
+    console.log(`📒 Recorded ${verified.length} signature(s) in task ledger ${ledger}`);
+  }
 }
 
 async function main() {
@@ -477,6 +499,7 @@ async function main() {
       'signature-path': { type: 'string', short: 'p' },
       facilitator: { type: 'string', short: 'f' },
       'common-name': { type: 'string', short: 'c' },
+      ledger: { type: 'string' },
       help: { type: 'boolean', short: 'h' },
     },
     allowPositionals: true,
@@ -515,6 +538,7 @@ async function main() {
   const signaturePath = values['signature-path'];
   const facilitatorValue = values['facilitator'];
   const commonName = values['common-name'];
+  const ledger = values.ledger ? path.resolve(process.cwd(), values.ledger) : undefined;
 
   // Validate required task-folder flag
   if (!taskFolder) {
@@ -566,7 +590,13 @@ async function main() {
         ? path.resolve(process.cwd(), signaturePath)
         : taskFolderPath;
 
-      await verifyTaskOrigin(taskFolderPath, signatureDir, facilitator, commonName);
+      const verified = await verifyTaskOrigin(
+        taskFolderPath,
+        signatureDir,
+        facilitator,
+        commonName
+      );
+      if (ledger && verified) await recordVerifiedSignatures(ledger, [verified]);
       break;
     }
     case 'verify-all': {
@@ -584,7 +614,8 @@ async function main() {
         ? path.resolve(process.cwd(), signaturePath)
         : taskFolderPath;
 
-      await verifyAllSignatures(taskFolderPath, signatureDir, commonName);
+      const verified = await verifyAllSignatures(taskFolderPath, signatureDir, commonName);
+      if (ledger) await recordVerifiedSignatures(ledger, verified);
       break;
     }
     case 'tar': {
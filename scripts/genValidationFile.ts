@@ -1,6 +1,140 @@
-import { StateDiffClient } from '@/lib/state-diff';
+import { READS_MODES, ReadsMode, SimulationResult, StateDiffClient } from '@/lib/state-diff';
+import { parseSimulationArtifact, SimulationArtifact } from '@/lib/simulation-artifact';
+import { parseSimulateV1Payload } from '@/lib/simulate-v1';
+import { checkSafeNonce } from '@/lib/safe-nonce';
+import { assertConnectedChain, resolveChain } from '@/lib/chains';
 import { L2GasEstimator } from '@/lib/l2-gas-estimator';
-import { writeFileSync, mkdirSync } from 'fs';
+import { DEFAULT_ALLOWANCE_SLOT } from '@/lib/permit';
+import { flushTelemetry } from '@/lib/telemetry';
+import { enableProgress } from '@/lib/progress';
+import {
+  newRedactionSalt,
+  parsePrivacyList,
+  PrivacyList,
+  Redacted,
+  redactedCopyPath,
+  redactTaskConfig,
+} from '@/lib/redaction';
+import {
+  buildPrestateOverrides,
+  describePrestateDependency,
+  describePrestateMismatch,
+  PRESTATE_FILE_ENV,
+  prestateEnvAssignment,
+  PrestateOverride,
+  prestateOverridesPath,
+  unappliedPrestate,
+} from '@/lib/chained-tasks';
+import { getValidationSummary, parseFromString } from '@/lib/parser';
+import type { NestedHashes, PrestateDependency, ReportWarning, TaskConfig } from '@/lib/types';
+import { OUTPUT_FORMATS, OutputFormat, serializeResult } from '@/lib/serialization';
+import { writeJsonFile } from '@/lib/json-stream';
+import { formatBuildInfo, getBuildInfo } from '@/lib/build-info';
+import {
+  applyReportScope,
+  describeFilteredCounts,
+  parseAddressList,
+  ScopeFilter,
+} from '@/lib/report-scope';
+import {
+  applyReportAudience,
+  omittedSections,
+  parseReportAudience,
+  ReportAudience,
+} from '@/lib/report-audience';
+import { AttestationSigner, signAttestation } from '@/lib/attestation';
+import { canonicalHash } from '@/lib/canonical-json';
+import { buildFocusTrace, forgeTraceLines, formatFocusTrace } from '@/lib/focus-trace';
+import { decodePreimages, decodeStateDiff, ParentPreimage } from '@/lib/state-diff-encoding';
+import { loadPreimageDatabase } from '@/lib/preimage-database';
+import { PREIMAGE_STORE_ENV } from '@/lib/preimage-store';
+import {
+  forkBlockPin,
+  hashSimulationInputs,
+  loadCachedSimulation,
+  saveCachedSimulation,
+  SIMULATION_CACHE_ENV,
+  simulationCacheKey,
+  simulationCachePath,
+} from '@/lib/simulation-cache';
+import {
+  DEFAULT_DOTENV_ALLOWLIST,
+  envAssignments,
+  loadWorkdirEnv,
+  recordSimulationEnv,
+  resolveSimulationEnv,
+  secretEnv,
+} from '@/lib/simulation-env';
+import { decryptKeystore } from '@/lib/keystore';
+import { ledgerTaskName, recordValidation } from '@/lib/task-ledger';
+import {
+  checkExpectedOverrides,
+  describeOverrideDiscrepancy,
+  ExpectedOverride,
+  parseExpectedOverrides,
+} from '@/lib/expected-overrides';
+import {
+  buildSignerInstructions,
+  DEFAULT_SIGNER_TEMPLATE,
+  parseSignerTemplate,
+  SignerTemplate,
+} from '@/lib/signer-instructions';
+import { UNKNOWN_MODES, UnknownMode } from '@/lib/unknown-entries';
+import {
+  DEFAULT_METADATA_CACHE_TTL_HOURS,
+  METADATA_CACHE_ENV,
+  MetadataCacheOptions,
+} from '@/lib/metadata-cache';
+import { EXIT_CODES, exitCodeFor, PolicyViolationError } from '@/lib/errors';
+import {
+  checkDiffFileSize,
+  DecodeLimits,
+  DEFAULT_DECODE_LIMITS,
+  parseByteSize,
+} from '@/lib/decode-limits';
+import { resolveSandboxConfig, SANDBOX_ENV, SandboxConfig } from '@/lib/sandbox';
+import {
+  ALLOWED_CMDS_ENV,
+  allowedCommandsFromEnv,
+  parseAllowedCommands,
+} from '@/lib/command-policy';
+import {
+  describeRecentModification,
+  findRecentModifications,
+  HistoryEntry,
+  loadHistory,
+  owningTaskDir,
+} from '@/lib/recent-modifications';
+import { checkRpcAgreement, compareRpcs, describeRpcAgreement } from '@/lib/rpc-agreement';
+import { deriveNestedHashes, describeNestedHash } from '@/lib/nested-safes';
+import { appendWarnings, reportWarning } from '@/lib/report-warnings';
+import { describeExecutionCheck } from '@/lib/execution-check';
+import { buildClearSigningDescriptor, clearSigningPath } from '@/lib/clear-signing';
+import { buildFoundryAssertions } from '@/lib/foundry-assertions';
+import { checkPolicyPlugins, loadPolicyPlugin, PolicyPlugin } from '@/lib/policy-plugins';
+import { parseWebhookUrl, postNotification } from '@/lib/webhook-notify';
+import { deliverToSinks, describeSink, OutputSink, parseSinks } from '@/lib/output-sinks';
+import { splitValidationFile } from '@/lib/artifact-split';
+import { buildViewerLink, parseCid, parseViewerUrl } from '@/lib/viewer-link';
+import { recordManifestArtifacts } from '@/lib/signing-manifest';
+import {
+  buildJustCommand,
+  findJustfile,
+  JUST_TARGETS,
+  JustRecipe,
+  justValidationPath,
+  missingJustEnv,
+  parseJustRecipes,
+} from '@/lib/justfile';
+import {
+  DIFF_MODES,
+  DiffMode,
+  matchDiffGlob,
+  MatchedDiff,
+  mergeSimulationArtifacts,
+} from '@/lib/diff-glob';
+import { readFileSync, writeFileSync, mkdirSync, rmSync, statSync } from 'fs';
+import { Hex, http, isAddress, numberToHex } from 'viem';
 import path from 'path';
 import { parseArgs } from 'node:util';
 import { parse as shellParse } from 'shell-quote';
@@ -12,18 +146,205 @@ Generate a validation JSON file from a forge run output.
 
 Usage:
   tsx scripts/genValidationFile.ts --rpc-url <URL> --workdir <DIR> --forge-cmd "<CMD>" [--ledger-id <ID>] [--out <FILE>]
+  tsx scripts/genValidationFile.ts --rpc-url <URL> --workdir <DIR> --use-just [--just-args "<ARGS>"] [--out <FILE>]
+  tsx scripts/genValidationFile.ts --workdir <DIR> --forge-cmd "<CMD>" --simulate-only <DIFF>
+  tsx scripts/genValidationFile.ts --rpc-url <URL> --report-only <DIFF> [--out <FILE>]
+  tsx scripts/genValidationFile.ts --rpc-url <URL> --diff-glob <PATTERN> [--workdir <DIR>] [--diff-mode <MODE>] --out <PATH>
+  tsx scripts/genValidationFile.ts --workdir <DIR> --forge-cmd "<CMD>" --focus <ADDR>
+  tsx scripts/genValidationFile.ts --rpc-url <URL> --from-trace <FILE> --target-safe <ADDR> --data-to-sign <HEX> [--out <FILE>]
+  tsx scripts/genValidationFile.ts --rpc-url <URL> --from-simulate-v1 <FILE> --target-safe <ADDR> --data-to-sign <HEX> [--out <FILE>]
+  tsx scripts/genValidationFile.ts --rpc-url <URL> --from-tenderly <FILE> --target-safe <ADDR> --data-to-sign <HEX> [--out <FILE>]
+  tsx scripts/genValidationFile.ts --rpc-url <URL> --from-permit <FILE> [--allowance-slot <SLOT>] [--nonces-slot <SLOT>] [--out <FILE>]
 
 Required flags:
-  --rpc-url, -r     HTTPS RPC URL used to resolve chainId for decoding
+  --rpc-url, -r     HTTPS RPC URL used to resolve chainId for decoding; optional with --chain
   --workdir, -w     Directory containing stateDiff.json (and where forge will run)
   --forge-cmd, -f   Full forge command to execute (quoted); e.g. "forge script ... --json"
 
 Optional flags:
+  --use-just           Run the workdir justfile's simulate recipe instead of --forge-cmd, as
+                       OP Foundation task repositories do; it is recorded as the file's cmd, and
+                       --out defaults to <workdir>/validations/<just args>.json
+  --just-args <args>   Arguments for the simulate recipe, e.g. the Safe to simulate for
+                       ("foundation"), separated by spaces
+  --chain <name>       Chain alias (e.g. base-sepolia) or ID; its registry RPC URL is used when
+                       --rpc-url is omitted, and the node's chain ID must match
   --ledger-id, -l      Ledger account index to use in the validation JSON (defaults to 0)
   --out, -o            Output file path for the resulting JSON (defaults to stdout)
+  --format <fmt>       Output format: json (default), yaml, or toml. Only JSON can be loaded by
+                       the app; the other formats are for review tooling
+  --max-output-size <bytes>
+                       Split a JSON file larger than this (e.g. 512KB, 2MB) into an index at --out
+                       and one file per contract under <out>.parts/; the app and state-diff
+                       join them again. Needs --out
   --estimate-l2-gas    Enable L2 gas estimation (automatically adds -vvvv to forge command)
   --l2-rpc-url <url>   L2 RPC URL for gas estimation (required with --estimate-l2-gas)
   --l2-gas-buffer      Buffer percentage to add to estimated L2 gas (defaults to 20)
+  --from-trace <file>  Build the validation file from debug_traceCall prestateTracer (diffMode)
+                       output instead of running forge; replaces --workdir and --forge-cmd
+  --from-simulate-v1 <file>
+                       Run the call in <file> ({ from, to, data, stateOverrides? }) through the
+                       node's eth_simulateV1 instead of forge; the storage diff comes from a
+                       debug_traceCall prestateTracer trace of the same call
+  --from-tenderly <file>
+                       Build the validation file from a Tenderly simulation export (state_diff,
+                       balance_diff, and state overrides) instead of running forge
+  --from-permit <file> Build the validation file for an EIP-2612 permit (eth_signTypedData_v4
+                       JSON) instead of a transaction; the allowance it sets is predicted from
+                       the token's storage and checked against allowance()
+  --allowance-slot <slot>
+                       Slot the token's allowances mapping is declared at, for --from-permit
+                       (defaults to 1, the OpenZeppelin ERC20 layout)
+  --nonces-slot <slot> Slot the token's permit nonces mapping is declared at; with it the nonce
+                       the permit uses up is predicted too
+  --target-safe <addr> Safe address the signature is for (required with --from-trace,
+                       --from-simulate-v1, and --from-tenderly)
+  --data-to-sign <hex> EIP-712 data to sign (required with --from-trace, --from-simulate-v1, and
+                       --from-tenderly): 0x1901 + domain + message, domain + message, or
+                       typed-data JSON; @<file> reads it from a file
+  --simulate-only <file>
+                       Run forge and save its encoded state diff to <file> without decoding it;
+                       --rpc-url is not needed
+  --report-only <file> Build the validation file from a diff saved by --simulate-only instead of
+                       running forge; replaces --workdir and --forge-cmd
+  --diff-glob <pattern>
+                       Like --report-only, for every saved diff in --workdir (or the current
+                       directory) whose name matches <pattern>, e.g. 'diff-*.json'
+  --diff-mode <mode>   separate (default): write one validation file per diff, named like the
+                       diff, into the --out directory; merge: join diffs of the same transaction
+                       into one file at --out
+  --focus <addr>       Instead of writing a validation file, re-run forge with -vvvvv and print
+                       every call and storage access involving <addr> in execution order, its
+                       net slot changes, and the forge trace lines naming it. With --report-only
+                       the saved diff is used; --rpc-url is not needed
+  --env KEY=VALUE      Set a variable for the forge run (repeatable); it is prepended to the
+                       recorded cmd, except secrets (names like *PRIVATE_KEY*, *TOKEN*), which are
+                       passed through the environment and never written or logged
+  --env-allow <keys>   Comma-separated keys to load from <workdir>/.env besides the defaults
+                       (${DEFAULT_DOTENV_ALLOWLIST.join(', ')}); other keys in it are ignored
+  --no-dotenv          Do not load <workdir>/.env
+  --simulation-cache <dir>
+                       Reuse the forge run of an earlier invocation with the same command,
+                       workdir files, inherited environment, and fork block; defaults to
+                       ${SIMULATION_CACHE_ENV}, and nothing is cached when neither is set. Only
+                       commands pinned with --fork-block-number are cached
+  --no-cache           Run forge even when the simulation cache has the run, and do not cache it
+  --prestate-from <file>
+                       Validation file of a task that executes before this one; its state changes
+                       are written to <file>.prestate.json, which ${PRESTATE_FILE_ENV} names to
+                       forge. Generation fails when a changed slot did not start at the previous
+                       task's value, and the dependency is recorded
+  --safe-nonce <n>     Safe nonce the transaction was built for, when it cannot be recovered from
+                       the simulated execTransaction call; compared with the on-chain nonce
+  --sandbox            Run forge in a container with the workdir mounted read-only; the image
+                       comes from --sandbox-image or ${SANDBOX_ENV.image}
+  --sandbox-image <image>
+                       Container image for --sandbox, pinned by digest
+  --sandbox-network <name>
+                       Docker network for --sandbox, one whose egress only reaches the RPC
+                       endpoint; defaults to ${SANDBOX_ENV.network}, and one of them is required
+  --allowed-cmds <list>
+                       Comma-separated binaries the forge command may run, e.g. forge,just;
+                       defaults to ${ALLOWED_CMDS_ENV}, and any command runs when neither is set
+  --max-diff-size <size>
+                       Refuse stateDiff.json files and encoded diffs larger than <size>, e.g.
+                       128MB (defaults to 64MB)
+  --max-accesses <n>   Refuse diffs with more than <n> account accesses (defaults to 20000)
+  --max-preimages <n>  Refuse diffs with more than <n> mapping preimages (defaults to 200000)
+  --force              Decode the diff whatever its size; only for tasks known to need it
+  --allow-failed-execution
+                       Write the file even when the transaction reverts or the Safe's inner call
+                       fails when replayed against --rpc-url; signing it stays blocked
+  --strict-hash-format Only accept dataToSign as exactly 0x1901 + domain hash + message hash
+  --include-reads critical
+                       Add a criticalReads section listing reads of slots marked critical in
+                       contracts.json, e.g. to confirm a Safe guard was checked
+  --unknowns <mode>    How contracts and slots missing from contracts.json are written:
+                       placeholder (<<ContractName>>, <<Summary>>; the default), label
+                       ("unknown (0x...)" and "unknown"), or error to refuse the report
+  --preimages <file>   JSON object mapping storage slots to the mapping slot and key they are
+                       derived from ({"0x<slot>": {"parent": "0x...", "key": "0x..."}}), e.g.
+                       from an indexer, for nested mapping slots forge did not record; entries
+                       that describe a reported slot are written to extraPreimages
+  --preimage-store <dir>
+                       Learn mapping preimages across runs: the run starts from the preimages
+                       earlier runs resolved the same contracts' slots with, and adds the ones it
+                       resolves; defaults to ${PREIMAGE_STORE_ENV}, and nothing is learned when
+                       neither is set. Share it with stateDiff.ts preimages export and import
+  --metadata-cache <dir>
+                       Reuse what earlier runs found out about contracts missing from
+                       contracts.json (code hash, implementation, matched patterns); defaults to
+                       ${METADATA_CACHE_ENV}, and no cache is used when neither is set
+  --cache-ttl <hours>  Refetch cached entries older than <hours> (defaults to
+                       ${DEFAULT_METADATA_CACHE_TTL_HOURS})
+  --refresh            Ignore the metadata cache and fetch everything again; the results are
+                       still saved for later runs
+  --no-progress        Do not draw the progress line (phase, elapsed time, and percentage) on
+                       stderr; it is only drawn on a terminal, so CI logs never contain it
+  --verbose, -v        Add an intermediateWrites section listing the values a slot held between
+                       its before and after values when it was written more than once
+  --attest             Sign the output with a facilitator Ledger and embed the attestation
+  --attest-ledger-id <n>
+                       Ledger account index for --attest (defaults to 0)
+  --attest-keystore <file>
+                       Sign the attestation with a keystore instead of a Ledger; the password is
+                       read from ATTEST_KEYSTORE_PASSWORD
+  --only <addrs>       Comma-separated contract addresses to limit the report to
+  --exclude <addrs>    Comma-separated contract addresses to leave out of the report; the number
+                       of entries filtered out by --only/--exclude is recorded under scope
+  --expected-overrides <file>
+                       expected-overrides.yaml of the task; refuse the report unless the state
+                       overrides are exactly the documented ones
+  --nested-hashes      When owners of the target Safe are Safes, list the hashes each of them signs
+                       to approve the transaction under nestedHashes
+  --compare-rpc <url>  Independent RPC provider to read the chain ID, before values, code hashes,
+                       and Safe nonce, threshold, and owners from again; refuse the report unless
+                       both providers and the report agree
+  --history <dir>      Earlier validation files to compare with; contracts this task changes that
+                       an earlier file also changed are listed under recentlyModified
+  --audience <who>     Tailor the file to signer (hashes and the changes the transaction makes),
+                       facilitator (also state overrides, the write trace, and warnings), or
+                       auditor (everything; implies --verbose and, with forge, --include-raw).
+                       Validation compares the same view
+  --include-raw        Embed the encoded stateDiff, overrides, preimages, and dataToSign forge
+                       wrote under raw, so the report can be re-derived from the file alone
+  --manifest <file>    Signing manifest to add the written files to (sha256, size, and block pin),
+                       with the tool build and RPC hosts; files must be in the manifest's
+                       directory. Check it with \`state-diff manifest verify\`. Needs --out
+  --ledger <file>      Task ledger to record the written file in (task, chain, hashes, and file
+                       hash); list it with \`state-diff ledger list\`. Needs --out
+  --signer-instructions
+                       Add a signerInstructions section: the commands each signer runs, the
+                       hashes to compare, and what their signing device shows
+  --signer-template <file>
+                       Team template for --signer-instructions (implies it); see README
+  --clear-signing      Write an ERC-7730 clear-signing descriptor for the SafeTx next to --out
+                       (<file>.erc7730.json), so clear-signing Ledger devices label its fields
+  --emit-foundry-assertions <file>
+                       Write a Solidity library of vm.load/assertEq checks of the post-state, for
+                       the task's forge script to run after the task executes
+  --policy-plugin <file.wasm>
+                       Run a WebAssembly policy module on the report (repeatable); a finding
+                       with severity error refuses it. See README for the interface
+  --viewer-link <url>  Print a link to the web viewer at <url> that carries the file, compressed,
+                       in its fragment, for signers to browse on any device
+  --viewer-cid <cid>   IPFS CID of the pinned file, for --viewer-link to refer to instead of
+                       embedding it; needed for files too large for a link
+  --notify-webhook <url>
+                       Post a summary (task, chain, hashes, change counts, and the file) to a
+                       Slack or Discord webhook once the file is written. Give the URL as
+                       '\${VAR}' to read it from the environment. Needs --out
+  --notify-link <url>  Link to the published file in the message, instead of its local path
+  --sink <spec>        Also write the file to file=PATH, stdout, http=URL (POST), or s3=URL (PUT
+                       to a presigned upload URL). Repeat the flag or separate sinks with commas;
+                       URLs may be \${VAR}. Remote sinks are retried, and a sink that still fails
+                       fails the run after the others are written. Without --out, the first file
+                       sink is the output file
+  --redact <file>      Privacy list JSON ({ "addresses": [...], "slotPatterns": [...] }); also
+                       writes a shareable copy, <out>.redacted.json, with calldata and listed
+                       values replaced by placeholders. Needs --out
+  --version            Print the tool version, git commit, embedded config hash, and build date;
+                       the same details are written to every file under generatedBy
   --help, -h           Show this help message
 
 Examples:
@@ -52,13 +373,82 @@ async function main() {
     args: process.argv.slice(2),
     options: {
       'rpc-url': { type: 'string', short: 'r' },
+      chain: { type: 'string' },
       workdir: { type: 'string', short: 'w' },
       'forge-cmd': { type: 'string', short: 'f' },
+      'use-just': { type: 'boolean' },
+      'just-args': { type: 'string' },
       'ledger-id': { type: 'string', short: 'l' },
       out: { type: 'string', short: 'o' },
+      format: { type: 'string' },
+      'max-output-size': { type: 'string' },
       'estimate-l2-gas': { type: 'boolean' },
       'l2-rpc-url': { type: 'string' },
       'l2-gas-buffer': { type: 'string' },
+      'safe-nonce': { type: 'string' },
+      'from-trace': { type: 'string' },
+      'from-simulate-v1': { type: 'string' },
+      'from-tenderly': { type: 'string' },
+      'from-permit': { type: 'string' },
+      'allowance-slot': { type: 'string' },
+      'nonces-slot': { type: 'string' },
+      'target-safe': { type: 'string' },
+      'data-to-sign': { type: 'string' },
+      'simulate-only': { type: 'string' },
+      'report-only': { type: 'string' },
+      'diff-glob': { type: 'string' },
+      'diff-mode': { type: 'string' },
+      focus: { type: 'string' },
+      'prestate-from': { type: 'string' },
+      env: { type: 'string', multiple: true },
+      'env-allow': { type: 'string' },
+      'no-dotenv': { type: 'boolean' },
+      'simulation-cache': { type: 'string' },
+      'no-cache': { type: 'boolean' },
+      'strict-hash-format': { type: 'boolean' },
+      'max-diff-size': { type: 'string' },
+      'max-accesses': { type: 'string' },
+      'max-preimages': { type: 'string' },
+      force: { type: 'boolean' },
+      'allow-failed-execution': { type: 'boolean' },
+      sandbox: { type: 'boolean' },
+      'sandbox-image': { type: 'string' },
+      'sandbox-network': { type: 'string' },
+      'allowed-cmds': { type: 'string' },
+      verbose: { type: 'boolean', short: 'v' },
+      'no-progress': { type: 'boolean' },
+      'include-reads': { type: 'string' },
+      unknowns: { type: 'string' },
+      preimages: { type: 'string' },
+      'preimage-store': { type: 'string' },
+      'metadata-cache': { type: 'string' },
+      'cache-ttl': { type: 'string' },
+      refresh: { type: 'boolean' },
+      attest: { type: 'boolean' },
+      'attest-ledger-id': { type: 'string' },
+      'attest-keystore': { type: 'string' },
+      only: { type: 'string' },
+      exclude: { type: 'string' },
+      audience: { type: 'string' },
+      redact: { type: 'string' },
+      history: { type: 'string' },
+      'expected-overrides': { type: 'string' },
+      'compare-rpc': { type: 'string' },
+      'nested-hashes': { type: 'boolean' },
+      'clear-signing': { type: 'boolean' },
+      'emit-foundry-assertions': { type: 'string' },
+      'policy-plugin': { type: 'string', multiple: true },
+      'viewer-link': { type: 'string' },
+      'viewer-cid': { type: 'string' },
+      'notify-webhook': { type: 'string' },
+      sink: { type: 'string', multiple: true },
+      'notify-link': { type: 'string' },
+      ledger: { type: 'string' },
+      manifest: { type: 'string' },
+      'include-raw': { type: 'boolean' },
+      'signer-instructions': { type: 'boolean' },
+      'signer-template': { type: 'string' },
+      version: { type: 'boolean' },
       help: { type: 'boolean', short: 'h' },
     },
   });
@@ -72,29 +462,223 @@ async function main() {
     return;
   }
 
-  const rpcUrl = values['rpc-url'] ?? '';
+  if (values.version) {
+    console.log(formatBuildInfo(getBuildInfo()));
+    return;
+  }
+
+  if (!values['no-progress']) enableProgress();
+
+  const chain = values.chain ? resolveChain(values.chain) : undefined;
+  const rpcUrl = values['rpc-url'] ?? chain?.rpcUrl ?? '';
+  if (chain) {
+    if (!rpcUrl) {
+      console.error(`${chain.name} has no default RPC URL; pass --rpc-url`);
+      process.exitCode = 1;
+      return;
+    }
+    await assertConnectedChain(rpcUrl, chain);
+    console.log(`🔗 Connected to ${chain.name} (${chain.chainId})`);
+  }
   const workdirFlag = values.workdir ?? '';
   const forgeCmdFlag = values['forge-cmd'] ?? '';
   const ledgerIdFlag = values['ledger-id'];
-  const outFlag = values.out;
+  const justArgs = (values['just-args'] ?? '').split(/\s+/).filter(Boolean);
+  const sinks = parseSinks(values.sink ?? []);
+  // Without --out, the first file sink is the file the manifest, ledger, and split parts use
+  const primarySink = values.out ? undefined : sinks.find(sink => sink.kind === 'file');
+  const outFlag =
+    values.out ??
+    primarySink?.path ??
+    (values['use-just'] && workdirFlag && !values['simulate-only']
+      ? justValidationPath(path.resolve(process.cwd(), workdirFlag), justArgs)
+      : undefined);
   const estimateL2Gas = values['estimate-l2-gas'] ?? false;
   const l2RpcUrl = values['l2-rpc-url'];
-  const l2GasBufferFlag = values['l2-gas-buffer'];
-
-  if (!rpcUrl || !workdirFlag || !forgeCmdFlag) {
-    console.error('Missing required flags.');
-    printUsage();
+  const fromTraceFlag = values['from-trace'];
+  const simulateOnlyFlag = values['simulate-only'];
+  const reportOnlyFlag = values['report-only'];
+  const diffGlobFlag = values['diff-glob'];
+  const focusFlag = values.focus;
+  const limits = loadDecodeLimits(values);
+  const includeReads = parseReadsMode(values['include-reads']);
+  const unknowns = parseUnknownMode(values.unknowns);
+  const metadataCache = loadMetadataCacheOptions(values);
+  const preimageStore = preimageStoreDir(values);
+  const outputOptions: OutputOptions = {
+    format: parseOutputFormat(values.format),
+    maxOutputSize:
+      values['max-output-size'] !== undefined
+        ? parseByteSize(values['max-output-size'], '--max-output-size')
+        : undefined,
+    scope: loadScopeFilter(values),
+    audience: values.audience ? parseReportAudience(values.audience) : undefined,
+    privacy: values.redact ? loadPrivacyList(values.redact) : undefined,
+    attest: loadAttestationSigner(values),
+    signerTemplate: loadSignerTemplate(values),
+    expectedOverrides: values['expected-overrides']
+      ? parseExpectedOverrides(
+          readFileSync(path.resolve(process.cwd(), values['expected-overrides']), 'utf-8')
+        )
+      : undefined,
+    history: values.history ? loadHistoryDir(values.history, outFlag) : undefined,
+    ledger: values.ledger ? path.resolve(process.cwd(), values.ledger) : undefined,
+    manifest: values.manifest ? path.resolve(process.cwd(), values.manifest) : undefined,
+    compareRpc: values['compare-rpc'],
+    nestedHashes: values['nested-hashes'],
+    clearSigning: values['clear-signing'],
+    foundryAssertions: values['emit-foundry-assertions']
+      ? path.resolve(process.cwd(), values['emit-foundry-assertions'])
+      : undefined,
+    // Loaded up front, so a broken plugin fails before the simulation runs
+    policyPlugins: values['policy-plugin']
+      ? await Promise.all(
+          values['policy-plugin'].map(file => loadPolicyPlugin(path.resolve(process.cwd(), file)))
+        )
+      : undefined,
+    viewerUrl: values['viewer-link'] ? parseViewerUrl(values['viewer-link']) : undefined,
+    viewerCid: values['viewer-cid'] ? parseCid(values['viewer-cid']) : undefined,
+    notifyWebhook: values['notify-webhook'] ? parseWebhookUrl(values['notify-webhook']) : undefined,
+    notifyLink: values['notify-link'],
+    sinks: sinks.filter(sink => sink !== primarySink),
+  };
+  if (outputOptions.compareRpc && outputOptions.compareRpc === rpcUrl) {
+    console.error('--compare-rpc must be a different provider than --rpc-url');
+    process.exitCode = 1;
+    return;
+  }
+  if (outputOptions.maxOutputSize !== undefined && (!outFlag || outputOptions.format !== 'json')) {
+    console.error('--max-output-size needs --out and JSON output; only JSON files are split');
+    process.exitCode = 1;
+    return;
+  }
+  if (outputOptions.viewerCid && !outputOptions.viewerUrl) {
+    console.error('--viewer-cid needs --viewer-link; the CID is put in the viewer link');
+    process.exitCode = 1;
+    return;
+  }
+  if (outputOptions.privacy && !outFlag) {
+    console.error('--redact needs --out; the shareable copy is written next to the full file');
+    process.exitCode = 1;
+    return;
+  }
+  if (outputOptions.manifest && !outFlag) {
+    console.error('--manifest needs --out; there is no file to list');
+    process.exitCode = 1;
+    return;
+  }
+  if (outputOptions.ledger && !outFlag) {
+    console.error('--ledger needs --out; there is no file to record');
+    process.exitCode = 1;
+    return;
+  }
+  if (outputOptions.clearSigning && !outFlag) {
+    console.error('--clear-signing needs --out; the descriptor is written next to it');
+    process.exitCode = 1;
+    return;
+  }
+  if ((outputOptions.notifyWebhook || outputOptions.notifyLink) && !outFlag) {
+    console.error('--notify-webhook needs --out; the message names the written file');
+    process.exitCode = 1;
+    return;
+  }
+  if (
+    sinks.length > 0 &&
+    (values['simulate-only'] || (values['diff-glob'] && values['diff-mode'] !== 'merge'))
+  ) {
+    console.error('--sink takes one validation file; not --simulate-only or --diff-mode separate');
     process.exitCode = 1;
     return;
   }
 
-  if (estimateL2Gas && !l2RpcUrl) {
-    console.error('--l2-rpc-url is required when using --estimate-l2-gas');
+  const withoutForge =
+    fromTraceFlag || values['from-simulate-v1'] || values['from-tenderly'] || values['from-permit'];
+  if (withoutForge && values['include-raw']) {
+    console.error('--include-raw needs a forge run or --report-only; other sources have no blobs');
+    process.exitCode = 1;
+    return;
+  }
+  if (outputOptions.audience === 'auditor') {
+    // Auditors get everything the run can produce
+    values.verbose = true;
+    if (!withoutForge) values['include-raw'] = true;
+  }
+  if ((values.env || values['env-allow']) && (withoutForge || reportOnlyFlag || diffGlobFlag)) {
+    console.error('--env and --env-allow need a forge run');
+    process.exitCode = 1;
+    return;
+  }
+  if (
+    (values['simulation-cache'] || values['no-cache']) &&
+    (withoutForge || reportOnlyFlag || diffGlobFlag)
+  ) {
+    console.error('--simulation-cache and --no-cache need a forge run');
+    process.exitCode = 1;
+    return;
+  }
+  if (focusFlag && (withoutForge || simulateOnlyFlag)) {
+    console.error('--focus needs a forge run or --report-only');
+    process.exitCode = 1;
+    return;
+  }
+  if (outputOptions.compareRpc && (simulateOnlyFlag || focusFlag)) {
+    console.error('--compare-rpc checks a report, which --simulate-only and --focus do not write');
+    process.exitCode = 1;
+    return;
+  }
+  if (values.preimages && simulateOnlyFlag) {
+    console.error('--preimages is used when the report is built; pass it with --report-only');
+    process.exitCode = 1;
+    return;
+  }
+  if (focusFlag && !isAddress(focusFlag)) {
+    console.error(`--focus must be an address, got ${focusFlag}`);
     process.exitCode = 1;
     return;
   }
 
-  const workdir = path.resolve(process.cwd(), workdirFlag);
+  if (fromTraceFlag) {
+    const source = { kind: 'trace', file: fromTraceFlag } as const;
+    await generateWithoutForge(rpcUrl, source, values, ledgerIdFlag, outFlag, outputOptions);
+    return;
+  }
+
+  const fromSimulateV1Flag = values['from-simulate-v1'];
+  if (fromSimulateV1Flag) {
+    const source = { kind: 'simulate-v1', file: fromSimulateV1Flag } as const;
+    await generateWithoutForge(rpcUrl, source, values, ledgerIdFlag, outFlag, outputOptions);
+    return;
+  }
+
+  const fromTenderlyFlag = values['from-tenderly'];
+  if (fromTenderlyFlag) {
+    const source = { kind: 'tenderly', file: fromTenderlyFlag } as const;
+    await generateWithoutForge(rpcUrl, source, values, ledgerIdFlag, outFlag, outputOptions);
+    return;
+  }
+
+  const fromPermitFlag = values['from-permit'];
+  if (fromPermitFlag) {
+    const source = { kind: 'permit', file: fromPermitFlag } as const;
+    await generateWithoutForge(rpcUrl, source, values, ledgerIdFlag, outFlag, outputOptions);
+    return;
+  }
+
+  if (simulateOnlyFlag && reportOnlyFlag) {
+    console.error('--simulate-only and --report-only cannot be combined');
+    process.exitCode = 1;
+    return;
+  }
+  if (diffGlobFlag && (simulateOnlyFlag || reportOnlyFlag || focusFlag || forgeCmdFlag)) {
+    console.error('--diff-glob reads saved diffs; it replaces --report-only and --forge-cmd');
+    process.exitCode = 1;
+    return;
+  }
+  if (values['diff-mode'] && !diffGlobFlag) {
+    console.error('--diff-mode needs --diff-glob');
+    process.exitCode = 1;
+    return;
+  }
 
   const ledgerId = ledgerIdFlag ? Number.parseInt(ledgerIdFlag, 10) : 0;
 
@@ -104,7 +688,125 @@ async function main() {
     return;
   }
 
-  const tokens = shellParse(forgeCmdFlag);
+  const preimages = loadPreimages(values);
+  if (diffGlobFlag) {
+    if (!rpcUrl || !outFlag) {
+      console.error('--diff-glob requires --rpc-url and --out.');
+      process.exitCode = 1;
+      return;
+    }
+    if (estimateL2Gas && !l2RpcUrl) {
+      console.error('--l2-rpc-url is required when using --estimate-l2-gas');
+      process.exitCode = 1;
+      return;
+    }
+    const mode = parseDiffMode(values['diff-mode']);
+    const dir = path.resolve(process.cwd(), workdirFlag || '.');
+    const names = matchDiffGlob(dir, diffGlobFlag);
+    if (names.length === 0) {
+      console.error(`No file in ${dir} matches ${diffGlobFlag}`);
+      process.exitCode = 1;
+      return;
+    }
+    console.log(`🗃️  ${names.length} diff(s) in ${dir} match ${diffGlobFlag}: ${names.join(', ')}`);
+    const diffs: MatchedDiff[] = names.map(name => ({
+      file: name,
+      artifact: loadSimulationArtifact(path.join(dir, name), limits),
+    }));
+    const sdc = new StateDiffClient(ledgerId, undefined, {
+      strictHashFormat: values['strict-hash-format'],
+      verbose: values.verbose,
+      limits,
+      includeReads,
+      unknowns,
+      metadataCache,
+      preimages,
+      preimageStore,
+    });
+    if (mode === 'merge') {
+      const artifact = mergeSimulationArtifacts(diffs);
+      console.log(`🧵 Merged ${diffs.length} diff(s); the file's cmd is the one of ${names[0]}`);
+      const simulation = await sdc.fromSimulationArtifact(rpcUrl, artifact);
+      await finishReport(simulation, artifact, values, outFlag, outputOptions);
+      return;
+    }
+    for (const { file, artifact } of diffs) {
+      console.log(`\n📄 ${file}`);
+      const simulation = await sdc.fromSimulationArtifact(rpcUrl, artifact);
+      const out = path.join(outFlag, file);
+      await finishReport(simulation, artifact, values, out, outputOptions);
+    }
+    return;
+  }
+  if (reportOnlyFlag) {
+    if (focusFlag) {
+      printFocusTrace(loadSimulationArtifact(reportOnlyFlag, limits), focusFlag, preimages);
+      return;
+    }
+    if (!rpcUrl) {
+      console.error('--report-only requires --rpc-url.');
+      printUsage();
+      process.exitCode = 1;
+      return;
+    }
+    if (estimateL2Gas && !l2RpcUrl) {
+      console.error('--l2-rpc-url is required when using --estimate-l2-gas');
+      process.exitCode = 1;
+      return;
+    }
+    const artifact = loadSimulationArtifact(reportOnlyFlag, limits);
+    const sdc = new StateDiffClient(ledgerId, undefined, {
+      strictHashFormat: values['strict-hash-format'],
+      verbose: values.verbose,
+      limits,
+      includeReads,
+      unknowns,
+      metadataCache,
+      preimages,
+      preimageStore,
+    });
+    const simulation = await sdc.fromSimulationArtifact(rpcUrl, artifact);
+    await finishReport(simulation, artifact, values, outFlag, outputOptions);
+    return;
+  }
+
+  const useJust = values['use-just'] ?? false;
+  if (useJust && (forgeCmdFlag || focusFlag || estimateL2Gas)) {
+    console.error(
+      '--use-just replaces --forge-cmd, and cannot add the -v flags of --focus or --estimate-l2-gas'
+    );
+    process.exitCode = 1;
+    return;
+  }
+  if (values['just-args'] && !useJust) {
+    console.error('--just-args needs --use-just');
+    process.exitCode = 1;
+    return;
+  }
+  if (
+    (!rpcUrl && !simulateOnlyFlag && !focusFlag) ||
+    !workdirFlag ||
+    (!forgeCmdFlag && !useJust)
+  ) {
+    console.error('Missing required flags.');
+    printUsage();
+    process.exitCode = 1;
+    return;
+  }
+
+  // The L2 RPC is only used when the report is built, which --simulate-only defers
+  if (estimateL2Gas && !l2RpcUrl && !simulateOnlyFlag) {
+    console.error('--l2-rpc-url is required when using --estimate-l2-gas');
+    process.exitCode = 1;
+    return;
+  }
+
+  const workdir = path.resolve(process.cwd(), workdirFlag);
+
+  const justRecipe = useJust ? loadJustRecipe(workdir) : undefined;
+  const tokens = justRecipe
+    ? buildJustCommand(justRecipe, justArgs)
+    : shellParse(forgeCmdFlag);
   const forgeCmdParts: string[] = tokens.map(t => {
     if (typeof t !== 'string') {
       throw new Error(
@@ -114,6 +816,73 @@ async function main() {
     return t;
   });
 
+  let prestateFrom: PrestateDependency | undefined;
+  let prestateOverrides: PrestateOverride[] = [];
+  const prestateFlag = values['prestate-from'];
+  if (prestateFlag) {
+    if (forgeCmdParts.some(part => part.startsWith(`${PRESTATE_FILE_ENV}=`))) {
+      console.error(`--prestate-from cannot be combined with ${PRESTATE_FILE_ENV} in --forge-cmd`);
+      process.exitCode = 1;
+      return;
+    }
+    const previousPath = path.resolve(process.cwd(), prestateFlag);
+    const parsedPrevious = parseFromString(readFileSync(previousPath, 'utf-8'));
+    if (!('config' in parsedPrevious)) {
+      console.error(`Invalid --prestate-from file ${previousPath}`);
+      console.error(getValidationSummary(parsedPrevious.result));
+      process.exitCode = 1;
+      return;
+    }
+    prestateOverrides = buildPrestateOverrides(parsedPrevious.config);
+    const overridesPath = prestateOverridesPath(previousPath);
+    const overridesFile = path.relative(workdir, overridesPath);
+    if (overridesFile.startsWith('..') || path.isAbsolute(overridesFile)) {
+      console.error(`--prestate-from ${previousPath} must be inside --workdir ${workdir}`);
+      process.exitCode = 1;
+      return;
+    }
+    writeFileSync(overridesPath, JSON.stringify(prestateOverrides, null, 2) + '\n');
+    forgeCmdParts.unshift(prestateEnvAssignment(overridesFile.split(path.sep).join('/')));
+    prestateFrom = describePrestateDependency(parsedPrevious.config, prestateFlag);
+    console.log(
+      `⛓️  Simulating on top of ${prestateFlag} (safeTxHash ${prestateFrom.safeTxHash}): ${prestateOverrides.length} contract(s) overridden from ${overridesFile}`
+    );
+  }
+
+  const dotenv = values['no-dotenv']
+    ? { values: {}, skipped: [] }
+    : loadWorkdirEnv(workdir, [
+        ...DEFAULT_DOTENV_ALLOWLIST,
+        ...(values['env-allow'] ?? '').split(',').map(key => key.trim()).filter(Boolean),
+      ]);
+  if (dotenv.skipped.length > 0) {
+    console.log(
+      `🙈 Ignoring keys in ${workdir}/.env that are not allowed: ${dotenv.skipped.join(', ')}`
+    );
+  }
+  const injectedEnv = resolveSimulationEnv({
+    flags: values.env ?? [],
+    dotenv: dotenv.values,
+    forgeCmdParts,
+  });
+  forgeCmdParts.unshift(...envAssignments(injectedEnv));
+  const simulationEnv = injectedEnv.length > 0 ? recordSimulationEnv(injectedEnv) : undefined;
+  for (const entry of simulationEnv ?? []) {
+    const from = entry.source === 'flag' ? '--env' : '.env';
+    console.log(`🌱 ${entry.name}=${entry.value} (from ${from})`);
+  }
+  if (justRecipe) {
+    const env = { ...process.env, ...Object.fromEntries(injectedEnv.map(e => [e.name, e.value])) };
+    const missing = missingJustEnv(justRecipe, env);
+    if (missing.length > 0) {
+      console.error(
+        `just ${justRecipe.name} reads ${missing.join(', ')} with env_var(); set them with --env or in the environment`
+      );
+      process.exitCode = 1;
+      return;
+    }
+  }
+
   // If L2 gas estimation is enabled, ensure -vvvv flag is present for event output
   if (estimateL2Gas) {
     const hasVerboseFlag = forgeCmdParts.some(part => part === '-vvvv' || part === '-vvvvv');
@@ -123,8 +892,130 @@ async function main() {
     }
   }
 
-  const sdc = new StateDiffClient(ledgerId, workdir);
-  const { result, forgeOutput } = await sdc.simulate(rpcUrl, forgeCmdParts, workdir);
+  const sandbox = loadSandboxConfig(values);
+  const sdc = new StateDiffClient(ledgerId, workdir, {
+    strictHashFormat: values['strict-hash-format'],
+    verbose: values.verbose,
+    sandbox,
+    allowedCommands: values['allowed-cmds']
+      ? parseAllowedCommands(values['allowed-cmds'])
+      : allowedCommandsFromEnv(),
+    limits,
+    includeReads,
+    unknowns,
+    metadataCache,
+    env: secretEnv(injectedEnv),
+    preimages,
+    preimageStore,
+  });
+
+  if (focusFlag) {
+    // The most verbose trace, so the forge output shows every call with its storage changes
+    const verbosity = forgeCmdParts.findIndex(part => /^-v+$/.test(part));
+    if (verbosity === -1) forgeCmdParts.push('-vvvvv');
+    else forgeCmdParts[verbosity] = '-vvvvv';
+    printFocusTrace(await sdc.simulateOnly(forgeCmdParts, workdir), focusFlag, preimages);
+    return;
+  }
+
+  const cacheDir = simulationCacheDir(values);
+  const cache = {
+    dir: cacheDir,
+    secretEnv: secretEnv(injectedEnv),
+    // Forge inherits the host environment unless it runs in the sandbox
+    hostEnv: sandbox ? {} : process.env,
+    sandboxImage: sandbox?.image,
+    // Written by this run, so they are not inputs to it
+    outputs: [
+      outFlag,
+      simulateOnlyFlag,
+      outputOptions.foundryAssertions,
+      outputOptions.manifest,
+      outputOptions.ledger,
+      cacheDir,
+    ]
+      .filter((output): output is string => Boolean(output))
+      .map(output => path.resolve(process.cwd(), output)),
+  };
+  if (simulateOnlyFlag) {
+    const artifact = await runForgeCached(sdc, forgeCmdParts, workdir, rpcUrl || undefined, cache);
+    writeSimulationArtifact(
+      {
+        ...artifact,
+        ...(prestateFrom ? { prestateFrom } : {}),
+        ...(simulationEnv ? { simulationEnv } : {}),
+      },
+      simulateOnlyFlag
+    );
+    return;
+  }
+
+  const artifact = await runForgeCached(sdc, forgeCmdParts, workdir, rpcUrl || undefined, cache);
+  const simulation = await sdc.fromSimulationArtifact(rpcUrl, artifact);
+  // The forge script applies the overrides, so check it did before writing the report
+  const unapplied = unappliedPrestate(prestateOverrides, simulation.result.stateChanges);
+  if (unapplied.length > 0) {
+    console.error(`❌ The forge script did not apply the overrides from ${prestateFlag}:`);
+    for (const mismatch of unapplied) console.error(`   ${describePrestateMismatch(mismatch)}`);
+    process.exitCode = EXIT_CODES.policyViolation;
+    return;
+  }
+  await finishReport(simulation, { prestateFrom, simulationEnv }, values, outFlag, outputOptions);
+}
+
+async function finishReport(
+  simulation: SimulationResult,
+  // Recorded with the forge run and carried into the report
+  { prestateFrom, simulationEnv }: Pick<SimulationArtifact, 'prestateFrom' | 'simulationEnv'>,
+  values: {
+    'estimate-l2-gas'?: boolean;
+    'l2-rpc-url'?: string;
+    'l2-gas-buffer'?: string;
+    'safe-nonce'?: string;
+    'include-raw'?: boolean;
+    'allow-failed-execution'?: boolean;
+  },
+  outFlag: string | undefined,
+  outputOptions: OutputOptions
+): Promise<void> {
+  const { forgeOutput } = simulation;
+  let result = simulation.result;
+  // Nobody should be asked to sign a transaction that does not execute
+  if (result.execution && result.execution.status !== 'success') {
+    if (!values['allow-failed-execution']) {
+      console.error(`❌ ${describeExecutionCheck(result.execution)}`);
+      console.error('   Fix the task, or pass --allow-failed-execution to write the file anyway');
+      process.exitCode = EXIT_CODES.policyViolation;
+      return;
+    }
+    console.warn('⚠️  --allow-failed-execution: writing a file for a transaction that fails');
+  }
+  const safeNonceFlag = values['safe-nonce'];
+  if (safeNonceFlag !== undefined) {
+    const safeNonce = Number.parseInt(safeNonceFlag, 10);
+    if (!Number.isInteger(safeNonce) || safeNonce < 0) {
+      console.error('--safe-nonce must be a non-negative integer');
+      process.exitCode = 1;
+      return;
+    }
+    if (result.safeNonce !== undefined && result.safeNonce !== safeNonce) {
+      console.error(
+        `--safe-nonce ${safeNonce} does not match nonce ${result.safeNonce} recovered from the simulated call`
+      );
+      process.exitCode = EXIT_CODES.policyViolation;
+      return;
+    }
+    if (result.safeNonce === undefined) {
+      const safe = result.expectedDomainAndMessageHashes.address;
+      const nonceWarning = await checkSafeNonce(result.rpcUrl, safe, safeNonce);
+      if (nonceWarning) console.warn(`⚠️  ${nonceWarning}`);
+      result = { ...result, safeNonce };
+    }
+  }
+
+  const estimateL2Gas = values['estimate-l2-gas'] ?? false;
+  const l2RpcUrl = values['l2-rpc-url'];
+  const l2GasBufferFlag = values['l2-gas-buffer'];
 
   // Optionally estimate L2 gas for deposit transactions
   let resultWithL2Gas = result;
@@ -185,6 +1076,9 @@ async function main() {
   const { identity } = await generateDeviceCertificate(undefined);
   const resultWithTaskOrigin = {
     ...resultWithL2Gas,
+    ...(prestateFrom ? { prestateFrom } : {}),
+    ...(simulationEnv ? { simulationEnv } : {}),
+    ...(values['include-raw'] ? { raw: simulation.encoded } : {}),
     taskOriginConfig: {
       taskCreator: {
         commonName: identity,
@@ -192,22 +1086,691 @@ async function main() {
     },
   };
 
-  const output = JSON.stringify(resultWithTaskOrigin, null, 2);
+  await writeOutput(resultWithTaskOrigin, outFlag, outputOptions);
 
+  // Note: Signing by the task creator should be done separately after all validation files are created
+}
+
+function loadSimulationArtifact(file: string, limits: DecodeLimits | null): SimulationArtifact {
+  const artifactPath = path.resolve(process.cwd(), file);
+  console.log(`📥 Reading simulation diff from ${artifactPath}`);
+  if (limits) checkDiffFileSize(file, statSync(artifactPath).size, limits);
+  return parseSimulationArtifact(JSON.parse(readFileSync(artifactPath, 'utf-8')));
+}
+
+function printFocusTrace(
+  artifact: SimulationArtifact,
+  focus: string,
+  extraPreimages: ParentPreimage[]
+): void {
+  const { stateDiff, preimages } = artifact.stateDiff;
+  // Later entries win, so the simulation's own preimages go last
+  const trace = buildFocusTrace(decodeStateDiff(stateDiff), focus, [
+    ...extraPreimages,
+    ...decodePreimages(preimages),
+  ]);
+  for (const line of formatFocusTrace(trace)) console.log(line);
+
+  const forgeLines = forgeTraceLines(artifact.forgeOutput, focus);
+  if (forgeLines.length === 0) {
+    console.log(`\nThe forge output does not name ${focus} (it may appear under a label)`);
+    return;
+  }
+  console.log(`\nForge trace lines naming ${focus}:`);
+  for (const line of forgeLines) console.log(line);
+}
+
+function writeSimulationArtifact(artifact: SimulationArtifact, file: string): void {
+  const artifactPath = path.resolve(process.cwd(), file);
+  mkdirSync(path.dirname(artifactPath), { recursive: true });
+  writeFileSync(artifactPath, JSON.stringify(artifact, null, 2) + '\n');
+  console.log(`Wrote simulation diff to: ${artifactPath}`);
+  console.log('   Generate the validation file with --report-only');
+}
+
+function simulationCacheDir(values: {
+  'simulation-cache'?: string;
+  'no-cache'?: boolean;
+}): string | null {
+  if (values['no-cache']) return null;
+  const dir = values['simulation-cache'] ?? process.env[SIMULATION_CACHE_ENV];
+  return dir ? path.resolve(process.cwd(), dir) : null;
+}
+
+/**
+ * Runs forge, or takes the run from the simulation cache when the command is pinned to a block
+ * and neither it, the workdir's files, nor forge's environment changed since the run was cached.
+ */
+async function runForgeCached(
+  sdc: StateDiffClient,
+  forgeCmdParts: string[],
+  workdir: string,
+  // Asked for the block forge forks from, which the run records; absent with --simulate-only
+  // and no --rpc-url
+  rpcUrl: string | undefined,
+  cache: {
+    dir: string | null;
+    secretEnv: Record<string, string>;
+    hostEnv: NodeJS.ProcessEnv;
+    sandboxImage?: string;
+    outputs: string[];
+  }
+): Promise<SimulationArtifact> {
+  if (!cache.dir) return sdc.simulateOnly(forgeCmdParts, workdir, rpcUrl);
+  const block = forkBlockPin(forgeCmdParts);
+  if (!block) {
+    console.log('🗃️  Not caching the forge run: pin its block with --fork-block-number');
+    return sdc.simulateOnly(forgeCmdParts, workdir, rpcUrl);
+  }
+  const key = simulationCacheKey({
+    forgeCmdParts,
+    block,
+    inputsHash: await hashSimulationInputs(workdir, cache.outputs),
+    secretEnv: cache.secretEnv,
+    hostEnv: cache.hostEnv,
+    sandboxImage: cache.sandboxImage,
+  });
+  const cached = await loadCachedSimulation(cache.dir, key);
+  if (cached) {
+    console.log(
+      `🗃️  Reusing the forge run at block ${block} from ${simulationCachePath(cache.dir, key)}; pass --no-cache to run forge again`
+    );
+    return cached;
+  }
+  const artifact = await sdc.simulateOnly(forgeCmdParts, workdir, rpcUrl);
+  await saveCachedSimulation(cache.dir, key, artifact);
+  console.log(`🗃️  Cached the forge run at block ${block}`);
+  return artifact;
+}
+
+function loadJustRecipe(workdir: string): JustRecipe {
+  const justfile = findJustfile(workdir);
+  if (!justfile) throw new Error(`--use-just: no justfile in ${workdir}`);
+  const recipes = parseJustRecipes(readFileSync(justfile, 'utf-8'));
+  const targets = JUST_TARGETS.filter(target => recipes.some(r => r.name === target));
+  console.log(`📜 ${justfile} has the ${targets.join(' and ') || 'none of the'} task recipe(s)`);
+  const simulate = recipes.find(r => r.name === 'simulate');
+  if (!simulate) throw new Error(`--use-just: ${justfile} has no simulate recipe`);
+  return simulate;
+}
+
+function loadDecodeLimits(values: {
+  'max-diff-size'?: string;
+  'max-accesses'?: string;
+  'max-preimages'?: string;
+  force?: boolean;
+}): DecodeLimits | null {
+  if (values.force) {
+    console.warn('⚠️  --force: decoding the state diff without size limits');
+    return null;
+  }
+  const count = (flag: string, value: string | undefined, fallback: number): number => {
+    if (value === undefined) return fallback;
+    const n = Number(value);
+    if (!Number.isInteger(n) || n <= 0) {
+      throw new Error(`${flag} must be a positive integer`);
+    }
+    return n;
+  };
+  return {
+    maxDiffBytes: values['max-diff-size']
+      ? parseByteSize(values['max-diff-size'], '--max-diff-size')
+      : DEFAULT_DECODE_LIMITS.maxDiffBytes,
+    maxAccesses: count('--max-accesses', values['max-accesses'], DEFAULT_DECODE_LIMITS.maxAccesses),
+    maxPreimages: count(
+      '--max-preimages',
+      values['max-preimages'],
+      DEFAULT_DECODE_LIMITS.maxPreimages
+    ),
+  };
+}
+
+function loadSandboxConfig(values: {
+  sandbox?: boolean;
+  'sandbox-image'?: string;
+  'sandbox-network'?: string;
+}): SandboxConfig | undefined {
+  if (!values.sandbox) return undefined;
+  const image = values['sandbox-image'] ?? process.env[SANDBOX_ENV.image];
+  if (!image) {
+    throw new Error(`--sandbox needs --sandbox-image or ${SANDBOX_ENV.image}`);
+  }
+  return resolveSandboxConfig({
+    image,
+    network: values['sandbox-network'] ?? process.env[SANDBOX_ENV.network],
+    runtime: process.env[SANDBOX_ENV.runtime],
+  });
+}
+
+function loadPreimages(values: { preimages?: string }): ParentPreimage[] {
+  if (!values.preimages) return [];
+  const file = path.resolve(process.cwd(), values.preimages);
+  const preimages = loadPreimageDatabase(file);
+  console.log(`🧩 Loaded ${preimages.length} mapping preimage(s) from ${file}`);
+  return preimages;
+}
+
+function preimageStoreDir(values: { 'preimage-store'?: string }): string | null {
+  const dir = values['preimage-store'] ?? process.env[PREIMAGE_STORE_ENV];
+  return dir ? path.resolve(process.cwd(), dir) : null;
+}
+
+function loadMetadataCacheOptions(values: {
+  'metadata-cache'?: string;
+  'cache-ttl'?: string;
+  refresh?: boolean;
+}): MetadataCacheOptions | null {
+  const dir = values['metadata-cache'] ?? process.env[METADATA_CACHE_ENV];
+  if (!dir) {
+    if (values['cache-ttl'] !== undefined || values.refresh) {
+      throw new Error(`--cache-ttl and --refresh need --metadata-cache or ${METADATA_CACHE_ENV}`);
+    }
+    return null;
+  }
+  const ttlHours =
+    values['cache-ttl'] === undefined
+      ? DEFAULT_METADATA_CACHE_TTL_HOURS
+      : Number(values['cache-ttl']);
+  if (!Number.isFinite(ttlHours) || ttlHours < 0) {
+    throw new Error('--cache-ttl must be a non-negative number of hours');
+  }
+  return {
+    dir: path.resolve(process.cwd(), dir),
+    ttlHours,
+    refresh: values.refresh ?? false,
+  };
+}
+
+function loadSignerTemplate(values: {
+  'signer-instructions'?: boolean;
+  'signer-template'?: string;
+}): SignerTemplate | undefined {
+  const file = values['signer-template'];
+  if (!file) return values['signer-instructions'] ? DEFAULT_SIGNER_TEMPLATE : undefined;
+  return parseSignerTemplate(JSON.parse(readFileSync(path.resolve(process.cwd(), file), 'utf-8')));
+}
+
+function loadPrivacyList(file: string): PrivacyList {
+  const privacyPath = path.resolve(process.cwd(), file);
+  return parsePrivacyList(JSON.parse(readFileSync(privacyPath, 'utf-8')));
+}
+
+function parseReadsMode(value: string | undefined): ReadsMode | undefined {
+  if (value === undefined) return undefined;
+  if (!(READS_MODES as readonly string[]).includes(value)) {
+    throw new Error(`--include-reads must be one of: ${READS_MODES.join(', ')}`);
+  }
+  return value as ReadsMode;
+}
+
+function parseUnknownMode(value: string | undefined): UnknownMode {
+  if (value === undefined) return 'placeholder';
+  if (!(UNKNOWN_MODES as readonly string[]).includes(value)) {
+    throw new Error(`--unknowns must be one of: ${UNKNOWN_MODES.join(', ')}`);
+  }
+  return value as UnknownMode;
+}
+
+function parseDiffMode(value: string | undefined): DiffMode {
+  if (value === undefined) return 'separate';
+  if (!(DIFF_MODES as readonly string[]).includes(value)) {
+    throw new Error(`--diff-mode must be one of: ${DIFF_MODES.join(', ')}`);
+  }
+  return value as DiffMode;
+}
+
+function parseOutputFormat(value: string | undefined): OutputFormat {
+  if (value === undefined) return 'json';
+  if (!(OUTPUT_FORMATS as readonly string[]).includes(value)) {
+    throw new Error(`--format must be one of: ${OUTPUT_FORMATS.join(', ')}`);
+  }
+  return value as OutputFormat;
+}
+
+function loadScopeFilter(values: { only?: string; exclude?: string }): ScopeFilter | undefined {
+  if (!values.only && !values.exclude) return undefined;
+  return {
+    only: values.only ? parseAddressList(values.only, '--only') : undefined,
+    exclude: values.exclude ? parseAddressList(values.exclude, '--exclude') : undefined,
+  };
+}
+
+type OutputOptions = {
+  format: OutputFormat;
+  // Bytes above which the JSON file is split into an index and per-contract parts
+  maxOutputSize?: number;
+  scope?: ScopeFilter;
+  // Reader the file is tailored for; sections for other readers are left out
+  audience?: ReportAudience;
+  privacy?: PrivacyList;
+  attest?: AttestationSigner;
+  history?: HistoryEntry[];
+  // Task ledger file the written validation is recorded in
+  ledger?: string;
+  // Signing manifest the written files are added to
+  manifest?: string;
+  signerTemplate?: SignerTemplate;
+  // Overrides the task documents; any other, missing, or different override refuses the report
+  expectedOverrides?: ExpectedOverride[];
+  // Independent RPC the report's chain state is read from again; any disagreement refuses it
+  compareRpc?: string;
+  // List the approveHash hashes of the target's owner Safes under nestedHashes
+  nestedHashes?: boolean;
+  // Write an ERC-7730 clear-signing descriptor for the SafeTx next to the output file
+  clearSigning?: boolean;
+  // Solidity file the post-state assertions are written to
+  foundryAssertions?: string;
+  // WASM policy checks run on the report; an error finding refuses it
+  policyPlugins?: PolicyPlugin[];
+  // Static viewer a link carrying the file is printed for
+  viewerUrl?: string;
+  // IPFS CID of the pinned file, referred to by the link instead of embedding the file
+  viewerCid?: string;
+  // Slack or Discord webhook the completion summary is posted to
+  notifyWebhook?: string;
+  // Link to the file in the summary, instead of the path it was written to
+  notifyLink?: string;
+  // Destinations written after the output file, or instead of stdout without one
+  sinks: OutputSink[];
+};
+
+function loadHistoryDir(dir: string, outFlag: string | undefined): HistoryEntry[] {
+  // The task's other validation files change the same contracts, so they are not history
+  const taskDir = outFlag ? owningTaskDir(path.resolve(process.cwd(), outFlag)) : null;
+  const { entries, skipped } = loadHistory(path.resolve(process.cwd(), dir), taskDir ?? undefined);
+  console.log(`🗂️  Loaded ${entries.length} earlier validation file(s) from ${dir}`);
+  if (skipped > 0) {
+    console.warn(`⚠️  Skipped ${skipped} JSON file(s) that are not validation files`);
+  }
+  return entries;
+}
+
+function loadAttestationSigner(values: {
+  attest?: boolean;
+  'attest-ledger-id'?: string;
+  'attest-keystore'?: string;
+}): AttestationSigner | undefined {
+  const keystoreFlag = values['attest-keystore'];
+  if (keystoreFlag) {
+    const password = process.env.ATTEST_KEYSTORE_PASSWORD;
+    if (password === undefined) {
+      throw new Error('ATTEST_KEYSTORE_PASSWORD must be set when using --attest-keystore');
+    }
+    const keystore = readFileSync(path.resolve(process.cwd(), keystoreFlag), 'utf-8');
+    return { kind: 'privateKey', privateKey: decryptKeystore(keystore, password) };
+  }
+  if (!values.attest) return undefined;
+
+  const ledgerAccount = Number.parseInt(values['attest-ledger-id'] ?? '0', 10);
+  if (!Number.isInteger(ledgerAccount) || ledgerAccount < 0) {
+    throw new Error('--attest-ledger-id must be a non-negative integer');
+  }
+  return { kind: 'ledger', ledgerAccount };
+}
+
+async function writeOutput(
+  result: TaskConfig,
+  outFlag: string | undefined,
+  {
+    format,
+    maxOutputSize,
+    scope,
+    audience,
+    privacy,
+    attest,
+    history,
+    ledger,
+    manifest,
+    signerTemplate,
+    expectedOverrides,
+    compareRpc,
+    nestedHashes,
+    clearSigning,
+    foundryAssertions,
+    policyPlugins,
+    viewerUrl,
+    viewerCid,
+    notifyWebhook,
+    notifyLink,
+    sinks,
+  }: OutputOptions
+): Promise<void> {
+  if (compareRpc) {
+    console.log("🔁 Reading the report's chain state from the second RPC...");
+    const agreement = await compareRpcs(result, http(result.rpcUrl), http(compareRpc));
+    checkRpcAgreement(agreement);
+    console.log(`✅ ${describeRpcAgreement(agreement)}`);
+  }
+  if (expectedOverrides) {
+    const discrepancies = checkExpectedOverrides(result.stateOverrides, expectedOverrides);
+    if (discrepancies.length > 0) {
+      throw new PolicyViolationError(
+        `State overrides do not match the expected overrides:\n${discrepancies.map(d => `  - ${describeOverrideDiscrepancy(d)}`).join('\n')}`
+      );
+    }
+    console.log(`✅ State overrides match the ${expectedOverrides.length} expected override(s)`);
+  }
+  let nested: NestedHashes | undefined;
+  const found: ReportWarning[] = [];
+  if (nestedHashes) {
+    const { address, safeTxHash } = result.expectedDomainAndMessageHashes;
+    nested = await deriveNestedHashes(http(result.rpcUrl), address, safeTxHash!);
+    const owners = Object.entries(nested);
+    for (const [owner, hashes] of owners) console.log(`🪆 ${describeNestedHash(owner, hashes)}`);
+    if (owners.length === 0) {
+      const message = `No owner of ${address} is a Safe; the file has no nestedHashes`;
+      console.warn(`⚠️  ${message}`);
+      found.push(reportWarning('NO_NESTED_SAFES', message, { address }));
+      nested = undefined;
+    }
+  }
+  let stamped: TaskConfig = appendWarnings(
+    {
+      ...result,
+      ...(nested ? { nestedHashes: nested } : {}),
+      generatedBy: getBuildInfo(),
+    },
+    found
+  );
+  if (policyPlugins && policyPlugins.length > 0) {
+    const findings = checkPolicyPlugins(policyPlugins, stamped);
+    for (const warning of findings) console.warn(`⚠️  ${warning.message}`);
+    console.log(`✅ ${policyPlugins.length} policy plugin(s) found no errors`);
+    stamped = appendWarnings(stamped, findings);
+  }
+  let reported = stamped;
+  if (scope) {
+    reported = applyReportScope(stamped, scope);
+    const summary = describeFilteredCounts(reported.scope!);
+    console.log(`🔎 ${summary ?? 'Nothing was outside the report scope'}`);
+  }
+  if (history) {
+    const recentlyModified = findRecentModifications(reported, history);
+    const modified = recentlyModified.map(modification =>
+      reportWarning('RECENTLY_MODIFIED', describeRecentModification(modification), {
+        address: modification.address,
+        file: modification.file,
+      })
+    );
+    for (const warning of modified) console.warn(`⚠️  ${warning.message}`);
+    if (recentlyModified.length > 0) {
+      reported = appendWarnings({ ...reported, recentlyModified }, modified);
+    }
+  }
+  if (signerTemplate) {
+    const task = outFlag ? ledgerTaskName(path.resolve(process.cwd(), outFlag)) : undefined;
+    const signerInstructions = buildSignerInstructions(reported, signerTemplate, task);
+    const devices = signerInstructions.devices.map(d => d.device).join(', ');
+    console.log(`📋 Added signer instructions for ${devices || 'no devices'}`);
+    reported = { ...reported, signerInstructions };
+  }
+  // From the report before redaction, since the checks need the real values
+  if (foundryAssertions) {
+    const task = outFlag ? ledgerTaskName(path.resolve(process.cwd(), outFlag)) : undefined;
+    mkdirSync(path.dirname(foundryAssertions), { recursive: true });
+    writeFileSync(foundryAssertions, buildFoundryAssertions(reported, task));
+    console.log(`🧪 Wrote Foundry post-state assertions to: ${foundryAssertions}`);
+  }
+  if (audience) {
+    const omitted = omittedSections(reported, audience);
+    reported = applyReportAudience(reported, audience);
+    const left = omitted.length > 0 ? `; left out ${omitted.join(', ')}` : '';
+    console.log(`👥 Tailored the report for the ${audience}${left}`);
+  }
+  let finalResult: object = reported;
+  // The full file is what gets attested and recorded; the copy only commits to what it hides,
+  // under a salt kept in the full file
+  let redacted: Redacted<object> | undefined;
+  if (privacy) {
+    const redactionSalt = newRedactionSalt();
+    redacted = redactTaskConfig(reported, privacy, redactionSalt);
+    finalResult = { ...reported, redactionSalt };
+  }
+  if (attest) {
+    console.log('✍️  Signing attestation...');
+    const attestation = await signAttestation(finalResult, attest);
+    console.log(`✅ Attested by ${attestation.signer}`);
+    finalResult = { ...finalResult, attestation };
+  }
+  // Built before anything is written, so a file too large for a link fails early
+  const viewerLink = viewerUrl ? buildViewerLink(viewerUrl, finalResult, viewerCid) : undefined;
   if (outFlag) {
     const outPath = path.resolve(process.cwd(), outFlag);
     const outDir = path.dirname(outPath);
+    // Built first, so a task that does not sign a SafeTx fails before anything is written
+    const descriptor = clearSigning
+      ? buildClearSigningDescriptor({
+          chainId: stamped.chainId!,
+          safe: stamped.expectedDomainAndMessageHashes.address,
+          domainHash: stamped.expectedDomainAndMessageHashes.domainHash,
+          intent: `Sign ${ledgerTaskName(outPath)}`,
+        })
+      : undefined;
     mkdirSync(outDir, { recursive: true });
-    writeFileSync(outPath, output + '\n');
-    console.log(`Wrote validation JSON to: ${outPath}`);
-  } else {
-    console.log(output);
+    const written = [outPath];
+    // JSON is streamed so files with large traces or calldata never exist as a single string
+    if (format === 'json') {
+      await writeJsonFile(outPath, finalResult);
+      const size = statSync(outPath).size;
+      if (maxOutputSize !== undefined && size > maxOutputSize) {
+        const split = splitValidationFile(finalResult, outPath);
+        const partsDir = path.join(outDir, split.index.split.dir);
+        // Parts of an earlier run would otherwise be left next to the ones the index names
+        rmSync(partsDir, { recursive: true, force: true });
+        mkdirSync(partsDir, { recursive: true });
+        for (const part of split.parts) {
+          await writeJsonFile(path.join(partsDir, part.file), part.content);
+          written.push(path.join(partsDir, part.file));
+        }
+        await writeJsonFile(outPath, split.index);
+        console.log(
+          `✂️  ${size} bytes is over --max-output-size; split into an index and ${split.parts.length} per-contract file(s) in ${partsDir}`
+        );
+      }
+    } else {
+      writeFileSync(outPath, serializeResult(finalResult, format) + '\n');
+    }
+    console.log(`Wrote validation ${format.toUpperCase()} to: ${outPath}`);
+    console.log(`🔑 Content hash (canonical JSON): ${canonicalHash(finalResult)}`);
+    if (redacted) {
+      const redactedPath = redactedCopyPath(outPath);
+      writeFileSync(redactedPath, serializeResult(redacted, format) + '\n');
+      const count = redacted.redactions.length;
+      console.log(`🔒 Wrote a shareable copy with ${count} value(s) redacted to: ${redactedPath}`);
+      console.log('   It cannot be signed; keep the full file to check it against later');
+    }
+    if (descriptor) {
+      const descriptorPath = clearSigningPath(outPath);
+      writeFileSync(descriptorPath, JSON.stringify(descriptor, null, 2) + '\n');
+      console.log(`🏷️  Wrote ERC-7730 clear-signing descriptor to: ${descriptorPath}`);
+      written.push(descriptorPath);
+    }
+    if (manifest) {
+      const manifestDir = path.dirname(manifest);
+      if (foundryAssertions?.startsWith(manifestDir + path.sep)) {
+        written.push(foundryAssertions);
+      } else if (foundryAssertions) {
+        console.log(`📦 ${foundryAssertions} is outside ${manifestDir}; not in the manifest`);
+      }
+      const { simulatedAt, chainId } = stamped;
+      const pin = {
+        chainId,
+        blockNumber: simulatedAt?.blockNumber,
+        blockHash: simulatedAt?.blockHash,
+      };
+      await recordManifestArtifacts(
+        manifest,
+        written.map((file, i) => (i === 0 ? { file, ...pin } : { file })),
+        {
+          buildInfo: getBuildInfo(),
+          rpcUrls: [stamped.rpcUrl, ...(compareRpc ? [compareRpc] : [])],
+        }
+      );
+      console.log(`📦 Added ${written.length} file(s) to the signing manifest ${manifest}`);
+    }
+    if (ledger) {
+      const task = ledgerTaskName(outPath);
+      await recordValidation(ledger, {
+        task,
+        outFile: outPath,
+        config: stamped,
+        artifact: finalResult,
+      });
+      console.log(`📒 Recorded in task ledger ${ledger} under ${task}`);
+    }
+    if (notifyWebhook) {
+      try {
+        await postNotification(notifyWebhook, {
+          event: 'generated',
+          task: ledgerTaskName(outPath),
+          config: finalResult as TaskConfig,
+          artifact: notifyLink ?? path.relative(process.cwd(), outPath),
+        });
+        console.log('📣 Posted the summary to the webhook');
+      } catch (err) {
+        console.warn(`⚠️  ${err instanceof Error ? err.message : String(err)}`);
+      }
+    }
+  } else if (sinks.length === 0) {
+    console.log(serializeResult(finalResult, format));
   }
+  if (sinks.length > 0) {
+    const deliveries = await deliverToSinks(sinks, serializeResult(finalResult, format), {
+      format,
+    });
+    const failed = deliveries.filter(d => d.error !== undefined);
+    for (const d of deliveries.filter(d => d.error === undefined)) {
+      if (d.sink.kind !== 'stdout') console.log(`📤 Wrote the file to ${describeSink(d.sink)}`);
+    }
+    if (failed.length > 0) {
+      throw new Error(
+        `${failed.length} of ${deliveries.length} sink(s) failed: ${failed.map(d => `${describeSink(d.sink)} (${d.error} after ${d.attempts} attempt(s))`).join(', ')}`
+      );
+    }
+  }
+  if (viewerLink) {
+    const hash = canonicalHash(finalResult);
+    console.log(`🔗 Viewer link; the hash the viewer shows must be ${hash}:`);
+    console.log(viewerLink);
+  }
+}
 
-  // Note: Signing by the task creator should be done separately after all validation files are created
+const SOURCE_NAMES = {
+  trace: 'prestateTracer output',
+  'simulate-v1': 'eth_simulateV1 payload',
+  tenderly: 'Tenderly simulation export',
+  permit: 'EIP-2612 permit',
+} as const;
+
+// A storage slot given as a decimal number or hex
+function parseSlot(flag: string, value: string): Hex {
+  if (/^0x[0-9a-fA-F]{1,64}$/.test(value) || /^\d+$/.test(value)) {
+    return numberToHex(BigInt(value), { size: 32 });
+  }
+  throw new Error(`${flag} must be a slot number or hex value`);
+}
+
+// Typed-data JSON is unwieldy on the command line, so --data-to-sign @file reads it from a file
+function readDataToSign(value: string): string {
+  if (!value.startsWith('@')) return value;
+  return readFileSync(path.resolve(process.cwd(), value.slice(1)), 'utf-8');
+}
+
+async function generateWithoutForge(
+  rpcUrl: string,
+  source: { kind: 'trace' | 'simulate-v1' | 'tenderly' | 'permit'; file: string },
+  values: {
+    'target-safe'?: string;
+    'data-to-sign'?: string;
+    'allowance-slot'?: string;
+    'nonces-slot'?: string;
+    'strict-hash-format'?: boolean;
+    unknowns?: string;
+    preimages?: string;
+    'preimage-store'?: string;
+    'metadata-cache'?: string;
+    'cache-ttl'?: string;
+    refresh?: boolean;
+  },
+  ledgerIdFlag: string | undefined,
+  outFlag: string | undefined,
+  outputOptions: OutputOptions
+): Promise<void> {
+  const targetSafe = values['target-safe'];
+  const dataToSign = values['data-to-sign'];
+
+  if (source.kind === 'permit' ? !rpcUrl : !rpcUrl || !targetSafe || !dataToSign) {
+    console.error(
+      source.kind === 'permit'
+        ? '--from-permit requires --rpc-url; the permit is the data to sign.'
+        : `--from-${source.kind} requires --rpc-url, --target-safe, and --data-to-sign.`
+    );
+    printUsage();
+    process.exitCode = 1;
+    return;
+  }
+  if (source.kind !== 'permit' && (values['allowance-slot'] || values['nonces-slot'])) {
+    console.error('--allowance-slot and --nonces-slot need --from-permit');
+    process.exitCode = 1;
+    return;
+  }
+
+  const ledgerId = ledgerIdFlag ? Number.parseInt(ledgerIdFlag, 10) : 0;
+  if (!Number.isInteger(ledgerId) || ledgerId < 0) {
+    console.error('--ledger-id must be a non-negative integer');
+    process.exitCode = 1;
+    return;
+  }
+
+  const sourcePath = path.resolve(process.cwd(), source.file);
+  const sourceName = SOURCE_NAMES[source.kind];
+  console.log(`📥 Reading ${sourceName} from ${sourcePath}`);
+  const input = JSON.parse(readFileSync(sourcePath, 'utf-8'));
+
+  const sdc = new StateDiffClient(ledgerId, undefined, {
+    strictHashFormat: values['strict-hash-format'],
+    unknowns: parseUnknownMode(values.unknowns),
+    metadataCache: loadMetadataCacheOptions(values),
+    preimages: loadPreimages(values),
+    preimageStore: preimageStoreDir(values),
+  });
+  const fromSource = () => {
+    if (source.kind === 'permit') {
+      return sdc.fromPermit(rpcUrl, input, {
+        allowanceSlot: values['allowance-slot']
+          ? parseSlot('--allowance-slot', values['allowance-slot'])
+          : DEFAULT_ALLOWANCE_SLOT,
+        noncesSlot: values['nonces-slot']
+          ? parseSlot('--nonces-slot', values['nonces-slot'])
+          : undefined,
+      });
+    }
+    const opts = { targetSafe: targetSafe!, dataToSign: readDataToSign(dataToSign!) };
+    return source.kind === 'trace'
+      ? sdc.fromPrestateTrace(rpcUrl, input, opts)
+      : source.kind === 'tenderly'
+        ? sdc.fromTenderlyExport(rpcUrl, input, opts)
+        : sdc.fromSimulateV1(rpcUrl, parseSimulateV1Payload(input), opts);
+  };
+  const { result, warnings } = await fromSource();
+  for (const warning of warnings) {
+    console.warn(`⚠️  ${warning.message}`);
+  }
+
+  const { identity } = await generateDeviceCertificate(undefined);
+  if (source.kind !== 'permit') {
+    console.log('⚠️  cmd is a placeholder; replace it with the forge command to run.');
+  }
+  await writeOutput(
+    { ...result, taskOriginConfig: { taskCreator: { commonName: identity } } },
+    outFlag,
+    outputOptions
+  );
 }
 
-main().catch(err => {
-  console.error(err);
-  process.exitCode = 1;
-});
+main()
+  .catch(err => {
+    console.error(err);
+    process.exitCode = exitCodeFor(err);
+  })
+  .finally(() => flushTelemetry());
@@ -0,0 +1,1068 @@
+import { existsSync, readFileSync, writeFileSync } from 'fs';
+import path from 'path';
+import { createInterface } from 'readline';
+import { parseArgs } from 'node:util';
+import { Hex, http, isAddress, isHex } from 'viem';
+import {
+  BLOB_KIND_FIELDS,
+  blobFromFile,
+  decodeBlob,
+  formatDecodedBlob,
+  isBlobKind,
+} from '@/lib/blob-decode';
+import { getValidationSummary, parseFromString } from '@/lib/parser';
+import { readValidationFile } from '@/lib/artifact-split';
+import { renderSuperchainOpsValidation } from '@/lib/superchain-ops';
+import { findTaskValidationFiles, formatBatchSummary, runBounded, taskWorkdir } from '@/lib/batch';
+import { StateDiffClient } from '@/lib/state-diff';
+import { applyReportScope } from '@/lib/report-scope';
+import { applyReportAudience } from '@/lib/report-audience';
+import { writeJsonFile } from '@/lib/json-stream';
+import { exitCodeFor } from '@/lib/errors';
+import { sandboxFromEnv } from '@/lib/sandbox';
+import { allowedCommandsFromEnv } from '@/lib/command-policy';
+import { loadPreimageDatabase, recordedPreimages } from '@/lib/preimage-database';
+import {
+  exportPreimageStore,
+  importPreimageStore,
+  PREIMAGE_STORE_ENV,
+} from '@/lib/preimage-store';
+import { DEFAULT_METADATA_CACHE_TTL_HOURS, METADATA_CACHE_ENV } from '@/lib/metadata-cache';
+import { enableProgress } from '@/lib/progress';
+import { readLedger, summarizeLedger, verifyLedger } from '@/lib/task-ledger';
+import { runSelftest } from '@/lib/selftest';
+import { embeddedConfigHash, formatBuildInfo, getBuildInfo } from '@/lib/build-info';
+import { recordManifestArtifacts, verifyManifest } from '@/lib/signing-manifest';
+import {
+  describeCeremony,
+  finalizeCeremony,
+  initCeremony,
+  pendingParticipants,
+  readCeremonyFile,
+  submitCeremonySignature,
+  updateCeremonyFile,
+  writeCeremonyFile,
+} from '@/lib/ceremony-state';
+import { parseAddressList } from '@/lib/report-scope';
+import { CONFIRM_PROTOCOLS, reviewItems, runConfirmProtocol } from '@/lib/confirm-protocol';
+import { canonicalHash, verifyCanonicalHash } from '@/lib/canonical-json';
+import {
+  checkSafeSignatures,
+  describeSignatureCheck,
+  parseCollectedSignatures,
+} from '@/lib/safe-signature-check';
+import {
+  describeOverrideEvaluation,
+  evaluateOverrides,
+  overridesFromExpected,
+  overridesFromValidationFile,
+  ProposedOverride,
+} from '@/lib/override-evaluation';
+import { parseExpectedOverrides } from '@/lib/expected-overrides';
+import { labelsToOverlay, parseLabelCsv } from '@/lib/label-import';
+import { buildDiffProof, parseDiffProofFile, verifyDiffProof } from '@/lib/diff-commitment';
+import { serializeResult } from '@/lib/serialization';
+import { DEFAULT_REPORT_LOCALE, resolveLocale } from '@/lib/locale-format';
+import contractsCfg from '@/lib/config/contracts.json';
+import type { TaskConfig } from '@/lib/types';
+
+const DEFAULT_BATCH_CONCURRENCY = 4;
+
+// Validation files built from a trace have no forge command to re-run
+const PLACEHOLDER_CMD = '<<ForgeCommand>>';
+
+function printUsage(): void {
+  const msg = `
+Helpers for stateDiff.json encodings and validation files.
+
+Usage:
+  tsx scripts/stateDiff.ts decode --kind <overrides|statediff|preimages> <0x...>
+  tsx scripts/stateDiff.ts decode --kind <overrides|statediff|preimages> --file <FILE>
+  tsx scripts/stateDiff.ts export --format superchain-ops --file <FILE> [--file <FILE>...] [--out <FILE>] [--locale <TAG>]
+  tsx scripts/stateDiff.ts batch [--concurrency <N>] [--no-progress] <TASK_DIR> [<TASK_DIR>...]
+  tsx scripts/stateDiff.ts ledger list --ledger <FILE>
+  tsx scripts/stateDiff.ts ledger show <TASK> --ledger <FILE>
+  tsx scripts/stateDiff.ts selftest
+  tsx scripts/stateDiff.ts hash --file <FILE> [--expect <HASH>]
+  tsx scripts/stateDiff.ts explain --address <ADDR> --slot <SLOT> (--rpc-url <URL> | --chain-id <ID>)
+  tsx scripts/stateDiff.ts preimages export [--preimage-store <DIR>] [--out <FILE>]
+  tsx scripts/stateDiff.ts preimages import --file <FILE> [--preimage-store <DIR>]
+  tsx scripts/stateDiff.ts signatures --file <FILE> --signatures <FILE> --rpc-url <URL> [--required <N>]
+  tsx scripts/stateDiff.ts overrides check (--file <FILE> | --expected-overrides <FILE>) --rpc-url <URL>
+  tsx scripts/stateDiff.ts manifest verify --manifest <FILE>
+  tsx scripts/stateDiff.ts manifest add --manifest <FILE> <FILE> [<FILE>...]
+  tsx scripts/stateDiff.ts ceremony init --ceremony <FILE> --file <FILE> --participants <ADDRS> --threshold <N>
+  tsx scripts/stateDiff.ts ceremony submit-signature --ceremony <FILE> --signature <HEX>
+  tsx scripts/stateDiff.ts ceremony status --ceremony <FILE>
+  tsx scripts/stateDiff.ts ceremony finalize --ceremony <FILE> [--rpc-url <URL>] [--out <FILE>]
+  tsx scripts/stateDiff.ts review --file <FILE> [--confirm-protocol jsonl]
+  tsx scripts/stateDiff.ts commitment build --file <FILE> [--out <FILE>]
+  tsx scripts/stateDiff.ts commitment verify --proof <FILE> [--file <FILE>]
+  tsx scripts/stateDiff.ts config import-labels <CSV> [--chain-id <ID>] [--format yaml|json] [--out <FILE>]
+
+decode flags:
+  --kind, -k   Blob type to decode
+  --file, -f   Read the blob from a file instead of the command line. The file may hold the raw
+               hex or be a stateDiff.json, in which case the field for --kind is used
+
+export flags:
+  --format     Output format; only superchain-ops (VALIDATION.md) is supported
+  --file, -f   Validation JSON file, repeated once per signer; hashes are listed under each
+               file's name. State changes are listed once when the files agree, and
+               under each file's name when they differ
+  --out, -o    Write the Markdown to a file instead of stdout
+  --locale     BCP 47 locale for amounts, dates, and durations in the text, e.g. de-DE
+               (default: ${DEFAULT_REPORT_LOCALE}). Hashes, words, and wei are never localized
+
+batch flags:
+  --concurrency  Validation files processed at once (default: ${DEFAULT_BATCH_CONCURRENCY}). Forge
+                 runs sharing a workdir still run one at a time
+  --no-progress  Do not draw the progress line on stderr (it is only drawn on a terminal)
+
+ledger flags:
+  --ledger     Task ledger written by genValidationFile.ts and genTaskOriginSig.ts --ledger.
+               list prints one line per task; show prints every entry recorded for <TASK>
+
+hash flags:
+  --file, -f   JSON file to hash: keccak256 of its RFC 8785 canonical JSON, the form the tool
+               signs and records in ledgers. Indentation and key order do not change it
+  --expect     Hash shared by the facilitator; exits non-zero when the file does not match
+
+explain flags:
+  --address    Contract whose storage holds the slot
+  --slot       Storage slot, as hex
+  --rpc-url    Read the slot's current value and match unconfigured contracts against the
+               built-in patterns; the chain comes from the endpoint
+  --chain-id   Chain to look the contract up on in contracts.json, when there is no --rpc-url
+  --preimages  Mapping preimages ({"0x<slot>": {"parent": "0x...", "key": "0x..."}}) to derive
+               the slot from, e.g. an indexer's or a validation file's extraPreimages
+  --metadata-cache <dir>
+               Reuse what earlier runs found out about unconfigured contracts; defaults to
+               ${METADATA_CACHE_ENV}
+  --preimage-store <dir>
+               Also derive the slot from preimages learned by earlier runs; defaults to
+               ${PREIMAGE_STORE_ENV}
+  --json       Print the explanation as JSON
+
+preimages flags:
+  --preimage-store <dir>
+               Store genValidationFile.ts --preimage-store learns into; defaults to
+               ${PREIMAGE_STORE_ENV}. export writes every chain's learned preimages as one
+               file, and import merges such a file from another facilitator into the store.
+               Every imported preimage is checked to hash to its slot
+  --out, -o    Write the export to a file instead of stdout
+  --file, -f   Export to import
+
+signatures flags:
+  --file, -f   Validation file of the transaction the signatures are for
+  --signatures Collected signatures: a JSON array of hex strings, or hex separated by whitespace.
+               They are sorted by signer and checked with the Safe's checkNSignatures over
+               eth_call; the packed signatures for execTransaction are printed (or written to
+               --out) when the Safe accepts them
+  --rpc-url    RPC endpoint of the Safe's chain
+  --required   Signatures to require (default: the Safe's threshold)
+
+overrides check flags:
+  --file, -f   Validation file whose stateOverrides are checked
+  --expected-overrides <file>
+               expected-overrides.yaml to check, e.g. before the validation file exists. With
+               --file, both are checked and must give every slot the same value
+  --rpc-url    RPC endpoint of the task's chain; slots are read at its latest block
+  --json       Print the evaluation as JSON
+  Each override is a no-op (the slot already holds the value), a change, or a conflict (two
+  values for one slot, or a simulated before value that is not the override's). Exits non-zero
+  when any override conflicts
+
+manifest flags:
+  --manifest   manifest.json written by genValidationFile.ts --manifest. verify hashes every
+               listed file again and exits non-zero when one changed or is missing; add lists
+               more files, such as collected signatures, that sit in the manifest's directory
+
+ceremony flags:
+  --ceremony   Shared JSON file tracking an M-of-N signing ceremony; commit it with the task
+  --file, -f   Validation file whose Safe and hashes are signed (init)
+  --participants <addrs>
+               Comma-separated signer addresses that may submit a signature (init)
+  --threshold  Signatures needed before the ceremony can be finalized (init)
+  --signature  A participant's 65-byte signature of the safeTxHash (submit-signature); it is
+               recovered and refused unless it comes from a participant who has not signed yet
+  --rpc-url    With finalize, check the packed signatures with the Safe's checkNSignatures first
+  --out, -o    With finalize, also write the packed signatures to a file
+  A ceremony is collecting until the threshold is reached, then ready, and finalized once the
+  signatures are packed, sorted by signer, for execTransaction
+
+review flags:
+  --file, -f   Validation file to step through: the hashes, overrides, state and balance
+               changes, and warnings, in the order the validation page shows them
+  --confirm-protocol jsonl
+               For GUIs wrapping the tool: write each item to stdout as a JSON line and wait
+               for {"ack": true} on stdin before the next. {"ack": false} or stdin closing
+               rejects the review and exits non-zero; the last line is done or rejected.
+               Without it the items are printed as JSON lines without waiting
+
+commitment flags:
+  --file, -f   Validation file. build commits to its state changes; verify checks that the
+               executed transaction's file changed exactly the committed slots
+  --out, -o    With build, write the proof file to a file instead of stdout
+  --proof      Proof file written by build. verify checks every leaf's proof against the
+               root and the commitment's encoding, and exits non-zero on any mismatch
+  The commitment is abi.encode(chainId, safeTxHash, root, leafCount), with a Merkle root over
+  (address, slot, before, after) leaves that OpenZeppelin's MerkleProof can check on-chain
+
+config import-labels flags:
+  <CSV>        Etherscan name-tag export (address and name tag columns) or Dune address book
+               (blockchain, address, and name columns); the format is told from the header
+  --chain-id   Chain of an Etherscan export, which does not name it; narrows a Dune export
+  --format     Overlay format: yaml (default) or json
+  --out, -o    Write the overlay to a file instead of stdout
+  Writes a contracts.json overlay naming each address. Addresses contracts.json already
+  configures are left out, and an address labeled twice keeps its first label
+
+selftest:
+  Decodes the embedded stateDiff.json fixtures (one per chain in contracts.json), builds their
+  reports offline, and checks them against the expected results. Run it before a ceremony to
+  confirm this installation works; it exits non-zero when any fixture fails
+
+  --help, -h   Show this help message
+
+Examples:
+  tsx scripts/stateDiff.ts decode --kind statediff --file active/evm/stateDiff.json
+  tsx scripts/stateDiff.ts decode --kind preimages 0x0000...
+  tsx scripts/stateDiff.ts export --format superchain-ops \\
+    --file validations/base-sc.json --file validations/base-nested.json --out VALIDATION.md
+  tsx scripts/stateDiff.ts batch active/evm/tasks/2025-*/
+  tsx scripts/stateDiff.ts ledger show 2025-06-04-upgrade-system-config --ledger ~/tasks.jsonl
+  tsx scripts/stateDiff.ts explain --rpc-url https://mainnet.example \\
+    --address 0x73a79Fab69143498Ed3712e519A88a918e1f4072 --slot 0x68
+`;
+  console.log(msg);
+}
+
+type CliValues = {
+  kind?: string;
+  file?: string[];
+  format?: string;
+  out?: string;
+  concurrency?: string;
+  ledger?: string;
+  expect?: string;
+  'no-progress'?: boolean;
+  address?: string;
+  slot?: string;
+  'rpc-url'?: string;
+  'chain-id'?: string;
+  preimages?: string;
+  'preimage-store'?: string;
+  'metadata-cache'?: string;
+  json?: boolean;
+  signatures?: string;
+  required?: string;
+  'expected-overrides'?: string;
+  manifest?: string;
+  ceremony?: string;
+  participants?: string;
+  threshold?: string;
+  signature?: string;
+  'confirm-protocol'?: string;
+  proof?: string;
+  locale?: string;
+};
+
+function runDecode(values: CliValues, blobArg: string | undefined): void {
+  const kind = values.kind;
+  if (!isBlobKind(kind)) {
+    console.error(`--kind must be one of: ${Object.keys(BLOB_KIND_FIELDS).join(', ')}`);
+    process.exitCode = 1;
+    return;
+  }
+  const files = values.file ?? [];
+  if (Boolean(blobArg) === files.length > 0 || files.length > 1) {
+    console.error('Provide the blob either as an argument or with one --file (exactly one).');
+    process.exitCode = 1;
+    return;
+  }
+
+  const encoded =
+    files.length > 0
+      ? blobFromFile(kind, readFileSync(path.resolve(process.cwd(), files[0]), 'utf-8'), files[0])
+      : blobArg!.trim();
+  console.log(formatDecodedBlob(decodeBlob(kind, encoded)));
+}
+
+async function runExport(values: CliValues): Promise<void> {
+  if (values.format !== 'superchain-ops') {
+    console.error('--format must be: superchain-ops');
+    process.exitCode = 1;
+    return;
+  }
+  const files = values.file ?? [];
+  if (files.length === 0) {
+    console.error('export needs at least one --file');
+    process.exitCode = 1;
+    return;
+  }
+
+  const locale = values.locale ? resolveLocale(values.locale) : undefined;
+  const signers = await Promise.all(
+    files.map(async file => {
+      const filePath = path.resolve(process.cwd(), file);
+      const parsed = parseFromString(await readValidationFile(filePath));
+      if (!('config' in parsed)) {
+        throw new Error(`${filePath}: ${getValidationSummary(parsed.result)}`);
+      }
+      return { label: path.basename(file, '.json'), config: parsed.config };
+    })
+  );
+
+  const markdown = renderSuperchainOpsValidation(signers, { locale });
+  if (values.out) {
+    const outPath = path.resolve(process.cwd(), values.out);
+    writeFileSync(outPath, markdown);
+    console.log(`Wrote ${outPath}`);
+  } else {
+    process.stdout.write(markdown);
+  }
+}
+
+/**
+ * Re-runs the forge command recorded in a validation file and rewrites the file with the new
+ * result. Fields that do not come from the simulation are carried over.
+ */
+async function regenerateValidationFile(file: string, workdir: string): Promise<void> {
+  const parsed = parseFromString(readFileSync(file, 'utf-8'));
+  if (!('config' in parsed)) {
+    throw new Error(getValidationSummary(parsed.result));
+  }
+  const cfg = parsed.config;
+  if (cfg.cmd.trim() === PLACEHOLDER_CMD) {
+    throw new Error('cmd is a placeholder; generate this file with genValidationFile');
+  }
+
+  const sdc = new StateDiffClient(cfg.ledgerId, workdir, {
+    sandbox: sandboxFromEnv(),
+    allowedCommands: allowedCommandsFromEnv(),
+  });
+  const forgeCmd = cfg.cmd.trim().split(/\s+/);
+  const { result, encoded, warnings } = await sdc.simulate(cfg.rpcUrl, forgeCmd, workdir, {
+    preimages: recordedPreimages(cfg),
+  });
+  for (const warning of warnings) {
+    console.warn(`⚠️  ${path.relative(process.cwd(), file)}: ${warning.message}`);
+  }
+
+  let regenerated: TaskConfig = {
+    ...result,
+    ...(cfg.safeNonce !== undefined && result.safeNonce === undefined
+      ? { safeNonce: cfg.safeNonce }
+      : {}),
+    ...(cfg.l2GasEstimation ? { l2GasEstimation: cfg.l2GasEstimation } : {}),
+    ...(cfg.prestateFrom ? { prestateFrom: cfg.prestateFrom } : {}),
+    ...(cfg.simulationEnv ? { simulationEnv: cfg.simulationEnv } : {}),
+    ...(cfg.taskOriginConfig ? { taskOriginConfig: cfg.taskOriginConfig } : {}),
+    // Files archived with --include-raw keep the fresh blobs
+    ...(cfg.raw ? { raw: encoded } : {}),
+    generatedBy: getBuildInfo(),
+  };
+  if (cfg.scope) {
+    regenerated = applyReportScope(regenerated, cfg.scope);
+  }
+  if (cfg.audience) {
+    regenerated = applyReportAudience(regenerated, cfg.audience);
+  }
+  await writeJsonFile(file, regenerated);
+}
+
+async function runBatch(values: CliValues, taskDirs: string[]): Promise<void> {
+  const concurrency = Number.parseInt(values.concurrency ?? `${DEFAULT_BATCH_CONCURRENCY}`, 10);
+  if (!Number.isInteger(concurrency) || concurrency < 1) {
+    console.error('--concurrency must be a positive integer');
+    process.exitCode = 1;
+    return;
+  }
+  if (taskDirs.length === 0) {
+    console.error('batch needs at least one task directory');
+    process.exitCode = 1;
+    return;
+  }
+
+  const jobs = taskDirs.flatMap(dir => {
+    const taskDir = path.resolve(process.cwd(), dir);
+    const files = findTaskValidationFiles(taskDir);
+    if (files.length === 0) console.warn(`⚠️  No validation files under ${dir}`);
+    return files.map(file => ({ file, taskDir }));
+  });
+  console.log(`🔁 Regenerating ${jobs.length} validation file(s) from ${taskDirs.length} task(s)`);
+  if (!values['no-progress']) enableProgress();
+
+  const byFile = new Map(jobs.map(job => [path.relative(process.cwd(), job.file), job]));
+  const outcomes = await runBounded(Array.from(byFile.keys()), concurrency, async name => {
+    const job = byFile.get(name)!;
+    await regenerateValidationFile(job.file, taskWorkdir(job.taskDir));
+    console.log(`✅ ${name}`);
+  });
+
+  console.log(`\n${formatBatchSummary(outcomes)}`);
+  if (outcomes.some(o => !o.ok)) process.exitCode = 1;
+}
+
+async function runLedger(values: CliValues, args: string[]): Promise<void> {
+  const [action, task] = args;
+  if (!values.ledger) {
+    console.error('ledger needs --ledger <FILE>');
+    process.exitCode = 1;
+    return;
+  }
+  if (!(action === 'list' && !task) && !(action === 'show' && task)) {
+    console.error('Usage: ledger list | ledger show <TASK>');
+    process.exitCode = 1;
+    return;
+  }
+
+  const entries = await readLedger(path.resolve(process.cwd(), values.ledger));
+  const problems = verifyLedger(entries);
+  for (const problem of problems) {
+    console.error(`❌ ${problem}`);
+  }
+  if (problems.length > 0) {
+    console.error('The ledger has been modified; its entries cannot be trusted');
+    process.exitCode = 1;
+  }
+
+  if (action === 'list') {
+    const tasks = summarizeLedger(entries);
+    if (tasks.length === 0) console.log('The ledger has no entries');
+    for (const t of tasks) {
+      const chains = t.chains.length > 0 ? ` on chain ${t.chains.join(', ')}` : '';
+      console.log(
+        `${t.task}: ${t.validations} validation(s), ${t.signatures} signature(s)${chains}, last ${t.lastActivity}`
+      );
+    }
+    return;
+  }
+
+  const recorded = entries.filter(e => e.task === task);
+  if (recorded.length === 0) {
+    console.error(`No entries for ${task}`);
+    process.exitCode = 1;
+    return;
+  }
+  for (const entry of recorded) {
+    console.log(`#${entry.seq} ${entry.timestamp} ${entry.kind}`);
+    for (const [key, value] of Object.entries(entry.details)) {
+      console.log(`  ${key}: ${value}`);
+    }
+  }
+}
+
+async function runHash(values: CliValues): Promise<void> {
+  const files = values.file ?? [];
+  if (files.length !== 1) {
+    console.error('Provide exactly one --file to hash.');
+    process.exitCode = 1;
+    return;
+  }
+  const filePath = path.resolve(process.cwd(), files[0]);
+  // A split file hashes as the file it was split from
+  const text = await readValidationFile(filePath);
+  if (!values.expect) {
+    console.log(canonicalHash(JSON.parse(text)));
+    return;
+  }
+  const check = verifyCanonicalHash(text, values.expect);
+  if (!check.valid) {
+    console.error(`❌ ${filePath}: ${check.error}`);
+    process.exitCode = 1;
+    return;
+  }
+  console.log(`✅ ${filePath} matches ${check.hash}`);
+}
+
+async function runExplain(values: CliValues): Promise<void> {
+  if (!values.address || !isAddress(values.address, { strict: false })) {
+    console.error('explain needs --address <ADDR>');
+    process.exitCode = 1;
+    return;
+  }
+  if (!values.slot || !/^0x[0-9a-fA-F]{1,64}$/.test(values.slot)) {
+    console.error('explain needs --slot <SLOT> as hex');
+    process.exitCode = 1;
+    return;
+  }
+  const chainId = values['chain-id'] !== undefined ? Number(values['chain-id']) : undefined;
+  if (chainId !== undefined && (!Number.isInteger(chainId) || chainId <= 0)) {
+    console.error('--chain-id must be a positive integer');
+    process.exitCode = 1;
+    return;
+  }
+  if (!values['rpc-url'] && chainId === undefined) {
+    console.error('explain needs --rpc-url or --chain-id');
+    process.exitCode = 1;
+    return;
+  }
+
+  const cacheDir = values['metadata-cache'] ?? process.env[METADATA_CACHE_ENV];
+  const sdc = new StateDiffClient(0, undefined, {
+    preimages: values.preimages
+      ? loadPreimageDatabase(path.resolve(process.cwd(), values.preimages))
+      : [],
+    metadataCache: cacheDir
+      ? {
+          dir: path.resolve(process.cwd(), cacheDir),
+          ttlHours: DEFAULT_METADATA_CACHE_TTL_HOURS,
+          refresh: false,
+        }
+      : null,
+    preimageStore: preimageStoreDir(values),
+  });
+  const explained = await sdc.explainSlot({
+    rpcUrl: values['rpc-url'],
+    chainId,
+    address: values.address,
+    slot: values.slot as Hex,
+  });
+  if (values.json) {
+    console.log(JSON.stringify(explained, null, 2));
+    return;
+  }
+
+  console.log(`Slot ${explained.slot}`);
+  console.log(`  of ${explained.address} on chain ${explained.chainId}`);
+  if (explained.contract) {
+    console.log(`Contract: ${explained.contract}`);
+  } else if (explained.patterns.length > 0) {
+    console.log(`Contract: not in contracts.json; matches ${explained.patterns.join(', ')}`);
+  } else {
+    console.log('Contract: not in contracts.json and matches no built-in pattern');
+  }
+  if (explained.entry) {
+    const critical = explained.entry.critical ? ' (critical)' : '';
+    console.log(`Type: ${explained.entry.type}${critical}`);
+    console.log(`Summary: ${explained.entry.description}`);
+    if (explained.entry.overrideMeaning) {
+      console.log(`Override meaning: ${explained.entry.overrideMeaning}`);
+    }
+  } else {
+    console.log('Summary: no entry describes this slot');
+  }
+  if (explained.derivation.length > 0) {
+    console.log('Derived from:');
+    for (const { slot, parent, key } of explained.derivation) {
+      console.log(`  ${slot} = keccak256(${key} . ${parent})`);
+    }
+  } else {
+    console.log('Derived from: no preimage is known for this slot');
+  }
+  if (explained.value !== null) console.log(`Current value: ${explained.value}`);
+}
+
+function preimageStoreDir(values: CliValues): string | null {
+  const dir = values['preimage-store'] ?? process.env[PREIMAGE_STORE_ENV];
+  return dir ? path.resolve(process.cwd(), dir) : null;
+}
+
+async function runPreimages(values: CliValues, action: string | undefined): Promise<void> {
+  const dir = preimageStoreDir(values);
+  if (!dir) {
+    console.error(`preimages needs --preimage-store <DIR> or ${PREIMAGE_STORE_ENV}`);
+    process.exitCode = 1;
+    return;
+  }
+
+  if (action === 'export') {
+    const exported = await exportPreimageStore(dir);
+    const json = JSON.stringify(exported, null, 2) + '\n';
+    if (!values.out) {
+      process.stdout.write(json);
+      return;
+    }
+    const outPath = path.resolve(process.cwd(), values.out);
+    writeFileSync(outPath, json);
+    const chains = Object.keys(exported.chains);
+    console.log(`Wrote the preimages of ${chains.length} chain(s) to ${outPath}`);
+    return;
+  }
+
+  const files = values.file ?? [];
+  if (action !== 'import' || files.length !== 1) {
+    console.error('Usage: preimages export [--out <FILE>] | preimages import --file <FILE>');
+    process.exitCode = 1;
+    return;
+  }
+  const file = path.resolve(process.cwd(), files[0]);
+  const added = await importPreimageStore(dir, readFileSync(file, 'utf-8'));
+  for (const [chainId, count] of Object.entries(added)) {
+    console.log(`Chain ${chainId}: ${count} new preimage(s)`);
+  }
+}
+
+async function runCommitment(values: CliValues, action: string | undefined): Promise<void> {
+  const files = values.file ?? [];
+  const readConfig = async (file: string): Promise<TaskConfig> => {
+    const filePath = path.resolve(process.cwd(), file);
+    const parsed = parseFromString(await readValidationFile(filePath));
+    if (!('config' in parsed)) {
+      throw new Error(`${filePath}: ${getValidationSummary(parsed.result)}`);
+    }
+    return parsed.config;
+  };
+
+  if (action === 'build') {
+    if (files.length !== 1) {
+      console.error('commitment build needs one --file');
+      process.exitCode = 1;
+      return;
+    }
+    const proof = buildDiffProof(await readConfig(files[0]));
+    const json = JSON.stringify(proof, null, 2) + '\n';
+    if (!values.out) {
+      process.stdout.write(json);
+      return;
+    }
+    const outPath = path.resolve(process.cwd(), values.out);
+    writeFileSync(outPath, json);
+    console.log(`Wrote the proofs of ${proof.leafCount} change(s) to ${outPath}`);
+    console.log(`Root:       ${proof.root}`);
+    console.log(`Commitment: ${proof.commitment}`);
+    return;
+  }
+
+  if (action !== 'verify' || !values.proof || files.length > 1) {
+    console.error('Usage: commitment build --file <FILE> | commitment verify --proof <FILE>');
+    process.exitCode = 1;
+    return;
+  }
+  const proofPath = path.resolve(process.cwd(), values.proof);
+  const proof = parseDiffProofFile(JSON.parse(readFileSync(proofPath, 'utf-8')));
+  const executed = files.length === 1 ? await readConfig(files[0]) : undefined;
+  const errors = verifyDiffProof(proof, executed);
+  if (errors.length > 0) {
+    for (const error of errors) console.error(`❌ ${error}`);
+    process.exitCode = 1;
+    return;
+  }
+  const against = executed ? ', and the executed transaction changed exactly those slots' : '';
+  console.log(`✅ ${proof.leafCount} committed change(s) prove into ${proof.root}${against}`);
+}
+
+async function runConfig(values: CliValues, args: string[]): Promise<void> {
+  const [action, file] = args;
+  if (action !== 'import-labels' || !file || args.length > 2) {
+    console.error('Usage: config import-labels <CSV> [--chain-id <ID>] [--out <FILE>]');
+    process.exitCode = 1;
+    return;
+  }
+  const format = values.format ?? 'yaml';
+  if (format !== 'yaml' && format !== 'json') {
+    console.error('--format must be yaml or json');
+    process.exitCode = 1;
+    return;
+  }
+  const chainId = values['chain-id']?.trim();
+  if (chainId !== undefined && !/^[1-9]\d*$/.test(chainId)) {
+    console.error('--chain-id must be a positive integer');
+    process.exitCode = 1;
+    return;
+  }
+
+  const csvPath = path.resolve(process.cwd(), file);
+  const { source, labels, skipped } = parseLabelCsv(readFileSync(csvPath, 'utf-8'), { chainId });
+  const embedded = contractsCfg as unknown as {
+    contracts: Record<string, Record<string, unknown>>;
+  };
+  const { overlay, known } = labelsToOverlay(labels, embedded.contracts);
+  const content = serializeResult(overlay, format) + '\n';
+  // The summary goes to stderr when the overlay is printed, so it can be piped
+  const report = values.out ? console.log : console.error;
+  if (values.out) {
+    const outPath = path.resolve(process.cwd(), values.out);
+    writeFileSync(outPath, content);
+    report(`Wrote ${outPath}`);
+  } else {
+    process.stdout.write(content);
+  }
+  report(
+    `Imported ${labels.length - known} label(s) from a ${source} export; ${known} already in contracts.json, ${skipped.length} row(s) skipped`
+  );
+  for (const { row, reason } of skipped) report(`  row ${row}: ${reason}`);
+}
+
+async function runSignatures(values: CliValues): Promise<void> {
+  const files = values.file ?? [];
+  if (files.length !== 1 || !values.signatures || !values['rpc-url']) {
+    console.error('signatures needs one --file, --signatures <FILE>, and --rpc-url');
+    process.exitCode = 1;
+    return;
+  }
+  const required = values.required !== undefined ? Number(values.required) : undefined;
+  if (required !== undefined && (!Number.isInteger(required) || required <= 0)) {
+    console.error('--required must be a positive integer');
+    process.exitCode = 1;
+    return;
+  }
+  const filePath = path.resolve(process.cwd(), files[0]);
+  const parsed = parseFromString(await readValidationFile(filePath));
+  if (!('config' in parsed)) {
+    throw new Error(`${filePath}: ${getValidationSummary(parsed.result)}`);
+  }
+  const { address, domainHash, messageHash } = parsed.config.expectedDomainAndMessageHashes;
+  const check = await checkSafeSignatures({
+    transport: http(values['rpc-url']),
+    safe: address as Hex,
+    domainHash: domainHash as Hex,
+    messageHash: messageHash as Hex,
+    signatures: parseCollectedSignatures(
+      readFileSync(path.resolve(process.cwd(), values.signatures), 'utf-8')
+    ),
+    requiredSignatures: required,
+  });
+  for (const note of check.notes) console.warn(`⚠️  ${note}`);
+  if (!check.accepted) {
+    console.error(`❌ ${describeSignatureCheck(check)}`);
+    process.exitCode = 1;
+    return;
+  }
+  console.log(`✅ ${describeSignatureCheck(check)}`);
+  if (!values.out) {
+    console.log(check.packed);
+    return;
+  }
+  const outPath = path.resolve(process.cwd(), values.out);
+  writeFileSync(outPath, check.packed + '\n');
+  console.log(`Wrote the packed signatures to ${outPath}`);
+}
+
+async function runOverrides(values: CliValues, action: string | undefined): Promise<void> {
+  const files = values.file ?? [];
+  const sources = files.length + (values['expected-overrides'] ? 1 : 0);
+  if (action !== 'check' || files.length > 1 || sources === 0 || !values['rpc-url']) {
+    console.error('overrides check needs --rpc-url and one --file, --expected-overrides, or both');
+    process.exitCode = 1;
+    return;
+  }
+
+  const proposed: ProposedOverride[] = [];
+  let stateChanges: TaskConfig['stateChanges'] = [];
+  if (files.length === 1) {
+    const filePath = path.resolve(process.cwd(), files[0]);
+    const parsed = parseFromString(await readValidationFile(filePath));
+    if (!('config' in parsed)) {
+      throw new Error(`${filePath}: ${getValidationSummary(parsed.result)}`);
+    }
+    proposed.push(...overridesFromValidationFile(parsed.config.stateOverrides));
+    stateChanges = parsed.config.stateChanges;
+  }
+  if (values['expected-overrides']) {
+    const file = path.resolve(process.cwd(), values['expected-overrides']);
+    proposed.push(...overridesFromExpected(parseExpectedOverrides(readFileSync(file, 'utf-8'))));
+  }
+
+  const report = await evaluateOverrides(http(values['rpc-url']), proposed, stateChanges);
+  const conflicts = report.overrides.filter(o => o.status === 'conflict').length;
+  if (conflicts > 0) process.exitCode = 1;
+  if (values.json) {
+    const json = { blockNumber: Number(report.blockNumber), overrides: report.overrides };
+    console.log(JSON.stringify(json, null, 2));
+    return;
+  }
+  const icons = { 'no-op': '➖', change: '✏️ ', conflict: '❌' } as const;
+  for (const o of report.overrides) {
+    console.log(`${icons[o.status]} ${describeOverrideEvaluation(o)}`);
+  }
+  const count = (status: string) => report.overrides.filter(o => o.status === status).length;
+  console.log(
+    `\n${report.overrides.length} override(s) at block ${report.blockNumber}: ${count('change')} change(s), ${count('no-op')} no-op(s), ${conflicts} conflict(s)`
+  );
+}
+
+async function runReview(values: CliValues): Promise<void> {
+  const files = values.file ?? [];
+  const protocol = values['confirm-protocol'];
+  if (files.length !== 1) {
+    console.error('review needs exactly one --file');
+    process.exitCode = 1;
+    return;
+  }
+  if (protocol !== undefined && !(CONFIRM_PROTOCOLS as readonly string[]).includes(protocol)) {
+    console.error(`--confirm-protocol must be one of: ${CONFIRM_PROTOCOLS.join(', ')}`);
+    process.exitCode = 1;
+    return;
+  }
+  const filePath = path.resolve(process.cwd(), files[0]);
+  // A split file is reviewed as the file it was split from
+  const text = await readValidationFile(filePath);
+  const parsed = parseFromString(text);
+  if (!('config' in parsed)) {
+    throw new Error(`${filePath}: ${getValidationSummary(parsed.result)}`);
+  }
+  const items = reviewItems(parsed.config);
+  if (!protocol) {
+    for (const item of items) console.log(JSON.stringify(item));
+    return;
+  }
+
+  const input = createInterface({ input: process.stdin, crlfDelay: Infinity });
+  try {
+    const outcome = await runConfirmProtocol(
+      items,
+      input,
+      line => process.stdout.write(line + '\n'),
+      canonicalHash(JSON.parse(text))
+    );
+    if (!outcome.confirmed) process.exitCode = 1;
+  } finally {
+    input.close();
+  }
+}
+
+async function runCeremony(values: CliValues, action: string | undefined): Promise<void> {
+  if (!values.ceremony) {
+    console.error('ceremony needs --ceremony <FILE>');
+    process.exitCode = 1;
+    return;
+  }
+  const ceremonyPath = path.resolve(process.cwd(), values.ceremony);
+
+  if (action === 'init') {
+    const files = values.file ?? [];
+    const threshold = Number(values.threshold);
+    if (files.length !== 1 || !values.participants || !Number.isInteger(threshold)) {
+      console.error('ceremony init needs one --file, --participants, and an integer --threshold');
+      process.exitCode = 1;
+      return;
+    }
+    if (existsSync(ceremonyPath)) {
+      console.error(`${ceremonyPath} already exists; a ceremony is never overwritten`);
+      process.exitCode = 1;
+      return;
+    }
+    const filePath = path.resolve(process.cwd(), files[0]);
+    const parsed = parseFromString(await readValidationFile(filePath));
+    if (!('config' in parsed)) {
+      throw new Error(`${filePath}: ${getValidationSummary(parsed.result)}`);
+    }
+    const { address, domainHash, messageHash } = parsed.config.expectedDomainAndMessageHashes;
+    const ceremony = initCeremony({
+      safe: address,
+      domainHash,
+      messageHash,
+      participants: parseAddressList(values.participants, '--participants'),
+      threshold,
+    });
+    await writeCeremonyFile(ceremonyPath, ceremony);
+    console.log(`Started a ceremony for safeTxHash ${ceremony.safeTxHash} on ${ceremony.safe}`);
+    console.log(describeCeremony(ceremony));
+    return;
+  }
+
+  if (action === 'submit-signature') {
+    const signature = values.signature;
+    if (!signature || !isHex(signature) || signature.length !== 132) {
+      console.error('ceremony submit-signature needs --signature with one 65-byte signature');
+      process.exitCode = 1;
+      return;
+    }
+    const { ceremony, signer, notes } = await updateCeremonyFile(ceremonyPath, current =>
+      submitCeremonySignature(current, signature)
+    );
+    for (const note of notes) console.warn(`⚠️  ${note}`);
+    console.log(`✅ Recorded the signature of ${signer}`);
+    console.log(describeCeremony(ceremony));
+    return;
+  }
+
+  if (action === 'status') {
+    const ceremony = await readCeremonyFile(ceremonyPath);
+    console.log(`Safe ${ceremony.safe}, safeTxHash ${ceremony.safeTxHash}`);
+    console.log(describeCeremony(ceremony));
+    for (const s of ceremony.signatures) console.log(`  ✍️  ${s.signer} at ${s.submittedAt}`);
+    for (const p of pendingParticipants(ceremony)) console.log(`  ⏳ ${p}`);
+    if (ceremony.packed) console.log(`Packed signatures: ${ceremony.packed}`);
+    return;
+  }
+
+  if (action === 'finalize') {
+    const ready = await readCeremonyFile(ceremonyPath);
+    if (values['rpc-url'] && ready.state === 'ready') {
+      const check = await checkSafeSignatures({
+        transport: http(values['rpc-url']),
+        safe: ready.safe as Hex,
+        domainHash: ready.domainHash as Hex,
+        messageHash: ready.messageHash as Hex,
+        signatures: ready.signatures.map(s => s.signature as Hex),
+        requiredSignatures: ready.threshold,
+      });
+      for (const note of check.notes) console.warn(`⚠️  ${note}`);
+      if (!check.accepted) {
+        console.error(`❌ ${describeSignatureCheck(check)}`);
+        process.exitCode = 1;
+        return;
+      }
+      console.log(`✅ ${describeSignatureCheck(check)}`);
+    }
+    const { ceremony } = await updateCeremonyFile(ceremonyPath, current => ({
+      ceremony: finalizeCeremony(current),
+    }));
+    console.log(describeCeremony(ceremony));
+    if (!values.out) {
+      console.log(ceremony.packed);
+      return;
+    }
+    const outPath = path.resolve(process.cwd(), values.out);
+    writeFileSync(outPath, ceremony.packed + '\n');
+    console.log(`Wrote the packed signatures to ${outPath}`);
+    return;
+  }
+
+  console.error('Usage: ceremony init | submit-signature | status | finalize');
+  process.exitCode = 1;
+}
+
+async function runManifest(values: CliValues, args: string[]): Promise<void> {
+  const [action, ...files] = args;
+  const valid = (action === 'verify' && files.length === 0) || (action === 'add' && files.length > 0);
+  if (!values.manifest || !valid) {
+    console.error(
+      'Usage: manifest verify --manifest <FILE> | manifest add --manifest <FILE> <FILE>...'
+    );
+    process.exitCode = 1;
+    return;
+  }
+  const manifestPath = path.resolve(process.cwd(), values.manifest);
+
+  if (action === 'add') {
+    const inputs = files.map(file => ({ file: path.resolve(process.cwd(), file) }));
+    const manifest = await recordManifestArtifacts(manifestPath, inputs, {
+      buildInfo: getBuildInfo(),
+    });
+    console.log(`📦 ${manifestPath} lists ${manifest.artifacts.length} file(s)`);
+    return;
+  }
+
+  const { manifest, checks } = await verifyManifest(manifestPath);
+  const { generatedBy } = manifest;
+  console.log(
+    `Written by ${generatedBy.tool} ${generatedBy.version} (${generatedBy.commit}), config ${generatedBy.configHash}`
+  );
+  if (generatedBy.configHash !== embeddedConfigHash()) {
+    console.warn(`⚠️  This installation's config hash is ${embeddedConfigHash()}`);
+  }
+  if (manifest.rpcEndpoints.length > 0) {
+    console.log(`RPC endpoints: ${manifest.rpcEndpoints.join(', ')}`);
+  }
+  const icons = { ok: '✅', changed: '❌', missing: '❌' } as const;
+  for (const check of checks) {
+    const artifact = manifest.artifacts.find(a => a.path === check.path)!;
+    const pin =
+      artifact.blockNumber !== undefined
+        ? ` (chain ${artifact.chainId}, block ${artifact.blockNumber})`
+        : '';
+    const status = check.status === 'ok' ? '' : ` ${check.status}`;
+    console.log(`${icons[check.status]} ${check.path}${pin}${status}`);
+  }
+  const failed = checks.filter(c => c.status !== 'ok').length;
+  if (failed > 0) {
+    console.error(`\n${failed} of ${checks.length} file(s) do not match the manifest`);
+    process.exitCode = 1;
+  } else {
+    console.log(`\nAll ${checks.length} file(s) match the manifest`);
+  }
+}
+
+async function runSelftestCommand(): Promise<void> {
+  console.log(formatBuildInfo(getBuildInfo()));
+  const results = await runSelftest();
+  console.log('');
+  for (const result of results) {
+    if (result.problems.length === 0) {
+      console.log(`✅ ${result.name} (chain ${result.chainId})`);
+      continue;
+    }
+    console.error(`❌ ${result.name} (chain ${result.chainId})`);
+    for (const problem of result.problems) console.error(`   ${problem}`);
+  }
+  const failed = results.filter(r => r.problems.length > 0).length;
+  if (failed > 0) {
+    console.error(`\n${failed} of ${results.length} selftest fixture(s) failed`);
+    process.exitCode = 1;
+  } else {
+    console.log(`\nAll ${results.length} selftest fixture(s) passed`);
+  }
+}
+
+async function main() {
+  const { values, positionals } = parseArgs({
+    args: process.argv.slice(2),
+    allowPositionals: true,
+    options: {
+      kind: { type: 'string', short: 'k' },
+      file: { type: 'string', short: 'f', multiple: true },
+      format: { type: 'string' },
+      out: { type: 'string', short: 'o' },
+      concurrency: { type: 'string' },
+      ledger: { type: 'string' },
+      expect: { type: 'string' },
+      'no-progress': { type: 'boolean' },
+      address: { type: 'string' },
+      slot: { type: 'string' },
+      'rpc-url': { type: 'string', short: 'r' },
+      'chain-id': { type: 'string' },
+      preimages: { type: 'string' },
+      'preimage-store': { type: 'string' },
+      'metadata-cache': { type: 'string' },
+      json: { type: 'boolean' },
+      signatures: { type: 'string' },
+      required: { type: 'string' },
+      'expected-overrides': { type: 'string' },
+      manifest: { type: 'string' },
+      ceremony: { type: 'string' },
+      participants: { type: 'string' },
+      threshold: { type: 'string' },
+      signature: { type: 'string' },
+      'confirm-protocol': { type: 'string' },
+      proof: { type: 'string' },
+      locale: { type: 'string' },
+      help: { type: 'boolean', short: 'h' },
+    },
+  });
+
+  const [command, blobArg] = positionals;
+  if (command === 'decode' && !values.help) {
+    runDecode(values, blobArg);
+  } else if (command === 'export' && !values.help) {
+    await runExport(values);
+  } else if (command === 'batch' && !values.help) {
+    await runBatch(values, positionals.slice(1));
+  } else if (command === 'ledger' && !values.help) {
+    await runLedger(values, positionals.slice(1));
+  } else if (command === 'hash' && !values.help) {
+    await runHash(values);
+  } else if (command === 'explain' && !values.help) {
+    await runExplain(values);
+  } else if (command === 'preimages' && !values.help) {
+    await runPreimages(values, blobArg);
+  } else if (command === 'signatures' && !values.help) {
+    await runSignatures(values);
+  } else if (command === 'overrides' && !values.help) {
+    await runOverrides(values, blobArg);
+  } else if (command === 'review' && !values.help) {
+    await runReview(values);
+  } else if (command === 'ceremony' && !values.help) {
+    await runCeremony(values, blobArg);
+  } else if (command === 'commitment' && !values.help) {
+    await runCommitment(values, blobArg);
+  } else if (command === 'config' && !values.help) {
+    await runConfig(values, positionals.slice(1));
+  } else if (command === 'manifest' && !values.help) {
+    await runManifest(values, positionals.slice(1));
+  } else if (command === 'selftest' && !values.help) {
+    await runSelftestCommand();
+  } else {
+    printUsage();
+    if (!values.help) process.exitCode = 1;
+  }
+}
+
+main().catch(err => {
+  console.error(err instanceof Error ? err.message : err);
+  process.exitCode = exitCodeFor(err);
+});
@@ -0,0 +1,100 @@
+import path from 'path';
+import { parseArgs } from 'node:util';
+import { http } from 'viem';
+import { readValidationFile } from '@/lib/artifact-split';
+import { verifyAttestation } from '@/lib/attestation';
+import { checkSimulatedBlock } from '@/lib/block-reorg';
+import { ledgerTaskName } from '@/lib/task-ledger';
+import { NotifySummary, parseWebhookUrl, postNotification } from '@/lib/webhook-notify';
+
+function printUsage(): void {
+  const msg = `
+Verify the facilitator attestation embedded in a validation JSON file.
+
+Usage:
+  tsx scripts/verifyAttestation.ts --file <FILE> [--signer <ADDR>] [--rpc-url <URL>]
+                                   [--notify-webhook <URL>]
+
+Flags:
+  --file, -f     Validation JSON file generated with --attest
+  --signer, -s   Expected facilitator address; without it any valid signature is reported
+  --rpc-url <url>
+                 Also check that the block the file was simulated at is still canonical, and
+                 warn if a reorg replaced it
+  --notify-webhook <url>
+                 Post the outcome with the file's task, chain, hashes, and change counts to a
+                 Slack or Discord webhook. Give the URL as '\${VAR}' to read it from the
+                 environment
+  --notify-link <url>
+                 Link to the published file in the message, instead of its local path
+  --help, -h     Show this help message
+`;
+  console.log(msg);
+}
+
+async function main() {
+  const { values } = parseArgs({
+    args: process.argv.slice(2),
+    options: {
+      file: { type: 'string', short: 'f' },
+      signer: { type: 'string', short: 's' },
+      'rpc-url': { type: 'string' },
+      'notify-webhook': { type: 'string' },
+      'notify-link': { type: 'string' },
+      help: { type: 'boolean', short: 'h' },
+    },
+  });
+
+  if (values.help || !values.file) {
+    printUsage();
+    if (!values.help) process.exitCode = 1;
+    return;
+  }
+
+  const webhook = values['notify-webhook'] ? parseWebhookUrl(values['notify-webhook']) : null;
+  const filePath = path.resolve(process.cwd(), values.file);
+  const json = JSON.parse(await readValidationFile(filePath));
+  const result = await verifyAttestation(json, values.signer);
+  const notify = async (outcome: Pick<NotifySummary, 'event' | 'detail'>) => {
+    if (!webhook) return;
+    try {
+      await postNotification(webhook, {
+        ...outcome,
+        task: ledgerTaskName(filePath),
+        config: json,
+        artifact: values['notify-link'] ?? path.relative(process.cwd(), filePath),
+      });
+      console.log('📣 Posted the outcome to the webhook');
+    } catch (err) {
+      console.warn(`⚠️  ${err instanceof Error ? err.message : String(err)}`);
+    }
+  };
+
+  if (!result.valid) {
+    console.error(`❌ Attestation check failed for ${filePath}: ${result.error}`);
+    await notify({ event: 'verification-failed', detail: `Attestation: ${result.error}` });
+    process.exitCode = 1;
+    return;
+  }
+
+  console.log(`✅ ${filePath} is attested by ${result.signer}`);
+  if (!values.signer) {
+    console.log('⚠️  No --signer given; confirm this is the expected facilitator address.');
+  }
+  if (values['rpc-url'] && json.simulatedAt) {
+    const reorg = await checkSimulatedBlock(http(values['rpc-url']), json.simulatedAt);
+    if (reorg) {
+      console.warn(`⚠️  ${reorg}`);
+    } else if (json.simulatedAt.blockHash) {
+      console.log(`✅ Block ${json.simulatedAt.blockNumber} is still canonical`);
+    } else {
+      console.log('⚠️  The file does not record its block hash, so reorgs cannot be checked');
+    }
+  }
+  await notify({ event: 'verified', detail: `Attested by ${result.signer}` });
+}
+
+main().catch(err => {
+  console.error(err);
+  process.exitCode = 1;
+});
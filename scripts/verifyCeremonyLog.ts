@@ -0,0 +1,59 @@
+import path from 'path';
+import { parseArgs } from 'node:util';
+import { readCeremonyLog, verifyCeremonyLog } from '@/lib/ceremony-log';
+
+function printUsage(): void {
+  const msg = `
+Verify the hash chain and signatures of a signing ceremony log.
+
+Usage:
+  tsx scripts/verifyCeremonyLog.ts --file <FILE>
+
+Flags:
+  --file, -f     ceremony-<session>.jsonl file written by the app when CEREMONY_LOG_DIR is set
+  --help, -h     Show this help message
+`;
+  console.log(msg);
+}
+
+async function main() {
+  const { values } = parseArgs({
+    args: process.argv.slice(2),
+    options: {
+      file: { type: 'string', short: 'f' },
+      help: { type: 'boolean', short: 'h' },
+    },
+  });
+
+  if (values.help || !values.file) {
+    printUsage();
+    if (!values.help) process.exitCode = 1;
+    return;
+  }
+
+  const filePath = path.resolve(process.cwd(), values.file);
+  const entries = await readCeremonyLog(filePath);
+  const problems = await verifyCeremonyLog(entries);
+
+  if (problems.length > 0) {
+    console.error(`❌ ${filePath} failed verification:`);
+    for (const problem of problems) console.error(`   ${problem}`);
+    process.exitCode = 1;
+    return;
+  }
+
+  for (const entry of entries) {
+    const details = entry.details ? ` ${JSON.stringify(entry.details)}` : '';
+    console.log(`${entry.timestamp}  ${entry.event}${details}`);
+  }
+  const signers = entries
+    .filter(entry => entry.event === 'log_signed')
+    .map(entry => entry.details?.signer);
+  const signedBy = signers.length > 0 ? `signed by ${signers.join(', ')}` : 'unsigned';
+  console.log(`✅ ${entries.length} entries intact, ${signedBy}`);
+}
+
+main().catch(err => {
+  console.error(err);
+  process.exitCode = 1;
+});
@@ -0,0 +1,12 @@
+import { writeFileSync } from 'fs';
+import { BUILD_INFO_FILE, formatBuildInfo, getBuildInfo, stampBuildInfo } from '@/lib/build-info';
+
+// Run when cutting a release so the commit and build date travel with the build instead of
+// being looked up from git at runtime.
+const stamp = stampBuildInfo();
+if (stamp.commit === 'unknown' || stamp.commit.endsWith('-dirty')) {
+  console.warn(`⚠️  Stamping build from commit "${stamp.commit}"; release from a clean checkout`);
+}
+writeFileSync(BUILD_INFO_FILE, JSON.stringify(stamp, null, 2) + '\n');
+console.log(`Wrote ${BUILD_INFO_FILE}`);
+console.log(formatBuildInfo(getBuildInfo()));
@@ -0,0 +1,83 @@
+import {
+  appendCeremonyEntry,
+  CEREMONY_EVENTS,
+  CEREMONY_LOG_DIR_ENV,
+  CeremonyDetails,
+  CeremonyEvent,
+  ceremonyHeadHashes,
+} from '@/lib/ceremony-log';
+import { signDomainAndMessageHash } from '@/lib/ledger-signing';
+import { NextRequest, NextResponse } from 'next/server';
+
+type CeremonyRequest = {
+  sessionId?: string;
+  event?: string;
+  artifact?: unknown;
+  details?: CeremonyDetails;
+  // Only for `closed`: sign the log head with the Ledger and append a `log_signed` entry
+  sign?: boolean;
+  ledgerAccount?: number;
+};
+
+export async function POST(req: NextRequest) {
+  const dir = process.env[CEREMONY_LOG_DIR_ENV];
+  if (!dir) {
+    return NextResponse.json({ enabled: false }, { status: 200 });
+  }
+
+  try {
+    const { sessionId, event, artifact, details, sign, ledgerAccount = 0 } =
+      (await req.json()) as CeremonyRequest;
+
+    if (!sessionId || !event || !(CEREMONY_EVENTS as readonly string[]).includes(event)) {
+      return NextResponse.json(
+        { error: `sessionId and an event of ${CEREMONY_EVENTS.join(', ')} are required` },
+        { status: 400 }
+      );
+    }
+    if (event === 'log_signed' || (sign && event !== 'closed')) {
+      return NextResponse.json(
+        { error: 'The log can only be signed by closing it with sign: true' },
+        { status: 400 }
+      );
+    }
+    if (!Number.isInteger(ledgerAccount) || ledgerAccount < 0) {
+      return NextResponse.json(
+        { error: 'Invalid ledgerAccount: must be a non-negative integer' },
+        { status: 400 }
+      );
+    }
+
+    const entry = await appendCeremonyEntry(dir, sessionId, {
+      event: event as CeremonyEvent,
+      artifact,
+      details,
+    });
+    if (!sign) {
+      return NextResponse.json({ enabled: true, entry }, { status: 200 });
+    }
+
+    const { domainHash, messageHash } = ceremonyHeadHashes(entry.entryHash);
+    const result = await signDomainAndMessageHash({ domainHash, messageHash, ledgerAccount });
+    if (!result.success || !result.signer || !result.signature) {
+      return NextResponse.json(
+        { error: result.error ?? 'Ledger returned no signature', entry },
+        { status: 500 }
+      );
+    }
+    const signature = result.signature.startsWith('0x')
+      ? result.signature
+      : `0x${result.signature}`;
+    const signedEntry = await appendCeremonyEntry(dir, sessionId, {
+      event: 'log_signed',
+      artifactHash: entry.entryHash,
+      details: { signer: result.signer, signature },
+    });
+    return NextResponse.json({ enabled: true, entry: signedEntry }, { status: 200 });
+  } catch (error) {
+    return NextResponse.json(
+      { error: error instanceof Error ? error.message : 'Unknown error occurred' },
+      { status: 500 }
+    );
+  }
+}
@@ -1,6 +1,7 @@
 import { promises as fs } from 'fs';
 import path from 'path';
 import { parseFromString } from '@/lib/parser';
+import { readValidationFile } from '@/lib/artifact-split';
 import { NextRequest, NextResponse } from 'next/server';
 import { findContractDeploymentsRoot } from '@/lib/deployments';
 import { assertWithinDir } from '@/lib/path-validation';
@@ -65,7 +66,7 @@ export async function GET(req: NextRequest) {
         const filePath = path.join(validationsPath, configFile);
 
         try {
-          const configContent = await fs.readFile(filePath, 'utf-8');
+          const configContent = await readValidationFile(filePath);
           const parsedConfig = parseFromString(configContent);
 
           if (parsedConfig.result.success && 'config' in parsedConfig) {
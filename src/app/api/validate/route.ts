@@ -1,11 +1,21 @@
 import { validateUpgrade } from '@/lib/validation-service';
 import { NextRequest, NextResponse } from 'next/server';
 import { NetworkType } from '@/lib/types';
+import { resolveTenant, TenantContext, tenantRegistryFromEnv, TenantRegistry } from '@/lib/tenants';
+import { PolicyViolationError } from '@/lib/errors';
+
+let registry: TenantRegistry | null | undefined;
+
+// Read once; a changed registry takes effect when the server restarts
+function tenantRegistry(): TenantRegistry | null {
+  if (registry === undefined) registry = tenantRegistryFromEnv();
+  return registry;
+}
 
 export async function POST(req: NextRequest) {
   try {
     const json = await req.json();
-    const { upgradeId, network, userType } = json;
+    const { upgradeId, network, userType, tenant, chainId, configOverlay, policySet } = json;
 
     if (
       typeof upgradeId !== 'string' ||
@@ -36,10 +46,42 @@ export async function POST(req: NextRequest) {
       );
     }
 
+    const hasTenantFields = [chainId, configOverlay, policySet].some(v => v !== undefined);
+    if (
+      (tenant === undefined && hasTenantFields) ||
+      (tenant !== undefined && (typeof tenant !== 'string' || !tenant.trim())) ||
+      (chainId !== undefined && !Number.isInteger(chainId)) ||
+      (configOverlay !== undefined && typeof configOverlay !== 'string') ||
+      (policySet !== undefined && typeof policySet !== 'string')
+    ) {
+      return NextResponse.json(
+        {
+          message:
+            'Invalid tenant parameters: tenant (a string) is required with chainId (an integer), configOverlay, and policySet (strings)',
+        },
+        { status: 400 }
+      );
+    }
+
+    let tenantContext: TenantContext | undefined;
+    if (typeof tenant === 'string') {
+      const tenants = tenantRegistry();
+      if (!tenants) {
+        return NextResponse.json({ message: 'This server has no tenant registry' }, { status: 400 });
+      }
+      tenantContext = resolveTenant(tenants, {
+        tenant: tenant.trim(),
+        chainId,
+        overlay: configOverlay,
+        policy: policySet,
+      });
+    }
+
     const validationResult = await validateUpgrade({
       upgradeId: trimmedUpgradeId,
       network: normalizedNetwork as NetworkType,
       taskConfigFileName: trimmedUserType,
+      ...(tenantContext && { tenant: tenantContext }),
     });
 
     return NextResponse.json({ success: true, data: validationResult }, { status: 200 });
@@ -47,7 +89,7 @@ export async function POST(req: NextRequest) {
     console.error('Validation failed:', error);
     return NextResponse.json(
       { error: error instanceof Error ? error.message : 'Validation failed' },
-      { status: 500 }
+      { status: error instanceof PolicyViolationError ? 403 : 500 }
     );
   }
 }
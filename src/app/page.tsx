@@ -14,6 +14,8 @@ import { PageShell, StepIndicator } from '@/components/ui';
 import { NetworkType, ValidationData, Upgrade } from '@/lib/types';
 import { ConfigOption } from '@/components/UserSelection';
 import { LedgerSigningResult } from '@/lib/ledger-signing';
+import { ValidationEntryEvaluation, ValidationNavEntry } from '@/lib/validation-results-utils';
+import { useCeremonyLog } from '@/hooks/useCeremonyLog';
 
 type Step = 'upgrade' | 'user' | 'validation' | 'ledger' | 'signing';
 type StepStatus = 'current' | 'completed' | 'pending';
@@ -34,6 +36,7 @@ export default function Home() {
   const [validationData, setValidationData] = useState<ValidationData | null>(null);
   const [signingData, setSigningData] = useState<LedgerSigningResult | null>(null);
   const [userLedgerAccount, setUserLedgerAccount] = useState<number>(0);
+  const ceremony = useCeremonyLog();
 
   const resetSelectionsFrom = (step: 'upgrade' | 'user') => {
     if (step === 'upgrade') {
@@ -42,6 +45,7 @@ export default function Home() {
     }
 
     if (step === 'upgrade' || step === 'user') {
+      ceremony.startNewSession();
       setSelectedUser(undefined);
       setValidationData(null);
       setSigningData(null);
@@ -59,16 +63,42 @@ export default function Home() {
     setSelectedUser(cfg);
     setUserLedgerAccount(cfg.ledgerId);
     setCurrentStep('validation');
+    void ceremony.record('session_started', {
+      details: {
+        upgradeId: selectedUpgrade?.id ?? '',
+        network: selectedNetwork ?? '',
+        userType: cfg.fileName,
+      },
+    });
+  };
+
+  const handleValidationItemViewed = (
+    entry: ValidationNavEntry,
+    evaluation: ValidationEntryEvaluation
+  ) => {
+    void ceremony.record('results_viewed', {
+      artifact: evaluation,
+      details: { kind: entry.kind, index: entry.index, matchStatus: evaluation.matchStatus },
+    });
   };
 
   const handleProceedToLedgerSigning = (validationResult: ValidationData) => {
     setValidationData(validationResult);
     setCurrentStep('ledger');
+    void ceremony.record('acknowledged', {
+      artifact: validationResult,
+      details: { warnings: validationResult.warnings?.length ?? 0 },
+    });
   };
 
   const handleLedgerSigningComplete = (res: LedgerSigningResult) => {
     setSigningData(res);
     setCurrentStep('signing');
+    void ceremony.record('signed', { artifact: res, details: { signer: res.signer ?? '' } });
+  };
+
+  const handleSignCeremonyLog = async () => {
+    await ceremony.record('closed', { sign: true, ledgerAccount: userLedgerAccount });
   };
 
   const handleGoToUpgradeSelection = () => {
@@ -164,6 +194,7 @@ export default function Home() {
             network={selectedNetwork || ''}
             upgradeId={selectedUpgrade.id}
             onProceedToLedgerSigning={handleProceedToLedgerSigning}
+            onItemViewed={handleValidationItemViewed}
           />
         )}
 
@@ -185,6 +216,7 @@ export default function Home() {
             }}
             signingData={signingData}
             onBackToSetup={handleGoToUpgradeSelection}
+            onSignCeremonyLog={ceremony.enabled ? handleSignCeremonyLog : undefined}
           />
         )}
       </div>
@@ -9,6 +9,8 @@ interface ComparisonCardProps {
   type: 'expected' | 'actual';
   contractName: string;
   contractAddress: string;
+  contractExplorerUrl?: string;
+  newAccount?: boolean;
   storageKey: string;
   storageKeyDiffs?: StringDiff[];
   beforeValue?: string;
@@ -66,6 +68,8 @@ export function ComparisonCard({
   type,
   contractName,
   contractAddress,
+  contractExplorerUrl,
+  newAccount,
   storageKey,
   storageKeyDiffs,
   beforeValue,
@@ -89,11 +93,27 @@ export function ComparisonCard({
       </div>
 
       <div className="mb-4 rounded-xl bg-gray-50 p-4 border border-[var(--cds-border)]">
-        <h4 className="mb-1 text-sm font-semibold text-[var(--cds-text-primary)]">
+        <h4 className="mb-1 flex items-center gap-2 text-sm font-semibold text-[var(--cds-text-primary)]">
           {contractName}
+          {newAccount && (
+            <Badge variant="warning" size="sm">
+              New account
+            </Badge>
+          )}
         </h4>
         <p className="m-0 break-all font-mono text-[10px] text-[var(--cds-text-secondary)]">
-          {toChecksumAddressSafe(contractAddress)}
+          {contractExplorerUrl ? (
+            <a
+              href={contractExplorerUrl}
+              target="_blank"
+              rel="noopener noreferrer"
+              className="underline hover:text-[var(--cds-primary)]"
+            >
+              {toChecksumAddressSafe(contractAddress)}
+            </a>
+          ) : (
+            toChecksumAddressSafe(contractAddress)
+          )}
         </p>
       </div>
 
@@ -107,7 +127,7 @@ export function ComparisonCard({
 
         {beforeValue && (
           <ValueSection
-            label="Before"
+            label={newAccount ? 'Before (new account, starts empty)' : 'Before'}
             value={beforeValue}
             diffs={beforeValueDiffs}
             shouldWrap={shouldWrap}
@@ -1,6 +1,7 @@
 import { useState } from 'react';
 import { ArrowRight, Lightbulb, XCircle } from 'lucide-react';
 import type { LedgerSigningResult } from '@/lib/ledger-signing';
+import { computeSafeTxHash } from '@/lib/safe-hash';
 import { Card } from './ui/Card';
 import { Button } from './ui/Button';
 import { Badge } from './ui/Badge';
@@ -47,6 +48,8 @@ export function LedgerSigning({
 
   const displayDomainHash = domainHash.toUpperCase();
   const displayMessageHash = messageHash.toUpperCase();
+  const displaySafeTxHash =
+    domainHash && messageHash ? computeSafeTxHash(domainHash, messageHash).toUpperCase() : '';
 
   const missingFields = [!domainHash && 'domainHash', !messageHash && 'messageHash'].filter(
     Boolean
@@ -197,6 +200,20 @@ export function LedgerSigning({
                   {displayMessageHash}
                 </div>
               </div>
+
+              {displaySafeTxHash && (
+                <div>
+                  <strong className="text-xs text-yellow-800 uppercase block mb-1">
+                    Safe Tx Hash
+                  </strong>
+                  <div className="font-mono text-xs bg-white border border-yellow-200 rounded-lg p-3 break-all text-gray-700">
+                    {displaySafeTxHash}
+                  </div>
+                  <p className="text-xs text-yellow-700 mt-1">
+                    Should match the transaction hash shown in the Safe web interface.
+                  </p>
+                </div>
+              )}
             </div>
 
             {errorMessage && (
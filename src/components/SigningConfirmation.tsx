@@ -14,6 +14,8 @@ interface SigningConfirmationProps {
   };
   signingData?: LedgerSigningResult | null;
   onBackToSetup: () => void;
+  // Present when the server keeps a ceremony log; signs it with the Ledger and closes it
+  onSignCeremonyLog?: () => Promise<void>;
 }
 
 type DetailItem = {
@@ -28,8 +30,12 @@ export function SigningConfirmation({
   selectedUpgrade,
   signingData,
   onBackToSetup,
+  onSignCeremonyLog,
 }: SigningConfirmationProps) {
   const [copied, setCopied] = useState(false);
+  const [ceremonyLogStatus, setCeremonyLogStatus] = useState<
+    'idle' | 'signing' | 'signed' | 'error'
+  >('idle');
   const resetTimeoutRef = useRef<ReturnType<typeof setTimeout> | null>(null);
 
   useEffect(() => {
@@ -65,6 +71,20 @@ export function SigningConfirmation({
     }
   };
 
+  const handleSignCeremonyLog = async () => {
+    if (!onSignCeremonyLog) {
+      return;
+    }
+
+    setCeremonyLogStatus('signing');
+    try {
+      await onSignCeremonyLog();
+      setCeremonyLogStatus('signed');
+    } catch {
+      setCeremonyLogStatus('error');
+    }
+  };
+
   const summaryItems: DetailItem[] = [
     {
       label: 'User Type',
@@ -211,6 +231,21 @@ export function SigningConfirmation({
       )}
 
       <div className="flex flex-col sm:flex-row justify-center gap-4 pt-8 border-t border-[var(--cds-divider)]">
+        {onSignCeremonyLog && (
+          <Button
+            onClick={handleSignCeremonyLog}
+            variant="secondary"
+            disabled={ceremonyLogStatus === 'signing' || ceremonyLogStatus === 'signed'}
+          >
+            {ceremonyLogStatus === 'signing'
+              ? 'Confirm on Ledger...'
+              : ceremonyLogStatus === 'signed'
+                ? 'Ceremony Log Signed'
+                : ceremonyLogStatus === 'error'
+                  ? 'Retry Signing Ceremony Log'
+                  : 'Sign Ceremony Log'}
+          </Button>
+        )}
         <Button onClick={onBackToSetup} variant="primary">
           Start New Validation
         </Button>
@@ -1,4 +1,4 @@
-import React, { useEffect, useState } from 'react';
+import React, { useEffect, useRef, useState } from 'react';
 import {
   AlertTriangle,
   ArrowLeft,
@@ -19,9 +19,12 @@ import {
   getStepInfo,
   STEP_LABELS,
   TASK_ORIGIN_ROLE_LABELS,
+  ValidationEntryEvaluation,
   ValidationNavEntry,
 } from '@/lib/validation-results-utils';
-import { TaskOriginSignerResult, ValidationData } from '@/lib/types';
+import { describeContractOwners, describeQuorum } from '@/lib/safe-quorum';
+import { explorerTxUrl } from '@/lib/explorers';
+import { ReportWarning, TaskOriginSignerResult, ValidationData } from '@/lib/types';
 import { ComparisonCard } from './ComparisonCard';
 import { Card } from './ui/Card';
 import { Button } from './ui/Button';
@@ -102,11 +105,38 @@ const TaskOriginSkippedCard: React.FC = () => {
   );
 };
 
+interface SimulationWarningsCardProps {
+  warnings: ReportWarning[];
+}
+
+const SimulationWarningsCard: React.FC<SimulationWarningsCardProps> = ({ warnings }) => {
+  return (
+    <div className="rounded-xl border border-yellow-200 bg-yellow-50 p-4">
+      <div className="flex items-center gap-2 mb-2">
+        <AlertTriangle className="text-yellow-600" size={20} />
+        <h3 className="text-sm font-bold uppercase tracking-wider text-yellow-800">
+          Simulation Warnings
+        </h3>
+      </div>
+      <ul className="list-disc pl-6 space-y-1 text-sm text-yellow-800 break-words">
+        {warnings.map((warning, idx) => (
+          <li key={idx}>
+            <code className="mr-1 rounded bg-yellow-200 px-1 text-xs">{warning.code}</code>
+            {warning.message}
+          </li>
+        ))}
+      </ul>
+    </div>
+  );
+};
+
 interface ValidationResultsProps {
   userType: string;
   network: string;
   upgradeId: string;
   onProceedToLedgerSigning: (validationResult: ValidationData) => void;
+  // Called once each time the signer navigates to an item, with exactly what is displayed
+  onItemViewed?: (entry: ValidationNavEntry, evaluation: ValidationEntryEvaluation) => void;
 }
 
 const getIcon = (iconName: string, size: number = 24, className?: string) => {
@@ -130,8 +160,10 @@ export const ValidationResults: React.FC<ValidationResultsProps> = ({
   network,
   upgradeId,
   onProceedToLedgerSigning,
+  onItemViewed,
 }) => {
   const [currentIndex, setCurrentIndex] = useState(0);
+  const lastViewedRef = useRef<string | null>(null);
   const [isResultModalOpen, setIsResultModalOpen] = useState(false);
 
   const {
@@ -176,6 +208,15 @@ export const ValidationResults: React.FC<ValidationResultsProps> = ({
   const currentEntry: ValidationNavEntry | undefined = navList[currentIndex];
   const evaluation = currentEntry ? evaluateValidationEntry(currentEntry, itemsByStep) : null;
   const totalItems = navList.length;
+
+  useEffect(() => {
+    if (!onItemViewed || !currentEntry || !evaluation) return;
+    const key = `${currentEntry.kind}:${currentEntry.index}`;
+    if (lastViewedRef.current === key) return;
+    lastViewedRef.current = key;
+    onItemViewed(currentEntry, evaluation);
+  });
+
   const stepInfo = getStepInfo(currentEntry, stepCounts);
 
   if (isLoading) {
@@ -248,6 +289,47 @@ export const ValidationResults: React.FC<ValidationResultsProps> = ({
           <h2 className="text-3xl font-bold text-[var(--cds-text-primary)] tracking-tight mb-2">
             Validation Results
           </h2>
+          {validationResult.chainName && (
+            <div className="text-sm text-[var(--cds-text-secondary)]">
+              Simulated on{' '}
+              <span className="font-semibold text-[var(--cds-text-primary)]">
+                {validationResult.chainName}
+              </span>{' '}
+              (chain ID {validationResult.chainId})
+            </div>
+          )}
+          {validationResult.quorum && (
+            <div className="text-sm text-[var(--cds-text-secondary)]">
+              {describeQuorum(validationResult.quorum)}
+              {describeContractOwners(validationResult.quorum).map(line => (
+                <div key={line}>{line}</div>
+              ))}
+              {validationResult.quorum.approvals?.map(approval => {
+                // From the explorers configured for the simulated chain, like the contract links
+                const url =
+                  validationResult.chainId !== undefined
+                    ? explorerTxUrl(String(validationResult.chainId), approval.txHash)
+                    : undefined;
+                return (
+                  <div key={approval.txHash} className="break-all">
+                    {approval.owner} approved on-chain in{' '}
+                    {url ? (
+                      <a
+                        href={url}
+                        target="_blank"
+                        rel="noopener noreferrer"
+                        className="font-mono underline hover:text-[var(--cds-primary)]"
+                      >
+                        {approval.txHash}
+                      </a>
+                    ) : (
+                      <span className="font-mono">{approval.txHash}</span>
+                    )}
+                  </div>
+                );
+              })}
+            </div>
+          )}
           <div className="flex items-center gap-4 mt-2 w-full max-w-md">
             <div className="h-2 flex-1 rounded-full bg-gray-200 overflow-hidden">
               <div
@@ -271,6 +353,10 @@ export const ValidationResults: React.FC<ValidationResultsProps> = ({
         </div>
       </div>
 
+      {validationResult.warnings && validationResult.warnings.length > 0 && (
+        <SimulationWarningsCard warnings={validationResult.warnings} />
+      )}
+
       <Card className="bg-gray-50/50">
         <div className="flex items-center justify-between gap-4 mb-6">
           <Button
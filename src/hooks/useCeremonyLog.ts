@@ -0,0 +1,62 @@
+import { useCallback, useState } from 'react';
+
+import type { CeremonyDetails, CeremonyEntry, CeremonyEvent } from '@/lib/ceremony-log';
+
+type CeremonyResponse = {
+  enabled?: boolean;
+  entry?: CeremonyEntry;
+  error?: string;
+};
+
+type RecordOptions = {
+  artifact?: unknown;
+  details?: CeremonyDetails;
+  sign?: boolean;
+  ledgerAccount?: number;
+};
+
+const newSessionId = () => crypto.randomUUID();
+
+interface UseCeremonyLogReturn {
+  // Null until the server has answered once; false when CEREMONY_LOG_DIR is not configured
+  enabled: boolean | null;
+  record: (event: CeremonyEvent, options?: RecordOptions) => Promise<CeremonyEntry | null>;
+  startNewSession: () => void;
+}
+
+/**
+ * Records what the signer saw and acknowledged to the server's ceremony log. Logging never
+ * blocks the signing flow: failures are reported to the console and the flow carries on.
+ */
+export const useCeremonyLog = (): UseCeremonyLogReturn => {
+  const [sessionId, setSessionId] = useState(newSessionId);
+  const [enabled, setEnabled] = useState<boolean | null>(null);
+
+  const record = useCallback(
+    async (event: CeremonyEvent, options: RecordOptions = {}) => {
+      if (enabled === false) return null;
+      try {
+        const response = await fetch('/api/ceremony', {
+          method: 'POST',
+          headers: {
+            'Content-Type': 'application/json',
+          },
+          body: JSON.stringify({ sessionId, event, ...options }),
+        });
+        const body = (await response.json()) as CeremonyResponse;
+        if (body.error) throw new Error(body.error);
+        setEnabled(body.enabled ?? false);
+        return body.entry ?? null;
+      } catch (error) {
+        console.error(`Failed to record ceremony event ${event}`, error);
+        if (options.sign) throw error;
+        return null;
+      }
+    },
+    [enabled, sessionId]
+  );
+
+  const startNewSession = useCallback(() => setSessionId(newSessionId()), []);
+
+  return { enabled, record, startNewSession };
+};
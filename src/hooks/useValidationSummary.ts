@@ -23,7 +23,7 @@ export const useValidationSummary = (validationResult: ValidationData | null): V
   useMemo(() => {
     const itemsByStep = buildValidationItems(validationResult);
     const navList = buildNavList(itemsByStep);
-    const blockingErrorsExist = hasBlockingErrors(itemsByStep);
+    const blockingErrorsExist = hasBlockingErrors(itemsByStep, validationResult?.warnings);
     const stepCounts = getStepCounts(itemsByStep);
 
     // Determine task origin validation state
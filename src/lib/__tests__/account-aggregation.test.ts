@@ -0,0 +1,171 @@
+import { describe, expect, it } from '@jest/globals';
+import { aggregateAccountAccesses } from '../account-aggregation';
+import { AccountAccessKind, VmSafeAccountAccess, VmSafeStorageAccess } from '../vm-safe';
+
+const SAFE = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const PROXY = '0x1111111111111111111111111111111111111111';
+const IMPL = '0x2222222222222222222222222222222222222222';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const write = (account: string, slot: number, previous: number, next: number, reverted = false) =>
+  ({
+    account,
+    slot: word(slot),
+    isWrite: true,
+    previousValue: word(previous),
+    newValue: word(next),
+    reverted,
+  }) as VmSafeStorageAccess;
+
+const access = (overrides: Partial<VmSafeAccountAccess>): VmSafeAccountAccess => ({
+  chainInfo: { forkId: BigInt(0), chainId: BigInt(1) },
+  kind: AccountAccessKind.Call,
+  account: SAFE,
+  accessor: SAFE,
+  initialized: true,
+  oldBalance: BigInt(0),
+  newBalance: BigInt(0),
+  deployedCode: '0x',
+  value: BigInt(0),
+  data: '0x',
+  reverted: false,
+  storageAccesses: [],
+  depth: BigInt(0),
+  oldNonce: BigInt(0),
+  newNonce: BigInt(0),
+  ...overrides,
+});
+
+describe('aggregateAccountAccesses', () => {
+  it('attributes delegatecall storage writes to the storage owner, not the callee', () => {
+    const { storage, accounts } = aggregateAccountAccesses([
+      access({ account: PROXY, oldBalance: BigInt(5), newBalance: BigInt(5) }),
+      access({
+        kind: AccountAccessKind.DelegateCall,
+        account: IMPL,
+        // Balances on a delegatecall belong to the proxy executing it
+        oldBalance: BigInt(5),
+        newBalance: BigInt(7),
+        storageAccesses: [write(PROXY, 1, 0, 3)],
+      }),
+    ]);
+
+    expect(Array.from(storage.keys())).toEqual([PROXY]);
+    expect(storage.get(PROXY)?.storageDiffs.get(word(1))).toEqual({
+      key: word(1),
+      before: word(0),
+      after: word(3),
+      intermediate: [],
+      writes: [{ value: word(3), writer: IMPL, depth: 0, reverted: false }],
+    });
+    expect(accounts.has(IMPL)).toBe(false);
+  });
+
+  it('keeps the first old value and the latest new value for balances and nonces', () => {
+    const { accounts } = aggregateAccountAccesses([
+      access({
+        oldBalance: BigInt(100),
+        newBalance: BigInt(90),
+        oldNonce: BigInt(4),
+        newNonce: BigInt(5),
+      }),
+      access({ account: PROXY, oldBalance: BigInt(0), newBalance: BigInt(10) }),
+      access({
+        oldBalance: BigInt(90),
+        newBalance: BigInt(60),
+        oldNonce: BigInt(5),
+        newNonce: BigInt(5),
+      }),
+    ]);
+
+    expect(accounts.get(SAFE)).toEqual({
+      address: SAFE,
+      balance: { before: BigInt(100), after: BigInt(60) },
+      nonce: { before: BigInt(4), after: BigInt(5) },
+    });
+    expect(accounts.get(PROXY)?.balance).toEqual({ before: BigInt(0), after: BigInt(10) });
+  });
+
+  it('drops accounts and slots that end where they started', () => {
+    const { storage, accounts } = aggregateAccountAccesses([
+      access({
+        oldBalance: BigInt(1),
+        newBalance: BigInt(2),
+        storageAccesses: [write(SAFE, 4, 1, 2)],
+      }),
+      access({
+        oldBalance: BigInt(2),
+        newBalance: BigInt(1),
+        storageAccesses: [write(SAFE, 4, 2, 1)],
+      }),
+    ]);
+
+    expect(storage.size).toBe(0);
+    expect(accounts.size).toBe(0);
+  });
+
+  it('uses the first previous value and the last non-reverted write for a slot', () => {
+    const { storage } = aggregateAccountAccesses([
+      access({ storageAccesses: [write(SAFE, 4, 1, 2), write(SAFE, 4, 2, 3)] }),
+      access({ depth: BigInt(1), storageAccesses: [write(SAFE, 4, 3, 5)] }),
+      access({ storageAccesses: [write(SAFE, 4, 5, 9, true)] }),
+      access({ reverted: true, storageAccesses: [write(SAFE, 4, 5, 8)] }),
+    ]);
+
+    expect(storage.get(SAFE)?.storageDiffs.get(word(4))).toEqual({
+      key: word(4),
+      before: word(1),
+      after: word(5),
+      intermediate: [word(2), word(3)],
+      writes: [
+        { value: word(2), writer: SAFE, depth: 0, reverted: false },
+        { value: word(3), writer: SAFE, depth: 0, reverted: false },
+        { value: word(5), writer: SAFE, depth: 1, reverted: false },
+        { value: word(9), writer: SAFE, depth: 0, reverted: true },
+        { value: word(8), writer: SAFE, depth: 0, reverted: true },
+      ],
+    });
+  });
+
+  it('drops slots whose only writes were reverted', () => {
+    const { storage } = aggregateAccountAccesses([
+      access({ storageAccesses: [write(SAFE, 4, 1, 2, true)] }),
+    ]);
+
+    expect(storage.size).toBe(0);
+  });
+
+  it('ignores the balance and nonce of reverted accesses', () => {
+    const { accounts } = aggregateAccountAccesses([
+      access({ oldBalance: BigInt(100), newBalance: BigInt(90) }),
+      // A call that sent ETH and bumped the nonce, then reverted
+      access({
+        oldBalance: BigInt(90),
+        newBalance: BigInt(0),
+        oldNonce: BigInt(0),
+        newNonce: BigInt(1),
+        reverted: true,
+      }),
+      access({ account: PROXY, oldBalance: BigInt(5), newBalance: BigInt(50), reverted: true }),
+    ]);
+
+    expect(accounts.get(SAFE)).toEqual({
+      address: SAFE,
+      balance: { before: BigInt(100), after: BigInt(90) },
+      nonce: { before: BigInt(0), after: BigInt(0) },
+    });
+    expect(accounts.has(PROXY)).toBe(false);
+  });
+
+  it('marks accounts the first access found uninitialized as new', () => {
+    const { newAccounts } = aggregateAccountAccesses([
+      access({ account: PROXY, initialized: false, storageAccesses: [write(PROXY, 1, 0, 3)] }),
+      access({ account: SAFE }),
+      // Later accesses see the account the run created
+      access({ account: PROXY }),
+      access({ account: SAFE, initialized: false }),
+    ]);
+
+    expect(Array.from(newAccounts)).toEqual([PROXY]);
+  });
+});
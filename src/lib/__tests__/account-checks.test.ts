@@ -0,0 +1,57 @@
+import { describe, expect, it, jest } from '@jest/globals';
+import type { Address, Hex } from 'viem';
+import { classifyAddress, findSuspiciousWrites } from '../account-checks';
+
+const CONTRACT = '0x73a79fab69143498ed3712e519a88a918e1f4072';
+const EOA = '0x1234567890123456789012345678901234567890';
+
+function mockClient(codeByAddress: Record<string, Hex | undefined>) {
+  return {
+    getCode: jest.fn(
+      async ({ address }: { address: Address }) => codeByAddress[address.toLowerCase()]
+    ),
+  };
+}
+
+describe('classifyAddress', () => {
+  it('recognizes precompiles, predeploys, and system addresses', () => {
+    expect(classifyAddress('0x0000000000000000000000000000000000000001').kind).toBe('precompile');
+    expect(classifyAddress('0x4200000000000000000000000000000000000015').kind).toBe('predeploy');
+    expect(classifyAddress('0xfffffffffffffffffffffffffffffffffffffffe').kind).toBe('system');
+    expect(classifyAddress(CONTRACT).kind).toBe('regular');
+  });
+
+  it('does not treat the zero address as a precompile', () => {
+    expect(classifyAddress('0x0000000000000000000000000000000000000000').kind).toBe('regular');
+  });
+});
+
+describe('findSuspiciousWrites', () => {
+  it('warns about writes to accounts without code', async () => {
+    const client = mockClient({ [CONTRACT]: '0x6080', [EOA]: '0x' });
+    const warnings = await findSuspiciousWrites(client, [CONTRACT, EOA], new Set());
+
+    expect(warnings).toHaveLength(1);
+    expect(warnings[0]).toMatch(/without code/);
+  });
+
+  it('skips the code check for accounts created during the simulation', async () => {
+    const client = mockClient({});
+    const warnings = await findSuspiciousWrites(client, [EOA], new Set([EOA]));
+
+    expect(warnings).toEqual([]);
+    expect(client.getCode).not.toHaveBeenCalled();
+  });
+
+  it('flags reserved ranges without querying code', async () => {
+    const client = mockClient({});
+    const warnings = await findSuspiciousWrites(
+      client,
+      ['0x4200000000000000000000000000000000000015'],
+      new Set()
+    );
+
+    expect(warnings[0]).toMatch(/predeploy/);
+    expect(client.getCode).not.toHaveBeenCalled();
+  });
+});
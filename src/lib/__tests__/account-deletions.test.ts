@@ -0,0 +1,81 @@
+import { describe, expect, it } from '@jest/globals';
+import { describeAccountDeletion, findAccountDeletions } from '../account-deletions';
+import { parsePrestateTrace, prestateTraceToAccountAccesses } from '../prestate-trace';
+import { AccountAccessKind, VmSafeAccountAccess } from '../vm-safe';
+
+const SAFE = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const TEMP = '0x1111111111111111111111111111111111111111';
+const LEGACY = '0x2222222222222222222222222222222222222222';
+
+const access = (overrides: Partial<VmSafeAccountAccess>): VmSafeAccountAccess => ({
+  chainInfo: { forkId: BigInt(0), chainId: BigInt(1) },
+  kind: AccountAccessKind.Call,
+  account: SAFE,
+  accessor: SAFE,
+  initialized: true,
+  oldBalance: BigInt(0),
+  newBalance: BigInt(0),
+  deployedCode: '0x',
+  value: BigInt(0),
+  data: '0x',
+  reverted: false,
+  storageAccesses: [],
+  depth: BigInt(0),
+  oldNonce: BigInt(0),
+  newNonce: BigInt(0),
+  ...overrides,
+});
+
+const selfDestruct = (contract: string, value: number, reverted = false) =>
+  access({
+    kind: AccountAccessKind.SelfDestruct,
+    accessor: contract,
+    account: SAFE,
+    value: BigInt(value),
+    reverted,
+  });
+
+describe('findAccountDeletions', () => {
+  it('removes the code of contracts created in the same transaction', () => {
+    const deletions = findAccountDeletions([
+      access({ kind: AccountAccessKind.Create, account: TEMP, deployedCode: '0x6080' }),
+      selfDestruct(TEMP, 5),
+    ]);
+
+    expect(deletions).toEqual([
+      {
+        address: TEMP,
+        beneficiary: SAFE,
+        sweptBalance: BigInt(5),
+        codeRemoved: true,
+        removedCode: '0x6080',
+      },
+    ]);
+  });
+
+  it('only sweeps the balance of pre-existing contracts and merges repeats', () => {
+    const [deletion] = findAccountDeletions([selfDestruct(LEGACY, 2), selfDestruct(LEGACY, 3)]);
+
+    expect(deletion.codeRemoved).toBe(false);
+    expect(deletion.sweptBalance).toBe(BigInt(5));
+    expect(describeAccountDeletion(deletion)).toMatch(/remain \(EIP-6780\)/);
+  });
+
+  it('ignores reverted self-destructs', () => {
+    expect(findAccountDeletions([selfDestruct(LEGACY, 2, true)])).toEqual([]);
+  });
+
+  it('picks up accounts deleted in a prestateTracer diff', () => {
+    const accesses = prestateTraceToAccountAccesses(
+      parsePrestateTrace({ pre: { [LEGACY]: { balance: '0x7', code: '0x6080' } }, post: {} })
+    );
+
+    const [deletion] = findAccountDeletions(accesses);
+    expect(deletion).toMatchObject({
+      address: LEGACY,
+      beneficiary: null,
+      sweptBalance: BigInt(7),
+      codeRemoved: true,
+    });
+  });
+});
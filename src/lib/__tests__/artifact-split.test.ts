@@ -0,0 +1,84 @@
+import { describe, expect, it } from '@jest/globals';
+import { mkdirSync, mkdtempSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import path from 'path';
+import { joinValidationFile, readValidationFile, splitValidationFile } from '../artifact-split';
+import { canonicalHash } from '../canonical-json';
+
+const A = '0x' + 'aa'.repeat(20);
+const B = '0x' + 'bb'.repeat(20);
+const SLOT = '0x' + '00'.repeat(32);
+
+const file = {
+  taskName: 'upgrade',
+  stateOverrides: [{ name: 'Safe', address: B, overrides: [] }],
+  stateChanges: [
+    { name: 'Proxy B', address: B, changes: [{ key: SLOT }] },
+    { name: 'Proxy A', address: A.toUpperCase().replace('0X', '0x'), changes: [] },
+  ],
+  balanceChanges: [{ name: 'Proxy A', address: A, before: '0', after: '1' }],
+};
+
+function writeSplit(dir: string) {
+  const outPath = path.join(dir, 'base-sc.json');
+  const split = splitValidationFile(file, outPath);
+  mkdirSync(path.join(dir, split.index.split.dir));
+  for (const part of split.parts) {
+    writeFileSync(path.join(dir, split.index.split.dir, part.file), JSON.stringify(part.content));
+  }
+  writeFileSync(outPath, JSON.stringify(split.index));
+  return { outPath, split };
+}
+
+describe('splitValidationFile', () => {
+  it('writes one part per contract and an index the parser refuses on its own', () => {
+    const { index, parts } = splitValidationFile(file, '/tmp/validations/base-sc.json');
+    expect(parts.map(p => p.file)).toEqual([`${A}.json`, `${B}.json`]);
+    expect(index).not.toHaveProperty('stateChanges');
+    expect(index).not.toHaveProperty('stateOverrides');
+    expect(index.split).toMatchObject({
+      dir: 'base-sc.parts',
+      counts: { stateOverrides: 1, stateChanges: 2, balanceChanges: 1 },
+    });
+  });
+
+  it('joins back to the same content', () => {
+    const { index, parts } = splitValidationFile(file, 'base-sc.json');
+    const byFile = new Map(parts.map(p => [p.file, JSON.stringify(p.content)]));
+    const joined = joinValidationFile(index, f => byFile.get(f)!);
+    expect(canonicalHash(joined)).toBe(canonicalHash(file));
+  });
+
+  it('refuses a part that was edited or left out', () => {
+    const { index, parts } = splitValidationFile(file, 'base-sc.json');
+    const edited = { ...parts[0].content, address: B };
+    expect(() => joinValidationFile(index, () => JSON.stringify(edited))).toThrow(
+      /does not match the hash in the index/
+    );
+    const short = { ...index, split: { ...index.split, parts: index.split.parts.slice(1) } };
+    const byFile = new Map(parts.map(p => [p.file, JSON.stringify(p.content)]));
+    expect(() => joinValidationFile(short, f => byFile.get(f)!)).toThrow(
+      'Split parts are missing stateChanges entries'
+    );
+  });
+});
+
+describe('readValidationFile', () => {
+  it('joins a split file and returns other files unchanged', async () => {
+    const dir = mkdtempSync(path.join(tmpdir(), 'artifact-split-'));
+    const { outPath } = writeSplit(dir);
+    expect(canonicalHash(JSON.parse(await readValidationFile(outPath)))).toBe(canonicalHash(file));
+
+    const plain = path.join(dir, 'plain.json');
+    writeFileSync(plain, 'not json');
+    expect(await readValidationFile(plain)).toBe('not json');
+  });
+
+  it('refuses an index whose parts directory leaves its folder', async () => {
+    const dir = mkdtempSync(path.join(tmpdir(), 'artifact-split-'));
+    const { outPath, split } = writeSplit(dir);
+    const index = { ...split.index, split: { ...split.index.split, dir: '../x.parts' } };
+    writeFileSync(outPath, JSON.stringify(index));
+    await expect(readValidationFile(outPath)).rejects.toThrow(/Invalid split index: split\.dir/);
+  });
+});
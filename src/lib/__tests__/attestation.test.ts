@@ -0,0 +1,34 @@
+import { describe, expect, it } from '@jest/globals';
+import { privateKeyToAccount } from 'viem/accounts';
+import { canonicalizeForAttestation, signAttestation, verifyAttestation } from '../attestation';
+
+const KEY = '0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d';
+const OTHER = '0x1111111111111111111111111111111111111111';
+const file = { cmd: 'forge script Run', ledgerId: 0, stateChanges: [{ b: 2, a: 1 }] };
+
+describe('attestation', () => {
+  it('canonicalizes with sorted keys and ignores the attestation field', () => {
+    expect(canonicalizeForAttestation({ b: { d: 1, c: 2 }, a: [], attestation: {} })).toBe(
+      '{"a":[],"b":{"c":2,"d":1}}'
+    );
+  });
+
+  it('round-trips a keystore-style signature', async () => {
+    const attestation = await signAttestation(file, { kind: 'privateKey', privateKey: KEY });
+    const signed = JSON.parse(JSON.stringify({ ...file, attestation }));
+
+    expect(attestation.signer).toBe(privateKeyToAccount(KEY).address);
+    await expect(verifyAttestation(signed, attestation.signer)).resolves.toMatchObject({
+      valid: true,
+    });
+    await expect(verifyAttestation(signed, OTHER)).resolves.toMatchObject({ valid: false });
+  });
+
+  it('rejects files modified after signing', async () => {
+    const attestation = await signAttestation(file, { kind: 'privateKey', privateKey: KEY });
+    const tampered = { ...file, cmd: 'forge script Evil', attestation };
+
+    const result = await verifyAttestation(tampered);
+    expect(result.valid).toBe(false);
+  });
+});
@@ -0,0 +1,68 @@
+import { describe, expect, it } from '@jest/globals';
+import { mkdirSync, mkdtempSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import path from 'path';
+import { findTaskValidationFiles, formatBatchSummary, runBounded, taskWorkdir } from '../batch';
+
+const tick = () => new Promise(resolve => setTimeout(resolve, 5));
+
+describe('runBounded', () => {
+  it('never runs more than the limit at once', async () => {
+    let active = 0;
+    let peak = 0;
+    await runBounded([1, 2, 3, 4, 5], 2, async () => {
+      active++;
+      peak = Math.max(peak, active);
+      await tick();
+      active--;
+    });
+    expect(peak).toBe(2);
+  });
+
+  it('keeps going after a failure and reports outcomes in input order', async () => {
+    const outcomes = await runBounded(['a', 'b', 'c'], 3, async item => {
+      if (item === 'b') throw new Error('forge failed');
+    });
+    expect(outcomes).toEqual([
+      { item: 'a', ok: true },
+      { item: 'b', ok: false, error: 'forge failed' },
+      { item: 'c', ok: true },
+    ]);
+  });
+});
+
+describe('findTaskValidationFiles', () => {
+  it('lists validation files for every network', () => {
+    const task = mkdtempSync(path.join(tmpdir(), 'batch-'));
+    for (const network of ['sepolia', 'mainnet']) {
+      const dir = path.join(task, 'config', network, 'validations');
+      mkdirSync(dir, { recursive: true });
+      writeFileSync(path.join(dir, 'base-sc.json'), '{}');
+      writeFileSync(path.join(dir, 'notes.txt'), '');
+    }
+    expect(findTaskValidationFiles(task)).toEqual([
+      path.join(task, 'config', 'mainnet', 'validations', 'base-sc.json'),
+      path.join(task, 'config', 'sepolia', 'validations', 'base-sc.json'),
+    ]);
+  });
+});
+
+describe('taskWorkdir', () => {
+  it('returns the directory holding tasks/', () => {
+    expect(taskWorkdir('/repo/active/evm/tasks/2025-01-01-upgrade/')).toBe('/repo/active/evm');
+  });
+
+  it('rejects directories outside tasks/', () => {
+    expect(() => taskWorkdir('/repo/active/evm')).toThrow('not inside a tasks/ directory');
+  });
+});
+
+describe('formatBatchSummary', () => {
+  it('counts successes and lists failures', () => {
+    const summary = formatBatchSummary([
+      { item: 'a.json', ok: true },
+      { item: 'b.json', ok: false, error: 'forge failed' },
+    ]);
+    expect(summary).toBe('1 of 2 file(s) regenerated\n  ❌ b.json: forge failed');
+  });
+});
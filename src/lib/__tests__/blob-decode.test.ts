@@ -0,0 +1,86 @@
+import { describe, expect, it } from '@jest/globals';
+import { blobFromFile, decodeBlob, formatDecodedBlob, isBlobKind } from '../blob-decode';
+import {
+  accountAccess,
+  mappingPreimage,
+  payload,
+  simulationArtifact,
+  storageWrite,
+  syntheticAddress,
+  word,
+} from '../state-diff-test';
+import { AccountAccessKind } from '../vm-safe';
+
+const PORTAL = syntheticAddress(1);
+
+const artifact = simulationArtifact({
+  accesses: [
+    accountAccess({
+      kind: AccountAccessKind.DelegateCall,
+      account: PORTAL,
+      newBalance: BigInt('1000000000000000000000'),
+      storageAccesses: [storageWrite(PORTAL, 1, 0, 2)],
+    }),
+  ],
+  preimages: [mappingPreimage(0, 7)],
+  payload: payload({
+    to: PORTAL,
+    stateOverrides: [{ contractAddress: PORTAL, overrides: [{ key: word(4), value: word(1) }] }],
+  }),
+});
+const stateDiffJson = JSON.stringify(artifact.stateDiff, null, 2);
+
+describe('blobFromFile', () => {
+  it('reads a bare blob, a stateDiff.json field, or a validation file raw field', () => {
+    const { stateDiff, preimages } = artifact.stateDiff;
+
+    expect(blobFromFile('statediff', `${stateDiff}\n`, 'blob.txt')).toBe(stateDiff);
+    expect(blobFromFile('preimages', stateDiffJson, 'stateDiff.json')).toBe(preimages);
+    const validation = JSON.stringify({ cmd: 'forge script', raw: artifact.stateDiff });
+    expect(blobFromFile('statediff', validation, 'base-sc.json')).toBe(stateDiff);
+  });
+
+  it('names the file and field when the blob is missing', () => {
+    expect(() => blobFromFile('overrides', '{"cmd": "forge script"}', 'base-sc.json')).toThrow(
+      'base-sc.json has no string field "overrides"'
+    );
+  });
+});
+
+describe('decodeBlob', () => {
+  it('decodes each kind from a stateDiff.json', () => {
+    const decode = (kind: 'overrides' | 'statediff' | 'preimages') =>
+      decodeBlob(kind, blobFromFile(kind, stateDiffJson, 'stateDiff.json'));
+
+    expect(decode('overrides')).toMatchObject({
+      to: PORTAL,
+      stateOverrides: [{ contractAddress: PORTAL, overrides: [{ key: word(4), value: word(1) }] }],
+    });
+    expect(decode('statediff')).toMatchObject([
+      { kind: 'DelegateCall (1)', account: PORTAL, storageAccesses: [{ slot: word(1) }] },
+    ]);
+    expect(decode('preimages')).toEqual([mappingPreimage(0, 7)]);
+  });
+
+  it('refuses input that is not hex', () => {
+    expect(() => decodeBlob('statediff', 'stateDiff.json')).toThrow('Blob must be 0x-prefixed hex');
+  });
+});
+
+describe('formatDecodedBlob', () => {
+  it('writes amounts as decimal strings', () => {
+    const decoded = decodeBlob('statediff', artifact.stateDiff.stateDiff);
+
+    expect(formatDecodedBlob(decoded)).toContain('"newBalance": "1000000000000000000000"');
+    expect(JSON.parse(formatDecodedBlob(decoded))[0].depth).toBe('1');
+  });
+});
+
+describe('isBlobKind', () => {
+  it('accepts the three kinds only', () => {
+    expect(['overrides', 'statediff', 'preimages'].every(isBlobKind)).toBe(true);
+    expect(isBlobKind('stateDiff')).toBe(false);
+    expect(isBlobKind('toString')).toBe(false);
+    expect(isBlobKind(undefined)).toBe(false);
+  });
+});
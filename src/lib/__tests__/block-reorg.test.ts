@@ -0,0 +1,67 @@
+import { describe, expect, it } from '@jest/globals';
+import { pad, toHex } from 'viem';
+import { checkSimulatedBlock } from '../block-reorg';
+import { StateDiffClient } from '../state-diff';
+import { FAKE_RPC_URL, fakeRpc, simulationArtifact } from '../state-diff-test';
+
+const simulatedAt = {
+  blockNumber: 90,
+  blockHash: pad(toHex(90)),
+  blockTimestamp: 1700000000,
+  generatedAt: '2026-10-01T00:00:00.000Z',
+};
+
+describe('checkSimulatedBlock', () => {
+  it('accepts a block that is still canonical', async () => {
+    const { transport } = fakeRpc({ blockNumber: 100 });
+    expect(await checkSimulatedBlock(transport, simulatedAt)).toBeNull();
+  });
+
+  it('warns when a reorg replaced the block', async () => {
+    const reorged = pad('0xbeef');
+    const { transport } = fakeRpc({ blockNumber: 100, blockHashes: { 90: reorged } });
+    expect(await checkSimulatedBlock(transport, simulatedAt)).toBe(
+      `The simulated block 90 was reorged out: the file was built on ${simulatedAt.blockHash}, but the canonical block is now ${reorged}. The prestate it was validated against may have changed; regenerate the file`
+    );
+  });
+
+  it('warns when the RPC does not have the block, and skips files without a hash', async () => {
+    const { transport, calls } = fakeRpc({ blockNumber: 80 });
+    expect(await checkSimulatedBlock(transport, simulatedAt)).toMatch(
+      /^The RPC does not know the simulated block 90/
+    );
+    const { blockHash: _, ...older } = simulatedAt;
+    calls.length = 0;
+    expect(await checkSimulatedBlock(transport, older)).toBeNull();
+    expect(calls).toEqual([]);
+  });
+});
+
+describe('simulatedAt', () => {
+  it('records the block a pinned run forked from, not the head', async () => {
+    const forked = pad('0xf0');
+    const rpc = fakeRpc({ blockNumber: 100, blockHashes: { 90: forked, 100: pad('0x1ead') } });
+    const client = new StateDiffClient(0, undefined, { transport: rpc.transport });
+    const artifact = simulationArtifact({ cmd: 'forge script Task.s.sol --fork-block-number 90' });
+
+    const { result } = await client.fromSimulationArtifact(FAKE_RPC_URL, artifact);
+
+    expect(result.simulatedAt).toMatchObject({ blockNumber: 90, blockHash: forked });
+    expect(await checkSimulatedBlock(rpc.transport, result.simulatedAt!)).toBeNull();
+    const reorged = fakeRpc({ blockNumber: 100, blockHashes: { 90: pad('0xbeef') } });
+    expect(await checkSimulatedBlock(reorged.transport, result.simulatedAt!)).toMatch(
+      /^The simulated block 90 was reorged out/
+    );
+  });
+
+  it('keeps the block a saved run recorded', async () => {
+    const rpc = fakeRpc({ blockNumber: 100 });
+    const client = new StateDiffClient(0, undefined, { transport: rpc.transport });
+    const artifact = { ...simulationArtifact(), simulatedAt };
+
+    const { result } = await client.fromSimulationArtifact(FAKE_RPC_URL, artifact);
+
+    expect(result.simulatedAt).toEqual(simulatedAt);
+    expect(rpc.calls.map(call => call.method)).not.toContain('eth_getBlockByNumber');
+  });
+});
@@ -0,0 +1,33 @@
+import { describe, expect, it } from '@jest/globals';
+import { keccak256, toBytes } from 'viem';
+import { canonicalJson } from '../canonical-json';
+import { embeddedConfigHash, formatBuildInfo, stampBuildInfo } from '../build-info';
+import contractsCfg from '../config/contracts.json';
+
+describe('build info', () => {
+  it('hashes the embedded config independently of key order', () => {
+    expect(embeddedConfigHash()).toBe(keccak256(toBytes(canonicalJson(contractsCfg))));
+  });
+
+  it('stamps the build date', () => {
+    expect(stampBuildInfo(new Date('2026-01-02T03:04:05Z')).buildDate).toBe(
+      '2026-01-02T03:04:05.000Z'
+    );
+  });
+
+  it('prints one detail per line', () => {
+    const text = formatBuildInfo({
+      tool: 'validation-tool-interface',
+      version: '0.0.0',
+      commit: 'abc123',
+      configHash: '0x' + '00'.repeat(32),
+      buildDate: 'development',
+    });
+    expect(text.split('\n')).toEqual([
+      'validation-tool-interface 0.0.0',
+      'commit:      abc123',
+      `config hash: 0x${'00'.repeat(32)}`,
+      'build date:  development',
+    ]);
+  });
+});
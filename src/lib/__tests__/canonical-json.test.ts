@@ -0,0 +1,62 @@
+import { describe, expect, it } from '@jest/globals';
+import { canonicalHash, canonicalJson, verifyCanonicalHash } from '../canonical-json';
+
+describe('canonicalJson', () => {
+  it('sorts keys at every level and removes whitespace', () => {
+    expect(canonicalJson({ b: { d: 1, c: [true, null] }, a: 'x' })).toBe(
+      '{"a":"x","b":{"c":[true,null],"d":1}}'
+    );
+  });
+
+  it('sorts integer-like keys as strings', () => {
+    expect(canonicalJson({ '8453': 'base', '10': 'op', '1': 'mainnet', a: 0 })).toBe(
+      '{"1":"mainnet","10":"op","8453":"base","a":0}'
+    );
+  });
+
+  it('orders keys by UTF-16 code units', () => {
+    // RFC 8785 section 3.2.3: U+20AC sorts before U+1F600 (surrogates 0xD83D...)
+    expect(canonicalJson({ '😀': 1, '€': 2, '\r': 3 })).toBe('{"\\r":3,"€":2,"😀":1}');
+  });
+
+  it('writes numbers and strings the way ECMAScript does', () => {
+    expect(canonicalJson([1e21, 1e-7, -0, 0.1 + 0.2, 'a "b'])).toBe(
+      '[1e+21,1e-7,0,0.30000000000000004,"a \\"b"]'
+    );
+  });
+
+  it('drops undefined members and writes them as null in arrays', () => {
+    expect(canonicalJson({ a: undefined, b: [undefined] })).toBe('{"b":[null]}');
+  });
+
+  it('rejects values JSON cannot represent exactly', () => {
+    expect(() => canonicalJson({ a: [Number.NaN] })).toThrow(/NaN at a\[0\]/);
+    expect(() => canonicalJson({ a: BigInt(1) })).toThrow(/bigint at a/);
+  });
+});
+
+describe('verifyCanonicalHash', () => {
+  const file = { stateChanges: [], cmd: 'forge script', chainId: 8453 };
+  const hash = canonicalHash(file);
+
+  it('matches a re-indented copy with reordered keys', () => {
+    const reindented = JSON.stringify(
+      { chainId: file.chainId, cmd: file.cmd, stateChanges: file.stateChanges },
+      null,
+      4
+    );
+    expect(verifyCanonicalHash(reindented, hash)).toEqual({ valid: true, hash });
+    const upper = `0x${hash.slice(2).toUpperCase()}`;
+    expect(verifyCanonicalHash(JSON.stringify(file), upper)).toEqual({ valid: true, hash });
+  });
+
+  it('reports a changed file', () => {
+    const result = verifyCanonicalHash(JSON.stringify({ ...file, chainId: 1 }), hash);
+    expect(result).toMatchObject({ valid: false, error: expect.stringMatching(/expected 0x/) });
+  });
+
+  it('reports malformed input', () => {
+    expect(verifyCanonicalHash('{', hash)).toMatchObject({ valid: false, hash: null });
+    expect(verifyCanonicalHash('{}', '0x1234')).toMatchObject({ valid: false, hash: null });
+  });
+});
@@ -0,0 +1,75 @@
+import { describe, expect, it } from '@jest/globals';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { privateKeyToAccount } from 'viem/accounts';
+import { eip712Digest } from '../attestation';
+import {
+  appendCeremonyEntry,
+  ceremonyHeadHashes,
+  ceremonyLogPath,
+  hashArtifact,
+  readCeremonyLog,
+  verifyCeremonyLog,
+} from '../ceremony-log';
+
+const SESSION = 'c0ffee00-1111-2222-3333-444455556666';
+const account = privateKeyToAccount(('0x' + '11'.repeat(32)) as `0x${string}`);
+
+async function tempDir(): Promise<string> {
+  return fs.mkdtemp(path.join(os.tmpdir(), 'ceremony-'));
+}
+
+describe('ceremony log', () => {
+  it('chains entries and hashes the artifacts shown', async () => {
+    const dir = await tempDir();
+    const first = await appendCeremonyEntry(dir, SESSION, {
+      event: 'session_started',
+      details: { network: 'mainnet' },
+    });
+    const second = await appendCeremonyEntry(dir, SESSION, {
+      event: 'acknowledged',
+      artifact: { b: 2, a: 1 },
+    });
+
+    expect(second.seq).toBe(1);
+    expect(second.prevHash).toBe(first.entryHash);
+    expect(second.artifactHash).toBe(hashArtifact({ a: 1, b: 2 }));
+
+    const entries = await readCeremonyLog(ceremonyLogPath(dir, SESSION));
+    expect(await verifyCeremonyLog(entries)).toEqual([]);
+  });
+
+  it('detects edited lines', async () => {
+    const dir = await tempDir();
+    await appendCeremonyEntry(dir, SESSION, { event: 'session_started' });
+    await appendCeremonyEntry(dir, SESSION, { event: 'signed', details: { signer: '0x01' } });
+
+    const entries = await readCeremonyLog(ceremonyLogPath(dir, SESSION));
+    entries[0] = { ...entries[0], timestamp: '2020-01-01T00:00:00.000Z' };
+    expect(await verifyCeremonyLog(entries)).toEqual(['line 1: entry hash mismatch']);
+  });
+
+  it('verifies a signature over the log head', async () => {
+    const dir = await tempDir();
+    const closed = await appendCeremonyEntry(dir, SESSION, { event: 'closed' });
+    const { domainHash, messageHash } = ceremonyHeadHashes(closed.entryHash);
+    const signature = await account.sign({ hash: eip712Digest(domainHash, messageHash) });
+    await appendCeremonyEntry(dir, SESSION, {
+      event: 'log_signed',
+      artifactHash: closed.entryHash,
+      details: { signer: account.address, signature },
+    });
+
+    const entries = await readCeremonyLog(ceremonyLogPath(dir, SESSION));
+    expect(await verifyCeremonyLog(entries)).toEqual([]);
+
+    entries[1].details = { signer: '0x' + '22'.repeat(20), signature };
+    const problems = await verifyCeremonyLog(entries);
+    expect(problems).toContain(`line 2: signature does not match ${'0x' + '22'.repeat(20)}`);
+  });
+
+  it('rejects session ids that could escape the log directory', () => {
+    expect(() => ceremonyLogPath('/tmp', '../../etc/passwd')).toThrow(/session id/);
+  });
+});
@@ -0,0 +1,117 @@
+import { describe, expect, it } from '@jest/globals';
+import { mkdtempSync } from 'fs';
+import os from 'os';
+import path from 'path';
+import { concatHex, Hex, keccak256, toBytes } from 'viem';
+import { privateKeyToAccount, sign } from 'viem/accounts';
+import {
+  describeCeremony,
+  finalizeCeremony,
+  initCeremony,
+  pendingParticipants,
+  readCeremonyFile,
+  submitCeremonySignature,
+  updateCeremonyFile,
+  writeCeremonyFile,
+} from '../ceremony-state';
+
+const KEYS = [
+  '0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d',
+  '0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80',
+  '0x5de4111afa1a4b94908f83103eb1f1706367c2e68ca870fc3fb9a804cdab365a',
+] as const;
+const [LOW, HIGH, OUTSIDER] = KEYS.map(key => privateKeyToAccount(key).address);
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const DOMAIN = keccak256(toBytes('domain'));
+const MESSAGE = keccak256(toBytes('message'));
+const NOW = new Date('2026-01-01T00:00:00Z');
+
+function newCeremony() {
+  return initCeremony({
+    safe: SAFE,
+    domainHash: DOMAIN,
+    messageHash: MESSAGE,
+    participants: [LOW, HIGH, LOW],
+    threshold: 2,
+    now: NOW,
+  });
+}
+
+async function signatureOf(signer: string, hash: string): Promise<Hex> {
+  const key = KEYS.find(k => privateKeyToAccount(k).address === signer)!;
+  return (await sign({ hash: hash as Hex, privateKey: key, to: 'hex' })) as Hex;
+}
+
+describe('initCeremony', () => {
+  it('deduplicates participants and refuses a threshold they cannot meet', () => {
+    const ceremony = newCeremony();
+    expect(ceremony.participants).toEqual([LOW, HIGH]);
+    expect(ceremony.state).toBe('collecting');
+    expect(() => initCeremony({ ...ceremony, participants: [LOW], threshold: 2 })).toThrow(
+      /cannot be met by 1 participant/
+    );
+  });
+});
+
+describe('submitCeremonySignature', () => {
+  it('becomes ready at the threshold and packs the signatures sorted by signer', async () => {
+    let ceremony = newCeremony();
+    const high = await signatureOf(HIGH, ceremony.safeTxHash);
+    const low = await signatureOf(LOW, ceremony.safeTxHash);
+
+    ({ ceremony } = await submitCeremonySignature(ceremony, high, NOW));
+    expect(ceremony.state).toBe('collecting');
+    expect(pendingParticipants(ceremony)).toEqual([LOW]);
+    expect(() => finalizeCeremony(ceremony)).toThrow(/1 of 2 signature\(s\) collected/);
+
+    ({ ceremony } = await submitCeremonySignature(ceremony, low, NOW));
+    expect(describeCeremony(ceremony)).toBe(
+      'ready: 2 of 2 required signature(s) from 2 participant(s), 0 pending'
+    );
+
+    const finalized = finalizeCeremony(ceremony, NOW);
+    const sorted = [LOW, HIGH].sort((a, b) => a.toLowerCase().localeCompare(b.toLowerCase()));
+    const bySigner = { [LOW]: low, [HIGH]: high };
+    expect(finalized.packed).toBe(concatHex(sorted.map(s => bySigner[s])).toLowerCase());
+    expect(finalizeCeremony(finalized)).toBe(finalized);
+    await expect(submitCeremonySignature(finalized, low)).rejects.toThrow(/is finalized/);
+  });
+
+  it('refuses duplicates, non-participants, and signatures of another transaction', async () => {
+    const { ceremony } = await submitCeremonySignature(
+      newCeremony(),
+      await signatureOf(LOW, newCeremony().safeTxHash),
+      NOW
+    );
+    await expect(
+      submitCeremonySignature(ceremony, await signatureOf(LOW, ceremony.safeTxHash))
+    ).rejects.toThrow(/already submitted/);
+    await expect(
+      submitCeremonySignature(ceremony, await signatureOf(OUTSIDER, ceremony.safeTxHash))
+    ).rejects.toThrow(/not a participant/);
+    await expect(
+      submitCeremonySignature(ceremony, await signatureOf(HIGH, keccak256(toBytes('other'))))
+    ).rejects.toThrow(/not a participant/);
+  });
+});
+
+describe('updateCeremonyFile', () => {
+  it('keeps both signatures when they are submitted concurrently', async () => {
+    const file = path.join(mkdtempSync(path.join(os.tmpdir(), 'ceremony-')), 'ceremony.json');
+    const ceremony = newCeremony();
+    await writeCeremonyFile(file, ceremony);
+    const [low, high] = [
+      await signatureOf(LOW, ceremony.safeTxHash),
+      await signatureOf(HIGH, ceremony.safeTxHash),
+    ];
+
+    await Promise.all(
+      [low, high].map(signature =>
+        updateCeremonyFile(file, current => submitCeremonySignature(current, signature, NOW))
+      )
+    );
+    const saved = await readCeremonyFile(file);
+    expect(saved.signatures.map(s => s.signer).sort()).toEqual([LOW, HIGH].sort());
+    expect(saved.state).toBe('ready');
+  });
+});
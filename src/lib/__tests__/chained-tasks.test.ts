@@ -0,0 +1,100 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  buildPrestateOverrides,
+  describePrestateDependency,
+  parsePrestateOverrides,
+  prestateEnvAssignment,
+  prestateFileFromCmd,
+  prestateOverridesPath,
+  unappliedPrestate,
+} from '../chained-tasks';
+import { computeSafeTxHash } from '../safe-hash';
+import type { TaskConfig } from '../types';
+
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const previous = {
+  cmd: 'forge script Upgrade',
+  ledgerId: 0,
+  rpcUrl: 'https://rpc.example',
+  expectedDomainAndMessageHashes: {
+    address: '0x9855054731540A48b28990B63DcF4f33d8AE46A1',
+    domainHash: word(1),
+    messageHash: word(2),
+  },
+  stateOverrides: [],
+  stateChanges: [
+    {
+      name: 'Proxy',
+      address: '0x1111111111111111111111111111111111111111',
+      changes: [
+        { key: word(3), before: word(0), after: word(4), description: 'x', allowDifference: false },
+      ],
+    },
+  ],
+} as TaskConfig;
+
+describe('chained tasks', () => {
+  it('turns the previous state changes into overrides with post values', () => {
+    const overrides = buildPrestateOverrides(previous);
+
+    expect(overrides).toEqual([
+      {
+        contractAddress: '0x1111111111111111111111111111111111111111',
+        overrides: [{ key: word(3), value: word(4) }],
+      },
+    ]);
+  });
+
+  it('names the overrides file in the command instead of inlining it', () => {
+    const file = prestateOverridesPath('tasks/a/validations/a.json');
+    const assignment = prestateEnvAssignment(file);
+
+    expect(file).toBe('tasks/a/validations/a.prestate.json');
+    expect(assignment).toBe('STATE_OVERRIDES_FILE=tasks/a/validations/a.prestate.json');
+    expect(prestateFileFromCmd([assignment, 'forge', 'script'])).toBe(file);
+    expect(prestateFileFromCmd(['forge', 'script'])).toBeNull();
+    expect(() => prestateEnvAssignment('my tasks/a.prestate.json')).toThrow(/whitespace/);
+  });
+
+  it('reads back the overrides file and refuses malformed ones', () => {
+    const overrides = buildPrestateOverrides(previous);
+
+    expect(parsePrestateOverrides(JSON.parse(JSON.stringify(overrides)))).toEqual(overrides);
+    expect(() => parsePrestateOverrides([{ contractAddress: '0x1', overrides: [] }])).toThrow(
+      /Invalid state overrides file: 0.contractAddress/
+    );
+  });
+
+  it('finds overridden slots the run changed from another value', () => {
+    const overrides = buildPrestateOverrides(previous);
+    const changes = (before: string) => [
+      {
+        name: 'Proxy',
+        address: '0x1111111111111111111111111111111111111111',
+        changes: [
+          { key: word(3), before, after: word(5), description: 'x', allowDifference: false },
+        ],
+      },
+    ];
+
+    expect(unappliedPrestate(overrides, changes(word(4)))).toEqual([]);
+    expect(unappliedPrestate(overrides, changes(word(0)))).toEqual([
+      {
+        address: '0x1111111111111111111111111111111111111111',
+        key: word(3),
+        expected: word(4),
+        actual: word(0),
+      },
+    ]);
+    // A slot the run leaves alone has no pre-value to check
+    expect(unappliedPrestate(overrides, [])).toEqual([]);
+  });
+
+  it('records the previous task by file and safeTxHash', () => {
+    expect(describePrestateDependency(previous, 'validations/a.json')).toEqual({
+      file: 'validations/a.json',
+      safeTxHash: computeSafeTxHash(word(1), word(2)),
+    });
+  });
+});
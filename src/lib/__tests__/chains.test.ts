@@ -0,0 +1,67 @@
+import { beforeAll, describe, expect, it } from '@jest/globals';
+import { mkdtempSync, writeFileSync } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CHAIN_REGISTRY_ENV, expandEnvVars, getChainInfo, resolveChain } from '../chains';
+
+describe('getChainInfo', () => {
+  beforeAll(() => {
+    const dir = mkdtempSync(path.join(os.tmpdir(), 'chains-'));
+    const file = path.join(dir, 'chains.json');
+    writeFileSync(
+      file,
+      JSON.stringify({
+        '8453': { name: 'Base (fork)' },
+        '901': { name: 'Devnet', alias: 'devnet', rpcUrl: 'https://rpc.devnet/${DEVNET_KEY}' },
+      })
+    );
+    process.env[CHAIN_REGISTRY_ENV] = file;
+  });
+
+  it('resolves embedded chains with their explorer', () => {
+    expect(getChainInfo('1')).toEqual({
+      chainId: 1,
+      name: 'Ethereum Mainnet',
+      explorerUrl: 'https://etherscan.io',
+      nativeCurrency: { name: 'Ether', symbol: 'ETH', decimals: 18 },
+    });
+  });
+
+  it('merges override entries over the embedded registry', () => {
+    expect(getChainInfo(8453).name).toBe('Base (fork)');
+    expect(getChainInfo(8453).explorerUrl).toBe('https://basescan.org');
+    expect(getChainInfo('901').name).toBe('Devnet');
+  });
+
+  it('names unknown chains by their ID', () => {
+    expect(getChainInfo('999999').name).toBe('Chain 999999');
+  });
+});
+
+describe('resolveChain', () => {
+  it('resolves aliases and chain IDs with their default RPC', () => {
+    expect(resolveChain('Base-Sepolia')).toEqual({
+      chainId: 84532,
+      name: 'Base Sepolia',
+      rpcUrl: 'https://sepolia.base.org',
+    });
+    expect(resolveChain('1').name).toBe('Ethereum Mainnet');
+  });
+
+  it('fills environment variables into registry RPC URLs', () => {
+    expect(resolveChain('devnet', { DEVNET_KEY: 'secret' }).rpcUrl).toBe(
+      'https://rpc.devnet/secret'
+    );
+    expect(() => resolveChain('devnet', {})).toThrow('DEVNET_KEY is not set');
+  });
+
+  it('lists the known aliases for unknown chains', () => {
+    expect(() => resolveChain('optimism')).toThrow(/known chains: .*base-sepolia/);
+  });
+});
+
+describe('expandEnvVars', () => {
+  it('leaves text without placeholders unchanged', () => {
+    expect(expandEnvVars('https://mainnet.base.org', {})).toBe('https://mainnet.base.org');
+  });
+});
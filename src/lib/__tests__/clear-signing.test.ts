@@ -0,0 +1,74 @@
+import { describe, expect, it } from '@jest/globals';
+import { hashDomain } from 'viem';
+import { buildClearSigningDescriptor, clearSigningPath, ERC7730_SCHEMA } from '../clear-signing';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+
+const domainHash = (withChainId: boolean) =>
+  withChainId
+    ? hashDomain({
+        domain: { chainId: 8453, verifyingContract: SAFE },
+        types: {
+          EIP712Domain: [
+            { name: 'chainId', type: 'uint256' },
+            { name: 'verifyingContract', type: 'address' },
+          ],
+        },
+      })
+    : hashDomain({
+        domain: { verifyingContract: SAFE },
+        types: { EIP712Domain: [{ name: 'verifyingContract', type: 'address' }] },
+      });
+
+describe('buildClearSigningDescriptor', () => {
+  it('describes the SafeTx of the Safe on its chain', () => {
+    const descriptor = buildClearSigningDescriptor({
+      chainId: 8453,
+      safe: SAFE.toLowerCase(),
+      domainHash: domainHash(true),
+      intent: 'Sign upgrade-fee-vault',
+    });
+
+    expect(descriptor.$schema).toBe(ERC7730_SCHEMA);
+    expect(descriptor.context.eip712.deployments).toEqual([{ chainId: 8453, address: SAFE }]);
+    const [schema] = descriptor.context.eip712.schemas;
+    expect(schema.types.EIP712Domain.map(f => f.name)).toEqual(['chainId', 'verifyingContract']);
+    expect(schema.types.SafeTx).toHaveLength(10);
+    const format = descriptor.display.formats.SafeTx;
+    expect(format.intent).toBe('Sign upgrade-fee-vault');
+    expect(format.fields.find(f => f.path === 'operation')).toEqual({
+      path: 'operation',
+      label: 'Operation',
+      format: 'enum',
+      params: { $ref: '$.metadata.enums.operation' },
+    });
+    expect(descriptor.metadata.enums.operation).toEqual({ '0': 'Call', '1': 'Delegatecall' });
+  });
+
+  it('uses the domain without a chain ID for Safes before 1.3.0', () => {
+    const descriptor = buildClearSigningDescriptor({
+      chainId: 1,
+      safe: SAFE,
+      domainHash: domainHash(false),
+    });
+    const [schema] = descriptor.context.eip712.schemas;
+    expect(schema.types.EIP712Domain).toEqual([{ name: 'verifyingContract', type: 'address' }]);
+  });
+
+  it("refuses a domain hash that is not the Safe's", () => {
+    expect(() =>
+      buildClearSigningDescriptor({ chainId: 1, safe: SAFE, domainHash: domainHash(true) })
+    ).toThrow(/is not the EIP-712 domain of Safe/);
+  });
+});
+
+describe('clearSigningPath', () => {
+  it('writes next to the validation file', () => {
+    expect(clearSigningPath('/tasks/a/validations/base-sc.json')).toBe(
+      '/tasks/a/validations/base-sc.erc7730.json'
+    );
+    expect(clearSigningPath('/tasks/a/validations/base-sc.yaml')).toBe(
+      '/tasks/a/validations/base-sc.yaml.erc7730.json'
+    );
+  });
+});
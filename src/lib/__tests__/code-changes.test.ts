@@ -0,0 +1,108 @@
+import { describe, expect, it } from '@jest/globals';
+import { Address, getAddress, Hex, keccak256 } from 'viem';
+import { aggregateAccountAccesses } from '../account-aggregation';
+import { describeCodeChange, extractSelectors, findCodeChanges } from '../code-changes';
+import { EIP1967_IMPLEMENTATION_SLOT } from '../slot-knowledge';
+import { AccountAccessKind, VmSafeAccountAccess } from '../vm-safe';
+
+const PROXY = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const OLD_IMPL = '0x1111111111111111111111111111111111111111';
+const NEW_IMPL = '0x2222222222222222222222222222222222222222';
+
+// DUP1 PUSH4 <selector> EQ PUSH2 0x0040 JUMPI
+const dispatch = (selector: string) => `8063${selector}1461004057`;
+// A PUSH32 whose data looks like a dispatcher entry must not count
+const CONSTANT = '7f' + '63deadbeef14'.padEnd(64, '0');
+// PUSH1 0 CALLDATALOAD PUSH1 0xe0 SHR
+const SELECTOR = '0x60003560e01c';
+const OLD_CODE = `${SELECTOR}${dispatch('a9059cbb')}${dispatch('70a08231')}00` as Hex;
+const NEW_CODE = `${SELECTOR}${CONSTANT}${dispatch('70a08231')}${dispatch('3659cfe6')}00` as Hex;
+
+const word = (address: string) => ('0x' + address.slice(2).padStart(64, '0')) as Hex;
+
+const access = (overrides: Partial<VmSafeAccountAccess>): VmSafeAccountAccess => ({
+  chainInfo: { forkId: BigInt(0), chainId: BigInt(1) },
+  kind: AccountAccessKind.Call,
+  account: PROXY,
+  accessor: PROXY,
+  initialized: true,
+  oldBalance: BigInt(0),
+  newBalance: BigInt(0),
+  deployedCode: '0x',
+  value: BigInt(0),
+  data: '0x',
+  reverted: false,
+  storageAccesses: [],
+  depth: BigInt(0),
+  oldNonce: BigInt(0),
+  newNonce: BigInt(0),
+  ...overrides,
+});
+
+describe('extractSelectors', () => {
+  it('finds dispatcher selectors and skips push data', () => {
+    expect(extractSelectors(OLD_CODE)).toEqual(['0x70a08231', '0xa9059cbb']);
+    expect(extractSelectors(NEW_CODE)).toEqual(['0x3659cfe6', '0x70a08231']);
+  });
+});
+
+describe('findCodeChanges', () => {
+  const upgrade = access({
+    storageAccesses: [
+      {
+        account: PROXY,
+        slot: EIP1967_IMPLEMENTATION_SLOT,
+        isWrite: true,
+        previousValue: word(OLD_IMPL),
+        newValue: word(NEW_IMPL),
+        reverted: false,
+      },
+    ],
+  });
+  const client = {
+    getCode: async ({ address }: { address: Address }) =>
+      address.toLowerCase() === OLD_IMPL ? OLD_CODE : undefined,
+  };
+
+  it('compares the old and new implementation of an upgraded proxy', async () => {
+    // The new implementation is deployed by the same transaction
+    const decoded = [
+      access({ kind: AccountAccessKind.Create, account: NEW_IMPL, deployedCode: NEW_CODE }),
+      upgrade,
+    ];
+    const { storage } = aggregateAccountAccesses(decoded);
+    const changes = await findCodeChanges(client, decoded, storage);
+
+    expect(changes).toEqual([
+      {
+        address: NEW_IMPL,
+        kind: 'created',
+        implementationBefore: null,
+        implementationAfter: null,
+        before: null,
+        after: { size: (NEW_CODE.length - 2) / 2, codeHash: keccak256(NEW_CODE) },
+        selectorsAdded: ['0x3659cfe6', '0x70a08231'],
+        selectorsRemoved: [],
+      },
+      {
+        address: PROXY,
+        kind: 'implementation',
+        implementationBefore: getAddress(OLD_IMPL),
+        implementationAfter: getAddress(NEW_IMPL),
+        before: { size: (OLD_CODE.length - 2) / 2, codeHash: keccak256(OLD_CODE) },
+        after: { size: (NEW_CODE.length - 2) / 2, codeHash: keccak256(NEW_CODE) },
+        selectorsAdded: ['0x3659cfe6'],
+        selectorsRemoved: ['0xa9059cbb'],
+      },
+    ]);
+    expect(describeCodeChange(changes[1])).toMatch(/1 selector\(s\) added, 1 removed$/);
+  });
+
+  it('leaves out contracts that self-destruct in the same transaction', async () => {
+    const decoded = [
+      access({ kind: AccountAccessKind.Create, account: NEW_IMPL, deployedCode: NEW_CODE }),
+      access({ kind: AccountAccessKind.SelfDestruct, accessor: NEW_IMPL }),
+    ];
+    expect(await findCodeChanges(client, decoded, new Map())).toEqual([]);
+  });
+});
@@ -0,0 +1,52 @@
+import { describe, expect, it } from '@jest/globals';
+import { mkdirSync, mkdtempSync, symlinkSync, writeFileSync } from 'fs';
+import os from 'os';
+import path from 'path';
+import { checkCommandAllowed, parseAllowedCommands } from '../command-policy';
+import { PolicyViolationError } from '../errors';
+import { assertRealPathWithinDir } from '../path-validation';
+
+describe('checkCommandAllowed', () => {
+  const allowed = parseAllowedCommands(' forge, just ');
+
+  it('runs listed binaries and anything when there is no list', () => {
+    expect(allowed).toEqual(['forge', 'just']);
+    expect(() => checkCommandAllowed('just', allowed)).not.toThrow();
+    expect(() => checkCommandAllowed('bash', null)).not.toThrow();
+  });
+
+  it('refuses other binaries and binaries named by path', () => {
+    expect(() => checkCommandAllowed('bash', allowed)).toThrow(
+      /runs bash, which is not an allowed command \(forge, just\)\. Task commands run with/
+    );
+    expect(() => checkCommandAllowed('./forge', allowed)).toThrow(PolicyViolationError);
+    expect(() => checkCommandAllowed('/tmp/forge', allowed)).toThrow(/by path/);
+  });
+
+  it('only accepts binary names in the list', () => {
+    expect(() => parseAllowedCommands('forge,/usr/bin/just')).toThrow(/not a path/);
+    expect(() => parseAllowedCommands(' , ')).toThrow(/empty/);
+  });
+});
+
+describe('assertRealPathWithinDir', () => {
+  it('refuses symlinks that lead out of the directory', () => {
+    const root = mkdtempSync(path.join(os.tmpdir(), 'command-policy-'));
+    const workdir = path.join(root, 'task');
+    mkdirSync(workdir);
+    writeFileSync(path.join(root, 'secret.json'), '{}');
+    symlinkSync(path.join(root, 'secret.json'), path.join(workdir, 'stateDiff.json'));
+    symlinkSync(workdir, path.join(workdir, 'self'));
+
+    expect(() => assertRealPathWithinDir(path.join(workdir, 'stateDiff.json'), workdir)).toThrow(
+      /Symlink escape detected/
+    );
+    expect(assertRealPathWithinDir(path.join(workdir, 'self'), workdir)).toBe(
+      path.join(workdir, 'self')
+    );
+    // Nothing to follow yet
+    expect(assertRealPathWithinDir(path.join(workdir, 'out.json'), workdir)).toBe(
+      path.join(workdir, 'out.json')
+    );
+  });
+});
@@ -0,0 +1,39 @@
+import { describe, expect, it } from '@jest/globals';
+import { normalizeAddressKeys, normalizeConfigAddress } from '../config-addresses';
+import contractsCfg from '../config/contracts.json';
+
+const CHECKSUMMED = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const LOWER = CHECKSUMMED.toLowerCase();
+
+describe('normalizeConfigAddress', () => {
+  it('accepts lowercase, uppercase, and checksummed keys', () => {
+    expect(normalizeConfigAddress(LOWER, 'contracts.1')).toBe(LOWER);
+    expect(normalizeConfigAddress('0x' + LOWER.slice(2).toUpperCase(), 'contracts.1')).toBe(LOWER);
+    expect(normalizeConfigAddress(CHECKSUMMED, 'contracts.1')).toBe(LOWER);
+  });
+
+  it('rejects mixed-case keys with a bad checksum', () => {
+    const mistyped = '0x9855054731540a48b28990B63DcF4f33d8AE46A1';
+    expect(() => normalizeConfigAddress(mistyped, 'contracts.1')).toThrow(
+      `contracts.1: ${mistyped} has an invalid checksum (expected ${CHECKSUMMED})`
+    );
+  });
+
+  it('rejects keys that are not addresses', () => {
+    expect(() => normalizeConfigAddress('0x1234', 'contracts.1')).toThrow('is not an address');
+  });
+});
+
+describe('normalizeAddressKeys', () => {
+  it('rejects the same address under two spellings', () => {
+    expect(() =>
+      normalizeAddressKeys({ [LOWER]: 'a', [CHECKSUMMED]: 'b' }, 'contracts.1')
+    ).toThrow(`contracts.1: ${LOWER} and ${CHECKSUMMED} are the same address`);
+  });
+
+  it('accepts every address in the embedded config', () => {
+    for (const [chainId, contracts] of Object.entries(contractsCfg.contracts)) {
+      expect(() => normalizeAddressKeys(contracts, `contracts.${chainId}`)).not.toThrow();
+    }
+  });
+});
@@ -1,4 +1,9 @@
-import { AddressSchema, HashSchema, ExpectedHashesSchema } from '../config-schemas';
+import {
+  AddressSchema,
+  EncodedStateDiffSchema,
+  HashSchema,
+  ExpectedHashesSchema,
+} from '../config-schemas';
 
 describe('AddressSchema', () => {
   it('accepts valid lowercase address and returns checksummed', () => {
@@ -81,3 +86,21 @@ describe('ExpectedHashesSchema', () => {
     expect(() => ExpectedHashesSchema.parse(input)).toThrow();
   });
 });
+
+describe('EncodedStateDiffSchema', () => {
+  const raw = {
+    targetSafe: '0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045',
+    dataToSign: '0x1901' + 'a'.repeat(128),
+    stateDiff: '0x',
+    preimages: '0x',
+    overrides: '0x',
+  };
+
+  it('accepts the blobs forge writes', () => {
+    expect(EncodedStateDiffSchema.parse(raw)).toEqual(raw);
+  });
+
+  it('rejects blobs that are not hex', () => {
+    expect(() => EncodedStateDiffSchema.parse({ ...raw, stateDiff: 'deadbeef' })).toThrow();
+  });
+});
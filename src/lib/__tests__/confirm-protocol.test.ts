@@ -0,0 +1,81 @@
+import { describe, expect, it } from '@jest/globals';
+import { reviewItems, runConfirmProtocol } from '../confirm-protocol';
+import type { TaskConfig } from '../types';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const HASH = `0x${'11'.repeat(32)}`;
+const WORD = (n: number) => `0x${n.toString(16).padStart(64, '0')}`;
+
+const config = {
+  cmd: 'forge script',
+  ledgerId: 0,
+  rpcUrl: 'https://mainnet.example',
+  expectedDomainAndMessageHashes: { address: SAFE, domainHash: HASH, messageHash: HASH },
+  stateOverrides: [
+    {
+      name: 'Safe',
+      address: SAFE,
+      overrides: [{ key: WORD(4), value: WORD(1), description: 'Threshold 1' }],
+    },
+  ],
+  stateChanges: [
+    {
+      name: 'Safe',
+      address: SAFE,
+      changes: [
+        {
+          key: WORD(5),
+          before: WORD(1),
+          after: WORD(2),
+          description: 'Increments the nonce',
+          allowDifference: false,
+          afterAlias: 'TWO',
+        },
+      ],
+    },
+  ],
+} as TaskConfig;
+
+async function* lines(...input: string[]): AsyncGenerator<string> {
+  for (const line of input) yield line;
+}
+
+describe('reviewItems', () => {
+  it('lists the hashes, then the overrides and changes', () => {
+    expect(reviewItems(config).map(item => item.kind)).toEqual(['hashes', 'override', 'change']);
+    expect(reviewItems(config)[2]).toMatchObject({ contract: 'Safe', afterAlias: 'TWO' });
+  });
+});
+
+describe('runConfirmProtocol', () => {
+  it('writes the next item only after an ack and ends with done', async () => {
+    const written: string[] = [];
+    const outcome = await runConfirmProtocol(
+      reviewItems(config),
+      lines('{"ack": true}', '', '{"ack": true}', '{"ack": true}'),
+      line => written.push(line),
+      HASH
+    );
+
+    expect(outcome).toEqual({ confirmed: true, reviewed: 3 });
+    expect(written.map(line => JSON.parse(line).type)).toEqual(['item', 'item', 'item', 'done']);
+    expect(JSON.parse(written[3])).toEqual({ type: 'done', reviewed: 3, contentHash: HASH });
+  });
+
+  it('rejects on a nack, a line that is not an ack, or stdin closing', async () => {
+    const items = reviewItems(config);
+    const run = (...input: string[]) => runConfirmProtocol(items, lines(...input), () => {}, HASH);
+
+    expect(await run('{"ack": true}', '{"ack": false, "reason": "wrong owner"}')).toEqual({
+      confirmed: false,
+      reviewed: 1,
+      reason: 'wrong owner',
+    });
+    expect(await run('yes')).toMatchObject({ confirmed: false, reviewed: 0 });
+    expect(await run('{"ack": true}')).toEqual({
+      confirmed: false,
+      reviewed: 1,
+      reason: 'stdin closed before the item was acknowledged',
+    });
+  });
+});
@@ -0,0 +1,89 @@
+import { describe, expect, it } from '@jest/globals';
+import { hashTypedData } from 'viem';
+import { normalizeDataToSign, parseDataToSign } from '../data-to-sign';
+import { computeSafeTxHash } from '../safe-hash';
+
+const DOMAIN = '11'.repeat(32);
+const MESSAGE = '22'.repeat(32);
+const expected = { domainHash: `0x${DOMAIN}`, messageHash: `0x${MESSAGE}` };
+
+describe('parseDataToSign', () => {
+  it('accepts the EIP-712 encoding with or without the 0x1901 prefix', () => {
+    expect(parseDataToSign(`0x1901${DOMAIN}${MESSAGE}`)).toEqual(expected);
+    expect(parseDataToSign(` 0x${DOMAIN}${MESSAGE}\n`)).toEqual(expected);
+  });
+
+  it('requires the full prefixed encoding in strict mode', () => {
+    expect(parseDataToSign(`0x1901${DOMAIN}${MESSAGE}`, { strict: true })).toEqual(expected);
+    expect(() => parseDataToSign(`0x${DOMAIN}${MESSAGE}`, { strict: true })).toThrow(
+      /must be 66 bytes .* got 64 bytes/
+    );
+    expect(() => parseDataToSign(` 0x1901${DOMAIN}${MESSAGE}`, { strict: true })).toThrow(
+      /0x-prefixed/
+    );
+  });
+
+  it('reports precise offsets for malformed input', () => {
+    expect(() => parseDataToSign(`0x1902${DOMAIN}${MESSAGE}`)).toThrow(
+      'dataToSign bytes 0-1 are 0x1902, expected the EIP-712 prefix 0x1901'
+    );
+    expect(() => parseDataToSign(`0x1901${DOMAIN}zz${MESSAGE.slice(2)}`)).toThrow(
+      "non-hex character 'z' at byte offset 34"
+    );
+    expect(() => parseDataToSign(`0x1901${DOMAIN}`)).toThrow('got 34 bytes');
+    expect(() => parseDataToSign('0x190')).toThrow('odd number of hex digits (3)');
+  });
+});
+
+const SAFE_TX = {
+  types: {
+    SafeTx: [
+      { name: 'to', type: 'address' },
+      { name: 'value', type: 'uint256' },
+      { name: 'data', type: 'bytes' },
+      { name: 'nonce', type: 'uint256' },
+    ],
+  },
+  primaryType: 'SafeTx',
+  domain: { chainId: 1, verifyingContract: '0x9855054731540A48b28990B63DcF4f33d8AE46A1' },
+  message: {
+    to: '0x73a79Fab69143498Ed3712e519A88a918e1f4072',
+    value: '0',
+    data: '0x',
+    nonce: '7',
+  },
+} as const;
+
+describe('normalizeDataToSign', () => {
+  it('records which hex form was supplied', () => {
+    expect(normalizeDataToSign(`0x1901${DOMAIN}${MESSAGE}`)).toEqual({
+      ...expected,
+      form: 'eip712-encoded',
+    });
+    expect(normalizeDataToSign(` 0x${DOMAIN}${MESSAGE}`)).toEqual({
+      ...expected,
+      form: 'hash-pair',
+    });
+  });
+
+  it('hashes typed-data JSON into the same digest viem signs', () => {
+    const normalized = normalizeDataToSign(JSON.stringify(SAFE_TX));
+
+    expect(normalized.form).toBe('typed-data');
+    expect(computeSafeTxHash(normalized.domainHash, normalized.messageHash)).toBe(
+      hashTypedData({
+        ...SAFE_TX,
+        message: { ...SAFE_TX.message, value: BigInt(0), nonce: BigInt(7) },
+      })
+    );
+    expect(normalizeDataToSign(SAFE_TX)).toEqual(normalized);
+  });
+
+  it('rejects typed data in strict mode and malformed typed data', () => {
+    expect(() => normalizeDataToSign(SAFE_TX, { strict: true })).toThrow(/strict mode/);
+    expect(() => normalizeDataToSign({ ...SAFE_TX, primaryType: 'Missing' })).toThrow(
+      'primaryType Missing is not in types'
+    );
+    expect(() => normalizeDataToSign('{"types":')).toThrow(/does not parse/);
+  });
+});
@@ -0,0 +1,78 @@
+import { describe, expect, it } from '@jest/globals';
+import { encodeAbiParameters, Hex } from 'viem';
+import {
+  checkDiffFileSize,
+  checkEncodedStateDiff,
+  DecodeLimits,
+  encodedArrayLength,
+  parseByteSize,
+} from '../decode-limits';
+import { DecodeError, PolicyViolationError } from '../errors';
+
+const LIMITS: DecodeLimits = { maxDiffBytes: 4096, maxAccesses: 2, maxPreimages: 3 };
+
+function encodeList(n: number): Hex {
+  return encodeAbiParameters(
+    [{ type: 'uint256[]' }],
+    [Array.from({ length: n }, (_, i) => BigInt(i))]
+  );
+}
+
+function encoded(accesses: number, preimages: number) {
+  return {
+    dataToSign: '0x',
+    overrides: '0x',
+    stateDiff: encodeList(accesses),
+    preimages: encodeList(preimages),
+  };
+}
+
+describe('parseByteSize', () => {
+  it('accepts plain byte counts and binary units', () => {
+    expect(parseByteSize('1048576', '--max-diff-size')).toBe(1048576);
+    expect(parseByteSize('512KB', '--max-diff-size')).toBe(512 * 1024);
+    expect(parseByteSize('64MiB', '--max-diff-size')).toBe(64 * 1024 * 1024);
+  });
+
+  it('rejects unknown units and zero', () => {
+    expect(() => parseByteSize('64 parsecs', '--max-diff-size')).toThrow(
+      '--max-diff-size must be a size'
+    );
+    expect(() => parseByteSize('0KB', '--max-output-size')).toThrow(
+      '--max-output-size must be a size'
+    );
+  });
+});
+
+describe('encodedArrayLength', () => {
+  it('reads the length from the head', () => {
+    expect(encodedArrayLength(encodeList(0))).toBe(0);
+    expect(encodedArrayLength(encodeList(5))).toBe(5);
+  });
+
+  it('rejects truncated data', () => {
+    expect(() => encodedArrayLength(encodeList(5).slice(0, 70))).toThrow(DecodeError);
+  });
+});
+
+describe('checkEncodedStateDiff', () => {
+  it('accepts diffs within the limits', () => {
+    expect(() => checkEncodedStateDiff(encoded(2, 3), LIMITS)).not.toThrow();
+  });
+
+  it('refuses too many accesses or preimages', () => {
+    expect(() => checkEncodedStateDiff(encoded(3, 0), LIMITS)).toThrow(PolicyViolationError);
+    expect(() => checkEncodedStateDiff(encoded(0, 4), LIMITS)).toThrow('--max-preimages');
+  });
+
+  it('refuses oversized hex before counting', () => {
+    const limits = { ...LIMITS, maxDiffBytes: 64 };
+    expect(() => checkEncodedStateDiff(encoded(2, 0), limits)).toThrow('--max-diff-size');
+  });
+});
+
+describe('checkDiffFileSize', () => {
+  it('suggests --force', () => {
+    expect(() => checkDiffFileSize('stateDiff.json', 5000, LIMITS)).toThrow('--force');
+  });
+});
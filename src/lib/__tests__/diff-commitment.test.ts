@@ -0,0 +1,116 @@
+import { describe, expect, it } from '@jest/globals';
+import { decodeAbiParameters, Hex, keccak256, zeroHash } from 'viem';
+import {
+  buildDiffProof,
+  merkleTree,
+  parseDiffProofFile,
+  processProof,
+  verifyDiffProof,
+} from '../diff-commitment';
+import type { TaskConfig } from '../types';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const PORTAL = '0x49048044D57e1C92A77f79988d21Fa8fAF74E97e';
+const HASH = `0x${'11'.repeat(32)}`;
+const WORD = (n: number) => `0x${n.toString(16).padStart(64, '0')}`;
+
+const change = (key: number, before: number, after: number) => ({
+  key: WORD(key),
+  before: WORD(before),
+  after: WORD(after),
+  description: `Slot ${key}`,
+  allowDifference: false,
+});
+
+const config = (changes: { address: string; changes: ReturnType<typeof change>[] }[]) =>
+  ({
+    cmd: 'forge script',
+    ledgerId: 0,
+    rpcUrl: 'https://mainnet.example',
+    chainId: 1,
+    expectedDomainAndMessageHashes: { address: SAFE, domainHash: HASH, messageHash: HASH },
+    stateOverrides: [],
+    stateChanges: changes.map(sc => ({ name: 'Contract', ...sc })),
+  }) as TaskConfig;
+
+const approved = config([
+  { address: SAFE, changes: [change(4, 1, 2), change(5, 7, 8)] },
+  { address: PORTAL, changes: [change(1, 0, 3)] },
+]);
+
+describe('merkleTree', () => {
+  it('proves every leaf into the root, carrying odd nodes up', () => {
+    const leaves = [1, 2, 3, 4, 5].map(n => keccak256(WORD(n) as Hex));
+    const { root, proofs } = merkleTree(leaves);
+
+    leaves.forEach((leaf, i) => expect(processProof(leaf, proofs[i])).toBe(root));
+    expect(proofs[4]).toHaveLength(1);
+    expect(merkleTree([])).toEqual({ root: zeroHash, proofs: [] });
+  });
+});
+
+describe('buildDiffProof', () => {
+  it('commits to the chain, safeTxHash, root, and leaf count', () => {
+    const proof = buildDiffProof(approved);
+    const [chainId, safeTxHash, root, count] = decodeAbiParameters(
+      [{ type: 'uint256' }, { type: 'bytes32' }, { type: 'bytes32' }, { type: 'uint256' }],
+      proof.commitment as Hex
+    );
+
+    expect([chainId, safeTxHash, root, count]).toEqual([
+      BigInt(1),
+      proof.safeTxHash,
+      proof.root,
+      BigInt(3),
+    ]);
+    expect(proof.leaves.map(l => [l.address, l.slot])).toEqual([
+      [PORTAL, WORD(1)],
+      [SAFE, WORD(4)],
+      [SAFE, WORD(5)],
+    ]);
+    expect(verifyDiffProof(parseDiffProofFile(JSON.parse(JSON.stringify(proof))))).toEqual([]);
+  });
+
+  it('refuses files without a chain', () => {
+    expect(() => buildDiffProof({ ...approved, chainId: undefined })).toThrow(/no chainId/);
+  });
+});
+
+describe('verifyDiffProof', () => {
+  it('accepts an executed transaction with exactly the approved changes', () => {
+    const executed = config([
+      { address: PORTAL, changes: [change(1, 0, 3)] },
+      { address: SAFE, changes: [change(5, 7, 8), change(4, 1, 2)] },
+    ]);
+
+    expect(verifyDiffProof(buildDiffProof(approved), executed)).toEqual([]);
+  });
+
+  it('lists unapproved and missing changes', () => {
+    const executed = config([
+      { address: SAFE, changes: [change(4, 1, 9), change(5, 7, 8)] },
+      { address: PORTAL, changes: [change(1, 0, 3)] },
+    ]);
+
+    const errors = verifyDiffProof(buildDiffProof(approved), executed);
+
+    expect(errors).toHaveLength(2);
+    expect(errors[0]).toMatch(/slot 0x0+4 changed .* which was not approved/);
+    expect(errors[1]).toMatch(/approved change of .* slot 0x0+4 did not happen/);
+  });
+
+  it('catches a tampered leaf or commitment', () => {
+    const proof = buildDiffProof(approved);
+    const tampered = {
+      ...proof,
+      leafCount: 2,
+      leaves: proof.leaves.map((l, i) => (i === 0 ? { ...l, after: WORD(4) } : l)),
+    };
+
+    expect(verifyDiffProof(tampered)).toEqual([
+      expect.stringMatching(/leaf 0 .* does not hash to/),
+      '3 leaves are listed, but leafCount is 2',
+      'commitment does not encode the chain, safeTxHash, root, and leaf count',
+    ]);
+  });
+});
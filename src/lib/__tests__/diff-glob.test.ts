@@ -0,0 +1,72 @@
+import { describe, expect, it } from '@jest/globals';
+import { mkdirSync, mkdtempSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import path from 'path';
+import { keccak256, toHex } from 'viem';
+import { matchDiffGlob, mergeSimulationArtifacts } from '../diff-glob';
+import { decodePreimages, decodeStateDiff } from '../state-diff-encoding';
+import {
+  accountAccess,
+  mappingPreimage,
+  simulationArtifact,
+  storageWrite,
+  syntheticAddress,
+  word,
+} from '../state-diff-test';
+
+describe('matchDiffGlob', () => {
+  it('matches file names and sorts numbers by value', () => {
+    const dir = mkdtempSync(path.join(tmpdir(), 'diff-glob-'));
+    for (const name of ['diff-10.json', 'diff-2.json', 'diff-l1.json', 'diff.json', 'other.json']) {
+      writeFileSync(path.join(dir, name), '{}');
+    }
+    mkdirSync(path.join(dir, 'diff-dir.json'));
+    expect(matchDiffGlob(dir, 'diff-*.json')).toEqual([
+      'diff-2.json',
+      'diff-10.json',
+      'diff-l1.json',
+    ]);
+    expect(matchDiffGlob(dir, 'diff-?.json')).toEqual(['diff-2.json']);
+  });
+
+  it('treats other characters literally and refuses paths', () => {
+    const dir = mkdtempSync(path.join(tmpdir(), 'diff-glob-'));
+    writeFileSync(path.join(dir, 'diffXjson'), '{}');
+    expect(matchDiffGlob(dir, 'diff.json')).toEqual([]);
+    expect(() => matchDiffGlob(dir, '../*.json')).toThrow(/file names only/);
+  });
+});
+
+describe('mergeSimulationArtifacts', () => {
+  const safe = syntheticAddress(1);
+
+  it('concatenates the accesses and preimages of every diff in order', () => {
+    const first = simulationArtifact({
+      cmd: 'forge script One.s.sol',
+      accesses: [accountAccess({ account: safe, storageAccesses: [storageWrite(safe, 4, 1, 2)] })],
+      preimages: [mappingPreimage(2, 1)],
+    });
+    const second = simulationArtifact({
+      cmd: 'forge script Two.s.sol',
+      accesses: [accountAccess({ account: safe, storageAccesses: [storageWrite(safe, 4, 2, 3)] })],
+    });
+    const merged = mergeSimulationArtifacts([
+      { file: 'diff-1.json', artifact: first },
+      { file: 'diff-2.json', artifact: second },
+    ]);
+    const writes = decodeStateDiff(merged.stateDiff.stateDiff).flatMap(a => a.storageAccesses);
+    expect(writes.map(w => w.newValue)).toEqual([word(2), word(3)]);
+    expect(decodePreimages(merged.stateDiff.preimages)).toHaveLength(1);
+    expect(merged.cmd).toBe('forge script One.s.sol');
+  });
+
+  it('refuses diffs that sign different data', () => {
+    const other = simulationArtifact({ messageHash: keccak256(toHex('other')) });
+    expect(() =>
+      mergeSimulationArtifacts([
+        { file: 'diff-1.json', artifact: simulationArtifact() },
+        { file: 'diff-l1.json', artifact: other },
+      ])
+    ).toThrow(/diff-l1\.json cannot be merged with diff-1\.json: dataToSign differ/);
+  });
+});
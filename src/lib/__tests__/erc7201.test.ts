@@ -0,0 +1,19 @@
+import { describe, expect, it } from '@jest/globals';
+import { erc7201Slot } from '../erc7201';
+
+describe('erc7201Slot', () => {
+  it('matches the roots OpenZeppelin publishes', () => {
+    expect(erc7201Slot('openzeppelin.storage.Ownable')).toBe(
+      '0x9016d09d72d40fdae2fd8ceac6b6234c7706214fd39c1cd1e609a0528c199300'
+    );
+    expect(erc7201Slot('openzeppelin.storage.Pausable')).toBe(
+      '0xcd5ed15c6e187e77e9aee88184c21f4f2182ab5827cb3b7e07fbedcd63f03300'
+    );
+  });
+
+  it('adds member offsets to the root', () => {
+    expect(erc7201Slot('openzeppelin.storage.Ownable', 1)).toBe(
+      '0x9016d09d72d40fdae2fd8ceac6b6234c7706214fd39c1cd1e609a0528c199301'
+    );
+  });
+});
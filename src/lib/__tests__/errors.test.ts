@@ -0,0 +1,43 @@
+import { describe, expect, it } from '@jest/globals';
+import { BaseError, HttpRequestError } from 'viem';
+import {
+  DecodeError,
+  EXIT_CODES,
+  exitCodeFor,
+  PolicyViolationError,
+  RpcError,
+  SimulationFailedError,
+} from '../errors';
+import { assertWithinDir } from '../path-validation';
+import { parseSimulationArtifact } from '../simulation-artifact';
+
+describe('exitCodeFor', () => {
+  it('maps each error class to its exit code', () => {
+    expect(exitCodeFor(new SimulationFailedError('forge failed'))).toBe(3);
+    expect(exitCodeFor(new DecodeError('bad blob'))).toBe(4);
+    expect(exitCodeFor(new RpcError('unreachable'))).toBe(5);
+    expect(exitCodeFor(new PolicyViolationError('refused'))).toBe(6);
+    expect(exitCodeFor(new Error('other'))).toBe(EXIT_CODES.failure);
+  });
+
+  it('treats wrapped viem transport errors as RPC failures', () => {
+    const err = new BaseError('readContract failed', {
+      cause: new HttpRequestError({ url: 'https://rpc.example' }),
+    });
+    expect(exitCodeFor(err)).toBe(EXIT_CODES.rpc);
+  });
+
+  it('names errors after their class', () => {
+    expect(new DecodeError('bad blob').name).toBe('DecodeError');
+  });
+});
+
+describe('classified throw sites', () => {
+  it('reports path traversal as a policy violation', () => {
+    expect(() => assertWithinDir('/tmp/../etc/passwd', '/tmp')).toThrow(PolicyViolationError);
+  });
+
+  it('reports an invalid simulation diff as a decode error', () => {
+    expect(() => parseSimulationArtifact({ version: 1 })).toThrow(DecodeError);
+  });
+});
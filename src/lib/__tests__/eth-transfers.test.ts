@@ -0,0 +1,72 @@
+import { describe, expect, it } from '@jest/globals';
+import { aggregateAccountAccesses } from '../account-aggregation';
+import { describeEthTransfer, findEthTransfers } from '../eth-transfers';
+import { AccountAccessKind, VmSafeAccountAccess } from '../vm-safe';
+
+const SIGNER = '0x1111111111111111111111111111111111111111';
+const SAFE = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const TREASURY = '0x2222222222222222222222222222222222222222';
+const OTHER = '0x3333333333333333333333333333333333333333';
+const COINBASE = '0x4444444444444444444444444444444444444444';
+
+const access = (overrides: Partial<VmSafeAccountAccess>): VmSafeAccountAccess => ({
+  chainInfo: { forkId: BigInt(0), chainId: BigInt(1) },
+  kind: AccountAccessKind.Call,
+  account: SAFE,
+  accessor: SIGNER,
+  initialized: true,
+  oldBalance: BigInt(0),
+  newBalance: BigInt(0),
+  deployedCode: '0x',
+  value: BigInt(0),
+  data: '0x',
+  reverted: false,
+  storageAccesses: [],
+  depth: BigInt(0),
+  oldNonce: BigInt(0),
+  newNonce: BigInt(0),
+  ...overrides,
+});
+
+const wei = (n: number) => BigInt(n);
+
+describe('findEthTransfers', () => {
+  it('sums value sent per sender and receiver and skips reverted calls', () => {
+    const decoded = [
+      access({ oldBalance: wei(10), newBalance: wei(5) }),
+      access({ accessor: SAFE, account: TREASURY, value: wei(2), newBalance: wei(2) }),
+      access({ accessor: SAFE, account: OTHER, value: wei(4), reverted: true }),
+      access({
+        accessor: SAFE,
+        account: TREASURY,
+        value: wei(3),
+        oldBalance: wei(2),
+        newBalance: wei(5),
+      }),
+    ];
+    const { accounts } = aggregateAccountAccesses(decoded);
+    const transfers = findEthTransfers(decoded, accounts);
+
+    expect(transfers).toEqual([{ from: SAFE, to: TREASURY, amount: wei(5) }]);
+    expect(describeEthTransfer(transfers[0])).toBe(`${SAFE} sends 5 wei to ${TREASURY}`);
+  });
+
+  it('lists balance changes no transfer explains', () => {
+    // A prestate trace only carries balances, e.g. the fee paid to the block producer
+    const decoded = [
+      access({ accessor: SAFE, account: SIGNER, oldBalance: wei(9), newBalance: wei(6) }),
+      access({ accessor: SAFE, account: COINBASE, newBalance: wei(3) }),
+    ];
+    const { accounts } = aggregateAccountAccesses(decoded);
+    const transfers = findEthTransfers(decoded, accounts);
+
+    expect(transfers).toEqual([
+      { from: SIGNER, to: null, amount: wei(3) },
+      { from: null, to: COINBASE, amount: wei(3) },
+    ]);
+    expect(transfers.map(describeEthTransfer)).toEqual([
+      `${SIGNER} loses 3 wei not sent by any call`,
+      `${COINBASE} gains 3 wei not sent by any call`,
+    ]);
+  });
+});
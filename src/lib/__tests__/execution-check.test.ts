@@ -0,0 +1,121 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  Address,
+  concatHex,
+  encodeAbiParameters,
+  encodeFunctionData,
+  Hex,
+  parseAbi,
+  PublicClient,
+  RpcRequestError,
+  zeroAddress,
+} from 'viem';
+import { callStatus, checkExecution, decodeRevertReason } from '../execution-check';
+import { PayloadDecoded } from '../state-diff-encoding';
+
+const SAFE: Address = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const SENDER: Address = '0x1111111111111111111111111111111111111111';
+
+function errorString(message: string): Hex {
+  return concatHex(['0x08c379a0', encodeAbiParameters([{ type: 'string' }], [message])]);
+}
+
+const execTransaction = encodeFunctionData({
+  abi: parseAbi([
+    'function execTransaction(address to, uint256 value, bytes data, uint8 operation, uint256 safeTxGas, uint256 baseGas, uint256 gasPrice, address gasToken, address refundReceiver, bytes signatures) payable returns (bool)',
+  ]),
+  functionName: 'execTransaction',
+  args: [
+    SENDER,
+    BigInt(0),
+    '0x',
+    0,
+    BigInt(50000),
+    BigInt(0),
+    BigInt(0),
+    zeroAddress,
+    zeroAddress,
+    '0x',
+  ],
+});
+
+const payload: PayloadDecoded = {
+  from: SENDER,
+  to: SAFE,
+  data: execTransaction,
+  stateOverrides: [],
+};
+
+const TRUE = encodeAbiParameters([{ type: 'bool' }], [true]);
+const FALSE = encodeAbiParameters([{ type: 'bool' }], [false]);
+
+function fakeClient(handler: (method: string, params: unknown[]) => unknown): PublicClient {
+  return {
+    request: async ({ method, params = [] }: { method: string; params?: unknown[] }) =>
+      handler(method, params),
+  } as unknown as PublicClient;
+}
+
+describe('decodeRevertReason', () => {
+  it('spells out Safe error codes', () => {
+    expect(decodeRevertReason(errorString('GS013'))).toBe(
+      'GS013: the Safe transaction failed and safeTxGas and gasPrice are 0'
+    );
+  });
+
+  it('decodes panics and keeps custom errors raw', () => {
+    const panic = concatHex([
+      '0x4e487b71',
+      encodeAbiParameters([{ type: 'uint256' }], [BigInt(0x11)]),
+    ]);
+    expect(decodeRevertReason(panic)).toBe('panic 0x11');
+    expect(decodeRevertReason('0xdeadbeef')).toBe('custom error 0xdeadbeef');
+    expect(decodeRevertReason('0x')).toBe('reverted without data');
+  });
+});
+
+describe('callStatus', () => {
+  it('treats execTransaction returning false as a failed Safe execution', () => {
+    expect(callStatus(payload, SAFE, FALSE).status).toBe('safe-execution-failed');
+    expect(callStatus(payload, SAFE, TRUE).status).toBe('success');
+  });
+
+  it('only reads the return value of execTransaction on the signing Safe', () => {
+    expect(callStatus({ ...payload, to: SENDER }, SAFE, FALSE).status).toBe('success');
+  });
+});
+
+describe('checkExecution', () => {
+  it('reports the gas estimate of a successful call', async () => {
+    const client = fakeClient(method => (method === 'eth_call' ? TRUE : '0x5208'));
+    expect(await checkExecution(client, payload, SAFE)).toEqual({
+      status: 'success',
+      estimatedGas: '21000',
+    });
+  });
+
+  it('replays the call at the block the simulation forked from', async () => {
+    const blocks: unknown[] = [];
+    const client = fakeClient((method, params) => {
+      blocks.push(params[1]);
+      return method === 'eth_call' ? TRUE : '0x5208';
+    });
+    await checkExecution(client, payload, SAFE, BigInt(90));
+    expect(blocks).toEqual(['0x5a', '0x5a']);
+  });
+
+  it('decodes the revert reason of a reverted call', async () => {
+    const data = errorString('GS026');
+    const client = fakeClient(() => {
+      throw new RpcRequestError({
+        body: {},
+        url: 'http://localhost:8545',
+        error: { code: 3, message: 'execution reverted', data },
+      });
+    });
+    expect(await checkExecution(client, payload, SAFE)).toEqual({
+      status: 'reverted',
+      revertReason: 'GS026: invalid owner provided',
+    });
+  });
+});
@@ -0,0 +1,66 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  checkExpectedOverrides,
+  describeOverrideDiscrepancy,
+  parseExpectedOverrides,
+} from '../expected-overrides';
+import type { StateOverride } from '../types';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const OTHER = '0x73a79Fab69143498Ed3712e519A88a918e1f4072';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const YAML = `
+overrides:
+  - address: ${SAFE}
+    key: 0x4
+    value: 0x1
+    reason: threshold overridden to 1 for simulation
+  - address: ${SAFE}
+    key: ${word(5)}
+    value: ${word(7)}
+    reason: nonce set to the task's nonce
+`;
+
+const overrides = (...entries: [string, string, string][]): StateOverride[] =>
+  entries.map(([address, key, value]) => ({
+    name: 'Safe',
+    address: address as StateOverride['address'],
+    overrides: [{ key, value, description: 'Override' }],
+  }));
+
+describe('parseExpectedOverrides', () => {
+  it('reads short hex as 32-byte words', () => {
+    expect(parseExpectedOverrides(YAML)[0]).toEqual({
+      address: SAFE,
+      key: word(4),
+      value: word(1),
+      reason: 'threshold overridden to 1 for simulation',
+    });
+  });
+
+  it('requires a reason for every override', () => {
+    expect(() =>
+      parseExpectedOverrides(`overrides:\n  - address: ${SAFE}\n    key: 0x4\n    value: 0x1\n`)
+    ).toThrow(/overrides\.0\.reason/);
+  });
+});
+
+describe('checkExpectedOverrides', () => {
+  const expected = parseExpectedOverrides(YAML);
+
+  it('accepts exactly the documented overrides', () => {
+    const actual = overrides([SAFE, word(4), word(1)], [SAFE.toLowerCase(), word(5), word(7)]);
+    expect(checkExpectedOverrides(actual, expected)).toEqual([]);
+  });
+
+  it('flags missing, different, and undocumented overrides', () => {
+    const actual = overrides([SAFE, word(4), word(2)], [OTHER, word(0), word(9)]);
+    const discrepancies = checkExpectedOverrides(actual, expected);
+
+    expect(discrepancies.map(d => d.kind)).toEqual(['mismatch', 'missing', 'undocumented']);
+    expect(describeOverrideDiscrepancy(discrepancies[2])).toBe(
+      `Override ${OTHER} ${word(0)} = ${word(9)} is not documented in expected-overrides.yaml`
+    );
+  });
+});
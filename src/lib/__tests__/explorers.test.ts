@@ -0,0 +1,20 @@
+import { describe, expect, it } from '@jest/globals';
+import { explorerAddressUrl, explorerTxUrl, getExplorerConfig } from '../explorers';
+
+describe('explorers', () => {
+  it('builds Etherscan address and tx links for mainnet', () => {
+    const address = '0x73a79Fab69143498Ed3712e519A88a918e1f4072';
+    expect(explorerAddressUrl('1', address)).toBe(`https://etherscan.io/address/${address}`);
+    expect(explorerTxUrl('1', '0xabc')).toBe('https://etherscan.io/tx/0xabc');
+  });
+
+  it('trims whitespace around the chain id', () => {
+    expect(getExplorerConfig(' 8453 ')?.url).toBe('https://basescan.org');
+  });
+
+  it('returns undefined for chains without a configured explorer', () => {
+    expect(explorerAddressUrl('999999', '0x0000000000000000000000000000000000000000')).toBe(
+      undefined
+    );
+  });
+});
@@ -0,0 +1,133 @@
+import { describe, expect, it } from '@jest/globals';
+import { pad } from 'viem';
+import { buildFocusTrace, forgeTraceLines, formatFocusTrace } from '../focus-trace';
+import { AccountAccessKind, VmSafeAccountAccess, VmSafeStorageAccess } from '../vm-safe';
+
+const SAFE = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const PROXY = '0x1111111111111111111111111111111111111111';
+const IMPL = '0x2222222222222222222222222222222222222222';
+const OTHER = '0x3333333333333333333333333333333333333333';
+const UNUSED = '0x4444444444444444444444444444444444444444';
+
+const access = (overrides: Partial<VmSafeAccountAccess>): VmSafeAccountAccess => ({
+  chainInfo: { forkId: BigInt(0), chainId: BigInt(1) },
+  kind: AccountAccessKind.Call,
+  account: SAFE,
+  accessor: SAFE,
+  initialized: true,
+  oldBalance: BigInt(0),
+  newBalance: BigInt(0),
+  deployedCode: '0x',
+  value: BigInt(0),
+  data: '0x',
+  reverted: false,
+  storageAccesses: [],
+  depth: BigInt(0),
+  oldNonce: BigInt(0),
+  newNonce: BigInt(0),
+  ...overrides,
+});
+
+const slot = (
+  account: string,
+  key: number,
+  before: number,
+  after: number,
+  isWrite = true
+): VmSafeStorageAccess => ({
+  account,
+  slot: pad(`0x${key.toString(16)}`),
+  isWrite,
+  previousValue: pad(`0x${before.toString(16)}`),
+  newValue: pad(`0x${after.toString(16)}`),
+  reverted: false,
+});
+
+const accesses = [
+  access({ account: OTHER, storageAccesses: [slot(OTHER, 1, 0, 1)] }),
+  access({ account: PROXY, depth: BigInt(1), data: '0xa9059cbb0000' }),
+  // The implementation runs in the proxy's storage
+  access({
+    kind: AccountAccessKind.DelegateCall,
+    accessor: PROXY,
+    account: IMPL,
+    depth: BigInt(2),
+    storageAccesses: [slot(PROXY, 3, 7, 7, false), slot(PROXY, 3, 7, 8), slot(PROXY, 4, 1, 2)],
+  }),
+  access({
+    account: PROXY,
+    depth: BigInt(1),
+    reverted: true,
+    storageAccesses: [slot(PROXY, 5, 0, 9)],
+  }),
+  access({ account: PROXY, depth: BigInt(1), storageAccesses: [slot(PROXY, 4, 2, 1)] }),
+];
+
+describe('buildFocusTrace', () => {
+  const trace = buildFocusTrace(accesses, PROXY);
+
+  it('keeps the calls that reach the contract, leave it, or touch its storage', () => {
+    expect(trace.frames.map(f => [f.index, f.kind, f.storage.length])).toEqual([
+      [1, 'Call', 0],
+      [2, 'DelegateCall', 3],
+      [3, 'Call', 1],
+      [4, 'Call', 1],
+    ]);
+    expect(trace.frames[0].selector).toBe('0xa9059cbb');
+  });
+
+  it('marks storage accesses in reverted calls', () => {
+    expect(trace.frames[2].storage[0]).toMatchObject({ isWrite: true, reverted: true });
+  });
+
+  it('reports net changes, including slots restored to their starting value', () => {
+    expect(trace.changes).toEqual([
+      { slot: pad('0x3'), before: pad('0x7'), after: pad('0x8'), writes: 1 },
+      { slot: pad('0x4'), before: pad('0x1'), after: pad('0x1'), writes: 2 },
+      { slot: pad('0x5'), before: pad('0x0'), after: pad('0x0'), writes: 0 },
+    ]);
+  });
+
+  it('names mapping entries from the recorded preimages', () => {
+    const withPreimage = buildFocusTrace(accesses, PROXY, [
+      { slot: pad('0x3'), parent: pad('0x1'), key: pad(SAFE) },
+    ]);
+    const lines = formatFocusTrace(withPreimage);
+    expect(lines).toContainEqual(
+      expect.stringContaining(`SSTORE ${pad('0x3')} (key ${pad(SAFE)} of slot ${pad('0x1')})`)
+    );
+  });
+});
+
+describe('formatFocusTrace', () => {
+  it('indents calls by depth and lists reads, writes, and net changes', () => {
+    const lines = formatFocusTrace(buildFocusTrace(accesses, PROXY));
+    expect(lines[0]).toBe(`🔬 ${PROXY}: 4 call(s), 5 storage access(es), 3 slot(s) written`);
+    expect(lines).toContainEqual(`       #2 DelegateCall ${PROXY} -> ${IMPL}`);
+    expect(lines).toContainEqual(`         SLOAD  ${pad('0x3')} = ${pad('0x7')}`);
+    expect(lines).toContainEqual(expect.stringMatching(/^ {5}#3 Call .* \[reverted\]$/));
+    expect(lines).toContainEqual(
+      `     ${pad('0x4')}: ${pad('0x1')} (2 write(s), ends where it started)`
+    );
+    expect(lines).toContainEqual(
+      `     ${pad('0x5')}: ${pad('0x0')} (only written in reverted calls)`
+    );
+  });
+
+  it('says when the contract was never involved', () => {
+    const lines = formatFocusTrace(buildFocusTrace(accesses, UNUSED));
+    expect(lines[1]).toMatch(/never called this contract/);
+  });
+});
+
+describe('forgeTraceLines', () => {
+  it('keeps the lines naming the contract regardless of case', () => {
+    const output = [
+      '  [5000] 0x9855054731540A48b28990B63DcF4f33d8AE46A1::execTransaction(...)',
+      `    ├─ [2000] ${PROXY}::transfer(...)`,
+      '    │   └─ ← [Return] true',
+    ].join('\n');
+    expect(forgeTraceLines(output, SAFE.toUpperCase().replace('0X', '0x'))).toHaveLength(1);
+    expect(forgeTraceLines(output, PROXY)).toEqual([`    ├─ [2000] ${PROXY}::transfer(...)`]);
+  });
+});
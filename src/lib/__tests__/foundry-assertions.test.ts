@@ -0,0 +1,54 @@
+import { describe, expect, it } from '@jest/globals';
+import { buildFoundryAssertions } from '../foundry-assertions';
+
+const PORTAL = '0x49048044D57e1C92A77f79988d21Fa8fAF74E97e';
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const change = (n: number, description: string, allowDifference = false) => ({
+  key: word(n),
+  before: word(0),
+  after: word(n + 10),
+  description,
+  allowDifference,
+});
+
+describe('buildFoundryAssertions', () => {
+  it('asserts each changed slot and comments out the rest', () => {
+    const sol = buildFoundryAssertions(
+      {
+        chainId: 8453,
+        chainName: 'Base',
+        expectedDomainAndMessageHashes: { address: SAFE, domainHash: word(1), messageHash: word(2) },
+        stateChanges: [
+          {
+            name: 'Portal "v2"',
+            address: PORTAL.toLowerCase(),
+            changes: [change(1, 'Implementation\nupdated'), change(2, 'Last update', true)],
+          },
+        ],
+        balanceChanges: [
+          {
+            name: 'Safe',
+            address: SAFE,
+            field: 'ETH Balance (wei)',
+            before: word(5),
+            after: word(3),
+            description: '',
+            allowDifference: false,
+          },
+        ],
+      },
+      'upgrade-portal'
+    );
+
+    expect(sol).toContain('// Post-state of upgrade-portal on Base (chain 8453)');
+    expect(sol).toContain('        // Implementation updated\n');
+    expect(sol).toContain(
+      `vm.assertEq(vm.load(${PORTAL}, bytes32(${word(1)})), bytes32(${word(11)}), "Portal \\"v2\\" slot ${word(1)}");`
+    );
+    expect(sol).not.toContain(`bytes32(${word(12)})`);
+    expect(sol).toContain(`// Not asserted, may differ at execution: slot ${word(2)}`);
+    expect(sol).toContain(`// Safe (${SAFE}): ${word(5)} -> ${word(3)}`);
+  });
+});
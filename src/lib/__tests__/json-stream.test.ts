@@ -0,0 +1,39 @@
+import { describe, expect, it } from '@jest/globals';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { jsonChunks, writeJsonFile } from '../json-stream';
+
+const sample = {
+  cmd: 'forge script',
+  stateOverrides: [],
+  stateChanges: [
+    { name: 'Safe', changes: [{ key: '0x04', before: '0x01', after: '0x02', skipped: undefined }] },
+  ],
+  scope: {},
+  nested: [[1, 'two', null, true]],
+  dropped: undefined,
+};
+
+describe('jsonChunks', () => {
+  it('produces the same text as JSON.stringify', () => {
+    expect(Array.from(jsonChunks(sample)).join('')).toBe(JSON.stringify(sample, null, 2));
+    expect(Array.from(jsonChunks([undefined, 'x'])).join('')).toBe('[\n  null,\n  "x"\n]');
+  });
+
+  it('yields the document incrementally', () => {
+    expect(Array.from(jsonChunks(sample)).length).toBeGreaterThan(10);
+  });
+});
+
+describe('writeJsonFile', () => {
+  it('writes indented JSON with a trailing newline', async () => {
+    const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'json-stream-'));
+    const file = path.join(dir, 'validation.json');
+    const large = { ...sample, calldata: '0x' + 'ab'.repeat(100000) };
+
+    await writeJsonFile(file, large);
+
+    expect(await fs.readFile(file, 'utf-8')).toBe(JSON.stringify(large, null, 2) + '\n');
+  });
+});
@@ -0,0 +1,82 @@
+import { describe, expect, it } from '@jest/globals';
+import { mkdtempSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import path from 'path';
+import {
+  buildJustCommand,
+  findJustfile,
+  justValidationPath,
+  missingJustEnv,
+  parseJustRecipes,
+} from '../justfile';
+
+const JUSTFILE = `
+set dotenv-load
+rpc_url := env_var('ETH_RPC_URL')
+
+# Simulates the task for one Safe
+[group('task')]
+simulate whichSafe hdPath='0':
+  forge script SignFromJson --sig "signJson(string)" {{whichSafe}} --rpc-url {{rpc_url}}
+
+@sign whichSafe hdPath="0" url='http://localhost:8545':
+  echo $(env_var('SIGNER_ADDRESS'))
+  eip712sign --ledger --hd-paths "m/44'/60'/{{hdPath}}'/0/0"
+
+approve +safes:
+  echo {{safes}}
+`;
+
+describe('parseJustRecipes', () => {
+  const recipes = parseJustRecipes(JUSTFILE);
+
+  it('lists recipes with their parameters', () => {
+    expect(recipes.map(r => r.name)).toEqual(['simulate', 'sign', 'approve']);
+    expect(recipes[0].parameters).toEqual([
+      { name: 'whichSafe', required: true, variadic: false },
+      { name: 'hdPath', required: false, variadic: false },
+    ]);
+    expect(recipes[1].parameters.map(p => p.name)).toEqual(['whichSafe', 'hdPath', 'url']);
+    expect(recipes[2].parameters).toEqual([{ name: 'safes', required: true, variadic: true }]);
+  });
+
+  it('collects variables read with env_var in the recipe and the justfile', () => {
+    expect(recipes[0].requiredEnv).toEqual(['ETH_RPC_URL']);
+    expect(recipes[1].requiredEnv).toEqual(['ETH_RPC_URL', 'SIGNER_ADDRESS']);
+  });
+});
+
+describe('buildJustCommand', () => {
+  const [simulate, , approve] = parseJustRecipes(JUSTFILE);
+
+  it('runs the recipe with its arguments', () => {
+    expect(buildJustCommand(simulate, ['foundation'])).toEqual(['just', 'simulate', 'foundation']);
+    expect(buildJustCommand(approve, ['a', 'b', 'c'])).toEqual(['just', 'approve', 'a', 'b', 'c']);
+  });
+
+  it('refuses too few or too many arguments', () => {
+    expect(() => buildJustCommand(simulate, [])).toThrow(/takes <whichSafe \[hdPath\]>/);
+    expect(() => buildJustCommand(simulate, ['a', 'b', 'c'])).toThrow(/--just-args/);
+  });
+});
+
+describe('missingJustEnv', () => {
+  it('lists required variables the environment does not set', () => {
+    const [simulate] = parseJustRecipes(JUSTFILE);
+    expect(missingJustEnv(simulate, {})).toEqual(['ETH_RPC_URL']);
+    expect(missingJustEnv(simulate, { ETH_RPC_URL: 'http://rpc' })).toEqual([]);
+  });
+});
+
+describe('findJustfile and justValidationPath', () => {
+  it('finds the justfile and names the output after the arguments', () => {
+    const dir = mkdtempSync(path.join(tmpdir(), 'justfile-'));
+    expect(findJustfile(dir)).toBeNull();
+    writeFileSync(path.join(dir, 'Justfile'), JUSTFILE);
+    expect(findJustfile(dir)).toBe(path.join(dir, 'Justfile'));
+    expect(justValidationPath(dir, ['foundation'])).toBe(
+      path.join(dir, 'validations', 'foundation.json')
+    );
+    expect(justValidationPath(dir, [])).toBe(path.join(dir, 'validations', 'simulate.json'));
+  });
+});
@@ -0,0 +1,42 @@
+import { describe, expect, it } from '@jest/globals';
+import { withKeyedLock } from '../keyed-lock';
+
+const tick = () => new Promise(resolve => setTimeout(resolve, 5));
+
+describe('withKeyedLock', () => {
+  it('runs work for the same key one at a time', async () => {
+    const events: string[] = [];
+    const job = (name: string) => async () => {
+      events.push(`${name}:start`);
+      await tick();
+      events.push(`${name}:end`);
+    };
+
+    await Promise.all([withKeyedLock('workdir', job('a')), withKeyedLock('workdir', job('b'))]);
+
+    expect(events).toEqual(['a:start', 'a:end', 'b:start', 'b:end']);
+  });
+
+  it('does not block different keys', async () => {
+    const events: string[] = [];
+    const job = (name: string) => async () => {
+      events.push(`${name}:start`);
+      await tick();
+      events.push(`${name}:end`);
+    };
+
+    await Promise.all([withKeyedLock('one', job('a')), withKeyedLock('two', job('b'))]);
+
+    expect(events.slice(0, 2)).toEqual(['a:start', 'b:start']);
+  });
+
+  it('releases the lock when the holder throws', async () => {
+    await expect(
+      withKeyedLock('failing', async () => {
+        throw new Error('boom');
+      })
+    ).rejects.toThrow('boom');
+
+    await expect(withKeyedLock('failing', async () => 'ok')).resolves.toBe('ok');
+  });
+});
@@ -0,0 +1,94 @@
+import { describe, expect, it } from '@jest/globals';
+import { decryptKeystore } from '../keystore';
+
+// Test vectors from the Web3 Secret Storage Definition, both encrypting PRIVATE_KEY under
+// "testpassword"
+const PASSWORD = 'testpassword';
+const PRIVATE_KEY = '0x7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d';
+
+const PBKDF2_KEYSTORE = {
+  crypto: {
+    cipher: 'aes-128-ctr',
+    cipherparams: { iv: '6087dab2f9fdbbfaddc31a909735c1e6' },
+    ciphertext: '5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46',
+    kdf: 'pbkdf2',
+    kdfparams: {
+      c: 262144,
+      dklen: 32,
+      prf: 'hmac-sha256',
+      salt: 'ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd',
+    },
+    mac: '517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2',
+  },
+  id: '3198bc9c-6672-5ab3-d995-4942343ae5b6',
+  version: 3,
+};
+
+const SCRYPT_KEYSTORE = {
+  crypto: {
+    cipher: 'aes-128-ctr',
+    cipherparams: { iv: '83dbcc02d8ccb40e466191a123791e0e' },
+    ciphertext: 'd172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c',
+    kdf: 'scrypt',
+    kdfparams: {
+      dklen: 32,
+      n: 262144,
+      p: 8,
+      r: 1,
+      salt: 'ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19',
+    },
+    mac: '2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097',
+  },
+  id: '3198bc9c-6672-5ab3-d995-4942343ae5b6',
+  version: 3,
+};
+
+describe('decryptKeystore', () => {
+  it('decrypts the pbkdf2 test vector', () => {
+    expect(decryptKeystore(JSON.stringify(PBKDF2_KEYSTORE), PASSWORD)).toBe(PRIVATE_KEY);
+  });
+
+  // scrypt with n = 2^18 takes a few seconds
+  it('decrypts the scrypt test vector', () => {
+    expect(decryptKeystore(JSON.stringify(SCRYPT_KEYSTORE), PASSWORD)).toBe(PRIVATE_KEY);
+  }, 60_000);
+
+  it('reads the capitalized Crypto section geth once wrote', () => {
+    const { crypto: section, ...rest } = PBKDF2_KEYSTORE;
+    expect(decryptKeystore(JSON.stringify({ ...rest, Crypto: section }), PASSWORD)).toBe(
+      PRIVATE_KEY
+    );
+  });
+
+  it('fails the MAC check on a wrong password or a corrupted ciphertext', () => {
+    const keystore = JSON.stringify(PBKDF2_KEYSTORE);
+    expect(() => decryptKeystore(keystore, 'wrongpassword')).toThrow(/MAC mismatch/);
+
+    const corrupted = {
+      ...PBKDF2_KEYSTORE,
+      crypto: {
+        ...PBKDF2_KEYSTORE.crypto,
+        ciphertext: '00' + PBKDF2_KEYSTORE.crypto.ciphertext.slice(2),
+      },
+    };
+    expect(() => decryptKeystore(JSON.stringify(corrupted), PASSWORD)).toThrow(/MAC mismatch/);
+  });
+
+  it('refuses unsupported ciphers and kdfs', () => {
+    const cipher = {
+      ...PBKDF2_KEYSTORE,
+      crypto: { ...PBKDF2_KEYSTORE.crypto, cipher: 'aes-128-cbc' },
+    };
+    expect(() => decryptKeystore(JSON.stringify(cipher), PASSWORD)).toThrow(/cipher: aes-128-cbc/);
+
+    const prf = {
+      ...PBKDF2_KEYSTORE,
+      crypto: {
+        ...PBKDF2_KEYSTORE.crypto,
+        kdfparams: { ...PBKDF2_KEYSTORE.crypto.kdfparams, prf: 'hmac-sha512' },
+      },
+    };
+    expect(() => decryptKeystore(JSON.stringify(prf), PASSWORD)).toThrow(/kdf: pbkdf2/);
+    expect(() => decryptKeystore('{}', PASSWORD)).toThrow(/missing crypto section/);
+  });
+});
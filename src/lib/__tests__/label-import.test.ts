@@ -0,0 +1,86 @@
+import { describe, expect, it } from '@jest/globals';
+import { labelsToOverlay, parseCsv, parseLabelCsv } from '../label-import';
+
+const A = '0x1111111111111111111111111111111111111111';
+const B = '0x2222222222222222222222222222222222222222';
+const C = '0x3333333333333333333333333333333333333333';
+
+describe('parseCsv', () => {
+  it('reads quoted fields with commas, quotes, and newlines', () => {
+    const text = '\uFEFFAddress,Name Tag\r\n"0x1","Vault, ""Main""\nv2"\r\n\r\n0x2,Plain\n';
+
+    expect(parseCsv(text)).toEqual([
+      ['Address', 'Name Tag'],
+      ['0x1', 'Vault, "Main"\nv2'],
+      ['0x2', 'Plain'],
+    ]);
+  });
+
+  it('refuses a quoted field that never closes', () => {
+    expect(() => parseCsv('address,name\n"0x1,Vault')).toThrow(/inside a quoted field/);
+  });
+});
+
+describe('parseLabelCsv', () => {
+  it('reads an Etherscan export for the given chain', () => {
+    const text = `Address,Name Tag,Note\n${A},Fee Vault,\n${B},,unnamed\n${A},Other,\n`;
+
+    expect(parseLabelCsv(text, { chainId: '8453' })).toEqual({
+      source: 'etherscan',
+      labels: [{ chainId: '8453', address: A, name: 'Fee Vault' }],
+      skipped: [
+        { row: 3, reason: 'no name' },
+        { row: 4, reason: `${A} is already labeled` },
+      ],
+    });
+  });
+
+  it('needs a chain for Etherscan exports', () => {
+    expect(() => parseLabelCsv(`Address,Name Tag\n${A},Vault\n`)).toThrow(/--chain-id/);
+  });
+
+  it('maps Dune blockchains to chain IDs and narrows to --chain-id', () => {
+    const text = [
+      'blockchain,address,name,category',
+      `ethereum,${A},Portal,infrastructure`,
+      `base,${B},Bridge,infrastructure`,
+      `solana,${C},Elsewhere,dex`,
+    ].join('\n');
+
+    expect(parseLabelCsv(text).labels).toEqual([
+      { chainId: '1', address: A, name: 'Portal' },
+      { chainId: '8453', address: B, name: 'Bridge' },
+    ]);
+    expect(parseLabelCsv(text).skipped).toEqual([
+      { row: 4, reason: 'unknown blockchain "solana"' },
+    ]);
+    expect(parseLabelCsv(text, { chainId: '8453' }).labels.map(l => l.name)).toEqual(['Bridge']);
+  });
+
+  it('fails on a malformed address with its row', () => {
+    expect(() => parseLabelCsv('address,name tag\n0x1234,Vault\n', { chainId: '1' })).toThrow(
+      /row 2/
+    );
+  });
+
+  it('refuses a CSV without an address column', () => {
+    expect(() => parseLabelCsv('wallet,name\n0x1,Vault\n', { chainId: '1' })).toThrow(
+      /no address column/
+    );
+  });
+});
+
+describe('labelsToOverlay', () => {
+  it('leaves out addresses contracts.json already configures', () => {
+    const { overlay, known } = labelsToOverlay(
+      [
+        { chainId: '1', address: A, name: 'Portal' },
+        { chainId: '1', address: B, name: 'Bridge' },
+      ],
+      { '1': { [A]: { name: 'OptimismPortal' } } }
+    );
+
+    expect(overlay).toEqual({ contracts: { '1': { [B]: { name: 'Bridge' } } } });
+    expect(known).toBe(1);
+  });
+});
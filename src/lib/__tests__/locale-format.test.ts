@@ -0,0 +1,52 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  localeAmount,
+  localeDateTime,
+  localeDecimal,
+  localeDuration,
+  resolveLocale,
+} from '../locale-format';
+
+describe('resolveLocale', () => {
+  it('canonicalizes tags and refuses ones that are not locales', () => {
+    expect(resolveLocale(' de-de ')).toBe('de-DE');
+    expect(() => resolveLocale('not a locale')).toThrow(/BCP 47/);
+  });
+});
+
+describe('localeDecimal', () => {
+  it('uses the locale separators without losing digits', () => {
+    expect(localeDecimal('123456789012345678901.5', 'de-DE')).toBe('123.456.789.012.345.678.901,5');
+    expect(localeDecimal('1234567', 'en-US')).toBe('1,234,567');
+    expect(localeDecimal('1234567.25', 'hi-IN')).toBe('12,34,567.25');
+  });
+});
+
+describe('localeAmount', () => {
+  it('reads values with the slot hints', () => {
+    expect(localeAmount(BigInt('1500000000'), 'de-DE', { format: 'gwei' })).toBe('1,5 gwei');
+    expect(localeAmount(BigInt('1234500000000000000000'), 'de-DE', { format: 'ether' })).toBe(
+      '1.234,5 ETH'
+    );
+    expect(localeAmount(BigInt(30000000), 'en-US')).toBe('30,000,000');
+  });
+});
+
+describe('localeDateTime', () => {
+  it('writes dates in UTC', () => {
+    const date = new Date('2026-10-16T10:00:00Z');
+
+    expect(localeDateTime(date, 'en-US')).toBe('October 16, 2026 at 10:00:00 AM UTC');
+    expect(localeDateTime(date, 'de-DE')).toBe('16. Oktober 2026 um 10:00:00 UTC');
+  });
+});
+
+describe('localeDuration', () => {
+  it('lists days, hours, and minutes', () => {
+    expect(localeDuration(72 * 3600 * 1000, 'en-US')).toBe('3 days');
+    expect(localeDuration((36 * 60 + 5) * 60 * 1000, 'de-DE')).toBe(
+      '1 Tag, 12 Stunden und 5 Minuten'
+    );
+    expect(localeDuration(30 * 1000, 'en-US')).toBe('0 minutes');
+  });
+});
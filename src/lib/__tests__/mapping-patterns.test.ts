@@ -0,0 +1,40 @@
+import { describe, expect, it } from '@jest/globals';
+import { mappingKey, parseMappingPattern, splitMappingPatterns } from '../mapping-patterns';
+
+const SLOT_8 = '0x0000000000000000000000000000000000000000000000000000000000000008';
+
+describe('parseMappingPattern', () => {
+  it('counts the wildcard keys', () => {
+    expect(parseMappingPattern('balances[*]')).toEqual({ name: 'balances', depth: 1 });
+    expect(parseMappingPattern('approvedHashes[*][*]')).toEqual({
+      name: 'approvedHashes',
+      depth: 2,
+    });
+  });
+
+  it('ignores plain slot keys', () => {
+    expect(parseMappingPattern(SLOT_8)).toBeNull();
+    expect(parseMappingPattern('balances[0]')).toBeNull();
+  });
+});
+
+describe('splitMappingPatterns', () => {
+  const cfg = { summary: 'approval' };
+
+  it('keys patterns by base slot and depth', () => {
+    const { slots, mappings } = splitMappingPatterns(
+      {
+        [SLOT_8]: cfg,
+        'approvedHashes[*][*]': { ...cfg, baseSlot: '8' },
+        'balances[*]': { ...cfg, baseSlot: '0x8' },
+      },
+      'test'
+    );
+    expect(Object.keys(slots)).toEqual([SLOT_8]);
+    expect(Object.keys(mappings)).toEqual([mappingKey(SLOT_8, 2), mappingKey(SLOT_8, 1)]);
+  });
+
+  it('requires a base slot', () => {
+    expect(() => splitMappingPatterns({ 'balances[*]': cfg }, 'test')).toThrow(/baseSlot/);
+  });
+});
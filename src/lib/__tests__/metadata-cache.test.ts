@@ -0,0 +1,61 @@
+import { describe, expect, it } from '@jest/globals';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  cachedMetadata,
+  ContractMetadata,
+  loadMetadataCache,
+  metadataCachePath,
+  saveMetadataCache,
+} from '../metadata-cache';
+
+const PROXY = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const OTHER = '0x73a79fab69143498ed3712e519a88a918e1f4072';
+
+const entry = (fetchedAt: string): ContractMetadata => ({
+  codeHash: `0x${'ab'.repeat(32)}`,
+  implementation: '0x1111111111111111111111111111111111111111',
+  patterns: ['Ownable'],
+  fetchedAt,
+});
+
+describe('metadata cache', () => {
+  it('round-trips entries and merges concurrent saves', async () => {
+    const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'metadata-cache-'));
+    const first = await loadMetadataCache(dir, '1', '0xpatterns');
+    const second = await loadMetadataCache(dir, '1', '0xpatterns');
+    expect(first.contracts).toEqual({});
+
+    await saveMetadataCache(first, { [PROXY]: entry('2026-10-01T00:00:00.000Z') });
+    await saveMetadataCache(second, { [OTHER]: entry('2026-10-02T00:00:00.000Z') });
+
+    const loaded = await loadMetadataCache(dir, '1', '0xpatterns');
+    expect(Object.keys(loaded.contracts).sort()).toEqual([OTHER, PROXY]);
+    expect(loaded.file).toBe(metadataCachePath(dir, '1'));
+  });
+
+  it('starts over when the patterns change or the file is unreadable', async () => {
+    const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'metadata-cache-'));
+    const cache = await loadMetadataCache(dir, '1', '0xpatterns');
+    await saveMetadataCache(cache, { [PROXY]: entry('2026-10-01T00:00:00.000Z') });
+
+    expect((await loadMetadataCache(dir, '1', '0xother')).contracts).toEqual({});
+    expect((await loadMetadataCache(dir, '10', '0xpatterns')).contracts).toEqual({});
+
+    await fs.writeFile(metadataCachePath(dir, '1'), '{');
+    expect((await loadMetadataCache(dir, '1', '0xpatterns')).contracts).toEqual({});
+  });
+
+  it('marks entries older than the TTL as stale', async () => {
+    const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'metadata-cache-'));
+    const cache = await loadMetadataCache(dir, '1', '0xpatterns');
+    cache.contracts[PROXY] = entry('2026-10-01T00:00:00.000Z');
+
+    const now = new Date('2026-10-01T12:00:00.000Z');
+    const checksummed = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+    expect(cachedMetadata(cache, checksummed, 24, now)).toMatchObject({ fresh: true });
+    expect(cachedMetadata(cache, PROXY, 6, now)).toMatchObject({ fresh: false });
+    expect(cachedMetadata(cache, OTHER, 24, now)).toBeNull();
+  });
+});
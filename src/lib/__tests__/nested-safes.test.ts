@@ -0,0 +1,161 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  custom,
+  decodeFunctionData,
+  encodeAbiParameters,
+  encodeFunctionResult,
+  Hex,
+  keccak256,
+  parseAbi,
+  toBytes,
+  toFunctionSelector,
+  zeroAddress,
+} from 'viem';
+import {
+  approveHashTx,
+  compareNestedHashes,
+  deriveNestedHashes,
+  MULTICALL3,
+} from '../nested-safes';
+import { computeSafeTxHash } from '../safe-hash';
+
+const TARGET = '0x7bB41C3008B3f03FE483B28b8DB90e19Cf07595c';
+const CHILD = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const EOA = '0x1111111111111111111111111111111111111111';
+const CONTRACT = '0x2222222222222222222222222222222222222222';
+const SAFE_TX_HASH = keccak256(toBytes('parent'));
+const CHILD_DOMAIN = keccak256(toBytes('child domain'));
+
+const abi = parseAbi([
+  'function getOwners() view returns (address[])',
+  'function nonce() view returns (uint256)',
+  'function domainSeparator() view returns (bytes32)',
+  'function approveHash(bytes32 hashToApprove)',
+  'struct Call3 { address target; bool allowFailure; bytes callData; }',
+  'function aggregate3(Call3[] calls) payable returns ((bool success, bytes returnData)[])',
+]);
+
+const transport = custom({
+  async request({ method, params }: { method: string; params?: unknown[] }) {
+    if (method === 'eth_chainId') return '0x1';
+    if (method === 'eth_getCode') {
+      const address = (params?.[0] as string).toLowerCase();
+      return address === EOA.toLowerCase() ? '0x' : '0x6001';
+    }
+    if (method === 'eth_call') {
+      const { to, data } = params?.[0] as { to: string; data: Hex };
+      const selector = data.slice(0, 10);
+      if (to.toLowerCase() === TARGET.toLowerCase()) {
+        return encodeFunctionResult({
+          abi,
+          functionName: 'getOwners',
+          result: [CHILD, EOA, CONTRACT],
+        });
+      }
+      if (to.toLowerCase() === CHILD.toLowerCase()) {
+        if (selector === toFunctionSelector('function nonce()')) {
+          return encodeFunctionResult({ abi, functionName: 'nonce', result: BigInt(12) });
+        }
+        return encodeFunctionResult({ abi, functionName: 'domainSeparator', result: CHILD_DOMAIN });
+      }
+      throw new Error('execution reverted');
+    }
+    throw new Error(`unexpected RPC call ${method}`);
+  },
+});
+
+// Independent of the implementation: the SafeTx struct hash from the Safe contracts
+function safeTxMessageHash(data: Hex, nonce: number) {
+  const typeHash = keccak256(
+    toBytes(
+      'SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 baseGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)'
+    )
+  );
+  return keccak256(
+    encodeAbiParameters(
+      [
+        { type: 'bytes32' },
+        { type: 'address' },
+        { type: 'uint256' },
+        { type: 'bytes32' },
+        { type: 'uint8' },
+        { type: 'uint256' },
+        { type: 'uint256' },
+        { type: 'uint256' },
+        { type: 'address' },
+        { type: 'address' },
+        { type: 'uint256' },
+      ],
+      [
+        typeHash,
+        MULTICALL3,
+        BigInt(0),
+        keccak256(data),
+        1,
+        BigInt(0),
+        BigInt(0),
+        BigInt(0),
+        zeroAddress,
+        zeroAddress,
+        BigInt(nonce),
+      ]
+    )
+  );
+}
+
+describe('approveHashTx', () => {
+  it('delegatecalls Multicall3 to approve the hash on the target', () => {
+    const tx = approveHashTx(TARGET, SAFE_TX_HASH);
+    expect(tx).toMatchObject({ to: MULTICALL3, value: BigInt(0), operation: 1 });
+    const { args } = decodeFunctionData({ abi, data: tx.data });
+    type Call3 = { target: string; allowFailure: boolean; callData: Hex };
+    const [call] = (args as unknown as [Call3[]])[0];
+    expect(call.target).toBe(TARGET);
+    expect(call.allowFailure).toBe(false);
+    expect(decodeFunctionData({ abi, data: call.callData }).args).toEqual([SAFE_TX_HASH]);
+  });
+});
+
+describe('deriveNestedHashes', () => {
+  it('lists hashes for owner Safes only', async () => {
+    const nested = await deriveNestedHashes(transport, TARGET, SAFE_TX_HASH);
+    const messageHash = safeTxMessageHash(approveHashTx(TARGET, SAFE_TX_HASH).data, 12);
+
+    expect(nested).toEqual({
+      [CHILD]: {
+        nonce: 12,
+        domainHash: CHILD_DOMAIN,
+        messageHash,
+        safeTxHash: computeSafeTxHash(CHILD_DOMAIN, messageHash),
+      },
+    });
+  });
+});
+
+describe('compareNestedHashes', () => {
+  const hashes = (safeTxHash: Hex, nonce = 12) => ({
+    nonce,
+    domainHash: CHILD_DOMAIN,
+    messageHash: SAFE_TX_HASH,
+    safeTxHash,
+  });
+
+  it('accepts matching hashes whatever the address case', () => {
+    const listed = { [CHILD.toLowerCase()]: hashes(SAFE_TX_HASH) };
+    expect(compareNestedHashes(listed, { [CHILD]: hashes(SAFE_TX_HASH) })).toEqual([]);
+  });
+
+  it('warns about changed, missing, and extra owner Safes', () => {
+    const other = keccak256(toBytes('other'));
+    const listed = { [CHILD]: hashes(SAFE_TX_HASH) };
+    expect(compareNestedHashes(listed, { [CHILD]: hashes(other, 13) })).toEqual([
+      `Owner Safe ${CHILD} approves with safeTxHash ${other} at nonce 13, but the validation file lists ${SAFE_TX_HASH} at nonce 12`,
+    ]);
+    expect(compareNestedHashes({}, { [CHILD]: hashes(SAFE_TX_HASH) })).toEqual([
+      `Owner Safe ${CHILD} has no hashes in the validation file`,
+    ]);
+    expect(compareNestedHashes(listed, {})).toEqual([
+      `The validation file lists hashes for ${CHILD}, which is not an owner Safe`,
+    ]);
+  });
+});
@@ -0,0 +1,28 @@
+import { describe, expect, it } from '@jest/globals';
+import { formatAmount, formatSlotWord, groupThousands, validateFormatHint } from '../number-format';
+
+describe('formatAmount', () => {
+  it('groups thousands and applies decimals or units', () => {
+    expect(groupThousands('-1234567.891011')).toBe('-1,234,567.891011');
+    expect(formatAmount(BigInt(999))).toBe('999');
+    expect(formatAmount(BigInt('1234567000000000000000'), { decimals: 18 })).toBe('1,234.567');
+    expect(formatAmount(BigInt(1500000000), { format: 'gwei' })).toBe('1.5 gwei');
+    expect(formatAmount(BigInt('2000000000000000000000'), { format: 'ether' })).toBe('2,000 ETH');
+    expect(formatSlotWord('not hex', { format: 'gwei' })).toBe('not hex');
+  });
+});
+
+describe('validateFormatHint', () => {
+  it('refuses unknown formats and decimals a unit already implies', () => {
+    expect(() => validateFormatHint({ decimals: 6 }, 'Slot 0x1')).not.toThrow();
+    expect(() => validateFormatHint({ decimals: 1.5 }, 'Slot 0x1')).toThrow(
+      'Slot 0x1: decimals must be an integer from 0 to 77'
+    );
+    expect(() => validateFormatHint({ format: 'wei' as never }, 'Slot 0x1')).toThrow(
+      'Slot 0x1: format must be one of: number, gwei, ether'
+    );
+    expect(() => validateFormatHint({ decimals: 9, format: 'gwei' }, 'Slot 0x1')).toThrow(
+      'Slot 0x1: gwei implies its decimals; give only one of them'
+    );
+  });
+});
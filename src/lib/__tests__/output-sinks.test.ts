@@ -0,0 +1,79 @@
+import { describe, expect, it, jest } from '@jest/globals';
+import { mkdtempSync, readFileSync } from 'fs';
+import os from 'os';
+import path from 'path';
+import { deliverToSinks, describeSink, parseSinks } from '../output-sinks';
+
+const PRESIGNED = 'https://bucket.s3.amazonaws.com/task.json?X-Amz-Signature=abc,def';
+const retry = { maxAttempts: 3, baseDelayMs: 0, maxDelayMs: 0 };
+const sleep = async () => {};
+
+describe('parseSinks', () => {
+  it('splits only before a sink kind and expands ${VAR}', () => {
+    const sinks = parseSinks(
+      [`file=out/a.json,s3=${PRESIGNED},stdout`, 'http=${HOOK}'],
+      '/work',
+      { HOOK: 'https://ci.example/hooks/secret' }
+    );
+    expect(sinks).toEqual([
+      { kind: 'file', path: path.resolve('/work', 'out/a.json') },
+      { kind: 's3', url: PRESIGNED },
+      { kind: 'stdout' },
+      { kind: 'http', url: 'https://ci.example/hooks/secret' },
+    ]);
+    expect(sinks.map(describeSink).slice(1)).toEqual([
+      's3 bucket.s3.amazonaws.com',
+      'stdout',
+      'http ci.example',
+    ]);
+  });
+
+  it('refuses unknown kinds, missing targets, and s3:// locations', () => {
+    expect(() => parseSinks(['ftp=host'])).toThrow(/Unknown sink "ftp"/);
+    expect(() => parseSinks(['file='])).toThrow(/needs a path/);
+    expect(() => parseSinks(['s3=s3://bucket/key'])).toThrow(/presigned https upload URL/);
+  });
+});
+
+describe('deliverToSinks', () => {
+  it('writes every sink and retries remote ones on server errors only', async () => {
+    const file = path.join(mkdtempSync(path.join(os.tmpdir(), 'sinks-')), 'copy', 'task.json');
+    const statuses = [503, 200, 400];
+    const fetchFn = jest.fn(async () => new Response(null, { status: statuses.shift() }));
+    const printed: string[] = [];
+
+    const deliveries = await deliverToSinks(
+      [
+        { kind: 'file', path: file },
+        { kind: 'stdout' },
+        { kind: 's3', url: PRESIGNED },
+        { kind: 'http', url: 'https://ci.example/hooks/secret' },
+      ],
+      '{"a":1}',
+      { format: 'json', fetchFn: fetchFn as never, retry, sleep, stdout: t => printed.push(t) }
+    );
+
+    expect(readFileSync(file, 'utf-8')).toBe('{"a":1}\n');
+    expect(printed).toEqual(['{"a":1}']);
+    expect(deliveries.map(d => [d.sink.kind, d.attempts, d.error])).toEqual([
+      ['file', 1, undefined],
+      ['stdout', 1, undefined],
+      ['s3', 2, undefined],
+      ['http', 1, 'status 400'],
+    ]);
+    const { calls } = (fetchFn as jest.Mock).mock;
+    expect(calls.map(c => (c[1] as RequestInit).method)).toEqual(['PUT', 'PUT', 'POST']);
+  });
+
+  it('gives up after the last attempt on network errors', async () => {
+    const fetchFn = jest.fn(async () => {
+      throw new Error('ECONNRESET');
+    });
+    const [delivery] = await deliverToSinks(
+      [{ kind: 'http', url: 'https://ci.example/hooks/secret' }],
+      '{}',
+      { format: 'json', fetchFn: fetchFn as never, retry, sleep }
+    );
+    expect(delivery).toMatchObject({ attempts: 3, error: 'ECONNRESET' });
+  });
+});
@@ -0,0 +1,80 @@
+import { describe, expect, it } from '@jest/globals';
+import { pad } from 'viem';
+import {
+  describeOverrideEvaluation,
+  evaluateOverrides,
+  overridesFromExpected,
+  overridesFromValidationFile,
+} from '../override-evaluation';
+import { fakeRpc } from '../state-diff-test';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const THRESHOLD = pad('0x4');
+const NONCE = pad('0x5');
+const OWNER_COUNT = pad('0x3');
+
+const stateOverrides = [
+  {
+    name: 'Safe',
+    address: SAFE,
+    overrides: [
+      { key: THRESHOLD, value: pad('0x1'), description: 'threshold 1' },
+      { key: NONCE, value: pad('0x2a'), description: 'nonce' },
+    ],
+  },
+];
+
+describe('evaluateOverrides', () => {
+  it('tells no-ops from changes against live state', async () => {
+    const { transport } = fakeRpc({
+      blockNumber: 7,
+      storage: { [SAFE]: { '0x4': '0x1', '0x5': '0x29' } },
+    });
+    const report = await evaluateOverrides(transport, overridesFromValidationFile(stateOverrides));
+    expect(report.blockNumber).toBe(BigInt(7));
+    expect(report.overrides.map(o => o.status)).toEqual(['no-op', 'change']);
+    expect(describeOverrideEvaluation(report.overrides[0])).toBe(
+      `Safe: ${SAFE} ${THRESHOLD} already holds ${pad('0x1')}; the override changes nothing`
+    );
+    expect(describeOverrideEvaluation(report.overrides[1])).toBe(
+      `Safe: ${SAFE} ${NONCE} changes from ${pad('0x29')} to ${pad('0x2a')}`
+    );
+  });
+
+  it('reports slots given two values, and before values the override does not explain', async () => {
+    const { transport } = fakeRpc({ storage: { [SAFE]: { '0x4': '0x2' } } });
+    const proposed = [
+      ...overridesFromValidationFile(stateOverrides),
+      ...overridesFromExpected([
+        { address: SAFE, key: '0x4', value: '0x2', reason: 'threshold 2' },
+        { address: SAFE, key: '0x3', value: '0x1', reason: 'one owner' },
+      ]),
+    ];
+    const stateChanges = [
+      {
+        name: 'Safe',
+        address: SAFE,
+        changes: [
+          {
+            key: OWNER_COUNT,
+            before: pad('0x3'),
+            after: pad('0x3'),
+            description: '',
+            allowDifference: false,
+          },
+        ],
+      },
+    ];
+    const report = await evaluateOverrides(transport, proposed, stateChanges);
+    expect(report.overrides.map(o => [o.label, o.status])).toEqual([
+      ['Safe', 'conflict'],
+      ['Safe', 'change'],
+      ['threshold 2', 'conflict'],
+      ['one owner', 'conflict'],
+    ]);
+    expect(report.overrides[0].conflict).toBe(`the slot is also overridden to ${pad('0x2')}`);
+    expect(report.overrides[3].conflict).toBe(
+      `the simulation saw ${pad('0x3')} before the transaction, not the override's value`
+    );
+  });
+});
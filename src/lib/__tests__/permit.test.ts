@@ -0,0 +1,93 @@
+import { describe, expect, it } from '@jest/globals';
+import { concat, keccak256, pad } from 'viem';
+import { hashTypedDataParts } from '../data-to-sign';
+import {
+  allowanceSlot,
+  DEFAULT_ALLOWANCE_SLOT,
+  describePermit,
+  parsePermit,
+  permitAccountAccesses,
+} from '../permit';
+
+const TOKEN = '0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913';
+const OWNER = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const SPENDER = '0x1111111111111111111111111111111111111111';
+
+const typedData = {
+  types: {
+    EIP712Domain: [
+      { name: 'name', type: 'string' },
+      { name: 'version', type: 'string' },
+      { name: 'chainId', type: 'uint256' },
+      { name: 'verifyingContract', type: 'address' },
+    ],
+    Permit: [
+      { name: 'owner', type: 'address' },
+      { name: 'spender', type: 'address' },
+      { name: 'value', type: 'uint256' },
+      { name: 'nonce', type: 'uint256' },
+      { name: 'deadline', type: 'uint256' },
+    ],
+  },
+  primaryType: 'Permit',
+  domain: { name: 'USD Coin', version: '2', chainId: 8453, verifyingContract: TOKEN },
+  message: { owner: OWNER, spender: SPENDER, value: '5000000', nonce: 0, deadline: '0x7fffffff' },
+};
+
+describe('parsePermit', () => {
+  it('reads the EIP-2612 fields', () => {
+    const permit = parsePermit(typedData);
+    expect(permit).toEqual({
+      token: TOKEN,
+      chainId: 8453,
+      owner: OWNER,
+      spender: SPENDER,
+      value: BigInt(5000000),
+      nonce: BigInt(0),
+      deadline: BigInt(0x7fffffff),
+    });
+    expect(describePermit(permit)).toBe(
+      `${OWNER} lets ${SPENDER} spend 5000000 of token ${TOKEN} (nonce 0, deadline 2147483647)`
+    );
+    // The report hashes the same typed data
+    expect(hashTypedDataParts(typedData).domainHash).toMatch(/^0x[0-9a-f]{64}$/);
+  });
+
+  it('refuses other typed data', () => {
+    expect(() => parsePermit({ ...typedData, primaryType: 'SafeTx' })).toThrow(/not Permit/);
+    const fields = typedData.types.Permit.slice(0, 4);
+    expect(() => parsePermit({ ...typedData, types: { Permit: fields } })).toThrow(
+      'got Permit(address owner, address spender, uint256 value, uint256 nonce)'
+    );
+    const message = { ...typedData.message, spender: 'nobody' };
+    expect(() => parsePermit({ ...typedData, message })).toThrow(
+      'Permit spender must be an address'
+    );
+  });
+});
+
+describe('allowanceSlot', () => {
+  it('derives allowance[owner][spender] with its preimages', () => {
+    const permit = parsePermit(typedData);
+    const owner = pad(OWNER).toLowerCase() as `0x${string}`;
+    const spender = pad(SPENDER).toLowerCase() as `0x${string}`;
+    const outer = keccak256(concat([owner, DEFAULT_ALLOWANCE_SLOT]));
+    const slot = keccak256(concat([spender, outer]));
+    const [first, second] = allowanceSlot(permit, DEFAULT_ALLOWANCE_SLOT);
+    expect(first).toEqual({ slot: outer, parent: DEFAULT_ALLOWANCE_SLOT, key: owner });
+    expect(second).toEqual({ slot, parent: outer, key: spender });
+
+    const [access] = permitAccountAccesses(permit, [{ slot, before: '0x0', after: '0x4c4b40' }]);
+    expect(access.account).toBe(TOKEN.toLowerCase());
+    expect(access.storageAccesses).toEqual([
+      {
+        account: TOKEN.toLowerCase(),
+        slot,
+        isWrite: true,
+        previousValue: pad('0x0'),
+        newValue: pad('0x4c4b40'),
+        reverted: false,
+      },
+    ]);
+  });
+});
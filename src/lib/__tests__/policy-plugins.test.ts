@@ -0,0 +1,72 @@
+import { describe, expect, it } from '@jest/globals';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { PolicyViolationError } from '../errors';
+import { checkPolicyPlugins, loadPolicyPlugin, runPolicyPlugin } from '../policy-plugins';
+
+/*
+ * A plugin that returns its input as its output, so each test's input is the findings:
+ *   (module
+ *     (memory (export "memory") 1)
+ *     (func (export "policy_abi_version") (result i32) i32.const 1)
+ *     (func (export "alloc") (param i32) (result i32) i32.const 1024)
+ *     (func (export "check") (param i32 i32) (result i64)
+ *       (i64.or (i64.shl (i64.extend_i32_u (local.get 0)) (i64.const 32))
+ *               (i64.extend_i32_u (local.get 1)))))
+ */
+const ECHO_PLUGIN =
+  'AGFzbQEAAAABEANgAAF/YAF/AX9gAn9/AX4DBAMAAQIFAwEAAQcvBAZtZW1vcnkCABJwb2xpY3lfYWJpX3ZlcnNpb24AAAVhbGxvYwABBWNoZWNrAAIKGQMEAEEBCwUAQYAICwwAIACtQiCGIAGthAs=';
+// (module (import "wasi_snapshot_preview1" "proc_exit" (func)))
+const WASI_PLUGIN = 'AGFzbQEAAAABBAFgAAACJAEWd2FzaV9zbmFwc2hvdF9wcmV2aWV3MQlwcm9jX2V4aXQAAA==';
+
+async function pluginFile(base64: string): Promise<string> {
+  const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'policy-plugin-'));
+  const file = path.join(dir, 'policy.wasm');
+  await fs.writeFile(file, Buffer.from(base64, 'base64'));
+  return file;
+}
+
+describe('policy plugins', () => {
+  it('passes the report in and reads the findings out', async () => {
+    const plugin = await loadPolicyPlugin(await pluginFile(ECHO_PLUGIN));
+    const findings = [{ severity: 'warning' as const, message: 'Touches the bridge', rule: 'b' }];
+
+    expect(runPolicyPlugin(plugin, { findings })).toEqual(findings);
+    expect(checkPolicyPlugins([plugin], { findings })).toEqual([
+      {
+        code: 'POLICY_FINDING',
+        severity: 'warning',
+        message: 'policy.wasm (b): Touches the bridge',
+        context: { plugin: 'policy.wasm', rule: 'b' },
+      },
+    ]);
+  });
+
+  it('refuses the report on an error finding', async () => {
+    const plugin = await loadPolicyPlugin(await pluginFile(ECHO_PLUGIN));
+    const findings = [
+      { severity: 'error', message: 'Upgrades need a ticket' },
+      { severity: 'error', message: 'Unknown bridge' },
+    ];
+
+    expect(() => checkPolicyPlugins([plugin], { findings })).toThrow(PolicyViolationError);
+    expect(() => checkPolicyPlugins([plugin], { findings })).toThrow(
+      'Policy plugins refused the report:\n  - policy.wasm: Upgrades need a ticket\n  - policy.wasm: Unknown bridge'
+    );
+  });
+
+  it('refuses plugins and output that do not follow the interface', async () => {
+    await expect(loadPolicyPlugin(await pluginFile(WASI_PLUGIN))).rejects.toThrow(
+      'Policy plugin policy.wasm imports wasi_snapshot_preview1.proc_exit'
+    );
+    await expect(loadPolicyPlugin(await pluginFile('AAAA'))).rejects.toThrow(
+      /is not a WebAssembly module/
+    );
+
+    const plugin = await loadPolicyPlugin(await pluginFile(ECHO_PLUGIN));
+    expect(() => runPolicyPlugin(plugin, { findings: [{ severity: 'fatal' }] })).toThrow(
+      /returned invalid findings: findings\.0\.severity/
+    );
+  });
+});
@@ -0,0 +1,62 @@
+import { describe, expect, it } from '@jest/globals';
+import { concat, Hex, keccak256, pad } from 'viem';
+import { parsePreimageDatabase, recordedPreimages, usedPreimages } from '../preimage-database';
+
+const OWNER = pad('0x9855054731540A48b28990B63DcF4f33d8AE46A1');
+const BALANCES = pad('0x1');
+// balances[owner] and balances[owner][7], as a nested mapping lays them out
+const ENTRY = keccak256(concat([OWNER, BALANCES]));
+const NESTED = keccak256(concat([pad('0x7'), ENTRY]));
+
+const database = (entries: Record<string, { parent: Hex; key: Hex }>) => JSON.stringify(entries);
+
+describe('parsePreimageDatabase', () => {
+  it('reads slot to parent and key mappings', () => {
+    const preimages = parsePreimageDatabase(
+      database({
+        [ENTRY.toUpperCase().replace('0X', '0x')]: { parent: BALANCES, key: OWNER },
+        [NESTED]: { parent: ENTRY, key: pad('0x7') },
+      })
+    );
+    expect(preimages).toEqual([
+      { slot: ENTRY, parent: BALANCES, key: OWNER.toLowerCase() },
+      { slot: NESTED, parent: ENTRY, key: pad('0x7') },
+    ]);
+  });
+
+  it('refuses entries that do not hash to their slot', () => {
+    const text = database({ [NESTED]: { parent: BALANCES, key: OWNER } });
+    expect(() => parsePreimageDatabase(text)).toThrow(
+      `Invalid preimage database: ${NESTED}: slot is not keccak256(key . parent)`
+    );
+  });
+
+  it('refuses malformed entries', () => {
+    const text = database({ [ENTRY]: { parent: '0x1', key: OWNER } });
+    expect(() => parsePreimageDatabase(text)).toThrow(`${ENTRY}.parent: Invalid hash format`);
+  });
+});
+
+describe('usedPreimages', () => {
+  const extra = [
+    { slot: NESTED, parent: ENTRY, key: pad('0x7') },
+    { slot: pad('0xabc'), parent: BALANCES, key: OWNER },
+  ];
+
+  it('keeps the extra preimages on the parent chains of the slots', () => {
+    // ENTRY's own preimage came from the simulation
+    const parentMap = new Map<Hex, Hex>([
+      [NESTED, ENTRY],
+      [ENTRY, BALANCES],
+    ]);
+    expect(usedPreimages([NESTED, pad('0x5')], parentMap, extra)).toEqual([extra[0]]);
+  });
+
+  it('round-trips through a validation file', () => {
+    const extraPreimages = [{ slot: NESTED.toUpperCase(), parent: ENTRY, key: pad('0x7') }];
+    expect(recordedPreimages({ extraPreimages })).toEqual([
+      { slot: NESTED.toLowerCase(), parent: ENTRY, key: pad('0x7') },
+    ]);
+    expect(recordedPreimages({})).toEqual([]);
+  });
+});
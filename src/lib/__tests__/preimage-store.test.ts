@@ -0,0 +1,110 @@
+import { describe, expect, it } from '@jest/globals';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { concat, getAddress, Hex, keccak256, pad } from 'viem';
+import {
+  exportPreimageStore,
+  importPreimageStore,
+  learnPreimages,
+  loadPreimageStore,
+  preimageStorePath,
+  savePreimageStore,
+  storedPreimages,
+} from '../preimage-store';
+
+const SAFE = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const OTHER = '0x73a79fab69143498ed3712e519a88a918e1f4072';
+
+// approvedHashes[owner][hash] of a Safe: mapping slot 8
+const BASE = pad('0x8');
+const OWNER = pad(OTHER);
+const HASH = `0x${'ab'.repeat(32)}` as Hex;
+const OUTER = keccak256(concat([OWNER, BASE]));
+const NESTED = keccak256(concat([HASH, OUTER]));
+
+describe('learnPreimages', () => {
+  it('follows each slot to its mapping and skips links that do not hash', () => {
+    const parentMap = new Map<Hex, Hex>([
+      [NESTED, OUTER],
+      [OUTER, BASE],
+    ]);
+    const preimageKeys = new Map<Hex, Hex>([
+      [NESTED, HASH],
+      [OUTER, OWNER],
+    ]);
+
+    const learned = learnPreimages(new Map([[SAFE, [NESTED]]]), parentMap, preimageKeys);
+    expect(learned).toEqual({
+      [SAFE]: [
+        { slot: NESTED, parent: OUTER, key: HASH },
+        { slot: OUTER, parent: BASE, key: OWNER },
+      ],
+    });
+
+    preimageKeys.set(OUTER, HASH);
+    expect(learnPreimages(new Map([[SAFE, [NESTED]]]), parentMap, preimageKeys)[SAFE]).toEqual([
+      { slot: NESTED, parent: OUTER, key: HASH },
+    ]);
+    expect(learnPreimages(new Map([[OTHER, [BASE]]]), parentMap, preimageKeys)).toEqual({});
+  });
+});
+
+describe('preimage store', () => {
+  it('keeps learned preimages per contract and merges concurrent saves', async () => {
+    const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'preimage-store-'));
+    const first = await loadPreimageStore(dir, '1');
+    const second = await loadPreimageStore(dir, '1');
+    expect(first.contracts).toEqual({});
+
+    const outer = { slot: OUTER, parent: BASE, key: OWNER };
+    const nested = { slot: NESTED, parent: OUTER, key: HASH };
+    expect(await savePreimageStore(first, { [SAFE]: [outer] })).toBe(1);
+    expect(await savePreimageStore(second, { [SAFE]: [outer, nested] })).toBe(1);
+
+    const loaded = await loadPreimageStore(dir, '1');
+    expect(storedPreimages(loaded, [getAddress(SAFE)])).toHaveLength(2);
+    expect(storedPreimages(loaded, [OTHER])).toEqual([]);
+  });
+
+  it('refuses a store with an entry that does not hash to its slot', async () => {
+    const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'preimage-store-'));
+    await fs.writeFile(
+      preimageStorePath(dir, '1'),
+      JSON.stringify({
+        version: 1,
+        chainId: '1',
+        contracts: { [SAFE]: { [NESTED]: { parent: BASE, key: OWNER } } },
+      })
+    );
+    await expect(loadPreimageStore(dir, '1')).rejects.toThrow(
+      `${SAFE}.${NESTED}: slot is not keccak256(key . parent)`
+    );
+  });
+
+  it('exports every chain and imports into another store', async () => {
+    const from = await fs.mkdtemp(path.join(os.tmpdir(), 'preimage-store-'));
+    const to = await fs.mkdtemp(path.join(os.tmpdir(), 'preimage-store-'));
+    await savePreimageStore(await loadPreimageStore(from, '1'), {
+      [SAFE]: [{ slot: OUTER, parent: BASE, key: OWNER }],
+    });
+    await savePreimageStore(await loadPreimageStore(from, '8453'), {
+      [OTHER]: [{ slot: OUTER, parent: BASE, key: OWNER }],
+    });
+
+    const exported = await exportPreimageStore(from);
+    expect(Object.keys(exported.chains)).toEqual(['1', '8453']);
+    const text = JSON.stringify(exported);
+    expect(await importPreimageStore(to, text)).toEqual({ '1': 1, '8453': 1 });
+    expect(await importPreimageStore(to, text)).toEqual({ '1': 0, '8453': 0 });
+    expect(await exportPreimageStore(to)).toEqual(exported);
+
+    const forged = {
+      version: 1,
+      chains: { '1': { [SAFE]: { [HASH]: { parent: BASE, key: OWNER } } } },
+    };
+    await expect(importPreimageStore(to, JSON.stringify(forged))).rejects.toThrow(
+      /slot is not keccak256/
+    );
+  });
+});
@@ -0,0 +1,56 @@
+import { describe, expect, it } from '@jest/globals';
+import { parsePrestateTrace, prestateTraceToAccountAccesses } from '../prestate-trace';
+import { AccountAccessKind } from '../vm-safe';
+
+const SAFE = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const NEW_CONTRACT = '0x1111111111111111111111111111111111111111';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+describe('parsePrestateTrace', () => {
+  it('unwraps a JSON-RPC response', () => {
+    const diff = parsePrestateTrace({ jsonrpc: '2.0', id: 1, result: { pre: {}, post: {} } });
+    expect(diff).toEqual({ pre: {}, post: {} });
+  });
+
+  it('rejects non-diffMode output', () => {
+    expect(() => parsePrestateTrace({ [SAFE]: { balance: '0x0' } })).toThrow(/diffMode/);
+  });
+});
+
+describe('prestateTraceToAccountAccesses', () => {
+  it('converts changed, cleared, and new slots into storage writes', () => {
+    const accesses = prestateTraceToAccountAccesses(
+      parsePrestateTrace({
+        pre: {
+          [SAFE]: {
+            balance: '0x10',
+            nonce: 5,
+            storage: { [word(4)]: word(2), [word(5)]: word(9) },
+          },
+        },
+        post: {
+          [SAFE]: { nonce: 6, storage: { [word(4)]: word(1), [word(6)]: word(3) } },
+        },
+      })
+    );
+
+    expect(accesses).toHaveLength(1);
+    const [safe] = accesses;
+    expect(safe.kind).toBe(AccountAccessKind.Call);
+    expect(safe.oldBalance).toBe(safe.newBalance);
+    expect(safe.storageAccesses.map(a => [a.slot, a.previousValue, a.newValue])).toEqual([
+      [word(4), word(2), word(1)],
+      [word(5), word(9), word(0)],
+      [word(6), word(0), word(3)],
+    ]);
+  });
+
+  it('marks accounts that only appear in post as created', () => {
+    const [created] = prestateTraceToAccountAccesses(
+      parsePrestateTrace({ pre: {}, post: { [NEW_CONTRACT]: { code: '0x6080', balance: '0x1' } } })
+    );
+
+    expect(created.kind).toBe(AccountAccessKind.Create);
+    expect(created.newBalance).toBe(BigInt(1));
+  });
+});
@@ -0,0 +1,49 @@
+import { afterEach, describe, expect, it } from '@jest/globals';
+import {
+  disableProgress,
+  enableProgress,
+  formatPhase,
+  reportProgress,
+  withPhase,
+} from '../progress';
+
+const fakeStream = (isTTY: boolean) => {
+  const writes: string[] = [];
+  const stream = { isTTY, columns: 80, write: (chunk: string) => writes.push(chunk) };
+  return { stream: stream as unknown as NodeJS.WriteStream, writes };
+};
+
+afterEach(() => disableProgress());
+
+describe('formatPhase', () => {
+  it('shows elapsed time, and a percentage once the phase reports one', () => {
+    const phase = { label: 'rpc.enrichment eth_getCode', startedAt: 1000 };
+    expect(formatPhase(phase, 3500)).toBe('rpc.enrichment eth_getCode 2.5s');
+    expect(formatPhase({ ...phase, done: 3, total: 40 }, 3500)).toBe(
+      'rpc.enrichment eth_getCode 3/40 (7%) 2.5s'
+    );
+  });
+});
+
+describe('progress line', () => {
+  it('stays off when stderr is not a terminal', async () => {
+    const { stream, writes } = fakeStream(false);
+    expect(enableProgress(stream)).toBe(false);
+
+    await withPhase('decode', async () => undefined);
+    expect(writes).toEqual([]);
+  });
+
+  it('clears the line around console output and when the last phase ends', async () => {
+    const { stream, writes } = fakeStream(true);
+    expect(enableProgress(stream)).toBe(true);
+
+    await withPhase('decode', async () => {
+      reportProgress(1, 2);
+      await new Promise(resolve => setTimeout(resolve, 150));
+      expect(writes.some(w => w.includes('decode 1/2 (50%)'))).toBe(true);
+    });
+
+    expect(writes[writes.length - 1]).toBe('\r\x1b[K');
+  });
+});
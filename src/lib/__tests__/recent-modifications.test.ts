@@ -0,0 +1,83 @@
+import { describe, expect, it } from '@jest/globals';
+import { mkdirSync, mkdtempSync, writeFileSync } from 'fs';
+import { tmpdir } from 'os';
+import path from 'path';
+import type { Address } from 'viem';
+import {
+  describeRecentModification,
+  findRecentModifications,
+  HistoryEntry,
+  loadHistory,
+  owningTaskDir,
+} from '../recent-modifications';
+import type { TaskConfig } from '../types';
+
+const PROXY = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const OTHER = '0x1111111111111111111111111111111111111111';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+function config(chainId: number, changes: Record<string, number[]>): TaskConfig {
+  return {
+    cmd: 'forge script Upgrade',
+    ledgerId: 0,
+    rpcUrl: 'https://rpc.example',
+    chainId,
+    expectedDomainAndMessageHashes: { address: PROXY, domainHash: word(1), messageHash: word(2) },
+    stateOverrides: [],
+    stateChanges: Object.entries(changes).map(([address, slots]) => ({
+      name: 'Contract',
+      address: address as Address,
+      changes: slots.map(slot => ({
+        key: word(slot),
+        before: word(0),
+        after: word(1),
+        description: '',
+        allowDifference: false,
+      })),
+    })),
+  };
+}
+
+describe('findRecentModifications', () => {
+  it('lists shared contracts with the slots both files change', () => {
+    const history: HistoryEntry[] = [
+      { file: 'b/base-sc.json', config: config(1, { [PROXY]: [3, 4] }) },
+      { file: 'a/base-sc.json', config: config(1, { [PROXY]: [9], [OTHER]: [1] }) },
+    ];
+    const found = findRecentModifications(config(1, { [PROXY]: [4, 5] }), history);
+    expect(found).toEqual([
+      { name: 'Contract', address: PROXY, file: 'a/base-sc.json', keys: [] },
+      { name: 'Contract', address: PROXY, file: 'b/base-sc.json', keys: [word(4)] },
+    ]);
+    expect(describeRecentModification(found[1])).toBe(
+      `Contract (${PROXY}) was also changed by b/base-sc.json, which touches the same 1 slot(s)`
+    );
+  });
+
+  it('ignores files for another chain', () => {
+    const history = [{ file: 'sepolia.json', config: config(11155111, { [PROXY]: [4] }) }];
+    expect(findRecentModifications(config(1, { [PROXY]: [4] }), history)).toEqual([]);
+  });
+});
+
+describe('loadHistory', () => {
+  it('skips the current task and files that are not validation files', () => {
+    const root = mkdtempSync(path.join(tmpdir(), 'history-'));
+    const write = (file: string, content: string) => {
+      mkdirSync(path.dirname(path.join(root, file)), { recursive: true });
+      writeFileSync(path.join(root, file), content);
+    };
+    const valid = JSON.stringify(config(1, { [PROXY]: [4] }));
+    write('tasks/2025-01-01-old/config/mainnet/validations/base-sc.json', valid);
+    write('tasks/2025-02-01-new/config/mainnet/validations/base-sc.json', valid);
+    write('tasks/2025-01-01-old/foundry.json', '{}');
+
+    const current = path.join(root, 'tasks/2025-02-01-new/config/mainnet/validations/cb.json');
+    const { entries, skipped } = loadHistory(root, owningTaskDir(current) ?? undefined);
+
+    expect(entries.map(e => e.file)).toEqual([
+      path.join('tasks', '2025-01-01-old', 'config', 'mainnet', 'validations', 'base-sc.json'),
+    ]);
+    expect(skipped).toBe(1);
+  });
+});
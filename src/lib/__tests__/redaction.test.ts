@@ -0,0 +1,91 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  newRedactionSalt,
+  parsePrivacyList,
+  REDACTED_CALLDATA_PLACEHOLDER,
+  REDACTED_PLACEHOLDER,
+  redactedCopyPath,
+  redactTaskConfig,
+  verifyRedactions,
+} from '../redaction';
+
+const PRIVATE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+const paddedPrivate = '0x' + PRIVATE.slice(2).toLowerCase().padStart(64, '0');
+
+const config = {
+  cmd: `forge script Run --sig 'run(bytes)' 0x${'ab'.repeat(68)} --sender ${PRIVATE}`,
+  stateChanges: [
+    {
+      name: 'Proxy',
+      address: '0x1111111111111111111111111111111111111111',
+      changes: [
+        { key: word(1), before: word(0), after: paddedPrivate, description: 'Owner' },
+        { key: word(7), before: word(2), after: word(3), description: 'Secret slot' },
+      ],
+    },
+  ],
+};
+
+describe('redactTaskConfig', () => {
+  const privacy = parsePrivacyList({ addresses: [PRIVATE], slotPatterns: ['^0x0+7$'] });
+  const salt = newRedactionSalt();
+  const redacted = redactTaskConfig(config, privacy, salt);
+
+  it('replaces calldata and privacy-listed addresses', () => {
+    expect(redacted.cmd).toBe(
+      `forge script Run --sig 'run(bytes)' ${REDACTED_CALLDATA_PLACEHOLDER} --sender ${REDACTED_PLACEHOLDER}`
+    );
+    expect(redacted.stateChanges[0].changes[0].after).toBe(REDACTED_PLACEHOLDER);
+    expect(redacted.stateChanges[0].address).toBe(config.stateChanges[0].address);
+  });
+
+  it('blanks storage entries whose slot matches a pattern', () => {
+    const secret = redacted.stateChanges[0].changes[1];
+    expect([secret.key, secret.before, secret.after]).toEqual([
+      REDACTED_PLACEHOLDER,
+      REDACTED_PLACEHOLDER,
+      REDACTED_PLACEHOLDER,
+    ]);
+    expect(secret.description).toBe('Secret slot');
+  });
+
+  it('records hashes that verify against the full file', () => {
+    expect(redacted.redactions.map(r => r.path)).toEqual([
+      'cmd',
+      'stateChanges[0].changes[0].after',
+      'stateChanges[0].changes[1].key',
+      'stateChanges[0].changes[1].before',
+      'stateChanges[0].changes[1].after',
+    ]);
+    expect(verifyRedactions(config, redacted.redactions, salt)).toEqual([]);
+
+    const tampered = structuredClone(config);
+    tampered.stateChanges[0].changes[1].after = word(4);
+    expect(verifyRedactions(tampered, redacted.redactions, salt)).toEqual([
+      'stateChanges[0].changes[1].after',
+    ]);
+  });
+
+  it('commits under the salt, so a guessed value cannot be confirmed without it', () => {
+    const owner = redacted.redactions.find(r => r.path === 'stateChanges[0].changes[0].after')!;
+    const guess = { stateChanges: [{ changes: [{ after: paddedPrivate }] }] };
+
+    expect(verifyRedactions(guess, [owner], salt)).toEqual([]);
+    expect(verifyRedactions(guess, [owner], newRedactionSalt())).toEqual([owner.path]);
+    expect(JSON.stringify(redacted)).not.toContain(salt.slice(2));
+  });
+});
+
+describe('redactedCopyPath', () => {
+  it('puts the copy next to the full file', () => {
+    expect(redactedCopyPath('/tasks/base-sc.json')).toBe('/tasks/base-sc.redacted.json');
+    expect(redactedCopyPath('/tasks/base-sc.yaml')).toBe('/tasks/base-sc.redacted.yaml');
+  });
+});
+
+describe('parsePrivacyList', () => {
+  it('rejects invalid slot patterns', () => {
+    expect(() => parsePrivacyList({ slotPatterns: ['('] })).toThrow(/Invalid privacy list/);
+  });
+});
@@ -0,0 +1,108 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  applyReportAudience,
+  assertCompleteView,
+  omittedSections,
+  parseReportAudience,
+  REPORT_AUDIENCES,
+  SECTION_AUDIENCES,
+  VALIDATED_SECTIONS,
+} from '../report-audience';
+import type { TaskConfig } from '../types';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const HASH = `0x${'11'.repeat(32)}`;
+const WORD = (n: number) => `0x${n.toString(16).padStart(64, '0')}`;
+
+const config = {
+  cmd: 'forge script',
+  ledgerId: 0,
+  rpcUrl: 'https://mainnet.example',
+  chainId: 1,
+  expectedDomainAndMessageHashes: { address: SAFE, domainHash: HASH, messageHash: HASH },
+  stateOverrides: [
+    {
+      name: 'Safe',
+      address: SAFE,
+      overrides: [{ key: WORD(4), value: WORD(1), description: 'Threshold' }],
+    },
+  ],
+  stateChanges: [
+    {
+      name: 'Safe',
+      address: SAFE,
+      changes: [
+        {
+          key: WORD(5),
+          before: WORD(7),
+          after: WORD(9),
+          description: 'Nonce',
+          allowDifference: false,
+          history: [
+            { value: WORD(8), writer: SAFE, depth: 1, reverted: false },
+            { value: WORD(9), writer: SAFE, depth: 1, reverted: false },
+          ],
+        },
+      ],
+    },
+  ],
+  warnings: [{ code: 'UNKNOWN_SLOTS', severity: 'warning', message: '1 slot is unknown' }],
+  raw: { stateDiff: '0x', overrides: '0x', preimages: '0x', dataToSign: '0x', targetSafe: SAFE },
+} as unknown as TaskConfig;
+
+describe('applyReportAudience', () => {
+  it('gives signers the hashes, overrides, and changes without warnings or the trace', () => {
+    const view = applyReportAudience(config, 'signer');
+
+    expect(view.audience).toBe('signer');
+    expect(view.expectedDomainAndMessageHashes).toEqual(config.expectedDomainAndMessageHashes);
+    expect(view.stateOverrides).toEqual(config.stateOverrides);
+    expect(view.warnings).toBeUndefined();
+    expect(view.raw).toBeUndefined();
+    expect(view.stateChanges[0].changes[0]).not.toHaveProperty('history');
+    expect(view.stateChanges[0].changes[0].after).toBe(WORD(9));
+  });
+
+  it('adds warnings and the trace for facilitators, and raw for auditors', () => {
+    const facilitator = applyReportAudience(config, 'facilitator');
+    const auditor = applyReportAudience(config, 'auditor');
+
+    expect(facilitator.stateOverrides).toEqual(config.stateOverrides);
+    expect(facilitator.warnings).toEqual(config.warnings);
+    expect(facilitator.stateChanges).toEqual(config.stateChanges);
+    expect(facilitator.raw).toBeUndefined();
+    expect(auditor).toEqual({ ...config, audience: 'auditor' });
+  });
+
+  it('keeps every section validation compares in every view', () => {
+    for (const section of VALIDATED_SECTIONS) expect(SECTION_AUDIENCES[section]).toBe('signer');
+    for (const audience of REPORT_AUDIENCES) {
+      expect(omittedSections(config, audience)).not.toContain('stateOverrides');
+    }
+  });
+});
+
+describe('assertCompleteView', () => {
+  it('refuses a view that leaves out entries the re-run found', () => {
+    const signer = { ...applyReportAudience(config, 'signer'), stateOverrides: [] };
+
+    expect(() => assertCompleteView(signer, 1)).toThrow(/signer view that leaves out 1/);
+    expect(() => assertCompleteView(signer, 0)).not.toThrow();
+    // A file without an audience fails on the comparison instead
+    expect(() => assertCompleteView(config, 1)).not.toThrow();
+  });
+});
+
+describe('omittedSections', () => {
+  it('lists what the view leaves out, in file order', () => {
+    expect(omittedSections(config, 'signer')).toEqual(['warnings', 'raw']);
+    expect(omittedSections(config, 'auditor')).toEqual([]);
+  });
+});
+
+describe('parseReportAudience', () => {
+  it('refuses unknown audiences', () => {
+    expect(parseReportAudience('auditor')).toBe('auditor');
+    expect(() => parseReportAudience('owner')).toThrow(/signer, facilitator, auditor/);
+  });
+});
@@ -0,0 +1,96 @@
+import { describe, expect, it } from '@jest/globals';
+import { getAddress } from 'viem';
+import {
+  categoryOf,
+  describeReportGroups,
+  GROUPING_THRESHOLD,
+  groupContracts,
+  ReportCategory,
+} from '../report-groups';
+import { summarizeReport } from '../report-summary';
+import { UNKNOWN_CONTRACT_NAME } from '../unknown-entries';
+
+const addr = (n: number) => getAddress('0x' + n.toString(16).padStart(40, '0'));
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const counts = (n: number, name: string, slotsChanged: number, category?: ReportCategory) => ({
+  name,
+  address: addr(n),
+  slotsChanged,
+  overrides: 1,
+  balanceChanged: false,
+  ...(category ? { category } : {}),
+});
+
+describe('categoryOf', () => {
+  it('takes the riskiest category the tags name', () => {
+    expect(categoryOf('Portal', ['proxy', 'safe'])).toBe('safe');
+    expect(categoryOf('USDC', ['stablecoin', 'token'])).toBe('token');
+  });
+
+  it('falls back to unknown for unnamed contracts and other for named ones', () => {
+    expect(categoryOf(UNKNOWN_CONTRACT_NAME)).toBe('unknown');
+    expect(categoryOf(`unknown (${addr(7)})`, ['audited'])).toBe('unknown');
+    expect(categoryOf('AddressManager')).toBe('other');
+  });
+});
+
+describe('groupContracts', () => {
+  it('orders groups by risk and contracts by slots changed', () => {
+    const groups = groupContracts([
+      counts(1, 'Bridge', 1, 'proxy'),
+      counts(2, 'Token', 4, 'token'),
+      counts(3, UNKNOWN_CONTRACT_NAME, 2),
+      counts(4, 'Portal', 3, 'proxy'),
+      counts(5, 'Safe', 1, 'safe'),
+    ]);
+
+    expect(groups.map(g => g.category)).toEqual(['unknown', 'safe', 'proxy', 'token']);
+    expect(groups[2]).toEqual({
+      category: 'proxy',
+      contracts: 2,
+      slotsChanged: 4,
+      overrides: 2,
+      addresses: [addr(4), addr(1)],
+    });
+    expect(describeReportGroups(groups)[2]).toBe(
+      'proxy: 2 contract(s), 4 slot(s) changed, 2 override(s)'
+    );
+  });
+});
+
+describe('summarizeReport groups', () => {
+  const report = (touched: number) => ({
+    stateOverrides: [],
+    stateChanges: Array.from({ length: touched }, (_, i) => ({
+      name: i === 0 ? 'Safe' : `Contract ${i}`,
+      address: addr(i + 1),
+      changes: [
+        { key: word(1), before: word(0), after: word(1), description: 'x', allowDifference: false },
+      ],
+    })),
+    balanceChanges: [],
+  });
+  const categories = new Map<string, ReportCategory>([[addr(1).toLowerCase(), 'safe']]);
+
+  it('records categories but no groups below the threshold', () => {
+    const summary = summarizeReport(report(GROUPING_THRESHOLD - 1), categories);
+    expect(summary.contracts[0].category).toBe('safe');
+    expect(summary.contracts[1].category).toBe('other');
+    expect(summary.groups).toBeUndefined();
+  });
+
+  it('groups tasks touching the threshold or more', () => {
+    const summary = summarizeReport(report(GROUPING_THRESHOLD), categories);
+    expect(summary.groups?.map(g => [g.category, g.contracts])).toEqual([
+      ['safe', 1],
+      ['other', GROUPING_THRESHOLD - 1],
+    ]);
+  });
+
+  it('leaves categories out without contracts.json tags', () => {
+    const summary = summarizeReport(report(GROUPING_THRESHOLD));
+    expect(summary.contracts[0]).not.toHaveProperty('category');
+    expect(summary.groups).toBeUndefined();
+  });
+});
@@ -0,0 +1,158 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  applyReportScope,
+  describeFilteredCounts,
+  findUnlistedEntries,
+  parseAddressList,
+} from '../report-scope';
+import { reportWarning } from '../report-warnings';
+import { buildValidationItems, hasBlockingErrors } from '../validation-results-utils';
+import type { TaskConfig } from '../types';
+
+const KEEP = '0x73a79Fab69143498Ed3712e519A88a918e1f4072';
+const BOOKKEEPING = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const change = (n: number) => ({
+  key: word(n),
+  before: word(0),
+  after: word(n),
+  description: '',
+  allowDifference: false,
+});
+
+const config: TaskConfig = {
+  cmd: 'forge script Upgrade',
+  ledgerId: 0,
+  rpcUrl: 'https://rpc.example',
+  expectedDomainAndMessageHashes: { address: KEEP, domainHash: word(1), messageHash: word(2) },
+  stateOverrides: [
+    {
+      name: 'Bookkeeping',
+      address: BOOKKEEPING,
+      overrides: [{ key: word(1), value: word(1), description: '' }],
+    },
+  ],
+  stateChanges: [
+    { name: 'Keep', address: KEEP, changes: [change(1)] },
+    { name: 'Bookkeeping', address: BOOKKEEPING, changes: [change(2), change(3)] },
+  ],
+  balanceChanges: [],
+};
+
+describe('applyReportScope', () => {
+  it('drops excluded contracts and records how many entries were filtered', () => {
+    const scoped = applyReportScope(config, { exclude: [BOOKKEEPING] });
+
+    expect(scoped.stateChanges.map(sc => sc.address)).toEqual([KEEP]);
+    expect(scoped.stateOverrides).toEqual([]);
+    expect(scoped.scope).toEqual({
+      exclude: [BOOKKEEPING],
+      filtered: {
+        stateOverrides: 1,
+        stateChanges: 2,
+        balanceChanges: 0,
+        ethTransfers: 0,
+        accountDeletions: 0,
+        codeChanges: 0,
+        intermediateWrites: 0,
+        criticalReads: 0,
+        recentlyModified: 0,
+      },
+    });
+    expect(describeFilteredCounts(scoped.scope!)).toMatch(/^2 state change\(s\), 1 override\(s\)/);
+  });
+
+  it('keeps only listed contracts, matching addresses case-insensitively', () => {
+    const scoped = applyReportScope(config, { only: [KEEP.toLowerCase() as `0x${string}`] });
+
+    expect(scoped.stateChanges.map(sc => sc.name)).toEqual(['Keep']);
+    expect(scoped.scope?.filtered.stateChanges).toBe(2);
+  });
+
+  it('filters critical reads with the rest of the report', () => {
+    const read = (name: string, address: typeof KEEP) => ({
+      name,
+      address,
+      key: word(4),
+      value: word(1),
+      description: 'threshold',
+    });
+    const scoped = applyReportScope(
+      { ...config, criticalReads: [read('Keep', KEEP), read('Bookkeeping', BOOKKEEPING)] },
+      { exclude: [BOOKKEEPING] }
+    );
+
+    expect(scoped.criticalReads?.map(r => r.name)).toEqual(['Keep']);
+    expect(scoped.scope?.filtered.criticalReads).toBe(1);
+  });
+
+  it('counts and describes an excluded code change', () => {
+    const codeChange = (name: string, address: typeof KEEP) => ({
+      name,
+      address,
+      kind: 'implementation' as const,
+    });
+    const scoped = applyReportScope(
+      {
+        ...config,
+        codeChanges: [codeChange('Keep', KEEP), codeChange('Bookkeeping', BOOKKEEPING)],
+      },
+      { exclude: [BOOKKEEPING] }
+    );
+
+    expect(scoped.codeChanges?.map(c => c.name)).toEqual(['Keep']);
+    expect(scoped.scope?.filtered.codeChanges).toBe(1);
+    expect(describeFilteredCounts(scoped.scope!)).toBe(
+      '2 state change(s), 1 override(s), 0 balance change(s) and 1 code change(s) are outside the report scope'
+    );
+  });
+
+  it('describes nothing when every section is in scope', () => {
+    const scoped = applyReportScope(config, { only: [KEEP, BOOKKEEPING] });
+
+    expect(describeFilteredCounts(scoped.scope!)).toBeNull();
+  });
+});
+
+describe('findUnlistedEntries', () => {
+  it('counts what a scoped file leaves out of the full simulation', () => {
+    const scoped = applyReportScope(config, { exclude: [BOOKKEEPING] });
+
+    expect(findUnlistedEntries(scoped, config)).toEqual({
+      stateOverrides: 1,
+      stateChanges: 2,
+      balanceChanges: 0,
+      contracts: [BOOKKEEPING],
+    });
+    expect(findUnlistedEntries(config, scoped)).toEqual({
+      stateOverrides: 0,
+      stateChanges: 0,
+      balanceChanges: 0,
+      contracts: [],
+    });
+  });
+
+  it("blocks signing when an excluded contract's change is in the re-run", () => {
+    const scoped = applyReportScope(config, { exclude: [BOOKKEEPING] });
+    const side = (c: TaskConfig) => ({
+      stateOverrides: c.stateOverrides,
+      stateChanges: c.stateChanges,
+      balanceChanges: c.balanceChanges,
+    });
+    // Every entry the file lists matches, so only the unlisted changes fail validation
+    const items = buildValidationItems({ expected: side(scoped), actual: side(config) });
+    expect(hasBlockingErrors(items)).toBe(false);
+
+    const unlisted = findUnlistedEntries(scoped, config);
+    const warning = reportWarning('UNLISTED_ENTRIES', `${unlisted.stateChanges} unlisted`);
+    expect(hasBlockingErrors(items, [warning])).toBe(true);
+  });
+});
+
+describe('parseAddressList', () => {
+  it('checksums entries and rejects invalid ones', () => {
+    expect(parseAddressList(` ${KEEP.toLowerCase()}, `, '--only')).toEqual([KEEP]);
+    expect(() => parseAddressList('0x1234', '--exclude')).toThrow(/--exclude: invalid address/);
+  });
+});
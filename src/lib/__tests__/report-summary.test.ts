@@ -0,0 +1,119 @@
+import { describe, expect, it } from '@jest/globals';
+import { Address } from 'viem';
+import { applyReportScope } from '../report-scope';
+import { describeReportSummary, summarizeReport } from '../report-summary';
+import type { TaskConfig } from '../types';
+import { UNKNOWN_SLOT_SUMMARY } from '../unknown-entries';
+
+const PORTAL = '0x73a79Fab69143498Ed3712e519A88a918e1f4072';
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const change = (n: number, description: string) => ({
+  key: word(n),
+  before: word(0),
+  after: word(n),
+  description,
+  allowDifference: false,
+});
+
+const balance = (name: string, address: Address, before: number, after: number) => ({
+  name,
+  address,
+  field: 'ETH Balance (wei)',
+  before: word(before),
+  after: word(after),
+  description: '',
+  allowDifference: false,
+});
+
+const report: Pick<TaskConfig, 'stateOverrides' | 'stateChanges' | 'balanceChanges'> = {
+  stateOverrides: [
+    {
+      name: 'Safe',
+      address: SAFE,
+      overrides: [
+        { key: word(4), value: word(1), description: 'threshold' },
+        { key: word(5), value: word(1), description: 'nonce' },
+      ],
+    },
+  ],
+  stateChanges: [
+    {
+      name: 'Portal',
+      address: PORTAL,
+      changes: [change(1, 'implementation'), change(2, UNKNOWN_SLOT_SUMMARY)],
+    },
+    { name: 'Safe', address: SAFE, changes: [change(5, 'nonce')] },
+  ],
+  balanceChanges: [balance('Portal', PORTAL, 0, 300), balance('Safe', SAFE, 500, 200)],
+};
+
+describe('summarizeReport', () => {
+  it('counts changes and rolls them up per contract', () => {
+    expect(summarizeReport(report)).toEqual({
+      contractsTouched: 2,
+      unknownContracts: 0,
+      slotsChanged: 3,
+      unknownSlots: 1,
+      overridesApplied: 2,
+      ethMoved: '300',
+      contracts: [
+        { name: 'Portal', address: PORTAL, slotsChanged: 2, overrides: 0, balanceChanged: true },
+        { name: 'Safe', address: SAFE, slotsChanged: 1, overrides: 2, balanceChanged: true },
+      ],
+    });
+  });
+
+  it('does not count contracts that were only overridden as touched', () => {
+    const summary = summarizeReport({ ...report, stateChanges: [], balanceChanges: [] });
+    expect(summary.contractsTouched).toBe(0);
+    expect(summary.contracts.map(c => c.address)).toEqual([SAFE]);
+  });
+
+  it('is recounted when a report scope drops contracts', () => {
+    const config = {
+      cmd: 'forge script Upgrade',
+      ledgerId: 0,
+      rpcUrl: 'https://rpc.example',
+      expectedDomainAndMessageHashes: { address: SAFE, domainHash: word(1), messageHash: word(2) },
+      ...report,
+      summary: summarizeReport(report),
+    } as TaskConfig;
+
+    const scoped = applyReportScope(config, { exclude: [SAFE] });
+    expect(scoped.summary).toMatchObject({ contractsTouched: 1, slotsChanged: 2, ethMoved: '300' });
+  });
+
+  it('keeps contract categories when a report scope recounts it', () => {
+    const config = {
+      cmd: 'forge script Upgrade',
+      ledgerId: 0,
+      rpcUrl: 'https://rpc.example',
+      expectedDomainAndMessageHashes: { address: SAFE, domainHash: word(1), messageHash: word(2) },
+      ...report,
+      summary: summarizeReport(report, new Map([[PORTAL.toLowerCase(), 'proxy' as const]])),
+    } as TaskConfig;
+
+    const scoped = applyReportScope(config, { exclude: [SAFE] });
+    expect(scoped.summary?.contracts).toEqual([
+      expect.objectContaining({ address: PORTAL, category: 'proxy' }),
+    ]);
+  });
+
+  it('keeps the noise count when a report scope recounts it', () => {
+    const noiseFiltered = { stateChanges: 2, balanceChanges: 1 };
+    const config = {
+      cmd: 'forge script Upgrade',
+      ledgerId: 0,
+      rpcUrl: 'https://rpc.example',
+      expectedDomainAndMessageHashes: { address: SAFE, domainHash: word(1), messageHash: word(2) },
+      ...report,
+      summary: { ...summarizeReport(report), noiseFiltered },
+    } as TaskConfig;
+
+    const scoped = applyReportScope(config, { exclude: [SAFE] });
+    expect(scoped.summary?.noiseFiltered).toEqual(noiseFiltered);
+    expect(describeReportSummary(scoped.summary!)).toMatch(/, 3 noise change\(s\) filtered$/);
+  });
+});
@@ -0,0 +1,108 @@
+import { describe, expect, it } from '@jest/globals';
+import { ReportWarningSchema } from '../config-schemas';
+import { buildValidationItems, hasBlockingErrors } from '../validation-results-utils';
+import {
+  appendWarnings,
+  isBlockingWarning,
+  reportWarning,
+  unknownEntryWarnings,
+  WARNING_CODES,
+} from '../report-warnings';
+
+describe('reportWarning', () => {
+  it("takes the code's severity and leaves out empty context", () => {
+    expect(reportWarning('ACCOUNT_DELETED', 'gone', { address: '0x1' })).toEqual({
+      code: 'ACCOUNT_DELETED',
+      severity: 'critical',
+      message: 'gone',
+      context: { address: '0x1' },
+    });
+    expect(reportWarning('UNKNOWN_SLOTS', 'slots', {})).toEqual({
+      code: 'UNKNOWN_SLOTS',
+      severity: WARNING_CODES.UNKNOWN_SLOTS,
+      message: 'slots',
+    });
+  });
+
+  it('produces entries the validation file schema accepts', () => {
+    const warning = reportWarning('CHAIN_MISMATCH', 'wrong chain', { expected: 1, actual: 8453 });
+    expect(ReportWarningSchema.parse(warning)).toEqual(warning);
+    // Codes from a newer tool still load
+    expect(ReportWarningSchema.safeParse({ ...warning, code: 'NEW_CODE' }).success).toBe(true);
+    expect(ReportWarningSchema.safeParse({ ...warning, severity: 'fatal' }).success).toBe(false);
+  });
+});
+
+describe('unknownEntryWarnings', () => {
+  it('reports unknown contracts and slots with their counts', () => {
+    const warnings = unknownEntryWarnings({ unknownContracts: 2, unknownSlots: 3 });
+    expect(warnings.map(w => [w.code, w.context])).toEqual([
+      ['UNKNOWN_CONTRACTS', { count: 2 }],
+      ['UNKNOWN_SLOTS', { count: 3 }],
+    ]);
+    expect(unknownEntryWarnings({ unknownContracts: 0, unknownSlots: 0 })).toEqual([]);
+    expect(unknownEntryWarnings(undefined)).toEqual([]);
+  });
+});
+
+describe('appendWarnings', () => {
+  it('appends after existing warnings and leaves the config alone when nothing was found', () => {
+    const first = reportWarning('SUSPICIOUS_WRITE', 'first');
+    const second = reportWarning('RECENTLY_MODIFIED', 'second');
+    const config = { cmd: 'forge', warnings: [first] };
+
+    expect(appendWarnings(config, [second]).warnings).toEqual([first, second]);
+    expect(appendWarnings({ cmd: 'forge' }, [])).not.toHaveProperty('warnings');
+  });
+});
+
+// A re-run whose compared entries all match the file
+const matching = buildValidationItems({
+  expected: { stateOverrides: [], stateChanges: [], balanceChanges: [] },
+  actual: { stateOverrides: [], stateChanges: [], balanceChanges: [] },
+});
+
+describe('isBlockingWarning', () => {
+  it('blocks signing when the Safe configuration changes differ from the file', () => {
+    const differs = reportWarning('SAFE_CONFIGURATION_DIFFERS', 'a new guard');
+
+    expect(isBlockingWarning(differs)).toBe(true);
+    expect(hasBlockingErrors(matching, [differs])).toBe(true);
+    expect(hasBlockingErrors(matching, [reportWarning('MISSING_SECRETS', 'unset')])).toBe(false);
+  });
+
+  it('blocks signing when the overrides differ from the expected ones', () => {
+    const mismatch = reportWarning('OVERRIDE_MISMATCH', 'an undocumented override');
+
+    expect(isBlockingWarning(mismatch)).toBe(true);
+    expect(hasBlockingErrors(matching, [mismatch])).toBe(true);
+  });
+
+  it("blocks signing when an owner Safe's hashes differ from the file", () => {
+    const mismatch = reportWarning('NESTED_HASH_MISMATCH', 'a different safeTxHash');
+
+    expect(isBlockingWarning(mismatch)).toBe(true);
+    expect(hasBlockingErrors(matching, [mismatch])).toBe(true);
+  });
+
+  it('blocks signing when the ETH transfers differ from the file', () => {
+    const differs = reportWarning('ETH_TRANSFERS_DIFFER', 'a different treasury movement');
+
+    expect(isBlockingWarning(differs)).toBe(true);
+    expect(hasBlockingErrors(matching, [differs])).toBe(true);
+  });
+
+  it('blocks signing when the self-destructs differ from the file', () => {
+    const differs = reportWarning('ACCOUNT_DELETIONS_DIFFER', 'an unlisted self-destruct');
+
+    expect(isBlockingWarning(differs)).toBe(true);
+    expect(hasBlockingErrors(matching, [differs])).toBe(true);
+  });
+
+  it('blocks signing when the code changes differ from the file', () => {
+    const differs = reportWarning('CODE_CHANGES_DIFFER', 'a different implementation');
+
+    expect(isBlockingWarning(differs)).toBe(true);
+    expect(hasBlockingErrors(matching, [differs])).toBe(true);
+  });
+});
@@ -0,0 +1,161 @@
+import { describe, expect, it } from '@jest/globals';
+import { custom, pad, toHex } from 'viem';
+import { PolicyViolationError } from '../errors';
+import {
+  checkRpcAgreement,
+  compareRpcs,
+  describeRpcAgreement,
+  describeRpcDisagreement,
+} from '../rpc-agreement';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const PORTAL = '0x49048044D57e1C92A77f79988d21Fa8fAF74E97e';
+const SLOT = pad('0x1');
+const word = (n: number) => pad(toHex(n));
+const GET_OWNERS = '0xa0e67e2b';
+
+type Node = {
+  chainId?: number;
+  storage?: number;
+  balance?: number;
+  owners?: string;
+  blockHash?: string;
+};
+
+// A node that serves the report's accounts; getOwners() on the Safe returns `owners` as raw data
+function node({
+  chainId = 1,
+  storage = 1,
+  balance = 500,
+  owners = word(7),
+  blockHash = word(100),
+}: Node = {}) {
+  const calls: string[] = [];
+  const transport = custom({
+    async request({ method, params }: { method: string; params?: unknown[] }) {
+      calls.push(method);
+      if (method === 'eth_chainId') return toHex(chainId);
+      if (method === 'eth_getStorageAt') return word(storage);
+      if (method === 'eth_getBalance') return toHex(balance);
+      if (method === 'eth_getBlockByNumber') {
+        return { number: toHex(100), hash: blockHash, timestamp: '0x0', transactions: [] };
+      }
+      if (method === 'eth_getCode') {
+        return (params?.[0] as string).toLowerCase() === SAFE.toLowerCase() ? '0x60016000' : '0x';
+      }
+      if (method === 'eth_call') {
+        const { data } = params?.[0] as { data: string };
+        return data === GET_OWNERS ? owners : word(1);
+      }
+      throw new Error(`unexpected RPC call ${method}`);
+    },
+  });
+  return { transport, calls };
+}
+
+const report = {
+  simulatedAt: { blockNumber: 100, blockTimestamp: 0, generatedAt: '2026-01-01T00:00:00.000Z' },
+  stateOverrides: [],
+  stateChanges: [
+    {
+      name: 'Portal',
+      address: PORTAL,
+      changes: [
+        { key: SLOT, before: word(1), after: word(2), description: '', allowDifference: false },
+      ],
+    },
+  ],
+  balanceChanges: [
+    {
+      name: 'Safe',
+      address: SAFE,
+      field: 'ETH Balance (wei)',
+      before: word(500),
+      after: word(0),
+      description: '',
+      allowDifference: false,
+    },
+  ],
+  expectedDomainAndMessageHashes: { address: SAFE, domainHash: word(1), messageHash: word(2) },
+};
+
+describe('compareRpcs', () => {
+  it('agrees when both providers return what the report was built from', async () => {
+    const agreement = await compareRpcs(report, node().transport, node().transport);
+    expect(agreement).toMatchObject({
+      chainId: 1,
+      blockNumber: BigInt(100),
+      slots: 1,
+      balances: 1,
+      codeHashes: 2,
+      disagreements: [],
+    });
+    expect(() => checkRpcAgreement(agreement)).not.toThrow();
+    expect(describeRpcAgreement(agreement)).toMatch(
+      /^The second RPC agrees on chain 1 at block 100:/
+    );
+  });
+
+  it('reports a provider that returns different values', async () => {
+    const agreement = await compareRpcs(
+      report,
+      node().transport,
+      node({ storage: 3, owners: word(8) }).transport
+    );
+    expect(agreement.disagreements.map(d => d.subject)).toEqual([
+      `storage of ${PORTAL} at ${SLOT}`,
+      `getOwners() of ${SAFE}`,
+    ]);
+    expect(() => checkRpcAgreement(agreement)).toThrow(PolicyViolationError);
+  });
+
+  it('reports before values both providers contradict', async () => {
+    const agreement = await compareRpcs(
+      report,
+      node({ storage: 5, balance: 400 }).transport,
+      node({ storage: 5, balance: 400 }).transport
+    );
+    expect(agreement.disagreements).toHaveLength(2);
+    expect(describeRpcDisagreement(agreement.disagreements[1])).toBe(
+      `balance of ${SAFE}: both RPCs return 400, but the report was built with 500`
+    );
+  });
+
+  it('compares the hash of the block the report recorded', async () => {
+    const recorded = { ...report, simulatedAt: { ...report.simulatedAt, blockHash: word(100) } };
+    const agreement = await compareRpcs(
+      recorded,
+      node().transport,
+      node({ blockHash: word(101) }).transport
+    );
+    expect(agreement.disagreements.map(d => d.subject)).toEqual(['hash of block 100']);
+  });
+
+  it('only compares overridden slots between the providers', async () => {
+    const overridden = {
+      ...report,
+      stateOverrides: [
+        {
+          name: 'Portal',
+          address: PORTAL,
+          overrides: [{ key: SLOT, value: word(1), description: '' }],
+        },
+      ],
+    };
+    const agreement = await compareRpcs(
+      overridden,
+      node({ storage: 9 }).transport,
+      node({ storage: 9 }).transport
+    );
+    expect(agreement.disagreements).toEqual([]);
+  });
+
+  it('stops at a chain ID mismatch', async () => {
+    const secondary = node({ chainId: 8453 });
+    const agreement = await compareRpcs(report, node().transport, secondary.transport);
+    expect(agreement.disagreements).toEqual([
+      { subject: 'chain ID', primary: '1', secondary: '8453' },
+    ]);
+    expect(secondary.calls).toEqual(['eth_chainId']);
+  });
+});
@@ -0,0 +1,114 @@
+import { describe, expect, it } from '@jest/globals';
+import { createPublicClient, custom, HttpRequestError, PublicClient, toHex } from 'viem';
+import {
+  backoffDelay,
+  DEFAULT_RETRY_POLICY,
+  newRetryLog,
+  retryingTransport,
+  retryPolicyFromEnv,
+  retryWarnings,
+} from '../rpc-retry';
+
+const ADDRESS = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const policy = { maxAttempts: 3, baseDelayMs: 100, maxDelayMs: 1000, budget: 10 };
+
+// A provider that answers 429 to the first `failures` requests of each method
+function flaky(failures: number, status = 429) {
+  const seen = new Map<string, number>();
+  return custom({
+    async request({ method }: { method: string }) {
+      const n = (seen.get(method) ?? 0) + 1;
+      seen.set(method, n);
+      if (n <= failures) throw new HttpRequestError({ url: 'http://rpc.invalid', status });
+      if (method === 'eth_chainId') return toHex(1);
+      if (method === 'eth_getCode') return '0x6001';
+      throw new Error('execution reverted');
+    },
+  });
+}
+
+function client(transport: ReturnType<typeof flaky>, p = policy) {
+  const log = newRetryLog();
+  const delays: number[] = [];
+  const retrying = retryingTransport(transport, p, log, {
+    sleep: async ms => {
+      delays.push(ms);
+    },
+    random: () => 1,
+  });
+  return { client: createPublicClient({ transport: retrying }) as PublicClient, log, delays };
+}
+
+describe('retryingTransport', () => {
+  it('retries rate-limited requests with backoff and logs them', async () => {
+    const { client: c, log, delays } = client(flaky(2));
+    expect(await c.getChainId()).toBe(1);
+    expect(delays).toEqual([100, 200]);
+    expect(log).toEqual({
+      retries: 2,
+      degraded: [{ method: 'eth_chainId', attempts: 3 }],
+      budgetExhausted: false,
+    });
+  });
+
+  it('gives up after the last attempt and records the failure', async () => {
+    const { client: c, log } = client(flaky(5, 503));
+    await expect(c.getCode({ address: ADDRESS })).rejects.toThrow(HttpRequestError);
+    expect(log.degraded).toEqual([
+      { method: 'eth_getCode', attempts: 3, error: 'HTTP request failed.' },
+    ]);
+  });
+
+  it('stops retrying once the run budget is spent', async () => {
+    const { client: c, log } = client(flaky(1), { ...policy, budget: 1 });
+    expect(await c.getChainId()).toBe(1);
+    await expect(c.getCode({ address: ADDRESS })).rejects.toThrow();
+    expect(log.retries).toBe(1);
+    expect(log.budgetExhausted).toBe(true);
+  });
+
+  it('does not retry reverts', async () => {
+    const { client: c, log } = client(flaky(0));
+    await expect(c.call({ to: ADDRESS, data: '0x' })).rejects.toThrow();
+    expect(log).toEqual(newRetryLog());
+  });
+});
+
+describe('backoffDelay', () => {
+  it('doubles up to the maximum, with up to half the delay random', () => {
+    expect(backoffDelay(policy, 1, () => 0)).toBe(50);
+    expect(backoffDelay(policy, 3, () => 1)).toBe(400);
+    expect(backoffDelay(policy, 10, () => 1)).toBe(1000);
+  });
+});
+
+describe('retryPolicyFromEnv', () => {
+  it('reads overrides and rejects invalid values', () => {
+    expect(retryPolicyFromEnv({})).toEqual(DEFAULT_RETRY_POLICY);
+    expect(retryPolicyFromEnv({ STATE_DIFF_RPC_RETRY_BUDGET: '0' }).budget).toBe(0);
+    expect(() => retryPolicyFromEnv({ STATE_DIFF_RPC_MAX_ATTEMPTS: '0' })).toThrow(
+      /STATE_DIFF_RPC_MAX_ATTEMPTS must be an integer of at least 1/
+    );
+  });
+});
+
+describe('retryWarnings', () => {
+  it('sums up degraded lookups by method', () => {
+    expect(retryWarnings(newRetryLog())).toEqual([]);
+    const [warning] = retryWarnings({
+      retries: 3,
+      degraded: [
+        { method: 'eth_getCode', attempts: 2 },
+        { method: 'eth_getCode', attempts: 2 },
+        { method: 'eth_getStorageAt', attempts: 2, error: 'HTTP request failed.' },
+      ],
+      budgetExhausted: false,
+    });
+    expect(warning).toMatchObject({
+      code: 'RPC_DEGRADED',
+      severity: 'warning',
+      message:
+        '3 RPC lookup(s) needed retries (eth_getCode x2, eth_getStorageAt x1), 3 retry(ies) in total, 1 failed',
+    });
+  });
+});
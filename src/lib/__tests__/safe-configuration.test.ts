@@ -0,0 +1,117 @@
+import { describe, expect, it } from '@jest/globals';
+import { Address, getAddress, Hex, keccak256 } from 'viem';
+import { aggregateAccountAccesses } from '../account-aggregation';
+import {
+  describeSafeConfigurationChange,
+  findSafeConfigurationChanges,
+  SAFE_FALLBACK_HANDLER_SLOT,
+  SAFE_GUARD_SLOT,
+  safeConfigurationWarning,
+} from '../safe-configuration';
+import { AccountAccessKind, VmSafeAccountAccess, VmSafeStorageAccess } from '../vm-safe';
+
+const SAFE = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const OLD_HANDLER = '0x1111111111111111111111111111111111111111';
+const NEW_HANDLER = '0x2222222222222222222222222222222222222222';
+const GUARD = '0x3333333333333333333333333333333333333333';
+const HANDLER_CODE = '0x6080604052' as Hex;
+const GUARD_CODE = '0x60006000fd' as Hex;
+
+const word = (address: string) => ('0x' + address.slice(2).padStart(64, '0')) as Hex;
+const ZERO = word('0x');
+
+const write = (slot: string, before: Hex, after: Hex): VmSafeStorageAccess => ({
+  account: SAFE,
+  slot,
+  isWrite: true,
+  previousValue: before,
+  newValue: after,
+  reverted: false,
+});
+
+const access = (overrides: Partial<VmSafeAccountAccess>): VmSafeAccountAccess => ({
+  chainInfo: { forkId: BigInt(0), chainId: BigInt(1) },
+  kind: AccountAccessKind.Call,
+  account: SAFE,
+  accessor: SAFE,
+  initialized: true,
+  oldBalance: BigInt(0),
+  newBalance: BigInt(0),
+  deployedCode: '0x',
+  value: BigInt(0),
+  data: '0x',
+  reverted: false,
+  storageAccesses: [],
+  depth: BigInt(0),
+  oldNonce: BigInt(0),
+  newNonce: BigInt(0),
+  ...overrides,
+});
+
+const client = {
+  getCode: async ({ address }: { address: Address }) =>
+    address.toLowerCase() === NEW_HANDLER ? HANDLER_CODE : undefined,
+};
+
+describe('findSafeConfigurationChanges', () => {
+  it('reports a new fallback handler and a guard deployed by the transaction', async () => {
+    const decoded = [
+      access({ kind: AccountAccessKind.Create, account: GUARD, deployedCode: GUARD_CODE }),
+      access({
+        storageAccesses: [
+          write(SAFE_FALLBACK_HANDLER_SLOT, word(OLD_HANDLER), word(NEW_HANDLER)),
+          write(SAFE_GUARD_SLOT, ZERO, word(GUARD)),
+        ],
+      }),
+    ];
+    const { storage } = aggregateAccountAccesses(decoded);
+    const changes = await findSafeConfigurationChanges(client, decoded, storage);
+
+    expect(changes).toEqual([
+      {
+        address: SAFE,
+        setting: 'guard',
+        before: null,
+        after: getAddress(GUARD),
+        afterCode: { size: 5, codeHash: keccak256(GUARD_CODE) },
+      },
+      {
+        address: SAFE,
+        setting: 'fallbackHandler',
+        before: getAddress(OLD_HANDLER),
+        after: getAddress(NEW_HANDLER),
+        afterCode: { size: 5, codeHash: keccak256(HANDLER_CODE) },
+      },
+    ]);
+    expect(describeSafeConfigurationChange(changes[0])).toBe(
+      `${SAFE} guard unset -> ${getAddress(GUARD)} (5 bytes)`
+    );
+    expect(changes.map(safeConfigurationWarning)).toEqual([null, null]);
+  });
+
+  it('warns when the guard is set to an address without code', async () => {
+    const decoded = [access({ storageAccesses: [write(SAFE_GUARD_SLOT, ZERO, word(GUARD))] })];
+    const { storage } = aggregateAccountAccesses(decoded);
+    const [change] = await findSafeConfigurationChanges(client, decoded, storage);
+
+    expect(change.afterCode).toBeNull();
+    expect(safeConfigurationWarning(change)).toMatch(/unable to execute transactions/);
+  });
+
+  it('reports a removed guard without a warning', async () => {
+    const decoded = [
+      access({
+        storageAccesses: [
+          write(SAFE_GUARD_SLOT, word(GUARD), ZERO),
+          write(word('0x4'), ZERO, word(GUARD)),
+        ],
+      }),
+    ];
+    const { storage } = aggregateAccountAccesses(decoded);
+    const [change, ...rest] = await findSafeConfigurationChanges(client, decoded, storage);
+
+    expect(rest).toEqual([]);
+    expect(change).toMatchObject({ before: getAddress(GUARD), after: null, afterCode: null });
+    expect(safeConfigurationWarning(change)).toBeNull();
+  });
+});
@@ -0,0 +1,14 @@
+import { describe, expect, it } from '@jest/globals';
+import { keccak256 } from 'viem';
+import { computeSafeTxHash } from '../safe-hash';
+
+describe('computeSafeTxHash', () => {
+  it('hashes 0x1901 || domainHash || messageHash', () => {
+    const domainHash = '0x' + '11'.repeat(32);
+    const messageHash = '0x' + '22'.repeat(32);
+
+    expect(computeSafeTxHash(domainHash, messageHash)).toBe(
+      keccak256(`0x1901${'11'.repeat(32)}${'22'.repeat(32)}`)
+    );
+  });
+});
@@ -0,0 +1,97 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  encodeAbiParameters,
+  encodeFunctionData,
+  keccak256,
+  parseAbi,
+  toBytes,
+  zeroAddress,
+} from 'viem';
+import { describeNonceMismatch, recoverSafeTxNonce } from '../safe-nonce';
+
+const SAFE = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const TARGET = '0x1111111111111111111111111111111111111111';
+const INNER_DATA = '0x12345678';
+
+const execTransaction = encodeFunctionData({
+  abi: parseAbi([
+    'function execTransaction(address to, uint256 value, bytes data, uint8 operation, uint256 safeTxGas, uint256 baseGas, uint256 gasPrice, address gasToken, address refundReceiver, bytes signatures) payable returns (bool)',
+  ]),
+  functionName: 'execTransaction',
+  args: [
+    TARGET,
+    BigInt(0),
+    INNER_DATA,
+    1,
+    BigInt(0),
+    BigInt(0),
+    BigInt(0),
+    zeroAddress,
+    zeroAddress,
+    '0x',
+  ],
+});
+
+// Independent of the implementation: the SafeTx struct hash from the Safe contracts
+function safeTxMessageHash(nonce: number) {
+  const typeHash = keccak256(
+    toBytes(
+      'SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 baseGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)'
+    )
+  );
+  return keccak256(
+    encodeAbiParameters(
+      [
+        { type: 'bytes32' },
+        { type: 'address' },
+        { type: 'uint256' },
+        { type: 'bytes32' },
+        { type: 'uint8' },
+        { type: 'uint256' },
+        { type: 'uint256' },
+        { type: 'uint256' },
+        { type: 'address' },
+        { type: 'address' },
+        { type: 'uint256' },
+      ],
+      [
+        typeHash,
+        TARGET,
+        BigInt(0),
+        keccak256(INNER_DATA),
+        1,
+        BigInt(0),
+        BigInt(0),
+        BigInt(0),
+        zeroAddress,
+        zeroAddress,
+        BigInt(nonce),
+      ]
+    )
+  );
+}
+
+describe('recoverSafeTxNonce', () => {
+  it('finds the nonce the message hash was built with', () => {
+    const params = { safe: SAFE, to: SAFE, data: execTransaction, around: BigInt(5) } as const;
+    expect(recoverSafeTxNonce({ ...params, messageHash: safeTxMessageHash(7) })).toBe(BigInt(7));
+    expect(recoverSafeTxNonce({ ...params, messageHash: safeTxMessageHash(2) })).toBe(BigInt(2));
+  });
+
+  it('skips calls that are not execTransaction on the signing Safe', () => {
+    const params = { safe: SAFE, messageHash: safeTxMessageHash(5), around: BigInt(5) } as const;
+    expect(recoverSafeTxNonce({ ...params, to: TARGET, data: execTransaction })).toBeNull();
+    expect(recoverSafeTxNonce({ ...params, to: SAFE, data: INNER_DATA })).toBeNull();
+  });
+});
+
+describe('describeNonceMismatch', () => {
+  it('is silent when the nonces match', () => {
+    expect(describeNonceMismatch(BigInt(4), BigInt(4))).toBeNull();
+  });
+
+  it('flags stale and future nonces', () => {
+    expect(describeNonceMismatch(BigInt(3), BigInt(4))).toMatch(/can never be executed/);
+    expect(describeNonceMismatch(BigInt(6), BigInt(4))).toMatch(/2 other transaction\(s\)/);
+  });
+});
@@ -0,0 +1,68 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  describeContractOwners,
+  describeQuorum,
+  isBytes4Result,
+  SafeQuorum,
+} from '../safe-quorum';
+
+const OWNER_A = '0x1111111111111111111111111111111111111111';
+const OWNER_B = '0x2222222222222222222222222222222222222222';
+
+const quorum = (approvedBy: SafeQuorum['approvedBy'], threshold = 3): SafeQuorum => ({
+  safe: '0x9855054731540a48b28990b63dcf4f33d8ae46a1',
+  safeTxHash: `0x${'ab'.repeat(32)}`,
+  threshold,
+  owners: 5,
+  approvedBy,
+  contractOwners: [],
+});
+
+describe('describeQuorum', () => {
+  it('says when this signature completes the quorum', () => {
+    expect(describeQuorum(quorum([OWNER_A, OWNER_B]))).toBe(
+      '2 of 3 approvals exist on-chain; your signature will make the transaction executable'
+    );
+  });
+
+  it('counts the signatures still needed after this one', () => {
+    expect(describeQuorum(quorum([]))).toMatch(/0 of 3 .*; 2 more signature\(s\) will be needed/);
+  });
+
+  it('flags a transaction that is already executable', () => {
+    expect(describeQuorum(quorum([OWNER_A, OWNER_B], 2))).toMatch(/already executable/);
+  });
+});
+
+describe('describeContractOwners', () => {
+  it('says nothing when every owner is an EOA', () => {
+    expect(describeContractOwners(quorum([]))).toEqual([]);
+  });
+
+  it('tells EIP-1271 owners apart from approveHash-only owners', () => {
+    const lines = describeContractOwners({
+      ...quorum([]),
+      contractOwners: [
+        { address: OWNER_A, signature: 'eip1271' },
+        { address: OWNER_B, signature: 'approve-hash' },
+      ],
+    });
+    expect(lines).toEqual([
+      expect.stringMatching(new RegExp(`^Owner ${OWNER_A} .*signs with EIP-1271`)),
+      expect.stringMatching(new RegExp(`^Owner ${OWNER_B} .*only approve on-chain`)),
+    ]);
+  });
+});
+
+describe('isBytes4Result', () => {
+  it('accepts an ABI-encoded bytes4', () => {
+    expect(isBytes4Result(`0x1626ba7e${'0'.repeat(56)}`)).toBe(true);
+    expect(isBytes4Result(`0xffffffff${'0'.repeat(56)}`)).toBe(true);
+  });
+
+  it('rejects empty and non-bytes4 return data', () => {
+    expect(isBytes4Result(undefined)).toBe(false);
+    expect(isBytes4Result('0x')).toBe(false);
+    expect(isBytes4Result(`0x${'0'.repeat(63)}1`)).toBe(false);
+  });
+});
@@ -0,0 +1,129 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  concatHex,
+  decodeFunctionData,
+  encodeFunctionResult,
+  Hex,
+  keccak256,
+  parseAbi,
+  toBytes,
+  toFunctionSelector,
+} from 'viem';
+import { privateKeyToAccount, sign } from 'viem/accounts';
+import { computeSafeTxHash } from '../safe-hash';
+import {
+  checkSafeSignatures,
+  explainSignatureRevert,
+  parseCollectedSignatures,
+  prepareSignatures,
+} from '../safe-signature-check';
+import { fakeRpc } from '../state-diff-test';
+
+const KEYS = [
+  '0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d',
+  '0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80',
+] as const;
+const [LOW, HIGH] = KEYS.map(key => privateKeyToAccount(key).address).sort((a, b) =>
+  a.toLowerCase().localeCompare(b.toLowerCase())
+);
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const DOMAIN = keccak256(toBytes('domain'));
+const MESSAGE = keccak256(toBytes('message'));
+const SAFE_TX_HASH = computeSafeTxHash(DOMAIN, MESSAGE);
+
+const abi = parseAbi([
+  'function VERSION() view returns (string)',
+  'function getThreshold() view returns (uint256)',
+  'function checkNSignatures(bytes32 dataHash, bytes data, bytes signatures, uint256 requiredSignatures) view',
+]);
+
+async function signatureOf(signer: string): Promise<Hex> {
+  const key = KEYS.find(k => privateKeyToAccount(k).address === signer)!;
+  return (await sign({ hash: SAFE_TX_HASH, privateKey: key, to: 'hex' })) as Hex;
+}
+
+// v written as 0 or 1 instead of 27 or 28, as some wallets do
+function lowV(signature: Hex): Hex {
+  const v = Number.parseInt(signature.slice(-2), 16) - 27;
+  return `${signature.slice(0, -2)}0${v}` as Hex;
+}
+
+describe('parseCollectedSignatures', () => {
+  it('reads JSON arrays, whitespace-separated hex, and packed signatures', async () => {
+    const [low, high] = [await signatureOf(LOW), await signatureOf(HIGH)];
+    expect(parseCollectedSignatures(JSON.stringify([low, high]))).toEqual([low, high]);
+    expect(parseCollectedSignatures(`${low}\n${high}\n`)).toEqual([low, high]);
+    expect(parseCollectedSignatures(concatHex([low, high]))).toEqual([low, high]);
+    expect(() => parseCollectedSignatures('0x1234')).toThrow(/Not a 65-byte signature/);
+  });
+});
+
+describe('prepareSignatures', () => {
+  it('sorts by signer and corrects v values of 0 and 1', async () => {
+    const high = await signatureOf(HIGH);
+    const low = await signatureOf(LOW);
+
+    const { signatures, notes } = await prepareSignatures([lowV(high), low], SAFE_TX_HASH);
+    expect(signatures).toEqual([
+      { type: 'ecdsa', signer: LOW, signature: low },
+      { type: 'ecdsa', signer: HIGH, signature: high },
+    ]);
+    expect(notes).toEqual([
+      expect.stringMatching(/^A signature had v = [01]; it was changed to 2[78]/),
+      'The signatures were sorted by signer, the order the Safe requires',
+    ]);
+  });
+});
+
+describe('checkSafeSignatures', () => {
+  it("calls the Safe's checkNSignatures with the sorted signatures", async () => {
+    const requests: Hex[] = [];
+    const rpc = fakeRpc({
+      call: ({ data }) => {
+        requests.push(data);
+        if (data.startsWith(toFunctionSelector('function VERSION()'))) {
+          return encodeFunctionResult({ abi, functionName: 'VERSION', result: '1.3.0' });
+        }
+        if (data.startsWith(toFunctionSelector('function getThreshold()'))) {
+          return encodeFunctionResult({ abi, functionName: 'getThreshold', result: BigInt(2) });
+        }
+        const { args } = decodeFunctionData({ abi, data });
+        return args[2].length === 2 + 130 * 2 ? '0x' : undefined;
+      },
+    });
+    const low = await signatureOf(LOW);
+    const high = await signatureOf(HIGH);
+
+    const check = await checkSafeSignatures({
+      transport: rpc.transport,
+      safe: SAFE,
+      domainHash: DOMAIN,
+      messageHash: MESSAGE,
+      signatures: [high, low],
+    });
+    expect(check).toMatchObject({ accepted: true, threshold: 2, packed: concatHex([low, high]) });
+    const { args } = decodeFunctionData({ abi, data: requests[2] });
+    expect(args).toEqual([
+      SAFE_TX_HASH,
+      concatHex(['0x1901', DOMAIN, MESSAGE]),
+      check.packed,
+      BigInt(2),
+    ]);
+
+    const refused = await checkSafeSignatures({
+      transport: rpc.transport,
+      safe: SAFE,
+      domainHash: DOMAIN,
+      messageHash: MESSAGE,
+      signatures: [low],
+    });
+    expect(refused.accepted).toBe(false);
+    expect(refused.reason).toBeDefined();
+  });
+
+  it("names the Safe's revert code", () => {
+    expect(explainSignatureRevert(new Error('execution reverted: GS026'))).toBe(
+      'GS026: a signer is not an owner, or the signatures are not sorted by signer'
+    );
+  });
+});
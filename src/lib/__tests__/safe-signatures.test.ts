@@ -0,0 +1,44 @@
+import { describe, expect, it } from '@jest/globals';
+import { concatHex, Hex, keccak256, numberToHex, pad, toBytes } from 'viem';
+import { privateKeyToAccount, sign } from 'viem/accounts';
+import { decodeSafeSignatures } from '../safe-signatures';
+
+const KEY = '0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d';
+const OWNER = privateKeyToAccount(KEY).address;
+const APPROVER = '0x1111111111111111111111111111111111111111';
+const CONTRACT = '0x2222222222222222222222222222222222222222';
+const SAFE_TX_HASH = keccak256(toBytes('safeTx'));
+
+const approvedHash = concatHex([pad(APPROVER), pad('0x00'), '0x01']);
+
+describe('decodeSafeSignatures', () => {
+  it('reads owners from approved-hash and contract signatures', async () => {
+    // The contract signature's data starts right after the two static parts
+    const contract = concatHex([pad(CONTRACT), numberToHex(130, { size: 32 }), '0x00']);
+    const dynamic = concatHex([numberToHex(2, { size: 32 }), '0xabcd']);
+    const signatures = concatHex([approvedHash, contract, dynamic]);
+
+    expect(await decodeSafeSignatures(signatures, null)).toEqual([
+      { type: 'approved-hash', signer: APPROVER },
+      { type: 'contract-signature', signer: CONTRACT },
+    ]);
+  });
+
+  it('recovers ECDSA and eth_sign signers from the SafeTx hash', async () => {
+    const ecdsa = (await sign({ hash: SAFE_TX_HASH, privateKey: KEY, to: 'hex' })) as Hex;
+    const account = privateKeyToAccount(KEY);
+    const ethSign = await account.signMessage({ message: { raw: SAFE_TX_HASH } });
+    const v = Number.parseInt(ethSign.slice(-2), 16) + 4;
+    const shifted = `${ethSign.slice(0, -2)}${v.toString(16)}` as Hex;
+
+    expect(await decodeSafeSignatures(concatHex([ecdsa, shifted]), SAFE_TX_HASH)).toEqual([
+      { type: 'ecdsa', signer: OWNER },
+      { type: 'eth_sign', signer: OWNER },
+    ]);
+  });
+
+  it('leaves ECDSA signers unknown without the SafeTx hash', async () => {
+    const ecdsa = (await sign({ hash: SAFE_TX_HASH, privateKey: KEY, to: 'hex' })) as Hex;
+    expect(await decodeSafeSignatures(ecdsa, null)).toEqual([{ type: 'ecdsa', signer: null }]);
+  });
+});
@@ -0,0 +1,53 @@
+import { describe, expect, it } from '@jest/globals';
+import { Hex } from 'viem';
+import { knownSafeVersion, readSafeSingleton, safeDomainHash } from '../safe-singleton';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+
+const client = (word: Hex | undefined) => ({
+  getStorageAt: async () => word,
+});
+
+describe('readSafeSingleton', () => {
+  it('reads the address in slot 0', async () => {
+    const word: Hex = '0x000000000000000000000000d9db270c1b5e3bd161e8c8503c55ceabee709552';
+
+    expect(await readSafeSingleton(client(word), SAFE)).toBe(
+      '0xd9Db270c1B5E3Bd161E8c8503c55cEABeE709552'
+    );
+  });
+
+  it('gives null for an empty slot or a failed read', async () => {
+    const failing = {
+      getStorageAt: async (): Promise<Hex | undefined> => {
+        throw new Error('rpc down');
+      },
+    };
+
+    expect(await readSafeSingleton(client(`0x${'0'.repeat(64)}`), SAFE)).toBeNull();
+    expect(await readSafeSingleton(client(undefined), SAFE)).toBeNull();
+    expect(await readSafeSingleton(failing, SAFE)).toBeNull();
+  });
+});
+
+describe('knownSafeVersion', () => {
+  it('recognizes canonical and L2 singletons in any case', () => {
+    expect(knownSafeVersion('0xd9db270c1b5e3bd161e8c8503c55ceabee709552')).toBe('1.3.0');
+    expect(knownSafeVersion('0x29fcB43b46531BcA003ddC8FCB67FFE91900C762')).toBe('1.4.1');
+    expect(knownSafeVersion('0x1111111111111111111111111111111111111111')).toBeNull();
+  });
+});
+
+describe('safeDomainHash', () => {
+  it('includes the chain from 1.3.0 on', () => {
+    expect(safeDomainHash('1.3.0', 1, SAFE)).toBe(
+      '0x88aac3dc27cc1618ec43a87b3df21482acd24d172027ba3fbb5a5e625d895a0b'
+    );
+    expect(safeDomainHash('1.4.1', 8453, SAFE)).toBe(
+      '0x50e0bd792035b6ee6d85c73ad24f56897aef992fb993f7ea0ccdb2c974772f71'
+    );
+    expect(safeDomainHash('1.1.1', 8453, SAFE)).toBe(
+      '0x3f6a941a7af9eda78c009f9d241ff524aa09640ac733b01be092b4f358609516'
+    );
+  });
+});
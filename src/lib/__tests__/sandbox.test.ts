@@ -0,0 +1,75 @@
+import { describe, expect, it } from '@jest/globals';
+import { buildSandboxArgs, isPinnedImage, sandboxFromEnv } from '../sandbox';
+
+const IMAGE = `registry.example/sandbox@sha256:${'a'.repeat(64)}`;
+
+describe('sandboxFromEnv', () => {
+  it('is off without an image', () => {
+    expect(sandboxFromEnv({})).toBeNull();
+  });
+
+  it('defaults to docker on the configured network', () => {
+    expect(
+      sandboxFromEnv({ STATE_DIFF_SANDBOX_IMAGE: IMAGE, STATE_DIFF_SANDBOX_NETWORK: 'rpc-only' })
+    ).toEqual({ image: IMAGE, network: 'rpc-only', runtime: 'docker' });
+  });
+
+  it('refuses to run without an explicit network', () => {
+    expect(() => sandboxFromEnv({ STATE_DIFF_SANDBOX_IMAGE: IMAGE })).toThrow(
+      /STATE_DIFF_SANDBOX_NETWORK/
+    );
+  });
+
+  it('refuses an image that is not pinned by digest', () => {
+    expect(() =>
+      sandboxFromEnv({
+        STATE_DIFF_SANDBOX_IMAGE: 'ghcr.io/foundry-rs/foundry:stable',
+        STATE_DIFF_SANDBOX_NETWORK: 'rpc-only',
+      })
+    ).toThrow(/not pinned by digest/);
+  });
+});
+
+describe('isPinnedImage', () => {
+  it('only accepts images pinned by digest', () => {
+    expect(isPinnedImage(IMAGE)).toBe(true);
+    expect(isPinnedImage('ghcr.io/foundry-rs/foundry:stable')).toBe(false);
+  });
+});
+
+describe('buildSandboxArgs', () => {
+  it('mounts the workdir read-only and passes only the command environment', () => {
+    const args = buildSandboxArgs({
+      config: { image: IMAGE, network: 'rpc-only', runtime: 'docker' },
+      workdir: '/repo/active/evm',
+      stateDiffPath: '/repo/active/evm/stateDiff.json',
+      command: 'forge',
+      args: ['script', 'Upgrade', '--sig', 'sign()'],
+      env: { RECORD_STATE_DIFF: 'true' },
+      user: '1000:1000',
+    }).join(' ');
+
+    expect(args).toContain('--read-only --network rpc-only --cap-drop ALL');
+    expect(args).toContain('--user 1000:1000');
+    expect(args).toContain('-v /repo/active/evm:/task:ro');
+    expect(args).toContain('--tmpfs /task/out');
+    expect(args).toContain('-v /repo/active/evm/stateDiff.json:/task/stateDiff.json');
+    expect(args).toContain('-e RECORD_STATE_DIFF=true');
+    expect(args).not.toContain('.svm');
+    expect(args.endsWith(`--entrypoint forge ${IMAGE} script Upgrade --sig sign()`)).toBe(true);
+  });
+
+  it('passes inherited variables by name so their values stay off the command line', () => {
+    const args = buildSandboxArgs({
+      config: { image: IMAGE, network: 'bridge', runtime: 'docker' },
+      workdir: '/repo/active/evm',
+      stateDiffPath: '/repo/active/evm/stateDiff.json',
+      command: 'forge',
+      args: [],
+      env: {},
+      inheritEnv: ['ETHERSCAN_API_KEY'],
+    });
+
+    expect(args.join(' ')).toContain('-e ETHERSCAN_API_KEY --entrypoint');
+  });
+});
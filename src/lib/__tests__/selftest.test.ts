@@ -0,0 +1,26 @@
+import { describe, expect, it } from '@jest/globals';
+import { runSelftest, runSelftestFixture, SELFTEST_FIXTURES } from '../selftest';
+
+describe('runSelftest', () => {
+  it('passes every embedded fixture', async () => {
+    const results = await runSelftest();
+    expect(results.map(r => r.chainId).sort()).toEqual(
+      SELFTEST_FIXTURES.map(f => f.chainId).sort()
+    );
+    for (const result of results) expect(result.problems).toEqual([]);
+  });
+
+  it('reports a report that differs from the golden digest', async () => {
+    const fixture = SELFTEST_FIXTURES[0];
+    const golden = [...fixture.golden];
+    golden[1] = golden[1].replace(/.$/, c => (c === '0' ? '1' : '0'));
+    const result = await runSelftestFixture({ ...fixture, golden });
+    expect(result.problems).toEqual([expect.stringMatching(/^line 2: expected hashes /)]);
+  });
+
+  it('reports a fixture that does not decode', async () => {
+    const fixture = SELFTEST_FIXTURES[0];
+    const result = await runSelftestFixture({ ...fixture, artifact: { version: 1 } });
+    expect(result.problems).toEqual([expect.stringMatching(/Invalid simulation diff/)]);
+  });
+});
@@ -0,0 +1,64 @@
+import { describe, expect, it } from '@jest/globals';
+import { serializeResult } from '../serialization';
+
+const result = {
+  cmd: 'forge script "Upgrade"',
+  ledgerId: 0,
+  expectedDomainAndMessageHashes: { address: '0xabc', domainHash: '0x01' },
+  stateOverrides: [],
+  stateChanges: [
+    {
+      name: 'Proxy',
+      changes: [{ key: '0x01', allowDifference: false }],
+    },
+  ],
+  skipTaskOriginValidation: undefined,
+  audience: null,
+};
+
+describe('serializeResult', () => {
+  it('renders YAML in the JSON field order, keeping nulls', () => {
+    expect(serializeResult(result, 'yaml')).toBe(
+      [
+        'cmd: forge script "Upgrade"',
+        'ledgerId: 0',
+        'expectedDomainAndMessageHashes:',
+        '  address: "0xabc"',
+        '  domainHash: "0x01"',
+        'stateOverrides: []',
+        'stateChanges:',
+        '  - name: Proxy',
+        '    changes:',
+        '      - key: "0x01"',
+        '        allowDifference: false',
+        'audience: null',
+      ].join('\n')
+    );
+  });
+
+  it('does not fold long YAML strings', () => {
+    const description = 'word '.repeat(40).trim();
+    expect(serializeResult({ description }, 'yaml')).toBe(`description: ${description}`);
+  });
+
+  it('renders TOML with tables after top-level keys, leaving out nulls', () => {
+    expect(serializeResult(result, 'toml')).toBe(
+      [
+        'cmd = "forge script \\"Upgrade\\""',
+        'ledgerId = 0',
+        'stateOverrides = []',
+        '',
+        '[expectedDomainAndMessageHashes]',
+        'address = "0xabc"',
+        'domainHash = "0x01"',
+        '',
+        '[[stateChanges]]',
+        'name = "Proxy"',
+        '',
+        '[[stateChanges.changes]]',
+        'key = "0x01"',
+        'allowDifference = false',
+      ].join('\n')
+    );
+  });
+});
@@ -0,0 +1,76 @@
+import { describe, expect, it } from '@jest/globals';
+import { computeSafeTxHash } from '../safe-hash';
+import {
+  buildSignerInstructions,
+  DEFAULT_SIGNER_TEMPLATE,
+  parseSignerTemplate,
+} from '../signer-instructions';
+import { renderSuperchainOpsValidation } from '../superchain-ops';
+import type { TaskConfig } from '../types';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const config = {
+  cmd: 'forge script Upgrade',
+  ledgerId: 1,
+  rpcUrl: 'https://rpc.example',
+  chainId: 8453,
+  expectedDomainAndMessageHashes: { address: SAFE, domainHash: word(1), messageHash: word(2) },
+  stateOverrides: [],
+  stateChanges: [],
+} as TaskConfig;
+
+describe('buildSignerInstructions', () => {
+  it('fills the default template with the hashes to compare', () => {
+    const instructions = buildSignerInstructions(config, DEFAULT_SIGNER_TEMPLATE);
+
+    expect(instructions.commands).toEqual(['forge script Upgrade']);
+    expect(instructions.hashes).toEqual({
+      safe: SAFE,
+      domainHash: word(1),
+      messageHash: word(2),
+      safeTxHash: computeSafeTxHash(word(1), word(2)),
+    });
+    expect(instructions.devices[0].device).toBe('ledger');
+    expect(instructions.devices[0].steps).toContain(
+      `Check that the "Domain hash" screen shows ${word(1)}`
+    );
+  });
+
+  it('renders a team template', () => {
+    const template = parseSignerTemplate({
+      team: 'base-sc',
+      commands: ['cd tasks/{{task}}', 'just sign --ledger-id {{ ledgerId }} --chain {{chainId}}'],
+      devices: { trezor: ['Confirm safeTxHash {{safeTxHash}}'] },
+      notes: ['Signing for {{safe}}'],
+    });
+
+    expect(buildSignerInstructions(config, template, '2026-10-01-upgrade')).toEqual({
+      team: 'base-sc',
+      commands: ['cd tasks/2026-10-01-upgrade', 'just sign --ledger-id 1 --chain 8453'],
+      hashes: expect.anything(),
+      devices: [
+        { device: 'trezor', steps: [`Confirm safeTxHash ${computeSafeTxHash(word(1), word(2))}`] },
+      ],
+      notes: [`Signing for ${SAFE}`],
+    });
+  });
+
+  it('rejects placeholders the file cannot fill', () => {
+    const template = parseSignerTemplate({ devices: { ledger: ['Nonce {{safeNonce}}'] } });
+
+    expect(() => buildSignerInstructions(config, template)).toThrow(/\{\{safeNonce\}\}/);
+    expect(() => parseSignerTemplate({ commands: [] })).toThrow(/Invalid signer template: devices/);
+  });
+
+  it('is rendered into the superchain-ops export', () => {
+    const signerInstructions = buildSignerInstructions(config, DEFAULT_SIGNER_TEMPLATE);
+    const markdown = renderSuperchainOpsValidation([
+      { label: 'base-sc', config: { ...config, signerInstructions } },
+    ]);
+
+    expect(markdown).toContain('## Signing Instructions\n\n### base-sc\n\n```bash');
+    expect(markdown).toContain('#### ledger\n\n1. Connect the Ledger');
+  });
+});
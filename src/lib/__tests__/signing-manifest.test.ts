@@ -0,0 +1,91 @@
+import { describe, expect, it } from '@jest/globals';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import type { BuildInfo } from '../build-info';
+import { recordManifestArtifacts, rpcEndpointOrigin, verifyManifest } from '../signing-manifest';
+
+const buildInfo: BuildInfo = {
+  tool: 'task-signing-tool',
+  version: '1.0.0',
+  commit: 'abc123',
+  configHash: '0x' + '11'.repeat(32),
+  buildDate: 'development',
+};
+
+async function bundle(): Promise<string> {
+  const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'manifest-'));
+  await fs.mkdir(path.join(dir, 'validations'));
+  await fs.writeFile(path.join(dir, 'validations', 'base-sc.json'), '{"cmd":"forge"}\n');
+  await fs.writeFile(path.join(dir, 'signature.json'), '{}\n');
+  return dir;
+}
+
+describe('recordManifestArtifacts', () => {
+  it('lists files relative to the manifest with their hash, size, and block pin', async () => {
+    const dir = await bundle();
+    const manifestFile = path.join(dir, 'manifest.json');
+    const validation = path.join(dir, 'validations', 'base-sc.json');
+    await recordManifestArtifacts(
+      manifestFile,
+      [{ file: validation, chainId: 1, blockNumber: 100, blockHash: '0x' + 'ab'.repeat(32) }],
+      { buildInfo, rpcUrls: ['https://eth.example/v2/secret-key'] }
+    );
+    const manifest = await recordManifestArtifacts(
+      manifestFile,
+      [{ file: path.join(dir, 'signature.json') }],
+      { buildInfo }
+    );
+    expect(manifest.rpcEndpoints).toEqual(['https://eth.example']);
+    expect(manifest.artifacts.map(a => a.path)).toEqual([
+      'signature.json',
+      'validations/base-sc.json',
+    ]);
+    expect(manifest.artifacts[1]).toMatchObject({ bytes: 16, chainId: 1, blockNumber: 100 });
+    expect(manifest.artifacts[1].sha256).toMatch(/^[0-9a-f]{64}$/);
+  });
+
+  it('refuses files outside the manifest directory and manifests of another build', async () => {
+    const dir = await bundle();
+    const manifestFile = path.join(dir, 'validations', 'manifest.json');
+    await expect(
+      recordManifestArtifacts(manifestFile, [{ file: path.join(dir, 'signature.json') }], {
+        buildInfo,
+      })
+    ).rejects.toThrow(/outside allowed directory/);
+
+    await recordManifestArtifacts(manifestFile, [], { buildInfo });
+    const other = { ...buildInfo, configHash: '0x' + '22'.repeat(32) };
+    await expect(recordManifestArtifacts(manifestFile, [], { buildInfo: other })).rejects.toThrow(
+      /start a new manifest/
+    );
+  });
+});
+
+describe('verifyManifest', () => {
+  it('reports changed and missing files', async () => {
+    const dir = await bundle();
+    const manifestFile = path.join(dir, 'manifest.json');
+    const files = ['validations/base-sc.json', 'signature.json'].map(f => ({
+      file: path.join(dir, f),
+    }));
+    await recordManifestArtifacts(manifestFile, files, { buildInfo });
+    expect((await verifyManifest(manifestFile)).checks.every(c => c.status === 'ok')).toBe(true);
+
+    await fs.writeFile(path.join(dir, 'validations', 'base-sc.json'), '{"cmd":"forgE"}\n');
+    await fs.rm(path.join(dir, 'signature.json'));
+    const { checks } = await verifyManifest(manifestFile);
+    expect(checks).toEqual([
+      { path: 'signature.json', status: 'missing' },
+      { path: 'validations/base-sc.json', status: 'changed' },
+    ]);
+  });
+});
+
+describe('rpcEndpointOrigin', () => {
+  it('drops credentials, path, and query', () => {
+    expect(rpcEndpointOrigin('https://user:pw@rpc.example:8545/key?x=1')).toBe(
+      'https://rpc.example:8545'
+    );
+  });
+});
@@ -0,0 +1,82 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  buildPrestateTraceParams,
+  buildSimulateV1Params,
+  parseSimulateV1Payload,
+  parseSimulateV1Result,
+} from '../simulate-v1';
+
+const SAFE = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const RECIPIENT = '0x1111111111111111111111111111111111111111';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const executedCall = { status: '0x1', returnData: '0x', gasUsed: '0x5208', logs: [] };
+
+describe('parseSimulateV1Payload', () => {
+  it('defaults to no state overrides', () => {
+    const payload = parseSimulateV1Payload({ from: SAFE, to: RECIPIENT, data: '0x' });
+    expect(payload.stateOverrides).toEqual([]);
+  });
+
+  it('rejects malformed overrides', () => {
+    expect(() =>
+      parseSimulateV1Payload({
+        from: SAFE,
+        to: RECIPIENT,
+        data: '0x',
+        stateOverrides: [{ contractAddress: SAFE, overrides: [{ key: '0x4', value: word(1) }] }],
+      })
+    ).toThrow(/stateOverrides\.0\.overrides\.0\.key/);
+  });
+});
+
+const overridden = () =>
+  parseSimulateV1Payload({
+    from: SAFE,
+    to: RECIPIENT,
+    data: '0x1234',
+    stateOverrides: [{ contractAddress: SAFE, overrides: [{ key: word(4), value: word(1) }] }],
+  });
+
+describe('buildSimulateV1Params', () => {
+  it('passes storage overrides as stateDiff', () => {
+    const payload = overridden();
+    const [opts, blockTag] = buildSimulateV1Params(payload) as [Record<string, unknown>, string];
+
+    expect(blockTag).toBe('latest');
+    expect(opts.validation).toBe(false);
+    expect(opts.blockStateCalls).toEqual([
+      {
+        stateOverrides: { [payload.from]: { stateDiff: { [word(4)]: word(1) } } },
+        calls: [{ from: payload.from, to: payload.to, data: '0x1234' }],
+      },
+    ]);
+  });
+});
+
+describe('buildPrestateTraceParams', () => {
+  it('traces the same call in diffMode with the same overrides', () => {
+    const payload = overridden();
+
+    expect(buildPrestateTraceParams(payload)).toEqual([
+      { from: payload.from, to: payload.to, data: '0x1234' },
+      'latest',
+      {
+        tracer: 'prestateTracer',
+        tracerConfig: { diffMode: true },
+        stateOverrides: { [payload.from]: { stateDiff: { [word(4)]: word(1) } } },
+      },
+    ]);
+  });
+});
+
+describe('parseSimulateV1Result', () => {
+  it('fails when a call reverted', () => {
+    const reverted = {
+      ...executedCall,
+      status: '0x0',
+      error: { code: 3, message: 'execution reverted: GS013' },
+    };
+    expect(() => parseSimulateV1Result([{ calls: [reverted] }])).toThrow(/GS013/);
+  });
+});
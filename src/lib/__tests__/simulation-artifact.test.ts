@@ -0,0 +1,30 @@
+import { describe, expect, it } from '@jest/globals';
+import { parseSimulationArtifact } from '../simulation-artifact';
+
+const artifact = {
+  version: 1,
+  cmd: 'forge script script/Simulate.s.sol --json',
+  forgeOutput: '{}',
+  stateDiff: {
+    targetSafe: '0x9855054731540a48b28990b63dcf4f33d8ae46a1',
+    dataToSign: '0x1901' + 'ab'.repeat(64),
+    stateDiff: '0x',
+    preimages: '0x',
+    overrides: '0x',
+  },
+};
+
+describe('parseSimulationArtifact', () => {
+  it('accepts a diff written by --simulate-only', () => {
+    expect(parseSimulationArtifact(artifact)).toEqual(artifact);
+  });
+
+  it('rejects a bare stateDiff.json', () => {
+    expect(() => parseSimulationArtifact(artifact.stateDiff)).toThrow(/Invalid simulation diff/);
+  });
+
+  it('reports which field is malformed', () => {
+    const bad = { ...artifact, stateDiff: { ...artifact.stateDiff, overrides: 'zz' } };
+    expect(() => parseSimulationArtifact(bad)).toThrow(/stateDiff\.overrides: Invalid hex value/);
+  });
+});
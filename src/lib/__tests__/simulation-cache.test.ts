@@ -0,0 +1,114 @@
+import { describe, expect, it } from '@jest/globals';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  forkBlockPin,
+  hashSimulationInputs,
+  loadCachedSimulation,
+  saveCachedSimulation,
+  simulationCacheKey,
+  simulationCachePath,
+} from '../simulation-cache';
+import type { SimulationArtifact } from '../simulation-artifact';
+
+const CMD = ['forge', 'script', 'Task.s.sol', '--fork-block-number', '21000000'];
+
+const artifact: SimulationArtifact = {
+  version: 1,
+  cmd: CMD.join(' '),
+  forgeOutput: 'Script ran successfully.',
+  stateDiff: {
+    targetSafe: '0x9855054731540A48b28990B63DcF4f33d8AE46A1',
+    dataToSign: `0x1901${'11'.repeat(64)}`,
+    stateDiff: '0x',
+    preimages: '0x',
+    overrides: '0x',
+  },
+};
+
+async function workdir(): Promise<string> {
+  const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'simulation-cache-'));
+  await fs.mkdir(path.join(dir, 'src'));
+  await fs.mkdir(path.join(dir, 'out'));
+  await fs.writeFile(path.join(dir, 'src', 'Task.s.sol'), 'contract Task {}');
+  await fs.writeFile(path.join(dir, 'config.toml'), 'safe = "0x1"');
+  return dir;
+}
+
+describe('forkBlockPin', () => {
+  it('reads both spellings and ignores unpinned commands', () => {
+    expect(forkBlockPin(CMD)).toBe('21000000');
+    expect(forkBlockPin(['forge', 'script', '--fork-block-number=7'])).toBe('7');
+    expect(forkBlockPin(['forge', 'script', 'Task.s.sol'])).toBeNull();
+  });
+});
+
+describe('hashSimulationInputs', () => {
+  it('changes with any file, but not with build output or the files the run writes', async () => {
+    const dir = await workdir();
+    const output = path.join(dir, 'validation.json');
+    const before = await hashSimulationInputs(dir, [output]);
+
+    await fs.writeFile(path.join(dir, 'out', 'Task.sol'), 'compiled');
+    await fs.writeFile(path.join(dir, 'stateDiff.json'), '{}');
+    await fs.writeFile(output, '{}');
+    expect(await hashSimulationInputs(dir, [output])).toBe(before);
+
+    await fs.writeFile(path.join(dir, 'config.toml'), 'safe = "0x2"');
+    expect(await hashSimulationInputs(dir, [output])).not.toBe(before);
+  });
+
+  it('misses the cache when a JSON input changes', async () => {
+    const dir = await workdir();
+    await fs.writeFile(path.join(dir, 'addresses.json'), '{"portal": "0x1"}');
+    const key = async () =>
+      simulationCacheKey({
+        forgeCmdParts: CMD,
+        block: '21000000',
+        inputsHash: await hashSimulationInputs(dir),
+        secretEnv: {},
+      });
+    const before = await key();
+
+    await fs.writeFile(path.join(dir, 'addresses.json'), '{"portal": "0x2"}');
+    expect(await key()).not.toBe(before);
+  });
+});
+
+describe('simulationCacheKey', () => {
+  it('depends on the block and secrets without containing them', () => {
+    const params = { forgeCmdParts: CMD, block: '21000000', inputsHash: 'ab', secretEnv: {} };
+    const key = simulationCacheKey(params);
+
+    expect(simulationCacheKey({ ...params, block: '21000001' })).not.toBe(key);
+    const withSecret = simulationCacheKey({ ...params, secretEnv: { PRIVATE_KEY: '0xsecret' } });
+    expect(withSecret).not.toBe(key);
+    expect(withSecret).not.toContain('secret');
+  });
+
+  it('depends on the host environment forge inherits, except shell bookkeeping', () => {
+    const params = { forgeCmdParts: CMD, block: '21000000', inputsHash: 'ab', secretEnv: {} };
+    const key = simulationCacheKey({ ...params, hostEnv: { FOUNDRY_PROFILE: 'default' } });
+
+    expect(simulationCacheKey({ ...params, hostEnv: { FOUNDRY_PROFILE: 'ci' } })).not.toBe(key);
+    expect(
+      simulationCacheKey({ ...params, hostEnv: { FOUNDRY_PROFILE: 'default', SHLVL: '2' } })
+    ).toBe(key);
+    const withToken = { FOUNDRY_PROFILE: 'default', RPC_TOKEN: 'abc' };
+    expect(simulationCacheKey({ ...params, hostEnv: withToken })).not.toBe(key);
+  });
+});
+
+describe('simulation cache', () => {
+  it('round-trips runs and treats unreadable entries as misses', async () => {
+    const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'simulation-cache-'));
+    expect(await loadCachedSimulation(dir, 'aa')).toBeNull();
+
+    await saveCachedSimulation(dir, 'aa', artifact);
+    expect(await loadCachedSimulation(dir, 'aa')).toEqual(artifact);
+
+    await fs.writeFile(simulationCachePath(dir, 'bb'), '{ not json');
+    expect(await loadCachedSimulation(dir, 'bb')).toBeNull();
+  });
+});
@@ -0,0 +1,109 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  envAssignments,
+  missingSecretEnv,
+  parseDotEnv,
+  recordSimulationEnv,
+  redactSecrets,
+  resolveSimulationEnv,
+  sanitizeCommand,
+  secretEnv,
+} from '../simulation-env';
+
+const SIGNER = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+
+describe('parseDotEnv', () => {
+  it('reads plain, exported, and quoted values and skips comments', () => {
+    const text = [
+      '# task settings',
+      `SIGNER_ADDRESS=${SIGNER}`,
+      'export SAFE_NONCE=12 # bumped after the last task',
+      'NOTE="two\\nlines"',
+      "RAW='keep \\n as is'",
+      '',
+    ].join('\n');
+    expect(parseDotEnv(text)).toEqual({
+      SIGNER_ADDRESS: SIGNER,
+      SAFE_NONCE: '12',
+      NOTE: 'two\nlines',
+      RAW: 'keep \\n as is',
+    });
+  });
+
+  it('rejects lines that are not assignments', () => {
+    expect(() => parseDotEnv('SAFE_NONCE=1\nnot an assignment')).toThrow(/line 2/);
+  });
+});
+
+describe('resolveSimulationEnv', () => {
+  it('lets --env override .env and keeps secrets out of the command', () => {
+    const entries = resolveSimulationEnv({
+      flags: ['SAFE_NONCE=13', 'ETHERSCAN_API_KEY=abc123'],
+      dotenv: { SIGNER_ADDRESS: SIGNER, SAFE_NONCE: '12' },
+      forgeCmdParts: ['forge', 'script', 'Upgrade'],
+    });
+
+    expect(envAssignments(entries)).toEqual([`SIGNER_ADDRESS=${SIGNER}`, 'SAFE_NONCE=13']);
+    expect(secretEnv(entries)).toEqual({ ETHERSCAN_API_KEY: 'abc123' });
+    expect(recordSimulationEnv(entries)).toEqual([
+      { name: 'SIGNER_ADDRESS', source: 'dotenv', value: SIGNER },
+      { name: 'SAFE_NONCE', source: 'flag', value: '13' },
+      { name: 'ETHERSCAN_API_KEY', source: 'flag', value: '<<Redacted>>' },
+    ]);
+  });
+
+  it('leaves keys the forge command assigns to the command', () => {
+    const forgeCmdParts = ['SAFE_NONCE=7', 'forge', 'script', 'Upgrade'];
+    const dotenv = { SAFE_NONCE: '12' };
+    expect(resolveSimulationEnv({ flags: [], dotenv, forgeCmdParts })).toEqual([]);
+    expect(() =>
+      resolveSimulationEnv({ flags: ['SAFE_NONCE=13'], dotenv: {}, forgeCmdParts })
+    ).toThrow(/already set in --forge-cmd/);
+  });
+
+  it('rejects malformed flags and values the command cannot carry', () => {
+    const forgeCmdParts = ['forge', 'script'];
+    expect(() => resolveSimulationEnv({ flags: ['NONCE'], dotenv: {}, forgeCmdParts })).toThrow(
+      /KEY=VALUE/
+    );
+    expect(() =>
+      resolveSimulationEnv({ flags: ['NOTE=two words'], dotenv: {}, forgeCmdParts })
+    ).toThrow(/whitespace/);
+  });
+});
+
+describe('log sanitizing', () => {
+  it('masks secret assignments and credential flags in the command', () => {
+    const parts = [
+      'PRIVATE_KEY=0xabc',
+      'SAFE_NONCE=1',
+      'forge',
+      'script',
+      '--private-key',
+      '0xdef',
+    ];
+    expect(sanitizeCommand(parts)).toBe(
+      'PRIVATE_KEY=<<Redacted>> SAFE_NONCE=1 forge script --private-key <<Redacted>>'
+    );
+    expect(sanitizeCommand(['forge', '--etherscan-api-key=xyz'])).toBe(
+      'forge --etherscan-api-key=<<Redacted>>'
+    );
+  });
+
+  it('masks secret values wherever they appear', () => {
+    expect(redactSecrets('key abc123 rejected (abc123)', ['abc123', ''])).toBe(
+      'key <<Redacted>> rejected (<<Redacted>>)'
+    );
+  });
+});
+
+describe('missingSecretEnv', () => {
+  it('lists recorded secrets that are not set', () => {
+    const recorded = [
+      { name: 'ETHERSCAN_API_KEY', source: 'flag' as const, value: '<<Redacted>>' },
+      { name: 'TENDERLY_TOKEN', source: 'dotenv' as const, value: '<<Redacted>>' },
+      { name: 'SAFE_NONCE', source: 'flag' as const, value: '13' },
+    ];
+    expect(missingSecretEnv(recorded, { TENDERLY_TOKEN: 't' })).toEqual(['ETHERSCAN_API_KEY']);
+  });
+});
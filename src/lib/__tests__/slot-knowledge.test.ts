@@ -0,0 +1,143 @@
+import { describe, expect, it, jest } from '@jest/globals';
+import { Address, Hex, keccak256 } from 'viem';
+import {
+  identifyKnownPatterns,
+  inspectContract,
+  KnownArtifact,
+  KnownPattern,
+  matchArtifact,
+  matchKnownPatterns,
+  metadataHash,
+  pinnedPatterns,
+} from '../slot-knowledge';
+
+const OWNABLE: KnownPattern<string> = {
+  name: 'Ownable',
+  selectors: ['0x8da5cb5b', '0xf2fde38b'],
+  codeHashes: [],
+  slots: { '0x33': 'owner' },
+};
+const PINNED_CODE = '0x6001600055' as Hex;
+const PINNED: KnownPattern<string> = {
+  name: 'Pinned',
+  selectors: [],
+  codeHashes: [keccak256(PINNED_CODE)],
+  slots: { '0x0': 'value' },
+};
+
+// Minimal dispatcher fragment: PUSH4 <selector> for each function
+const dispatcher = (...selectors: string[]) =>
+  ('0x6080' + selectors.map(s => '63' + s.slice(2) + '14').join('')) as Hex;
+
+describe('matchKnownPatterns', () => {
+  it('matches when every selector is dispatched', () => {
+    expect(matchKnownPatterns(dispatcher('0x8da5cb5b', '0xf2fde38b'), [OWNABLE])).toEqual([
+      OWNABLE,
+    ]);
+    expect(matchKnownPatterns(dispatcher('0x8da5cb5b'), [OWNABLE])).toEqual([]);
+  });
+
+  it('matches pinned code hashes', () => {
+    expect(matchKnownPatterns(PINNED_CODE, [OWNABLE, PINNED])).toEqual([PINNED]);
+  });
+
+  it('ignores empty code', () => {
+    expect(matchKnownPatterns('0x', [OWNABLE])).toEqual([]);
+  });
+});
+
+describe('identifyKnownPatterns', () => {
+  it('falls back to the EIP-1967 implementation of a proxy', async () => {
+    const proxy: Address = '0x1111111111111111111111111111111111111111';
+    const implementation = '0x2222222222222222222222222222222222222222';
+    const client = {
+      getCode: jest.fn(async ({ address }: { address: string }) =>
+        address === implementation ? dispatcher('0x8da5cb5b', '0xf2fde38b') : ('0x6080' as Hex)
+      ),
+      getStorageAt: jest.fn(async () => ('0x' + implementation.slice(2).padStart(64, '0')) as Hex),
+    };
+
+    const matches = await identifyKnownPatterns(client as never, proxy, [OWNABLE]);
+
+    expect(matches.map(m => m.name)).toEqual(['Ownable']);
+  });
+});
+
+describe('pinnedPatterns', () => {
+  it('names only the patterns matched by code hash', () => {
+    const both = { ...OWNABLE, codeHashes: [keccak256(PINNED_CODE)] };
+    expect(pinnedPatterns(PINNED_CODE, [OWNABLE, PINNED, both])).toEqual(['Pinned', 'Ownable']);
+    expect(pinnedPatterns(dispatcher('0x8da5cb5b', '0xf2fde38b'), [OWNABLE])).toEqual([]);
+    expect(pinnedPatterns('0x', [PINNED])).toEqual([]);
+  });
+});
+
+describe('inspectContract', () => {
+  it('reports selector matches as unpinned', async () => {
+    const address: Address = '0x1111111111111111111111111111111111111111';
+    const client = {
+      getCode: jest.fn(async () => dispatcher('0x8da5cb5b', '0xf2fde38b')),
+      getStorageAt: jest.fn(async () => `0x${'00'.repeat(32)}` as Hex),
+    };
+
+    const inspection = await inspectContract(client as never, address, [OWNABLE]);
+
+    expect(inspection.matches).toEqual([OWNABLE]);
+    expect(inspection.pinned).toEqual([]);
+    expect(await inspectContract(client as never, address, [OWNABLE], PINNED_CODE)).toEqual(
+      expect.objectContaining({ matches: [], pinned: [] })
+    );
+  });
+
+  it('reports code hash matches as pinned', async () => {
+    const address: Address = '0x1111111111111111111111111111111111111111';
+    const client = { getCode: jest.fn(async () => PINNED_CODE), getStorageAt: jest.fn() };
+
+    const inspection = await inspectContract(client as never, address, [PINNED]);
+
+    expect(inspection.pinned).toEqual(['Pinned']);
+  });
+});
+
+// solc's trailer: {"ipfs": <34 bytes>, "solc": 0.8.24} followed by its length, 0x0033
+const IPFS_HASH = ('0x1220' + 'ab'.repeat(32)) as Hex;
+const withMetadata = (runtime: string) =>
+  (runtime + 'a26469706673' + '5822' + IPFS_HASH.slice(2) + '64736f6c6343000818' + '0033') as Hex;
+
+describe('metadataHash', () => {
+  it('reads the IPFS hash from the CBOR trailer', () => {
+    expect(metadataHash(withMetadata('0x6080'))).toBe(IPFS_HASH);
+  });
+
+  it('returns null for code without metadata', () => {
+    expect(metadataHash(PINNED_CODE)).toBeNull();
+    expect(metadataHash('0x')).toBeNull();
+  });
+});
+
+describe('matchArtifact', () => {
+  const IMPL: KnownArtifact<string> = {
+    codeHashes: [],
+    metadataHashes: [IPFS_HASH],
+    contract: 'Implementation',
+  };
+  const PINNED_IMPL: KnownArtifact<string> = {
+    codeHashes: [keccak256(PINNED_CODE)],
+    metadataHashes: [],
+    contract: 'Pinned',
+  };
+
+  it('matches the metadata hash whatever the immutables', () => {
+    expect(matchArtifact(withMetadata('0x6080'), [PINNED_IMPL, IMPL])).toBe(IMPL);
+    expect(matchArtifact(withMetadata('0x6080600101'), [PINNED_IMPL, IMPL])).toBe(IMPL);
+  });
+
+  it('prefers an exact code hash', () => {
+    const exact = { ...PINNED_IMPL, codeHashes: [keccak256(withMetadata('0x6080'))] };
+    expect(matchArtifact(withMetadata('0x6080'), [IMPL, exact])).toBe(exact);
+  });
+
+  it('returns null when nothing matches', () => {
+    expect(matchArtifact(dispatcher('0x8da5cb5b'), [PINNED_IMPL, IMPL])).toBeNull();
+  });
+});
@@ -0,0 +1,85 @@
+import { describe, expect, it } from '@jest/globals';
+import { pad } from 'viem';
+import contractsCfg from '../config/contracts.json';
+import { renderSlotTemplate, slotTemplateValues } from '../slot-templates';
+
+type SlotEntry = { summary: string; overrideMeaning: string };
+
+const OWNER = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+
+describe('renderSlotTemplate', () => {
+  it('writes the words as integers, addresses, or hex', () => {
+    const values = slotTemplateValues({ before: pad('0x2'), after: pad('0x3') }, [pad(OWNER)]);
+    const threshold = 'Threshold changed from {{beforeDec}} to {{ afterDec }}';
+    expect(renderSlotTemplate(threshold, values, 'x')).toBe('Threshold changed from 2 to 3');
+    expect(renderSlotTemplate('Sets balances[{{mappingKeyAddr}}] to {{after}}', values, 'x')).toBe(
+      `Sets balances[${OWNER}] to ${pad('0x3')}`
+    );
+  });
+
+  it('leaves descriptions without variables as they are', () => {
+    expect(renderSlotTemplate('Updates the owner.', {}, 'x')).toBe('Updates the owner.');
+  });
+
+  it('refuses variables the slot does not have', () => {
+    const values = slotTemplateValues({ value: pad('0x1') }, []);
+    const where = 'Override meaning of Safe slot 0x4';
+    expect(() => renderSlotTemplate('Was {{beforeDec}}', values, where)).toThrow(
+      `${where} refers to {{beforeDec}}, which is not available; use one of: value`
+    );
+  });
+});
+
+describe('slotTemplateValues', () => {
+  it('names mapping keys outermost first', () => {
+    const values = slotTemplateValues({}, [pad('0xa'), pad('0xb')]);
+    expect(values).toEqual({ mappingKey: pad('0xa'), mappingKey2: pad('0xb') });
+  });
+});
+
+describe('byte ranges', () => {
+  it('reads a field out of a packed slot', () => {
+    // SystemConfig slot 0x68: gasLimit (uint64), basefeeScalar and blobbasefeeScalar (uint32)
+    const packed = pad('0x000f79c50000146b0000000001c9c380');
+    const values = slotTemplateValues({ after: packed }, []);
+    const summary =
+      'Gas limit {{afterDec[0:8]}}, scalars {{afterDec[8:12]}} and {{ afterDec[12:16] }}';
+    expect(renderSlotTemplate(summary, values, 'x')).toBe(
+      'Gas limit 30000000, scalars 5227 and 1014213'
+    );
+  });
+
+  it('refuses empty or out of range byte ranges', () => {
+    const values = slotTemplateValues({ after: pad('0x1') }, []);
+    expect(() => renderSlotTemplate('{{afterDec[4:4]}}', values, 'x')).toThrow(
+      'x has an invalid byte range in {{afterDec[4:4]}}'
+    );
+    expect(() => renderSlotTemplate('{{after[0:33]}}', values, 'x')).toThrow(/invalid byte range/);
+  });
+});
+
+describe('display hints', () => {
+  it('groups thousands and reads the word as the slot hint says', () => {
+    const values = slotTemplateValues({ before: pad('0x59682f00'), after: pad('0x1c9c380') }, []);
+    expect(renderSlotTemplate('{{afterNum}} gas', values, 'x')).toBe('30,000,000 gas');
+    expect(renderSlotTemplate('{{beforeFmt}}', values, 'x', { format: 'gwei' })).toBe('1.5 gwei');
+    expect(renderSlotTemplate('{{afterFmt}}', values, 'x', { decimals: 6 })).toBe('30');
+    expect(renderSlotTemplate('{{afterFmt}}', values, 'x')).toBe('30,000,000');
+  });
+});
+
+describe('contracts.json', () => {
+  it('only refers to values its slots have', () => {
+    const word = pad('0x1');
+    const summaries = slotTemplateValues({ before: word, after: word }, [word, word]);
+    const overrides = slotTemplateValues({ value: word }, [word, word]);
+    for (const [layout, slots] of Object.entries(contractsCfg.storageLayouts)) {
+      for (const [key, slot] of Object.entries(slots as Record<string, SlotEntry>)) {
+        expect(() => renderSlotTemplate(slot.summary, summaries, `${layout} ${key}`)).not.toThrow();
+        expect(() =>
+          renderSlotTemplate(slot.overrideMeaning, overrides, `${layout} ${key}`)
+        ).not.toThrow();
+      }
+    }
+  });
+});
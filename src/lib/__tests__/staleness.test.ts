@@ -0,0 +1,36 @@
+import { describe, expect, it } from '@jest/globals';
+import { DEFAULT_STALENESS_LIMITS, findStaleness, stalenessLimitsFromEnv } from '../staleness';
+
+const recorded = {
+  blockNumber: 1000,
+  blockTimestamp: 1760000000,
+  generatedAt: '2026-10-01T12:00:00.000Z',
+};
+
+describe('findStaleness', () => {
+  it('accepts a recent file', () => {
+    const current = { now: new Date('2026-10-02T12:00:00Z'), blockNumber: 1500 };
+    expect(findStaleness(recorded, current, DEFAULT_STALENESS_LIMITS)).toEqual([]);
+  });
+
+  it('warns when the file is older than the TTL or the head moved too far', () => {
+    const current = { now: new Date('2026-10-05T12:00:00Z'), blockNumber: 30000 };
+    const warnings = findStaleness(recorded, current, DEFAULT_STALENESS_LIMITS);
+
+    expect(warnings).toHaveLength(2);
+    expect(warnings[0]).toMatch(/generated 96 hours ago \(limit 72\)/);
+    expect(warnings[1]).toMatch(/advanced 29000 blocks since the simulated block 1000/);
+  });
+});
+
+describe('stalenessLimitsFromEnv', () => {
+  it('reads the limits from the environment', () => {
+    expect(stalenessLimitsFromEnv({ VALIDATION_MAX_AGE_HOURS: '24' })).toEqual({
+      maxAgeHours: 24,
+      maxBlocksBehind: DEFAULT_STALENESS_LIMITS.maxBlocksBehind,
+    });
+    expect(() => stalenessLimitsFromEnv({ VALIDATION_MAX_BLOCKS_BEHIND: '-1' })).toThrow(
+      'VALIDATION_MAX_BLOCKS_BEHIND must be a positive number'
+    );
+  });
+});
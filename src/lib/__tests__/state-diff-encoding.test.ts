@@ -0,0 +1,77 @@
+import { describe, expect, it } from '@jest/globals';
+import { encodeAbiParameters, getAddress } from 'viem';
+import {
+  decodeOverrides,
+  decodePreimages,
+  decodeStateDiff,
+  OVERRIDES_PARAMS,
+  PREIMAGES_PARAMS,
+  STATE_DIFF_PARAMS,
+} from '../state-diff-encoding';
+import {
+  accountAccess,
+  mappingPreimage,
+  payload,
+  storageRead,
+  storageWrite,
+  syntheticAddress,
+  word,
+} from '../state-diff-test';
+import { AccountAccessKind } from '../vm-safe';
+
+const PORTAL = syntheticAddress(1);
+const TOKEN = syntheticAddress(2);
+
+const accesses = [
+  accountAccess({
+    account: PORTAL,
+    accessor: TOKEN,
+    oldBalance: BigInt(5),
+    newBalance: BigInt(7),
+    storageAccesses: [storageWrite(PORTAL, 1, 0, 2), storageRead(PORTAL, 3, 4)],
+  }),
+  accountAccess({
+    kind: AccountAccessKind.Create,
+    account: TOKEN,
+    deployedCode: '0x6001',
+    depth: BigInt(2),
+    oldNonce: BigInt(0),
+    newNonce: BigInt(1),
+    reverted: true,
+  }),
+];
+
+describe('state diff encoding', () => {
+  it('decodes what the module-level parameter trees encode', () => {
+    const call = payload({
+      from: TOKEN,
+      to: PORTAL,
+      data: '0x1234',
+      stateOverrides: [{ contractAddress: PORTAL, overrides: [{ key: word(4), value: word(1) }] }],
+    });
+    const preimages = [mappingPreimage(0, 7), mappingPreimage(1, 8)];
+
+    expect(decodeStateDiff(encodeAbiParameters(STATE_DIFF_PARAMS, [accesses]))).toEqual(accesses);
+    expect(decodeOverrides(encodeAbiParameters(OVERRIDES_PARAMS, [call]))).toEqual(call);
+    expect(decodePreimages(encodeAbiParameters(PREIMAGES_PARAMS, [preimages]))).toEqual(preimages);
+  });
+
+  it('returns independent results for repeated decodes', () => {
+    const encoded = encodeAbiParameters(STATE_DIFF_PARAMS, [accesses]);
+    const first = decodeStateDiff(encoded);
+    const other = decodeStateDiff(encodeAbiParameters(STATE_DIFF_PARAMS, [[accesses[1]]]));
+    const second = decodeStateDiff(encoded);
+
+    expect(second).toEqual(first);
+    expect(second).not.toBe(first);
+    expect(other).toEqual([accesses[1]]);
+  });
+
+  it('checksums decoded addresses and keeps empty lists', () => {
+    const lower = accountAccess({ account: PORTAL.toLowerCase() as typeof PORTAL });
+    const [decoded] = decodeStateDiff(encodeAbiParameters(STATE_DIFF_PARAMS, [[lower]]));
+
+    expect(decoded.account).toBe(getAddress(PORTAL));
+    expect(decodePreimages(encodeAbiParameters(PREIMAGES_PARAMS, [[]]))).toEqual([]);
+  });
+});
@@ -0,0 +1,48 @@
+import { describe, expect, it } from '@jest/globals';
+import { concat, custom, Hex, keccak256, pad, toHex } from 'viem';
+import { StateDiffClient } from '../state-diff';
+
+const SYSTEM_CONFIG = '0x73a79Fab69143498Ed3712e519A88a918e1f4072';
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+// SystemConfig slot 0x68: a 30M gas limit and the Ecotone fee scalars
+const PACKED = pad('0x000f79c50000146b0000000001c9c380');
+
+const transport = custom({
+  async request({ method }: { method: string }) {
+    if (method === 'eth_chainId') return toHex(1);
+    if (method === 'eth_getStorageAt') return PACKED;
+    throw new Error(`unexpected RPC call ${method}`);
+  },
+});
+
+describe('explainSlot', () => {
+  it('describes a configured slot with its current value', async () => {
+    const explained = await new StateDiffClient(0, undefined, { transport }).explainSlot({
+      address: SYSTEM_CONFIG,
+      slot: '0x68',
+    });
+    expect(explained).toMatchObject({
+      chainId: '1',
+      address: SYSTEM_CONFIG,
+      contract: 'System Config - Mainnet',
+      slot: pad('0x68'),
+      derivation: [],
+      value: PACKED,
+    });
+    expect(explained.entry?.description).toMatch(
+      /^Updates the L2 gas limit from 30,000,000 to 30,000,000 gas/
+    );
+  });
+
+  it('derives mapping slots from the preimages without an RPC endpoint', async () => {
+    const owner = pad(SAFE).toLowerCase() as Hex;
+    const slot = keccak256(concat([owner, pad('0x2')]));
+    const client = new StateDiffClient(0, undefined, {
+      preimages: [{ slot, parent: pad('0x2'), key: owner }],
+    });
+    const explained = await client.explainSlot({ chainId: 1, address: SAFE, slot });
+    expect(explained.derivation).toEqual([{ slot, parent: pad('0x2'), key: owner }]);
+    expect(explained.entry?.description).toBe('Updates the owners mapping');
+    expect(explained.value).toBeNull();
+  });
+});
@@ -0,0 +1,337 @@
+import { describe, expect, it } from '@jest/globals';
+import { createPublicClient, Hex, PublicClient, zeroAddress } from 'viem';
+import { PreimageSchema } from '../config-schemas';
+import { safeDomainHash } from '../safe-singleton';
+import { parseSimulationArtifact } from '../simulation-artifact';
+import { StateDiffClient } from '../state-diff';
+import { decodeOverrides, decodePreimages, decodeStateDiff } from '../state-diff-encoding';
+import { AccountAccessKind } from '../vm-safe';
+import {
+  accountAccess,
+  FAKE_RPC_URL,
+  fakeRpc,
+  mappingPreimage,
+  payload,
+  simulationArtifact,
+  storageRead,
+  storageWrite,
+  syntheticAddress,
+  word,
+} from '../state-diff-test';
+
+const PORTAL = syntheticAddress(1);
+const TOKEN = syntheticAddress(2);
+
+describe('simulationArtifact', () => {
+  it('encodes the run the way forge writes stateDiff.json', () => {
+    const accesses = [
+      accountAccess({
+        account: PORTAL,
+        storageAccesses: [storageWrite(PORTAL, 1, 0, 2), storageRead(PORTAL, 3, 4)],
+      }),
+    ];
+    const preimage = mappingPreimage(0, TOKEN);
+    const overridden = payload({
+      to: PORTAL,
+      stateOverrides: [{ contractAddress: PORTAL, overrides: [{ key: word(5), value: word(6) }] }],
+    });
+
+    const artifact = parseSimulationArtifact(
+      simulationArtifact({ accesses, preimages: [preimage], payload: overridden })
+    );
+
+    expect(decodeStateDiff(artifact.stateDiff.stateDiff)).toEqual(accesses);
+    expect(decodePreimages(artifact.stateDiff.preimages)).toEqual([preimage]);
+    expect(decodeOverrides(artifact.stateDiff.overrides)).toEqual(overridden);
+    expect(PreimageSchema.safeParse(preimage).success).toBe(true);
+  });
+});
+
+describe('fakeRpc', () => {
+  it('serves the listed state and records every request', async () => {
+    const rpc = fakeRpc({
+      chainId: 8453,
+      storage: { [PORTAL.toLowerCase()]: { '0x1': word(7) } },
+      balances: { [TOKEN]: BigInt(9) },
+      call: ({ to }) => (to === TOKEN ? word(1) : undefined),
+    });
+    const client = createPublicClient({ transport: rpc.transport }) as PublicClient;
+
+    expect(await client.getChainId()).toBe(8453);
+    expect(await client.getStorageAt({ address: PORTAL, slot: word(1) })).toBe(word(7));
+    expect(await client.getStorageAt({ address: PORTAL, slot: word(2) })).toBe(word(0));
+    expect(await client.getBalance({ address: TOKEN })).toBe(BigInt(9));
+    expect((await client.call({ to: TOKEN, data: '0x' })).data).toBe(word(1));
+    await expect(client.call({ to: PORTAL, data: '0x' })).rejects.toThrow();
+    await expect(client.getTransactionCount({ address: zeroAddress })).rejects.toThrow(
+      /unexpected call eth_getTransactionCount/
+    );
+    expect(rpc.calls.map(c => c.method)).toEqual([
+      'eth_chainId',
+      'eth_getStorageAt',
+      'eth_getStorageAt',
+      'eth_getBalance',
+      'eth_call',
+      'eth_call',
+      'eth_getTransactionCount',
+    ]);
+  });
+});
+
+describe('StateDiffClient with the fakes', () => {
+  it('builds a report from a synthetic run', async () => {
+    const rpc = fakeRpc({ code: { [PORTAL]: '0x6001' } });
+    const client = new StateDiffClient(0, undefined, { transport: rpc.transport });
+    const artifact = simulationArtifact({
+      accesses: [
+        accountAccess({ account: PORTAL, storageAccesses: [storageWrite(PORTAL, 1, 0, 2)] }),
+      ],
+    });
+
+    const { result } = await client.fromSimulationArtifact(FAKE_RPC_URL, artifact);
+
+    expect(result.stateChanges.map(sc => sc.address)).toEqual([PORTAL]);
+    expect(result.stateChanges[0].changes[0]).toMatchObject({
+      key: word(1),
+      before: word(0),
+      after: word(2),
+    });
+  });
+
+  it('marks accounts that did not exist before the run as new', async () => {
+    const client = new StateDiffClient(0, undefined, { transport: fakeRpc({}).transport });
+    const artifact = simulationArtifact({
+      accesses: [
+        accountAccess({ account: PORTAL, storageAccesses: [storageWrite(PORTAL, 1, 0, 2)] }),
+        accountAccess({
+          account: TOKEN,
+          initialized: false,
+          storageAccesses: [storageWrite(TOKEN, 1, 0, 2)],
+        }),
+      ],
+    });
+
+    const { result } = await client.fromSimulationArtifact(FAKE_RPC_URL, artifact);
+
+    expect(result.stateChanges.map(sc => [sc.address, sc.newAccount])).toEqual([
+      [PORTAL, undefined],
+      [TOKEN, true],
+    ]);
+  });
+
+  it('names a contract the run deploys after its artifact', async () => {
+    const impl = syntheticAddress(3);
+    const metadata = '0x1220' + 'cd'.repeat(32);
+    // Runtime code ending in solc's CBOR trailer: {"ipfs": metadata, "solc": 0.8.24}
+    const deployedCode: Hex = `0x6080a264697066735822${metadata.slice(2)}64736f6c63430008180033`;
+    const client = new StateDiffClient(0, undefined, {
+      transport: fakeRpc({}).transport,
+      configOverlay: {
+        artifacts: [
+          {
+            name: 'FeeVault',
+            metadataHashes: [metadata],
+            slots: {
+              '0x1': {
+                type: 'uint256',
+                summary: 'Sets the fee.',
+                overrideMeaning: '',
+                allowDifference: false,
+                allowOverrideDifference: false,
+              },
+            },
+          },
+        ],
+      },
+    });
+    const artifact = simulationArtifact({
+      accesses: [
+        accountAccess({ kind: AccountAccessKind.Create, account: impl, deployedCode }),
+        accountAccess({ account: impl, storageAccesses: [storageWrite(impl, 1, 0, 5)] }),
+      ],
+    });
+
+    const { result } = await client.fromSimulationArtifact(FAKE_RPC_URL, artifact);
+
+    expect(result.stateChanges.map(sc => sc.name)).toEqual(['FeeVault']);
+    expect(result.stateChanges[0].changes[0].description).toContain('Sets the fee.');
+  });
+
+  it('leaves noise slots out of the changes and counts them', async () => {
+    const slot = (noise: boolean) => ({
+      type: 'uint256',
+      summary: noise ? 'Last update time.' : 'Sets the fee.',
+      overrideMeaning: '',
+      allowDifference: false,
+      allowOverrideDifference: false,
+      ...(noise && { noise }),
+    });
+    const client = new StateDiffClient(0, undefined, {
+      transport: fakeRpc({ code: { [PORTAL]: '0x6001' } }).transport,
+      configOverlay: {
+        contracts: {
+          '1': { [PORTAL]: { name: 'Portal', slots: { '0x1': slot(false), '0x2': slot(true) } } },
+        },
+      },
+    });
+    const artifact = simulationArtifact({
+      accesses: [
+        accountAccess({
+          account: PORTAL,
+          storageAccesses: [storageWrite(PORTAL, 1, 0, 5), storageWrite(PORTAL, 2, 0, 9)],
+        }),
+      ],
+    });
+
+    const { result } = await client.fromSimulationArtifact(FAKE_RPC_URL, artifact);
+
+    expect(result.stateChanges[0].changes.map(c => c.key)).toEqual([word(1)]);
+    expect(result.summary?.slotsChanged).toBe(1);
+    expect(result.summary?.noiseFiltered).toEqual({ stateChanges: 1, balanceChanges: 0 });
+  });
+  it('names before and after values that equal a constant, except amounts', async () => {
+    const slot = (decimals?: number) => ({
+      type: 'uint256',
+      summary: 'Sets a value.',
+      overrideMeaning: '',
+      allowDifference: false,
+      allowOverrideDifference: false,
+      ...(decimals !== undefined && { decimals }),
+    });
+    const client = new StateDiffClient(0, undefined, {
+      transport: fakeRpc({ code: { [PORTAL]: '0x6001' } }).transport,
+      configOverlay: {
+        contracts: {
+          '1': {
+            [PORTAL]: {
+              name: 'Portal',
+              slots: { '0x1': slot(), '0x2': slot(18), '0x3': slot() },
+            },
+          },
+        },
+        constants: { FEE_VAULT: TOKEN },
+      },
+    });
+    const artifact = simulationArtifact({
+      accesses: [
+        accountAccess({
+          account: PORTAL,
+          storageAccesses: [
+            storageWrite(PORTAL, 1, 0, 1),
+            storageWrite(PORTAL, 2, 0, 1),
+            storageWrite(PORTAL, 3, 1, word(TOKEN)),
+          ],
+        }),
+      ],
+    });
+
+    const { result } = await client.fromSimulationArtifact(FAKE_RPC_URL, artifact);
+
+    expect(result.stateChanges[0].changes.map(c => [c.key, c.beforeAlias, c.afterAlias])).toEqual([
+      [word(1), undefined, 'SENTINEL_OWNERS'],
+      [word(2), undefined, undefined],
+      [word(3), 'SENTINEL_OWNERS', 'FEE_VAULT'],
+    ]);
+  });
+
+  it('marks changes whose before value came from an override', async () => {
+    const client = new StateDiffClient(0, undefined, { transport: fakeRpc({}).transport });
+    const artifact = simulationArtifact({
+      accesses: [
+        accountAccess({
+          account: PORTAL,
+          storageAccesses: [storageWrite(PORTAL, 1, 7, 8), storageWrite(PORTAL, 2, 0, 9)],
+        }),
+      ],
+      payload: payload({
+        stateOverrides: [{ contractAddress: PORTAL, overrides: [{ key: word(1), value: word(7) }] }],
+      }),
+    });
+
+    const { result } = await client.fromSimulationArtifact(FAKE_RPC_URL, artifact);
+
+    expect(result.stateChanges[0].changes.map(c => [c.key, c.beforeFromOverride])).toEqual([
+      [word(1), true],
+      [word(2), undefined],
+    ]);
+  });
+
+  it('records the write history of slots written more than once', async () => {
+    const client = new StateDiffClient(0, undefined, { transport: fakeRpc({}).transport });
+    const artifact = simulationArtifact({
+      accesses: [
+        accountAccess({
+          account: PORTAL,
+          storageAccesses: [storageWrite(PORTAL, 1, 0, 5), storageWrite(PORTAL, 4, 1, 2)],
+        }),
+        accountAccess({
+          kind: AccountAccessKind.DelegateCall,
+          account: TOKEN,
+          depth: BigInt(2),
+          storageAccesses: [storageWrite(PORTAL, 4, 2, 3), storageWrite(PORTAL, 4, 3, 9, true)],
+        }),
+      ],
+    });
+
+    const { result } = await client.fromSimulationArtifact(FAKE_RPC_URL, artifact);
+
+    const [single, repeated] = result.stateChanges[0].changes;
+    expect(single.history).toBeUndefined();
+    expect(repeated.history).toEqual([
+      { value: word(2), writer: PORTAL, depth: 1, reverted: false },
+      { value: word(3), writer: TOKEN, depth: 2, reverted: false },
+      { value: word(9), writer: TOKEN, depth: 2, reverted: true },
+    ]);
+  });
+
+  it("records the Safe's singleton and checks the domain hash against its version", async () => {
+    const safe = syntheticAddress(0x5afe);
+    const singleton = '0x41675C099F32341bf84BFc5382aF534df5C7461a';
+    const rpc = fakeRpc({ storage: { [safe.toLowerCase()]: { '0x0': word(singleton) } } });
+    const client = new StateDiffClient(0, undefined, { transport: rpc.transport });
+
+    const signed = await client.fromSimulationArtifact(
+      FAKE_RPC_URL,
+      simulationArtifact({ targetSafe: safe, domainHash: safeDomainHash('1.4.1', 1, safe) })
+    );
+    const mismatched = await client.fromSimulationArtifact(FAKE_RPC_URL, simulationArtifact());
+
+    expect(signed.result.expectedDomainAndMessageHashes).toMatchObject({
+      singleton,
+      safeVersion: '1.4.1',
+    });
+    expect(signed.warnings.map(w => w.code)).not.toContain('SAFE_DOMAIN_MISMATCH');
+    expect(mismatched.warnings.map(w => w.code)).toContain('SAFE_DOMAIN_MISMATCH');
+  });
+
+  it('takes the storage diff of an eth_simulateV1 run from a prestateTracer trace', async () => {
+    const executed = [{ calls: [{ status: '0x1', returnData: '0x', gasUsed: '0x1', logs: [] }] }];
+    const trace = {
+      pre: { [PORTAL]: { storage: { [word(1)]: word(3) } } },
+      post: { [PORTAL]: { storage: { [word(1)]: word(4) } } },
+    };
+    const opts = { targetSafe: syntheticAddress(0x5afe), dataToSign: `0x1901${'11'.repeat(64)}` };
+    const tracing = fakeRpc({
+      methods: { eth_simulateV1: () => executed, debug_traceCall: () => trace },
+    });
+    const client = new StateDiffClient(0, undefined, { transport: tracing.transport });
+
+    const { result } = await client.fromSimulateV1(FAKE_RPC_URL, payload(), opts);
+
+    expect(result.stateChanges[0].changes[0]).toMatchObject({
+      key: word(1),
+      before: word(3),
+      after: word(4),
+    });
+    const traceCall = tracing.calls.find(c => c.method === 'debug_traceCall');
+    expect(traceCall?.params[2]).toMatchObject({ tracerConfig: { diffMode: true } });
+
+    // Without a trace there are no storage changes to report, so no file is written
+    const untraced = new StateDiffClient(0, undefined, {
+      transport: fakeRpc({ methods: { eth_simulateV1: () => executed } }).transport,
+    });
+    await expect(untraced.fromSimulateV1(FAKE_RPC_URL, payload(), opts)).rejects.toThrow(
+      /debug_traceCall request failed/
+    );
+  });
+});
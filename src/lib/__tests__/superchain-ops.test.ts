@@ -0,0 +1,138 @@
+import { describe, expect, it } from '@jest/globals';
+import { renderSuperchainOpsValidation } from '../superchain-ops';
+import type { TaskConfig } from '../types';
+
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const config = (safe: string, domain: number): TaskConfig => ({
+  cmd: 'forge script Upgrade',
+  ledgerId: 0,
+  rpcUrl: 'https://rpc.example',
+  expectedDomainAndMessageHashes: { address: safe, domainHash: word(domain), messageHash: word(9) },
+  stateOverrides: [],
+  stateChanges: [
+    {
+      name: 'System Config',
+      address: '0x73a79Fab69143498Ed3712e519A88a918e1f4072',
+      changes: [
+        {
+          key: word(1),
+          before: word(2),
+          after: word(3),
+          description: 'Updates the gas limit.',
+          allowDifference: false,
+        },
+      ],
+    },
+  ],
+});
+
+describe('renderSuperchainOpsValidation', () => {
+  it('renders a hash block per signer and the shared state changes', () => {
+    const markdown = renderSuperchainOpsValidation([
+      { label: 'base-sc', config: config('0x9855054731540A48b28990B63DcF4f33d8AE46A1', 1) },
+      { label: 'base-nested', config: config('0x9C4a57Feb77e294Fd7BF5EBE9AB01CAA0a90A110', 2) },
+    ]);
+
+    expect(markdown).toContain('> ### base-sc (`0x9855054731540A48b28990B63DcF4f33d8AE46A1`)');
+    expect(markdown).toContain(`> - Domain Hash: \`${word(2)}\``);
+    expect(markdown).toContain('### `0x73a79Fab69143498Ed3712e519A88a918e1f4072` (System Config)');
+    expect(markdown).toContain(`  - **After**: \`${word(3)}\``);
+    expect(markdown).not.toContain('## State Overrides');
+  });
+
+  it("lists each file's state changes when the files differ", () => {
+    const nested = config('0x9C4a57Feb77e294Fd7BF5EBE9AB01CAA0a90A110', 2);
+    const markdown = renderSuperchainOpsValidation([
+      { label: 'base-sc', config: config('0x9855054731540A48b28990B63DcF4f33d8AE46A1', 1) },
+      {
+        label: 'base-nested',
+        config: {
+          ...nested,
+          stateOverrides: [
+            {
+              name: 'Nested Safe',
+              address: '0x9C4a57Feb77e294Fd7BF5EBE9AB01CAA0a90A110',
+              overrides: [{ key: word(4), value: word(1), description: 'Threshold' }],
+            },
+          ],
+          stateChanges: [
+            {
+              ...nested.stateChanges[0],
+              changes: [{ ...nested.stateChanges[0].changes[0], after: word(5) }],
+            },
+          ],
+        },
+      },
+    ]);
+
+    expect(markdown).toContain(
+      '## State Changes\n\n### base-sc\n\n#### `0x73a79Fab69143498Ed3712e519A88a918e1f4072`'
+    );
+    expect(markdown).toContain(`  - **After**: \`${word(3)}\``);
+    expect(markdown).toContain(
+      '### base-nested\n\n#### `0x73a79Fab69143498Ed3712e519A88a918e1f4072`'
+    );
+    expect(markdown).toContain(`  - **After**: \`${word(5)}\``);
+    expect(markdown).toContain('### base-sc\n\nNone.\n\n### base-nested\n\n#### `0x9C4a57');
+  });
+
+  it('writes amounts, dates, and durations for the locale and keeps raw values', () => {
+    const markdown = renderSuperchainOpsValidation(
+      [
+        {
+          label: 'base-sc',
+          config: {
+            ...config('0x9855054731540A48b28990B63DcF4f33d8AE46A1', 1),
+            simulatedAt: {
+              blockNumber: 21000000,
+              blockTimestamp: Date.parse('2026-10-16T09:59:48Z') / 1000,
+              generatedAt: '2026-10-16T10:00:00.000Z',
+            },
+            balanceChanges: [
+              {
+                name: 'Fee Vault',
+                address: '0x4200000000000000000000000000000000000011',
+                field: 'ETH Balance (wei)',
+                before: word(0),
+                after: '0x' + BigInt('1500000000000000000').toString(16).padStart(64, '0'),
+                description: 'Receives fees.',
+                allowDifference: false,
+              },
+            ],
+          },
+        },
+      ],
+      { locale: 'de-DE' }
+    );
+
+    expect(markdown).toContain(
+      'against block 21.000.000, produced on 16. Oktober 2026 um 09:59:48 UTC'
+    );
+    expect(markdown).toContain(
+      'stale 3 Tage after it was generated, from 19. Oktober 2026 um 10:00:00 UTC'
+    );
+    expect(markdown).toContain('  - **After**: `1500000000000000000` (1,5 ETH)');
+  });
+
+  it("names the target Safe's version above its hashes", () => {
+    const safe = config('0x9855054731540A48b28990B63DcF4f33d8AE46A1', 1);
+    const markdown = renderSuperchainOpsValidation([
+      {
+        label: 'base-sc',
+        config: {
+          ...safe,
+          expectedDomainAndMessageHashes: {
+            ...safe.expectedDomainAndMessageHashes,
+            singleton: '0xd9Db270c1B5E3Bd161E8c8503c55cEABeE709552',
+            safeVersion: '1.3.0',
+          },
+        },
+      },
+    ]);
+
+    expect(markdown).toContain(
+      '> - Safe Version: 1.3.0 (singleton `0xd9Db270c1B5E3Bd161E8c8503c55cEABeE709552`)\n> - Domain Hash:'
+    );
+  });
+});
@@ -0,0 +1,94 @@
+import { describe, expect, it } from '@jest/globals';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { keccak256, toBytes } from 'viem';
+import { hashArtifact } from '../ceremony-log';
+import {
+  ledgerTaskName,
+  readLedger,
+  recordSignature,
+  recordValidation,
+  summarizeLedger,
+  verifyLedger,
+} from '../task-ledger';
+import type { TaskConfig } from '../types';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const TASK = '2026-10-01-upgrade-portal';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const config = {
+  cmd: 'forge script Upgrade',
+  ledgerId: 0,
+  rpcUrl: 'https://rpc.example',
+  chainId: 1,
+  expectedDomainAndMessageHashes: { address: SAFE, domainHash: word(1), messageHash: word(2) },
+  stateOverrides: [],
+  stateChanges: [],
+} as TaskConfig;
+
+async function tempDir(): Promise<string> {
+  return fs.mkdtemp(path.join(os.tmpdir(), 'task-ledger-'));
+}
+
+describe('task ledger', () => {
+  it('records validations and signatures in a hash chain', async () => {
+    const dir = await tempDir();
+    const ledger = path.join(dir, 'ledger.jsonl');
+    const signatureFile = path.join(dir, 'creator-signature.json');
+    await fs.writeFile(signatureFile, '{"bundle":1}');
+
+    const outFile = path.join(dir, 'tasks', TASK, 'validations', 'base-sc.json');
+    const artifact = { ...config, attestation: { signer: SAFE } };
+    const validation = await recordValidation(ledger, {
+      task: ledgerTaskName(outFile),
+      outFile,
+      config,
+      artifact,
+    });
+    const signature = await recordSignature(ledger, {
+      task: TASK,
+      role: 'taskCreator',
+      signer: 'alice@example.com',
+      signatureFile,
+    });
+
+    expect(validation.task).toBe(TASK);
+    expect(validation.details).toMatchObject({ chainId: 1, safe: SAFE });
+    expect(validation.details.artifactHash).toBe(hashArtifact(artifact));
+    expect(signature.prevHash).toBe(validation.entryHash);
+    expect(signature.details.fileHash).toBe(keccak256(toBytes('{"bundle":1}')));
+
+    const entries = await readLedger(ledger);
+    expect(verifyLedger(entries)).toEqual([]);
+    expect(summarizeLedger(entries)).toEqual([
+      {
+        task: TASK,
+        validations: 1,
+        signatures: 1,
+        chains: [1],
+        lastActivity: signature.timestamp,
+      },
+    ]);
+  });
+
+  it('detects edited and dropped entries', async () => {
+    const ledger = path.join(await tempDir(), 'ledger.jsonl');
+    for (const task of ['a', 'b', 'c']) {
+      await recordValidation(ledger, { task, outFile: `${task}.json`, config, artifact: config });
+    }
+    const entries = await readLedger(ledger);
+
+    const edited = entries.map(e => (e.seq === 1 ? { ...e, task: 'x' } : e));
+    expect(verifyLedger(edited)).toEqual(['line 2: entry hash mismatch']);
+    expect(verifyLedger([entries[0], entries[2]])).toEqual([
+      'line 2: expected seq 1',
+      'line 2: broken hash chain',
+    ]);
+  });
+
+  it('reads a missing ledger as empty', async () => {
+    expect(await readLedger(path.join(await tempDir(), 'missing.jsonl'))).toEqual([]);
+  });
+});
@@ -0,0 +1,103 @@
+import { afterEach, beforeEach, describe, expect, it, jest } from '@jest/globals';
+import { StateDiffClient } from '../state-diff';
+import {
+  accountAccess,
+  FAKE_RPC_URL,
+  fakeRpc,
+  payload,
+  simulationArtifact,
+  storageWrite,
+  syntheticAddress,
+  word,
+} from '../state-diff-test';
+import { flushTelemetry, incrementCounter, withSpan, withTelemetryRun } from '../telemetry';
+
+type Export = {
+  resourceSpans?: { scopeSpans: { spans: { name: string }[] }[] }[];
+  resourceMetrics?: {
+    scopeMetrics: { metrics: { name: string; sum: { dataPoints: { asInt: string }[] } }[] }[];
+  }[];
+};
+
+let exports: Export[];
+const realFetch = global.fetch;
+
+beforeEach(() => {
+  exports = [];
+  process.env.OTEL_EXPORTER_OTLP_ENDPOINT = 'https://otel.example';
+  global.fetch = jest.fn(async (_url: unknown, init?: RequestInit) => {
+    exports.push(JSON.parse(String(init?.body)));
+    return new Response(null, { status: 200 });
+  }) as unknown as typeof fetch;
+});
+
+afterEach(() => {
+  delete process.env.OTEL_EXPORTER_OTLP_ENDPOINT;
+  global.fetch = realFetch;
+});
+
+const spanNames = (e: Export) =>
+  (e.resourceSpans ?? []).flatMap(r => r.scopeSpans.flatMap(s => s.spans.map(span => span.name)));
+const counters = (e: Export) =>
+  Object.fromEntries(
+    (e.resourceMetrics ?? []).flatMap(r =>
+      r.scopeMetrics.flatMap(s => s.metrics.map(m => [m.name, Number(m.sum.dataPoints[0].asInt)]))
+    )
+  );
+
+describe('withTelemetryRun', () => {
+  it('exports only the spans and counters of its own run', async () => {
+    let release!: () => void;
+    const blocked = new Promise<void>(resolve => (release = resolve));
+
+    const slow = withTelemetryRun(async () => {
+      incrementCounter('slow.count');
+      await blocked;
+      await withSpan('slow', {}, async () => undefined);
+    });
+    await withTelemetryRun(async () => {
+      incrementCounter('fast.count', 2);
+      await withSpan('fast', {}, async () => undefined);
+    });
+
+    expect(exports.flatMap(spanNames)).toEqual(['fast']);
+    expect(Object.assign({}, ...exports.map(counters))).toEqual({ 'fast.count': 2 });
+
+    release();
+    await slow;
+    expect(exports.flatMap(spanNames)).toEqual(['fast', 'slow']);
+    expect(counters(exports[exports.length - 1])).toEqual({ 'slow.count': 1 });
+  });
+
+  it('leaves the process buffer to flushTelemetry outside a run', async () => {
+    incrementCounter('cli.count');
+    await withTelemetryRun(async () => incrementCounter('run.count'));
+    expect(exports.map(counters)).toEqual([{ 'run.count': 1 }]);
+
+    await flushTelemetry();
+    expect(exports.map(counters)).toEqual([{ 'run.count': 1 }, { 'cli.count': 1 }]);
+  });
+});
+
+describe('task_signing.unknown_slots', () => {
+  it('counts each unknown changed slot once, however often it is looked up', async () => {
+    const portal = syntheticAddress(1);
+    const client = new StateDiffClient(0, undefined, { transport: fakeRpc({}).transport });
+    // The overridden slot is described for its override and again for its change
+    const artifact = simulationArtifact({
+      accesses: [
+        accountAccess({
+          account: portal,
+          storageAccesses: [storageWrite(portal, 1, 7, 8), storageWrite(portal, 2, 0, 9)],
+        }),
+      ],
+      payload: payload({
+        stateOverrides: [{ contractAddress: portal, overrides: [{ key: word(1), value: word(7) }] }],
+      }),
+    });
+
+    await withTelemetryRun(() => client.fromSimulationArtifact(FAKE_RPC_URL, artifact));
+
+    expect(Object.assign({}, ...exports.map(counters))['task_signing.unknown_slots']).toBe(2);
+  });
+});
@@ -0,0 +1,79 @@
+import { describe, expect, it } from '@jest/globals';
+import path from 'path';
+import { PolicyViolationError } from '../errors';
+import { parseTenantRegistry, resolveTenant, tenantStalenessLimits } from '../tenants';
+
+const REGISTRY = `
+tenants:
+  base:
+    chainIds: [1, 8453]
+    overlays:
+      staging: overlays/staging.json
+    policies:
+      strict: { unknowns: error, maxAgeHours: 24 }
+    metadataCache: cache/base
+  sandbox:
+    chainIds: [11155111]
+`;
+
+const DIR = path.resolve('/srv/tenants');
+const registry = parseTenantRegistry(REGISTRY, DIR);
+
+describe('parseTenantRegistry', () => {
+  it('rejects unknown fields and empty chain lists', () => {
+    expect(() => parseTenantRegistry('tenants:\n  a:\n    chainIds: []\n', DIR)).toThrow(
+      /tenants\.a\.chainIds/
+    );
+    expect(() =>
+      parseTenantRegistry('tenants:\n  a:\n    chainIds: [1]\n    rpcUrl: x\n', DIR)
+    ).toThrow(/Unrecognized key/);
+  });
+});
+
+describe('resolveTenant', () => {
+  it('resolves the named overlay, policy set, and cache relative to the registry', () => {
+    const context = resolveTenant(registry, {
+      tenant: 'base',
+      chainId: 8453,
+      overlay: 'staging',
+      policy: 'strict',
+    });
+    expect(context).toEqual({
+      key: JSON.stringify(['base', 'staging', 'strict']),
+      tenant: 'base',
+      chainIds: [8453],
+      overlayFile: path.join(DIR, 'overlays/staging.json'),
+      policy: { unknowns: 'error', maxAgeHours: 24 },
+      metadataCacheDir: path.join(DIR, 'cache/base'),
+    });
+  });
+
+  it('allows all of the tenant chains when none is requested', () => {
+    const context = resolveTenant(registry, { tenant: 'sandbox' });
+    expect(context).toMatchObject({ chainIds: [11155111], overlayFile: null, policy: {} });
+    expect(context.metadataCacheDir).toBeNull();
+  });
+
+  it('refuses anything the registry does not list for the tenant', () => {
+    const refused = [
+      { tenant: 'other' },
+      { tenant: 'toString' },
+      { tenant: 'sandbox', chainId: 8453 },
+      { tenant: 'sandbox', overlay: 'staging' },
+      { tenant: 'base', policy: 'lenient' },
+    ];
+    for (const request of refused) {
+      expect(() => resolveTenant(registry, request)).toThrow(PolicyViolationError);
+    }
+  });
+});
+
+describe('tenantStalenessLimits', () => {
+  it('replaces the server limits the policy set names', () => {
+    const base = { maxAgeHours: 72, maxBlocksBehind: 21600 };
+    expect(tenantStalenessLimits({ maxAgeHours: 24 }, base)).toEqual({
+      maxAgeHours: 24,
+      maxBlocksBehind: 21600,
+    });
+  });
+});
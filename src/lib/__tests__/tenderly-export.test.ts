@@ -0,0 +1,79 @@
+import { describe, expect, it } from '@jest/globals';
+import {
+  parseTenderlyExport,
+  tenderlyToAccountAccesses,
+  tenderlyToPayload,
+} from '../tenderly-export';
+
+const SAFE = '0x9855054731540a48b28990b63dcf4f33d8ae46a1';
+const RECIPIENT = '0x1111111111111111111111111111111111111111';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const exported = {
+  simulation: {
+    network_id: '1',
+    from: RECIPIENT,
+    to: SAFE,
+    input: '0x6a761202',
+    state_objects: { [SAFE]: { storage: { '0x4': '0x1' } } },
+  },
+  transaction: {
+    transaction_info: {
+      state_diff: [
+        {
+          soltype: { name: 'nonce', type: 'uint256' },
+          original: '5',
+          dirty: '6',
+          raw: [{ address: SAFE, key: word(5), original: word(5), dirty: word(6) }],
+        },
+        { raw: [{ address: SAFE, key: word(4), original: word(1), dirty: word(2) }] },
+      ],
+      balance_diff: [{ address: RECIPIENT, original: '0', dirty: '1000', is_miner: false }],
+    },
+  },
+};
+
+describe('parseTenderlyExport', () => {
+  it('rejects exports without a simulation', () => {
+    expect(() => parseTenderlyExport({ transaction: {} })).toThrow(
+      /Invalid Tenderly simulation export: simulation/
+    );
+  });
+});
+
+describe('tenderlyToPayload', () => {
+  it('takes the call and storage overrides from the simulation', () => {
+    const payload = tenderlyToPayload(parseTenderlyExport(exported));
+    expect(payload.to).toBe('0x9855054731540A48b28990B63DcF4f33d8AE46A1');
+    expect(payload.data).toBe('0x6a761202');
+    expect(payload.stateOverrides).toEqual([
+      {
+        contractAddress: '0x9855054731540A48b28990B63DcF4f33d8AE46A1',
+        overrides: [{ key: word(4), value: word(1) }],
+      },
+    ]);
+  });
+
+  it('falls back to top-level state_overrides', () => {
+    const { state_objects: _, ...simulation } = exported.simulation;
+    const payload = tenderlyToPayload(
+      parseTenderlyExport({ ...exported, simulation, state_overrides: { [SAFE]: { storage: {} } } })
+    );
+    expect(payload.stateOverrides).toEqual([]);
+  });
+});
+
+describe('tenderlyToAccountAccesses', () => {
+  it('groups raw storage writes and balance changes by account', () => {
+    const accesses = tenderlyToAccountAccesses(parseTenderlyExport(exported));
+    expect(accesses.map(a => a.account)).toEqual([RECIPIENT, SAFE]);
+
+    const [recipient, safe] = accesses;
+    expect(recipient.oldBalance).toBe(BigInt(0));
+    expect(recipient.newBalance).toBe(BigInt(1000));
+    expect(safe.storageAccesses.map(s => [s.slot, s.previousValue, s.newValue])).toEqual([
+      [word(4), word(1), word(2)],
+      [word(5), word(5), word(6)],
+    ]);
+  });
+});
@@ -0,0 +1,76 @@
+import { describe, expect, it } from '@jest/globals';
+import { PolicyViolationError } from '../errors';
+import { summarizeReport } from '../report-summary';
+import type { TaskConfig } from '../types';
+import {
+  applyUnknownMode,
+  findUnknownEntries,
+  UNKNOWN_CONTRACT_NAME,
+  UNKNOWN_OVERRIDE_MEANING,
+  UNKNOWN_SLOT_SUMMARY,
+} from '../unknown-entries';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const OTHER = '0x73a79Fab69143498Ed3712e519A88a918e1f4072';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const report = {
+  stateOverrides: [
+    {
+      name: 'Safe',
+      address: SAFE,
+      overrides: [{ key: word(9), value: word(1), description: UNKNOWN_OVERRIDE_MEANING }],
+    },
+  ],
+  stateChanges: [
+    {
+      name: UNKNOWN_CONTRACT_NAME,
+      address: OTHER,
+      changes: [
+        {
+          key: word(1),
+          before: word(0),
+          after: word(1),
+          description: UNKNOWN_SLOT_SUMMARY,
+          allowDifference: false,
+        },
+      ],
+    },
+  ],
+  balanceChanges: [],
+};
+
+const config = {
+  cmd: 'forge script Upgrade',
+  ledgerId: 0,
+  rpcUrl: 'https://rpc.example',
+  expectedDomainAndMessageHashes: { address: SAFE, domainHash: word(1), messageHash: word(2) },
+  ...report,
+  summary: summarizeReport(report),
+} as TaskConfig;
+
+describe('applyUnknownMode', () => {
+  it('leaves placeholders in place by default', () => {
+    expect(applyUnknownMode(config, 'placeholder')).toBe(config);
+  });
+
+  it('labels unknown contracts with their address and still counts them', () => {
+    const labeled = applyUnknownMode(config, 'label');
+
+    expect(labeled.stateChanges[0].name).toBe(`unknown (${OTHER})`);
+    expect(labeled.stateChanges[0].changes[0].description).toBe('unknown');
+    expect(labeled.stateOverrides[0].overrides[0].description).toBe('unknown');
+    expect(labeled.summary!.contracts.map(c => c.name)).toEqual([`unknown (${OTHER})`, 'Safe']);
+    expect(summarizeReport(labeled)).toMatchObject({ unknownContracts: 1, unknownSlots: 1 });
+    expect(findUnknownEntries(labeled)).toEqual(findUnknownEntries(config));
+  });
+
+  it('refuses a report with unknowns in error mode', () => {
+    expect(() => applyUnknownMode(config, 'error')).toThrow(PolicyViolationError);
+    expect(findUnknownEntries(config)).toEqual([
+      `contract ${OTHER}`,
+      `slot ${SAFE} ${word(9)}`,
+      `slot ${OTHER} ${word(1)}`,
+    ]);
+  });
+});
@@ -0,0 +1,58 @@
+import { describe, expect, it } from '@jest/globals';
+import { describeExecutionCheck } from '../execution-check';
+import { reportWarning } from '../report-warnings';
+import type { ValidationData } from '../types';
+import { buildValidationItems, hasBlockingErrors } from '../validation-results-utils';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const word = (n: number) => '0x' + n.toString(16).padStart(64, '0');
+
+const side = (explorerUrl: string) => ({
+  stateOverrides: [],
+  stateChanges: [
+    {
+      name: 'Safe',
+      address: SAFE,
+      explorerUrl,
+      changes: [
+        {
+          key: word(5),
+          before: word(1),
+          after: word(2),
+          description: 'Nonce',
+          allowDifference: false,
+        },
+      ],
+    },
+  ],
+});
+
+describe('buildValidationItems', () => {
+  it('links contracts to the configured explorer, never to the URL in the file', () => {
+    const result: ValidationData = {
+      expected: side('https://phishing.example/address'),
+      actual: side('https://phishing.example/address'),
+      chainId: 1,
+    };
+
+    const [change] = buildValidationItems(result).changes;
+    expect(change.contractExplorerUrl).toBe(`https://etherscan.io/address/${SAFE}`);
+    expect(buildValidationItems({ ...result, chainId: undefined }).changes[0]).toMatchObject({
+      contractExplorerUrl: undefined,
+    });
+  });
+});
+
+describe('hasBlockingErrors', () => {
+  it('disables signing when the transaction reverts', () => {
+    const empty = { stateOverrides: [], stateChanges: [], balanceChanges: [] };
+    const items = buildValidationItems({ expected: empty, actual: empty });
+    const reverted = { status: 'reverted' as const, revertReason: 'GS013' };
+    const warning = reportWarning('EXECUTION_FAILED', describeExecutionCheck(reverted), {
+      status: reverted.status,
+    });
+
+    expect(hasBlockingErrors(items, [])).toBe(false);
+    expect(hasBlockingErrors(items, [warning])).toBe(true);
+  });
+});
@@ -0,0 +1,29 @@
+import { describe, expect, it } from '@jest/globals';
+import { resolveValueAliases, valueAlias } from '../value-aliases';
+
+const IMPL = '0x6A5F8F5a4E6d7E3a1a0F2D1c5e8b7A4c3D2e1F00';
+
+describe('resolveValueAliases', () => {
+  it('matches words that hold the value, whatever their case and padding', () => {
+    const aliases = resolveValueAliases({
+      SENTINEL_OWNERS: '0x0000000000000000000000000000000000000001',
+      PORTAL_IMPL: IMPL,
+    });
+    expect(valueAlias(aliases, `0x${'0'.repeat(63)}1`)).toBe('SENTINEL_OWNERS');
+    expect(valueAlias(aliases, `0x${'0'.repeat(24)}${IMPL.slice(2).toLowerCase()}`)).toBe(
+      'PORTAL_IMPL'
+    );
+    expect(valueAlias(aliases, `0x${'0'.repeat(63)}2`)).toBeUndefined();
+    expect(valueAlias(aliases, 'not hex')).toBeUndefined();
+  });
+
+  it('refuses bad names, bad values, and two names for one value', () => {
+    expect(() => resolveValueAliases({ sentinel: '0x1' })).toThrow(/upper snake case/);
+    expect(() => resolveValueAliases({ TOO_LONG: `0x${'1'.repeat(65)}` })).toThrow(
+      /up to 32 bytes/
+    );
+    expect(() => resolveValueAliases({ ONE: '0x1', SENTINEL_OWNERS: '0x01' })).toThrow(
+      'constants: ONE and SENTINEL_OWNERS name the same value'
+    );
+  });
+});
@@ -0,0 +1,50 @@
+import { describe, expect, it } from '@jest/globals';
+import { canonicalHash } from '../canonical-json';
+import {
+  buildViewerLink,
+  decodeViewerLink,
+  MAX_VIEWER_PAYLOAD,
+  parseCid,
+  parseViewerUrl,
+} from '../viewer-link';
+
+const VIEWER = 'https://viewer.example/validate';
+const CID = 'bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku';
+const file = { taskName: 'upgrade', stateChanges: [{ name: 'Proxy', changes: [] }] };
+
+describe('buildViewerLink', () => {
+  it('carries the file in the fragment and reads it back', () => {
+    const link = buildViewerLink(VIEWER, file);
+    expect(link.startsWith(`${VIEWER}#v=1&hash=${canonicalHash(file)}&data=`)).toBe(true);
+    expect(decodeViewerLink(link)).toEqual({
+      hash: canonicalHash(file),
+      kind: 'data',
+      value: file,
+    });
+  });
+
+  it('refers to a pinned file by CID', () => {
+    const link = buildViewerLink(VIEWER, file, CID);
+    expect(decodeViewerLink(link)).toEqual({ hash: canonicalHash(file), kind: 'cid', cid: CID });
+  });
+
+  it('refuses a file too large for a link, and a link edited in transit', () => {
+    const large = { blob: Array.from({ length: 40000 }, (_, i) => i.toString(36)) };
+    expect(() => buildViewerLink(VIEWER, large)).toThrow(/pass its CID with --viewer-cid/);
+    expect(MAX_VIEWER_PAYLOAD).toBeGreaterThan(0);
+
+    const other = buildViewerLink(VIEWER, { ...file, taskName: 'drain' });
+    const forged = `${VIEWER}#v=1&hash=${canonicalHash(file)}&${other.split('&')[2]}`;
+    expect(() => decodeViewerLink(forged)).toThrow('does not match its content hash');
+  });
+});
+
+describe('parseViewerUrl', () => {
+  it('takes http(s) URLs without a fragment and CIDs', () => {
+    expect(parseViewerUrl('https://viewer.example')).toBe('https://viewer.example/');
+    expect(() => parseViewerUrl('javascript:alert(1)')).toThrow('must be an http(s) URL');
+    expect(() => parseViewerUrl('https://viewer.example/#x')).toThrow('must not have a fragment');
+    expect(parseCid(CID)).toBe(CID);
+    expect(() => parseCid('QmNotACid')).toThrow('--viewer-cid is not an IPFS CID');
+  });
+});
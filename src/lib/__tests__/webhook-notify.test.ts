@@ -0,0 +1,83 @@
+import { describe, expect, it, jest } from '@jest/globals';
+import {
+  formatNotification,
+  NotifySummary,
+  parseWebhookUrl,
+  postNotification,
+  webhookKind,
+} from '../webhook-notify';
+
+const SAFE = '0x9855054731540A48b28990B63DcF4f33d8AE46A1';
+const DOMAIN = `0x${'11'.repeat(32)}`;
+const MESSAGE = `0x${'22'.repeat(32)}`;
+
+const summary: NotifySummary = {
+  event: 'generated',
+  task: '2026-10-01-upgrade-fee-vault',
+  config: {
+    chainId: 8453,
+    chainName: 'Base',
+    expectedDomainAndMessageHashes: { address: SAFE, domainHash: DOMAIN, messageHash: MESSAGE },
+    stateOverrides: [],
+    stateChanges: [
+      {
+        name: 'Fee Vault',
+        address: SAFE,
+        changes: [
+          { key: DOMAIN, before: DOMAIN, after: MESSAGE, description: 'x', allowDifference: false },
+        ],
+      },
+    ],
+    warnings: [{ code: 'UNKNOWN_SLOTS', severity: 'warning', message: '1 unknown slot' }],
+  },
+  artifact: 'validations/base-sc.json',
+};
+
+describe('formatNotification', () => {
+  it('lists the task, chain, hashes, and counts', () => {
+    expect(formatNotification(summary, 'slack')).toBe(
+      [
+        '✅ Validation file generated: *2026-10-01-upgrade-fee-vault*',
+        `Chain: Base (8453) · Safe \`${SAFE}\``,
+        `Domain hash: \`${DOMAIN}\``,
+        `Message hash: \`${MESSAGE}\``,
+        'Changes: 1 contract(s), 1 slot(s), 0 override(s), 0 balance change(s), 1 warning(s)',
+        'Artifact: validations/base-sc.json',
+      ].join('\n')
+    );
+    expect(formatNotification(summary, 'discord')).toContain('**2026-10-01-upgrade-fee-vault**');
+  });
+});
+
+describe('postNotification', () => {
+  it('sends content to Discord and text to everything else', async () => {
+    const fetchFn = jest.fn(async () => new Response(null, { status: 204 })) as never;
+    await postNotification('https://discord.com/api/webhooks/1/secret', summary, fetchFn);
+    await postNotification('https://hooks.slack.com/services/T/B/secret', summary, fetchFn);
+
+    const { calls } = (fetchFn as jest.Mock).mock;
+    const bodies = calls.map(
+      call => JSON.parse((call as unknown as [string, RequestInit])[1].body as string) as object
+    );
+    expect(Object.keys(bodies[0])).toEqual(['content']);
+    expect(Object.keys(bodies[1])).toEqual(['text']);
+  });
+
+  it('names only the host when the webhook refuses the message', async () => {
+    const fetchFn = jest.fn(async () => new Response(null, { status: 404 }));
+    await expect(
+      postNotification('https://hooks.slack.com/services/T/B/secret', summary, fetchFn as never)
+    ).rejects.toThrow('The webhook at hooks.slack.com refused the message with status 404');
+  });
+});
+
+describe('parseWebhookUrl', () => {
+  it('reads the URL from the environment and refuses other schemes', () => {
+    const env = { SLACK_WEBHOOK_URL: 'https://hooks.slack.com/services/T/B/secret' };
+    expect(parseWebhookUrl('${SLACK_WEBHOOK_URL}', env)).toBe(env.SLACK_WEBHOOK_URL);
+    expect(() => parseWebhookUrl('${MISSING}', env)).toThrow('MISSING is not set');
+    expect(() => parseWebhookUrl('file:///etc/passwd', env)).toThrow(/must be an http\(s\) URL/);
+    expect(webhookKind('https://discordapp.com/api/webhooks/1/x')).toBe('discord');
+    expect(webhookKind('https://chat.example/hooks/x')).toBe('slack');
+  });
+});
@@ -0,0 +1,131 @@
+import type { Hex } from 'viem';
+import { AccountAccessKind, VmSafeAccountAccess } from './vm-safe';
+
+// One write to a slot: the value written, the account whose code wrote it (the implementation,
+// under a DELEGATECALL), the call depth, and whether the write was reverted
+export type SlotWrite = { value: Hex; writer: string; depth: number; reverted: boolean };
+// `intermediate` holds the values committed between `before` and `after`, in write order, and
+// `writes` every write to the slot, reverted ones included
+export type SlotDiff = {
+  key: Hex;
+  before: Hex;
+  after: Hex;
+  intermediate: Hex[];
+  writes: SlotWrite[];
+};
+export type StorageDiff = { address: string; storageDiffs: Map<string, SlotDiff> };
+export type ValueChange = { before: bigint; after: bigint };
+export type AccountChange = { address: string; balance: ValueChange; nonce: ValueChange };
+
+export type AggregatedAccesses = {
+  // Keyed by the lowercased account that owns the storage, which is not always the access's account
+  storage: Map<string, StorageDiff>;
+  // Accounts whose balance or nonce ended up different from where it started
+  accounts: Map<string, AccountChange>;
+  // Lowercased accounts that did not exist when the run first touched them, so their storage,
+  // balance, and nonce started at zero
+  newAccounts: Set<string>;
+};
+
+function word(hex: string): Hex {
+  const body = (hex || '').toLowerCase().replace(/^0x/, '');
+  return ('0x' + body.padStart(64, '0')) as Hex;
+}
+
+/**
+ * Folds a VmSafe account access trace into net per-account changes.
+ *
+ * Storage writes are attributed to the account recorded on each storage access, since a
+ * DELEGATECALL writes to the caller's storage while the access itself names the callee.
+ * A slot's before value is the previous value of its first write and its after value is the
+ * new value of its last non-reverted write; reverted writes never reach the final state.
+ * Every write, reverted or not, is also kept in order as the slot's write history.
+ * Balances and nonces are attributed to the access's own account: the first access seen for
+ * an account supplies the starting value and the latest one supplies the final value.
+ * DELEGATECALL accesses are skipped for balances, as the balances they carry belong to the
+ * calling contract rather than the account they name.
+ * An account is new when the first access naming it was not `initialized`.
+ */
+export function aggregateAccountAccesses(
+  decoded: readonly VmSafeAccountAccess[]
+): AggregatedAccesses {
+  const storage = new Map<string, StorageDiff>();
+  const accounts = new Map<string, AccountChange>();
+  const touched = new Set<string>();
+  const newAccounts = new Set<string>();
+  // Non-reverted writes seen per slot
+  const committed = new Map<SlotDiff, number>();
+
+  for (const access of decoded) {
+    const account = access.account.toLowerCase();
+    if (!touched.has(account)) {
+      touched.add(account);
+      if (!access.initialized) newAccounts.add(account);
+    }
+    for (const s of access.storageAccesses) {
+      if (!s.isWrite) continue;
+      const addr = s.account.toLowerCase();
+      let acct = storage.get(addr);
+      if (!acct) {
+        acct = { address: addr, storageDiffs: new Map() };
+        storage.set(addr, acct);
+      }
+      const slot = word(s.slot);
+      let diff = acct.storageDiffs.get(slot);
+      if (!diff) {
+        const before = word(s.previousValue);
+        diff = { key: slot, before, after: before, intermediate: [], writes: [] };
+        acct.storageDiffs.set(slot, diff);
+        committed.set(diff, 0);
+      }
+      const reverted = s.reverted || access.reverted;
+      diff.writes.push({
+        value: word(s.newValue),
+        writer: account,
+        depth: Number(access.depth),
+        reverted,
+      });
+      if (reverted) continue;
+      const writes = committed.get(diff)! + 1;
+      committed.set(diff, writes);
+      if (writes > 1) diff.intermediate.push(diff.after);
+      diff.after = word(s.newValue);
+    }
+
+    if (access.kind === AccountAccessKind.DelegateCall) continue;
+    const addr = access.account.toLowerCase();
+    const seen = accounts.get(addr);
+    // Like storage writes, a reverted access's balance and nonce were rolled back, so it only
+    // contributes the values the account had before it
+    if (!seen) {
+      accounts.set(addr, {
+        address: addr,
+        balance: {
+          before: access.oldBalance,
+          after: access.reverted ? access.oldBalance : access.newBalance,
+        },
+        nonce: {
+          before: access.oldNonce,
+          after: access.reverted ? access.oldNonce : access.newNonce,
+        },
+      });
+    } else if (!access.reverted) {
+      seen.balance.after = access.newBalance;
+      seen.nonce.after = access.newNonce;
+    }
+  }
+
+  for (const [addr, acct] of storage) {
+    for (const [slot, diff] of acct.storageDiffs) {
+      if (diff.before === diff.after) acct.storageDiffs.delete(slot);
+    }
+    if (acct.storageDiffs.size === 0) storage.delete(addr);
+  }
+  for (const [addr, acct] of accounts) {
+    if (acct.balance.before === acct.balance.after && acct.nonce.before === acct.nonce.after) {
+      accounts.delete(addr);
+    }
+  }
+
+  return { storage, accounts, newAccounts };
+}
@@ -0,0 +1,73 @@
+import { Address, getAddress, PublicClient } from 'viem';
+import { reportProgress } from './progress';
+
+// Precompiles occupy the low address range (0x01..0xff covers current and near-future forks).
+const PRECOMPILE_MAX = BigInt(0xff);
+// OP Stack predeploys live at 0x4200000000000000000000000000000000000000 + n.
+const PREDEPLOY_BASE = BigInt('0x4200000000000000000000000000000000000000');
+const PREDEPLOY_MAX = BigInt('0x42000000000000000000000000000000000007ff');
+
+const SYSTEM_ADDRESSES: Record<string, string> = {
+  '0xfffffffffffffffffffffffffffffffffffffffe': 'EIP-4788/EIP-2935 system caller',
+  '0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001': 'OP Stack L1 attributes depositor',
+  '0x000f3df6d732807ef1319fb7b8bb8522d0beac02': 'EIP-4788 beacon roots contract',
+};
+
+export type AddressClassification =
+  | { kind: 'precompile' }
+  | { kind: 'predeploy' }
+  | { kind: 'system'; label: string }
+  | { kind: 'regular' };
+
+export function classifyAddress(address: string): AddressClassification {
+  const lower = address.toLowerCase();
+  const label = SYSTEM_ADDRESSES[lower];
+  if (label) return { kind: 'system', label };
+
+  const value = BigInt(lower);
+  if (value > BigInt(0) && value <= PRECOMPILE_MAX) return { kind: 'precompile' };
+  if (value >= PREDEPLOY_BASE && value <= PREDEPLOY_MAX) return { kind: 'predeploy' };
+  return { kind: 'regular' };
+}
+
+/**
+ * Flags storage writes that land on reserved address ranges or on accounts without code.
+ * Such writes are almost always a simulation bug or dangerous behavior. Accounts created
+ * during the simulation are skipped for the code check since they have no code on-chain yet.
+ */
+export async function findSuspiciousWrites(
+  client: Pick<PublicClient, 'getCode'>,
+  writtenAccounts: string[],
+  createdAccounts: Set<string>
+): Promise<string[]> {
+  const warnings: string[] = [];
+
+  for (const [i, account] of writtenAccounts.entries()) {
+    reportProgress(i, writtenAccounts.length);
+    const address = getAddress(account) as Address;
+    const classification = classifyAddress(address);
+
+    switch (classification.kind) {
+      case 'precompile':
+        warnings.push(`Storage write to precompile address ${address}`);
+        continue;
+      case 'system':
+        warnings.push(`Storage write to system address ${address} (${classification.label})`);
+        continue;
+      case 'predeploy':
+        warnings.push(`Storage write to reserved predeploy range address ${address}`);
+        continue;
+      case 'regular':
+        break;
+    }
+
+    if (createdAccounts.has(account.toLowerCase())) continue;
+
+    const code = await client.getCode({ address });
+    if (!code || code === '0x') {
+      warnings.push(`Storage write to account without code (EOA) ${address}`);
+    }
+  }
+
+  return warnings;
+}
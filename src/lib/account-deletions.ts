@@ -0,0 +1,56 @@
+import { Hex, zeroAddress } from 'viem';
+import { AccountAccessKind, VmSafeAccountAccess } from './vm-safe';
+
+export type DeletedAccount = {
+  // Lowercased address of the contract that self-destructed
+  address: string;
+  // Where its balance went; null when the source does not say (prestateTracer diffs)
+  beneficiary: string | null;
+  sweptBalance: bigint;
+  // Under EIP-6780 only contracts created in the same transaction lose their code and storage
+  codeRemoved: boolean;
+  // Code known to be removed: deployed earlier in the same transaction, or reported by the trace
+  removedCode: Hex | null;
+};
+
+/**
+ * Finds contracts that self-destructed in a VmSafe access trace. For SELFDESTRUCT accesses
+ * forge records the destroyed contract as the accessor and the beneficiary as the account.
+ * Synthetic accesses built from other traces may carry the removed code in `deployedCode`.
+ * Reverted self-destructs are ignored, and repeated ones for the same contract are merged.
+ */
+export function findAccountDeletions(decoded: readonly VmSafeAccountAccess[]): DeletedAccount[] {
+  const created = new Map<string, Hex>();
+  for (const access of decoded) {
+    if (access.kind === AccountAccessKind.Create && !access.reverted) {
+      created.set(access.account.toLowerCase(), access.deployedCode);
+    }
+  }
+
+  const deletions = new Map<string, DeletedAccount>();
+  for (const access of decoded) {
+    if (access.kind !== AccountAccessKind.SelfDestruct || access.reverted) continue;
+    const address = access.accessor.toLowerCase();
+    const beneficiary =
+      access.account.toLowerCase() === zeroAddress ? null : access.account.toLowerCase();
+    const previous = deletions.get(address);
+    const removedCode =
+      access.deployedCode !== '0x' ? access.deployedCode : (created.get(address) ?? null);
+    deletions.set(address, {
+      address,
+      beneficiary: beneficiary ?? previous?.beneficiary ?? null,
+      sweptBalance: (previous?.sweptBalance ?? BigInt(0)) + access.value,
+      codeRemoved: created.has(address) || removedCode !== null || !!previous?.codeRemoved,
+      removedCode: removedCode ?? previous?.removedCode ?? null,
+    });
+  }
+  return Array.from(deletions.values()).sort((a, b) => a.address.localeCompare(b.address));
+}
+
+export function describeAccountDeletion(d: DeletedAccount): string {
+  const destination = d.beneficiary ?? 'an unknown beneficiary';
+  const effect = d.codeRemoved
+    ? 'its code and storage are removed'
+    : 'its code and storage remain (EIP-6780)';
+  return `${d.address} self-destructs: ${d.sweptBalance} wei goes to ${destination} and ${effect}`;
+}
@@ -0,0 +1,189 @@
+import { promises as fs } from 'fs';
+import path from 'path';
+import { z } from 'zod';
+import { canonicalHash } from './canonical-json';
+
+/**
+ * Large validation files as an index and one part per contract (genValidationFile.ts
+ * --max-output-size). Review tools and GitHub stop rendering diffs of very large files, so a
+ * task touching hundreds of contracts is easier to review as many small files. The index keeps
+ * everything but the per-contract entries and pins each part by its canonical hash, so hashing
+ * or attesting the index covers the parts. The index leaves out `stateChanges` and
+ * `stateOverrides`, so a reader that does not join the parts refuses it instead of showing an
+ * empty report.
+ */
+
+export const SPLIT_VERSION = 1;
+
+// The arrays split by contract, in the order they are restored
+const SPLIT_FIELDS = ['stateOverrides', 'stateChanges', 'balanceChanges'] as const;
+
+type SplitField = (typeof SPLIT_FIELDS)[number];
+
+const PART_FILE = /^0x[0-9a-f]{40}\.json$/;
+
+const SplitIndexSchema = z.object({
+  version: z.literal(SPLIT_VERSION),
+  // Directory of the parts, next to the index
+  dir: z.string().regex(/^[^/\\]+\.parts$/),
+  // Entries in each split array, so a missing part is noticed
+  counts: z.object({
+    stateOverrides: z.number().int().nonnegative(),
+    stateChanges: z.number().int().nonnegative(),
+    balanceChanges: z.number().int().nonnegative().optional(),
+  }),
+  parts: z.array(
+    z.object({
+      file: z.string().regex(PART_FILE),
+      hash: z.string().regex(/^0x[0-9a-f]{64}$/),
+    })
+  ),
+});
+
+export type SplitIndex = z.infer<typeof SplitIndexSchema>;
+
+type PositionedEntry = { position: number; entry: { address: string } };
+
+const SplitPartSchema = z.object({
+  address: z.string(),
+  stateOverrides: z.array(z.object({ position: z.number().int(), entry: z.any() })),
+  stateChanges: z.array(z.object({ position: z.number().int(), entry: z.any() })),
+  balanceChanges: z.array(z.object({ position: z.number().int(), entry: z.any() })),
+});
+
+export type SplitPart = {
+  address: string;
+} & Record<SplitField, PositionedEntry[]>;
+
+export type SplitValidationFile = {
+  index: Record<string, unknown> & { split: SplitIndex };
+  parts: { file: string; content: SplitPart }[];
+};
+
+export function splitPartsDir(outPath: string): string {
+  return `${path.basename(outPath, path.extname(outPath))}.parts`;
+}
+
+/**
+ * Splits a validation file into the index written at `outPath` and one part per contract,
+ * written under splitPartsDir(outPath). Each entry keeps its position so joining restores the
+ * original order.
+ */
+export function splitValidationFile(file: object, outPath: string): SplitValidationFile {
+  const fields = file as Record<string, unknown>;
+  const byAddress = new Map<string, SplitPart>();
+  const counts: Partial<Record<SplitField, number>> = {};
+  for (const field of SPLIT_FIELDS) {
+    const entries = fields[field] as { address: string }[] | undefined;
+    if (!entries) continue;
+    counts[field] = entries.length;
+    entries.forEach((entry, position) => {
+      const address = entry.address.toLowerCase();
+      let part = byAddress.get(address);
+      if (!part) {
+        part = { address, stateOverrides: [], stateChanges: [], balanceChanges: [] };
+        byAddress.set(address, part);
+      }
+      part[field].push({ position, entry });
+    });
+  }
+
+  const parts = [...byAddress.values()]
+    .sort((a, b) => a.address.localeCompare(b.address))
+    .map(content => ({ file: `${content.address}.json`, content }));
+  const rest = Object.fromEntries(
+    Object.entries(fields).filter(([key]) => !(SPLIT_FIELDS as readonly string[]).includes(key))
+  );
+  return {
+    index: {
+      ...rest,
+      split: {
+        version: SPLIT_VERSION,
+        dir: splitPartsDir(outPath),
+        counts: counts as SplitIndex['counts'],
+        parts: parts.map(p => ({ file: p.file, hash: canonicalHash(p.content) })),
+      },
+    },
+    parts,
+  };
+}
+
+export function isSplitIndex(value: unknown): value is { split: unknown } {
+  return value !== null && typeof value === 'object' && 'split' in value;
+}
+
+function parseSplitIndex(split: unknown): SplitIndex {
+  const parsed = SplitIndexSchema.safeParse(split);
+  if (!parsed.success) {
+    const issue = parsed.error.issues[0];
+    throw new Error(
+      `Invalid split index: split.${issue.path.join('.') || '<root>'}: ${issue.message}`
+    );
+  }
+  return parsed.data;
+}
+
+/**
+ * Restores the validation file from its index and parts. Every part must match the hash the
+ * index pins, and together they must fill each array exactly.
+ */
+export function joinValidationFile(
+  index: Record<string, unknown>,
+  readPart: (file: string) => string
+): Record<string, unknown> {
+  const split = parseSplitIndex(index.split);
+  const arrays: Partial<Record<SplitField, unknown[]>> = {};
+  for (const field of SPLIT_FIELDS) {
+    const count = split.counts[field];
+    if (count !== undefined) arrays[field] = new Array(count);
+  }
+  for (const { file, hash } of split.parts) {
+    const content: unknown = JSON.parse(readPart(file));
+    if (canonicalHash(content) !== hash) {
+      throw new Error(`Split part ${file} does not match the hash in the index`);
+    }
+    const part = SplitPartSchema.parse(content);
+    for (const field of SPLIT_FIELDS) {
+      for (const { position, entry } of part[field]) {
+        const array = arrays[field];
+        if (!array || position < 0 || position >= array.length || position in array) {
+          throw new Error(`Split part ${file} has an unexpected ${field} entry ${position}`);
+        }
+        array[position] = entry;
+      }
+    }
+  }
+  for (const [field, array] of Object.entries(arrays)) {
+    if (array.filter(() => true).length !== array.length) {
+      throw new Error(`Split parts are missing ${field} entries`);
+    }
+  }
+
+  const rest = Object.fromEntries(Object.entries(index).filter(([key]) => key !== 'split'));
+  return { ...rest, ...arrays };
+}
+
+/**
+ * Reads a validation file, joining it first when it is a split index. Text that is not JSON is
+ * returned as it is, for the parser to report.
+ */
+export async function readValidationFile(file: string): Promise<string> {
+  const text = await fs.readFile(file, 'utf-8');
+  let value: unknown;
+  try {
+    value = JSON.parse(text);
+  } catch {
+    return text;
+  }
+  if (!isSplitIndex(value)) return text;
+
+  const index = value as Record<string, unknown>;
+  // The index's names are checked before any part is read, so it cannot point outside its dir
+  const split = parseSplitIndex(index.split);
+  const dir = path.join(path.dirname(file), split.dir);
+  const parts = new Map<string, string>();
+  for (const { file: part } of split.parts) {
+    parts.set(part, await fs.readFile(path.join(dir, part), 'utf-8'));
+  }
+  return JSON.stringify(joinValidationFile(index, part => parts.get(part)!));
+}
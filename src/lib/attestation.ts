@@ -0,0 +1,138 @@
+import {
+  Address,
+  concatHex,
+  encodeAbiParameters,
+  getAddress,
+  Hex,
+  isAddressEqual,
+  keccak256,
+  recoverAddress,
+  toBytes,
+} from 'viem';
+import { privateKeyToAccount } from 'viem/accounts';
+import { canonicalJson } from './canonical-json';
+import { signDomainAndMessageHash } from './ledger-signing';
+
+export type Attestation = {
+  signer: Address;
+  signature: Hex;
+};
+
+export type AttestationSigner =
+  | { kind: 'ledger'; ledgerAccount?: number }
+  | { kind: 'privateKey'; privateKey: Hex };
+
+const DOMAIN_TYPEHASH = keccak256(toBytes('EIP712Domain(string name,string version)'));
+const ATTESTATION_TYPEHASH = keccak256(toBytes('ValidationFileAttestation(bytes32 contentHash)'));
+const DOMAIN_NAME = 'task-signing-tool';
+const DOMAIN_VERSION = '1';
+
+const BYTES32 = { type: 'bytes32' } as const;
+
+export function eip712Digest(domainHash: Hex, messageHash: Hex): Hex {
+  return keccak256(concatHex(['0x1901', domainHash, messageHash]));
+}
+
+/**
+ * Canonical JSON of a validation file. The top-level `attestation` field is dropped so the
+ * signature covers everything else in the file.
+ */
+export function canonicalizeForAttestation(value: unknown): string {
+  const { attestation: _attestation, ...rest } = value as Record<string, unknown>;
+  return canonicalJson(rest);
+}
+
+/**
+ * EIP-712 hashes for a `<Type>(bytes32 <field>)` message under this tool's domain. Using
+ * EIP-712 lets a Ledger sign through the same eip712sign flow as Safe transactions.
+ */
+export function toolTypedDataHashes(
+  typeHash: Hex,
+  contentHash: Hex
+): { domainHash: Hex; messageHash: Hex } {
+  const domainHash = keccak256(
+    encodeAbiParameters([BYTES32, BYTES32, BYTES32], [
+      DOMAIN_TYPEHASH,
+      keccak256(toBytes(DOMAIN_NAME)),
+      keccak256(toBytes(DOMAIN_VERSION)),
+    ])
+  );
+  const messageHash = keccak256(encodeAbiParameters([BYTES32, BYTES32], [typeHash, contentHash]));
+  return { domainHash, messageHash };
+}
+
+/**
+ * EIP-712 hashes for an attestation over a validation file.
+ */
+export function attestationHashes(value: unknown): { domainHash: Hex; messageHash: Hex } {
+  return toolTypedDataHashes(
+    ATTESTATION_TYPEHASH,
+    keccak256(toBytes(canonicalizeForAttestation(value)))
+  );
+}
+
+export async function signAttestation(
+  value: unknown,
+  signer: AttestationSigner
+): Promise<Attestation> {
+  const { domainHash, messageHash } = attestationHashes(value);
+
+  if (signer.kind === 'privateKey') {
+    const account = privateKeyToAccount(signer.privateKey);
+    const signature = await account.sign({ hash: eip712Digest(domainHash, messageHash) });
+    return { signer: account.address, signature };
+  }
+
+  const result = await signDomainAndMessageHash({
+    domainHash,
+    messageHash,
+    ledgerAccount: signer.ledgerAccount,
+  });
+  if (!result.success || !result.signer || !result.signature) {
+    throw new Error(`Attestation signing failed: ${result.error ?? 'no signature returned'}`);
+  }
+  const signature = (
+    result.signature.startsWith('0x') ? result.signature : `0x${result.signature}`
+  ) as Hex;
+  return { signer: getAddress(result.signer), signature };
+}
+
+/**
+ * Recovers the attestation signer and checks it matches both the embedded signer and, when
+ * given, the expected facilitator address.
+ */
+export async function verifyAttestation(
+  value: { attestation?: Attestation },
+  expectedSigner?: string
+): Promise<{ valid: boolean; signer?: Address; error?: string }> {
+  const { attestation } = value;
+  if (!attestation) return { valid: false, error: 'File has no attestation' };
+
+  const { domainHash, messageHash } = attestationHashes(value);
+  let recovered: Address;
+  try {
+    recovered = await recoverAddress({
+      hash: eip712Digest(domainHash, messageHash),
+      signature: attestation.signature,
+    });
+  } catch (error) {
+    const message = error instanceof Error ? error.message : String(error);
+    return { valid: false, error: `Invalid signature: ${message}` };
+  }
+
+  if (!isAddressEqual(recovered, attestation.signer)) {
+    return {
+      valid: false,
+      signer: recovered,
+      error: `Signature was made by ${recovered}, not the embedded signer ${attestation.signer}`,
+    };
+  }
+  if (expectedSigner && !isAddressEqual(recovered, getAddress(expectedSigner))) {
+    return {
+      valid: false,
+      signer: recovered,
+      error: `Attested by ${recovered}, expected ${getAddress(expectedSigner)}`,
+    };
+  }
+  return { valid: true, signer: recovered };
+}
@@ -0,0 +1,70 @@
+import { existsSync, readdirSync, statSync } from 'fs';
+import path from 'path';
+
+export type BatchOutcome<T> = { item: T; ok: true } | { item: T; ok: false; error: string };
+
+/**
+ * Runs `fn` over `items` with at most `limit` calls in flight. A failing item does not stop
+ * the others; outcomes are returned in input order.
+ */
+export async function runBounded<T>(
+  items: readonly T[],
+  limit: number,
+  fn: (item: T) => Promise<void>
+): Promise<BatchOutcome<T>[]> {
+  const outcomes: BatchOutcome<T>[] = new Array(items.length);
+  let next = 0;
+  const worker = async () => {
+    while (next < items.length) {
+      const index = next++;
+      const item = items[index];
+      try {
+        await fn(item);
+        outcomes[index] = { item, ok: true };
+      } catch (error) {
+        const message = error instanceof Error ? error.message : String(error);
+        outcomes[index] = { item, ok: false, error: message };
+      }
+    }
+  };
+  const workers = Math.max(1, Math.min(limit, items.length));
+  await Promise.all(Array.from({ length: workers }, worker));
+  return outcomes;
+}
+
+/**
+ * Lists a task's validation files, `<task>/config/<network>/validations/*.json`, sorted by path.
+ */
+export function findTaskValidationFiles(taskDir: string): string[] {
+  const configDir = path.join(taskDir, 'config');
+  if (!existsSync(configDir)) return [];
+  const files: string[] = [];
+  for (const network of readdirSync(configDir).sort()) {
+    const validationsDir = path.join(configDir, network, 'validations');
+    if (!existsSync(validationsDir) || !statSync(validationsDir).isDirectory()) continue;
+    for (const name of readdirSync(validationsDir).sort()) {
+      if (name.endsWith('.json')) files.push(path.join(validationsDir, name));
+    }
+  }
+  return files;
+}
+
+/**
+ * The forge workdir for a task is the directory holding `tasks/`, e.g. `active/evm`.
+ */
+export function taskWorkdir(taskDir: string): string {
+  const tasksDir = path.dirname(path.resolve(taskDir));
+  if (path.basename(tasksDir) !== 'tasks') {
+    throw new Error(`${taskDir} is not inside a tasks/ directory`);
+  }
+  return path.dirname(tasksDir);
+}
+
+export function formatBatchSummary(outcomes: readonly BatchOutcome<string>[]): string {
+  const failures = outcomes.filter(o => !o.ok);
+  const lines = [`${outcomes.length - failures.length} of ${outcomes.length} file(s) regenerated`];
+  for (const failure of failures) {
+    if (!failure.ok) lines.push(`  ❌ ${failure.item}: ${failure.error}`);
+  }
+  return lines.join('\n');
+}
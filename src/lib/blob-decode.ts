@@ -0,0 +1,64 @@
+import { decodeOverrides, decodePreimages, decodeStateDiff } from './state-diff-encoding';
+import { AccountAccessKind } from './vm-safe';
+
+/**
+ * The `decode` command of scripts/stateDiff.ts: one encoded blob from a forge run, given as
+ * hex or read from a stateDiff.json or a validation file written with --include-raw, decoded
+ * to JSON for debugging a forge script.
+ */
+
+// Field in stateDiff.json that holds each blob kind
+export const BLOB_KIND_FIELDS = {
+  overrides: 'overrides',
+  statediff: 'stateDiff',
+  preimages: 'preimages',
+} as const;
+
+export type BlobKind = keyof typeof BLOB_KIND_FIELDS;
+
+export function isBlobKind(kind: string | undefined): kind is BlobKind {
+  return kind !== undefined && Object.hasOwn(BLOB_KIND_FIELDS, kind);
+}
+
+/**
+ * The blob of `kind` in a file's content: the content itself when it is not JSON, otherwise the
+ * field for `kind` of a stateDiff.json, or of `raw` in a validation file. `file` names the file
+ * in errors.
+ */
+export function blobFromFile(kind: BlobKind, content: string, file: string): string {
+  const trimmed = content.trim();
+  if (!trimmed.startsWith('{')) return trimmed;
+
+  const json = JSON.parse(trimmed) as Record<string, unknown>;
+  // Validation files written with --include-raw keep the blobs under raw
+  const blobs =
+    json.raw && typeof json.raw === 'object' ? (json.raw as Record<string, unknown>) : json;
+  const value = blobs[BLOB_KIND_FIELDS[kind]];
+  if (typeof value !== 'string') {
+    throw new Error(`${file} has no string field "${BLOB_KIND_FIELDS[kind]}"`);
+  }
+  return value;
+}
+
+// Account access kinds are written by name next to their number
+export function decodeBlob(kind: BlobKind, encoded: string): unknown {
+  if (!/^0x[0-9a-fA-F]*$/.test(encoded)) {
+    throw new Error('Blob must be 0x-prefixed hex');
+  }
+  switch (kind) {
+    case 'overrides':
+      return decodeOverrides(encoded);
+    case 'statediff':
+      return decodeStateDiff(encoded).map(access => ({
+        ...access,
+        kind: `${AccountAccessKind[access.kind] ?? 'Unknown'} (${access.kind})`,
+      }));
+    case 'preimages':
+      return decodePreimages(encoded);
+  }
+}
+
+// The command's output; uint256 and uint64 fields decode to bigints, which are written as decimals
+export function formatDecodedBlob(decoded: unknown): string {
+  return JSON.stringify(decoded, (_, v) => (typeof v === 'bigint' ? v.toString() : v), 2);
+}
@@ -0,0 +1,26 @@
+import { BlockNotFoundError, createPublicClient, Transport } from 'viem';
+import type { SimulatedAt } from './types/index';
+
+/**
+ * Whether the block a validation file was simulated at is still part of the chain. Files record
+ * the hash of that block in `simulatedAt.blockHash`; if a reorg replaced it, the prestate the
+ * report was built from may never have existed on the canonical chain, and the file should be
+ * regenerated. Files written before the hash was recorded are not checked.
+ */
+export async function checkSimulatedBlock(
+  transport: Transport,
+  simulatedAt: SimulatedAt
+): Promise<string | null> {
+  if (!simulatedAt.blockHash) return null;
+  const client = createPublicClient({ transport });
+  const blockNumber = BigInt(simulatedAt.blockNumber);
+  let hash: string | null;
+  try {
+    ({ hash } = await client.getBlock({ blockNumber }));
+  } catch (err) {
+    if (!(err instanceof BlockNotFoundError)) throw err;
+    return `The RPC does not know the simulated block ${blockNumber}, so it cannot confirm the block is still canonical; the RPC may be behind, or the block was reorged out`;
+  }
+  if (hash?.toLowerCase() === simulatedAt.blockHash.toLowerCase()) return null;
+  return `The simulated block ${blockNumber} was reorged out: the file was built on ${simulatedAt.blockHash}, but the canonical block is now ${hash}. The prestate it was validated against may have changed; regenerate the file`;
+}
@@ -0,0 +1,84 @@
+import { execFileSync } from 'child_process';
+import { readFileSync } from 'fs';
+import path from 'path';
+import { canonicalHash } from './canonical-json';
+import contractsCfg from './config/contracts.json';
+import packageJson from '../../package.json';
+
+// Written by `npm run build-info` when a release is cut; absent in development checkouts.
+export const BUILD_INFO_FILE = path.join(process.cwd(), 'build-info.json');
+
+export type BuildInfo = {
+  tool: string;
+  version: string;
+  commit: string;
+  // keccak256 of the canonical JSON of the embedded contracts.json
+  configHash: string;
+  buildDate: string;
+};
+
+type StampedInfo = Pick<BuildInfo, 'commit' | 'buildDate'>;
+
+export function embeddedConfigHash(): string {
+  return canonicalHash(contractsCfg);
+}
+
+function gitCommit(): string {
+  try {
+    const commit = execFileSync('git', ['rev-parse', 'HEAD'], {
+      encoding: 'utf-8',
+      stdio: ['ignore', 'pipe', 'ignore'],
+    }).trim();
+    const dirty = execFileSync('git', ['status', '--porcelain'], {
+      encoding: 'utf-8',
+      stdio: ['ignore', 'pipe', 'ignore'],
+    }).trim();
+    return dirty ? `${commit}-dirty` : commit;
+  } catch {
+    return 'unknown';
+  }
+}
+
+function readStamp(): StampedInfo | null {
+  try {
+    return JSON.parse(readFileSync(BUILD_INFO_FILE, 'utf-8')) as StampedInfo;
+  } catch {
+    return null;
+  }
+}
+
+let cached: BuildInfo | null = null;
+
+/**
+ * Identifies the build that is running. Release builds read the commit and build date stamped
+ * into build-info.json; development checkouts ask git and report the build date as
+ * "development". The config hash is always computed from the config actually loaded.
+ */
+export function getBuildInfo(): BuildInfo {
+  if (cached) return cached;
+  const stamp = readStamp();
+  cached = {
+    tool: packageJson.name,
+    version: packageJson.version,
+    commit: stamp?.commit ?? gitCommit(),
+    configHash: embeddedConfigHash(),
+    buildDate: stamp?.buildDate ?? 'development',
+  };
+  return cached;
+}
+
+/**
+ * Stamps the current commit and time into build-info.json for a release build.
+ */
+export function stampBuildInfo(now: Date = new Date()): StampedInfo {
+  return { commit: gitCommit(), buildDate: now.toISOString() };
+}
+
+export function formatBuildInfo(info: BuildInfo): string {
+  return [
+    `${info.tool} ${info.version}`,
+    `commit:      ${info.commit}`,
+    `config hash: ${info.configHash}`,
+    `build date:  ${info.buildDate}`,
+  ].join('\n');
+}
@@ -0,0 +1,81 @@
+import { Hex, isHex, keccak256, toBytes } from 'viem';
+
+/**
+ * JSON Canonicalization Scheme (RFC 8785): object keys sorted by UTF-16 code units at every
+ * level, no whitespace, and strings and numbers written as ECMAScript's JSON.stringify writes
+ * them. Every hash and signature the tool makes over its own output is taken over this form, so
+ * it does not change when a file is re-indented, reformatted, or has its keys reordered.
+ */
+export function canonicalJson(value: unknown): string {
+  const text = serialize(value, '');
+  if (text === undefined) throw new Error('Cannot canonicalize a value with no JSON form');
+  return text;
+}
+
+function serialize(value: unknown, path: string): string | undefined {
+  if (value !== null && typeof value === 'object' && 'toJSON' in value) {
+    value = (value as { toJSON: () => unknown }).toJSON();
+  }
+  switch (typeof value) {
+    case 'string':
+    case 'boolean':
+      return JSON.stringify(value);
+    case 'number':
+      // RFC 8785 forbids NaN and Infinity rather than writing them as null
+      if (!Number.isFinite(value)) {
+        throw new Error(`Cannot canonicalize ${value} at ${path || '<root>'}`);
+      }
+      return JSON.stringify(value);
+    case 'bigint':
+      throw new Error(`Cannot canonicalize a bigint at ${path || '<root>'}`);
+    case 'undefined':
+    case 'function':
+    case 'symbol':
+      return undefined;
+  }
+  if (value === null) return 'null';
+  if (Array.isArray(value)) {
+    const items = value.map((item, i) => serialize(item, `${path}[${i}]`) ?? 'null');
+    return `[${items.join(',')}]`;
+  }
+  // Sorted here rather than by rebuilding the object: integer-like keys ("10", "8453") would
+  // otherwise be iterated in numeric order ahead of the rest
+  const record = value as Record<string, unknown>;
+  const members = Object.keys(record)
+    .sort()
+    .flatMap(key => {
+      const member = serialize(record[key], path ? `${path}.${key}` : key);
+      return member === undefined ? [] : [`${JSON.stringify(key)}:${member}`];
+    });
+  return `{${members.join(',')}}`;
+}
+
+export function canonicalHash(value: unknown): Hex {
+  return keccak256(toBytes(canonicalJson(value)));
+}
+
+export type CanonicalHashCheck =
+  | { valid: true; hash: Hex }
+  | { valid: false; hash: Hex | null; error: string };
+
+/**
+ * Checks a shared hash against a JSON file's text. The text is parsed and canonicalized first,
+ * so a copy that was re-indented or had its keys reordered still matches.
+ */
+export function verifyCanonicalHash(text: string, expected: string): CanonicalHashCheck {
+  if (!isHex(expected) || expected.length !== 66) {
+    return { valid: false, hash: null, error: `Expected a 32-byte hex hash, got ${expected}` };
+  }
+  let parsed: unknown;
+  try {
+    parsed = JSON.parse(text);
+  } catch (error) {
+    const message = error instanceof Error ? error.message : String(error);
+    return { valid: false, hash: null, error: `Not valid JSON: ${message}` };
+  }
+  const hash = canonicalHash(parsed);
+  if (hash !== expected.toLowerCase()) {
+    return { valid: false, hash, error: `Content hash is ${hash}, expected ${expected}` };
+  }
+  return { valid: true, hash };
+}
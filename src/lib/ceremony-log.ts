@@ -0,0 +1,156 @@
+import { promises as fs } from 'fs';
+import path from 'path';
+import { Address, Hex, isAddress, isAddressEqual, keccak256, recoverAddress, toBytes } from 'viem';
+import { eip712Digest, toolTypedDataHashes } from './attestation';
+import { canonicalHash } from './canonical-json';
+import { withKeyedLock } from './keyed-lock';
+
+// Directory for ceremony logs; logging is disabled when unset.
+export const CEREMONY_LOG_DIR_ENV = 'CEREMONY_LOG_DIR';
+
+export const CEREMONY_EVENTS = [
+  'session_started',
+  'results_viewed',
+  'acknowledged',
+  'signed',
+  'closed',
+  'log_signed',
+] as const;
+export type CeremonyEvent = (typeof CEREMONY_EVENTS)[number];
+
+export type CeremonyDetails = Record<string, string | number | boolean>;
+
+export type CeremonyEntry = {
+  seq: number;
+  timestamp: string;
+  sessionId: string;
+  event: CeremonyEvent;
+  // keccak256 of the canonical JSON of what the signer was shown
+  artifactHash?: Hex;
+  details?: CeremonyDetails;
+  // entryHash of the previous line, or zero for the first one
+  prevHash: Hex;
+  entryHash: Hex;
+};
+
+const ZERO_HASH = ('0x' + '0'.repeat(64)) as Hex;
+const CEREMONY_TYPEHASH = keccak256(toBytes('CeremonyLog(bytes32 headHash)'));
+const SESSION_ID_PATTERN = /^[A-Za-z0-9-]{8,64}$/;
+
+export function hashArtifact(value: unknown): Hex {
+  return canonicalHash(value);
+}
+
+function hashEntry(entry: Omit<CeremonyEntry, 'entryHash'>): Hex {
+  return canonicalHash(entry);
+}
+
+export function ceremonyLogPath(dir: string, sessionId: string): string {
+  if (!SESSION_ID_PATTERN.test(sessionId)) {
+    throw new Error('Invalid ceremony session id');
+  }
+  return path.join(dir, `ceremony-${sessionId}.jsonl`);
+}
+
+export async function readCeremonyLog(file: string): Promise<CeremonyEntry[]> {
+  let content: string;
+  try {
+    content = await fs.readFile(file, 'utf-8');
+  } catch (error) {
+    if (error instanceof Error && 'code' in error && error.code === 'ENOENT') return [];
+    throw error;
+  }
+  return content
+    .split('\n')
+    .filter(line => line.trim().length > 0)
+    .map(line => JSON.parse(line) as CeremonyEntry);
+}
+
+/**
+ * Appends one entry to a session's ceremony log. Each line carries the hash of the line before
+ * it, so editing or dropping an earlier line breaks every later hash.
+ */
+export async function appendCeremonyEntry(
+  dir: string,
+  sessionId: string,
+  input: {
+    event: CeremonyEvent;
+    artifact?: unknown;
+    artifactHash?: Hex;
+    details?: CeremonyDetails;
+  }
+): Promise<CeremonyEntry> {
+  const file = ceremonyLogPath(dir, sessionId);
+  return withKeyedLock(file, async () => {
+    const entries = await readCeremonyLog(file);
+    const last = entries[entries.length - 1];
+    const artifactHash =
+      input.artifactHash ??
+      (input.artifact !== undefined ? hashArtifact(input.artifact) : undefined);
+
+    const unsigned: Omit<CeremonyEntry, 'entryHash'> = {
+      seq: entries.length,
+      timestamp: new Date().toISOString(),
+      sessionId,
+      event: input.event,
+      ...(artifactHash ? { artifactHash } : {}),
+      ...(input.details ? { details: input.details } : {}),
+      prevHash: last?.entryHash ?? ZERO_HASH,
+    };
+    const entry: CeremonyEntry = { ...unsigned, entryHash: hashEntry(unsigned) };
+
+    await fs.mkdir(dir, { recursive: true });
+    await fs.appendFile(file, JSON.stringify(entry) + '\n', { flag: 'a' });
+    return entry;
+  });
+}
+
+/**
+ * EIP-712 hashes a signer signs to vouch for a log up to and including `headHash`.
+ */
+export function ceremonyHeadHashes(headHash: Hex): { domainHash: Hex; messageHash: Hex } {
+  return toolTypedDataHashes(CEREMONY_TYPEHASH, headHash);
+}
+
+/**
+ * Checks the hash chain of a ceremony log and any `log_signed` signatures in it.
+ * Returns a list of problems; empty means the log is intact.
+ */
+export async function verifyCeremonyLog(entries: CeremonyEntry[]): Promise<string[]> {
+  const problems: string[] = [];
+  let prevHash = ZERO_HASH;
+
+  for (const [index, entry] of entries.entries()) {
+    const line = `line ${index + 1}`;
+    const { entryHash, ...unsigned } = entry;
+    if (entry.seq !== index) problems.push(`${line}: expected seq ${index}`);
+    if (entry.prevHash !== prevHash) problems.push(`${line}: broken hash chain`);
+    if (hashEntry(unsigned) !== entryHash) problems.push(`${line}: entry hash mismatch`);
+    prevHash = entryHash;
+
+    if (entry.event === 'log_signed') {
+      const { signer, signature } = entry.details ?? {};
+      if (typeof signer !== 'string' || typeof signature !== 'string' || !entry.artifactHash) {
+        problems.push(`${line}: log_signed entry is missing its signature`);
+        continue;
+      }
+      if (entry.artifactHash !== entry.prevHash) {
+        problems.push(`${line}: signature does not cover the preceding entry`);
+      }
+      const { domainHash, messageHash } = ceremonyHeadHashes(entry.artifactHash);
+      let recovered: Address | undefined;
+      try {
+        recovered = await recoverAddress({
+          hash: eip712Digest(domainHash, messageHash),
+          signature: signature as Hex,
+        });
+      } catch {
+        recovered = undefined;
+      }
+      if (!recovered || !isAddress(signer) || !isAddressEqual(recovered, signer)) {
+        problems.push(`${line}: signature does not match ${signer}`);
+      }
+    }
+  }
+  return problems;
+}
@@ -0,0 +1,176 @@
+import { promises as fs } from 'fs';
+import path from 'path';
+import { z } from 'zod';
+import { Address, concat, getAddress, Hex, isAddressEqual } from 'viem';
+import { AddressSchema, describeZodIssues, HashSchema } from './config-schemas';
+import { withKeyedLock } from './keyed-lock';
+import { prepareSignatures } from './safe-signature-check';
+import { computeSafeTxHash } from './safe-hash';
+import { PolicyViolationError } from './errors';
+
+/**
+ * State of an M-of-N signing ceremony, kept in a JSON file the facilitators share (e.g. in the
+ * task repository), so nobody tracks who has signed in a spreadsheet. A ceremony moves from
+ * `collecting` to `ready` once `threshold` participants have submitted a signature, and to
+ * `finalized` when the signatures are packed for execTransaction. Every signature is recovered
+ * against the ceremony's safeTxHash when it is submitted, so one from a non-participant, for
+ * another transaction, or from a signer who already signed, is refused right away.
+ */
+
+export const CEREMONY_STATES = ['collecting', 'ready', 'finalized'] as const;
+export type CeremonyState = (typeof CEREMONY_STATES)[number];
+
+const CeremonyFileSchema = z.object({
+  version: z.literal(1),
+  safe: AddressSchema,
+  domainHash: HashSchema,
+  messageHash: HashSchema,
+  safeTxHash: HashSchema,
+  threshold: z.number().int().positive(),
+  participants: z.array(AddressSchema).min(1),
+  state: z.enum(CEREMONY_STATES),
+  createdAt: z.string().datetime(),
+  signatures: z.array(
+    z.object({
+      signer: AddressSchema,
+      // As packed for the Safe, after any v correction
+      signature: z.string().regex(/^0x[0-9a-fA-F]{130}$/),
+      submittedAt: z.string().datetime(),
+    })
+  ),
+  // Set by finalize: the signatures sorted by signer, as execTransaction takes them
+  packed: z.string().optional(),
+  finalizedAt: z.string().datetime().optional(),
+});
+
+export type CeremonyFile = z.infer<typeof CeremonyFileSchema>;
+
+export function parseCeremonyFile(raw: unknown): CeremonyFile {
+  const parsed = CeremonyFileSchema.safeParse(raw);
+  if (!parsed.success) {
+    const issues = describeZodIssues(parsed.error);
+    throw new Error(`Invalid ceremony file: ${issues}`);
+  }
+  return parsed.data;
+}
+
+export function initCeremony(params: {
+  safe: string;
+  domainHash: string;
+  messageHash: string;
+  participants: readonly Address[];
+  threshold: number;
+  now?: Date;
+}): CeremonyFile {
+  const participants = [...new Set(params.participants.map(p => getAddress(p)))];
+  if (!Number.isInteger(params.threshold) || params.threshold <= 0) {
+    throw new Error('The threshold must be a positive integer');
+  }
+  if (params.threshold > participants.length) {
+    throw new Error(
+      `A threshold of ${params.threshold} cannot be met by ${participants.length} participant(s)`
+    );
+  }
+  return {
+    version: 1,
+    safe: getAddress(params.safe),
+    domainHash: params.domainHash,
+    messageHash: params.messageHash,
+    safeTxHash: computeSafeTxHash(params.domainHash, params.messageHash),
+    threshold: params.threshold,
+    participants,
+    state: 'collecting',
+    createdAt: (params.now ?? new Date()).toISOString(),
+    signatures: [],
+  };
+}
+
+/**
+ * Adds one participant's signature. Refused when the ceremony is finalized, the signature does
+ * not recover to a participant for this safeTxHash, or the participant already signed.
+ */
+export async function submitCeremonySignature(
+  ceremony: CeremonyFile,
+  signature: Hex,
+  now: Date = new Date()
+): Promise<{ ceremony: CeremonyFile; signer: Address; notes: string[] }> {
+  if (ceremony.state === 'finalized') {
+    throw new PolicyViolationError('The ceremony is finalized; start a new one to re-sign');
+  }
+  const { signatures, notes } = await prepareSignatures([signature], ceremony.safeTxHash as Hex);
+  const [prepared] = signatures;
+  const signer = prepared.signer;
+  if (!signer || !ceremony.participants.some(p => isAddressEqual(p, signer))) {
+    throw new PolicyViolationError(
+      `The signature recovers to ${signer ?? 'no address'}, which is not a participant; check that it signs safeTxHash ${ceremony.safeTxHash}`
+    );
+  }
+  if (ceremony.signatures.some(s => isAddressEqual(s.signer, signer))) {
+    throw new PolicyViolationError(`${signer} has already submitted a signature`);
+  }
+  const collected = [
+    ...ceremony.signatures,
+    { signer, signature: prepared.signature, submittedAt: now.toISOString() },
+  ];
+  const state: CeremonyState = collected.length >= ceremony.threshold ? 'ready' : 'collecting';
+  return { ceremony: { ...ceremony, signatures: collected, state }, signer, notes };
+}
+
+// Participants who have not signed yet, in the order they were listed
+export function pendingParticipants(ceremony: CeremonyFile): string[] {
+  return ceremony.participants.filter(
+    p => !ceremony.signatures.some(s => isAddressEqual(s.signer, p))
+  );
+}
+
+/**
+ * Packs the collected signatures sorted by signer, the order the Safe requires. Only a ready
+ * ceremony can be finalized; finalizing again returns the same packing.
+ */
+export function finalizeCeremony(ceremony: CeremonyFile, now: Date = new Date()): CeremonyFile {
+  if (ceremony.state === 'finalized') return ceremony;
+  if (ceremony.state !== 'ready') {
+    throw new PolicyViolationError(
+      `${ceremony.signatures.length} of ${ceremony.threshold} signature(s) collected; the ceremony is not ready`
+    );
+  }
+  const sorted = [...ceremony.signatures].sort((a, b) =>
+    a.signer.toLowerCase().localeCompare(b.signer.toLowerCase())
+  );
+  return {
+    ...ceremony,
+    state: 'finalized',
+    packed: concat(sorted.map(s => s.signature as Hex)),
+    finalizedAt: now.toISOString(),
+  };
+}
+
+export function describeCeremony(ceremony: CeremonyFile): string {
+  const pending = pendingParticipants(ceremony).length;
+  return `${ceremony.state}: ${ceremony.signatures.length} of ${ceremony.threshold} required signature(s) from ${ceremony.participants.length} participant(s), ${pending} pending`;
+}
+
+export async function readCeremonyFile(file: string): Promise<CeremonyFile> {
+  return parseCeremonyFile(JSON.parse(await fs.readFile(file, 'utf-8')));
+}
+
+/**
+ * Reads the ceremony at `file`, applies `update`, and writes the result back. Updates from the
+ * same process are serialized, so two submissions cannot drop each other's signature.
+ */
+export async function updateCeremonyFile<T extends { ceremony: CeremonyFile }>(
+  file: string,
+  update: (ceremony: CeremonyFile) => Promise<T> | T
+): Promise<T> {
+  const resolved = path.resolve(file);
+  return withKeyedLock(resolved, async () => {
+    const result = await update(await readCeremonyFile(resolved));
+    await writeCeremonyFile(resolved, result.ceremony);
+    return result;
+  });
+}
+
+export async function writeCeremonyFile(file: string, ceremony: CeremonyFile): Promise<void> {
+  await fs.mkdir(path.dirname(path.resolve(file)), { recursive: true });
+  await fs.writeFile(file, JSON.stringify(ceremony, null, 2) + '\n');
+}
@@ -0,0 +1,106 @@
+import path from 'path';
+import { z } from 'zod';
+import { AddressSchema, describeZodIssues, HashSchema } from './config-schemas';
+import { computeSafeTxHash } from './safe-hash';
+import type { PrestateDependency, StateChange, TaskConfig } from './types/index';
+
+// Forge scripts read the previous task's post-state from the JSON file this variable names and
+// apply it with vm.store before simulating, e.g.
+// vm.parseJson(vm.readFile(vm.envOr("STATE_OVERRIDES_FILE", string("")))). The file is written
+// next to the previous task's validation file, so signers re-run `cmd` with the same overrides.
+export const PRESTATE_FILE_ENV = 'STATE_OVERRIDES_FILE';
+
+const PrestateOverridesSchema = z.array(
+  z.object({
+    contractAddress: AddressSchema,
+    overrides: z.array(z.object({ key: HashSchema, value: HashSchema })),
+  })
+);
+
+export type PrestateOverride = {
+  contractAddress: string;
+  overrides: { key: string; value: string }[];
+};
+
+// An overridden slot the simulation read at another value than the override
+export type PrestateMismatch = {
+  address: string;
+  key: string;
+  expected: string;
+  actual: string;
+};
+
+/**
+ * Converts the state changes of a previous task into storage overrides that reproduce its
+ * post-state. Only `stateChanges` are carried over: the previous task's `stateOverrides` are
+ * simulation-only and do not persist on-chain, and ETH balances cannot be set through storage.
+ */
+export function buildPrestateOverrides(previous: TaskConfig): PrestateOverride[] {
+  return previous.stateChanges.map(change => ({
+    contractAddress: change.address,
+    overrides: change.changes.map(c => ({ key: c.key, value: c.after })),
+  }));
+}
+
+// The overrides file for a previous task's validation file, e.g. a.json gives a.prestate.json
+export function prestateOverridesPath(previousFile: string): string {
+  const { dir, name } = path.parse(previousFile);
+  return path.join(dir, `${name}.prestate.json`);
+}
+
+/**
+ * Returns the `NAME=path` assignment to prepend to the forge command. `file` is relative to the
+ * directory forge runs in and must not contain whitespace, since `cmd` is split on it.
+ */
+export function prestateEnvAssignment(file: string): string {
+  if (/\s/.test(file)) throw new Error(`The overrides file path ${file} contains whitespace`);
+  return `${PRESTATE_FILE_ENV}=${file}`;
+}
+
+// An overrides file as prestateOverridesPath writes it; it is read back from the task repo
+export function parsePrestateOverrides(raw: unknown): PrestateOverride[] {
+  const parsed = PrestateOverridesSchema.safeParse(raw);
+  if (!parsed.success) {
+    const issues = describeZodIssues(parsed.error);
+    throw new Error(`Invalid state overrides file: ${issues}`);
+  }
+  return parsed.data;
+}
+
+// The overrides file a command passes to forge, or null when it passes none
+export function prestateFileFromCmd(cmdParts: readonly string[]): string | null {
+  const assignment = cmdParts.find(part => part.startsWith(`${PRESTATE_FILE_ENV}=`));
+  return assignment ? assignment.slice(PRESTATE_FILE_ENV.length + 1) : null;
+}
+
+/**
+ * Overridden slots the simulated transaction changed from another value than the override, which
+ * means the forge script did not apply it. Only slots the transaction changes carry their
+ * pre-value in the report, so overrides of slots it leaves alone cannot be checked.
+ */
+export function unappliedPrestate(
+  overrides: PrestateOverride[],
+  stateChanges: StateChange[]
+): PrestateMismatch[] {
+  const before = new Map<string, string>();
+  for (const sc of stateChanges) {
+    for (const c of sc.changes) before.set(`${sc.address.toLowerCase()}:${c.key}`, c.before);
+  }
+  return overrides.flatMap(o =>
+    o.overrides.flatMap(({ key, value }) => {
+      const actual = before.get(`${o.contractAddress.toLowerCase()}:${key}`);
+      return actual !== undefined && BigInt(actual) !== BigInt(value)
+        ? [{ address: o.contractAddress, key, expected: value, actual }]
+        : [];
+    })
+  );
+}
+
+export function describePrestateMismatch(m: PrestateMismatch): string {
+  return `${m.address} slot ${m.key} started at ${m.actual} instead of the previous task's ${m.expected}`;
+}
+
+export function describePrestateDependency(previous: TaskConfig, file: string): PrestateDependency {
+  const { domainHash, messageHash } = previous.expectedDomainAndMessageHashes;
+  return { file, safeTxHash: computeSafeTxHash(domainHash, messageHash) };
+}
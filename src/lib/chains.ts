@@ -0,0 +1,124 @@
+import { readFileSync } from 'fs';
+import { createPublicClient, http } from 'viem';
+import contractsCfg from './config/contracts.json';
+import { PolicyViolationError } from './errors';
+import { getExplorerConfig } from './explorers';
+
+export type NativeCurrency = { name: string; symbol: string; decimals: number };
+
+export type ChainInfo = {
+  chainId: number;
+  name: string;
+  explorerUrl?: string;
+  nativeCurrency: NativeCurrency;
+};
+
+type ChainEntry = {
+  name?: string;
+  explorerUrl?: string;
+  nativeCurrency?: NativeCurrency;
+  // Name accepted by --chain, e.g. base-sepolia
+  alias?: string;
+  // Default RPC for --chain; ${VAR} is replaced from the environment, e.g. for API keys
+  rpcUrl?: string;
+};
+
+// Points at a JSON file of { "<chainId>": { name?, explorerUrl?, nativeCurrency?, alias?,
+// rpcUrl? } } entries that are merged over the embedded registry, e.g. for devnets.
+export const CHAIN_REGISTRY_ENV = 'CHAIN_REGISTRY_PATH';
+
+const DEFAULT_CURRENCY: NativeCurrency = { name: 'Ether', symbol: 'ETH', decimals: 18 };
+
+const embedded = (contractsCfg as unknown as { chains?: Record<string, ChainEntry> }).chains ?? {};
+
+let overrides: Record<string, ChainEntry> | null = null;
+
+function loadOverrides(): Record<string, ChainEntry> {
+  if (overrides) return overrides;
+  const file = process.env[CHAIN_REGISTRY_ENV];
+  if (!file) return (overrides = {});
+  try {
+    overrides = JSON.parse(readFileSync(file, 'utf-8')) as Record<string, ChainEntry>;
+  } catch (error) {
+    const message = error instanceof Error ? error.message : String(error);
+    throw new Error(`Failed to load ${CHAIN_REGISTRY_ENV} (${file}): ${message}`);
+  }
+  return overrides;
+}
+
+/**
+ * Looks up display metadata for a chain. Unknown chains still resolve, named by their ID, so
+ * callers never have to handle a missing entry.
+ */
+export function getChainInfo(chainId: number | string): ChainInfo {
+  const key = String(chainId).trim();
+  const entry = { ...embedded[key], ...loadOverrides()[key] };
+  return {
+    chainId: Number(key),
+    name: entry.name ?? `Chain ${key}`,
+    explorerUrl: entry.explorerUrl ?? getExplorerConfig(key)?.url,
+    nativeCurrency: entry.nativeCurrency ?? DEFAULT_CURRENCY,
+  };
+}
+
+function registryEntries(): [string, ChainEntry][] {
+  const extra = loadOverrides();
+  const ids = new Set([...Object.keys(embedded), ...Object.keys(extra)]);
+  return Array.from(ids).map(id => [id, { ...embedded[id], ...extra[id] }]);
+}
+
+/**
+ * Replaces `${VAR}` placeholders with environment variables. A missing variable is an error
+ * naming it, rather than a URL that silently lacks its API key.
+ */
+export function expandEnvVars(template: string, env: NodeJS.ProcessEnv = process.env): string {
+  return template.replace(/\$\{([A-Za-z_][A-Za-z0-9_]*)\}/g, (_, name: string) => {
+    const value = env[name];
+    if (!value) throw new Error(`Environment variable ${name} is not set`);
+    return value;
+  });
+}
+
+/**
+ * Resolves a `--chain` value, an alias such as `base-sepolia` or a chain ID, to the chain ID
+ * and its default RPC URL (undefined when the registry has none).
+ */
+export function resolveChain(
+  value: string,
+  env: NodeJS.ProcessEnv = process.env
+): { chainId: number; name: string; rpcUrl?: string } {
+  const wanted = value.trim().toLowerCase();
+  const match = registryEntries().find(
+    ([id, entry]) => id === wanted || entry.alias?.toLowerCase() === wanted
+  );
+  if (!match) {
+    const aliases = registryEntries()
+      .map(([, entry]) => entry.alias)
+      .filter((alias): alias is string => Boolean(alias))
+      .sort();
+    throw new Error(`Unknown chain "${value}"; known chains: ${aliases.join(', ')}`);
+  }
+  const [id, entry] = match;
+  return {
+    chainId: Number(id),
+    name: entry.name ?? `Chain ${id}`,
+    ...(entry.rpcUrl ? { rpcUrl: expandEnvVars(entry.rpcUrl, env) } : {}),
+  };
+}
+
+/**
+ * Refuses an RPC endpoint whose node is on a different chain than the one asked for, so a
+ * stale registry entry or a mistyped --rpc-url cannot simulate against the wrong network.
+ */
+export async function assertConnectedChain(
+  rpcUrl: string,
+  expected: { chainId: number; name: string }
+): Promise<void> {
+  const client = createPublicClient({ transport: http(rpcUrl) });
+  const connected = await client.getChainId();
+  if (connected !== expected.chainId) {
+    throw new PolicyViolationError(
+      `Expected ${expected.name} (${expected.chainId}), but the RPC endpoint is on chain ${connected}`
+    );
+  }
+}
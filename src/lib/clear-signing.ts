@@ -0,0 +1,160 @@
+import { Address, encodeAbiParameters, getAddress, Hex, keccak256, toBytes } from 'viem';
+
+/**
+ * ERC-7730 clear-signing descriptor for the SafeTx a task asks owners to sign. Ledger devices
+ * with clear signing use it to show each SafeTx field with a label (destination, value,
+ * operation, nonce, ...) instead of only the domain and message hashes. The descriptor is
+ * bound to the signing Safe on its chain, and to the EIP-712 domain that Safe version uses.
+ * It does not replace the hash check: the device still shows the hashes, and those are what
+ * the validation file pins.
+ */
+
+export const ERC7730_SCHEMA = 'https://eips.ethereum.org/assets/eip-7730/erc7730-v1.schema.json';
+
+type TypedField = { name: string; type: string };
+
+const SAFE_TX_FIELDS: TypedField[] = [
+  { name: 'to', type: 'address' },
+  { name: 'value', type: 'uint256' },
+  { name: 'data', type: 'bytes' },
+  { name: 'operation', type: 'uint8' },
+  { name: 'safeTxGas', type: 'uint256' },
+  { name: 'baseGas', type: 'uint256' },
+  { name: 'gasPrice', type: 'uint256' },
+  { name: 'gasToken', type: 'address' },
+  { name: 'refundReceiver', type: 'address' },
+  { name: 'nonce', type: 'uint256' },
+];
+
+// Safe 1.3.0 and later include the chain ID in the domain; earlier versions only the Safe
+const DOMAINS = [
+  {
+    fields: [
+      { name: 'chainId', type: 'uint256' },
+      { name: 'verifyingContract', type: 'address' },
+    ],
+    hash: (chainId: number, safe: Address) =>
+      keccak256(
+        encodeAbiParameters(
+          [{ type: 'bytes32' }, { type: 'uint256' }, { type: 'address' }],
+          [
+            keccak256(toBytes('EIP712Domain(uint256 chainId,address verifyingContract)')),
+            BigInt(chainId),
+            safe,
+          ]
+        )
+      ),
+  },
+  {
+    fields: [{ name: 'verifyingContract', type: 'address' }],
+    hash: (_chainId: number, safe: Address) =>
+      keccak256(
+        encodeAbiParameters(
+          [{ type: 'bytes32' }, { type: 'address' }],
+          [keccak256(toBytes('EIP712Domain(address verifyingContract)')), safe]
+        )
+      ),
+  },
+];
+
+export type ClearSigningDescriptor = {
+  $schema: string;
+  context: {
+    eip712: {
+      deployments: { chainId: number; address: Address }[];
+      schemas: { primaryType: 'SafeTx'; types: Record<string, TypedField[]> }[];
+    };
+  };
+  metadata: {
+    owner: string;
+    enums: Record<string, Record<string, string>>;
+  };
+  display: {
+    formats: {
+      SafeTx: {
+        intent: string;
+        fields: { path: string; label: string; format: string; params?: object }[];
+        required: string[];
+      };
+    };
+  };
+};
+
+/**
+ * The descriptor for SafeTx messages of `safe` on `chainId`. `domainHash` is the validation
+ * file's: it picks the domain layout the Safe signs with, and a hash neither layout produces
+ * means the task does not sign a SafeTx of this Safe, so there is nothing to describe.
+ */
+export function buildClearSigningDescriptor(params: {
+  chainId: number;
+  safe: string;
+  domainHash: string;
+  // Shown as the signing intent, e.g. the task name
+  intent?: string;
+}): ClearSigningDescriptor {
+  const safe = getAddress(params.safe);
+  const domain = DOMAINS.find(
+    d => d.hash(params.chainId, safe) === (params.domainHash.toLowerCase() as Hex)
+  );
+  if (!domain) {
+    throw new Error(
+      `Domain hash ${params.domainHash} is not the EIP-712 domain of Safe ${safe} on chain ${params.chainId}; a clear-signing descriptor only describes SafeTx messages`
+    );
+  }
+
+  const address = (path: string, label: string) => ({
+    path,
+    label,
+    format: 'addressName',
+    params: { types: ['eoa', 'contract'], sources: ['local', 'ens'] },
+  });
+  const raw = (path: string, label: string) => ({ path, label, format: 'raw' });
+  return {
+    $schema: ERC7730_SCHEMA,
+    context: {
+      eip712: {
+        deployments: [{ chainId: params.chainId, address: safe }],
+        schemas: [
+          {
+            primaryType: 'SafeTx',
+            types: { EIP712Domain: domain.fields, SafeTx: SAFE_TX_FIELDS },
+          },
+        ],
+      },
+    },
+    metadata: {
+      owner: 'Safe',
+      enums: { operation: { '0': 'Call', '1': 'Delegatecall' } },
+    },
+    display: {
+      formats: {
+        SafeTx: {
+          intent: params.intent ?? 'Sign Safe transaction',
+          fields: [
+            address('to', 'To'),
+            { path: 'value', label: 'Value', format: 'amount' },
+            { path: 'data', label: 'Data', format: 'calldata', params: { calleePath: 'to' } },
+            {
+              path: 'operation',
+              label: 'Operation',
+              format: 'enum',
+              params: { $ref: '$.metadata.enums.operation' },
+            },
+            raw('safeTxGas', 'SafeTx gas'),
+            raw('baseGas', 'Base gas'),
+            raw('gasPrice', 'Gas price'),
+            address('gasToken', 'Gas token'),
+            address('refundReceiver', 'Refund receiver'),
+            raw('nonce', 'Nonce'),
+          ],
+          required: SAFE_TX_FIELDS.map(f => f.name),
+        },
+      },
+    },
+  };
+}
+
+// Where the descriptor is written next to a validation file: task.json -> task.erc7730.json
+export function clearSigningPath(validationPath: string): string {
+  return validationPath.replace(/(\.json)?$/, '.erc7730.json');
+}
@@ -0,0 +1,136 @@
+import { Address, getAddress, Hex, keccak256, PublicClient } from 'viem';
+import type { StorageDiff } from './account-aggregation';
+import { EIP1967_IMPLEMENTATION_SLOT } from './slot-knowledge';
+import { AccountAccessKind, VmSafeAccountAccess } from './vm-safe';
+
+const PUSH1 = 0x60;
+const PUSH4 = 0x63;
+const PUSH32 = 0x7f;
+const DUP2 = 0x81;
+const EQ = 0x14;
+
+export type CodeSnapshot = { size: number; codeHash: Hex };
+
+export type CodeDiff = {
+  // Lowercased address whose code changed: a new contract, or a proxy whose implementation did
+  address: string;
+  kind: 'created' | 'implementation';
+  implementationBefore: Address | null;
+  implementationAfter: Address | null;
+  // Null when there was no code on that side
+  before: CodeSnapshot | null;
+  after: CodeSnapshot | null;
+  selectorsAdded: Hex[];
+  selectorsRemoved: Hex[];
+};
+
+export function codeSnapshot(code: Hex): CodeSnapshot | null {
+  if (code === '0x') return null;
+  return { size: (code.length - 2) / 2, codeHash: keccak256(code) };
+}
+
+/**
+ * Function selectors a contract dispatches on, found by disassembling its code. Solidity and
+ * Vyper dispatchers compare the calldata selector with `PUSH4 <selector> EQ` (or
+ * `PUSH4 <selector> DUP2 EQ`); push data is skipped so constants inside it are not mistaken for
+ * instructions. This is a heuristic: selectors of hand-written or unusual dispatchers are missed.
+ */
+export function extractSelectors(code: Hex): Hex[] {
+  const bytes = Buffer.from(code.slice(2), 'hex');
+  const selectors = new Set<Hex>();
+  let pc = 0;
+  while (pc < bytes.length) {
+    const op = bytes[pc];
+    if (op < PUSH1 || op > PUSH32) {
+      pc++;
+      continue;
+    }
+    const next = pc + 1 + (op - PUSH1 + 1);
+    const isComparison = bytes[next] === EQ || (bytes[next] === DUP2 && bytes[next + 1] === EQ);
+    if (op === PUSH4 && next <= bytes.length && isComparison) {
+      selectors.add(('0x' + bytes.subarray(pc + 1, next).toString('hex')) as Hex);
+    }
+    pc = next;
+  }
+  return Array.from(selectors).sort();
+}
+
+function compareCode(before: Hex, after: Hex) {
+  const previous = new Set(extractSelectors(before));
+  const current = new Set(extractSelectors(after));
+  return {
+    before: codeSnapshot(before),
+    after: codeSnapshot(after),
+    selectorsAdded: Array.from(current).filter(s => !previous.has(s)),
+    selectorsRemoved: Array.from(previous).filter(s => !current.has(s)),
+  };
+}
+
+function implementationFromWord(word: Hex): Address | null {
+  return BigInt(word) === BigInt(0) ? null : getAddress('0x' + word.slice(-40));
+}
+
+/**
+ * Finds the code a transaction changes: contracts it deploys, and EIP-1967 proxies whose
+ * implementation slot it rewrites, where the old and new implementation code are compared.
+ * Code deployed during the simulation is taken from the trace; other code is read from the
+ * node. Contracts that also self-destruct are left to the account deletions section.
+ */
+export async function findCodeChanges(
+  client: Pick<PublicClient, 'getCode'>,
+  decoded: readonly VmSafeAccountAccess[],
+  storage: Map<string, StorageDiff>
+): Promise<CodeDiff[]> {
+  const destroyed = new Set(
+    decoded
+      .filter(a => a.kind === AccountAccessKind.SelfDestruct && !a.reverted)
+      .map(a => a.accessor.toLowerCase())
+  );
+  const created = new Map<string, Hex>();
+  for (const access of decoded) {
+    if (access.kind !== AccountAccessKind.Create || access.reverted) continue;
+    if (access.deployedCode === '0x') continue;
+    created.set(access.account.toLowerCase(), access.deployedCode);
+  }
+  const codeAt = async (address: Address | null): Promise<Hex> => {
+    if (!address) return '0x';
+    return created.get(address.toLowerCase()) ?? (await client.getCode({ address })) ?? '0x';
+  };
+
+  const changes: CodeDiff[] = [];
+  for (const [address, code] of created) {
+    if (destroyed.has(address)) continue;
+    changes.push({
+      address,
+      kind: 'created',
+      implementationBefore: null,
+      implementationAfter: null,
+      ...compareCode('0x', code),
+    });
+  }
+  for (const diff of storage.values()) {
+    const slot = diff.storageDiffs.get(EIP1967_IMPLEMENTATION_SLOT);
+    if (!slot || slot.before === slot.after) continue;
+    const implementationBefore = implementationFromWord(slot.before);
+    const implementationAfter = implementationFromWord(slot.after);
+    changes.push({
+      address: diff.address,
+      kind: 'implementation',
+      implementationBefore,
+      implementationAfter,
+      ...compareCode(await codeAt(implementationBefore), await codeAt(implementationAfter)),
+    });
+  }
+  return changes.sort((a, b) => a.address.localeCompare(b.address));
+}
+
+export function describeCodeChange(change: CodeDiff): string {
+  const size = (s: CodeSnapshot | null) => (s ? `${s.size} bytes` : 'no code');
+  const from = change.implementationBefore ?? 'unset';
+  const to = change.implementationAfter ?? 'unset';
+  const what =
+    change.kind === 'created'
+      ? `${change.address} is deployed (${size(change.after)})`
+      : `${change.address} implementation ${from} -> ${to} (${size(change.before)} -> ${size(change.after)})`;
+  return `${what}, ${change.selectorsAdded.length} selector(s) added, ${change.selectorsRemoved.length} removed`;
+}
@@ -0,0 +1,46 @@
+import { PolicyViolationError } from './errors';
+
+/**
+ * Which binaries a task command may start. The command comes from the task (a validation file's
+ * cmd, or --forge-cmd) and runs with the signer's environment, credentials, and file access, so
+ * a tampered task could name any program. With an allowlist only the named binaries run, and
+ * they are looked up on PATH by name, never by a path the task chooses.
+ */
+
+// Comma-separated binaries, e.g. "forge,just"; any command runs when unset
+export const ALLOWED_CMDS_ENV = 'STATE_DIFF_ALLOWED_CMDS';
+
+export function parseAllowedCommands(list: string): string[] {
+  const names = list
+    .split(',')
+    .map(name => name.trim())
+    .filter(Boolean);
+  if (names.length === 0) throw new Error('The allowed commands list is empty');
+  for (const name of names) {
+    if (!/^[A-Za-z0-9._+-]+$/.test(name)) {
+      throw new Error(`Allowed command ${name} must be a binary name, not a path`);
+    }
+  }
+  return names;
+}
+
+export function allowedCommandsFromEnv(env: NodeJS.ProcessEnv = process.env): string[] | null {
+  const list = env[ALLOWED_CMDS_ENV];
+  return list ? parseAllowedCommands(list) : null;
+}
+
+export function checkCommandAllowed(command: string, allowed: readonly string[] | null): void {
+  if (!allowed) return;
+  const trust =
+    'Task commands run with your environment, credentials, and file access, so only binaries you trust may run';
+  if (command.includes('/') || command.includes('\\')) {
+    throw new PolicyViolationError(
+      `The task command runs ${command} by path; allowed commands (${allowed.join(', ')}) are looked up on PATH by name. ${trust}`
+    );
+  }
+  if (!allowed.includes(command)) {
+    throw new PolicyViolationError(
+      `The task command runs ${command}, which is not an allowed command (${allowed.join(', ')}). ${trust}`
+    );
+  }
+}
@@ -0,0 +1,40 @@
+import { getAddress } from 'viem';
+
+/**
+ * Normalizes an address used as a key in contracts.json to the lowercase form lookups use.
+ * Keys may be written lowercase, uppercase, or checksummed; a mixed-case key must carry a
+ * valid EIP-55 checksum so a mistyped address is caught instead of never matching.
+ */
+export function normalizeConfigAddress(key: string, where: string): string {
+  const address = key.trim();
+  if (!/^0x[0-9a-fA-F]{40}$/.test(address)) {
+    throw new Error(`${where}: "${key}" is not an address`);
+  }
+  const body = address.slice(2);
+  const singleCase = body === body.toLowerCase() || body === body.toUpperCase();
+  if (!singleCase && getAddress(address) !== address) {
+    throw new Error(`${where}: ${key} has an invalid checksum (expected ${getAddress(address)})`);
+  }
+  return address.toLowerCase();
+}
+
+/**
+ * Re-keys a record of per-address entries by lowercase address. Two keys for the same address
+ * in different cases are rejected rather than one silently replacing the other.
+ */
+export function normalizeAddressKeys<T>(
+  entries: Record<string, T>,
+  where: string
+): Record<string, T> {
+  const out: Record<string, T> = {};
+  const original: Record<string, string> = {};
+  for (const [key, value] of Object.entries(entries)) {
+    const address = normalizeConfigAddress(key, where);
+    if (address in out) {
+      throw new Error(`${where}: ${original[address]} and ${key} are the same address`);
+    }
+    out[address] = value;
+    original[address] = key;
+  }
+  return out;
+}
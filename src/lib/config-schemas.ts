@@ -1,5 +1,10 @@
 import { z } from 'zod';
-import { isAddress, getAddress, Address } from 'viem';
+import { isAddress, getAddress, Address, concat, Hex, keccak256 } from 'viem';
+import { DATA_TO_SIGN_FORMS } from './data-to-sign';
+import { SLOT_FORMATS } from './number-format';
+import { REPORT_AUDIENCES } from './report-audience';
+import { REPORT_CATEGORIES } from './report-groups';
+import { WARNING_SEVERITIES } from './report-warnings';
 
 /**
  * Validates an Ethereum address using viem's isAddress() which checks both
@@ -15,10 +20,36 @@ export const AddressSchema = z
 
 export const HashSchema = z.string().regex(/^0x[a-fA-F0-9]{64}$/, 'Invalid hash format');
 
+export const HexValueSchema = z.string().regex(/^0x[0-9a-fA-F]*$/, 'Invalid hex value');
+
+/**
+ * One line for every issue in a failed parse, each prefixed with the path of the offending
+ * field, for errors about files and RPC responses the tool does not trust.
+ */
+export function describeZodIssues(error: z.ZodError): string {
+  return error.issues
+    .map(issue => `${issue.path.join('.') || '<root>'}: ${issue.message}`)
+    .join('; ');
+}
+
+// A mapping entry's slot with the mapping slot and key it is derived from; the slot is checked
+// to be keccak256(key . parent), so a preimage cannot relabel an unrelated slot
+export const PreimageSchema = z
+  .object({ slot: HashSchema, parent: HashSchema, key: HashSchema })
+  .refine(p => keccak256(concat([p.key as Hex, p.parent as Hex])) === p.slot.toLowerCase(), {
+    message: 'slot is not keccak256(key . parent)',
+  });
+
 export const ExpectedHashesSchema = z.object({
   address: AddressSchema,
   domainHash: HashSchema,
   messageHash: HashSchema,
+  // keccak256(0x1901 || domainHash || messageHash); informational, recomputed during validation
+  safeTxHash: HashSchema.optional(),
+  // Implementation in the Safe's slot 0, and its version when it is a known Safe deployment;
+  // informational, since the version decides how the domain hash is computed
+  singleton: AddressSchema.optional(),
+  safeVersion: z.string().min(1).optional(),
 });
 
 export const OverrideSchema = z.object({
@@ -31,26 +62,53 @@ export const OverrideSchema = z.object({
 export const StateOverrideSchema = z.object({
   name: z.string().min(1),
   address: AddressSchema,
+  explorerUrl: z.string().url().optional(),
   overrides: z.array(OverrideSchema),
 });
 
+// One write to a slot; writer is the account whose code ran the SSTORE, so the implementation
+// rather than the proxy under a DELEGATECALL
+export const SlotWriteSchema = z.object({
+  value: HashSchema,
+  writer: AddressSchema,
+  depth: z.number().int().min(0),
+  reverted: z.boolean(),
+});
+
 export const ChangeSchema = z.object({
   key: HashSchema,
   before: HashSchema,
   after: HashSchema,
   description: z.string(),
   allowDifference: z.boolean(),
+  // How before and after are displayed, from the slot's hints in contracts.json
+  decimals: z.number().int().min(0).max(77).optional(),
+  format: z.enum(SLOT_FORMATS).optional(),
+  // Names from the constants in contracts.json that before and after equal; display only
+  beforeAlias: z.string().min(1).optional(),
+  afterAlias: z.string().min(1).optional(),
+  // The slot is also in stateOverrides, so before is the override's value rather than the
+  // chain's and cannot be checked against it
+  beforeFromOverride: z.boolean().optional(),
+  // Every write to the slot in execution order, set when it was written more than once
+  history: z.array(SlotWriteSchema).optional(),
 });
 
 export const StateChangeSchema = z.object({
   name: z.string().min(1),
   address: AddressSchema,
+  explorerUrl: z.string().url().optional(),
+  // The account did not exist before the transaction, so every slot started at zero
+  newAccount: z.boolean().optional(),
   changes: z.array(ChangeSchema),
 });
 
 export const BalanceChangeSchema = z.object({
   name: z.string().min(1),
   address: AddressSchema,
+  explorerUrl: z.string().url().optional(),
+  // The account did not exist before the transaction
+  newAccount: z.boolean().optional(),
   field: z.string().min(1),
   before: HashSchema,
   after: HashSchema,
@@ -58,12 +116,244 @@ export const BalanceChangeSchema = z.object({
   allowDifference: z.boolean(),
 });
 
+// ETH moved by the transaction, summed per sender and receiver. A balance change no value
+// transfer explains is listed with only `to` (a gain) or only `from` (a loss).
+export const EthTransferSchema = z.object({
+  from: AddressSchema.optional(),
+  fromName: z.string().min(1).optional(),
+  to: AddressSchema.optional(),
+  toName: z.string().min(1).optional(),
+  // Wei
+  amount: HashSchema,
+});
+
+// Contracts that self-destructed during the simulation
+export const AccountDeletionSchema = z.object({
+  name: z.string().min(1),
+  address: AddressSchema,
+  explorerUrl: z.string().url().optional(),
+  // False when EIP-6780 limited the self-destruct to sweeping the balance
+  codeRemoved: z.boolean(),
+  codeHash: HashSchema.optional(),
+  beneficiary: AddressSchema.optional(),
+  sweptBalance: HashSchema,
+});
+
+const CodeSnapshotSchema = z.object({
+  size: z.number().int().positive(),
+  codeHash: HashSchema,
+});
+
+// Code the transaction changes: a contract it deploys, or a new implementation behind a proxy
+export const CodeChangeSchema = z.object({
+  name: z.string().min(1),
+  address: AddressSchema,
+  explorerUrl: z.string().url().optional(),
+  kind: z.enum(['created', 'implementation']),
+  // EIP-1967 implementation before and after; absent when unset
+  implementationBefore: AddressSchema.optional(),
+  implementationAfter: AddressSchema.optional(),
+  // Absent when there was no code on that side
+  before: CodeSnapshotSchema.optional(),
+  after: CodeSnapshotSchema.optional(),
+  // Dispatcher selectors found by disassembling the code; best effort
+  selectorsAdded: z.array(z.string().regex(/^0x[a-f0-9]{8}$/)),
+  selectorsRemoved: z.array(z.string().regex(/^0x[a-f0-9]{8}$/)),
+});
+
+// A Safe's guard, module guard, or fallback handler changing; listed for every Safe, whether or
+// not contracts.json describes it
+export const SafeConfigurationChangeSchema = z.object({
+  name: z.string().min(1),
+  address: AddressSchema,
+  explorerUrl: z.string().url().optional(),
+  setting: z.enum(['guard', 'moduleGuard', 'fallbackHandler']),
+  // Absent when unset
+  before: AddressSchema.optional(),
+  after: AddressSchema.optional(),
+  // contracts.json name of the new guard or handler, when it has one
+  afterName: z.string().min(1).optional(),
+  // Absent when the new guard or handler has no code
+  afterCode: CodeSnapshotSchema.optional(),
+});
+
+// A signature already present in the simulated execTransaction call (pre-approved flows)
+export const PayloadSignatureSchema = z.object({
+  type: z.enum(['ecdsa', 'eth_sign', 'approved-hash', 'contract-signature']),
+  // Absent when an ECDSA signer could not be recovered
+  signer: AddressSchema.optional(),
+});
+
+// Outcome of replaying the simulated call against the node with eth_call and eth_estimateGas
+export const ExecutionCheckSchema = z.object({
+  // safe-execution-failed: execTransaction returned false because the Safe's inner call failed
+  status: z.enum(['success', 'reverted', 'safe-execution-failed']),
+  // Decimal eth_estimateGas result, an upper bound on the gas the transaction uses
+  estimatedGas: z.string().regex(/^\d+$/).optional(),
+  revertReason: z.string().optional(),
+});
+
+// stateDiff.json as written by the forge simulation script
+export const EncodedStateDiffSchema = z.object({
+  targetSafe: z.string(),
+  // 0x1901 + domain(32B) + message(32B), the bare 64 bytes, or EIP-712 typed-data JSON
+  dataToSign: z.string().min(1),
+  stateDiff: HexValueSchema, // hex-encoded ABI tuple[]
+  preimages: HexValueSchema, // hex-encoded ABI tuple[]
+  overrides: HexValueSchema, // hex-encoded ABI tuple
+});
+
+// When the simulation ran; validation warns about files older than its staleness limits
+export const SimulatedAtSchema = z.object({
+  blockNumber: z.number().int().nonnegative(),
+  // Checked again at validation, so a reorg that replaced the block is noticed; absent in files
+  // written before it was recorded
+  blockHash: HashSchema.optional(),
+  // Unix seconds
+  blockTimestamp: z.number().int().nonnegative(),
+  generatedAt: z.string().datetime(),
+});
+
+// Counts derived from the rest of the file for dashboards and quick review; never compared
+export const ReportSummarySchema = z.object({
+  // Contracts with a state or balance change
+  contractsTouched: z.number().int().nonnegative(),
+  // Contracts contracts.json has no name for; absent in files written before it was counted
+  unknownContracts: z.number().int().nonnegative().optional(),
+  slotsChanged: z.number().int().nonnegative(),
+  // Changed slots contracts.json has no description for
+  unknownSlots: z.number().int().nonnegative(),
+  overridesApplied: z.number().int().nonnegative(),
+  // Decimal wei, the sum of balance increases
+  ethMoved: z.string().regex(/^\d+$/),
+  contracts: z.array(
+    z.object({
+      name: z.string().min(1),
+      address: AddressSchema,
+      slotsChanged: z.number().int().nonnegative(),
+      overrides: z.number().int().nonnegative(),
+      balanceChanged: z.boolean(),
+      // From the contract's contracts.json tags; see report-groups.ts
+      category: z.enum(REPORT_CATEGORIES).optional(),
+    })
+  ),
+  // Changes contracts.json marks as noise and left out of the file: slots tagged `noise` and
+  // balance changes below noise.minBalanceDeltaWei
+  noiseFiltered: z
+    .object({
+      stateChanges: z.number().int().nonnegative(),
+      balanceChanges: z.number().int().nonnegative(),
+    })
+    .optional(),
+  // Table of contents for large tasks, riskiest category first
+  groups: z
+    .array(
+      z.object({
+        category: z.enum(REPORT_CATEGORIES),
+        contracts: z.number().int().nonnegative(),
+        slotsChanged: z.number().int().nonnegative(),
+        overrides: z.number().int().nonnegative(),
+        addresses: z.array(AddressSchema),
+      })
+    )
+    .optional(),
+});
+
+// A read of a slot marked critical in contracts.json (genValidationFile.ts --include-reads)
+export const CriticalReadSchema = z.object({
+  name: z.string().min(1),
+  address: AddressSchema,
+  key: HashSchema,
+  // Value at the first read
+  value: HashSchema,
+  description: z.string(),
+});
+
+// Values a slot held between its before and after values (genValidationFile.ts --verbose)
+export const IntermediateWriteSchema = z.object({
+  address: AddressSchema,
+  key: HashSchema,
+  values: z.array(HashSchema),
+});
+
+// An earlier validation file that changed the same contract (genValidationFile.ts --history)
+export const RecentlyModifiedSchema = z.object({
+  name: z.string().min(1),
+  address: AddressSchema,
+  // Path of the earlier file, relative to the history directory
+  file: z.string().min(1),
+  // Slots both files change; empty when they change different slots of the contract
+  keys: z.array(HashSchema),
+});
+
 export const L2GasEstimationSchema = z.object({
   estimatedGas: z.string().min(1),
   buffer: z.number().int().nonnegative(),
   recommendedGasLimit: z.string().min(1),
 });
 
+// Previous task whose post-state this task was simulated on top of (--prestate-from)
+export const PrestateDependencySchema = z.object({
+  file: z.string().min(1),
+  safeTxHash: HashSchema,
+});
+
+// Environment genValidationFile.ts injected into forge (--env and the workdir .env)
+export const SimulationEnvSchema = z.array(
+  z.object({
+    name: z.string().min(1),
+    source: z.enum(['flag', 'dotenv']),
+    // <<Redacted>> for secrets, which each signer supplies from their own environment
+    value: z.string(),
+  })
+);
+
+// Hashes of each owner Safe's approveHash transaction, keyed by the owner Safe's address
+// (genValidationFile.ts --nested-hashes)
+export const NestedHashesSchema = z.record(
+  z.object({
+    // The owner Safe's nonce the hashes were computed at
+    nonce: z.number().int().nonnegative(),
+    domainHash: HashSchema,
+    messageHash: HashSchema,
+    safeTxHash: HashSchema,
+  })
+);
+
+// Something a reviewer should look at, under a code automated gates can match on; codes are
+// strings rather than an enum so files with codes from a newer tool still load
+export const ReportWarningSchema = z.object({
+  code: z.string().min(1),
+  severity: z.enum(WARNING_SEVERITIES),
+  message: z.string(),
+  context: z.record(z.union([z.string(), z.number(), z.boolean()])).optional(),
+});
+
+// Facilitator signature over the rest of the file (genValidationFile.ts --attest)
+export const AttestationSchema = z.object({
+  signer: AddressSchema,
+  signature: z.string().regex(/^0x[a-fA-F0-9]{130}$/, 'Invalid signature format'),
+});
+
+// Contracts the report was limited to (genValidationFile.ts --only/--exclude), and how many
+// entries that left out
+export const ReportScopeSchema = z.object({
+  only: z.array(AddressSchema).optional(),
+  exclude: z.array(AddressSchema).optional(),
+  filtered: z.object({
+    stateOverrides: z.number().int().nonnegative(),
+    stateChanges: z.number().int().nonnegative(),
+    balanceChanges: z.number().int().nonnegative(),
+    // Absent from files written before the other sections were counted
+    ethTransfers: z.number().int().nonnegative().optional(),
+    accountDeletions: z.number().int().nonnegative().optional(),
+    codeChanges: z.number().int().nonnegative().optional(),
+    intermediateWrites: z.number().int().nonnegative().optional(),
+    criticalReads: z.number().int().nonnegative().optional(),
+    recentlyModified: z.number().int().nonnegative().optional(),
+  }),
+});
+
 // Only taskCreator needs a config for the commonName parameter
 // All other fields are hardcoded including the signature file names
 export const TaskOriginValidationConfigSchema = z.object({
@@ -72,17 +362,77 @@ export const TaskOriginValidationConfigSchema = z.object({
   }),
 });
 
+// Tool build that produced the file, so a file can be traced back to the code that made it
+export const GeneratedBySchema = z.object({
+  tool: z.string(),
+  version: z.string(),
+  commit: z.string(),
+  configHash: HashSchema,
+  buildDate: z.string(),
+});
+
+// Runbook for each signer, rendered from a team template by genValidationFile.ts
+export const SignerInstructionsSchema = z.object({
+  team: z.string().min(1).optional(),
+  commands: z.array(z.string()),
+  hashes: z.object({
+    safe: AddressSchema,
+    domainHash: HashSchema,
+    messageHash: HashSchema,
+    safeTxHash: HashSchema,
+  }),
+  devices: z.array(z.object({ device: z.string().min(1), steps: z.array(z.string()) })),
+  notes: z.array(z.string()).optional(),
+});
+
 export const TaskConfigSchema = z.object({
   cmd: z.string(),
   ledgerId: z.number().int().nonnegative(),
   rpcUrl: z.string().url().min(1),
+  // Chain the simulation ran against; informational, written by genValidationFile
+  chainId: z.number().int().positive().optional(),
+  chainName: z.string().min(1).optional(),
   expectedDomainAndMessageHashes: ExpectedHashesSchema,
+  // Shape dataToSign was supplied in; informational
+  dataToSignForm: z.enum(DATA_TO_SIGN_FORMS).optional(),
+  // Safe nonce the transaction was built for; checked against the Safe's on-chain nonce
+  safeNonce: z.number().int().nonnegative().optional(),
   stateOverrides: z.array(StateOverrideSchema),
   stateChanges: z.array(StateChangeSchema),
   balanceChanges: z.array(BalanceChangeSchema).optional(),
+  ethTransfers: z.array(EthTransferSchema).optional(),
+  accountDeletions: z.array(AccountDeletionSchema).optional(),
+  codeChanges: z.array(CodeChangeSchema).optional(),
+  safeConfigurationChanges: z.array(SafeConfigurationChangeSchema).optional(),
+  // Preimages from genValidationFile.ts --preimages that resolve slots in the report; validation
+  // passes them to the re-run so its slots are described the same way
+  extraPreimages: z.array(PreimageSchema).optional(),
+  payloadSignatures: z.array(PayloadSignatureSchema).optional(),
+  execution: ExecutionCheckSchema.optional(),
+  criticalReads: z.array(CriticalReadSchema).optional(),
+  simulatedAt: SimulatedAtSchema.optional(),
+  summary: ReportSummarySchema.optional(),
+  // The encoded blobs the report was decoded from (genValidationFile.ts --include-raw)
+  raw: EncodedStateDiffSchema.optional(),
+  intermediateWrites: z.array(IntermediateWriteSchema).optional(),
+  recentlyModified: z.array(RecentlyModifiedSchema).optional(),
+  scope: ReportScopeSchema.optional(),
+  // Reader the file was tailored for (genValidationFile.ts --audience); sections for other
+  // readers are left out
+  audience: z.enum(REPORT_AUDIENCES).optional(),
   l2GasEstimation: L2GasEstimationSchema.optional(),
+  prestateFrom: PrestateDependencySchema.optional(),
+  simulationEnv: SimulationEnvSchema.optional(),
+  signerInstructions: SignerInstructionsSchema.optional(),
+  nestedHashes: NestedHashesSchema.optional(),
+  warnings: z.array(ReportWarningSchema).optional(),
+  attestation: AttestationSchema.optional(),
+  // Salt of the commitments in the shareable copy genValidationFile.ts --redact writes next to
+  // the file; kept only here, since with it a redacted value can be guessed by hashing candidates
+  redactionSalt: HashSchema.optional(),
   // Task origin validation (opt-out, enabled by default)
   skipTaskOriginValidation: z.boolean().optional(),
   hideTaskOriginSkippedPage: z.boolean().optional(),
   taskOriginConfig: TaskOriginValidationConfigSchema.optional(),
+  generatedBy: GeneratedBySchema.optional(),
 });
@@ -0,0 +1,158 @@
+import { z } from 'zod';
+import { computeSafeTxHash } from './safe-hash';
+import type { SlotWrite, TaskConfig } from './types/index';
+
+/**
+ * Step-through review for GUIs that wrap the CLI (`state-diff review --confirm-protocol jsonl`).
+ * Each thing a signer checks is written to stdout as one JSON line, and the tool waits for the
+ * wrapper to answer `{"ack": true}` on stdin before writing the next one. `{"ack": false}`
+ * rejects the report, and so does stdin closing early, so a crashed wrapper never counts as
+ * a review. The last line is `done` with the file's content hash, or `rejected`.
+ */
+
+export const CONFIRM_PROTOCOLS = ['jsonl'] as const;
+export type ConfirmProtocol = (typeof CONFIRM_PROTOCOLS)[number];
+
+export type ReviewItem =
+  | {
+      kind: 'hashes';
+      safe: string;
+      domainHash: string;
+      messageHash: string;
+      safeTxHash: string;
+    }
+  | {
+      kind: 'override';
+      contract: string;
+      address: string;
+      key: string;
+      value: string;
+      description: string;
+    }
+  | {
+      kind: 'change';
+      contract: string;
+      address: string;
+      key: string;
+      before: string;
+      after: string;
+      // Names of the constants the values equal; see value-aliases.ts
+      beforeAlias?: string;
+      afterAlias?: string;
+      // before came from a simulation override, not the chain
+      beforeFromOverride?: boolean;
+      history?: SlotWrite[];
+      description: string;
+    }
+  | {
+      kind: 'balance';
+      contract: string;
+      address: string;
+      before: string;
+      after: string;
+      description: string;
+    }
+  | { kind: 'warning'; code: string; severity: string; message: string };
+
+export type ReviewOutcome =
+  | { confirmed: true; reviewed: number }
+  | { confirmed: false; reviewed: number; reason: string };
+
+const AckSchema = z.object({ ack: z.boolean(), reason: z.string().optional() }).strict();
+
+function parseAck(line: string): z.infer<typeof AckSchema> | null {
+  try {
+    const parsed = AckSchema.safeParse(JSON.parse(line));
+    return parsed.success ? parsed.data : null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * What a signer reviews, in the order the validation page shows it: the hashes, the overrides,
+ * the state and balance changes, and then the report's warnings.
+ */
+export function reviewItems(config: TaskConfig): ReviewItem[] {
+  const { address, domainHash, messageHash } = config.expectedDomainAndMessageHashes;
+  return [
+    {
+      kind: 'hashes',
+      safe: address,
+      domainHash,
+      messageHash,
+      safeTxHash: computeSafeTxHash(domainHash, messageHash),
+    },
+    ...config.stateOverrides.flatMap(o =>
+      o.overrides.map(v => ({
+        kind: 'override' as const,
+        contract: o.name,
+        address: o.address,
+        key: v.key,
+        value: v.value,
+        description: v.description,
+      }))
+    ),
+    ...config.stateChanges.flatMap(sc =>
+      sc.changes.map(c => ({
+        kind: 'change' as const,
+        contract: sc.name,
+        address: sc.address,
+        key: c.key,
+        before: c.before,
+        after: c.after,
+        ...(c.beforeAlias && { beforeAlias: c.beforeAlias }),
+        ...(c.afterAlias && { afterAlias: c.afterAlias }),
+        ...(c.beforeFromOverride && { beforeFromOverride: true }),
+        ...(c.history && { history: c.history }),
+        description: c.description,
+      }))
+    ),
+    ...(config.balanceChanges ?? []).map(b => ({
+      kind: 'balance' as const,
+      contract: b.name,
+      address: b.address,
+      before: b.before,
+      after: b.after,
+      description: b.description,
+    })),
+    ...(config.warnings ?? []).map(w => ({
+      kind: 'warning' as const,
+      code: w.code,
+      severity: w.severity,
+      message: w.message,
+    })),
+  ];
+}
+
+/**
+ * Writes each item with `write` and reads the wrapper's answer from `input`, one line each.
+ * Blank lines are skipped; anything that is not an ack object ends the review as rejected.
+ */
+export async function runConfirmProtocol(
+  items: readonly ReviewItem[],
+  input: AsyncIterable<string>,
+  write: (line: string) => void,
+  contentHash: string
+): Promise<ReviewOutcome> {
+  const lines = input[Symbol.asyncIterator]();
+  const reject = (reviewed: number, reason: string): ReviewOutcome => {
+    write(JSON.stringify({ type: 'rejected', reviewed, reason }));
+    return { confirmed: false, reviewed, reason };
+  };
+
+  for (const [index, item] of items.entries()) {
+    write(JSON.stringify({ type: 'item', index, total: items.length, item }));
+    let answer: string | undefined;
+    while (answer === undefined) {
+      const next = await lines.next();
+      if (next.done) return reject(index, 'stdin closed before the item was acknowledged');
+      if (next.value.trim()) answer = next.value;
+    }
+    const ack = parseAck(answer);
+    if (!ack) return reject(index, `expected {"ack": true|false}, got ${answer}`);
+    if (!ack.ack) return reject(index, ack.reason ?? `item ${index} was not acknowledged`);
+  }
+  write(JSON.stringify({ type: 'done', reviewed: items.length, contentHash }));
+  return { confirmed: true, reviewed: items.length };
+}
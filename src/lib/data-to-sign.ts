@@ -0,0 +1,151 @@
+import { getTypesForEIP712Domain, hashDomain, hashStruct, Hex, TypedDataDomain } from 'viem';
+
+const EIP712_PREFIX = '1901';
+const HASH_BYTES = 32;
+
+// How dataToSign was supplied: 0x1901 || domainHash || messageHash, the bare 64-byte
+// domainHash || messageHash, or EIP-712 typed-data JSON hashed here
+export const DATA_TO_SIGN_FORMS = ['eip712-encoded', 'hash-pair', 'typed-data'] as const;
+export type DataToSignForm = (typeof DATA_TO_SIGN_FORMS)[number];
+
+export type DataToSignOptions = {
+  // Require the exact 0x1901 || domainHash || messageHash encoding
+  strict?: boolean;
+};
+
+/**
+ * Splits EIP-712 data to sign into its domain and message hashes.
+ *
+ * By default surrounding whitespace is ignored and the 0x1901 prefix is optional, so the bare
+ * 64-byte domainHash || messageHash form also parses. Strict mode only accepts the exact
+ * 66-byte encoding. Errors report byte offsets into the decoded data (after 0x).
+ */
+export function parseDataToSign(
+  input: string,
+  options: DataToSignOptions = {}
+): { domainHash: Hex; messageHash: Hex } {
+  const strict = options.strict ?? false;
+  const raw = strict ? input : input.trim();
+
+  if (!raw.startsWith('0x')) {
+    throw new Error('dataToSign must be 0x-prefixed hex');
+  }
+  const hex = raw.slice(2);
+
+  const badChar = hex.search(/[^0-9a-fA-F]/);
+  if (badChar !== -1) {
+    throw new Error(
+      `dataToSign has non-hex character '${hex[badChar]}' at byte offset ${Math.floor(badChar / 2)}`
+    );
+  }
+  if (hex.length % 2 !== 0) {
+    throw new Error(`dataToSign has an odd number of hex digits (${hex.length})`);
+  }
+
+  const byteLength = hex.length / 2;
+  let body: string;
+  if (byteLength === 2 + 2 * HASH_BYTES) {
+    const prefix = hex.slice(0, 4);
+    if (prefix !== EIP712_PREFIX) {
+      throw new Error(
+        `dataToSign bytes 0-1 are 0x${prefix}, expected the EIP-712 prefix 0x${EIP712_PREFIX}`
+      );
+    }
+    body = hex.slice(4);
+  } else if (byteLength === 2 * HASH_BYTES && !strict) {
+    body = hex;
+  } else {
+    throw new Error(
+      strict
+        ? `dataToSign must be 66 bytes (0x1901 + 32-byte domain hash + 32-byte message hash), got ${byteLength} bytes`
+        : `dataToSign must be 66 bytes (0x1901 + domain hash + message hash) or 64 bytes (domain hash + message hash), got ${byteLength} bytes`
+    );
+  }
+
+  return {
+    domainHash: `0x${body.slice(0, 2 * HASH_BYTES)}`,
+    messageHash: `0x${body.slice(2 * HASH_BYTES)}`,
+  };
+}
+
+type TypedDataInput = {
+  types: Record<string, { name: string; type: string }[]>;
+  primaryType: string;
+  domain: TypedDataDomain;
+  message: Record<string, unknown>;
+};
+
+function parseTypedData(input: unknown): TypedDataInput {
+  if (!input || typeof input !== 'object') {
+    throw new Error('dataToSign typed data must be a JSON object');
+  }
+  const { types, primaryType, domain, message } = input as Record<string, unknown>;
+  if (!types || typeof types !== 'object') {
+    throw new Error('dataToSign typed data has no types');
+  }
+  if (typeof primaryType !== 'string' || !(primaryType in types)) {
+    throw new Error(`dataToSign typed data primaryType ${String(primaryType)} is not in types`);
+  }
+  if (primaryType === 'EIP712Domain') {
+    throw new Error('dataToSign typed data must have a message type other than EIP712Domain');
+  }
+  if (!domain || typeof domain !== 'object' || !message || typeof message !== 'object') {
+    throw new Error('dataToSign typed data needs a domain and a message object');
+  }
+  return input as TypedDataInput;
+}
+
+/**
+ * Domain and message hashes of EIP-712 typed data, e.g. the SafeTx a Safe transaction builder
+ * exports. EIP712Domain is derived from the domain's fields when `types` does not declare it.
+ */
+export function hashTypedDataParts(input: unknown): { domainHash: Hex; messageHash: Hex } {
+  const { types, primaryType, domain, message } = parseTypedData(input);
+  const allTypes = {
+    EIP712Domain: getTypesForEIP712Domain({ domain }),
+    ...types,
+  };
+  try {
+    return {
+      domainHash: hashDomain({ domain, types: allTypes }),
+      messageHash: hashStruct({ data: message, primaryType, types: allTypes }),
+    };
+  } catch (err) {
+    const reason = err instanceof Error ? err.message : String(err);
+    throw new Error(`dataToSign typed data could not be hashed: ${reason}`, { cause: err });
+  }
+}
+
+/**
+ * Accepts dataToSign in any of the shapes simulation scripts emit and returns the hashes with
+ * the form that was supplied. JSON (a string starting with `{`, or an object) is hashed as
+ * EIP-712 typed data; anything else goes through parseDataToSign. Strict mode only accepts the
+ * 0x1901-prefixed encoding.
+ */
+export function normalizeDataToSign(
+  input: string | object,
+  options: DataToSignOptions = {}
+): { domainHash: Hex; messageHash: Hex; form: DataToSignForm } {
+  const isJson = typeof input === 'object' || input.trim().startsWith('{');
+  if (isJson && options.strict) {
+    throw new Error('dataToSign must be 0x1901-prefixed hex in strict mode, got typed-data JSON');
+  }
+  if (typeof input === 'object') {
+    return { ...hashTypedDataParts(input), form: 'typed-data' };
+  }
+  if (isJson) {
+    let parsed: unknown;
+    try {
+      parsed = JSON.parse(input);
+    } catch (err) {
+      const reason = err instanceof Error ? err.message : String(err);
+      throw new Error(`dataToSign looks like JSON but does not parse: ${reason}`, { cause: err });
+    }
+    return { ...hashTypedDataParts(parsed), form: 'typed-data' };
+  }
+
+  const hashes = parseDataToSign(input, options);
+  // parseDataToSign only accepts the prefixed 66 bytes or the bare 64
+  const byteLength = (input.trim().length - 2) / 2;
+  return { ...hashes, form: byteLength === 2 * HASH_BYTES ? 'hash-pair' : 'eip712-encoded' };
+}
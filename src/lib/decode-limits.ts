@@ -0,0 +1,100 @@
+import { DecodeError, PolicyViolationError } from './errors';
+import type { EncodedStateDiff } from './simulation-artifact';
+
+/**
+ * Upper bounds on what a forge run may hand to the decoder. A buggy or malicious task script
+ * can write a state diff large enough to exhaust the signer's memory, so oversized input is
+ * refused before it is read or decoded.
+ */
+export type DecodeLimits = {
+  // Size of stateDiff.json, and of its hex fields together
+  maxDiffBytes: number;
+  // Account accesses in the encoded state diff
+  maxAccesses: number;
+  // Mapping preimages
+  maxPreimages: number;
+};
+
+export const DEFAULT_DECODE_LIMITS: DecodeLimits = {
+  maxDiffBytes: 64 * 1024 * 1024,
+  maxAccesses: 20000,
+  maxPreimages: 200000,
+};
+
+const SIZE_UNITS: Record<string, number> = {
+  '': 1,
+  b: 1,
+  kb: 1024,
+  kib: 1024,
+  mb: 1024 * 1024,
+  mib: 1024 * 1024,
+  gb: 1024 * 1024 * 1024,
+  gib: 1024 * 1024 * 1024,
+};
+
+/**
+ * Parses sizes such as `512KB`, `64MiB`, or a plain byte count. Units are powers of 1024.
+ */
+export function parseByteSize(value: string, flag: string): number {
+  const m = value.trim().match(/^(\d+)\s*([a-zA-Z]*)$/);
+  const unit = m ? SIZE_UNITS[m[2].toLowerCase()] : undefined;
+  if (!m || unit === undefined || Number(m[1]) === 0) {
+    throw new Error(`${flag} must be a size such as 64MB or 1048576`);
+  }
+  return Number(m[1]) * unit;
+}
+
+function refuse(what: string, actual: number, limit: number, flag: string): never {
+  throw new PolicyViolationError(
+    `${what} is ${actual}, over the limit of ${limit}. Raise it with ${flag}, or pass --force if the task really needs it`
+  );
+}
+
+/**
+ * Checks the size of a file holding an encoded state diff before it is read into memory.
+ */
+export function checkDiffFileSize(label: string, bytes: number, limits: DecodeLimits): void {
+  if (bytes > limits.maxDiffBytes) {
+    refuse(`Size of ${label} in bytes`, bytes, limits.maxDiffBytes, '--max-diff-size');
+  }
+}
+
+/**
+ * Reads the length of an ABI-encoded top-level dynamic array from its head, without decoding
+ * the elements.
+ */
+export function encodedArrayLength(encoded: string): number {
+  const body = encoded.replace(/^0x/, '');
+  const word = (byteOffset: number): bigint => {
+    const chunk = body.slice(byteOffset * 2, byteOffset * 2 + 64);
+    if (chunk.length !== 64) {
+      throw new DecodeError('Encoded array is truncated before its length');
+    }
+    return BigInt('0x' + chunk);
+  };
+  const offset = word(0);
+  if (offset > BigInt(body.length / 2)) {
+    throw new DecodeError('Encoded array offset points past the end of the data');
+  }
+  const length = word(Number(offset));
+  return length > BigInt(Number.MAX_SAFE_INTEGER) ? Number.MAX_SAFE_INTEGER : Number(length);
+}
+
+/**
+ * Checks an encoded state diff against the limits before it is decoded.
+ */
+export function checkEncodedStateDiff(encoded: EncodedStateDiff, limits: DecodeLimits): void {
+  const hexBytes =
+    (encoded.stateDiff.length + encoded.preimages.length + encoded.overrides.length) / 2;
+  if (hexBytes > limits.maxDiffBytes) {
+    refuse('Encoded state diff size in bytes', hexBytes, limits.maxDiffBytes, '--max-diff-size');
+  }
+  const accesses = encodedArrayLength(encoded.stateDiff);
+  if (accesses > limits.maxAccesses) {
+    refuse('Number of account accesses', accesses, limits.maxAccesses, '--max-accesses');
+  }
+  const preimages = encodedArrayLength(encoded.preimages);
+  if (preimages > limits.maxPreimages) {
+    refuse('Number of mapping preimages', preimages, limits.maxPreimages, '--max-preimages');
+  }
+}
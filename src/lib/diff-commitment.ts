@@ -0,0 +1,210 @@
+import { z } from 'zod';
+import { concat, encodeAbiParameters, getAddress, Hex, keccak256, zeroHash } from 'viem';
+import { AddressSchema, describeZodIssues, HashSchema } from './config-schemas';
+import { computeSafeTxHash } from './safe-hash';
+import type { TaskConfig } from './types/index';
+
+/**
+ * A compact commitment to a report's state changes, for a verifier contract or service that
+ * attests the executed transaction changed exactly what was approved. Each change is a leaf
+ * over (address, slot, before, after), and the commitment is
+ * `abi.encode(uint256 chainId, bytes32 safeTxHash, bytes32 root, uint256 leafCount)`.
+ * Leaves are hashed as OpenZeppelin's StandardMerkleTree does,
+ * `keccak256(bytes.concat(keccak256(abi.encode(address, bytes32, bytes32, bytes32))))`, and
+ * pairs are hashed sorted, so the proofs in the proof file check with `MerkleProof.verify`.
+ */
+
+export const DIFF_COMMITMENT_VERSION = 1;
+
+export const DIFF_LEAF_ENCODING =
+  'keccak256(keccak256(abi.encode(address,bytes32,bytes32,bytes32)))';
+
+export type DiffLeaf = { address: string; slot: Hex; before: Hex; after: Hex };
+
+const DiffProofFileSchema = z.object({
+  version: z.literal(DIFF_COMMITMENT_VERSION),
+  leafEncoding: z.literal(DIFF_LEAF_ENCODING),
+  chainId: z.number().int().positive(),
+  safe: AddressSchema,
+  safeTxHash: HashSchema,
+  root: HashSchema,
+  leafCount: z.number().int().nonnegative(),
+  // abi.encode(chainId, safeTxHash, root, leafCount), what a verifier contract stores
+  commitment: z.string().regex(/^0x[0-9a-fA-F]+$/),
+  leaves: z.array(
+    z.object({
+      address: AddressSchema,
+      slot: HashSchema,
+      before: HashSchema,
+      after: HashSchema,
+      leaf: HashSchema,
+      proof: z.array(HashSchema),
+    })
+  ),
+});
+
+export type DiffProofFile = z.infer<typeof DiffProofFileSchema>;
+
+export function parseDiffProofFile(raw: unknown): DiffProofFile {
+  const parsed = DiffProofFileSchema.safeParse(raw);
+  if (!parsed.success) {
+    throw new Error(`Invalid diff proof file: ${describeZodIssues(parsed.error)}`);
+  }
+  return parsed.data;
+}
+
+// The report's state changes as leaves, sorted by address and then slot
+export function diffLeaves(config: Pick<TaskConfig, 'stateChanges'>): DiffLeaf[] {
+  return config.stateChanges
+    .flatMap(sc =>
+      sc.changes.map(c => ({
+        address: getAddress(sc.address),
+        slot: c.key.toLowerCase() as Hex,
+        before: c.before.toLowerCase() as Hex,
+        after: c.after.toLowerCase() as Hex,
+      }))
+    )
+    .sort(
+      (a, b) =>
+        a.address.toLowerCase().localeCompare(b.address.toLowerCase()) ||
+        a.slot.localeCompare(b.slot)
+    );
+}
+
+export function diffLeafHash(leaf: DiffLeaf): Hex {
+  const encoded = encodeAbiParameters(
+    [{ type: 'address' }, { type: 'bytes32' }, { type: 'bytes32' }, { type: 'bytes32' }],
+    [getAddress(leaf.address), leaf.slot, leaf.before, leaf.after]
+  );
+  return keccak256(keccak256(encoded));
+}
+
+// Hashes a pair in sorted order, as MerkleProof does; lowercase words compare as numbers
+function hashPair(a: Hex, b: Hex): Hex {
+  return keccak256(a < b ? concat([a, b]) : concat([b, a]));
+}
+
+/**
+ * The root of `leaves` and each leaf's proof. A level with an odd node carries it up unpaired.
+ * An empty diff has the zero root, so a verifier can still tell it apart from a missing one.
+ */
+export function merkleTree(leaves: readonly Hex[]): { root: Hex; proofs: Hex[][] } {
+  if (leaves.length === 0) return { root: zeroHash, proofs: [] };
+  const proofs: Hex[][] = leaves.map(() => []);
+  // Position of each leaf's ancestor in the current level
+  const positions = leaves.map((_, i) => i);
+  let level = [...leaves];
+  while (level.length > 1) {
+    const next: Hex[] = [];
+    for (let i = 0; i < level.length; i += 2) {
+      next.push(i + 1 < level.length ? hashPair(level[i], level[i + 1]) : level[i]);
+    }
+    positions.forEach((position, leaf) => {
+      const sibling = position ^ 1;
+      if (sibling < level.length) proofs[leaf].push(level[sibling]);
+      positions[leaf] = position >> 1;
+    });
+    level = next;
+  }
+  return { root: level[0], proofs };
+}
+
+// What MerkleProof.processProof computes
+export function processProof(leaf: Hex, proof: readonly Hex[]): Hex {
+  return proof.reduce<Hex>((node, sibling) => hashPair(node, sibling.toLowerCase() as Hex), leaf);
+}
+
+export function encodeDiffCommitment(params: {
+  chainId: number;
+  safeTxHash: Hex;
+  root: Hex;
+  leafCount: number;
+}): Hex {
+  return encodeAbiParameters(
+    [{ type: 'uint256' }, { type: 'bytes32' }, { type: 'bytes32' }, { type: 'uint256' }],
+    [BigInt(params.chainId), params.safeTxHash, params.root, BigInt(params.leafCount)]
+  );
+}
+
+/**
+ * Builds the commitment and proof file for a validation file. The chain is part of the
+ * commitment, so files written before genValidationFile recorded chainId are refused.
+ */
+export function buildDiffProof(config: TaskConfig): DiffProofFile {
+  if (config.chainId === undefined) {
+    throw new Error('The validation file has no chainId; regenerate it to commit to its diff');
+  }
+  const { address, domainHash, messageHash } = config.expectedDomainAndMessageHashes;
+  const safeTxHash = computeSafeTxHash(domainHash, messageHash);
+  const leaves = diffLeaves(config);
+  const hashes = leaves.map(diffLeafHash);
+  const { root, proofs } = merkleTree(hashes);
+  return {
+    version: DIFF_COMMITMENT_VERSION,
+    leafEncoding: DIFF_LEAF_ENCODING,
+    chainId: config.chainId,
+    safe: getAddress(address),
+    safeTxHash,
+    root,
+    leafCount: leaves.length,
+    commitment: encodeDiffCommitment({
+      chainId: config.chainId,
+      safeTxHash,
+      root,
+      leafCount: leaves.length,
+    }),
+    leaves: leaves.map((leaf, i) => ({ ...leaf, leaf: hashes[i], proof: proofs[i] })),
+  };
+}
+
+/**
+ * Checks a proof file on its own: every leaf hashes to its recorded value and proves into the
+ * root, and the commitment encodes the root. With `executed`, the validation file of the
+ * executed transaction, its changes must be exactly the committed ones.
+ */
+export function verifyDiffProof(file: DiffProofFile, executed?: TaskConfig): string[] {
+  const errors: string[] = [];
+  for (const [i, leaf] of file.leaves.entries()) {
+    const hash = diffLeafHash(leaf as DiffLeaf);
+    if (hash !== leaf.leaf.toLowerCase()) {
+      errors.push(`leaf ${i} (${leaf.address} ${leaf.slot}) does not hash to ${leaf.leaf}`);
+    } else if (processProof(hash, leaf.proof as Hex[]) !== file.root.toLowerCase()) {
+      errors.push(`leaf ${i} (${leaf.address} ${leaf.slot}) does not prove into the root`);
+    }
+  }
+  if (file.leaves.length !== file.leafCount) {
+    errors.push(`${file.leaves.length} leaves are listed, but leafCount is ${file.leafCount}`);
+  }
+  const commitment = encodeDiffCommitment({
+    chainId: file.chainId,
+    safeTxHash: file.safeTxHash as Hex,
+    root: file.root as Hex,
+    leafCount: file.leafCount,
+  });
+  if (commitment !== file.commitment.toLowerCase()) {
+    errors.push('commitment does not encode the chain, safeTxHash, root, and leaf count');
+  }
+  if (!executed) return errors;
+
+  const actual = buildDiffProof(executed);
+  if (actual.chainId !== file.chainId) {
+    errors.push(`the transaction ran on chain ${actual.chainId}, not ${file.chainId}`);
+  }
+  if (actual.safeTxHash !== file.safeTxHash.toLowerCase()) {
+    errors.push(`the transaction's safeTxHash is ${actual.safeTxHash}, not ${file.safeTxHash}`);
+  }
+  const committed = new Set(file.leaves.map(l => l.leaf.toLowerCase()));
+  const found = new Set(actual.leaves.map(l => l.leaf));
+  for (const leaf of actual.leaves) {
+    if (!committed.has(leaf.leaf)) {
+      const change = `${leaf.address} slot ${leaf.slot} changed ${leaf.before} -> ${leaf.after}`;
+      errors.push(`${change}, which was not approved`);
+    }
+  }
+  for (const leaf of file.leaves) {
+    if (!found.has(leaf.leaf.toLowerCase())) {
+      errors.push(`the approved change of ${leaf.address} slot ${leaf.slot} did not happen`);
+    }
+  }
+  return errors;
+}
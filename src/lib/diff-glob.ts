@@ -0,0 +1,88 @@
+import { readdirSync } from 'fs';
+import { Address, encodeAbiParameters } from 'viem';
+import {
+  decodePreimages,
+  decodeStateDiff,
+  PREIMAGES_PARAMS,
+  STATE_DIFF_PARAMS,
+} from './state-diff-encoding';
+import { PolicyViolationError } from './errors';
+import type { SimulationArtifact } from './simulation-artifact';
+
+// How the diffs matched by --diff-glob become validation files
+export const DIFF_MODES = ['separate', 'merge'] as const;
+export type DiffMode = (typeof DIFF_MODES)[number];
+
+export type MatchedDiff = { file: string; artifact: SimulationArtifact };
+
+// `*` matches any run of characters and `?` one character; everything else is literal
+function globToRegExp(pattern: string): RegExp {
+  const source = pattern
+    .split('')
+    .map(c => (c === '*' ? '.*' : c === '?' ? '.' : c.replace(/[\\^$.|+()[\]{}]/g, '\\$&')))
+    .join('');
+  return new RegExp(`^${source}$`);
+}
+
+/**
+ * Names of the files in `dir` that match `pattern`, such as `diff-*.json`. Only file names are
+ * matched, so the pattern cannot reach other directories. Numbers sort by value, so diff-2.json
+ * comes before diff-10.json.
+ */
+export function matchDiffGlob(dir: string, pattern: string): string[] {
+  if (pattern.includes('/') || pattern.includes('\\')) {
+    throw new PolicyViolationError(`--diff-glob matches file names only, got ${pattern}`);
+  }
+  const regex = globToRegExp(pattern);
+  return readdirSync(dir, { withFileTypes: true })
+    .filter(entry => entry.isFile() && regex.test(entry.name))
+    .map(entry => entry.name)
+    .sort((a, b) => a.localeCompare(b, 'en', { numeric: true }));
+}
+
+/**
+ * Joins the diffs of one transaction, simulated in parts, into a single artifact. Account
+ * accesses and preimages are concatenated in file order, so a slot written in several diffs
+ * reports its first before and last after value. Every diff must sign the same data for the
+ * same Safe with the same call and overrides; the command is the first diff's.
+ */
+export function mergeSimulationArtifacts(diffs: readonly MatchedDiff[]): SimulationArtifact {
+  if (diffs.length === 0) throw new Error('No diffs to merge');
+  const [first, ...rest] = diffs;
+  const base = first.artifact.stateDiff;
+  for (const { file, artifact } of rest) {
+    const { targetSafe, dataToSign, overrides } = artifact.stateDiff;
+    const differs = [
+      targetSafe.toLowerCase() !== base.targetSafe.toLowerCase() ? 'targetSafe' : null,
+      dataToSign !== base.dataToSign ? 'dataToSign' : null,
+      overrides.toLowerCase() !== base.overrides.toLowerCase() ? 'overrides' : null,
+    ].filter(Boolean);
+    if (differs.length > 0) {
+      throw new PolicyViolationError(
+        `${file} cannot be merged with ${first.file}: ${differs.join(', ')} differ; use --diff-mode separate`
+      );
+    }
+  }
+
+  const accesses = diffs.flatMap(d => decodeStateDiff(d.artifact.stateDiff.stateDiff));
+  const preimages = diffs.flatMap(d => decodePreimages(d.artifact.stateDiff.preimages));
+  return {
+    version: 1,
+    cmd: first.artifact.cmd,
+    forgeOutput: diffs.map(d => d.artifact.forgeOutput).join('\n'),
+    ...(first.artifact.prestateFrom ? { prestateFrom: first.artifact.prestateFrom } : {}),
+    ...(first.artifact.simulationEnv ? { simulationEnv: first.artifact.simulationEnv } : {}),
+    stateDiff: {
+      ...base,
+      stateDiff: encodeAbiParameters(STATE_DIFF_PARAMS, [
+        accesses.map(a => ({
+          ...a,
+          account: a.account as Address,
+          accessor: a.accessor as Address,
+          storageAccesses: a.storageAccesses.map(s => ({ ...s, account: s.account as Address })),
+        })),
+      ]),
+      preimages: encodeAbiParameters(PREIMAGES_PARAMS, [preimages]),
+    },
+  };
+}
@@ -0,0 +1,16 @@
+import { encodeAbiParameters, Hex, keccak256, toBytes, toHex } from 'viem';
+
+/**
+ * ERC-7201 storage root for a namespace id:
+ * keccak256(abi.encode(uint256(keccak256(id)) - 1)) & ~bytes32(uint256(0xff))
+ */
+export function erc7201Root(namespace: string): bigint {
+  const inner = BigInt(keccak256(toBytes(namespace))) - BigInt(1);
+  const outer = BigInt(keccak256(encodeAbiParameters([{ type: 'uint256' }], [inner])));
+  return outer & ~BigInt(0xff);
+}
+
+/** Slot of the struct member `offset` slots past the namespace root, as a 32-byte hex key. */
+export function erc7201Slot(namespace: string, offset: number | bigint = 0): Hex {
+  return toHex(erc7201Root(namespace) + BigInt(offset), { size: 32 });
+}
@@ -0,0 +1,63 @@
+import { BaseError, HttpRequestError, RpcRequestError, TimeoutError } from 'viem';
+
+/**
+ * Process exit codes for the CLI scripts. Wrapper scripts and CI can branch on these instead of
+ * matching stderr. Flag and usage errors exit with `failure`.
+ */
+export const EXIT_CODES = {
+  success: 0,
+  failure: 1,
+  simulationFailed: 3,
+  decode: 4,
+  rpc: 5,
+  policyViolation: 6,
+} as const;
+
+export type ExitCode = (typeof EXIT_CODES)[keyof typeof EXIT_CODES];
+
+abstract class ClassifiedError extends Error {
+  abstract readonly exitCode: ExitCode;
+
+  constructor(message: string, options?: { cause?: unknown }) {
+    super(message, options);
+    this.name = new.target.name;
+  }
+}
+
+// The forge script failed, produced no stateDiff.json, or the simulated call reverted
+export class SimulationFailedError extends ClassifiedError {
+  readonly exitCode = EXIT_CODES.simulationFailed;
+}
+
+// An input could not be parsed: encoded blobs, dataToSign, traces, or simulation payloads
+export class DecodeError extends ClassifiedError {
+  readonly exitCode = EXIT_CODES.decode;
+}
+
+// The RPC endpoint was unreachable or rejected a request
+export class RpcError extends ClassifiedError {
+  readonly exitCode = EXIT_CODES.rpc;
+}
+
+// The run was refused on purpose, e.g. a path outside the allowed directory
+export class PolicyViolationError extends ClassifiedError {
+  readonly exitCode = EXIT_CODES.policyViolation;
+}
+
+function isRpcFailure(err: unknown): boolean {
+  if (!(err instanceof BaseError)) return false;
+  const transportError = err.walk(
+    e => e instanceof HttpRequestError || e instanceof RpcRequestError || e instanceof TimeoutError
+  );
+  return transportError !== null;
+}
+
+/**
+ * Exit code for an error that reached a script's top level. viem transport errors are treated
+ * as RPC failures even when they were not wrapped in an RpcError.
+ */
+export function exitCodeFor(err: unknown): ExitCode {
+  if (err instanceof ClassifiedError) return err.exitCode;
+  if (isRpcFailure(err)) return EXIT_CODES.rpc;
+  return EXIT_CODES.failure;
+}
@@ -0,0 +1,62 @@
+import type { AccountChange } from './account-aggregation';
+import { AccountAccessKind, VmSafeAccountAccess } from './vm-safe';
+
+export type ValueTransfer = {
+  // Lowercased; null when a balance change has no value transfer to explain it, as in traces
+  // that carry only balances
+  from: string | null;
+  to: string | null;
+  amount: bigint;
+};
+
+// Access kinds whose value moves from the accessor to the account
+const VALUE_KINDS = new Set([
+  AccountAccessKind.Call,
+  AccountAccessKind.Create,
+  AccountAccessKind.SelfDestruct,
+]);
+
+/**
+ * ETH the transaction moves. Non-reverted calls, creations, and self-destructs that carry value
+ * are summed per sender and receiver, in the order each pair first appears. What remains of an
+ * account's balance change once those transfers are accounted for is listed with only a
+ * receiver (a gain) or only a sender (a loss), so every balance change is explained by the list.
+ */
+export function findEthTransfers(
+  decoded: readonly VmSafeAccountAccess[],
+  accounts: Map<string, AccountChange>
+): ValueTransfer[] {
+  const transfers = new Map<string, ValueTransfer>();
+  const net = new Map<string, bigint>();
+  const add = (address: string, amount: bigint) =>
+    net.set(address, (net.get(address) ?? BigInt(0)) + amount);
+
+  for (const access of decoded) {
+    if (access.reverted || !VALUE_KINDS.has(access.kind) || access.value === BigInt(0)) continue;
+    const from = access.accessor.toLowerCase();
+    const to = access.account.toLowerCase();
+    if (from === to) continue;
+    const id = `${from}:${to}`;
+    const transfer = transfers.get(id) ?? { from, to, amount: BigInt(0) };
+    transfer.amount += access.value;
+    transfers.set(id, transfer);
+    add(from, -access.value);
+    add(to, access.value);
+  }
+
+  const unexplained: ValueTransfer[] = [];
+  for (const [address, { balance }] of accounts) {
+    const rest = balance.after - balance.before - (net.get(address) ?? BigInt(0));
+    if (rest > BigInt(0)) unexplained.push({ from: null, to: address, amount: rest });
+    if (rest < BigInt(0)) unexplained.push({ from: address, to: null, amount: -rest });
+  }
+  const addressOf = (t: ValueTransfer) => t.to ?? t.from ?? '';
+  unexplained.sort((a, b) => addressOf(a).localeCompare(addressOf(b)));
+  return [...transfers.values(), ...unexplained];
+}
+
+export function describeEthTransfer(transfer: ValueTransfer): string {
+  if (!transfer.from) return `${transfer.to} gains ${transfer.amount} wei not sent by any call`;
+  if (!transfer.to) return `${transfer.from} loses ${transfer.amount} wei not sent by any call`;
+  return `${transfer.from} sends ${transfer.amount} wei to ${transfer.to}`;
+}
@@ -0,0 +1,143 @@
+import {
+  Address,
+  BaseError,
+  decodeAbiParameters,
+  Hex,
+  hexToBigInt,
+  HttpRequestError,
+  isAddressEqual,
+  isHex,
+  PublicClient,
+  TimeoutError,
+  toHex,
+} from 'viem';
+import { RpcError } from './errors';
+import { decodeExecTransaction } from './safe-nonce';
+import { rpcStateOverrides } from './simulate-v1';
+import { PayloadDecoded } from './state-diff-encoding';
+import type { ExecutionCheck } from './types';
+
+const ERROR_SELECTOR = '0x08c379a0';
+const PANIC_SELECTOR = '0x4e487b71';
+
+// Safe reverts with short codes; these are the ones a signer is likely to hit
+const SAFE_ERROR_CODES: Record<string, string> = {
+  GS010: 'not enough gas to execute the Safe transaction',
+  GS013: 'the Safe transaction failed and safeTxGas and gasPrice are 0',
+  GS020: 'signatures data too short',
+  GS025: 'hash has not been approved',
+  GS026: 'invalid owner provided',
+};
+
+/**
+ * Human-readable revert reason: Error(string) messages (with Safe's GSxxx codes spelled out),
+ * Panic(uint256) codes, or the raw data of a custom error.
+ */
+export function decodeRevertReason(data: Hex): string {
+  if (data === '0x') return 'reverted without data';
+  const selector = data.slice(0, 10).toLowerCase();
+  const args = `0x${data.slice(10)}` as Hex;
+  try {
+    if (selector === ERROR_SELECTOR) {
+      const [message] = decodeAbiParameters([{ type: 'string' }], args);
+      const safeError = SAFE_ERROR_CODES[message];
+      return safeError ? `${message}: ${safeError}` : message;
+    }
+    if (selector === PANIC_SELECTOR) {
+      const [code] = decodeAbiParameters([{ type: 'uint256' }], args);
+      return `panic 0x${code.toString(16)}`;
+    }
+  } catch {
+    // Malformed Error/Panic payloads are reported raw below
+  }
+  return `custom error ${data}`;
+}
+
+function revertDataOf(err: BaseError): Hex | undefined {
+  const inner = err.walk() as { data?: unknown };
+  // Some transports nest the revert data one level down
+  const data =
+    inner.data && typeof inner.data === 'object'
+      ? (inner.data as { data?: unknown }).data
+      : inner.data;
+  return typeof data === 'string' && isHex(data) ? data : undefined;
+}
+
+/**
+ * Status of a successful call. execTransaction on the signing Safe returns false instead of
+ * reverting when the inner call fails and safeTxGas or gasPrice is set.
+ */
+export function callStatus(
+  payload: PayloadDecoded,
+  safe: Address,
+  returnData: Hex
+): ExecutionCheck {
+  const isExecTransaction =
+    isAddressEqual(payload.to, safe) && decodeExecTransaction(payload.data) !== null;
+  if (isExecTransaction && returnData.length === 66 && hexToBigInt(returnData) === BigInt(0)) {
+    return {
+      status: 'safe-execution-failed',
+      revertReason: 'execTransaction returned false; the Safe emitted ExecutionFailure',
+    };
+  }
+  return { status: 'success' };
+}
+
+/**
+ * Replays the simulated call against the node with the payload's state overrides applied, to
+ * learn whether it would succeed on-chain, the gas it needs, and why it reverts if it does.
+ * `blockNumber` is the block the simulation forked from; the head when not given.
+ */
+export async function checkExecution(
+  client: PublicClient,
+  payload: PayloadDecoded,
+  safe: Address,
+  blockNumber?: bigint
+): Promise<ExecutionCheck> {
+  // State override sets are not in viem's typed request schema for every method
+  const request = client.request as (args: {
+    method: string;
+    params?: unknown;
+  }) => Promise<unknown>;
+  const tx = { from: payload.from, to: payload.to, data: payload.data };
+  const overrides = rpcStateOverrides(payload);
+  const block = blockNumber === undefined ? 'latest' : toHex(blockNumber);
+
+  let returnData: Hex;
+  try {
+    returnData = (await request({ method: 'eth_call', params: [tx, block, overrides] })) as Hex;
+  } catch (err) {
+    const transportError =
+      err instanceof BaseError &&
+      err.walk(e => e instanceof HttpRequestError || e instanceof TimeoutError) !== null;
+    if (!(err instanceof BaseError) || transportError) {
+      const message = err instanceof Error ? err.message : String(err);
+      throw new RpcError(`eth_call to check execution failed: ${message}`, { cause: err });
+    }
+    const data = revertDataOf(err);
+    return { status: 'reverted', revertReason: data ? decodeRevertReason(data) : err.shortMessage };
+  }
+
+  const status = callStatus(payload, safe, returnData);
+  if (status.status !== 'success') return status;
+  try {
+    const gas = await request({ method: 'eth_estimateGas', params: [tx, block, overrides] });
+    return { ...status, estimatedGas: hexToBigInt(gas as Hex).toString() };
+  } catch {
+    // Some nodes do not accept state overrides for eth_estimateGas; the status still stands
+    return status;
+  }
+}
+
+export function describeExecutionCheck(check: ExecutionCheck): string {
+  switch (check.status) {
+    case 'success':
+      return check.estimatedGas
+        ? `The transaction executes successfully (estimated gas ${check.estimatedGas})`
+        : 'The transaction executes successfully';
+    case 'reverted':
+      return `The transaction reverts on-chain: ${check.revertReason}`;
+    case 'safe-execution-failed':
+      return `The Safe transaction fails on-chain: ${check.revertReason}`;
+  }
+}
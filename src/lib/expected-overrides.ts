@@ -0,0 +1,115 @@
+import { promises as fs } from 'fs';
+import { Hex, isHex, pad } from 'viem';
+import { parse as parseYaml } from 'yaml';
+import { z } from 'zod';
+import { AddressSchema, describeZodIssues } from './config-schemas';
+import type { StateOverride } from './types/index';
+
+// Read from the task's config/<network> directory by the validation page
+export const EXPECTED_OVERRIDES_FILE = 'expected-overrides.yaml';
+
+// Slots and values may be written short (0x4); they are compared as 32-byte words
+const WordSchema = z
+  .string()
+  .refine(value => isHex(value) && value.length <= 66, {
+    message: 'Expected hex of 32 bytes or less',
+  })
+  .transform(value => pad(value.toLowerCase() as Hex, { size: 32 }));
+
+const ExpectedOverridesSchema = z.object({
+  overrides: z.array(
+    z.object({
+      address: AddressSchema,
+      key: WordSchema,
+      value: WordSchema,
+      // Why the simulation needs it, e.g. "threshold overridden to 1 for simulation"
+      reason: z.string().min(1),
+    })
+  ),
+});
+
+export type ExpectedOverride = z.infer<typeof ExpectedOverridesSchema>['overrides'][number];
+
+export type OverrideDiscrepancy =
+  | { kind: 'missing'; expected: ExpectedOverride }
+  | { kind: 'mismatch'; expected: ExpectedOverride; actual: Hex }
+  | { kind: 'undocumented'; address: string; key: Hex; value: Hex };
+
+export function parseExpectedOverrides(text: string): ExpectedOverride[] {
+  // failsafe keeps every scalar a string; the default schema reads 0x4 as the number 4
+  const parsed = ExpectedOverridesSchema.safeParse(parseYaml(text, { schema: 'failsafe' }));
+  if (!parsed.success) {
+    const issues = describeZodIssues(parsed.error);
+    throw new Error(`Invalid ${EXPECTED_OVERRIDES_FILE}: ${issues}`);
+  }
+  return parsed.data.overrides;
+}
+
+/**
+ * Reads a task's expected overrides, or null when the task does not declare any.
+ */
+export async function readExpectedOverrides(file: string): Promise<ExpectedOverride[] | null> {
+  let text: string;
+  try {
+    text = await fs.readFile(file, 'utf-8');
+  } catch (error: unknown) {
+    if (error instanceof Error && 'code' in error && error.code === 'ENOENT') return null;
+    throw error;
+  }
+  return parseExpectedOverrides(text);
+}
+
+const slotId = (address: string, key: string) => `${address.toLowerCase()}:${key.toLowerCase()}`;
+
+/**
+ * Compares the decoded state overrides with the ones a task documents. Every documented
+ * override must be present with exactly its value, and any override the task does not document
+ * is reported: an unexplained override can hide what the transaction really does.
+ */
+export function checkExpectedOverrides(
+  actual: StateOverride[],
+  expected: ExpectedOverride[]
+): OverrideDiscrepancy[] {
+  const actualValues = new Map<string, Hex>();
+  for (const o of actual) {
+    for (const override of o.overrides) {
+      actualValues.set(slotId(o.address, override.key), override.value.toLowerCase() as Hex);
+    }
+  }
+
+  const discrepancies: OverrideDiscrepancy[] = [];
+  const documented = new Set<string>();
+  for (const e of expected) {
+    const id = slotId(e.address, e.key);
+    documented.add(id);
+    const value = actualValues.get(id);
+    if (value === undefined) {
+      discrepancies.push({ kind: 'missing', expected: e });
+    } else if (value !== e.value) {
+      discrepancies.push({ kind: 'mismatch', expected: e, actual: value });
+    }
+  }
+  for (const o of actual) {
+    for (const override of o.overrides) {
+      if (documented.has(slotId(o.address, override.key))) continue;
+      discrepancies.push({
+        kind: 'undocumented',
+        address: o.address,
+        key: override.key as Hex,
+        value: override.value as Hex,
+      });
+    }
+  }
+  return discrepancies;
+}
+
+export function describeOverrideDiscrepancy(d: OverrideDiscrepancy): string {
+  switch (d.kind) {
+    case 'missing':
+      return `Expected override ${d.expected.address} ${d.expected.key} (${d.expected.reason}) is not applied`;
+    case 'mismatch':
+      return `Override ${d.expected.address} ${d.expected.key} is ${d.actual}, but ${EXPECTED_OVERRIDES_FILE} expects ${d.expected.value} (${d.expected.reason})`;
+    case 'undocumented':
+      return `Override ${d.address} ${d.key} = ${d.value} is not documented in ${EXPECTED_OVERRIDES_FILE}`;
+  }
+}
@@ -0,0 +1,49 @@
+import contractsCfg from './config/contracts.json';
+
+export type ExplorerType = 'etherscan' | 'blockscout' | 'custom';
+
+export type ExplorerConfig = {
+  type: ExplorerType;
+  url: string;
+  // Path templates for custom explorers. {address} and {hash} are substituted.
+  addressPath?: string;
+  txPath?: string;
+};
+
+// Etherscan and Blockscout share the same URL layout for addresses and transactions.
+const DEFAULT_PATHS: Record<Exclude<ExplorerType, 'custom'>, { address: string; tx: string }> = {
+  etherscan: { address: '/address/{address}', tx: '/tx/{hash}' },
+  blockscout: { address: '/address/{address}', tx: '/tx/{hash}' },
+};
+
+const explorers = (contractsCfg as unknown as { explorers?: Record<string, ExplorerConfig> })
+  .explorers;
+
+export function getExplorerConfig(chainId: string): ExplorerConfig | undefined {
+  return explorers?.[chainId.trim()];
+}
+
+function buildUrl(explorer: ExplorerConfig, kind: 'address' | 'tx', value: string): string {
+  const template =
+    explorer.type === 'custom'
+      ? kind === 'address'
+        ? explorer.addressPath
+        : explorer.txPath
+      : DEFAULT_PATHS[explorer.type][kind];
+  if (!template) {
+    throw new Error(`Explorer ${explorer.url} has no ${kind} path template configured`);
+  }
+
+  const pathPart = template.replace('{address}', value).replace('{hash}', value);
+  return `${explorer.url.replace(/\/+$/, '')}${pathPart.startsWith('/') ? '' : '/'}${pathPart}`;
+}
+
+export function explorerAddressUrl(chainId: string, address: string): string | undefined {
+  const explorer = getExplorerConfig(chainId);
+  return explorer ? buildUrl(explorer, 'address', address) : undefined;
+}
+
+export function explorerTxUrl(chainId: string, txHash: string): string | undefined {
+  const explorer = getExplorerConfig(chainId);
+  return explorer ? buildUrl(explorer, 'tx', txHash) : undefined;
+}
@@ -0,0 +1,165 @@
+import type { Hex } from 'viem';
+import type { ParentPreimage } from './state-diff-encoding';
+import { AccountAccessKind, VmSafeAccountAccess } from './vm-safe';
+
+/**
+ * Everything one contract did during a simulation, in execution order, for `--focus`: each call
+ * that reached it, made from it, or touched its storage, with every read and write of its slots.
+ * The report only shows net changes; this shows how they came about.
+ */
+
+export type FocusStorageAccess = {
+  slot: Hex;
+  isWrite: boolean;
+  previousValue: Hex;
+  newValue: Hex;
+  reverted: boolean;
+  // Set when the slot is a mapping or array entry whose preimage forge recorded
+  preimage?: { parent: Hex; key: Hex };
+};
+
+export type FocusFrame = {
+  // Position of the access in the stateDiff.json trace
+  index: number;
+  depth: number;
+  kind: string;
+  accessor: string;
+  account: string;
+  selector: Hex | null;
+  value: bigint;
+  reverted: boolean;
+  storage: FocusStorageAccess[];
+};
+
+export type FocusSlotChange = {
+  slot: Hex;
+  before: Hex;
+  after: Hex;
+  // Writes that were not reverted
+  writes: number;
+};
+
+export type FocusTrace = {
+  contract: string;
+  frames: FocusFrame[];
+  // Every slot written, including ones that end where they started, which the report omits
+  changes: FocusSlotChange[];
+};
+
+function word(hex: string): Hex {
+  return `0x${hex.toLowerCase().replace(/^0x/, '').padStart(64, '0')}` as Hex;
+}
+
+export function buildFocusTrace(
+  accesses: readonly VmSafeAccountAccess[],
+  contract: string,
+  preimages: readonly ParentPreimage[] = []
+): FocusTrace {
+  const focus = contract.toLowerCase();
+  const parents = new Map(preimages.map(p => [word(p.slot), p]));
+
+  const frames: FocusFrame[] = [];
+  for (const [index, access] of accesses.entries()) {
+    // A DELEGATECALL into a library touches the caller's storage, so storage is matched on
+    // its own account rather than the access's
+    const storage = access.storageAccesses
+      .filter(s => s.account.toLowerCase() === focus)
+      .map(s => {
+        const preimage = parents.get(word(s.slot));
+        return {
+          slot: word(s.slot),
+          isWrite: s.isWrite,
+          previousValue: word(s.previousValue),
+          newValue: word(s.newValue),
+          reverted: s.reverted || access.reverted,
+          ...(preimage && { preimage: { parent: word(preimage.parent), key: preimage.key } }),
+        };
+      });
+    const involved =
+      access.account.toLowerCase() === focus || access.accessor.toLowerCase() === focus;
+    if (!involved && storage.length === 0) continue;
+    frames.push({
+      index,
+      depth: Number(access.depth),
+      kind: AccountAccessKind[access.kind] ?? `kind ${access.kind}`,
+      accessor: access.accessor,
+      account: access.account,
+      selector: access.data.length >= 10 ? (access.data.slice(0, 10) as Hex) : null,
+      value: access.value,
+      reverted: access.reverted,
+      storage,
+    });
+  }
+
+  const changes = new Map<Hex, FocusSlotChange>();
+  for (const s of frames.flatMap(f => f.storage)) {
+    if (!s.isWrite) continue;
+    let change = changes.get(s.slot);
+    if (!change) {
+      change = { slot: s.slot, before: s.previousValue, after: s.previousValue, writes: 0 };
+      changes.set(s.slot, change);
+    }
+    if (s.reverted) continue;
+    change.after = s.newValue;
+    change.writes += 1;
+  }
+  return { contract, frames, changes: [...changes.values()] };
+}
+
+function describeSlot(access: FocusStorageAccess): string {
+  if (!access.preimage) return access.slot;
+  return `${access.slot} (key ${access.preimage.key} of slot ${access.preimage.parent})`;
+}
+
+export function formatFocusTrace(trace: FocusTrace): string[] {
+  const accessCount = trace.frames.reduce((n, f) => n + f.storage.length, 0);
+  const lines = [
+    `🔬 ${trace.contract}: ${trace.frames.length} call(s), ${accessCount} storage access(es), ${trace.changes.length} slot(s) written`,
+  ];
+  if (trace.frames.length === 0) {
+    lines.push('   The simulation never called this contract or touched its storage');
+    return lines;
+  }
+
+  for (const frame of trace.frames) {
+    const indent = '   ' + '  '.repeat(Math.max(frame.depth, 0));
+    const selector = frame.selector ? ` ${frame.selector}` : '';
+    const value = frame.value > BigInt(0) ? ` value ${frame.value}` : '';
+    const reverted = frame.reverted ? ' [reverted]' : '';
+    lines.push(
+      `${indent}#${frame.index} ${frame.kind} ${frame.accessor} -> ${frame.account}${selector}${value}${reverted}`
+    );
+    for (const s of frame.storage) {
+      const note = s.reverted ? ' [reverted]' : '';
+      lines.push(
+        s.isWrite
+          ? `${indent}  SSTORE ${describeSlot(s)}: ${s.previousValue} -> ${s.newValue}${note}`
+          : `${indent}  SLOAD  ${describeSlot(s)} = ${s.previousValue}${note}`
+      );
+    }
+  }
+
+  if (trace.changes.length > 0) {
+    lines.push('   Net changes:');
+    for (const change of trace.changes) {
+      let outcome = `${change.before} -> ${change.after}`;
+      if (change.writes === 0) {
+        outcome = `${change.before} (only written in reverted calls)`;
+      } else if (change.before === change.after) {
+        outcome = `${change.before} (${change.writes} write(s), ends where it started)`;
+      }
+      lines.push(`     ${change.slot}: ${outcome}`);
+    }
+  }
+  return lines;
+}
+
+/**
+ * Lines of a forge -vvvvv trace that mention the contract, with the tree drawing that shows
+ * where each sits in the call stack. Contracts the script labelled appear under their label
+ * and are not found.
+ */
+export function forgeTraceLines(forgeOutput: string, contract: string): string[] {
+  const focus = contract.toLowerCase();
+  return forgeOutput.split('\n').filter(line => line.toLowerCase().includes(focus));
+}
@@ -0,0 +1,82 @@
+import { getAddress } from 'viem';
+import type { TaskConfig } from './types/index';
+
+/**
+ * Solidity checks of the post-state a validation file pins, for task scripts
+ * (genValidationFile.ts --emit-foundry-assertions). Authors paste the library into their forge
+ * script and call it after the task executes, so a script change that alters the state diff
+ * fails the simulation as well as validation. Each changed slot becomes a `vm.load` compared
+ * with its `after` value through `vm.assertEq`, which scripts and tests both have.
+ *
+ * Slots that may differ at execution time (`allowDifference`) are listed as comments instead,
+ * and so are balance changes, since gas payments move them. Overrides are not asserted: they
+ * only exist in the simulation.
+ */
+
+export const FOUNDRY_ASSERTIONS_LIBRARY = 'StateDiffAssertions';
+
+// Single line, so a description cannot end the comment it is written in
+function comment(text: string): string {
+  return text.replace(/\s+/g, ' ').trim();
+}
+
+function solidityString(text: string): string {
+  return `"${text.replace(/[\\"]/g, c => `\\${c}`).replace(/[^\x20-\x7e]/g, '?')}"`;
+}
+
+export function buildFoundryAssertions(
+  config: Pick<
+    TaskConfig,
+    'chainId' | 'chainName' | 'expectedDomainAndMessageHashes' | 'stateChanges' | 'balanceChanges'
+  >,
+  task?: string
+): string {
+  const { address: safe, safeTxHash } = config.expectedDomainAndMessageHashes;
+  const body: string[] = [];
+  for (const sc of config.stateChanges) {
+    const address = getAddress(sc.address);
+    if (body.length > 0) body.push('');
+    body.push(`        // ${comment(sc.name)} (${address})`);
+    for (const change of sc.changes) {
+      body.push(`        // ${comment(change.description)}`);
+      if (change.allowDifference) {
+        body.push(`        // Not asserted, may differ at execution: slot ${change.key}`);
+        continue;
+      }
+      body.push(
+        `        vm.assertEq(vm.load(${address}, bytes32(${change.key})), bytes32(${change.after}), ${solidityString(`${sc.name} slot ${change.key}`)});`
+      );
+    }
+  }
+  const balances = config.balanceChanges ?? [];
+  if (balances.length > 0) {
+    if (body.length > 0) body.push('');
+    body.push('        // Balance changes, not asserted since gas payments move them:');
+    for (const b of balances) {
+      body.push(`        // ${comment(b.name)} (${getAddress(b.address)}): ${b.before} -> ${b.after}`);
+    }
+  }
+
+  const chain =
+    config.chainId !== undefined
+      ? `${config.chainName ? `${config.chainName} ` : ''}(chain ${config.chainId})`
+      : 'an unknown chain';
+  return [
+    '// SPDX-License-Identifier: MIT',
+    'pragma solidity ^0.8.0;',
+    '',
+    'import {Vm} from "forge-std/Vm.sol";',
+    '',
+    `// Post-state of ${task ? `${task} ` : 'the task '}on ${chain}, as simulated by genValidationFile.ts`,
+    `// Safe ${safe}${safeTxHash ? `, SafeTx hash ${safeTxHash}` : ''}`,
+    `// Call ${FOUNDRY_ASSERTIONS_LIBRARY}.check() after the task executes.`,
+    `library ${FOUNDRY_ASSERTIONS_LIBRARY} {`,
+    '    Vm private constant vm = Vm(address(uint160(uint256(keccak256("hevm cheat code")))));',
+    '',
+    '    function check() internal view {',
+    ...body,
+    '    }',
+    '}',
+    '',
+  ].join('\n');
+}
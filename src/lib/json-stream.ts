@@ -0,0 +1,77 @@
+import { createWriteStream } from 'fs';
+import { Readable } from 'stream';
+import { pipeline } from 'stream/promises';
+
+// Chunks are batched up to this size before being handed to the stream.
+const FLUSH_BYTES = 64 * 1024;
+
+function isSkipped(value: unknown): boolean {
+  return value === undefined || typeof value === 'function' || typeof value === 'symbol';
+}
+
+function* chunks(value: unknown, key: string, indent: string, pad: string): Generator<string> {
+  if (value !== null && typeof value === 'object' && 'toJSON' in value) {
+    const toJSON = (value as { toJSON: unknown }).toJSON;
+    if (typeof toJSON === 'function') value = toJSON.call(value, key);
+  }
+  if (value === null || typeof value !== 'object') {
+    // JSON.stringify handles scalars, including throwing on bigint
+    yield JSON.stringify(value) ?? 'null';
+    return;
+  }
+
+  const inner = pad + indent;
+  if (Array.isArray(value)) {
+    if (value.length === 0) {
+      yield '[]';
+      return;
+    }
+    yield '[';
+    for (let i = 0; i < value.length; i++) {
+      yield `${i === 0 ? '' : ','}\n${inner}`;
+      yield* chunks(isSkipped(value[i]) ? null : value[i], String(i), indent, inner);
+    }
+    yield `\n${pad}]`;
+    return;
+  }
+
+  let first = true;
+  for (const [k, v] of Object.entries(value)) {
+    if (isSkipped(v)) continue;
+    yield `${first ? '{' : ','}\n${inner}${JSON.stringify(k)}: `;
+    yield* chunks(v, k, indent, inner);
+    first = false;
+  }
+  yield first ? '{}' : `\n${pad}}`;
+}
+
+/**
+ * Yields the JSON text of `value` piece by piece. Concatenated, the pieces equal
+ * `JSON.stringify(value, null, indent)`, without ever holding the whole document as one string.
+ */
+export function jsonChunks(value: unknown, indent = 2): Generator<string> {
+  return chunks(value, '', ' '.repeat(indent), '');
+}
+
+function* batched(pieces: Iterable<string>): Generator<string> {
+  let buffer = '';
+  for (const piece of pieces) {
+    buffer += piece;
+    if (buffer.length >= FLUSH_BYTES) {
+      yield buffer;
+      buffer = '';
+    }
+  }
+  yield buffer + '\n';
+}
+
+/**
+ * Writes `value` as indented JSON without building the whole document in memory. Intended for
+ * validation files that carry large traces or calldata.
+ */
+export async function writeJsonFile(file: string, value: unknown, indent = 2): Promise<void> {
+  await pipeline(
+    Readable.from(batched(jsonChunks(value, indent))),
+    createWriteStream(file, { encoding: 'utf-8' })
+  );
+}
@@ -0,0 +1,150 @@
+import { existsSync, statSync } from 'fs';
+import path from 'path';
+
+/**
+ * Task repositories following the OP Foundation conventions (superchain-ops) drive each task
+ * with a justfile: `just simulate <safe>` runs the forge script for a signer group, and `just
+ * sign` signs with a Ledger. genValidationFile.ts --use-just runs the simulate recipe instead of
+ * a raw --forge-cmd, so facilitators and signers run the same entry point. The recipe is
+ * recorded as the file's cmd, the environment variables it reads with env_var() are checked
+ * before the run, so a missing one fails with its name rather than halfway through the recipe,
+ * and the file is written to the workdir's validations directory unless --out is given.
+ */
+
+// File names just looks for, in its search order
+export const JUSTFILE_NAMES = ['justfile', 'Justfile', '.justfile'] as const;
+
+// Recipes the task conventions define
+export const JUST_TARGETS = ['simulate', 'sign'] as const;
+export type JustTarget = (typeof JUST_TARGETS)[number];
+
+export type JustParameter = {
+  name: string;
+  // No default and not `*variadic`, so an argument must be given
+  required: boolean;
+  variadic: boolean;
+};
+
+export type JustRecipe = {
+  name: string;
+  parameters: JustParameter[];
+  // Read with env_var('NAME'), which fails when the variable is unset
+  requiredEnv: string[];
+};
+
+const SETTING = /^(set|alias|export|import|mod)\s/;
+const NAME = /^[A-Za-z_][A-Za-z0-9_-]*/;
+const ENV_VAR = /\benv_var\(\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]\s*\)/g;
+
+export function findJustfile(workdir: string): string | null {
+  for (const name of JUSTFILE_NAMES) {
+    const file = path.join(workdir, name);
+    if (existsSync(file) && statSync(file).isFile()) return file;
+  }
+  return null;
+}
+
+// Splits a recipe header's parameters on whitespace outside quotes, up to the closing colon
+function splitHeader(rest: string): { params: string[]; complete: boolean } {
+  const params: string[] = [];
+  let current = '';
+  let quote: string | null = null;
+  for (const c of rest) {
+    if (quote) {
+      current += c;
+      if (c === quote) quote = null;
+    } else if (c === "'" || c === '"') {
+      current += c;
+      quote = c;
+    } else if (c === ':') {
+      if (current) params.push(current);
+      return { params, complete: true };
+    } else if (/\s/.test(c)) {
+      if (current) params.push(current);
+      current = '';
+    } else {
+      current += c;
+    }
+  }
+  return { params, complete: false };
+}
+
+function parseParameter(token: string): JustParameter {
+  const variadic = token.startsWith('+') || token.startsWith('*');
+  const name = token.replace(/^[+*]?\$?/, '').split('=')[0];
+  return { name, required: !token.includes('=') && !token.startsWith('*'), variadic };
+}
+
+/**
+ * The recipes of a justfile with their parameters and the variables they read with env_var().
+ * Only what --use-just needs is understood; a variable the justfile itself reads with env_var()
+ * outside any recipe counts for every recipe, since just evaluates it on every run.
+ */
+export function parseJustRecipes(text: string): JustRecipe[] {
+  const recipes: JustRecipe[] = [];
+  const globalEnv = new Set<string>();
+  let current: JustRecipe | null = null;
+  for (const line of text.split(/\r?\n/)) {
+    // Blank lines do not end a recipe
+    if (!line.trim()) continue;
+    if (/^\s/.test(line)) {
+      if (current) for (const match of line.matchAll(ENV_VAR)) current.requiredEnv.push(match[1]);
+      continue;
+    }
+    current = null;
+    const trimmed = line.trim();
+    if (trimmed.startsWith('#') || trimmed.startsWith('[') || SETTING.test(trimmed)) {
+      continue;
+    }
+    const header = trimmed.replace(/^@/, '');
+    const name = NAME.exec(header)?.[0];
+    const rest = name ? header.slice(name.length) : '';
+    // An assignment such as `rpc := env_var('ETH_RPC_URL')`
+    if (!name || /^\s*:=/.test(rest)) {
+      for (const match of line.matchAll(ENV_VAR)) globalEnv.add(match[1]);
+      continue;
+    }
+    const { params, complete } = splitHeader(rest);
+    if (!complete) continue;
+    current = { name, parameters: params.map(parseParameter), requiredEnv: [] };
+    for (const match of line.matchAll(ENV_VAR)) current.requiredEnv.push(match[1]);
+    recipes.push(current);
+  }
+  for (const recipe of recipes) {
+    recipe.requiredEnv = [...new Set([...globalEnv, ...recipe.requiredEnv])].sort();
+  }
+  return recipes;
+}
+
+/**
+ * The command that runs `recipe` with `args`, refusing too few or too many arguments.
+ */
+export function buildJustCommand(recipe: JustRecipe, args: readonly string[]): string[] {
+  const required = recipe.parameters.filter(p => p.required).length;
+  const variadic = recipe.parameters.some(p => p.variadic);
+  if (args.length < required || (!variadic && args.length > recipe.parameters.length)) {
+    const names = recipe.parameters.map(p => (p.required ? p.name : `[${p.name}]`)).join(' ');
+    throw new Error(
+      `just ${recipe.name} takes ${names ? `<${names}>` : 'no arguments'}; pass them with --just-args`
+    );
+  }
+  return ['just', recipe.name, ...args];
+}
+
+/**
+ * Where --use-just writes the validation file when --out is not given: the workdir's
+ * validations directory, named after the recipe arguments, so `just simulate foundation`
+ * writes validations/foundation.json.
+ */
+export function justValidationPath(workdir: string, args: readonly string[]): string {
+  const name = args.length > 0 ? args.join('-').replace(/[^A-Za-z0-9._-]/g, '_') : 'simulate';
+  return path.join(workdir, 'validations', `${name}.json`);
+}
+
+// Variables the recipe reads with env_var() that `env` does not set
+export function missingJustEnv(
+  recipe: JustRecipe,
+  env: Readonly<Record<string, string | undefined>>
+): string[] {
+  return recipe.requiredEnv.filter(name => env[name] === undefined);
+}
@@ -0,0 +1,19 @@
+const tails = new Map<string, Promise<unknown>>();
+
+/**
+ * Serializes async work per key within this process. Calls with different keys run
+ * concurrently; calls with the same key run one at a time in arrival order.
+ */
+export async function withKeyedLock<T>(key: string, fn: () => Promise<T>): Promise<T> {
+  const previous = tails.get(key) ?? Promise.resolve();
+  const run = previous.then(fn, fn);
+  const tail = run.catch(() => undefined);
+  tails.set(key, tail);
+
+  try {
+    return await run;
+  } finally {
+    // Drop the entry once the queue for this key drains so the map does not grow unbounded.
+    if (tails.get(key) === tail) tails.delete(key);
+  }
+}
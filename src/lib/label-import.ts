@@ -0,0 +1,180 @@
+import { normalizeConfigAddress } from './config-addresses';
+
+/**
+ * Seeds contract names from address-label exports (`state-diff config import-labels`), so a
+ * team can name hundreds of addresses at once instead of writing contracts.json entries by
+ * hand. Two CSV shapes are read: Etherscan name-tag exports (an address and a name tag column,
+ * for one chain given with --chain-id) and Dune address books (a blockchain, address, and name
+ * column, as in labels.addresses). The result is a contracts.json overlay with a name per
+ * address; slots are still described in contracts.json by hand.
+ */
+
+export const LABEL_SOURCES = ['etherscan', 'dune'] as const;
+export type LabelSource = (typeof LABEL_SOURCES)[number];
+
+export type ImportedLabel = { chainId: string; address: string; name: string };
+
+export type LabelImport = {
+  source: LabelSource;
+  labels: ImportedLabel[];
+  // Rows left out, with the reason, e.g. a chain the tool does not know
+  skipped: { row: number; reason: string }[];
+};
+
+// Dune's blockchain names for the chains in contracts.json
+export const DUNE_BLOCKCHAINS: Readonly<Record<string, string>> = {
+  ethereum: '1',
+  sepolia: '11155111',
+  hoodi: '560048',
+  base: '8453',
+  base_sepolia: '84532',
+};
+
+// Header names of the label column, in order of preference
+const ETHERSCAN_NAME_COLUMNS = ['name tag', 'private name tag', 'nametag', 'name', 'label'];
+const DUNE_NAME_COLUMNS = ['name', 'label'];
+
+/**
+ * Splits RFC 4180 CSV into rows: quoted fields may hold commas, newlines, and doubled quotes.
+ * A leading byte order mark, as Excel writes, is dropped, and blank lines are skipped.
+ */
+export function parseCsv(text: string): string[][] {
+  const rows: string[][] = [];
+  let row: string[] = [];
+  let field = '';
+  let quoted = false;
+  const input = text.replace(/^\uFEFF/, '');
+  for (let i = 0; i < input.length; i++) {
+    const c = input[i];
+    if (quoted) {
+      if (c === '"' && input[i + 1] === '"') {
+        field += '"';
+        i++;
+      } else if (c === '"') {
+        quoted = false;
+      } else {
+        field += c;
+      }
+    } else if (c === '"') {
+      quoted = true;
+    } else if (c === ',') {
+      row.push(field);
+      field = '';
+    } else if (c === '\n' || c === '\r') {
+      if (c === '\r' && input[i + 1] === '\n') i++;
+      row.push(field);
+      if (row.some(f => f.trim())) rows.push(row);
+      row = [];
+      field = '';
+    } else {
+      field += c;
+    }
+  }
+  if (quoted) throw new Error('CSV ends inside a quoted field');
+  row.push(field);
+  if (row.some(f => f.trim())) rows.push(row);
+  return rows;
+}
+
+function columnOf(header: readonly string[], names: readonly string[]): number {
+  for (const name of names) {
+    const index = header.indexOf(name);
+    if (index !== -1) return index;
+  }
+  return -1;
+}
+
+// Dune exports name the chain of every row; Etherscan exports are for one chain
+export function detectLabelSource(header: readonly string[]): LabelSource {
+  const columns = header.map(h => h.trim().toLowerCase());
+  if (!columns.includes('address')) throw new Error('The CSV has no address column');
+  if (columns.includes('blockchain')) return 'dune';
+  if (columnOf(columns, ETHERSCAN_NAME_COLUMNS) !== -1) return 'etherscan';
+  throw new Error(
+    `The CSV has no name column; expected one of: ${ETHERSCAN_NAME_COLUMNS.join(', ')}`
+  );
+}
+
+/**
+ * Reads the labels of an Etherscan or Dune CSV export. `chainId` is required for Etherscan
+ * exports and narrows a Dune export to one chain. An address labeled more than once keeps its
+ * first label, as address books often list several, and the others are reported as skipped.
+ * A malformed address fails the import with its row number rather than being dropped.
+ */
+export function parseLabelCsv(text: string, options: { chainId?: string } = {}): LabelImport {
+  const [header, ...rows] = parseCsv(text);
+  if (!header) throw new Error('The CSV is empty');
+  const source = detectLabelSource(header);
+  const columns = header.map(h => h.trim().toLowerCase());
+  const addressColumn = columns.indexOf('address');
+  const nameColumn = columnOf(
+    columns,
+    source === 'dune' ? DUNE_NAME_COLUMNS : ETHERSCAN_NAME_COLUMNS
+  );
+  const chainColumn = columns.indexOf('blockchain');
+  if (nameColumn === -1) throw new Error(`The ${source} CSV has no name column`);
+  if (source === 'etherscan' && !options.chainId) {
+    throw new Error('Etherscan exports do not name their chain; pass --chain-id');
+  }
+
+  const labels: ImportedLabel[] = [];
+  const skipped: LabelImport['skipped'] = [];
+  const seen = new Set<string>();
+  rows.forEach((fields, i) => {
+    // Row numbers as a spreadsheet shows them, counting the header
+    const row = i + 2;
+    const name = (fields[nameColumn] ?? '').trim().replace(/\s+/g, ' ');
+    if (!name) {
+      skipped.push({ row, reason: 'no name' });
+      return;
+    }
+    let chainId = options.chainId;
+    if (source === 'dune') {
+      const blockchain = (fields[chainColumn] ?? '').trim().toLowerCase();
+      const rowChain = Object.hasOwn(DUNE_BLOCKCHAINS, blockchain)
+        ? DUNE_BLOCKCHAINS[blockchain]
+        : undefined;
+      if (!rowChain) {
+        skipped.push({ row, reason: `unknown blockchain "${blockchain}"` });
+        return;
+      }
+      if (chainId && rowChain !== chainId) return;
+      chainId = rowChain;
+    }
+    const address = normalizeConfigAddress(fields[addressColumn] ?? '', `row ${row}`);
+    const key = `${chainId}:${address}`;
+    if (seen.has(key)) {
+      skipped.push({ row, reason: `${address} is already labeled` });
+      return;
+    }
+    seen.add(key);
+    labels.push({ chainId: chainId!, address, name });
+  });
+  return { source, labels, skipped };
+}
+
+/**
+ * The labels as a contracts.json overlay of named contracts. Addresses contracts.json already
+ * configures are left out, since an overlay entry replaces the embedded one with its slots.
+ */
+export function labelsToOverlay(
+  labels: readonly ImportedLabel[],
+  configured: Readonly<Record<string, Readonly<Record<string, unknown>>>>
+): { overlay: { contracts: Record<string, Record<string, { name: string }>> }; known: number } {
+  const contracts: Record<string, Record<string, { name: string }>> = {};
+  const existing = new Set(
+    Object.entries(configured).flatMap(([chainId, entries]) =>
+      Object.keys(entries).map(a => `${chainId}:${a.toLowerCase()}`)
+    )
+  );
+  let known = 0;
+  for (const label of labels) {
+    if (existing.has(`${label.chainId}:${label.address}`)) {
+      known++;
+      continue;
+    }
+    contracts[label.chainId] ??= {};
+    contracts[label.chainId][label.address] = { name: label.name };
+  }
+  return { overlay: { contracts }, known };
+}
@@ -0,0 +1,79 @@
+import { formatUnits } from 'viem';
+import { FORMAT_UNITS, SlotFormatHint } from './number-format';
+
+/**
+ * Numbers, dates, and durations for documents people read, such as the VALIDATION.md export,
+ * written the way a signer's locale writes them (`stateDiff.ts export --locale de-DE` gives
+ * 1.234.567,5 and 16. Oktober 2026). Only the prose is localized: hashes, words, and wei stay
+ * in their canonical form next to it, and JSON output never changes. Dates are in UTC, so
+ * signers in different time zones read the same time.
+ */
+
+export const DEFAULT_REPORT_LOCALE = 'en-US';
+
+// Refuses tags that are not BCP 47 and locales the runtime has no data for
+export function resolveLocale(value: string): string {
+  let canonical: string | undefined;
+  try {
+    [canonical] = Intl.getCanonicalLocales(value.trim());
+  } catch {
+    canonical = undefined;
+  }
+  if (!canonical) {
+    throw new Error(`"${value}" is not a locale; use a BCP 47 tag such as en-US or de-DE`);
+  }
+  if (Intl.NumberFormat.supportedLocalesOf(canonical).length === 0) {
+    throw new Error(`Locale ${canonical} is not available in this Node.js build`);
+  }
+  return canonical;
+}
+
+/**
+ * A decimal string, such as formatUnits returns, with the locale's grouping and decimal
+ * separator. The integer part is formatted as a bigint, so no digits are lost to floats.
+ */
+export function localeDecimal(value: string, locale: string): string {
+  const format = new Intl.NumberFormat(locale, { maximumFractionDigits: 0 });
+  const [whole, fraction] = value.split('.');
+  const decimal =
+    new Intl.NumberFormat(locale).formatToParts(0.5).find(p => p.type === 'decimal')?.value ?? '.';
+  const grouped = format.format(BigInt(whole));
+  if (!fraction) return grouped;
+  return `${grouped}${decimal}${fraction.replace(/\d/g, d => format.format(Number(d)))}`;
+}
+
+// formatAmount with the locale's separators
+export function localeAmount(value: bigint, locale: string, hint?: SlotFormatHint): string {
+  const { decimals, unit } = FORMAT_UNITS[hint?.format ?? 'number'];
+  return `${localeDecimal(formatUnits(value, hint?.decimals ?? decimals), locale)}${unit}`;
+}
+
+export function localeDateTime(date: Date, locale: string): string {
+  return new Intl.DateTimeFormat(locale, {
+    dateStyle: 'long',
+    timeStyle: 'long',
+    timeZone: 'UTC',
+  }).format(date);
+}
+
+/**
+ * A duration as the locale's list of its days, hours, and minutes, e.g. "3 days" or
+ * "1 Tag und 12 Stunden". Seconds are dropped, and anything shorter than a minute is 0 minutes.
+ */
+export function localeDuration(ms: number, locale: string): string {
+  const minutes = Math.floor(ms / 60000);
+  const parts = [
+    { unit: 'day', value: Math.floor(minutes / 1440) },
+    { unit: 'hour', value: Math.floor((minutes % 1440) / 60) },
+    { unit: 'minute', value: minutes % 60 },
+  ].filter(p => p.value > 0);
+  const shown = parts.length > 0 ? parts : [{ unit: 'minute', value: 0 }];
+  const list = new Intl.ListFormat(locale, { style: 'long', type: 'conjunction' });
+  return list.format(
+    shown.map(p =>
+      new Intl.NumberFormat(locale, { style: 'unit', unit: p.unit, unitDisplay: 'long' }).format(
+        p.value
+      )
+    )
+  );
+}
@@ -0,0 +1,53 @@
+import { Hex, numberToHex } from 'viem';
+
+// Slot config keys such as `balances[*]` or `approvedHashes[*][*]` describe every member of a
+// mapping family instead of one slot. The number of `[*]` is how many keys deep the member is.
+const PATTERN = /^([A-Za-z_][A-Za-z0-9_]*)((?:\[\*\])+)$/;
+
+export type MappingPattern = { name: string; depth: number };
+
+export function parseMappingPattern(key: string): MappingPattern | null {
+  const m = key.match(PATTERN);
+  if (!m) return null;
+  return { name: m[1], depth: m[2].length / 3 };
+}
+
+/**
+ * Lookup key for the pattern whose mapping is declared at `baseSlot` and whose members sit
+ * `depth` keys below it.
+ */
+export function mappingKey(baseSlot: Hex, depth: number): string {
+  return `${baseSlot}/${depth}`;
+}
+
+function parseBaseSlot(value: unknown, where: string): Hex {
+  if (typeof value === 'string' && /^0x[0-9a-fA-F]{1,64}$/.test(value)) {
+    return ('0x' + value.slice(2).toLowerCase().padStart(64, '0')) as Hex;
+  }
+  if (typeof value === 'string' && /^\d+$/.test(value)) {
+    return numberToHex(BigInt(value), { size: 32 });
+  }
+  throw new Error(`${where}: baseSlot must be a slot number or hex value`);
+}
+
+/**
+ * Separates mapping patterns from plain slot entries. Patterns are returned keyed by
+ * `mappingKey(baseSlot, depth)`.
+ */
+export function splitMappingPatterns<T extends { baseSlot?: string }>(
+  entries: Record<string, T>,
+  where: string
+): { slots: Record<string, T>; mappings: Record<string, T> } {
+  const slots: Record<string, T> = {};
+  const mappings: Record<string, T> = {};
+  for (const [key, value] of Object.entries(entries)) {
+    const pattern = parseMappingPattern(key);
+    if (!pattern) {
+      slots[key] = value;
+      continue;
+    }
+    const baseSlot = parseBaseSlot(value.baseSlot, `${where} ${key}`);
+    mappings[mappingKey(baseSlot, pattern.depth)] = value;
+  }
+  return { slots, mappings };
+}
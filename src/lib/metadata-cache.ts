@@ -0,0 +1,113 @@
+import { promises as fs } from 'fs';
+import path from 'path';
+import { Address, Hex } from 'viem';
+import { withKeyedLock } from './keyed-lock';
+
+// Default directory for the cache; caching is off when neither it nor --metadata-cache is set
+export const METADATA_CACHE_ENV = 'STATE_DIFF_METADATA_CACHE';
+export const DEFAULT_METADATA_CACHE_TTL_HOURS = 24;
+
+export type MetadataCacheOptions = {
+  dir: string;
+  ttlHours: number;
+  // Ignore cached entries and fetch everything again (the fresh results are still saved)
+  refresh: boolean;
+};
+
+/**
+ * What the RPC lookups found out about a contract missing from contracts.json.
+ */
+export type ContractMetadata = {
+  // keccak256 of the contract's own code; null when it has none
+  codeHash: Hex | null;
+  // EIP-1967 implementation, when the slot is set
+  implementation: Address | null;
+  // Names of the built-in knownPatterns the contract matched
+  patterns: string[];
+  // The matches found by code hash; the others only matched by selectors. Absent in entries
+  // written before it was recorded, which are treated as selector matches
+  pinned?: string[];
+  fetchedAt: string;
+};
+
+type MetadataCacheFile = {
+  version: 1;
+  chainId: string;
+  // Hash of the knownPatterns the matches were computed against; entries for other patterns
+  // are discarded
+  patternsHash: string;
+  contracts: Record<string, ContractMetadata>;
+};
+
+export type MetadataCache = {
+  file: string;
+  chainId: string;
+  patternsHash: string;
+  contracts: Record<string, ContractMetadata>;
+};
+
+export function metadataCachePath(dir: string, chainId: string): string {
+  return path.join(dir, `metadata-${chainId}.json`);
+}
+
+/**
+ * Loads the per-chain cache. A missing or unreadable file, or one written for other patterns,
+ * gives an empty cache rather than an error, since everything in it can be fetched again.
+ */
+export async function loadMetadataCache(
+  dir: string,
+  chainId: string,
+  patternsHash: string
+): Promise<MetadataCache> {
+  const file = metadataCachePath(dir, chainId);
+  const empty = { file, chainId, patternsHash, contracts: {} };
+  let parsed: MetadataCacheFile;
+  try {
+    parsed = JSON.parse(await fs.readFile(file, 'utf-8')) as MetadataCacheFile;
+  } catch {
+    return empty;
+  }
+  if (parsed.version !== 1 || parsed.chainId !== chainId || parsed.patternsHash !== patternsHash) {
+    return empty;
+  }
+  return { ...empty, contracts: parsed.contracts ?? {} };
+}
+
+export function cachedMetadata(
+  cache: MetadataCache,
+  address: string,
+  ttlHours: number,
+  now: Date = new Date()
+): { metadata: ContractMetadata; fresh: boolean } | null {
+  const metadata = cache.contracts[address.toLowerCase()];
+  if (!metadata) return null;
+  const ageHours = (now.getTime() - Date.parse(metadata.fetchedAt)) / (3600 * 1000);
+  return { metadata, fresh: ageHours <= ttlHours };
+}
+
+/**
+ * Writes the cache back, merged with entries other runs saved since it was loaded.
+ */
+export async function saveMetadataCache(
+  cache: MetadataCache,
+  updates: Record<string, ContractMetadata>
+): Promise<void> {
+  if (Object.keys(updates).length === 0) return;
+  await withKeyedLock(cache.file, async () => {
+    const current = await loadMetadataCache(
+      path.dirname(cache.file),
+      cache.chainId,
+      cache.patternsHash
+    );
+    const contents: MetadataCacheFile = {
+      version: 1,
+      chainId: cache.chainId,
+      patternsHash: cache.patternsHash,
+      contracts: { ...current.contracts, ...updates },
+    };
+    await fs.mkdir(path.dirname(cache.file), { recursive: true });
+    const tmp = `${cache.file}.${process.pid}.tmp`;
+    await fs.writeFile(tmp, JSON.stringify(contents, null, 2) + '\n');
+    await fs.rename(tmp, cache.file);
+  });
+}
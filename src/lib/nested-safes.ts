@@ -0,0 +1,151 @@
+import {
+  Address,
+  BaseError,
+  createPublicClient,
+  encodeFunctionData,
+  getAddress,
+  Hex,
+  HttpRequestError,
+  parseAbi,
+  PublicClient,
+  TimeoutError,
+  Transport,
+  zeroAddress,
+} from 'viem';
+import { computeSafeTxHash } from './safe-hash';
+import { SafeTx, safeTxMessageHash } from './safe-nonce';
+import type { NestedHashes } from './types/index';
+
+/**
+ * Hashes for Safes that own the target Safe. A nested owner does not sign the target's SafeTx
+ * itself: its own signers approve it with a transaction on the owner Safe that calls
+ * approveHash(safeTxHash) on the target, batched through Multicall3 with a DELEGATECALL as the
+ * task scripts build it. That transaction has its own hashes, which are the ones those signers
+ * see on their devices.
+ */
+
+export const MULTICALL3 = getAddress('0xca11bde05977b3631167028862be2a173976ca11');
+
+const SAFE_ABI = parseAbi([
+  'function getOwners() view returns (address[])',
+  'function nonce() view returns (uint256)',
+  'function domainSeparator() view returns (bytes32)',
+]);
+
+const APPROVE_HASH_ABI = parseAbi(['function approveHash(bytes32 hashToApprove)']);
+
+const MULTICALL3_ABI = parseAbi([
+  'struct Call3 { address target; bool allowFailure; bytes callData; }',
+  'function aggregate3(Call3[] calls) payable returns ((bool success, bytes returnData)[])',
+]);
+
+const DELEGATECALL = 1;
+
+// Code of an EOA that delegated to a contract with EIP-7702; it signs with its key
+const DELEGATION_PREFIX = '0xef0100';
+
+// The owner Safe's transaction approving `safeTxHash` on `target`
+export function approveHashTx(target: Address, safeTxHash: Hex): SafeTx {
+  const approve = encodeFunctionData({
+    abi: APPROVE_HASH_ABI,
+    functionName: 'approveHash',
+    args: [safeTxHash],
+  });
+  return {
+    to: MULTICALL3,
+    value: BigInt(0),
+    data: encodeFunctionData({
+      abi: MULTICALL3_ABI,
+      functionName: 'aggregate3',
+      args: [[{ target, allowFailure: false, callData: approve }]],
+    }),
+    operation: DELEGATECALL,
+    safeTxGas: BigInt(0),
+    baseGas: BigInt(0),
+    gasPrice: BigInt(0),
+    gasToken: zeroAddress,
+    refundReceiver: zeroAddress,
+  };
+}
+
+/**
+ * Finds the target's owners that are Safes and computes, for each, the hashes of its
+ * approveHash transaction at its current nonce. Keyed by the owner Safe's checksummed address;
+ * empty when no owner is a Safe. A nonce the owner Safe uses up before signing changes its
+ * hashes, so the file must be regenerated then.
+ */
+export async function deriveNestedHashes(
+  transport: Transport,
+  target: Address,
+  safeTxHash: Hex
+): Promise<NestedHashes> {
+  const client = createPublicClient({ transport }) as PublicClient;
+  const owners = await client.readContract({
+    address: target,
+    abi: SAFE_ABI,
+    functionName: 'getOwners',
+  });
+  const tx = approveHashTx(getAddress(target), safeTxHash);
+
+  const nested: NestedHashes = {};
+  for (const owner of owners) {
+    const safe = await readOwnerSafe(client, owner);
+    if (!safe) continue;
+    const messageHash = safeTxMessageHash(tx, safe.nonce);
+    nested[getAddress(owner)] = {
+      nonce: Number(safe.nonce),
+      domainHash: safe.domainSeparator,
+      messageHash,
+      safeTxHash: computeSafeTxHash(safe.domainSeparator, messageHash),
+    };
+  }
+  return nested;
+}
+
+// The owner's nonce and domain separator, or null for an EOA or a contract that is not a Safe
+async function readOwnerSafe(
+  client: PublicClient,
+  owner: Address
+): Promise<{ nonce: bigint; domainSeparator: Hex } | null> {
+  const code = await client.getCode({ address: owner });
+  if (!code || code === '0x' || code.startsWith(DELEGATION_PREFIX)) return null;
+  try {
+    const [nonce, domainSeparator] = await Promise.all([
+      client.readContract({ address: owner, abi: SAFE_ABI, functionName: 'nonce' }),
+      client.readContract({ address: owner, abi: SAFE_ABI, functionName: 'domainSeparator' }),
+    ]);
+    return { nonce, domainSeparator };
+  } catch (err) {
+    const transportError =
+      err instanceof BaseError &&
+      err.walk(e => e instanceof HttpRequestError || e instanceof TimeoutError) !== null;
+    if (!(err instanceof BaseError) || transportError) throw err;
+    return null;
+  }
+}
+
+export function describeNestedHash(owner: string, hashes: NestedHashes[string]): string {
+  return `Owner Safe ${owner} approves at nonce ${hashes.nonce}: domain hash ${hashes.domainHash}, message hash ${hashes.messageHash}, safeTxHash ${hashes.safeTxHash}`;
+}
+
+/**
+ * Compares the nested hashes a validation file lists with the ones derived now. Returns one
+ * warning per owner Safe that is missing, extra, or has different hashes.
+ */
+export function compareNestedHashes(expected: NestedHashes, actual: NestedHashes): string[] {
+  const byOwner = (hashes: NestedHashes) =>
+    new Map(Object.entries(hashes).map(([owner, h]) => [getAddress(owner), h]));
+  const listed = byOwner(expected);
+  const derived = byOwner(actual);
+  const owners = Array.from(new Set([...listed.keys(), ...derived.keys()])).sort();
+  return owners.flatMap(owner => {
+    const e = listed.get(owner);
+    const a = derived.get(owner);
+    if (!a) return [`The validation file lists hashes for ${owner}, which is not an owner Safe`];
+    if (!e) return [`Owner Safe ${owner} has no hashes in the validation file`];
+    if (e.safeTxHash.toLowerCase() === a.safeTxHash.toLowerCase()) return [];
+    return [
+      `Owner Safe ${owner} approves with safeTxHash ${a.safeTxHash} at nonce ${a.nonce}, but the validation file lists ${e.safeTxHash} at nonce ${e.nonce}`,
+    ];
+  });
+}
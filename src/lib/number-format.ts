@@ -0,0 +1,70 @@
+import { formatUnits } from 'viem';
+
+/**
+ * Readable numbers for slots that hold amounts. A slot in contracts.json can say how its word is
+ * read: `decimals` for token amounts (18 for most ERC-20s), or `format` for the common units,
+ * `gwei` (9 decimals) and `ether` (18). Values are written with thousands separators, so
+ * 30000000 gas reads as 30,000,000 and 1500000000 wei as 1.5 gwei.
+ */
+
+export const SLOT_FORMATS = ['number', 'gwei', 'ether'] as const;
+
+export type SlotFormat = (typeof SLOT_FORMATS)[number];
+
+export type SlotFormatHint = {
+  decimals?: number;
+  format?: SlotFormat;
+};
+
+export const FORMAT_UNITS: Record<SlotFormat, { decimals: number; unit: string }> = {
+  number: { decimals: 0, unit: '' },
+  gwei: { decimals: 9, unit: ' gwei' },
+  ether: { decimals: 18, unit: ' ETH' },
+};
+
+// 1234567.5 -> 1,234,567.5; the fraction is left as it is
+export function groupThousands(value: string): string {
+  const [whole, fraction] = value.split('.');
+  const sign = whole.startsWith('-') ? '-' : '';
+  const digits = sign ? whole.slice(1) : whole;
+  const grouped = digits.replace(/\B(?=(\d{3})+(?!\d))/g, ',');
+  return `${sign}${grouped}${fraction !== undefined ? `.${fraction}` : ''}`;
+}
+
+export function hasFormatHint(hint: SlotFormatHint | undefined): hint is SlotFormatHint {
+  return hint !== undefined && (hint.decimals !== undefined || hint.format !== undefined);
+}
+
+// Checks a hint from contracts.json, naming the slot it was found on
+export function validateFormatHint(hint: SlotFormatHint, where: string): void {
+  if (
+    hint.decimals !== undefined &&
+    (!Number.isInteger(hint.decimals) || hint.decimals < 0 || hint.decimals > 77)
+  ) {
+    throw new Error(`${where}: decimals must be an integer from 0 to 77`);
+  }
+  if (hint.format !== undefined && !(SLOT_FORMATS as readonly string[]).includes(hint.format)) {
+    throw new Error(`${where}: format must be one of: ${SLOT_FORMATS.join(', ')}`);
+  }
+  if (hint.decimals !== undefined && hint.format !== undefined && hint.format !== 'number') {
+    throw new Error(`${where}: ${hint.format} implies its decimals; give only one of them`);
+  }
+}
+
+/**
+ * An unsigned value as the hint reads it: `decimals` shift the decimal point, `gwei` and
+ * `ether` also name the unit. Without a hint the value is a plain grouped integer.
+ */
+export function formatAmount(value: bigint, hint?: SlotFormatHint): string {
+  const { decimals, unit } = FORMAT_UNITS[hint?.format ?? 'number'];
+  return `${groupThousands(formatUnits(value, hint?.decimals ?? decimals))}${unit}`;
+}
+
+// A 32-byte slot word as the hint reads it; words that are not hex are returned unchanged
+export function formatSlotWord(word: string, hint?: SlotFormatHint): string {
+  try {
+    return formatAmount(BigInt(word), hint);
+  } catch {
+    return word;
+  }
+}
@@ -0,0 +1,176 @@
+import { mkdirSync, writeFileSync } from 'fs';
+import path from 'path';
+import { expandEnvVars } from './chains';
+import { backoffDelay, RetryPolicy } from './rpc-retry';
+import type { OutputFormat } from './serialization';
+
+/**
+ * Destinations a validation file is written to besides --out (genValidationFile.ts --sink),
+ * so one run can archive the file and hand it to another system. `file=PATH` writes a copy,
+ * `stdout` prints it, `http=URL` POSTs it, and `s3=URL` PUTs it to a presigned S3 (or
+ * S3-compatible) upload URL; the tool holds no cloud credentials. Remote sinks are retried with
+ * backoff on network errors, rate limits, and server errors.
+ */
+
+export const SINK_KINDS = ['file', 'stdout', 's3', 'http'] as const;
+export type SinkKind = (typeof SINK_KINDS)[number];
+
+export type OutputSink =
+  | { kind: 'file'; path: string }
+  | { kind: 'stdout' }
+  | { kind: 's3' | 'http'; url: string };
+
+export type SinkDelivery = { sink: OutputSink; attempts: number; error?: string };
+
+export type SinkRetry = Pick<RetryPolicy, 'maxAttempts' | 'baseDelayMs' | 'maxDelayMs'>;
+
+export const DEFAULT_SINK_RETRY: SinkRetry = {
+  maxAttempts: 4,
+  baseDelayMs: 500,
+  maxDelayMs: 8000,
+};
+
+const CONTENT_TYPES: Record<OutputFormat, string> = {
+  json: 'application/json',
+  yaml: 'application/yaml',
+  toml: 'application/toml',
+};
+
+// Splits only before a sink kind, since presigned URLs may hold commas
+const SINK_SEPARATOR = new RegExp(`,(?=(?:${SINK_KINDS.join('|')})(?:=|,|$))`);
+
+function parseRemoteUrl(kind: 's3' | 'http', value: string, env: NodeJS.ProcessEnv): string {
+  const expanded = expandEnvVars(value, env);
+  let url: URL;
+  try {
+    url = new URL(expanded);
+  } catch {
+    throw new Error(`--sink ${kind} must be an http(s) URL`);
+  }
+  if (kind === 's3' && url.protocol === 's3:') {
+    throw new Error('--sink s3 takes a presigned https upload URL, not an s3:// location');
+  }
+  if (url.protocol !== 'https:' && url.protocol !== 'http:') {
+    throw new Error(`--sink ${kind} must be an http(s) URL`);
+  }
+  return expanded;
+}
+
+/**
+ * Reads --sink values, each one sink or several separated by commas. URLs often carry a
+ * secret (a webhook token, a presigned signature), so they can be given as `${VAR}`.
+ */
+export function parseSinks(
+  values: readonly string[],
+  cwd: string = process.cwd(),
+  env: NodeJS.ProcessEnv = process.env
+): OutputSink[] {
+  const sinks = values
+    .flatMap(value => value.split(SINK_SEPARATOR))
+    .map((spec): OutputSink => {
+      const separator = spec.indexOf('=');
+      const kind = separator === -1 ? spec : spec.slice(0, separator);
+      const target = separator === -1 ? '' : spec.slice(separator + 1);
+      switch (kind) {
+        case 'stdout':
+          if (target) throw new Error('--sink stdout takes no target');
+          return { kind };
+        case 'file':
+          if (!target) throw new Error('--sink file needs a path, as in file=out/task.json');
+          return { kind, path: path.resolve(cwd, target) };
+        case 's3':
+        case 'http':
+          if (!target) throw new Error(`--sink ${kind} needs a URL, as in ${kind}=https://...`);
+          return { kind, url: parseRemoteUrl(kind, target, env) };
+        default:
+          throw new Error(`Unknown sink "${kind}"; use one of: ${SINK_KINDS.join(', ')}`);
+      }
+    });
+  if (sinks.filter(s => s.kind === 'stdout').length > 1) {
+    throw new Error('--sink stdout is given more than once');
+  }
+  return sinks;
+}
+
+// Remote sinks are named by host only, since the rest of the URL may be a secret
+export function describeSink(sink: OutputSink): string {
+  if (sink.kind === 'file') return sink.path;
+  if (sink.kind === 'stdout') return 'stdout';
+  return `${sink.kind} ${new URL(sink.url).host}`;
+}
+
+function retryableStatus(status: number): boolean {
+  return status === 429 || status >= 500;
+}
+
+async function sendWithRetry(
+  sink: Extract<OutputSink, { url: string }>,
+  content: string,
+  contentType: string,
+  options: { fetchFn: typeof fetch; retry: SinkRetry; sleep: (ms: number) => Promise<void> }
+): Promise<SinkDelivery> {
+  const policy = { ...options.retry, budget: 0 };
+  let error = '';
+  for (let attempt = 1; attempt <= options.retry.maxAttempts; attempt++) {
+    if (attempt > 1) await options.sleep(backoffDelay(policy, attempt - 1));
+    try {
+      const response = await options.fetchFn(sink.url, {
+        method: sink.kind === 's3' ? 'PUT' : 'POST',
+        headers: { 'Content-Type': contentType },
+        body: content,
+      });
+      if (response.ok) return { sink, attempts: attempt };
+      error = `status ${response.status}`;
+      if (!retryableStatus(response.status)) return { sink, attempts: attempt, error };
+    } catch (err) {
+      error = err instanceof Error ? err.message : String(err);
+    }
+  }
+  return { sink, attempts: options.retry.maxAttempts, error };
+}
+
+/**
+ * Writes `content` to every sink, in order. A sink that fails does not stop the others; the
+ * failures are returned so the caller can report all of them at once.
+ */
+export async function deliverToSinks(
+  sinks: readonly OutputSink[],
+  content: string,
+  options: {
+    format: OutputFormat;
+    fetchFn?: typeof fetch;
+    retry?: SinkRetry;
+    sleep?: (ms: number) => Promise<void>;
+    stdout?: (text: string) => void;
+  }
+): Promise<SinkDelivery[]> {
+  const remote = {
+    fetchFn: options.fetchFn ?? fetch,
+    retry: options.retry ?? DEFAULT_SINK_RETRY,
+    sleep: options.sleep ?? ((ms: number) => new Promise<void>(r => setTimeout(r, ms))),
+  };
+  const stdout =
+    options.stdout ??
+    ((text: string) => {
+      process.stdout.write(text + '\n');
+    });
+  const deliveries: SinkDelivery[] = [];
+  for (const sink of sinks) {
+    if (sink.kind === 'file') {
+      try {
+        mkdirSync(path.dirname(sink.path), { recursive: true });
+        writeFileSync(sink.path, content + '\n');
+        deliveries.push({ sink, attempts: 1 });
+      } catch (err) {
+        const error = err instanceof Error ? err.message : String(err);
+        deliveries.push({ sink, attempts: 1, error });
+      }
+    } else if (sink.kind === 'stdout') {
+      stdout(content);
+      deliveries.push({ sink, attempts: 1 });
+    } else {
+      deliveries.push(await sendWithRetry(sink, content, CONTENT_TYPES[options.format], remote));
+    }
+  }
+  return deliveries;
+}
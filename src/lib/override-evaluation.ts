@@ -0,0 +1,114 @@
+import { createPublicClient, getAddress, Hex, pad, Transport } from 'viem';
+import type { ExpectedOverride } from './expected-overrides';
+import type { StateChange, StateOverride } from './types/index';
+
+/**
+ * What each state override would do to live state (`state-diff overrides check`). Facilitators
+ * run it before a ceremony: an override that writes the value the slot already holds, such as
+ * a threshold of 1 on a Safe whose threshold is 1, is a no-op and can be dropped; one that
+ * changes the slot is the part of the simulation reviewers must accept; and one that two
+ * sources give different values, or that the simulated `before` contradicts, is a conflict.
+ */
+
+export type OverrideStatus = 'no-op' | 'change' | 'conflict';
+
+export type ProposedOverride = {
+  address: string;
+  key: Hex;
+  value: Hex;
+  // Contract name or documented reason, for the report
+  label: string;
+};
+
+export type OverrideEvaluation = ProposedOverride & {
+  // Value the slot holds on-chain at `blockNumber`
+  live: Hex;
+  status: OverrideStatus;
+  // Why it is a conflict
+  conflict?: string;
+};
+
+export type OverrideEvaluationReport = {
+  blockNumber: bigint;
+  overrides: OverrideEvaluation[];
+};
+
+const word = (value: string): Hex => pad(value.toLowerCase() as Hex, { size: 32 });
+
+const slotId = (address: string, key: string) => `${address.toLowerCase()}:${word(key)}`;
+
+export function overridesFromValidationFile(stateOverrides: StateOverride[]): ProposedOverride[] {
+  return stateOverrides.flatMap(o =>
+    o.overrides.map(v => ({
+      address: getAddress(o.address),
+      key: word(v.key),
+      value: word(v.value),
+      label: o.name,
+    }))
+  );
+}
+
+export function overridesFromExpected(expected: ExpectedOverride[]): ProposedOverride[] {
+  return expected.map(e => ({
+    address: getAddress(e.address),
+    key: word(e.key),
+    value: word(e.value),
+    label: e.reason,
+  }));
+}
+
+/**
+ * Reads every overridden slot at the latest block and classifies the override. `stateChanges`
+ * are the validation file's, whose `before` of an overridden slot must be the override's value,
+ * since the simulation ran with it.
+ */
+export async function evaluateOverrides(
+  transport: Transport,
+  proposed: ProposedOverride[],
+  stateChanges: StateChange[] = []
+): Promise<OverrideEvaluationReport> {
+  const client = createPublicClient({ transport });
+  const blockNumber = await client.getBlockNumber();
+
+  const values = new Map<string, Set<Hex>>();
+  for (const o of proposed) {
+    const id = slotId(o.address, o.key);
+    values.set(id, (values.get(id) ?? new Set()).add(o.value));
+  }
+  const befores = new Map<string, Hex>();
+  for (const sc of stateChanges) {
+    for (const c of sc.changes) befores.set(slotId(sc.address, c.key), word(c.before));
+  }
+
+  const overrides = await Promise.all(
+    proposed.map(async (o): Promise<OverrideEvaluation> => {
+      const address = getAddress(o.address);
+      const stored = await client.getStorageAt({ address, slot: o.key, blockNumber });
+      const live = word(stored ?? '0x0');
+      const id = slotId(o.address, o.key);
+      const others = [...values.get(id)!].filter(v => v !== o.value);
+      const before = befores.get(id);
+      let conflict: string | undefined;
+      if (others.length > 0) {
+        conflict = `the slot is also overridden to ${others.join(', ')}`;
+      } else if (before !== undefined && before !== o.value) {
+        conflict = `the simulation saw ${before} before the transaction, not the override's value`;
+      }
+      if (conflict) return { ...o, address, live, status: 'conflict', conflict };
+      return { ...o, address, live, status: live === o.value ? 'no-op' : 'change' };
+    })
+  );
+  return { blockNumber, overrides };
+}
+
+export function describeOverrideEvaluation(e: OverrideEvaluation): string {
+  const slot = `${e.label}: ${e.address} ${e.key}`;
+  switch (e.status) {
+    case 'no-op':
+      return `${slot} already holds ${e.value}; the override changes nothing`;
+    case 'change':
+      return `${slot} changes from ${e.live} to ${e.value}`;
+    case 'conflict':
+      return `${slot} is overridden to ${e.value} (live ${e.live}), but ${e.conflict}`;
+  }
+}
@@ -3,6 +3,21 @@ import { TaskConfigSchema } from './config-schemas';
 import { ParsedConfig, ParseResult } from './types/index';
 
 export function parseConfig(jsonData: unknown): ParsedConfig {
+  if (jsonData && typeof jsonData === 'object' && 'redactions' in jsonData) {
+    return {
+      result: {
+        success: false,
+        zodError: new z.ZodError([
+          {
+            code: z.ZodIssueCode.custom,
+            path: ['redactions'],
+            message: 'Redacted validation files are for sharing only and cannot be used to sign',
+          },
+        ]),
+      },
+    };
+  }
+
   const parsed = TaskConfigSchema.safeParse(jsonData);
 
   if (parsed.success) {
@@ -1,4 +1,6 @@
+import { realpathSync } from 'fs';
 import path from 'path';
+import { PolicyViolationError } from './errors';
 
 /**
  * Validates that a resolved path is within the given directory.
@@ -9,7 +11,35 @@ export function assertWithinDir(targetPath: string, allowedDir: string): string
   const resolved = path.resolve(targetPath);
   const dir = path.resolve(allowedDir);
   if (!resolved.startsWith(dir + path.sep) && resolved !== dir) {
-    throw new Error(`Path traversal detected: ${resolved} is outside allowed directory ${dir}`);
+    throw new PolicyViolationError(
+      `Path traversal detected: ${resolved} is outside allowed directory ${dir}`
+    );
+  }
+  return resolved;
+}
+
+/**
+ * Like assertWithinDir, but follows symlinks first, so a link inside the directory cannot
+ * point outside it. A path that does not exist yet has nothing to follow and is only checked
+ * as written.
+ */
+export function assertRealPathWithinDir(targetPath: string, allowedDir: string): string {
+  const resolved = assertWithinDir(targetPath, allowedDir);
+  const real = (p: string) => {
+    try {
+      return realpathSync(p);
+    } catch (err: unknown) {
+      if (err instanceof Error && 'code' in err && err.code === 'ENOENT') return null;
+      throw err;
+    }
+  };
+  const realTarget = real(resolved);
+  const realDir = real(path.resolve(allowedDir));
+  if (!realTarget || !realDir) return resolved;
+  if (!realTarget.startsWith(realDir + path.sep) && realTarget !== realDir) {
+    throw new PolicyViolationError(
+      `Symlink escape detected: ${resolved} resolves to ${realTarget}, outside allowed directory ${realDir}`
+    );
   }
   return resolved;
 }
@@ -0,0 +1,148 @@
+import {
+  Address,
+  concat,
+  getAddress,
+  Hex,
+  isAddress,
+  keccak256,
+  numberToHex,
+  pad,
+  parseAbi,
+} from 'viem';
+import type { ParentPreimage } from './state-diff-encoding';
+import { AccountAccessKind, VmSafeAccountAccess } from './vm-safe';
+
+/**
+ * EIP-2612 permits signed during a ceremony, e.g. to fund an executor from a token the signer
+ * holds. Nothing is simulated: a permit only sets allowance[owner][spender] and bumps the
+ * owner's nonce, so the report predicts those writes from the token's storage layout.
+ */
+
+// OpenZeppelin ERC20 keeps _balances at slot 0 and _allowances at slot 1
+export const DEFAULT_ALLOWANCE_SLOT = numberToHex(1, { size: 32 });
+
+const PERMIT_FIELDS = [
+  'address owner',
+  'address spender',
+  'uint256 value',
+  'uint256 nonce',
+  'uint256 deadline',
+];
+
+export const PERMIT_TOKEN_ABI = parseAbi([
+  'function allowance(address owner, address spender) view returns (uint256)',
+  'function nonces(address owner) view returns (uint256)',
+]);
+
+export type Permit = {
+  token: Address;
+  chainId: number | null;
+  owner: Address;
+  spender: Address;
+  value: bigint;
+  nonce: bigint;
+  deadline: bigint;
+};
+
+function uint(value: unknown, field: string): bigint {
+  if (typeof value === 'number' && Number.isSafeInteger(value) && value >= 0) return BigInt(value);
+  if (typeof value === 'string' && /^(0x[0-9a-fA-F]+|\d+)$/.test(value)) return BigInt(value);
+  throw new Error(`Permit ${field} must be an unsigned integer`);
+}
+
+function address(value: unknown, field: string): Address {
+  if (typeof value !== 'string' || !isAddress(value, { strict: false })) {
+    throw new Error(`Permit ${field} must be an address`);
+  }
+  return getAddress(value);
+}
+
+/**
+ * Reads EIP-2612 typed data (the eth_signTypedData_v4 JSON a wallet or script produces). The
+ * Permit type must have exactly the EIP-2612 fields, and the domain must name the token as
+ * verifyingContract.
+ */
+export function parsePermit(input: unknown): Permit {
+  if (!input || typeof input !== 'object') throw new Error('Permit must be a JSON object');
+  const { types, primaryType, domain, message } = input as Record<string, unknown>;
+  if (primaryType !== 'Permit') {
+    throw new Error(`Permit typed data has primaryType ${String(primaryType)}, not Permit`);
+  }
+  const fields = (types as Record<string, unknown> | undefined)?.Permit;
+  const expected = PERMIT_FIELDS.join(', ');
+  const actual = Array.isArray(fields)
+    ? fields.map(f => `${(f as { type?: unknown }).type} ${(f as { name?: unknown }).name}`)
+    : [];
+  if (actual.join(', ') !== expected) {
+    throw new Error(`Permit type must be Permit(${expected}), got Permit(${actual.join(', ')})`);
+  }
+  const d = (domain ?? {}) as Record<string, unknown>;
+  const m = (message ?? {}) as Record<string, unknown>;
+  return {
+    token: address(d.verifyingContract, 'domain.verifyingContract'),
+    chainId: d.chainId === undefined ? null : Number(uint(d.chainId, 'domain.chainId')),
+    owner: address(m.owner, 'owner'),
+    spender: address(m.spender, 'spender'),
+    value: uint(m.value, 'value'),
+    nonce: uint(m.nonce, 'nonce'),
+    deadline: uint(m.deadline, 'deadline'),
+  };
+}
+
+// Slot of mapping[key] for a mapping declared at `base`, with its preimage
+function mappingEntry(base: Hex, key: Hex): ParentPreimage {
+  const parent = pad(base);
+  const k = pad(key).toLowerCase() as Hex;
+  return { slot: keccak256(concat([k, parent])), parent, key: k };
+}
+
+// allowance[owner][spender] in a nested mapping at `base`, outermost preimage first
+export function allowanceSlot(permit: Permit, base: Hex): ParentPreimage[] {
+  const outer = mappingEntry(base, permit.owner);
+  return [outer, mappingEntry(outer.slot, permit.spender)];
+}
+
+export function noncesSlot(permit: Permit, base: Hex): ParentPreimage {
+  return mappingEntry(base, permit.owner);
+}
+
+/**
+ * The token's storage writes a submitted permit makes, shaped like a forge account access so
+ * the report is built as for a simulation. `before` values are the slots' current contents.
+ */
+export function permitAccountAccesses(
+  permit: Permit,
+  writes: { slot: Hex; before: Hex; after: Hex }[]
+): VmSafeAccountAccess[] {
+  const account = permit.token.toLowerCase();
+  return [
+    {
+      chainInfo: { forkId: BigInt(0), chainId: BigInt(permit.chainId ?? 0) },
+      kind: AccountAccessKind.Call,
+      account,
+      accessor: permit.spender.toLowerCase(),
+      initialized: true,
+      oldBalance: BigInt(0),
+      newBalance: BigInt(0),
+      deployedCode: '0x',
+      value: BigInt(0),
+      data: '0x',
+      reverted: false,
+      storageAccesses: writes.map(w => ({
+        account,
+        slot: w.slot,
+        isWrite: true,
+        previousValue: pad(w.before),
+        newValue: pad(w.after),
+        reverted: false,
+      })),
+      depth: BigInt(0),
+      oldNonce: BigInt(0),
+      newNonce: BigInt(0),
+    },
+  ];
+}
+
+export function describePermit(permit: Permit): string {
+  return `${permit.owner} lets ${permit.spender} spend ${permit.value} of token ${permit.token} (nonce ${permit.nonce}, deadline ${permit.deadline})`;
+}
@@ -0,0 +1,167 @@
+import { promises as fs } from 'fs';
+import path from 'path';
+import { z } from 'zod';
+import { PolicyViolationError } from './errors';
+import { reportWarning } from './report-warnings';
+import type { ReportWarning } from './types/index';
+
+/**
+ * Organization-specific checks shipped as WebAssembly modules (genValidationFile.ts
+ * --policy-plugin), so teams can refuse reports on their own rules without forking the tool.
+ * Plugins can be written in any language that compiles to WASM without a host runtime, such
+ * as TinyGo (-target=wasm-unknown), Rust (wasm32-unknown-unknown), or AssemblyScript.
+ *
+ * Interface, version 1. A plugin may not import anything, so it cannot reach the file system,
+ * the network, or the clock, and it exports:
+ *   memory                  its linear memory
+ *   policy_abi_version()    -> i32, returns 1
+ *   alloc(len: i32)         -> i32, a buffer of len bytes for the input
+ *   check(ptr: i32, len: i32) -> i64, given the UTF-8 JSON validation file, returns the output's
+ *                           pointer in the high 32 bits and its length in the low 32 bits
+ * The output is UTF-8 JSON: { "findings": [{ "severity": "error" | "warning", "message": "...",
+ * "rule": "..." }] }. Errors refuse the report; warnings are recorded as POLICY_FINDING.
+ */
+
+export const POLICY_PLUGIN_ABI_VERSION = 1;
+
+// Output larger than this is refused rather than decoded
+const MAX_OUTPUT_BYTES = 1 << 20;
+
+const PolicyOutputSchema = z.object({
+  findings: z.array(
+    z.object({
+      severity: z.enum(['error', 'warning']),
+      message: z.string().min(1),
+      // The plugin's name for the rule, for gates and for reviewers asking about it
+      rule: z.string().min(1).optional(),
+    })
+  ),
+});
+
+export type PolicyFinding = z.infer<typeof PolicyOutputSchema>['findings'][number];
+
+type PluginExports = {
+  memory: WebAssembly.Memory;
+  policy_abi_version: () => number;
+  alloc: (len: number) => number;
+  check: (ptr: number, len: number) => bigint;
+};
+
+export type PolicyPlugin = {
+  // File name, for messages
+  name: string;
+  exports: PluginExports;
+};
+
+const REQUIRED_EXPORTS = {
+  memory: 'memory',
+  policy_abi_version: 'function',
+  alloc: 'function',
+  check: 'function',
+} as const;
+
+export async function loadPolicyPlugin(file: string): Promise<PolicyPlugin> {
+  const name = path.basename(file);
+  let module: WebAssembly.Module;
+  try {
+    module = await WebAssembly.compile(await fs.readFile(file));
+  } catch (err) {
+    throw new Error(
+      `Policy plugin ${name} is not a WebAssembly module: ${err instanceof Error ? err.message : String(err)}`
+    );
+  }
+  const imports = WebAssembly.Module.imports(module);
+  if (imports.length > 0) {
+    throw new Error(
+      `Policy plugin ${name} imports ${imports[0].module}.${imports[0].name}; plugins may not import anything`
+    );
+  }
+  const exported = new Map(WebAssembly.Module.exports(module).map(e => [e.name, e.kind]));
+  for (const [exportName, kind] of Object.entries(REQUIRED_EXPORTS)) {
+    if (exported.get(exportName) !== kind) {
+      throw new Error(`Policy plugin ${name} does not export the ${kind} ${exportName}`);
+    }
+  }
+  const instance = await WebAssembly.instantiate(module, {});
+  const exports = instance.exports as unknown as PluginExports;
+  const version = exports.policy_abi_version();
+  if (version !== POLICY_PLUGIN_ABI_VERSION) {
+    throw new Error(
+      `Policy plugin ${name} implements interface version ${version}; this tool supports ${POLICY_PLUGIN_ABI_VERSION}`
+    );
+  }
+  return { name, exports };
+}
+
+/**
+ * Runs the plugin on `input`, the validation file as it would be written. Throws when the
+ * plugin's output does not follow the interface.
+ */
+export function runPolicyPlugin(plugin: PolicyPlugin, input: object): PolicyFinding[] {
+  const { memory, alloc, check } = plugin.exports;
+  const bytes = new TextEncoder().encode(JSON.stringify(input));
+  const ptr = alloc(bytes.length) >>> 0;
+  if (ptr + bytes.length > memory.buffer.byteLength) {
+    throw new Error(`Policy plugin ${plugin.name} allocated the input outside its memory`);
+  }
+  new Uint8Array(memory.buffer, ptr, bytes.length).set(bytes);
+
+  const result = BigInt.asUintN(64, check(ptr, bytes.length));
+  const outPtr = Number(result >> BigInt(32));
+  const outLen = Number(result & BigInt(0xffffffff));
+  // check may have grown the memory, so its buffer is read again
+  if (outLen > MAX_OUTPUT_BYTES || outPtr + outLen > memory.buffer.byteLength) {
+    throw new Error(`Policy plugin ${plugin.name} returned output outside its memory`);
+  }
+  const text = new TextDecoder().decode(new Uint8Array(memory.buffer, outPtr, outLen));
+  let parsed: z.SafeParseReturnType<unknown, z.infer<typeof PolicyOutputSchema>>;
+  try {
+    parsed = PolicyOutputSchema.safeParse(JSON.parse(text));
+  } catch {
+    throw new Error(`Policy plugin ${plugin.name} returned output that is not JSON`);
+  }
+  if (!parsed.success) {
+    const issue = parsed.error.issues[0];
+    throw new Error(
+      `Policy plugin ${plugin.name} returned invalid findings: ${issue.path.join('.') || '<root>'}: ${issue.message}`
+    );
+  }
+  return parsed.data.findings;
+}
+
+export function describePolicyFinding(plugin: string, finding: PolicyFinding): string {
+  return `${plugin}${finding.rule ? ` (${finding.rule})` : ''}: ${finding.message}`;
+}
+
+/**
+ * Runs every plugin and refuses the report when any finds an error, listing all of them.
+ * Returns the warnings to record in the file.
+ */
+export function checkPolicyPlugins(
+  plugins: readonly PolicyPlugin[],
+  input: object
+): ReportWarning[] {
+  const errors: string[] = [];
+  const warnings: ReportWarning[] = [];
+  for (const plugin of plugins) {
+    for (const finding of runPolicyPlugin(plugin, input)) {
+      const message = describePolicyFinding(plugin.name, finding);
+      if (finding.severity === 'error') {
+        errors.push(message);
+        continue;
+      }
+      warnings.push(
+        reportWarning('POLICY_FINDING', message, {
+          plugin: plugin.name,
+          ...(finding.rule ? { rule: finding.rule } : {}),
+        })
+      );
+    }
+  }
+  if (errors.length > 0) {
+    throw new PolicyViolationError(
+      `Policy plugins refused the report:\n${errors.map(e => `  - ${e}`).join('\n')}`
+    );
+  }
+  return warnings;
+}
@@ -0,0 +1,76 @@
+import { readFileSync } from 'fs';
+import type { Hex } from 'viem';
+import { z } from 'zod';
+import { describeZodIssues, HashSchema, PreimageSchema } from './config-schemas';
+import type { ParentPreimage } from './state-diff-encoding';
+import type { TaskConfig } from './types/index';
+
+/**
+ * Mapping preimages collected outside the simulation, e.g. by an indexer, for slots forge did
+ * not record. The file maps each slot to the mapping slot and key it is derived from:
+ *
+ *   { "0x<slot>": { "parent": "0x<mapping slot>", "key": "0x<key>" } }
+ *
+ * Every entry is checked to hash to its slot, so the file can only name slots, not mislabel them.
+ */
+
+const PreimageDatabaseSchema = z.record(z.object({ parent: HashSchema, key: HashSchema }));
+
+function word(hex: string): Hex {
+  return hex.toLowerCase() as Hex;
+}
+
+export function parsePreimageDatabase(text: string): ParentPreimage[] {
+  const records = PreimageDatabaseSchema.safeParse(JSON.parse(text));
+  if (!records.success) {
+    throw new Error(`Invalid preimage database: ${describeZodIssues(records.error)}`);
+  }
+  // Still keyed by slot, so an issue is reported against the slot it is about
+  const withSlots = Object.entries(records.data).map(([slot, p]) => [slot, { slot, ...p }]);
+  const entries = z.record(PreimageSchema).safeParse(Object.fromEntries(withSlots));
+  if (!entries.success) {
+    throw new Error(`Invalid preimage database: ${describeZodIssues(entries.error)}`);
+  }
+  return Object.values(entries.data).map(p => ({
+    slot: word(p.slot),
+    parent: word(p.parent),
+    key: word(p.key),
+  }));
+}
+
+export function loadPreimageDatabase(file: string): ParentPreimage[] {
+  return parsePreimageDatabase(readFileSync(file, 'utf-8'));
+}
+
+/**
+ * The extra preimages on the parent chains of the given slots: the ones a report relies on to
+ * describe them, which it records so validation resolves the slots the same way.
+ */
+export function usedPreimages(
+  slots: Iterable<Hex>,
+  parentMap: Map<Hex, Hex>,
+  extra: readonly ParentPreimage[]
+): ParentPreimage[] {
+  const bySlot = new Map(extra.map(p => [word(p.slot), p]));
+  const used = new Map<Hex, ParentPreimage>();
+  for (const slot of slots) {
+    const seen = new Set<Hex>();
+    let current: Hex | undefined = word(slot);
+    while (current && !seen.has(current)) {
+      seen.add(current);
+      const preimage = bySlot.get(current);
+      if (preimage) used.set(current, preimage);
+      current = parentMap.get(current);
+    }
+  }
+  return [...used.values()].sort((a, b) => a.slot.localeCompare(b.slot));
+}
+
+// Preimages a validation file recorded, to pass back to a re-run of its simulation
+export function recordedPreimages(config: Pick<TaskConfig, 'extraPreimages'>): ParentPreimage[] {
+  return (config.extraPreimages ?? []).map(p => ({
+    slot: word(p.slot),
+    parent: word(p.parent),
+    key: word(p.key),
+  }));
+}
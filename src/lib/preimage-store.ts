@@ -0,0 +1,229 @@
+import { promises as fs } from 'fs';
+import path from 'path';
+import { concat, Hex, keccak256 } from 'viem';
+import { z } from 'zod';
+import { AddressSchema, HashSchema, PreimageSchema } from './config-schemas';
+import { withKeyedLock } from './keyed-lock';
+import type { ParentPreimage } from './state-diff-encoding';
+
+/**
+ * Mapping preimages learned from earlier runs. A simulation only records the preimages of the
+ * keys it hashed itself, so a task that writes a mapping entry without computing its slot
+ * (e.g. through a precomputed slot in calldata) leaves it unresolved. Every preimage a report
+ * resolved a contract's slots with is kept per chain and contract, and later runs that touch
+ * the contract start from them. Entries are checked to hash to their slot on load, so a store
+ * copied from another facilitator can only name slots, not mislabel them.
+ */
+
+// Default directory for the store; learning is off when neither it nor --preimage-store is set
+export const PREIMAGE_STORE_ENV = 'STATE_DIFF_PREIMAGE_STORE';
+
+// Contract address -> slot -> the mapping slot and key it is derived from
+const ContractPreimagesSchema = z.record(
+  z.record(z.object({ parent: HashSchema, key: HashSchema }))
+);
+
+const PreimageStoreFileSchema = z.object({
+  version: z.literal(1),
+  chainId: z.string().regex(/^\d+$/),
+  contracts: ContractPreimagesSchema,
+});
+
+// What `preimages export` writes and `preimages import` reads: every chain's store in one file
+const PreimageExportSchema = z.object({
+  version: z.literal(1),
+  chains: z.record(ContractPreimagesSchema),
+});
+
+export type PreimageExport = z.infer<typeof PreimageExportSchema>;
+
+export type PreimageStore = {
+  file: string;
+  chainId: string;
+  // Keyed by lowercase address
+  contracts: Record<string, ParentPreimage[]>;
+};
+
+export function preimageStorePath(dir: string, chainId: string): string {
+  return path.join(dir, `preimages-${chainId}.json`);
+}
+
+function word(hex: string): Hex {
+  return hex.toLowerCase() as Hex;
+}
+
+// Validates every entry, naming the contract and slot of the first bad one
+function toPreimages(
+  contracts: z.infer<typeof ContractPreimagesSchema>,
+  where: string
+): Record<string, ParentPreimage[]> {
+  const out: Record<string, ParentPreimage[]> = {};
+  for (const [address, slots] of Object.entries(contracts)) {
+    if (!AddressSchema.safeParse(address).success) {
+      throw new Error(`Invalid preimage store ${where}: ${address} is not an address`);
+    }
+    out[address.toLowerCase()] = Object.entries(slots).map(([slot, link]) => {
+      const parsed = PreimageSchema.safeParse({ slot, ...link });
+      if (!parsed.success) {
+        const issue = parsed.error.issues[0];
+        throw new Error(`Invalid preimage store ${where}: ${address}.${slot}: ${issue.message}`);
+      }
+      return { slot: word(slot), parent: word(link.parent), key: word(link.key) };
+    });
+  }
+  return out;
+}
+
+function fromPreimages(
+  contracts: Record<string, readonly ParentPreimage[]>
+): z.infer<typeof ContractPreimagesSchema> {
+  const out: z.infer<typeof ContractPreimagesSchema> = {};
+  for (const address of Object.keys(contracts).sort()) {
+    const slots = [...contracts[address]].sort((a, b) => a.slot.localeCompare(b.slot));
+    out[address] = Object.fromEntries(slots.map(p => [p.slot, { parent: p.parent, key: p.key }]));
+  }
+  return out;
+}
+
+/**
+ * Loads the per-chain store. A missing file is an empty store; an unreadable or invalid one
+ * is an error, since learned preimages cannot be fetched again and saving would drop them.
+ */
+export async function loadPreimageStore(dir: string, chainId: string): Promise<PreimageStore> {
+  const file = preimageStorePath(dir, chainId);
+  let text: string;
+  try {
+    text = await fs.readFile(file, 'utf-8');
+  } catch (err) {
+    if ((err as NodeJS.ErrnoException).code === 'ENOENT') return { file, chainId, contracts: {} };
+    throw err;
+  }
+  const parsed = PreimageStoreFileSchema.safeParse(JSON.parse(text));
+  if (!parsed.success || parsed.data.chainId !== chainId) {
+    throw new Error(`Invalid preimage store ${file}: not a version 1 store for chain ${chainId}`);
+  }
+  return { file, chainId, contracts: toPreimages(parsed.data.contracts, file) };
+}
+
+// The learned preimages of the given contracts
+export function storedPreimages(
+  store: PreimageStore,
+  accounts: Iterable<string>
+): ParentPreimage[] {
+  return Array.from(accounts).flatMap(addr => store.contracts[addr.toLowerCase()] ?? []);
+}
+
+/**
+ * The preimages each contract's slots were resolved with: every link on the parent chains of
+ * `slotsByContract`, from whichever source the run had it. Links that do not hash to their
+ * slot are left out, so only confirmed relationships are learned.
+ */
+export function learnPreimages(
+  slotsByContract: Map<string, Iterable<Hex>>,
+  parentMap: Map<Hex, Hex>,
+  preimageKeys: Map<Hex, Hex>
+): Record<string, ParentPreimage[]> {
+  const learned: Record<string, ParentPreimage[]> = {};
+  for (const [address, slots] of slotsByContract) {
+    const links = new Map<Hex, ParentPreimage>();
+    for (const slot of slots) {
+      let current = word('0x' + slot.slice(2).padStart(64, '0'));
+      for (let parent = parentMap.get(current); parent; parent = parentMap.get(current)) {
+        const key = preimageKeys.get(current);
+        if (!key || links.has(current) || keccak256(concat([key, parent])) !== current) break;
+        links.set(current, { slot: current, parent, key });
+        current = parent;
+      }
+    }
+    if (links.size > 0) learned[address.toLowerCase()] = [...links.values()];
+  }
+  return learned;
+}
+
+// Merges `learned` into `base`, keeping the first preimage seen for each slot
+function merged(
+  base: Record<string, ParentPreimage[]>,
+  learned: Record<string, readonly ParentPreimage[]>
+): { contracts: Record<string, ParentPreimage[]>; added: number } {
+  const contracts = { ...base };
+  let added = 0;
+  for (const [address, preimages] of Object.entries(learned)) {
+    const addr = address.toLowerCase();
+    const known = new Set((contracts[addr] ?? []).map(p => p.slot));
+    const fresh = preimages.filter(p => !known.has(word(p.slot)));
+    if (fresh.length === 0) continue;
+    contracts[addr] = [...(contracts[addr] ?? []), ...fresh];
+    added += fresh.length;
+  }
+  return { contracts, added };
+}
+
+/**
+ * Adds `learned` to the store on disk, merged with entries other runs saved since it was
+ * loaded. Returns how many preimages were new.
+ */
+export async function savePreimageStore(
+  store: PreimageStore,
+  learned: Record<string, readonly ParentPreimage[]>
+): Promise<number> {
+  if (Object.values(learned).every(p => p.length === 0)) return 0;
+  return withKeyedLock(store.file, async () => {
+    const current = await loadPreimageStore(path.dirname(store.file), store.chainId);
+    const { contracts, added } = merged(current.contracts, learned);
+    if (added === 0) return 0;
+    const contents: z.infer<typeof PreimageStoreFileSchema> = {
+      version: 1,
+      chainId: store.chainId,
+      contracts: fromPreimages(contracts),
+    };
+    await fs.mkdir(path.dirname(store.file), { recursive: true });
+    const tmp = `${store.file}.${process.pid}.tmp`;
+    await fs.writeFile(tmp, JSON.stringify(contents, null, 2) + '\n');
+    await fs.rename(tmp, store.file);
+    return added;
+  });
+}
+
+// Every chain's store under `dir`, for handing to another facilitator
+export async function exportPreimageStore(dir: string): Promise<PreimageExport> {
+  let names: string[];
+  try {
+    names = await fs.readdir(dir);
+  } catch (err) {
+    if ((err as NodeJS.ErrnoException).code === 'ENOENT') return { version: 1, chains: {} };
+    throw err;
+  }
+  const chains: PreimageExport['chains'] = {};
+  for (const name of names.sort()) {
+    const m = name.match(/^preimages-(\d+)\.json$/);
+    if (!m) continue;
+    const store = await loadPreimageStore(dir, m[1]);
+    chains[m[1]] = fromPreimages(store.contracts);
+  }
+  return { version: 1, chains };
+}
+
+/**
+ * Merges an export into the stores under `dir`. The whole file is validated before anything is
+ * written, and entries already in a store are kept. Returns the new preimages per chain.
+ */
+export async function importPreimageStore(
+  dir: string,
+  text: string
+): Promise<Record<string, number>> {
+  const parsed = PreimageExportSchema.safeParse(JSON.parse(text));
+  if (!parsed.success) {
+    throw new Error('Invalid preimage export: expected { "version": 1, "chains": { ... } }');
+  }
+  const byChain = Object.entries(parsed.data.chains).map(([chainId, contracts]) => {
+    if (!/^\d+$/.test(chainId)) {
+      throw new Error(`Invalid preimage export: chain ${chainId} is not a chain ID`);
+    }
+    return { chainId, learned: toPreimages(contracts, `export, chain ${chainId}`) };
+  });
+  const added: Record<string, number> = {};
+  for (const { chainId, learned } of byChain) {
+    added[chainId] = await savePreimageStore(await loadPreimageStore(dir, chainId), learned);
+  }
+  return added;
+}
@@ -0,0 +1,126 @@
+import { z } from 'zod';
+import { Hex, zeroAddress } from 'viem';
+import { describeZodIssues, HexValueSchema } from './config-schemas';
+import { DecodeError } from './errors';
+import { AccountAccessKind, VmSafeAccountAccess, VmSafeStorageAccess } from './vm-safe';
+
+const PrestateAccountSchema = z.object({
+  balance: HexValueSchema.optional(),
+  nonce: z.number().int().nonnegative().optional(),
+  code: HexValueSchema.optional(),
+  storage: z.record(HexValueSchema).optional(),
+});
+
+const PrestateDiffSchema = z.object({
+  pre: z.record(PrestateAccountSchema),
+  post: z.record(PrestateAccountSchema),
+});
+
+export type PrestateAccount = z.infer<typeof PrestateAccountSchema>;
+export type PrestateDiff = z.infer<typeof PrestateDiffSchema>;
+
+/**
+ * Accepts either the bare tracer result ({ pre, post }) or a full JSON-RPC response
+ * ({ jsonrpc, id, result: { pre, post } }).
+ */
+export function parsePrestateTrace(raw: unknown): PrestateDiff {
+  const candidate =
+    raw && typeof raw === 'object' && 'result' in raw ? (raw as { result: unknown }).result : raw;
+  const parsed = PrestateDiffSchema.safeParse(candidate);
+  if (!parsed.success) {
+    const issues = describeZodIssues(parsed.error);
+    throw new DecodeError(
+      `Invalid prestateTracer output (expected diffMode { pre, post }): ${issues}`
+    );
+  }
+  return parsed.data;
+}
+
+function toWord(value: string | undefined): Hex {
+  const body = (value ?? '0x0').toLowerCase().replace(/^0x/, '');
+  return ('0x' + body.padStart(64, '0')) as Hex;
+}
+
+function lowercaseKeys<T>(record: Record<string, T> | undefined): Map<string, T> {
+  return new Map(Object.entries(record ?? {}).map(([k, v]) => [k.toLowerCase(), v]));
+}
+
+/**
+ * Converts a prestateTracer diff into synthetic VmSafe account accesses so the regular
+ * state-diff pipeline can consume it.
+ *
+ * In diffMode, `pre` holds the original values of everything that changed and `post` holds
+ * the new values. A slot present in `pre` but missing from `post` was cleared, and an account
+ * missing from `post` entirely was deleted.
+ */
+export function prestateTraceToAccountAccesses(diff: PrestateDiff): VmSafeAccountAccess[] {
+  const preAccounts = lowercaseKeys(diff.pre);
+  const postAccounts = lowercaseKeys(diff.post);
+  const accounts = new Set([...preAccounts.keys(), ...postAccounts.keys()]);
+
+  const accesses: VmSafeAccountAccess[] = [];
+  for (const account of Array.from(accounts).sort()) {
+    const pre = preAccounts.get(account);
+    const post = postAccounts.get(account);
+    const deleted = pre !== undefined && post === undefined;
+
+    const preStorage = lowercaseKeys(pre?.storage);
+    const postStorage = lowercaseKeys(post?.storage);
+    const slots = new Set([...preStorage.keys(), ...postStorage.keys()]);
+
+    const storageAccesses: VmSafeStorageAccess[] = Array.from(slots)
+      .sort()
+      .map(slot => ({
+        account,
+        slot: toWord(slot),
+        isWrite: true,
+        previousValue: toWord(preStorage.get(slot)),
+        newValue: deleted ? toWord(undefined) : toWord(postStorage.get(slot)),
+        reverted: false,
+      }));
+
+    const oldBalance = BigInt(pre?.balance ?? '0x0');
+    const newBalance = deleted ? BigInt(0) : BigInt(post?.balance ?? pre?.balance ?? '0x0');
+
+    accesses.push({
+      chainInfo: { forkId: BigInt(0), chainId: BigInt(0) },
+      kind: pre === undefined ? AccountAccessKind.Create : AccountAccessKind.Call,
+      account,
+      accessor: zeroAddress,
+      initialized: pre !== undefined,
+      oldBalance,
+      newBalance,
+      deployedCode: (pre === undefined ? (post?.code ?? '0x') : '0x') as Hex,
+      value: BigInt(0),
+      data: '0x',
+      reverted: false,
+      storageAccesses,
+      depth: BigInt(0),
+      oldNonce: BigInt(pre?.nonce ?? 0),
+      newNonce: BigInt(post?.nonce ?? pre?.nonce ?? 0),
+    });
+
+    if (deleted) {
+      // The trace does not say where the balance went, so the beneficiary is left unknown
+      accesses.push({
+        chainInfo: { forkId: BigInt(0), chainId: BigInt(0) },
+        kind: AccountAccessKind.SelfDestruct,
+        account: zeroAddress,
+        accessor: account,
+        initialized: true,
+        oldBalance: BigInt(0),
+        newBalance: BigInt(0),
+        deployedCode: (pre?.code ?? '0x') as Hex,
+        value: oldBalance,
+        data: '0x',
+        reverted: false,
+        storageAccesses: [],
+        depth: BigInt(0),
+        oldNonce: BigInt(0),
+        newNonce: BigInt(0),
+      });
+    }
+  }
+
+  return accesses;
+}
@@ -0,0 +1,130 @@
+import { AsyncLocalStorage } from 'async_hooks';
+
+/**
+ * Progress line for the CLIs. While a phase (decode, an RPC enrichment, the forge run) is in
+ * flight a spinner with its elapsed time, and a percentage when the phase reports one, is drawn
+ * on stderr, so a hung RPC can be told apart from a slow decode. Phases are the telemetry spans
+ * (withSpan), so nothing is drawn until enableProgress is called.
+ */
+
+const FRAMES = ['⠋', '⠙', '⠹', '⠸', '⠼', '⠴', '⠦', '⠧', '⠇', '⠏'];
+const REDRAW_MS = 100;
+const CONSOLE_METHODS = ['log', 'info', 'warn', 'error'] as const;
+
+type Phase = {
+  label: string;
+  startedAt: number;
+  done?: number;
+  total?: number;
+};
+
+const phaseContext = new AsyncLocalStorage<Phase>();
+// Phases in the order they started; batch runs several files at once
+const active: Phase[] = [];
+let output: NodeJS.WriteStream | null = null;
+let timer: NodeJS.Timeout | null = null;
+let frame = 0;
+let drawn = false;
+let restoreConsole: (() => void) | null = null;
+
+/**
+ * Turns the progress line on. It is only drawn on a terminal, so redirected output and CI logs
+ * stay clean; returns whether it was enabled.
+ */
+export function enableProgress(stream: NodeJS.WriteStream = process.stderr): boolean {
+  if (output || !stream.isTTY) return false;
+  output = stream;
+
+  // Clear the line before anything else is printed, and draw it again afterwards
+  const originals = CONSOLE_METHODS.map(method => [method, console[method]] as const);
+  for (const [method, original] of originals) {
+    console[method] = (...args: unknown[]) => {
+      clear();
+      original.apply(console, args);
+      draw();
+    };
+  }
+  restoreConsole = () => {
+    for (const [method, original] of originals) console[method] = original;
+  };
+  return true;
+}
+
+export function disableProgress(): void {
+  stopTimer();
+  clear();
+  restoreConsole?.();
+  restoreConsole = null;
+  output = null;
+  active.length = 0;
+}
+
+/**
+ * Runs fn as a phase shown on the progress line.
+ */
+export async function withPhase<T>(label: string, fn: () => Promise<T>): Promise<T> {
+  if (!output) return fn();
+
+  const phase: Phase = { label, startedAt: Date.now() };
+  active.push(phase);
+  startTimer();
+  try {
+    return await phaseContext.run(phase, fn);
+  } finally {
+    active.splice(active.indexOf(phase), 1);
+    if (active.length === 0) {
+      stopTimer();
+      clear();
+    }
+  }
+}
+
+/**
+ * Records how far the current phase is, e.g. 12 of 40 contracts inspected.
+ */
+export function reportProgress(done: number, total: number): void {
+  const phase = phaseContext.getStore();
+  if (!phase) return;
+  phase.done = done;
+  phase.total = total;
+}
+
+export function formatPhase(phase: Phase, now: number): string {
+  const elapsed = `${((now - phase.startedAt) / 1000).toFixed(1)}s`;
+  if (phase.total === undefined || phase.done === undefined || phase.total === 0) {
+    return `${phase.label} ${elapsed}`;
+  }
+  const percent = Math.floor((phase.done / phase.total) * 100);
+  return `${phase.label} ${phase.done}/${phase.total} (${percent}%) ${elapsed}`;
+}
+
+function startTimer(): void {
+  if (timer) return;
+  timer = setInterval(() => {
+    frame = (frame + 1) % FRAMES.length;
+    clear();
+    draw();
+  }, REDRAW_MS);
+  // The progress line must never keep the process alive
+  timer.unref();
+}
+
+function stopTimer(): void {
+  if (timer) clearInterval(timer);
+  timer = null;
+}
+
+function draw(): void {
+  const phase = active[active.length - 1];
+  if (!output || !phase) return;
+  const others = active.length > 1 ? ` (+${active.length - 1} more)` : '';
+  const line = `${FRAMES[frame]} ${formatPhase(phase, Date.now())}${others}`;
+  output.write(line.slice(0, Math.max((output.columns ?? 80) - 1, 1)));
+  drawn = true;
+}
+
+function clear(): void {
+  if (!output || !drawn) return;
+  output.write('\r\x1b[K');
+  drawn = false;
+}
@@ -0,0 +1,92 @@
+import { readdirSync, readFileSync, statSync } from 'fs';
+import path from 'path';
+import { parseFromString } from './parser';
+import type { RecentlyModified, TaskConfig } from './types';
+
+export type HistoryEntry = { file: string; config: TaskConfig };
+
+function listJsonFiles(dir: string): string[] {
+  const files: string[] = [];
+  for (const name of readdirSync(dir).sort()) {
+    const full = path.join(dir, name);
+    if (statSync(full).isDirectory()) {
+      files.push(...listJsonFiles(full));
+    } else if (name.endsWith('.json')) {
+      files.push(full);
+    }
+  }
+  return files;
+}
+
+/**
+ * The `tasks/<task>` directory a file belongs to, or null when it is not inside one.
+ */
+export function owningTaskDir(file: string): string | null {
+  let dir = path.dirname(path.resolve(file));
+  while (path.dirname(dir) !== dir) {
+    if (path.basename(path.dirname(dir)) === 'tasks') return dir;
+    dir = path.dirname(dir);
+  }
+  return null;
+}
+
+/**
+ * Loads every validation file under `dir`. Files inside `excludeDir` (the task being
+ * generated, whose other signer files change the same contracts) are left out, and JSON files
+ * that are not validation files are counted as skipped.
+ */
+export function loadHistory(
+  dir: string,
+  excludeDir?: string
+): { entries: HistoryEntry[]; skipped: number } {
+  const root = path.resolve(dir);
+  const entries: HistoryEntry[] = [];
+  let skipped = 0;
+  for (const file of listJsonFiles(root)) {
+    if (excludeDir && file.startsWith(path.resolve(excludeDir) + path.sep)) continue;
+    const parsed = parseFromString(readFileSync(file, 'utf-8'));
+    if (!('config' in parsed)) {
+      skipped++;
+      continue;
+    }
+    entries.push({ file: path.relative(root, file), config: parsed.config });
+  }
+  return { entries, skipped };
+}
+
+/**
+ * Lists earlier validation files that change the same contracts as `config`, with the slots
+ * both change. Files for a different chain are ignored when both record their chain.
+ */
+export function findRecentModifications(
+  config: TaskConfig,
+  history: readonly HistoryEntry[]
+): RecentlyModified[] {
+  const found: RecentlyModified[] = [];
+  for (const change of config.stateChanges) {
+    const keys = new Set(change.changes.map(c => c.key.toLowerCase()));
+    for (const entry of history) {
+      const other = entry.config;
+      if (config.chainId !== undefined && other.chainId !== undefined) {
+        if (config.chainId !== other.chainId) continue;
+      }
+      const match = other.stateChanges.find(
+        c => c.address.toLowerCase() === change.address.toLowerCase()
+      );
+      if (!match) continue;
+      found.push({
+        name: change.name,
+        address: change.address,
+        file: entry.file,
+        keys: match.changes.map(c => c.key.toLowerCase()).filter(key => keys.has(key)),
+      });
+    }
+  }
+  return found.sort((a, b) => a.address.localeCompare(b.address) || a.file.localeCompare(b.file));
+}
+
+export function describeRecentModification(m: RecentlyModified): string {
+  const slots =
+    m.keys.length > 0 ? `the same ${m.keys.length} slot(s)` : 'different slots of the contract';
+  return `${m.name} (${m.address}) was also changed by ${m.file}, which touches ${slots}`;
+}
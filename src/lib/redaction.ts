@@ -0,0 +1,169 @@
+import { randomBytes } from 'crypto';
+import { z } from 'zod';
+import { concat, Hex, keccak256, toBytes, toHex } from 'viem';
+import { AddressSchema, describeZodIssues } from './config-schemas';
+
+export const REDACTED_PLACEHOLDER = '<<Redacted>>';
+export const REDACTED_CALLDATA_PLACEHOLDER = '<<RedactedCalldata>>';
+
+// Hex blobs longer than a single 32-byte word are treated as raw calldata.
+const CALLDATA_PATTERN = /0x[0-9a-fA-F]{65,}/g;
+
+// Storage entry fields that are blanked when the entry's slot matches a privacy pattern.
+const SLOT_FIELDS = new Set(['key', 'value', 'before', 'after', 'values']);
+
+const PrivacyListSchema = z.object({
+  addresses: z.array(AddressSchema).default([]),
+  slotPatterns: z
+    .array(
+      z.string().refine(
+        pattern => {
+          try {
+            new RegExp(pattern);
+            return true;
+          } catch {
+            return false;
+          }
+        },
+        { message: 'Invalid regular expression' }
+      )
+    )
+    .default([]),
+});
+
+export type PrivacyList = z.infer<typeof PrivacyListSchema>;
+
+export type Redaction = {
+  path: string;
+  hash: Hex;
+};
+
+export type Redacted<T> = T & { redactions: Redaction[] };
+
+type RedactionContext = {
+  addressPattern?: RegExp;
+  slotPatterns: RegExp[];
+  salt: Hex;
+  redactions: Redaction[];
+};
+
+/**
+ * A fresh salt for redactTaskConfig. It must stay with the full file: without it, a redacted
+ * address or storage word could be recovered by hashing candidates such as known contracts.
+ */
+export function newRedactionSalt(): Hex {
+  return toHex(new Uint8Array(randomBytes(32)));
+}
+
+function commitment(value: string, salt: Hex): Hex {
+  return keccak256(concat([salt, toBytes(value)]));
+}
+
+// The shareable copy genValidationFile.ts --redact writes next to the full file
+export function redactedCopyPath(validationPath: string): string {
+  return validationPath.replace(/(\.\w+)?$/, '.redacted$1');
+}
+
+export function parsePrivacyList(raw: unknown): PrivacyList {
+  const parsed = PrivacyListSchema.safeParse(raw);
+  if (!parsed.success) {
+    const issues = describeZodIssues(parsed.error);
+    throw new Error(`Invalid privacy list: ${issues}`);
+  }
+  return parsed.data;
+}
+
+function joinPath(parent: string, key: string | number): string {
+  if (typeof key === 'number') return `${parent}[${key}]`;
+  return parent ? `${parent}.${key}` : key;
+}
+
+function redactString(value: string, path: string, ctx: RedactionContext, force: boolean): string {
+  let redacted = REDACTED_PLACEHOLDER;
+  if (!force) {
+    redacted = value.replace(CALLDATA_PATTERN, REDACTED_CALLDATA_PLACEHOLDER);
+    if (ctx.addressPattern) redacted = redacted.replace(ctx.addressPattern, REDACTED_PLACEHOLDER);
+  }
+
+  if (redacted !== value) {
+    ctx.redactions.push({ path, hash: commitment(value, ctx.salt) });
+  }
+  return redacted;
+}
+
+function redactNode(node: unknown, path: string, ctx: RedactionContext, force: boolean): unknown {
+  if (typeof node === 'string') return redactString(node, path, ctx, force);
+  if (Array.isArray(node)) {
+    return node.map((item, i) => redactNode(item, joinPath(path, i), ctx, force));
+  }
+  if (node && typeof node === 'object') {
+    const record = node as Record<string, unknown>;
+    const slot = record.key;
+    const slotMatches =
+      typeof slot === 'string' && ctx.slotPatterns.some(pattern => pattern.test(slot));
+    return Object.fromEntries(
+      Object.entries(record).map(([key, value]) => [
+        key,
+        redactNode(value, joinPath(path, key), ctx, slotMatches && SLOT_FIELDS.has(key)),
+      ])
+    );
+  }
+  return node;
+}
+
+/**
+ * Produces a shareable copy of a validation file. Raw calldata and every occurrence of a
+ * privacy-listed address (including left-padded storage words) are replaced with
+ * placeholders, and storage entries whose slot matches a privacy pattern are blanked.
+ *
+ * Each redacted string is recorded with keccak256(salt . original value) so the full file
+ * can later be checked against the redacted one with `verifyRedactions` and the same salt.
+ */
+export function redactTaskConfig<T extends object>(
+  config: T,
+  privacy: PrivacyList,
+  salt: Hex
+): Redacted<T> {
+  const bodies = privacy.addresses.map(address => address.slice(2));
+  const ctx: RedactionContext = {
+    // Optional 0x and 12 zero bytes so padded storage words are replaced as a whole.
+    addressPattern: bodies.length
+      ? new RegExp(`(?:0x(?:0{24})?)?(?:${bodies.join('|')})`, 'gi')
+      : undefined,
+    slotPatterns: privacy.slotPatterns.map(pattern => new RegExp(pattern, 'i')),
+    salt,
+    redactions: [],
+  };
+
+  const redacted = redactNode(config, '', ctx, false) as T;
+  return { ...redacted, redactions: ctx.redactions };
+}
+
+function collectStrings(node: unknown, path: string, out: Map<string, string>): void {
+  if (typeof node === 'string') {
+    out.set(path, node);
+  } else if (Array.isArray(node)) {
+    node.forEach((item, i) => collectStrings(item, joinPath(path, i), out));
+  } else if (node && typeof node === 'object') {
+    for (const [key, value] of Object.entries(node)) {
+      collectStrings(value, joinPath(path, key), out);
+    }
+  }
+}
+
+/**
+ * Checks a full validation file against the redaction hashes of its shared copy, with the salt
+ * kept in the full file's `redactionSalt`. Returns the paths whose original value is missing or
+ * does not match; empty means verified.
+ */
+export function verifyRedactions(full: unknown, redactions: Redaction[], salt: Hex): string[] {
+  const leaves = new Map<string, string>();
+  collectStrings(full, '', leaves);
+
+  return redactions
+    .filter(({ path, hash }) => {
+      const original = leaves.get(path);
+      return original === undefined || commitment(original, salt) !== hash;
+    })
+    .map(({ path }) => path);
+}
@@ -0,0 +1,117 @@
+import { PolicyViolationError } from './errors';
+import type { TaskConfig } from './types/index';
+
+/**
+ * Reports tailored to who reads them. Signers get the hashes they compare on their device and
+ * everything validation compares; facilitators, who build and debug the task, also get the
+ * write trace and the warnings; auditors get everything, including the raw payloads the report
+ * was decoded from. One table says which reader each section of the file is written for, so
+ * the views cannot drift apart as sections are added. A view only changes what is shown:
+ * validation compares the whole re-run whatever the file's audience.
+ */
+export const REPORT_AUDIENCES = ['signer', 'facilitator', 'auditor'] as const;
+
+export type ReportAudience = (typeof REPORT_AUDIENCES)[number];
+
+type Section = Exclude<keyof TaskConfig, 'audience'>;
+
+/**
+ * The first audience each section is written for; every later audience gets it too. Sections
+ * validation compares or needs to re-run the task the same way (overrides, preimages, scope,
+ * environment) are kept for signers even though they do not read them all.
+ */
+export const SECTION_AUDIENCES: Record<Section, ReportAudience> = {
+  cmd: 'signer',
+  ledgerId: 'signer',
+  rpcUrl: 'signer',
+  chainId: 'signer',
+  chainName: 'signer',
+  expectedDomainAndMessageHashes: 'signer',
+  dataToSignForm: 'signer',
+  safeNonce: 'signer',
+  stateOverrides: 'signer',
+  stateChanges: 'signer',
+  balanceChanges: 'signer',
+  ethTransfers: 'signer',
+  accountDeletions: 'signer',
+  codeChanges: 'signer',
+  safeConfigurationChanges: 'signer',
+  extraPreimages: 'signer',
+  payloadSignatures: 'facilitator',
+  execution: 'signer',
+  criticalReads: 'facilitator',
+  simulatedAt: 'signer',
+  summary: 'facilitator',
+  raw: 'auditor',
+  intermediateWrites: 'facilitator',
+  recentlyModified: 'facilitator',
+  scope: 'signer',
+  l2GasEstimation: 'facilitator',
+  prestateFrom: 'signer',
+  simulationEnv: 'signer',
+  signerInstructions: 'signer',
+  nestedHashes: 'signer',
+  warnings: 'facilitator',
+  attestation: 'signer',
+  redactionSalt: 'signer',
+  skipTaskOriginValidation: 'signer',
+  hideTaskOriginSkippedPage: 'signer',
+  taskOriginConfig: 'signer',
+  generatedBy: 'signer',
+};
+
+// Sections validation compares with its re-run in full, so every view must keep them
+export const VALIDATED_SECTIONS = ['stateOverrides', 'stateChanges', 'balanceChanges'] as const;
+
+// The write history of a change is part of the trace, so it goes with intermediateWrites
+const CHANGE_HISTORY_AUDIENCE: ReportAudience = SECTION_AUDIENCES.intermediateWrites;
+
+export function parseReportAudience(value: string): ReportAudience {
+  if (!(REPORT_AUDIENCES as readonly string[]).includes(value)) {
+    throw new Error(`--audience must be one of ${REPORT_AUDIENCES.join(', ')}, not "${value}"`);
+  }
+  return value as ReportAudience;
+}
+
+export function audienceSees(audience: ReportAudience, section: ReportAudience): boolean {
+  return REPORT_AUDIENCES.indexOf(audience) >= REPORT_AUDIENCES.indexOf(section);
+}
+
+// Sections of the file left out for the audience, in file order
+export function omittedSections(config: TaskConfig, audience: ReportAudience): Section[] {
+  // audience is not a section, so it is never in the table
+  return (Object.keys(config) as Section[]).filter(
+    key => key in SECTION_AUDIENCES && !audienceSees(audience, SECTION_AUDIENCES[key])
+  );
+}
+
+/**
+ * The file as the audience reads it, with `audience` recorded. Validation never applies a view
+ * to its re-run, so the sections it compares are kept in every view.
+ */
+export function applyReportAudience(config: TaskConfig, audience: ReportAudience): TaskConfig {
+  const view: Record<string, unknown> = { ...config };
+  for (const section of omittedSections(config, audience)) delete view[section];
+  return {
+    ...(view as TaskConfig),
+    stateChanges: audienceSees(audience, CHANGE_HISTORY_AUDIENCE)
+      ? config.stateChanges
+      : config.stateChanges.map(sc => ({
+          ...sc,
+          changes: sc.changes.map(({ history: _history, ...change }) => change),
+        })),
+    audience,
+  };
+}
+
+/**
+ * Refuses a file written for an audience that leaves out `unlisted` overrides or changes the
+ * re-run found, such as a signer view whose overrides were emptied, rather than comparing what
+ * is left of it.
+ */
+export function assertCompleteView(config: TaskConfig, unlisted: number): void {
+  if (!config.audience || unlisted === 0) return;
+  throw new PolicyViolationError(
+    `The validation file is a ${config.audience} view that leaves out ${unlisted} override(s) or change(s) the simulation finds; regenerate it before validating`
+  );
+}
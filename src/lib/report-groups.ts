@@ -0,0 +1,65 @@
+import type { ReportSummary } from './types/index';
+import { isUnknownName } from './unknown-entries';
+
+/**
+ * Categories a report's contracts are grouped in, riskiest first. Contracts nothing describes
+ * lead, then the Safes whose owners and thresholds gate everything else, then the proxies and
+ * implementations that decide what code runs, then tokens. A contract's category comes from the
+ * `tags` of its contracts.json entry; named contracts without a category tag are `other`.
+ */
+export const REPORT_CATEGORIES = [
+  'unknown',
+  'safe',
+  'proxy',
+  'implementation',
+  'token',
+  'other',
+] as const;
+
+export type ReportCategory = (typeof REPORT_CATEGORIES)[number];
+
+// Contracts touched before the summary groups them; smaller tasks are read top to bottom
+export const GROUPING_THRESHOLD = 50;
+
+type ContractCounts = ReportSummary['contracts'][number];
+type ReportGroup = NonNullable<ReportSummary['groups']>[number];
+
+const CATEGORY_TAGS: readonly string[] = REPORT_CATEGORIES.filter(
+  c => c !== 'unknown' && c !== 'other'
+);
+
+export function categoryOf(name: string, tags: readonly string[] = []): ReportCategory {
+  const tagged = CATEGORY_TAGS.find(c => tags.includes(c));
+  if (tagged) return tagged as ReportCategory;
+  return isUnknownName(name) ? 'unknown' : 'other';
+}
+
+/**
+ * Groups per-contract counts by category, in REPORT_CATEGORIES order; empty groups are left out.
+ * Within a group, contracts with the most changed slots come first.
+ */
+export function groupContracts(contracts: readonly ContractCounts[]): ReportGroup[] {
+  return REPORT_CATEGORIES.flatMap(category => {
+    const members = contracts
+      .filter(c => (c.category ?? categoryOf(c.name)) === category)
+      .sort((a, b) => b.slotsChanged - a.slotsChanged || a.address.localeCompare(b.address));
+    if (members.length === 0) return [];
+    return [
+      {
+        category,
+        contracts: members.length,
+        slotsChanged: members.reduce((n, c) => n + c.slotsChanged, 0),
+        overrides: members.reduce((n, c) => n + c.overrides, 0),
+        addresses: members.map(c => c.address),
+      },
+    ];
+  });
+}
+
+// Table of contents lines, one per group, e.g. "safe: 3 contract(s), 12 slot(s) changed"
+export function describeReportGroups(groups: readonly ReportGroup[]): string[] {
+  return groups.map(
+    g =>
+      `${g.category}: ${g.contracts} contract(s), ${g.slotsChanged} slot(s) changed, ${g.overrides} override(s)`
+  );
+}
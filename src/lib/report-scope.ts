@@ -0,0 +1,165 @@
+import { Address, getAddress, isAddress } from 'viem';
+import type { ReportCategory } from './report-groups';
+import { summarizeReport } from './report-summary';
+import type { ReportScope, ReportSummary, TaskConfig } from './types/index';
+
+export type ScopeFilter = Pick<ReportScope, 'only' | 'exclude'>;
+
+/**
+ * Parses a comma-separated address list such as `0xA,0xB` from a CLI flag.
+ */
+export function parseAddressList(value: string, flag: string): Address[] {
+  return value
+    .split(',')
+    .map(entry => entry.trim())
+    .filter(entry => entry.length > 0)
+    .map(entry => {
+      if (!isAddress(entry, { strict: false })) {
+        throw new Error(`${flag}: invalid address "${entry}"`);
+      }
+      return getAddress(entry);
+    });
+}
+
+/**
+ * Drops report entries for contracts outside the scope. An address is kept when `only` is
+ * empty or lists it, and `exclude` does not. The number of dropped entries is recorded under
+ * `scope.filtered` so reviewers can see that something was left out.
+ */
+export function applyReportScope(config: TaskConfig, filter: ScopeFilter): TaskConfig {
+  const only = new Set((filter.only ?? []).map(a => a.toLowerCase()));
+  const exclude = new Set((filter.exclude ?? []).map(a => a.toLowerCase()));
+  const inScope = (address: string) =>
+    (only.size === 0 || only.has(address.toLowerCase())) && !exclude.has(address.toLowerCase());
+
+  const balanceChanges = config.balanceChanges ?? [];
+  const droppedOverrides = config.stateOverrides.filter(o => !inScope(o.address));
+  const droppedChanges = config.stateChanges.filter(sc => !inScope(sc.address));
+
+  const stateOverrides = config.stateOverrides.filter(o => inScope(o.address));
+  const stateChanges = config.stateChanges.filter(sc => inScope(sc.address));
+  const scopedBalanceChanges =
+    config.balanceChanges && balanceChanges.filter(b => inScope(b.address));
+  // A transfer is kept when either side is in scope
+  const ethTransfers = config.ethTransfers?.filter(
+    t => (t.from !== undefined && inScope(t.from)) || (t.to !== undefined && inScope(t.to))
+  );
+  const accountDeletions = config.accountDeletions?.filter(d => inScope(d.address));
+  const codeChanges = config.codeChanges?.filter(c => inScope(c.address));
+  const intermediateWrites = config.intermediateWrites?.filter(w => inScope(w.address));
+  const criticalReads = config.criticalReads?.filter(r => inScope(r.address));
+  const recentlyModified = config.recentlyModified?.filter(m => inScope(m.address));
+  const dropped = (all?: unknown[], kept?: unknown[]) => (all?.length ?? 0) - (kept?.length ?? 0);
+
+  return {
+    ...config,
+    stateOverrides,
+    stateChanges,
+    ...(scopedBalanceChanges && { balanceChanges: scopedBalanceChanges }),
+    ...(ethTransfers && { ethTransfers }),
+    ...(accountDeletions && { accountDeletions }),
+    ...(codeChanges && { codeChanges }),
+    ...(intermediateWrites && { intermediateWrites }),
+    ...(criticalReads && { criticalReads }),
+    ...(recentlyModified && { recentlyModified }),
+    // The summary describes what the file lists, so it is recounted for the scope, keeping the
+    // categories the full report gave each contract and the noise it left out
+    ...(config.summary && {
+      summary: {
+        ...summarizeReport(
+          { stateOverrides, stateChanges, balanceChanges: scopedBalanceChanges },
+          summaryCategories(config.summary)
+        ),
+        ...(config.summary.noiseFiltered && { noiseFiltered: config.summary.noiseFiltered }),
+      },
+    }),
+    scope: {
+      ...(filter.only?.length ? { only: filter.only } : {}),
+      ...(filter.exclude?.length ? { exclude: filter.exclude } : {}),
+      filtered: {
+        stateOverrides: droppedOverrides.reduce((n, o) => n + o.overrides.length, 0),
+        stateChanges: droppedChanges.reduce((n, sc) => n + sc.changes.length, 0),
+        balanceChanges: balanceChanges.filter(b => !inScope(b.address)).length,
+        ethTransfers: dropped(config.ethTransfers, ethTransfers),
+        accountDeletions: dropped(config.accountDeletions, accountDeletions),
+        codeChanges: dropped(config.codeChanges, codeChanges),
+        intermediateWrites: dropped(config.intermediateWrites, intermediateWrites),
+        criticalReads: dropped(config.criticalReads, criticalReads),
+        recentlyModified: dropped(config.recentlyModified, recentlyModified),
+      },
+    },
+  };
+}
+
+function summaryCategories(summary: ReportSummary): Map<string, ReportCategory> | undefined {
+  const categorized = summary.contracts.filter(c => c.category !== undefined);
+  if (categorized.length === 0) return undefined;
+  return new Map(categorized.map(c => [c.address.toLowerCase(), c.category!]));
+}
+
+const OTHER_FILTERED_LABELS = {
+  ethTransfers: 'ETH transfer(s)',
+  accountDeletions: 'account deletion(s)',
+  codeChanges: 'code change(s)',
+  intermediateWrites: 'intermediate write(s)',
+  criticalReads: 'critical read(s)',
+  recentlyModified: 'recent modification(s)',
+} as const;
+
+/**
+ * One-line summary of what a scope left out, or null when nothing was filtered. The other
+ * sections are only named when the scope dropped some of their entries.
+ */
+export function describeFilteredCounts(scope: ReportScope): string | null {
+  const { stateOverrides, stateChanges, balanceChanges } = scope.filtered;
+  const parts = [
+    `${stateChanges} state change(s)`,
+    `${stateOverrides} override(s)`,
+    `${balanceChanges} balance change(s)`,
+  ];
+  let total = stateOverrides + stateChanges + balanceChanges;
+  for (const [section, label] of Object.entries(OTHER_FILTERED_LABELS)) {
+    const count = scope.filtered[section as keyof typeof OTHER_FILTERED_LABELS] ?? 0;
+    if (count > 0) parts.push(`${count} ${label}`);
+    total += count;
+  }
+  if (total === 0) return null;
+  return `${parts.slice(0, -1).join(', ')} and ${parts[parts.length - 1]} are outside the report scope`;
+}
+
+export type UnlistedEntries = Pick<
+  ReportScope['filtered'],
+  'stateOverrides' | 'stateChanges' | 'balanceChanges'
+> & { contracts: Address[] };
+
+type EntryKey = { address: string; key: string };
+
+const overrideKeys = (config: TaskConfig): EntryKey[] =>
+  config.stateOverrides.flatMap(o => o.overrides.map(ov => ({ address: o.address, key: ov.key })));
+const changeKeys = (config: TaskConfig): EntryKey[] =>
+  config.stateChanges.flatMap(sc => sc.changes.map(c => ({ address: sc.address, key: c.key })));
+const balanceKeys = (config: TaskConfig): EntryKey[] =>
+  (config.balanceChanges ?? []).map(b => ({ address: b.address, key: b.field }));
+
+/**
+ * Entries of the `actual` simulation that `expected` does not list, by contract and slot, or
+ * by contract and field for balances. A validation file is untrusted, so what it leaves out,
+ * through its scope or by hand, is counted against it rather than filtered from the re-run.
+ */
+export function findUnlistedEntries(expected: TaskConfig, actual: TaskConfig): UnlistedEntries {
+  const id = (e: EntryKey) => `${e.address.toLowerCase()}:${e.key.toLowerCase()}`;
+  const contracts = new Set<Address>();
+  const unlisted = (keys: (config: TaskConfig) => EntryKey[]) => {
+    const listed = new Set(keys(expected).map(id));
+    const missing = keys(actual).filter(e => !listed.has(id(e)));
+    missing.forEach(e => contracts.add(getAddress(e.address)));
+    return missing.length;
+  };
+
+  return {
+    stateOverrides: unlisted(overrideKeys),
+    stateChanges: unlisted(changeKeys),
+    balanceChanges: unlisted(balanceKeys),
+    contracts: Array.from(contracts),
+  };
+}
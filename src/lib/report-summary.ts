@@ -0,0 +1,78 @@
+import { Address, hexToBigInt, Hex } from 'viem';
+import { categoryOf, groupContracts, GROUPING_THRESHOLD, ReportCategory } from './report-groups';
+import type { ReportSummary, TaskConfig } from './types/index';
+import { isUnknownDescription, isUnknownName } from './unknown-entries';
+
+type ContractCounts = ReportSummary['contracts'][number];
+
+/**
+ * Counts and per-contract rollups of a report, so dashboards and reviewers can size a task
+ * without walking the changes arrays. ETH moved is the sum of balance increases, which equals
+ * what left the other accounts unless ETH was minted or burned.
+ *
+ * With `categories` (lowercased address to category, from contracts.json tags) each contract
+ * records its category, and tasks touching GROUPING_THRESHOLD or more contracts get `groups`.
+ */
+export function summarizeReport(
+  config: Pick<TaskConfig, 'stateOverrides' | 'stateChanges' | 'balanceChanges'>,
+  categories?: ReadonlyMap<string, ReportCategory>
+): ReportSummary {
+  const contracts = new Map<string, ContractCounts>();
+  const entry = (name: string, address: Address): ContractCounts => {
+    const key = address.toLowerCase();
+    let counts = contracts.get(key);
+    if (!counts) {
+      counts = {
+        name,
+        address,
+        slotsChanged: 0,
+        overrides: 0,
+        balanceChanged: false,
+        ...(categories ? { category: categories.get(key) ?? categoryOf(name) } : {}),
+      };
+      contracts.set(key, counts);
+    }
+    return counts;
+  };
+
+  for (const o of config.stateOverrides) {
+    entry(o.name, o.address).overrides += o.overrides.length;
+  }
+  for (const sc of config.stateChanges) {
+    entry(sc.name, sc.address).slotsChanged += sc.changes.length;
+  }
+
+  let ethMoved = BigInt(0);
+  for (const b of config.balanceChanges ?? []) {
+    entry(b.name, b.address).balanceChanged = true;
+    const delta = hexToBigInt(b.after as Hex) - hexToBigInt(b.before as Hex);
+    if (delta > BigInt(0)) ethMoved += delta;
+  }
+
+  const all = Array.from(contracts.values()).sort((a, b) => a.address.localeCompare(b.address));
+  const contractsTouched = all.filter(c => c.slotsChanged > 0 || c.balanceChanged).length;
+  return {
+    contractsTouched,
+    unknownContracts: all.filter(c => isUnknownName(c.name)).length,
+    slotsChanged: config.stateChanges.reduce((n, sc) => n + sc.changes.length, 0),
+    unknownSlots: config.stateChanges.reduce(
+      (n, sc) => n + sc.changes.filter(c => isUnknownDescription(c.description)).length,
+      0
+    ),
+    overridesApplied: config.stateOverrides.reduce((n, o) => n + o.overrides.length, 0),
+    ethMoved: ethMoved.toString(),
+    contracts: all,
+    ...(categories && contractsTouched >= GROUPING_THRESHOLD
+      ? { groups: groupContracts(all) }
+      : {}),
+  };
+}
+
+export function describeReportSummary(summary: ReportSummary): string {
+  const unknown =
+    summary.unknownContracts !== undefined ? ` (${summary.unknownContracts} unknown)` : '';
+  const noise = summary.noiseFiltered
+    ? `, ${summary.noiseFiltered.stateChanges + summary.noiseFiltered.balanceChanges} noise change(s) filtered`
+    : '';
+  return `${summary.contractsTouched} contract(s) touched${unknown}, ${summary.slotsChanged} slot(s) changed (${summary.unknownSlots} unknown), ${summary.overridesApplied} override(s), ${summary.ethMoved} wei moved${noise}`;
+}
@@ -0,0 +1,122 @@
+import type { ReportWarning } from './types/index';
+
+/**
+ * Warnings in a form automated gates can act on. Every subsystem that finds something a
+ * reviewer should look at reports it under a stable code with a severity, so a CI gate can
+ * fail on `ACCOUNT_DELETED` while letting `UNKNOWN_SLOTS` through, without matching on message
+ * text that may be reworded. The message is what is printed to the console.
+ */
+export const WARNING_SEVERITIES = ['info', 'warning', 'critical'] as const;
+
+export type WarningSeverity = (typeof WARNING_SEVERITIES)[number];
+
+// Each code's severity; codes are never renamed once released, since gates match on them
+export const WARNING_CODES = {
+  // Found while generating the report
+  SUSPICIOUS_WRITE: 'warning',
+  SAFE_NONCE_MISMATCH: 'warning',
+  ACCOUNT_DELETED: 'critical',
+  SAFE_CONFIGURATION_CHANGED: 'critical',
+  EXECUTION_FAILED: 'critical',
+  CHAIN_MISMATCH: 'critical',
+  PERMIT_EXPIRED: 'critical',
+  PERMIT_NONCE_MISMATCH: 'critical',
+  PERMIT_NONCE_UNPREDICTED: 'info',
+  UNKNOWN_CONTRACTS: 'warning',
+  UNKNOWN_SLOTS: 'warning',
+  RECENTLY_MODIFIED: 'warning',
+  NO_NESTED_SAFES: 'info',
+  RPC_DEGRADED: 'warning',
+  POLICY_FINDING: 'warning',
+  UNKNOWN_SAFE_SINGLETON: 'warning',
+  SAFE_DOMAIN_MISMATCH: 'critical',
+  // Found while validating a file against a fresh simulation
+  STALE_PRESTATE: 'warning',
+  PRESTATE_REORGED: 'warning',
+  PRESTATE_DEPENDENCY: 'info',
+  PRESTATE_NOT_APPLIED: 'critical',
+  MISSING_SECRETS: 'warning',
+  OVERRIDE_MISMATCH: 'critical',
+  NESTED_HASH_MISMATCH: 'critical',
+  UNLISTED_ENTRIES: 'critical',
+  ETH_TRANSFERS_DIFFER: 'critical',
+  ACCOUNT_DELETIONS_DIFFER: 'critical',
+  CODE_CHANGES_DIFFER: 'critical',
+  SAFE_CONFIGURATION_DIFFERS: 'critical',
+  PAYLOAD_SIGNATURES: 'info',
+  QUORUM_UNAVAILABLE: 'info',
+} as const satisfies Record<string, WarningSeverity>;
+
+export type WarningCode = keyof typeof WARNING_CODES;
+
+// Validation codes for a re-run that disagrees with the file; like a mismatched state change,
+// these block signing
+export const BLOCKING_WARNING_CODES: ReadonlySet<string> = new Set<WarningCode>([
+  'UNLISTED_ENTRIES',
+  'PRESTATE_NOT_APPLIED',
+  'CODE_CHANGES_DIFFER',
+  'SAFE_CONFIGURATION_DIFFERS',
+  // Overrides the task does not document are a common way to fake a prestate
+  'OVERRIDE_MISMATCH',
+  // An owner Safe's signers would sign a hash the re-run does not reproduce
+  'NESTED_HASH_MISMATCH',
+  'ETH_TRANSFERS_DIFFER',
+  'ACCOUNT_DELETIONS_DIFFER',
+  // A transaction that reverts or whose Safe call fails must never be signed
+  'EXECUTION_FAILED',
+]);
+
+export function isBlockingWarning(warning: ReportWarning): boolean {
+  return BLOCKING_WARNING_CODES.has(warning.code);
+}
+
+export function reportWarning(
+  code: WarningCode,
+  message: string,
+  context?: ReportWarning['context']
+): ReportWarning {
+  return {
+    code,
+    severity: WARNING_CODES[code],
+    message,
+    ...(context && Object.keys(context).length > 0 ? { context } : {}),
+  };
+}
+
+/**
+ * Warnings for contracts and slots the report could not name, from its summary. Placeholders
+ * are expected while a task is drafted, so these do not fail a gate on their own.
+ */
+export function unknownEntryWarnings(
+  summary: { unknownContracts?: number; unknownSlots: number } | undefined
+): ReportWarning[] {
+  const warnings: ReportWarning[] = [];
+  if (summary?.unknownContracts) {
+    warnings.push(
+      reportWarning(
+        'UNKNOWN_CONTRACTS',
+        `${summary.unknownContracts} contract(s) are not described in contracts.json`,
+        { count: summary.unknownContracts }
+      )
+    );
+  }
+  if (summary?.unknownSlots) {
+    warnings.push(
+      reportWarning(
+        'UNKNOWN_SLOTS',
+        `${summary.unknownSlots} changed slot(s) have no description`,
+        { count: summary.unknownSlots }
+      )
+    );
+  }
+  return warnings;
+}
+
+// The config with `found` appended to its warnings; unchanged when nothing was found
+export function appendWarnings<T extends { warnings?: ReportWarning[] }>(
+  config: T,
+  found: readonly ReportWarning[]
+): T {
+  if (found.length === 0) return config;
+  return { ...config, warnings: [...(config.warnings ?? []), ...found] };
+}
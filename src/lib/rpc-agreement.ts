@@ -0,0 +1,220 @@
+import {
+  Address,
+  BaseError,
+  createPublicClient,
+  encodeFunctionData,
+  getAddress,
+  Hex,
+  HttpRequestError,
+  keccak256,
+  parseAbi,
+  PublicClient,
+  TimeoutError,
+  Transport,
+} from 'viem';
+import { PolicyViolationError } from './errors';
+import type { TaskConfig } from './types/index';
+
+/**
+ * Second opinion on what the report's RPC said about the chain. Forge forks from one endpoint,
+ * so a compromised or broken provider can feed it false "before" values, code, or Safe owners
+ * and the report would describe a transaction that is not the one being signed. Everything the
+ * report relies on is read again from an independent provider at the same block, and both
+ * providers, and the report, must agree.
+ */
+
+// Second RPC URL the validation service checks every simulation against; none when unset
+export const COMPARE_RPC_ENV = 'STATE_DIFF_COMPARE_RPC_URL';
+
+export function compareRpcFromEnv(env: NodeJS.ProcessEnv = process.env): string | null {
+  return env[COMPARE_RPC_ENV] || null;
+}
+
+const SAFE_METADATA_ABI = parseAbi([
+  'function nonce() view returns (uint256)',
+  'function getThreshold() view returns (uint256)',
+  'function getOwners() view returns (address[])',
+]);
+
+export type RpcDisagreement = {
+  // What was read, e.g. "code hash of 0x..."
+  subject: string;
+  primary: string;
+  secondary: string;
+  // Set when both providers agree but the report says otherwise
+  reported?: string;
+};
+
+export type RpcAgreement = {
+  chainId: number;
+  blockNumber: bigint;
+  slots: number;
+  balances: number;
+  codeHashes: number;
+  disagreements: RpcDisagreement[];
+};
+
+type ComparedReport = Pick<
+  TaskConfig,
+  | 'simulatedAt'
+  | 'stateOverrides'
+  | 'stateChanges'
+  | 'balanceChanges'
+  | 'expectedDomainAndMessageHashes'
+  | 'prestateFrom'
+>;
+
+/**
+ * Reads the chain ID, the hash of the block when the report recorded it, the before value of
+ * every changed slot and balance, the code of every account the report names, and the target
+ * Safe's nonce, threshold, and owners from both providers at the block the report was built at.
+ * Slots the report overrides, and every before value of a report simulated on top of another
+ * task (--prestate-from), are only compared between the providers, since the report does not
+ * hold their on-chain values.
+ */
+export async function compareRpcs(
+  report: ComparedReport,
+  primary: Transport,
+  secondary: Transport
+): Promise<RpcAgreement> {
+  const clients = [primary, secondary].map(transport => createPublicClient({ transport }));
+  const both = <T>(read: (client: PublicClient) => Promise<T>) =>
+    Promise.all(clients.map(client => read(client as PublicClient)));
+  const disagreements: RpcDisagreement[] = [];
+
+  const [chainId, secondaryChainId] = await both(c => c.getChainId());
+  if (chainId !== secondaryChainId) {
+    // Nothing else is comparable across chains
+    disagreements.push({
+      subject: 'chain ID',
+      primary: String(chainId),
+      secondary: String(secondaryChainId),
+    });
+    const none = { blockNumber: BigInt(0), slots: 0, balances: 0, codeHashes: 0 };
+    return { chainId, ...none, disagreements };
+  }
+
+  const blockNumber = report.simulatedAt
+    ? BigInt(report.simulatedAt.blockNumber)
+    : await clients[0].getBlockNumber();
+  const check = (subject: string, [a, b]: string[], reported?: string) => {
+    if (a !== b) disagreements.push({ subject, primary: a, secondary: b });
+    else if (reported !== undefined && reported !== a) {
+      disagreements.push({ subject, primary: a, secondary: b, reported });
+    }
+  };
+
+  if (report.simulatedAt?.blockHash) {
+    // Otherwise the providers may be reading different blocks of the same height. A reorg that
+    // both providers followed is reported by validation instead (block-reorg.ts)
+    const hashes = await both(async client =>
+      String((await client.getBlock({ blockNumber })).hash)
+    );
+    check(`hash of block ${blockNumber}`, hashes);
+  }
+
+  const overridden = new Set(
+    report.stateOverrides.flatMap(o => o.overrides.map(v => slotId(o.address, v.key)))
+  );
+  const compareToReport = !report.prestateFrom;
+  const slots = report.stateChanges.flatMap(sc =>
+    sc.changes.map(c => ({ address: getAddress(sc.address), c }))
+  );
+  await Promise.all(
+    slots.map(async ({ address, c }) => {
+      const values = await both(async client => {
+        const value = await client.getStorageAt({ address, slot: c.key as Hex, blockNumber });
+        return normalizeWord(value ?? '0x');
+      });
+      const reported =
+        compareToReport && !overridden.has(slotId(address, c.key))
+          ? normalizeWord(c.before)
+          : undefined;
+      check(`storage of ${address} at ${c.key}`, values, reported);
+    })
+  );
+
+  const balances = report.balanceChanges ?? [];
+  await Promise.all(
+    balances.map(async b => {
+      const values = await both(async client =>
+        String(await client.getBalance({ address: b.address as Address, blockNumber }))
+      );
+      const reported = compareToReport ? String(BigInt(b.before)) : undefined;
+      check(`balance of ${getAddress(b.address)}`, values, reported);
+    })
+  );
+
+  const safe = getAddress(report.expectedDomainAndMessageHashes.address);
+  const accounts = Array.from(
+    new Set([safe, ...report.stateChanges.map(sc => getAddress(sc.address))])
+  );
+  await Promise.all(
+    accounts.map(async address => {
+      const values = await both(async client => {
+        const code = (await client.getCode({ address, blockNumber })) ?? '0x';
+        return code === '0x' ? 'no code' : keccak256(code);
+      });
+      check(`code hash of ${address}`, values);
+    })
+  );
+
+  // Raw results, so a target that is not a Safe (e.g. a permit's token) agrees by reverting
+  for (const functionName of ['nonce', 'getThreshold', 'getOwners'] as const) {
+    const data = encodeFunctionData({ abi: SAFE_METADATA_ABI, functionName });
+    const values = await both(client => rawCall(client, safe, data, blockNumber));
+    check(`${functionName}() of ${safe}`, values);
+  }
+
+  return {
+    chainId,
+    blockNumber,
+    slots: slots.length,
+    balances: balances.length,
+    codeHashes: accounts.length,
+    disagreements,
+  };
+}
+
+export function checkRpcAgreement(agreement: RpcAgreement): void {
+  if (agreement.disagreements.length === 0) return;
+  throw new PolicyViolationError(
+    `The RPCs disagree, so one of them may be compromised or out of sync:\n${agreement.disagreements.map(d => `  - ${describeRpcDisagreement(d)}`).join('\n')}`
+  );
+}
+
+export function describeRpcDisagreement(d: RpcDisagreement): string {
+  if (d.reported !== undefined) {
+    return `${d.subject}: both RPCs return ${d.primary}, but the report was built with ${d.reported}`;
+  }
+  return `${d.subject}: the RPC returns ${d.primary}, the second RPC returns ${d.secondary}`;
+}
+
+export function describeRpcAgreement(agreement: RpcAgreement): string {
+  return `The second RPC agrees on chain ${agreement.chainId} at block ${agreement.blockNumber}: ${agreement.slots} slot(s), ${agreement.balances} balance(s), ${agreement.codeHashes} code hash(es), and the Safe's nonce, threshold, and owners`;
+}
+
+function slotId(address: string, key: string): string {
+  return `${address.toLowerCase()}:${normalizeWord(key)}`;
+}
+
+function normalizeWord(value: string): Hex {
+  return `0x${value.toLowerCase().replace(/^0x/, '').padStart(64, '0')}`;
+}
+
+async function rawCall(
+  client: PublicClient,
+  to: Address,
+  data: Hex,
+  blockNumber: bigint
+): Promise<string> {
+  try {
+    return (await client.call({ to, data, blockNumber })).data ?? '0x';
+  } catch (err) {
+    const transportError =
+      err instanceof BaseError &&
+      err.walk(e => e instanceof HttpRequestError || e instanceof TimeoutError) !== null;
+    if (!(err instanceof BaseError) || transportError) throw err;
+    return 'reverted';
+  }
+}
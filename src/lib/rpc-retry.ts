@@ -0,0 +1,162 @@
+import {
+  BaseError,
+  custom,
+  HttpRequestError,
+  LimitExceededRpcError,
+  TimeoutError,
+  Transport,
+} from 'viem';
+import { reportWarning } from './report-warnings';
+import type { ReportWarning } from './types/index';
+
+/**
+ * Retries for the RPC lookups a report makes. Providers rate-limit bursts (HTTP 429) and have
+ * brief outages, and a report makes hundreds of getCode and getStorageAt calls, so one failed
+ * lookup should not abort the run. Failed requests are retried with exponential backoff and
+ * jitter. A per-run budget caps the total retries, so a provider that is down fails the run
+ * quickly instead of stalling it, and every lookup that needed retries is logged so the report
+ * can say its enrichment was degraded.
+ */
+export type RetryPolicy = {
+  // Tries per request, including the first
+  maxAttempts: number;
+  baseDelayMs: number;
+  maxDelayMs: number;
+  // Retries allowed across the whole run
+  budget: number;
+};
+
+export const RETRY_ENV = {
+  maxAttempts: 'STATE_DIFF_RPC_MAX_ATTEMPTS',
+  baseDelayMs: 'STATE_DIFF_RPC_BASE_DELAY_MS',
+  maxDelayMs: 'STATE_DIFF_RPC_MAX_DELAY_MS',
+  budget: 'STATE_DIFF_RPC_RETRY_BUDGET',
+} as const;
+
+export const DEFAULT_RETRY_POLICY: RetryPolicy = {
+  maxAttempts: 4,
+  baseDelayMs: 250,
+  maxDelayMs: 4000,
+  budget: 50,
+};
+
+export function retryPolicyFromEnv(env: NodeJS.ProcessEnv = process.env): RetryPolicy {
+  const read = (key: keyof RetryPolicy, min: number): number => {
+    const value = env[RETRY_ENV[key]];
+    if (!value) return DEFAULT_RETRY_POLICY[key];
+    const n = Number(value);
+    if (!Number.isInteger(n) || n < min) {
+      throw new Error(`${RETRY_ENV[key]} must be an integer of at least ${min}`);
+    }
+    return n;
+  };
+  return {
+    maxAttempts: read('maxAttempts', 1),
+    baseDelayMs: read('baseDelayMs', 0),
+    maxDelayMs: read('maxDelayMs', 0),
+    budget: read('budget', 0),
+  };
+}
+
+// A lookup that failed at least once; `error` is set when every attempt failed
+export type DegradedLookup = { method: string; attempts: number; error?: string };
+
+export type RetryLog = {
+  retries: number;
+  degraded: DegradedLookup[];
+  budgetExhausted: boolean;
+};
+
+export function newRetryLog(): RetryLog {
+  return { retries: 0, degraded: [], budgetExhausted: false };
+}
+
+// Rate limits, server errors, timeouts, and failed connections; not reverts or bad requests
+export function isRetryableRpcError(err: unknown): boolean {
+  if (!(err instanceof BaseError)) return false;
+  const retryable = err.walk(
+    e =>
+      (e instanceof HttpRequestError &&
+        (e.status === undefined || e.status === 429 || e.status >= 500)) ||
+      e instanceof TimeoutError ||
+      e instanceof LimitExceededRpcError
+  );
+  return retryable !== null;
+}
+
+// The delay before retry `attempt` (1 for the first retry): half fixed, half random
+export function backoffDelay(
+  policy: RetryPolicy,
+  attempt: number,
+  random: () => number = Math.random
+): number {
+  const ceiling = Math.min(policy.maxDelayMs, policy.baseDelayMs * 2 ** (attempt - 1));
+  return Math.round(ceiling / 2 + random() * (ceiling / 2));
+}
+
+/**
+ * Wraps `base` so failed requests are retried under `policy`, recording into `log`. The
+ * wrapped transport's own retries are turned off so the two do not multiply.
+ */
+export function retryingTransport(
+  base: Transport,
+  policy: RetryPolicy,
+  log: RetryLog,
+  options: { sleep?: (ms: number) => Promise<void>; random?: () => number } = {}
+): Transport {
+  const sleep = options.sleep ?? (ms => new Promise<void>(resolve => setTimeout(resolve, ms)));
+  return params => {
+    const inner = base({ ...params, retryCount: 0 });
+    const request = async (args: { method: string; params?: unknown }) => {
+      let entry: DegradedLookup | undefined;
+      for (let attempt = 1; ; attempt++) {
+        try {
+          const result = await inner.request(args as Parameters<typeof inner.request>[0]);
+          if (entry) entry.attempts = attempt;
+          return result;
+        } catch (err) {
+          if (!isRetryableRpcError(err)) throw err;
+          if (!entry) {
+            entry = { method: args.method, attempts: attempt };
+            log.degraded.push(entry);
+          }
+          entry.attempts = attempt;
+          if (attempt >= policy.maxAttempts || log.retries >= policy.budget) {
+            if (log.retries >= policy.budget) log.budgetExhausted = true;
+            entry.error = err instanceof BaseError ? err.shortMessage : String(err);
+            throw err;
+          }
+          log.retries++;
+          await sleep(backoffDelay(policy, attempt, options.random));
+        }
+      }
+    };
+    return custom({ request }, { key: inner.config.key, retryCount: 0 })(params);
+  };
+}
+
+/**
+ * One warning summing up the lookups that needed retries, or none when every lookup succeeded
+ * at once. Names the methods, so a reviewer can tell whether the slow part was code or
+ * storage reads.
+ */
+export function retryWarnings(log: RetryLog): ReportWarning[] {
+  if (log.degraded.length === 0) return [];
+  const failed = log.degraded.filter(d => d.error !== undefined).length;
+  const byMethod = new Map<string, number>();
+  for (const d of log.degraded) byMethod.set(d.method, (byMethod.get(d.method) ?? 0) + 1);
+  const methods = Array.from(byMethod, ([method, n]) => `${method} x${n}`).join(', ');
+  const budget = log.budgetExhausted ? '; the retry budget ran out' : '';
+  return [
+    reportWarning(
+      'RPC_DEGRADED',
+      `${log.degraded.length} RPC lookup(s) needed retries (${methods}), ${log.retries} retry(ies) in total, ${failed} failed${budget}`,
+      {
+        lookups: log.degraded.length,
+        retries: log.retries,
+        failed,
+        budgetExhausted: log.budgetExhausted,
+      }
+    ),
+  ];
+}
@@ -0,0 +1,97 @@
+import { Address, getAddress, Hex, PublicClient } from 'viem';
+import type { StorageDiff } from './account-aggregation';
+import { CodeSnapshot, codeSnapshot } from './code-changes';
+import { AccountAccessKind, VmSafeAccountAccess } from './vm-safe';
+
+// keccak256('guard_manager.guard.address')
+export const SAFE_GUARD_SLOT =
+  '0x4a204f620c8c5ccdca3fd54d003badd85ba500436a431f0cbda4f558c93c34c8';
+// keccak256('fallback_manager.handler.address')
+export const SAFE_FALLBACK_HANDLER_SLOT =
+  '0x6c9a6c4a39284e37ed1cf53d337577d14212a4870fb976a4366c693b939918d5';
+// keccak256('module_manager.module_guard.address'), Safe 1.5
+export const SAFE_MODULE_GUARD_SLOT =
+  '0xb104e0b93118902c651344349b610029d694cfdec91c589c91ebafbcd0289947';
+
+export type SafeSetting = 'guard' | 'moduleGuard' | 'fallbackHandler';
+
+const SETTING_SLOTS: [SafeSetting, string][] = [
+  ['guard', SAFE_GUARD_SLOT],
+  ['moduleGuard', SAFE_MODULE_GUARD_SLOT],
+  ['fallbackHandler', SAFE_FALLBACK_HANDLER_SLOT],
+];
+
+export type SafeConfigDiff = {
+  // Lowercased address of the Safe
+  address: string;
+  setting: SafeSetting;
+  // Null when unset
+  before: Address | null;
+  after: Address | null;
+  // Code of the new guard or handler; null when it has none
+  afterCode: CodeSnapshot | null;
+};
+
+function addressFromWord(word: Hex): Address | null {
+  return BigInt(word) === BigInt(0) ? null : getAddress('0x' + word.slice(-40));
+}
+
+/**
+ * Finds writes to the slots where a Safe keeps its guard, module guard, and fallback handler.
+ * These decide which transactions the Safe accepts and how it answers calls it does not
+ * implement, so they are reported on every account whether or not contracts.json describes it.
+ * The new guard or handler's code is taken from the trace when the transaction deploys it and
+ * read from the node otherwise.
+ */
+export async function findSafeConfigurationChanges(
+  client: Pick<PublicClient, 'getCode'>,
+  decoded: readonly VmSafeAccountAccess[],
+  storage: Map<string, StorageDiff>
+): Promise<SafeConfigDiff[]> {
+  const created = new Map<string, Hex>();
+  for (const access of decoded) {
+    if (access.kind !== AccountAccessKind.Create || access.reverted) continue;
+    created.set(access.account.toLowerCase(), access.deployedCode);
+  }
+
+  const changes: SafeConfigDiff[] = [];
+  for (const diff of storage.values()) {
+    for (const [setting, key] of SETTING_SLOTS) {
+      const slot = diff.storageDiffs.get(key);
+      if (!slot || slot.before === slot.after) continue;
+      const after = addressFromWord(slot.after);
+      const code = after
+        ? (created.get(after.toLowerCase()) ?? (await client.getCode({ address: after })) ?? '0x')
+        : '0x';
+      changes.push({
+        address: diff.address,
+        setting,
+        before: addressFromWord(slot.before),
+        after,
+        afterCode: codeSnapshot(code),
+      });
+    }
+  }
+  return changes.sort((a, b) => a.address.localeCompare(b.address));
+}
+
+const SETTING_NAMES: Record<SafeSetting, string> = {
+  guard: 'guard',
+  moduleGuard: 'module guard',
+  fallbackHandler: 'fallback handler',
+};
+
+export function describeSafeConfigurationChange(change: SafeConfigDiff): string {
+  const code = change.afterCode ? `${change.afterCode.size} bytes` : 'no code';
+  const to = change.after ? `${change.after} (${code})` : 'unset';
+  return `${change.address} ${SETTING_NAMES[change.setting]} ${change.before ?? 'unset'} -> ${to}`;
+}
+
+/**
+ * A guard without code makes the Safe's own check call revert, so every later transaction or
+ * module call fails and the Safe cannot undo the change.
+ */
+export function safeConfigurationWarning(change: SafeConfigDiff): string | null {
+  if (change.setting === 'fallbackHandler' || !change.after || change.afterCode) return null;
+  return `${change.address} ${SETTING_NAMES[change.setting]} is set to ${change.after}, which has no code; the Safe would be unable to execute transactions`;
+}
@@ -0,0 +1,10 @@
+import { concatHex, Hex, keccak256 } from 'viem';
+
+/**
+ * Reconstructs the Safe transaction hash, keccak256(0x1901 || domainHash || messageHash).
+ * This is the value the Safe web interface shows as the transaction hash, so signers can
+ * cross-check it against what they see there.
+ */
+export function computeSafeTxHash(domainHash: string, messageHash: string): Hex {
+  return keccak256(concatHex(['0x1901', domainHash as Hex, messageHash as Hex]));
+}
@@ -0,0 +1,144 @@
+import {
+  Address,
+  createPublicClient,
+  decodeFunctionData,
+  encodeAbiParameters,
+  Hex,
+  http,
+  isAddressEqual,
+  keccak256,
+  parseAbi,
+  toBytes,
+} from 'viem';
+
+export const SAFE_NONCE_ABI = parseAbi(['function nonce() view returns (uint256)']);
+
+const EXEC_TRANSACTION_ABI = parseAbi([
+  'function execTransaction(address to, uint256 value, bytes data, uint8 operation, uint256 safeTxGas, uint256 baseGas, uint256 gasPrice, address gasToken, address refundReceiver, bytes signatures) payable returns (bool)',
+]);
+
+const SAFE_TX_TYPEHASH = keccak256(
+  toBytes(
+    'SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 baseGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)'
+  )
+);
+
+const SAFE_TX_PARAMS = [
+  { type: 'bytes32' },
+  { type: 'address' },
+  { type: 'uint256' },
+  { type: 'bytes32' },
+  { type: 'uint8' },
+  { type: 'uint256' },
+  { type: 'uint256' },
+  { type: 'uint256' },
+  { type: 'address' },
+  { type: 'address' },
+  { type: 'uint256' },
+] as const;
+
+// SafeTx fields other than the nonce, as execTransaction takes them
+export type SafeTx = {
+  to: Address;
+  value: bigint;
+  data: Hex;
+  operation: number;
+  safeTxGas: bigint;
+  baseGas: bigint;
+  gasPrice: bigint;
+  gasToken: Address;
+  refundReceiver: Address;
+};
+
+// The EIP-712 struct hash of a SafeTx, the message hash a Safe owner signs
+export function safeTxMessageHash(tx: SafeTx, nonce: bigint): Hex {
+  return keccak256(
+    encodeAbiParameters(SAFE_TX_PARAMS, [
+      SAFE_TX_TYPEHASH,
+      tx.to,
+      tx.value,
+      keccak256(tx.data),
+      tx.operation,
+      tx.safeTxGas,
+      tx.baseGas,
+      tx.gasPrice,
+      tx.gasToken,
+      tx.refundReceiver,
+      nonce,
+    ])
+  );
+}
+
+// How far either side of the on-chain nonce to search when recovering it from a message hash
+const NONCE_SEARCH_WINDOW = 128;
+
+export async function readSafeNonce(rpcUrl: string, safe: Address): Promise<bigint> {
+  const client = createPublicClient({ transport: http(rpcUrl) });
+  return client.readContract({ address: safe, abi: SAFE_NONCE_ABI, functionName: 'nonce' });
+}
+
+/**
+ * Decodes `execTransaction` calldata, or returns null when `data` is some other call.
+ */
+export function decodeExecTransaction(data: Hex) {
+  try {
+    const decoded = decodeFunctionData({ abi: EXEC_TRANSACTION_ABI, data });
+    return decoded.functionName === 'execTransaction' ? decoded.args : null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Recovers the nonce a SafeTx was hashed with. The nonce is not in the calldata, but when the
+ * simulated call is `execTransaction` on the signing Safe every other SafeTx field is, so the
+ * message hash can be recomputed for nonces near the on-chain one until it matches.
+ * Returns null when the calldata is not such a call or no nonce in the window matches.
+ */
+export function recoverSafeTxNonce(params: {
+  safe: Address;
+  to: Address;
+  data: Hex;
+  messageHash: Hex;
+  around: bigint;
+}): bigint | null {
+  if (!isAddressEqual(params.to, params.safe)) return null;
+
+  const args = decodeExecTransaction(params.data);
+  if (!args) return null;
+  const [to, value, data, operation, safeTxGas, baseGas, gasPrice, gasToken, refundReceiver] =
+    args;
+  const tx = { to, value, data, operation, safeTxGas, baseGas, gasPrice, gasToken, refundReceiver };
+
+  const span = BigInt(NONCE_SEARCH_WINDOW);
+  const start = params.around > span ? params.around - span : BigInt(0);
+  for (let nonce = start; nonce <= params.around + span; nonce++) {
+    if (safeTxMessageHash(tx, nonce) === params.messageHash.toLowerCase()) return nonce;
+  }
+  return null;
+}
+
+/**
+ * Compares the nonce the SafeTx was built for with the Safe's current nonce. Returns a warning
+ * when they differ, or null when they match.
+ */
+export function describeNonceMismatch(txNonce: bigint, onChainNonce: bigint): string | null {
+  if (txNonce === onChainNonce) return null;
+  if (txNonce < onChainNonce) {
+    return `The transaction uses Safe nonce ${txNonce} but the Safe is already at nonce ${onChainNonce}; signatures for it can never be executed`;
+  }
+  const pending = txNonce - onChainNonce;
+  return `The transaction uses Safe nonce ${txNonce} but the Safe is at nonce ${onChainNonce}; ${pending} other transaction(s) must execute before it`;
+}
+
+/**
+ * Reads the Safe's nonce and compares it with `txNonce`, for nonces given by hand rather than
+ * recovered from calldata.
+ */
+export async function checkSafeNonce(
+  rpcUrl: string,
+  safe: Address,
+  txNonce: number
+): Promise<string | null> {
+  return describeNonceMismatch(BigInt(txNonce), await readSafeNonce(rpcUrl, safe));
+}
@@ -0,0 +1,198 @@
+import {
+  Address,
+  BaseError,
+  createPublicClient,
+  encodeFunctionData,
+  getAddress,
+  Hex,
+  http,
+  HttpRequestError,
+  parseAbi,
+  parseAbiItem,
+  PublicClient,
+  TimeoutError,
+} from 'viem';
+
+const SAFE_ABI = parseAbi([
+  'function getThreshold() view returns (uint256)',
+  'function getOwners() view returns (address[])',
+  'function approvedHashes(address owner, bytes32 hash) view returns (uint256)',
+]);
+
+const APPROVE_HASH_EVENT = parseAbiItem(
+  'event ApproveHash(bytes32 indexed approvedHash, address indexed owner)'
+);
+
+const EIP1271_ABI = parseAbi([
+  'function isValidSignature(bytes32 hash, bytes signature) view returns (bytes4)',
+]);
+
+// Code of an EOA that delegated to a contract with EIP-7702; it still signs with its key
+const DELEGATION_PREFIX = '0xef0100';
+
+/**
+ * How a contract owner provides its signature. EIP-1271 owners sign through their own signers
+ * and the Safe checks isValidSignature; any other contract can only approve on-chain by calling
+ * approveHash itself.
+ */
+export type ContractOwner = {
+  address: Address;
+  signature: 'eip1271' | 'approve-hash';
+};
+
+export type SafeApproval = {
+  owner: Address;
+  txHash: Hex;
+};
+
+export type SafeQuorum = {
+  safe: Address;
+  safeTxHash: Hex;
+  threshold: number;
+  owners: number;
+  // Owners that approved the hash on-chain with approveHash
+  approvedBy: Address[];
+  // The approveHash transactions, for explorer links; absent when the node cannot search logs
+  approvals?: SafeApproval[];
+  // Owners that are contracts; every other owner is an EOA and signs with its key
+  contractOwners: ContractOwner[];
+};
+
+/**
+ * Reads the Safe's threshold and which owners have approved `safeTxHash` on-chain. Signatures
+ * collected off-chain are not visible here, so the count is a lower bound.
+ */
+export async function readSafeQuorum(
+  rpcUrl: string,
+  safe: Address,
+  safeTxHash: Hex
+): Promise<SafeQuorum> {
+  const client = createPublicClient({ transport: http(rpcUrl) });
+  const [threshold, owners] = await Promise.all([
+    client.readContract({ address: safe, abi: SAFE_ABI, functionName: 'getThreshold' }),
+    client.readContract({ address: safe, abi: SAFE_ABI, functionName: 'getOwners' }),
+  ]);
+  const [approvals, contractOwners, approvalTxs] = await Promise.all([
+    Promise.all(
+      owners.map(owner =>
+        client.readContract({
+          address: safe,
+          abi: SAFE_ABI,
+          functionName: 'approvedHashes',
+          args: [owner, safeTxHash],
+        })
+      )
+    ),
+    Promise.all(owners.map(owner => readContractOwner(client, owner, safeTxHash))),
+    readApprovalTxs(client, safe, safeTxHash),
+  ]);
+
+  return {
+    safe: getAddress(safe),
+    safeTxHash,
+    threshold: Number(threshold),
+    owners: owners.length,
+    approvedBy: owners.filter((_, i) => approvals[i] !== BigInt(0)).map(o => getAddress(o)),
+    contractOwners: contractOwners.filter((o): o is ContractOwner => o !== null),
+    ...(approvalTxs && { approvals: approvalTxs }),
+  };
+}
+
+/**
+ * The transactions that approved `safeTxHash`, from the Safe's ApproveHash events. Many nodes
+ * limit log searches over the whole chain, so a failed search leaves the links out rather than
+ * failing the quorum.
+ */
+async function readApprovalTxs(
+  client: PublicClient,
+  safe: Address,
+  safeTxHash: Hex
+): Promise<SafeApproval[] | null> {
+  try {
+    const logs = await client.getLogs({
+      address: safe,
+      event: APPROVE_HASH_EVENT,
+      args: { approvedHash: safeTxHash },
+      fromBlock: 'earliest',
+    });
+    return logs.map(log => ({ owner: getAddress(log.args.owner!), txHash: log.transactionHash! }));
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Returns null for an EOA owner. For a contract owner, probes isValidSignature with an empty
+ * signature: a contract implementing it answers with a bytes4 or rejects the signature with a
+ * reason, while one without it (including a Safe with no fallback handler) returns nothing or
+ * reverts without data.
+ */
+async function readContractOwner(
+  client: PublicClient,
+  owner: Address,
+  safeTxHash: Hex
+): Promise<ContractOwner | null> {
+  const code = await client.getCode({ address: owner });
+  if (!code || code === '0x' || code.startsWith(DELEGATION_PREFIX)) return null;
+
+  const data = encodeFunctionData({
+    abi: EIP1271_ABI,
+    functionName: 'isValidSignature',
+    args: [safeTxHash, '0x'],
+  });
+  let supported: boolean;
+  try {
+    const result = await client.call({ to: owner, data });
+    supported = isBytes4Result(result.data);
+  } catch (err) {
+    const transportError =
+      err instanceof BaseError &&
+      err.walk(e => e instanceof HttpRequestError || e instanceof TimeoutError) !== null;
+    if (!(err instanceof BaseError) || transportError) throw err;
+    supported = hasRevertData(err);
+  }
+  return { address: getAddress(owner), signature: supported ? 'eip1271' : 'approve-hash' };
+}
+
+// An ABI-encoded bytes4: one word, the value left-aligned and the rest zero
+export function isBytes4Result(data: Hex | undefined): boolean {
+  return data !== undefined && data.length === 66 && /^0{56}$/.test(data.slice(10));
+}
+
+function hasRevertData(err: BaseError): boolean {
+  const inner = err.walk() as { data?: unknown };
+  const data =
+    inner.data && typeof inner.data === 'object'
+      ? (inner.data as { data?: unknown }).data
+      : inner.data;
+  return typeof data === 'string' && data.length > 2;
+}
+
+/**
+ * Tells the signer where the transaction stands, e.g. "2 of 3 approvals exist on-chain; your
+ * signature will make the transaction executable".
+ */
+export function describeQuorum(quorum: SafeQuorum): string {
+  const approvals = quorum.approvedBy.length;
+  const status = `${approvals} of ${quorum.threshold} approvals exist on-chain`;
+  if (approvals >= quorum.threshold) {
+    return `${status}; the transaction is already executable without your signature`;
+  }
+  if (approvals + 1 === quorum.threshold) {
+    return `${status}; your signature will make the transaction executable`;
+  }
+  const remaining = quorum.threshold - approvals - 1;
+  return `${status}; ${remaining} more signature(s) will be needed after yours`;
+}
+
+/**
+ * Explains how each contract owner's approval is collected, or returns nothing when every owner
+ * is an EOA.
+ */
+export function describeContractOwners(quorum: SafeQuorum): string[] {
+  return quorum.contractOwners.map(owner =>
+    owner.signature === 'eip1271'
+      ? `Owner ${owner.address} is a contract that signs with EIP-1271; collect its signature from its own signers, not from a key`
+      : `Owner ${owner.address} is a contract without EIP-1271 support; it can only approve on-chain by calling approveHash`
+  );
+}
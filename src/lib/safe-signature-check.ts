@@ -0,0 +1,212 @@
+import {
+  Address,
+  BaseError,
+  concat,
+  createPublicClient,
+  getAddress,
+  Hex,
+  HttpRequestError,
+  isHex,
+  parseAbi,
+  TimeoutError,
+  Transport,
+} from 'viem';
+import { decodeSafeSignatures, SafeSignatureType } from './safe-signatures';
+import { computeSafeTxHash } from './safe-hash';
+
+/**
+ * Checks collected signatures with the Safe itself before anyone submits the transaction. The
+ * Safe's checkNSignatures is called with eth_call, so a signature the Safe would refuse, in the
+ * wrong order, from a non-owner, or with a v the wallet wrote as 0/1, is found while signers are
+ * still around to fix it rather than when execution reverts.
+ */
+
+const SAFE_ABI = parseAbi([
+  'function VERSION() view returns (string)',
+  'function getThreshold() view returns (uint256)',
+  'function checkNSignatures(bytes32 dataHash, bytes data, bytes signatures, uint256 requiredSignatures) view',
+]);
+
+// Safe 1.5 checks approved hashes against an executor instead of msg.sender
+const SAFE_15_ABI = parseAbi([
+  'function checkNSignatures(address executor, bytes32 dataHash, bytes signatures, uint256 requiredSignatures) view',
+]);
+
+// Revert codes of Safe's signature checks, from its Errors documentation
+const SAFE_ERRORS: Record<string, string> = {
+  GS020: 'the signatures are shorter than the threshold requires',
+  GS021: 'a contract signature points inside the static part of the signatures',
+  GS022: 'a contract signature is out of bounds',
+  GS023: 'a contract signature is out of bounds',
+  GS024: "a contract owner's isValidSignature refused its signature",
+  GS025: 'an approved-hash signature is for an owner that has not approved the hash on-chain',
+  GS026: 'a signer is not an owner, or the signatures are not sorted by signer',
+};
+
+export type CollectedSignature = {
+  type: SafeSignatureType;
+  signer: Address | null;
+  // As it is packed for the Safe, after any v correction
+  signature: Hex;
+};
+
+export type SignatureCheck = {
+  safe: Address;
+  safeTxHash: Hex;
+  threshold: number;
+  // Sorted by signer, the order the Safe requires
+  signatures: CollectedSignature[];
+  // What execTransaction takes as its signatures argument
+  packed: Hex;
+  accepted: boolean;
+  // Why the Safe refused them
+  reason?: string;
+  // Corrections made to the input, e.g. a v byte of 0 or 1
+  notes: string[];
+};
+
+/**
+ * Reads collected signatures: a JSON array of hex strings, or hex strings separated by
+ * whitespace or commas. Each may hold one or more 65-byte signatures packed together. Contract
+ * signatures carry dynamic data and must be packed by the Safe tooling that collected them.
+ */
+export function parseCollectedSignatures(text: string): Hex[] {
+  const trimmed = text.trim();
+  const tokens: unknown[] = trimmed.startsWith('[')
+    ? (JSON.parse(trimmed) as unknown[])
+    : trimmed.split(/[\s,]+/).filter(t => t.length > 0);
+  const out: Hex[] = [];
+  for (const token of tokens) {
+    if (typeof token !== 'string' || !isHex(token) || (token.length - 2) % 130 !== 0) {
+      throw new Error(`Not a 65-byte signature or signatures packed together: ${String(token)}`);
+    }
+    for (let i = 2; i < token.length; i += 130) out.push(`0x${token.slice(i, i + 130)}` as Hex);
+  }
+  if (out.length === 0) throw new Error('No signatures were given');
+  return out;
+}
+
+/**
+ * Recovers each signer and sorts the signatures by signer. Wallets that write v as 0 or 1
+ * produce bytes the Safe reads as a contract or approved-hash signature; when r is not an
+ * address, v is taken to mean 27 or 28 and the correction is noted.
+ */
+export async function prepareSignatures(
+  signatures: readonly Hex[],
+  safeTxHash: Hex
+): Promise<{ signatures: CollectedSignature[]; notes: string[] }> {
+  const notes: string[] = [];
+  const prepared: CollectedSignature[] = [];
+  for (const input of signatures) {
+    let signature = input.toLowerCase() as Hex;
+    const v = Number.parseInt(signature.slice(130), 16);
+    const r = signature.slice(2, 66);
+    if (v <= 1 && !/^0{24}/.test(r)) {
+      signature = `${signature.slice(0, 130)}${(v + 27).toString(16)}` as Hex;
+      notes.push(`A signature had v = ${v}; it was changed to ${v + 27}, as ECDSA signatures use`);
+    }
+    const [decoded] = await decodeSafeSignatures(signature, safeTxHash);
+    if (decoded.type === 'contract-signature') {
+      throw new Error('Contract signatures must be packed by the tooling that collected them');
+    }
+    prepared.push({ ...decoded, signature });
+  }
+
+  const seen = new Set<string>();
+  for (const sig of prepared) {
+    if (!sig.signer) continue;
+    if (seen.has(sig.signer)) notes.push(`${sig.signer} signed more than once`);
+    seen.add(sig.signer);
+  }
+  const sorted = [...prepared].sort((a, b) =>
+    (a.signer ?? '').toLowerCase().localeCompare((b.signer ?? '').toLowerCase())
+  );
+  if (sorted.some((sig, i) => sig !== prepared[i])) {
+    notes.push('The signatures were sorted by signer, the order the Safe requires');
+  }
+  return { signatures: sorted, notes };
+}
+
+// The Safe's reason for refusing the signatures, from its GS0xx revert code when there is one
+export function explainSignatureRevert(err: unknown): string {
+  const message = err instanceof BaseError ? err.shortMessage + ' ' + err.message : String(err);
+  const code = message.match(/GS0\d\d/)?.[0];
+  if (code && SAFE_ERRORS[code]) return `${code}: ${SAFE_ERRORS[code]}`;
+  if (code) return code;
+  return err instanceof BaseError ? err.shortMessage : String(err);
+}
+
+function isSafe15(version: string): boolean {
+  const [major, minor] = version.split('.').map(Number);
+  return major > 1 || (major === 1 && minor >= 5);
+}
+
+/**
+ * Calls the Safe's checkNSignatures with the collected signatures for the SafeTx, requiring
+ * `requiredSignatures` or the Safe's threshold. Refusals are reported in the result; RPC
+ * failures are thrown.
+ */
+export async function checkSafeSignatures(params: {
+  transport: Transport;
+  safe: Address;
+  domainHash: Hex;
+  messageHash: Hex;
+  signatures: readonly Hex[];
+  requiredSignatures?: number;
+}): Promise<SignatureCheck> {
+  const client = createPublicClient({ transport: params.transport });
+  const safe = getAddress(params.safe);
+  const safeTxHash = computeSafeTxHash(params.domainHash, params.messageHash);
+  const { signatures, notes } = await prepareSignatures(params.signatures, safeTxHash);
+  const packed = concat(signatures.map(s => s.signature));
+
+  const [version, threshold] = await Promise.all([
+    client.readContract({ address: safe, abi: SAFE_ABI, functionName: 'VERSION' }),
+    client.readContract({ address: safe, abi: SAFE_ABI, functionName: 'getThreshold' }),
+  ]);
+  const required = BigInt(params.requiredSignatures ?? threshold);
+  const result = {
+    safe,
+    safeTxHash,
+    threshold: Number(threshold),
+    signatures,
+    packed,
+    notes,
+  };
+  try {
+    if (isSafe15(version)) {
+      await client.readContract({
+        address: safe,
+        abi: SAFE_15_ABI,
+        functionName: 'checkNSignatures',
+        args: [safe, safeTxHash, packed, required],
+      });
+    } else {
+      // Before 1.5 the Safe passes the hash's preimage to EIP-1271 owners
+      const data = concat(['0x1901', params.domainHash, params.messageHash]);
+      await client.readContract({
+        address: safe,
+        abi: SAFE_ABI,
+        functionName: 'checkNSignatures',
+        args: [safeTxHash, data, packed, required],
+      });
+    }
+  } catch (err) {
+    if (!(err instanceof BaseError) || isTransportFailure(err)) throw err;
+    return { ...result, accepted: false, reason: explainSignatureRevert(err) };
+  }
+  return { ...result, accepted: true };
+}
+
+function isTransportFailure(err: BaseError): boolean {
+  return err.walk(e => e instanceof HttpRequestError || e instanceof TimeoutError) !== null;
+}
+
+export function describeSignatureCheck(check: SignatureCheck): string {
+  const signers = check.signatures
+    .map(s => `${s.signer ?? 'unknown signer'} (${s.type})`)
+    .join(', ');
+  return check.accepted
+    ? `${check.safe} accepts ${check.signatures.length} signature(s) for ${check.safeTxHash} (threshold ${check.threshold}): ${signers}`
+    : `${check.safe} refuses the signatures for ${check.safeTxHash}: ${check.reason}. Signers: ${signers}`;
+}
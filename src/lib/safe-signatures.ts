@@ -0,0 +1,103 @@
+import {
+  Address,
+  getAddress,
+  hashMessage,
+  Hex,
+  hexToBigInt,
+  isAddressEqual,
+  recoverAddress,
+} from 'viem';
+import { decodeExecTransaction } from './safe-nonce';
+
+// How the Safe checks each 65-byte signature, chosen by its v byte
+export const SAFE_SIGNATURE_TYPES = [
+  'ecdsa',
+  'eth_sign',
+  'approved-hash',
+  'contract-signature',
+] as const;
+export type SafeSignatureType = (typeof SAFE_SIGNATURE_TYPES)[number];
+
+export type DecodedSafeSignature = {
+  type: SafeSignatureType;
+  // Null when the signer must be recovered but the hash it signed is not known
+  signer: Address | null;
+};
+
+const SIGNATURE_BYTES = 65;
+
+function signatureType(v: number): SafeSignatureType {
+  if (v === 0) return 'contract-signature';
+  if (v === 1) return 'approved-hash';
+  return v > 30 ? 'eth_sign' : 'ecdsa';
+}
+
+/**
+ * Splits packed Safe signatures into per-signer entries. Approved-hash and contract signatures
+ * carry the owner in r. ECDSA and eth_sign signatures are recovered from `safeTxHash` when it is
+ * known. Contract signatures point at dynamic data appended after the static parts, which
+ * marks where the static parts end.
+ */
+export async function decodeSafeSignatures(
+  signatures: Hex,
+  safeTxHash: Hex | null
+): Promise<DecodedSafeSignature[]> {
+  const body = signatures.slice(2);
+  const byteLength = body.length / 2;
+  const word = (offset: number, bytes: number) =>
+    `0x${body.slice(offset * 2, (offset + bytes) * 2)}` as Hex;
+
+  const decoded: DecodedSafeSignature[] = [];
+  let staticEnd = byteLength;
+  for (let offset = 0; offset + SIGNATURE_BYTES <= staticEnd; offset += SIGNATURE_BYTES) {
+    const r = word(offset, 32);
+    const s = word(offset + 32, 32);
+    const v = Number.parseInt(body.slice((offset + 64) * 2, (offset + 65) * 2), 16);
+    const type = signatureType(v);
+
+    if (type === 'contract-signature' || type === 'approved-hash') {
+      if (type === 'contract-signature') {
+        staticEnd = Math.min(staticEnd, Number(hexToBigInt(s)));
+      }
+      decoded.push({ type, signer: getAddress(`0x${r.slice(-40)}`) });
+      continue;
+    }
+
+    let signer: Address | null = null;
+    if (safeTxHash) {
+      // eth_sign signatures add 4 to v and sign the Ethereum-prefixed hash
+      const hash = type === 'eth_sign' ? hashMessage({ raw: safeTxHash }) : safeTxHash;
+      const recoveryV = type === 'eth_sign' ? v - 4 : v;
+      const signature = `${r}${s.slice(2)}${recoveryV.toString(16).padStart(2, '0')}` as Hex;
+      try {
+        signer = await recoverAddress({ hash, signature });
+      } catch {
+        // A malformed signature would also fail on-chain; report it without a signer
+      }
+    }
+    decoded.push({ type, signer });
+  }
+  return decoded;
+}
+
+/**
+ * Decodes the signatures passed to a simulated `execTransaction` call. The SafeTx hash is only
+ * known when the call is on the Safe being signed for; otherwise ECDSA signers stay unknown.
+ * Returns an empty list for other calls or calls without signatures.
+ */
+export async function decodePayloadSignatures(params: {
+  to: Address;
+  data: Hex;
+  targetSafe: Address;
+  safeTxHash: Hex;
+}): Promise<DecodedSafeSignature[]> {
+  const args = decodeExecTransaction(params.data);
+  if (!args) return [];
+  const signatures = args[9];
+  const hash = isAddressEqual(params.to, params.targetSafe) ? params.safeTxHash : null;
+  return decodeSafeSignatures(signatures, hash);
+}
+
+export function describeSafeSignature(sig: DecodedSafeSignature): string {
+  return `${sig.signer ?? 'unknown signer'} (${sig.type})`;
+}
@@ -0,0 +1,90 @@
+import {
+  Address,
+  encodeAbiParameters,
+  getAddress,
+  Hex,
+  keccak256,
+  PublicClient,
+  toBytes,
+} from 'viem';
+
+/**
+ * Which Safe contract a target Safe runs. A Safe proxy keeps the address of its implementation,
+ * the singleton, in slot 0, and the singletons Safe deploys at the same address on every chain
+ * identify the version. The version decides how the Safe hashes what its owners sign: from
+ * 1.3.0 on the EIP-712 domain includes the chain ID, before it only has the Safe's address.
+ */
+
+// Canonical singleton deployments, from safe-global/safe-deployments, keyed by lowercase address
+const SAFE_SINGLETONS: Record<string, string> = {
+  '0xb6029ea3b2c51d09a50b53ca8012feeb05bda35a': '1.0.0',
+  '0x34cfac646f301356faa8b21e94227e3583fe3f5f': '1.1.1',
+  '0x6851d6fdfafd08c0295c392436245e5bc78b0185': '1.2.0',
+  // GnosisSafe and GnosisSafeL2, canonical and EIP-155 deployments
+  '0xd9db270c1b5e3bd161e8c8503c55ceabee709552': '1.3.0',
+  '0x3e5c63644e683549055b9be8653de26e0b4cd36e': '1.3.0',
+  '0x69f4d1788e39c87893c980c06edf4b7f686e2938': '1.3.0',
+  '0xfb1bffc9d739b8d520daf37df666da4c687191ea': '1.3.0',
+  // Safe and SafeL2
+  '0x41675c099f32341bf84bfc5382af534df5c7461a': '1.4.1',
+  '0x29fcb43b46531bca003ddc8fcb67ffe91900c762': '1.4.1',
+  '0xff51a5898e281db6dfc7855790607438df2ca44b': '1.5.0',
+  '0xedd160febbd92e350d4d398fb636302fccd67c7e': '1.5.0',
+};
+
+const DOMAIN_TYPEHASH = keccak256(
+  toBytes('EIP712Domain(uint256 chainId,address verifyingContract)')
+);
+// Safes before 1.3.0 leave the chain out of their domain
+const LEGACY_DOMAIN_TYPEHASH = keccak256(toBytes('EIP712Domain(address verifyingContract)'));
+
+export function knownSafeVersion(singleton: string): string | null {
+  return SAFE_SINGLETONS[singleton.toLowerCase()] ?? null;
+}
+
+/**
+ * The singleton in slot 0 of `safe`; null when the slot is empty, as it is for an account that
+ * is not a Safe proxy, or cannot be read.
+ */
+export async function readSafeSingleton(
+  client: Pick<PublicClient, 'getStorageAt'>,
+  safe: Address
+): Promise<Address | null> {
+  let word: Hex | undefined;
+  try {
+    word = await client.getStorageAt({ address: safe, slot: '0x0' });
+  } catch {
+    return null;
+  }
+  if (!word || BigInt(word) === BigInt(0)) return null;
+  return getAddress(`0x${word.slice(-40)}`);
+}
+
+function versionAtLeast(version: string, minimum: [number, number]): boolean {
+  const [major, minor] = version.split('.').map(Number);
+  return major > minimum[0] || (major === minimum[0] && minor >= minimum[1]);
+}
+
+// The domain separator a Safe of `version` signs under, as its domainSeparator() returns it
+export function safeDomainHash(version: string, chainId: number, safe: Address): Hex {
+  if (!versionAtLeast(version, [1, 3])) {
+    return keccak256(
+      encodeAbiParameters(
+        [{ type: 'bytes32' }, { type: 'address' }],
+        [LEGACY_DOMAIN_TYPEHASH, getAddress(safe)]
+      )
+    );
+  }
+  return keccak256(
+    encodeAbiParameters(
+      [{ type: 'bytes32' }, { type: 'uint256' }, { type: 'address' }],
+      [DOMAIN_TYPEHASH, BigInt(chainId), getAddress(safe)]
+    )
+  );
+}
+
+export function describeSafeVersion(singleton: string, safeVersion?: string): string {
+  return safeVersion
+    ? `Safe ${safeVersion} (singleton ${singleton})`
+    : `an unknown singleton ${singleton}`;
+}
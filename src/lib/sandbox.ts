@@ -0,0 +1,146 @@
+import { closeSync, existsSync, mkdirSync, openSync } from 'fs';
+import { homedir } from 'os';
+import path from 'path';
+
+/**
+ * Runs the forge command inside a container instead of on the host. The workdir is mounted
+ * read-only, the container's own filesystem is read-only, and only stateDiff.json and forge's
+ * build directories are writable, so a task script cannot change the host or other files in
+ * the task repo.
+ */
+export type SandboxConfig = {
+  // Container image with forge and the solc versions the scripts need; pin it by digest
+  image: string;
+  // Docker network to attach, one whose egress only reaches the RPC endpoint; there is no
+  // default, since Docker's bridge network reaches the whole internet
+  network: string;
+  // Container CLI, docker or podman
+  runtime: string;
+};
+
+export const SANDBOX_ENV = {
+  image: 'STATE_DIFF_SANDBOX_IMAGE',
+  network: 'STATE_DIFF_SANDBOX_NETWORK',
+  runtime: 'STATE_DIFF_SANDBOX_RUNTIME',
+} as const;
+
+const CONTAINER_WORKDIR = '/task';
+
+// Directories forge writes while compiling and broadcasting; each run gets them empty
+const SCRATCH_DIRS = ['out', 'cache', 'broadcast'];
+
+export function isPinnedImage(image: string): boolean {
+  return /@sha256:[0-9a-f]{64}$/.test(image);
+}
+
+/**
+ * Checks sandbox settings before anything runs in the container. The image must be pinned by
+ * digest, so every signer runs the same forge, and the network must be named explicitly.
+ */
+export function resolveSandboxConfig(settings: {
+  image: string;
+  network?: string;
+  runtime?: string;
+}): SandboxConfig {
+  const { image, network } = settings;
+  if (!isPinnedImage(image)) {
+    throw new Error(`Sandbox image ${image} is not pinned by digest (image@sha256:...)`);
+  }
+  if (!network) {
+    throw new Error(
+      `The sandbox needs a network: set ${SANDBOX_ENV.network} (or --sandbox-network) to one whose egress only reaches the RPC endpoint`
+    );
+  }
+  return { image, network, runtime: settings.runtime || 'docker' };
+}
+
+/**
+ * Sandbox settings from the environment, or null when no image is configured. Throws when an
+ * image is configured without a pinned digest or a network.
+ */
+export function sandboxFromEnv(env: NodeJS.ProcessEnv = process.env): SandboxConfig | null {
+  const image = env[SANDBOX_ENV.image];
+  if (!image) return null;
+  return resolveSandboxConfig({
+    image,
+    network: env[SANDBOX_ENV.network],
+    runtime: env[SANDBOX_ENV.runtime],
+  });
+}
+
+/**
+ * Creates the mount points the container needs inside the read-only workdir: the scratch
+ * directories and an empty stateDiff.json for forge to write into.
+ */
+export function prepareSandboxWorkdir(workdir: string, stateDiffPath: string): void {
+  for (const dir of SCRATCH_DIRS) {
+    mkdirSync(path.join(workdir, dir), { recursive: true });
+  }
+  closeSync(openSync(stateDiffPath, 'w'));
+}
+
+/**
+ * Host details the container borrows: the user to run as, so stateDiff.json stays owned by the
+ * signer, and the solc builds foundry installed under ~/.svm, since the sandbox cannot
+ * download compilers.
+ */
+export function hostSandboxContext(): { user?: string; compilersDir?: string } {
+  const svm = path.join(homedir(), '.svm');
+  // getuid/getgid do not exist on Windows, where Docker Desktop maps ownership itself
+  const user = process.getuid && process.getgid ? `${process.getuid()}:${process.getgid()}` : null;
+  return {
+    ...(user ? { user } : {}),
+    ...(existsSync(svm) ? { compilersDir: svm } : {}),
+  };
+}
+
+/**
+ * Arguments for `<runtime> run` that execute `command args` in the sandbox. Only the
+ * environment assignments from the forge command are passed in; the host environment is not.
+ * Variables named in `inheritEnv` are passed by name, so the runtime copies their values from
+ * its own environment and secrets stay out of its command line.
+ */
+export function buildSandboxArgs(params: {
+  config: SandboxConfig;
+  workdir: string;
+  stateDiffPath: string;
+  command: string;
+  args: string[];
+  env: Record<string, string>;
+  inheritEnv?: string[];
+  user?: string;
+  compilersDir?: string;
+}): string[] {
+  const { config, workdir, stateDiffPath, command, args, env, user, compilersDir } = params;
+  const inheritEnv = params.inheritEnv ?? [];
+  return [
+    'run',
+    '--rm',
+    '--read-only',
+    '--network',
+    config.network,
+    '--cap-drop',
+    'ALL',
+    '--security-opt',
+    'no-new-privileges',
+    ...(user ? ['--user', user] : []),
+    '--tmpfs',
+    '/tmp',
+    ...(compilersDir ? ['-v', `${compilersDir}:/tmp/.svm:ro`] : []),
+    '-v',
+    `${workdir}:${CONTAINER_WORKDIR}:ro`,
+    ...SCRATCH_DIRS.flatMap(dir => ['--tmpfs', `${CONTAINER_WORKDIR}/${dir}`]),
+    '-v',
+    `${stateDiffPath}:${CONTAINER_WORKDIR}/stateDiff.json`,
+    '-w',
+    CONTAINER_WORKDIR,
+    '-e',
+    'HOME=/tmp',
+    ...Object.entries(env).flatMap(([key, value]) => ['-e', `${key}=${value}`]),
+    ...inheritEnv.flatMap(key => ['-e', key]),
+    '--entrypoint',
+    command,
+    config.image,
+    ...args,
+  ];
+}
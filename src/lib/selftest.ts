@@ -0,0 +1,133 @@
+import { custom, Hex, toHex } from 'viem';
+import fixturesJson from './config/selftest-fixtures.json';
+import { getValidationSummary, parseFromString } from './parser';
+import { parseSimulationArtifact } from './simulation-artifact';
+import { StateDiffClient } from './state-diff';
+import type { TaskConfig } from './types/index';
+
+/**
+ * End-to-end check of the decode → report → verify pipeline against embedded stateDiff.json
+ * fixtures, one per chain with contracts in contracts.json. Everything runs offline: RPC
+ * calls are answered from the fixture, so a failure means the tool itself is broken.
+ */
+
+export type SelftestFixture = {
+  name: string;
+  chainId: number;
+  // eth_getCode answers, keyed by address
+  code: Record<string, Hex>;
+  // A --simulate-only artifact holding the encoded stateDiff.json
+  artifact: unknown;
+  // reportDigest of the expected report
+  golden: string[];
+};
+
+export type SelftestResult = {
+  name: string;
+  chainId: number;
+  problems: string[];
+};
+
+type SelftestFixtureFile = { fixtures: SelftestFixture[] };
+
+export const SELFTEST_FIXTURES = (fixturesJson as unknown as SelftestFixtureFile).fixtures;
+
+// Used where a report needs an RPC URL; nothing is ever sent to it
+const SELFTEST_RPC_URL = 'http://selftest.invalid';
+const BLOCK_NUMBER = 1;
+const BLOCK_TIMESTAMP = 1700000000;
+const ZERO_WORD = `0x${'0'.repeat(64)}`;
+
+/**
+ * One line per fact a signer relies on: the hashes to compare on the device and every
+ * override and storage change with its description.
+ */
+export function reportDigest(config: TaskConfig): string[] {
+  const { address, domainHash, messageHash, safeTxHash } = config.expectedDomainAndMessageHashes;
+  return [
+    `chain ${config.chainId}`,
+    `hashes ${address} ${domainHash} ${messageHash} ${safeTxHash}`,
+    ...config.stateOverrides.flatMap(o =>
+      o.overrides.map(
+        v => `override ${o.name} ${o.address} ${v.key} = ${v.value}: ${v.description}`
+      )
+    ),
+    ...config.stateChanges.flatMap(sc =>
+      sc.changes.map(
+        c => `change ${sc.name} ${sc.address} ${c.key} ${c.before} -> ${c.after}: ${c.description}`
+      )
+    ),
+    ...(config.balanceChanges ?? []).map(
+      b => `balance ${b.name} ${b.address} ${b.before} -> ${b.after}`
+    ),
+  ];
+}
+
+function offlineTransport(fixture: SelftestFixture) {
+  const code = new Map(
+    Object.entries(fixture.code).map(([address, hex]) => [address.toLowerCase(), hex])
+  );
+  return custom({
+    async request({ method, params }: { method: string; params?: unknown[] }) {
+      switch (method) {
+        case 'eth_chainId':
+          return toHex(fixture.chainId);
+        case 'eth_getCode':
+          return code.get(String(params?.[0]).toLowerCase()) ?? '0x';
+        case 'eth_getStorageAt':
+          return ZERO_WORD;
+        case 'eth_getBlockByNumber':
+          return {
+            number: toHex(BLOCK_NUMBER),
+            hash: ZERO_WORD,
+            parentHash: ZERO_WORD,
+            timestamp: toHex(BLOCK_TIMESTAMP),
+            transactions: [],
+            uncles: [],
+          };
+        default:
+          throw new Error(`selftest: unexpected RPC call ${method}`);
+      }
+    },
+  });
+}
+
+function compareDigests(actual: string[], golden: string[]): string[] {
+  const problems: string[] = [];
+  for (let i = 0; i < Math.max(actual.length, golden.length); i++) {
+    if (actual[i] === golden[i]) continue;
+    problems.push(
+      `line ${i + 1}: expected ${golden[i] ?? '<nothing>'}, got ${actual[i] ?? '<nothing>'}`
+    );
+  }
+  return problems;
+}
+
+export async function runSelftestFixture(fixture: SelftestFixture): Promise<SelftestResult> {
+  const problems: string[] = [];
+  try {
+    const artifact = parseSimulationArtifact(fixture.artifact);
+    const client = new StateDiffClient(0, undefined, { transport: offlineTransport(fixture) });
+    const { result, warnings } = await client.fromSimulationArtifact(SELFTEST_RPC_URL, artifact);
+    problems.push(...warnings.map(warning => `unexpected warning: ${warning.message}`));
+
+    // Verify the report the way the app loads a validation file
+    const parsed = parseFromString(JSON.stringify(result));
+    if (!('config' in parsed)) {
+      problems.push(`report does not parse: ${getValidationSummary(parsed.result)}`);
+    } else {
+      problems.push(...compareDigests(reportDigest(parsed.config), fixture.golden));
+    }
+  } catch (error) {
+    problems.push(error instanceof Error ? error.message : String(error));
+  }
+  return { name: fixture.name, chainId: fixture.chainId, problems };
+}
+
+export async function runSelftest(
+  fixtures: SelftestFixture[] = SELFTEST_FIXTURES
+): Promise<SelftestResult[]> {
+  const results: SelftestResult[] = [];
+  for (const fixture of fixtures) results.push(await runSelftestFixture(fixture));
+  return results;
+}
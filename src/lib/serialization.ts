@@ -0,0 +1,85 @@
+import { stringify as stringifyYaml } from 'yaml';
+
+export const OUTPUT_FORMATS = ['json', 'yaml', 'toml'] as const;
+export type OutputFormat = (typeof OUTPUT_FORMATS)[number];
+
+type Scalar = string | number | boolean;
+
+function isPlainObject(value: unknown): value is Record<string, unknown> {
+  return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
+
+// Keys keep the order they have in the object, which is the order the JSON output uses, so
+// every format lists fields the same way. TOML has no null, so undefined and null values are
+// both left out of it; YAML keeps nulls as JSON does.
+function entries(node: Record<string, unknown>): [string, unknown][] {
+  return Object.entries(node).filter(([, value]) => value !== undefined && value !== null);
+}
+
+// JSON string escapes are valid in TOML basic strings.
+function quote(value: string): string {
+  return JSON.stringify(value);
+}
+
+function scalar(value: Scalar): string {
+  return typeof value === 'string' ? quote(value) : String(value);
+}
+
+function tomlKey(key: string): string {
+  return /^[A-Za-z0-9_-]+$/.test(key) ? key : quote(key);
+}
+
+function isTableArray(value: unknown): value is Record<string, unknown>[] {
+  return Array.isArray(value) && value.length > 0 && value.every(isPlainObject);
+}
+
+function tomlInline(value: unknown): string {
+  if (Array.isArray(value)) return `[${value.map(tomlInline).join(', ')}]`;
+  if (isPlainObject(value)) {
+    return `{ ${entries(value)
+      .map(([k, v]) => `${tomlKey(k)} = ${tomlInline(v)}`)
+      .join(', ')} }`;
+  }
+  return scalar(value as Scalar);
+}
+
+function tomlTable(node: Record<string, unknown>, path: string[]): string[] {
+  const fields = entries(node);
+  // TOML requires a table's own key/value pairs before any of its sub-tables
+  const lines = fields
+    .filter(([, value]) => !isPlainObject(value) && !isTableArray(value))
+    .map(([key, value]) => `${tomlKey(key)} = ${tomlInline(value)}`);
+
+  for (const [key, value] of fields) {
+    const childPath = [...path, tomlKey(key)];
+    if (isPlainObject(value)) {
+      lines.push('', `[${childPath.join('.')}]`, ...tomlTable(value, childPath));
+    } else if (isTableArray(value)) {
+      for (const item of value) {
+        lines.push('', `[[${childPath.join('.')}]]`, ...tomlTable(item, childPath));
+      }
+    }
+  }
+  return lines;
+}
+
+function toToml(value: unknown): string {
+  if (!isPlainObject(value)) throw new Error('TOML output requires an object at the top level');
+  return tomlTable(value, []).join('\n').trimStart();
+}
+
+/**
+ * Serializes a validation result. All formats are produced from the same object, so they
+ * always carry the same fields in the same order. JSON remains the only format the app reads.
+ */
+export function serializeResult(value: unknown, format: OutputFormat): string {
+  switch (format) {
+    case 'json':
+      return JSON.stringify(value, null, 2);
+    case 'yaml':
+      // No line width, so long strings such as descriptions are never folded
+      return stringifyYaml(value, { lineWidth: 0 }).trimEnd();
+    case 'toml':
+      return toToml(value);
+  }
+}
@@ -0,0 +1,104 @@
+import { z } from 'zod';
+import { describeZodIssues } from './config-schemas';
+import { computeSafeTxHash } from './safe-hash';
+import type { SignerInstructions, TaskConfig } from './types/index';
+
+const SignerTemplateSchema = z.object({
+  team: z.string().min(1).optional(),
+  commands: z.array(z.string()).default(['{{cmd}}']),
+  // Steps per signing device, keyed by device name ("ledger", "trezor", ...)
+  devices: z.record(z.array(z.string())),
+  notes: z.array(z.string()).optional(),
+});
+
+export type SignerTemplate = z.infer<typeof SignerTemplateSchema>;
+
+/**
+ * Used when genValidationFile.ts --signer-instructions is given without --signer-template.
+ * Matches what a Ledger shows when eip712sign asks it to sign a Safe transaction.
+ */
+export const DEFAULT_SIGNER_TEMPLATE: SignerTemplate = {
+  commands: ['{{cmd}}'],
+  devices: {
+    ledger: [
+      'Connect the Ledger, unlock it, and open the Ethereum app',
+      'Run the command above; it simulates the transaction and asks the Ledger to sign',
+      'The Ledger shows "Review typed message"; step through the screens',
+      'Check that the "Domain hash" screen shows {{domainHash}}',
+      'Check that the "Message hash" screen shows {{messageHash}}',
+      'Approve only if both match, then send the printed signature to the facilitator',
+    ],
+  },
+};
+
+export function parseSignerTemplate(raw: unknown): SignerTemplate {
+  const parsed = SignerTemplateSchema.safeParse(raw);
+  if (!parsed.success) {
+    const issues = describeZodIssues(parsed.error);
+    throw new Error(`Invalid signer template: ${issues}`);
+  }
+  return parsed.data;
+}
+
+/**
+ * Values a template can refer to as {{name}}. Fields the file does not carry are left out, so a
+ * template using them is rejected instead of rendering an empty string.
+ */
+export function templateValues(config: TaskConfig, task?: string): Record<string, string> {
+  const { address, domainHash, messageHash } = config.expectedDomainAndMessageHashes;
+  const values: Record<string, string> = {
+    cmd: config.cmd,
+    rpcUrl: config.rpcUrl,
+    ledgerId: String(config.ledgerId),
+    safe: address,
+    domainHash,
+    messageHash,
+    safeTxHash: computeSafeTxHash(domainHash, messageHash),
+  };
+  if (config.chainId !== undefined) values.chainId = String(config.chainId);
+  if (config.chainName) values.chainName = config.chainName;
+  if (config.safeNonce !== undefined) values.safeNonce = String(config.safeNonce);
+  if (task) values.task = task;
+  return values;
+}
+
+function render(line: string, values: Record<string, string>): string {
+  return line.replace(/\{\{\s*(\w+)\s*\}\}/g, (_, name: string) => {
+    const value = values[name];
+    if (value === undefined) {
+      throw new Error(
+        `Signer template refers to {{${name}}}, which is not available; use one of: ${Object.keys(values).join(', ')}`
+      );
+    }
+    return value;
+  });
+}
+
+/**
+ * Renders the per-signer runbook for a validation file: the commands to run, the hashes to
+ * compare, and the steps for each signing device.
+ */
+export function buildSignerInstructions(
+  config: TaskConfig,
+  template: SignerTemplate,
+  task?: string
+): SignerInstructions {
+  const { address, domainHash, messageHash } = config.expectedDomainAndMessageHashes;
+  const values = templateValues(config, task);
+  const lines = (list: string[]) => list.map(line => render(line, values));
+  return {
+    ...(template.team && { team: template.team }),
+    commands: lines(template.commands),
+    hashes: {
+      safe: address,
+      domainHash,
+      messageHash,
+      safeTxHash: computeSafeTxHash(domainHash, messageHash),
+    },
+    devices: Object.entries(template.devices).map(([device, steps]) => ({
+      device,
+      steps: lines(steps),
+    })),
+    ...(template.notes && { notes: lines(template.notes) }),
+  };
+}
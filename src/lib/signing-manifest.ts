@@ -0,0 +1,175 @@
+import { createHash } from 'crypto';
+import { promises as fs } from 'fs';
+import path from 'path';
+import { z } from 'zod';
+import { BuildInfo } from './build-info';
+import { describeZodIssues } from './config-schemas';
+import { PolicyViolationError } from './errors';
+import { withKeyedLock } from './keyed-lock';
+import { assertWithinDir } from './path-validation';
+
+/**
+ * The files of a signing bundle with their sha256, so the bundle can be checked with one
+ * command (`state-diff manifest verify`) before a ceremony. Files are listed relative to the
+ * manifest's directory, so the bundle can be moved or committed as a whole. Validation files
+ * also record the chain and block their report was built at, and the manifest the RPC
+ * endpoints that were read from.
+ */
+
+export const MANIFEST_VERSION = 1;
+
+const ManifestArtifactSchema = z.object({
+  path: z.string().min(1),
+  sha256: z.string().regex(/^[0-9a-f]{64}$/),
+  bytes: z.number().int().nonnegative(),
+  // Block pin of a validation file's report
+  chainId: z.number().int().positive().optional(),
+  blockNumber: z.number().int().nonnegative().optional(),
+  blockHash: z.string().optional(),
+});
+
+const SigningManifestSchema = z.object({
+  version: z.literal(MANIFEST_VERSION),
+  // Build of the tool that wrote the artifacts; every artifact comes from the same build
+  generatedBy: z.object({
+    tool: z.string(),
+    version: z.string(),
+    commit: z.string(),
+    configHash: z.string(),
+    buildDate: z.string(),
+  }),
+  updatedAt: z.string().datetime(),
+  // Scheme and host only, since RPC URLs often carry an API key in the path or query
+  rpcEndpoints: z.array(z.string()),
+  artifacts: z.array(ManifestArtifactSchema),
+});
+
+export type ManifestArtifact = z.infer<typeof ManifestArtifactSchema>;
+export type SigningManifest = z.infer<typeof SigningManifestSchema>;
+
+// A file to add, with the block pin of the report when it is a validation file
+export type ManifestInput = {
+  file: string;
+  chainId?: number;
+  blockNumber?: number;
+  blockHash?: string;
+};
+
+export type ManifestCheck = {
+  path: string;
+  status: 'ok' | 'changed' | 'missing';
+};
+
+export function parseSigningManifest(raw: unknown): SigningManifest {
+  const parsed = SigningManifestSchema.safeParse(raw);
+  if (!parsed.success) {
+    const issues = describeZodIssues(parsed.error);
+    throw new Error(`Invalid signing manifest: ${issues}`);
+  }
+  return parsed.data;
+}
+
+// The part of an RPC URL that is safe to publish
+export function rpcEndpointOrigin(url: string): string {
+  try {
+    const { protocol, host } = new URL(url);
+    return `${protocol}//${host}`;
+  } catch {
+    // The URL itself is not repeated, since it may hold a key
+    throw new Error('An RPC endpoint for the manifest is not a URL');
+  }
+}
+
+async function hashFile(file: string): Promise<{ sha256: string; bytes: number }> {
+  const content = await fs.readFile(file);
+  return {
+    sha256: createHash('sha256').update(new Uint8Array(content)).digest('hex'),
+    bytes: content.length,
+  };
+}
+
+// Artifacts are kept inside the manifest's directory so a bundle cannot vouch for other files
+function artifactPath(manifestFile: string, file: string): string {
+  const dir = path.dirname(path.resolve(manifestFile));
+  return assertWithinDir(path.resolve(dir, file), dir);
+}
+
+async function readManifest(file: string): Promise<SigningManifest | null> {
+  try {
+    return parseSigningManifest(JSON.parse(await fs.readFile(file, 'utf-8')));
+  } catch (error) {
+    if (error instanceof Error && 'code' in error && error.code === 'ENOENT') return null;
+    throw error;
+  }
+}
+
+/**
+ * Adds `inputs` to the manifest at `manifestFile`, creating it when it does not exist. A file
+ * already listed is hashed again. A manifest written by another build of the tool is refused,
+ * since its artifacts could differ from what this build produces.
+ */
+export async function recordManifestArtifacts(
+  manifestFile: string,
+  inputs: readonly ManifestInput[],
+  options: { buildInfo: BuildInfo; rpcUrls?: readonly string[]; now?: Date }
+): Promise<SigningManifest> {
+  const file = path.resolve(manifestFile);
+  return withKeyedLock(file, async () => {
+    const existing = await readManifest(file);
+    if (existing && existing.generatedBy.configHash !== options.buildInfo.configHash) {
+      throw new PolicyViolationError(
+        `${file} lists artifacts built with config ${existing.generatedBy.configHash}, not ${options.buildInfo.configHash}; start a new manifest`
+      );
+    }
+    const artifacts = new Map((existing?.artifacts ?? []).map(a => [a.path, a]));
+    for (const input of inputs) {
+      const absolute = artifactPath(file, path.resolve(input.file));
+      const relative = path.relative(path.dirname(file), absolute).split(path.sep).join('/');
+      artifacts.set(relative, {
+        path: relative,
+        ...(await hashFile(absolute)),
+        ...(input.chainId !== undefined ? { chainId: input.chainId } : {}),
+        ...(input.blockNumber !== undefined ? { blockNumber: input.blockNumber } : {}),
+        ...(input.blockHash ? { blockHash: input.blockHash } : {}),
+      });
+    }
+    const endpoints = new Set(existing?.rpcEndpoints ?? []);
+    for (const url of options.rpcUrls ?? []) endpoints.add(rpcEndpointOrigin(url));
+
+    const manifest: SigningManifest = {
+      version: MANIFEST_VERSION,
+      generatedBy: options.buildInfo,
+      updatedAt: (options.now ?? new Date()).toISOString(),
+      rpcEndpoints: [...endpoints].sort(),
+      artifacts: [...artifacts.values()].sort((a, b) => a.path.localeCompare(b.path)),
+    };
+    await fs.mkdir(path.dirname(file), { recursive: true });
+    await fs.writeFile(file, JSON.stringify(manifest, null, 2) + '\n');
+    return manifest;
+  });
+}
+
+/**
+ * Hashes every artifact the manifest lists again. Files next to the manifest that it does not
+ * list are not reported; the manifest vouches only for what it names.
+ */
+export async function verifyManifest(
+  manifestFile: string
+): Promise<{ manifest: SigningManifest; checks: ManifestCheck[] }> {
+  const file = path.resolve(manifestFile);
+  const manifest = await readManifest(file);
+  if (!manifest) throw new Error(`No manifest at ${file}`);
+  const checks: ManifestCheck[] = [];
+  for (const artifact of manifest.artifacts) {
+    const absolute = artifactPath(file, artifact.path);
+    try {
+      const { sha256, bytes } = await hashFile(absolute);
+      const ok = sha256 === artifact.sha256 && bytes === artifact.bytes;
+      checks.push({ path: artifact.path, status: ok ? 'ok' : 'changed' });
+    } catch (error) {
+      if (!(error instanceof Error && 'code' in error && error.code === 'ENOENT')) throw error;
+      checks.push({ path: artifact.path, status: 'missing' });
+    }
+  }
+  return { manifest, checks };
+}
@@ -0,0 +1,128 @@
+import { z } from 'zod';
+import { Hex, hexToBigInt } from 'viem';
+import { AddressSchema, describeZodIssues, HashSchema, HexValueSchema } from './config-schemas';
+import { DecodeError, SimulationFailedError } from './errors';
+import { PayloadDecoded } from './state-diff-encoding';
+
+const PayloadSchema = z.object({
+  from: AddressSchema,
+  to: AddressSchema,
+  data: HexValueSchema,
+  stateOverrides: z
+    .array(
+      z.object({
+        contractAddress: AddressSchema,
+        overrides: z.array(z.object({ key: HashSchema, value: HashSchema })),
+      })
+    )
+    .default([]),
+});
+
+const SimulatedLogSchema = z.object({
+  address: AddressSchema,
+  topics: z.array(HexValueSchema),
+  data: HexValueSchema,
+});
+
+const SimulatedCallSchema = z.object({
+  status: HexValueSchema,
+  returnData: HexValueSchema,
+  gasUsed: HexValueSchema,
+  logs: z.array(SimulatedLogSchema).default([]),
+  error: z.object({ code: z.number(), message: z.string() }).optional(),
+});
+
+const SimulatedBlocksSchema = z.array(z.object({ calls: z.array(SimulatedCallSchema) }));
+
+export type SimulatedBlocks = z.infer<typeof SimulatedBlocksSchema>;
+
+/**
+ * Parses the call to simulate: { from, to, data, stateOverrides? } in the same shape the forge
+ * script encodes into stateDiff.json.
+ */
+export function parseSimulateV1Payload(raw: unknown): PayloadDecoded {
+  const parsed = PayloadSchema.safeParse(raw);
+  if (!parsed.success) {
+    throw new DecodeError(`Invalid eth_simulateV1 payload: ${describeZodIssues(parsed.error)}`);
+  }
+  const { from, to, data, stateOverrides } = parsed.data;
+  return {
+    from,
+    to,
+    data: data as Hex,
+    stateOverrides: stateOverrides.map(o => ({
+      contractAddress: o.contractAddress,
+      overrides: o.overrides.map(({ key, value }) => ({ key: key as Hex, value: value as Hex })),
+    })),
+  };
+}
+
+/**
+ * The payload's storage overrides as a JSON-RPC state override set. They use `stateDiff` so
+ * only the listed slots change.
+ */
+export function rpcStateOverrides(
+  payload: PayloadDecoded
+): Record<string, { stateDiff: Record<string, Hex> }> {
+  return Object.fromEntries(
+    payload.stateOverrides.map(o => [
+      o.contractAddress,
+      { stateDiff: Object.fromEntries(o.overrides.map(({ key, value }) => [key, value])) },
+    ])
+  );
+}
+
+/**
+ * JSON-RPC params for eth_simulateV1. Validation is off so the Safe can be simulated without
+ * funding its sender.
+ */
+export function buildSimulateV1Params(payload: PayloadDecoded, blockTag = 'latest'): unknown[] {
+  const stateOverrides = rpcStateOverrides(payload);
+  return [
+    {
+      blockStateCalls: [
+        {
+          stateOverrides,
+          calls: [{ from: payload.from, to: payload.to, data: payload.data }],
+        },
+      ],
+      validation: false,
+    },
+    blockTag,
+  ];
+}
+
+/**
+ * Validates an eth_simulateV1 response and fails on the first reverted call, since a report
+ * for a transaction that does not execute would be misleading.
+ */
+export function parseSimulateV1Result(raw: unknown): SimulatedBlocks {
+  const parsed = SimulatedBlocksSchema.safeParse(raw);
+  if (!parsed.success) {
+    throw new DecodeError(`Invalid eth_simulateV1 response: ${describeZodIssues(parsed.error)}`);
+  }
+  for (const call of parsed.data.flatMap(block => block.calls)) {
+    if (hexToBigInt(call.status as Hex) !== BigInt(1)) {
+      const reason = call.error?.message ?? `returnData ${call.returnData}`;
+      throw new SimulationFailedError(`eth_simulateV1 call reverted: ${reason}`);
+    }
+  }
+  return parsed.data;
+}
+
+/**
+ * JSON-RPC params for a debug_traceCall of the same call with the prestateTracer in diffMode.
+ * eth_simulateV1 does not report the storage a call writes, so the diff comes from this trace,
+ * with the payload's overrides applied the same way.
+ */
+export function buildPrestateTraceParams(payload: PayloadDecoded, blockTag = 'latest'): unknown[] {
+  return [
+    { from: payload.from, to: payload.to, data: payload.data },
+    blockTag,
+    {
+      tracer: 'prestateTracer',
+      tracerConfig: { diffMode: true },
+      stateOverrides: rpcStateOverrides(payload),
+    },
+  ];
+}
@@ -0,0 +1,40 @@
+import { z } from 'zod';
+import {
+  describeZodIssues,
+  EncodedStateDiffSchema,
+  PrestateDependencySchema,
+  SimulatedAtSchema,
+  SimulationEnvSchema,
+} from './config-schemas';
+import { DecodeError } from './errors';
+
+const SimulationArtifactSchema = z.object({
+  version: z.literal(1),
+  cmd: z.string(),
+  forgeOutput: z.string(),
+  // recorded when the run was made on top of another task with --prestate-from
+  prestateFrom: PrestateDependencySchema.optional(),
+  // recorded when --env or the workdir .env injected variables into the run
+  simulationEnv: SimulationEnvSchema.optional(),
+  // The block forge forked from, looked up when the run was made, so a saved or cached run
+  // keeps it; absent when the run had no RPC to ask
+  simulatedAt: SimulatedAtSchema.optional(),
+  stateDiff: EncodedStateDiffSchema,
+});
+
+export type EncodedStateDiff = z.infer<typeof EncodedStateDiffSchema>;
+
+/**
+ * Everything the report stage needs from a forge run, so the simulation and the report can
+ * happen on different machines. `forgeOutput` is kept for L2 gas estimation.
+ */
+export type SimulationArtifact = z.infer<typeof SimulationArtifactSchema>;
+
+export function parseSimulationArtifact(raw: unknown): SimulationArtifact {
+  const parsed = SimulationArtifactSchema.safeParse(raw);
+  if (!parsed.success) {
+    const issues = describeZodIssues(parsed.error);
+    throw new DecodeError(`Invalid simulation diff file: ${issues}`);
+  }
+  return parsed.data;
+}
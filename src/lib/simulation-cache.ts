@@ -0,0 +1,154 @@
+import { createHash } from 'crypto';
+import { promises as fs } from 'fs';
+import path from 'path';
+import { canonicalHash } from './canonical-json';
+import { withKeyedLock } from './keyed-lock';
+import { parseSimulationArtifact, SimulationArtifact } from './simulation-artifact';
+import { isSecretKey } from './simulation-env';
+
+/**
+ * Forge runs reused across invocations. Re-running genValidationFile to tweak labels or
+ * contracts.json does not change what forge simulates, so when the command, every file in the
+ * workdir, the environment forge inherits, and the pinned fork block are the same as an earlier
+ * run, its encoded diff is decoded again instead of running forge. Only runs pinned with
+ * --fork-block-number are cached: against the latest block the same script can produce a
+ * different diff every block.
+ */
+
+// Default directory for the cache; caching is off when neither it nor --simulation-cache is set
+export const SIMULATION_CACHE_ENV = 'STATE_DIFF_SIMULATION_CACHE';
+
+// 2: runs record the block they forked from
+const SIMULATION_CACHE_VERSION = 2;
+
+// Build output, dependencies installed by package managers, and history; none of it is read by
+// the script as an input
+const SKIPPED_DIRS = new Set(['.git', 'node_modules', 'cache', 'out', 'broadcast']);
+
+// The diff forge writes into the workdir on every run
+const FORGE_OUTPUT = 'stateDiff.json';
+
+// Shell bookkeeping that changes between invocations without changing what forge does
+const VOLATILE_ENV = new Set(['_', 'OLDPWD', 'SHLVL']);
+
+export type SimulationCacheKeyParams = {
+  forgeCmdParts: readonly string[];
+  // Fork block from forkBlockPin
+  block: string;
+  inputsHash: string;
+  // Secrets passed through forge's environment; only their hash is part of the key
+  secretEnv: Record<string, string>;
+  // The host environment forge inherits; empty in the sandbox, which passes none of it
+  hostEnv?: NodeJS.ProcessEnv;
+  sandboxImage?: string;
+};
+
+/**
+ * The block the command forks from, from `--fork-block-number <n>` or `--fork-block-number=<n>`;
+ * null when it forks the latest block.
+ */
+export function forkBlockPin(forgeCmdParts: readonly string[]): string | null {
+  for (const [i, part] of forgeCmdParts.entries()) {
+    const value = part.startsWith('--fork-block-number=')
+      ? part.slice('--fork-block-number='.length)
+      : part === '--fork-block-number'
+        ? forgeCmdParts[i + 1]
+        : undefined;
+    if (value !== undefined && /^\d+$/.test(value)) return value;
+  }
+  return null;
+}
+
+async function inputFiles(dir: string, root: string, skipped: Set<string>): Promise<string[]> {
+  const entries = await fs.readdir(dir, { withFileTypes: true });
+  const files: string[] = [];
+  for (const entry of entries) {
+    const full = path.join(dir, entry.name);
+    if (skipped.has(full)) continue;
+    if (entry.isDirectory()) {
+      if (!SKIPPED_DIRS.has(entry.name)) files.push(...(await inputFiles(full, root, skipped)));
+    } else if (entry.isFile()) {
+      files.push(path.relative(root, full));
+    }
+  }
+  return files;
+}
+
+/**
+ * Hashes every file under the workdir by path and content, dependencies in lib/ included, since
+ * a script can read any of them. Build output, installed packages, history, and forge's own
+ * diff are skipped, as are `outputs`, the files this run writes, such as the validation file.
+ */
+export async function hashSimulationInputs(
+  workdir: string,
+  outputs: readonly string[] = []
+): Promise<string> {
+  const skipped = new Set([
+    path.resolve(workdir, FORGE_OUTPUT),
+    ...outputs.map(output => path.resolve(output)),
+  ]);
+  const root = path.resolve(workdir);
+  const files = (await inputFiles(root, root, skipped)).sort();
+  const hash = createHash('sha256');
+  for (const file of files) {
+    const content = await fs.readFile(path.join(root, file));
+    const digest = createHash('sha256').update(new Uint8Array(content)).digest('hex');
+    hash.update(`${file.split(path.sep).join('/')}\0${digest}\n`);
+  }
+  return hash.digest('hex');
+}
+
+export function simulationCacheKey(params: SimulationCacheKeyParams): string {
+  const byName = ([a]: [string, string], [b]: [string, string]) => a.localeCompare(b);
+  const hostEnv = Object.entries(params.hostEnv ?? {}).filter(
+    (entry): entry is [string, string] => entry[1] !== undefined && !VOLATILE_ENV.has(entry[0])
+  );
+  // Host secrets, such as an RPC token, are hashed with the injected ones
+  const secrets = [
+    ...Object.entries(params.secretEnv),
+    ...hostEnv.filter(([name]) => isSecretKey(name)),
+  ].sort(byName);
+  return canonicalHash({
+    version: SIMULATION_CACHE_VERSION,
+    cmd: params.forgeCmdParts,
+    block: params.block,
+    inputs: params.inputsHash,
+    env: hostEnv.filter(([name]) => !isSecretKey(name)).sort(byName),
+    secrets: canonicalHash(secrets),
+    sandboxImage: params.sandboxImage ?? null,
+  }).slice(2);
+}
+
+export function simulationCachePath(dir: string, key: string): string {
+  return path.join(dir, `simulation-${key}.json`);
+}
+
+/**
+ * The cached run for `key`, a diff file as --simulate-only writes it. A missing or unreadable
+ * entry is a miss rather than an error, since forge can always run again.
+ */
+export async function loadCachedSimulation(
+  dir: string,
+  key: string
+): Promise<SimulationArtifact | null> {
+  try {
+    const raw = await fs.readFile(simulationCachePath(dir, key), 'utf-8');
+    return parseSimulationArtifact(JSON.parse(raw));
+  } catch {
+    return null;
+  }
+}
+
+export async function saveCachedSimulation(
+  dir: string,
+  key: string,
+  artifact: SimulationArtifact
+): Promise<void> {
+  const file = simulationCachePath(dir, key);
+  await withKeyedLock(file, async () => {
+    await fs.mkdir(dir, { recursive: true });
+    const tmp = `${file}.${process.pid}.tmp`;
+    await fs.writeFile(tmp, JSON.stringify(artifact, null, 2) + '\n');
+    await fs.rename(tmp, file);
+  });
+}
@@ -0,0 +1,179 @@
+import { existsSync, readFileSync } from 'fs';
+import path from 'path';
+import { REDACTED_PLACEHOLDER } from './redaction';
+import type { SimulationEnv } from './types/index';
+
+/**
+ * Environment variables genValidationFile.ts injects into the forge run. Values from --env and
+ * from allowlisted keys of the workdir's .env are prepended to the forge command as KEY=value,
+ * like STATE_OVERRIDES_FILE, so `cmd` replays them when signers re-run the simulation. Secrets are
+ * passed to forge through its environment only: they never appear in `cmd`, the report, or
+ * the logs, and each signer supplies their own.
+ */
+
+// Keys loaded from the workdir's .env without --env-allow
+export const DEFAULT_DOTENV_ALLOWLIST = ['SIGNER_ADDRESS', 'SAFE_NONCE'];
+
+const KEY_PATTERN = /^[A-Za-z_][A-Za-z0-9_]*$/;
+const SECRET_KEY_PATTERN = /PRIVATE_KEY|MNEMONIC|SECRET|PASSWORD|TOKEN|API_KEY/i;
+// Forge flags whose value is a credential
+const SECRET_FLAGS = new Set(['--private-key', '--mnemonic', '--etherscan-api-key', '--password']);
+
+export type EnvSource = SimulationEnv[number]['source'];
+
+export type SimulationEnvEntry = {
+  name: string;
+  value: string;
+  source: EnvSource;
+  secret: boolean;
+};
+
+export function isSecretKey(name: string): boolean {
+  return SECRET_KEY_PATTERN.test(name);
+}
+
+export function parseEnvFlag(flag: string): { name: string; value: string } {
+  const eq = flag.indexOf('=');
+  const name = eq === -1 ? flag : flag.slice(0, eq);
+  if (eq === -1 || !KEY_PATTERN.test(name)) {
+    throw new Error(`--env must be KEY=VALUE, got ${flag}`);
+  }
+  return { name, value: flag.slice(eq + 1) };
+}
+
+/**
+ * Parses the subset of .env syntax forge and most tools agree on: KEY=value lines, an optional
+ * `export ` prefix, `#` comments, and single- or double-quoted values.
+ */
+export function parseDotEnv(text: string): Record<string, string> {
+  const values: Record<string, string> = {};
+  for (const [index, raw] of text.split(/\r?\n/).entries()) {
+    const line = raw.trim();
+    if (!line || line.startsWith('#')) continue;
+    const match = /^(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$/.exec(line);
+    if (!match) throw new Error(`.env line ${index + 1}: expected KEY=value`);
+    const [, name, rest] = match;
+    const quoted = /^(["'])(.*)\1$/.exec(rest);
+    if (quoted) {
+      values[name] = quoted[1] === '"' ? quoted[2].replace(/\\n/g, '\n') : quoted[2];
+    } else {
+      values[name] = rest.replace(/\s+#.*$/, '');
+    }
+  }
+  return values;
+}
+
+/**
+ * Allowlisted values from `<workdir>/.env`, and the keys it has that were left out.
+ */
+export function loadWorkdirEnv(
+  workdir: string,
+  allowlist: string[]
+): { values: Record<string, string>; skipped: string[] } {
+  const file = path.join(workdir, '.env');
+  if (!existsSync(file)) return { values: {}, skipped: [] };
+  const all = parseDotEnv(readFileSync(file, 'utf-8'));
+  const allowed = new Set(allowlist);
+  const values: Record<string, string> = {};
+  const skipped: string[] = [];
+  for (const [name, value] of Object.entries(all)) {
+    if (allowed.has(name)) values[name] = value;
+    else skipped.push(name);
+  }
+  return { values, skipped };
+}
+
+/**
+ * Combines the .env values and --env flags (which win) into the entries to inject. Keys the
+ * forge command already assigns are rejected, since it is unclear which value should apply.
+ */
+export function resolveSimulationEnv(params: {
+  flags: string[];
+  dotenv: Record<string, string>;
+  forgeCmdParts: string[];
+}): SimulationEnvEntry[] {
+  const assigned = new Set<string>();
+  for (const part of params.forgeCmdParts) {
+    const eq = part.indexOf('=');
+    if (eq === -1 || !KEY_PATTERN.test(part.slice(0, eq))) break;
+    assigned.add(part.slice(0, eq));
+  }
+
+  const entries = new Map<string, SimulationEnvEntry>();
+  const add = (name: string, value: string, source: EnvSource) => {
+    const secret = isSecretKey(name);
+    if (!secret && /\s/.test(value)) {
+      // cmd is split on whitespace when signers re-run it
+      throw new Error(`${name} contains whitespace and cannot be written to the forge command`);
+    }
+    entries.set(name, { name, value, source, secret });
+  };
+  for (const [name, value] of Object.entries(params.dotenv)) {
+    if (!assigned.has(name)) add(name, value, 'dotenv');
+  }
+  for (const flag of params.flags) {
+    const { name, value } = parseEnvFlag(flag);
+    if (assigned.has(name)) throw new Error(`--env ${name} is already set in --forge-cmd`);
+    add(name, value, 'flag');
+  }
+  return [...entries.values()];
+}
+
+/**
+ * KEY=value assignments to prepend to the forge command, secrets excluded.
+ */
+export function envAssignments(entries: SimulationEnvEntry[]): string[] {
+  return entries.filter(e => !e.secret).map(e => `${e.name}=${e.value}`);
+}
+
+// Passed to forge through its environment instead of the command
+export function secretEnv(entries: SimulationEnvEntry[]): Record<string, string> {
+  return Object.fromEntries(entries.filter(e => e.secret).map(e => [e.name, e.value]));
+}
+
+export function recordSimulationEnv(entries: SimulationEnvEntry[]): SimulationEnv {
+  return entries.map(e => ({
+    name: e.name,
+    source: e.source,
+    value: e.secret ? REDACTED_PLACEHOLDER : e.value,
+  }));
+}
+
+/**
+ * Secrets a file was simulated with that are not set in this environment; the re-run does not
+ * see them unless forge finds them in the workdir's .env.
+ */
+export function missingSecretEnv(
+  recorded: SimulationEnv,
+  env: NodeJS.ProcessEnv = process.env
+): string[] {
+  return recorded
+    .filter(e => e.value === REDACTED_PLACEHOLDER && env[e.name] === undefined)
+    .map(e => e.name);
+}
+
+/**
+ * The forge command as it may be logged: values of secret-looking assignments and of
+ * credential flags are masked.
+ */
+export function sanitizeCommand(parts: string[]): string {
+  return parts
+    .map((part, i) => {
+      if (i > 0 && SECRET_FLAGS.has(parts[i - 1])) return REDACTED_PLACEHOLDER;
+      const eq = part.indexOf('=');
+      if (eq === -1) return part;
+      const name = part.slice(0, eq);
+      const secret = SECRET_FLAGS.has(name) || (KEY_PATTERN.test(name) && isSecretKey(name));
+      return secret ? `${name}=${REDACTED_PLACEHOLDER}` : part;
+    })
+    .join(' ');
+}
+
+/**
+ * Masks every occurrence of the given secret values, e.g. in forge output echoed to the logs.
+ */
+export function redactSecrets(text: string, secrets: string[]): string {
+  return secrets
+    .filter(secret => secret.length > 0)
+    .reduce((masked, secret) => masked.split(secret).join(REDACTED_PLACEHOLDER), text);
+}
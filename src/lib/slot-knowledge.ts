@@ -0,0 +1,149 @@
+import { Address, getAddress, Hex, keccak256, PublicClient } from 'viem';
+
+// keccak256('eip1967.proxy.implementation') - 1
+export const EIP1967_IMPLEMENTATION_SLOT =
+  '0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc';
+// Solidity dispatchers compare the calldata selector against PUSH4 <selector>
+const PUSH4 = '63';
+
+export type KnownPattern<S> = {
+  name: string;
+  selectors: string[];
+  codeHashes: string[];
+  slots: Record<string, S>;
+};
+
+/**
+ * Returns the built-in patterns a bytecode blob matches. A pattern matches when the
+ * keccak256 of the code is one of its pinned code hashes, or when every one of its
+ * function selectors appears in the dispatcher. Only a code hash identifies the code; any
+ * contract can have the selectors, so a selector match is a hint (see pinnedPatterns).
+ */
+export function matchKnownPatterns<S>(
+  code: Hex | undefined,
+  patterns: KnownPattern<S>[]
+): KnownPattern<S>[] {
+  if (!code || code === '0x') return [];
+  const body = code.toLowerCase();
+  const codeHash = keccak256(code).toLowerCase();
+
+  return patterns.filter(
+    pattern =>
+      pattern.codeHashes.some(hash => hash.toLowerCase() === codeHash) ||
+      (pattern.selectors.length > 0 &&
+        pattern.selectors.every(selector => body.includes(PUSH4 + selector.slice(2).toLowerCase())))
+  );
+}
+
+// Names of the patterns whose pinned code hashes include the keccak256 of `code`
+export function pinnedPatterns<S>(code: Hex | undefined, patterns: KnownPattern<S>[]): string[] {
+  if (!code || code === '0x') return [];
+  const codeHash = keccak256(code).toLowerCase();
+  return patterns
+    .filter(pattern => pattern.codeHashes.some(hash => hash.toLowerCase() === codeHash))
+    .map(pattern => pattern.name);
+}
+
+// CBOR keys solc uses for the metadata hash, with the byte-string header that follows them
+const METADATA_KEYS = [
+  { key: '6469706673' + '5822', size: 34 }, // "ipfs": bytes(34)
+  { key: '65627a7a7231' + '5820', size: 32 }, // "bzzr1": bytes(32)
+  { key: '65627a7a7230' + '5820', size: 32 }, // "bzzr0": bytes(32)
+];
+
+/**
+ * The metadata hash solc appends to runtime code (IPFS or Swarm hash of the metadata JSON),
+ * or null when the code carries none. The CBOR blob it sits in ends the code, followed by its
+ * two-byte length. Unlike the code hash it does not change with immutables, so it identifies
+ * a build across deployments.
+ */
+export function metadataHash(code: Hex | undefined): Hex | null {
+  if (!code) return null;
+  const body = code.slice(2).toLowerCase();
+  const length = parseInt(body.slice(-4), 16);
+  if (!length || length * 2 + 4 > body.length) return null;
+  const cbor = body.slice(body.length - 4 - length * 2, body.length - 4);
+  for (const { key, size } of METADATA_KEYS) {
+    for (let at = cbor.indexOf(key); at !== -1; at = cbor.indexOf(key, at + 1)) {
+      if (at % 2 !== 0) continue;
+      const hash = cbor.slice(at + key.length, at + key.length + size * 2);
+      if (hash.length === size * 2) return `0x${hash}`;
+    }
+  }
+  return null;
+}
+
+export type KnownArtifact<C> = {
+  codeHashes: string[];
+  metadataHashes: string[];
+  contract: C;
+};
+
+/**
+ * The artifact `code` was built from: the first whose code hash matches exactly, else the
+ * first whose metadata hash matches, for deployments whose immutables differ from the build.
+ */
+export function matchArtifact<C>(
+  code: Hex | undefined,
+  artifacts: KnownArtifact<C>[]
+): KnownArtifact<C> | null {
+  if (!code || code === '0x') return null;
+  const codeHash = keccak256(code).toLowerCase();
+  const exact = artifacts.find(a => a.codeHashes.some(h => h.toLowerCase() === codeHash));
+  if (exact) return exact;
+  const metadata = metadataHash(code);
+  if (!metadata) return null;
+  return artifacts.find(a => a.metadataHashes.some(h => h.toLowerCase() === metadata)) ?? null;
+}
+
+export type ContractInspection<S> = {
+  // keccak256 of the contract's own code; null when it has none
+  codeHash: Hex | null;
+  // EIP-1967 implementation, only read when the contract's own code matches nothing
+  implementation: Address | null;
+  matches: KnownPattern<S>[];
+  // Names of the matches found by code hash; the others only matched by selectors
+  pinned: string[];
+};
+
+/**
+ * Fingerprints the contract at `address`. Storage of an EIP-1967 proxy follows its
+ * implementation, so when the proxy's own code matches nothing the implementation is tried.
+ * `deployedCode` is used instead of eth_getCode for contracts created during the simulation.
+ */
+export async function inspectContract<S>(
+  client: Pick<PublicClient, 'getCode' | 'getStorageAt'>,
+  address: Address,
+  patterns: KnownPattern<S>[],
+  deployedCode?: Hex
+): Promise<ContractInspection<S>> {
+  const code = deployedCode ?? (await client.getCode({ address }));
+  const codeHash = code && code !== '0x' ? keccak256(code) : null;
+  const direct = matchKnownPatterns(code, patterns);
+  if (direct.length > 0 || deployedCode) {
+    return {
+      codeHash,
+      implementation: null,
+      matches: direct,
+      pinned: pinnedPatterns(code, direct),
+    };
+  }
+
+  const implSlot = await client.getStorageAt({ address, slot: EIP1967_IMPLEMENTATION_SLOT });
+  if (!implSlot || BigInt(implSlot) === BigInt(0)) {
+    return { codeHash, implementation: null, matches: [], pinned: [] };
+  }
+  const implementation = getAddress('0x' + implSlot.slice(-40));
+  const implCode = await client.getCode({ address: implementation });
+  const matches = matchKnownPatterns(implCode, patterns);
+  return { codeHash, implementation, matches, pinned: pinnedPatterns(implCode, matches) };
+}
+
+export async function identifyKnownPatterns<S>(
+  client: Pick<PublicClient, 'getCode' | 'getStorageAt'>,
+  address: Address,
+  patterns: KnownPattern<S>[],
+  deployedCode?: Hex
+): Promise<KnownPattern<S>[]> {
+  return (await inspectContract(client, address, patterns, deployedCode)).matches;
+}
@@ -0,0 +1,63 @@
+import { getAddress, Hex } from 'viem';
+import { formatAmount, SlotFormatHint } from './number-format';
+
+/**
+ * Slot descriptions in contracts.json may refer to the values of the slot they describe, as in
+ * "Threshold changed from {{beforeDec}} to {{afterDec}}". A variable names a 32-byte word and
+ * may end in `Dec` (the word as an unsigned integer), `Num` (the same with thousands separators),
+ * `Fmt` (as the slot's `decimals` or `format` hint reads it, e.g. 1.5 gwei), or `Addr` (its low
+ * 20 bytes, checksummed); without a suffix the word is written as hex. A byte range such as
+ * `{{afterDec[0:8]}}` picks a field out of a packed slot first; offsets count from the low-order
+ * end of the word, as the `offset` in a Solidity storage layout does.
+ */
+
+const VARIABLE = /\{\{\s*(\w+)(?:\[(\d+):(\d+)\])?\s*\}\}/g;
+const FORMAT = /^(\w*?)(Dec|Num|Fmt|Addr)?$/;
+
+/**
+ * Words a slot description can refer to. `mappingKey`, `mappingKey2`, ... are the keys on the
+ * slot's preimage chain, outermost first, so `balances[{{mappingKeyAddr}}]` names the owner.
+ */
+export function slotTemplateValues(
+  words: { before?: Hex; after?: Hex; value?: Hex },
+  mappingKeys: readonly Hex[]
+): Record<string, Hex> {
+  const values: Record<string, Hex> = {};
+  if (words.before) values.before = words.before;
+  if (words.after) values.after = words.after;
+  if (words.value) values.value = words.value;
+  mappingKeys.forEach((key, i) => {
+    values[i === 0 ? 'mappingKey' : `mappingKey${i + 1}`] = key;
+  });
+  return values;
+}
+
+export function renderSlotTemplate(
+  template: string,
+  values: Record<string, Hex>,
+  where: string,
+  hint?: SlotFormatHint
+): string {
+  if (!template.includes('{{')) return template;
+  return template.replace(VARIABLE, (whole: string, name: string, from?: string, to?: string) => {
+    const [, base, format] = name.match(FORMAT) ?? [];
+    const value = values[base];
+    if (value === undefined) {
+      throw new Error(
+        `${where} refers to {{${name}}}, which is not available; use one of: ${Object.keys(values).join(', ')}`
+      );
+    }
+    let word = value.slice(2).padStart(64, '0');
+    if (from !== undefined && to !== undefined) {
+      if (Number(from) >= Number(to) || Number(to) > 32) {
+        throw new Error(`${where} has an invalid byte range in ${whole}`);
+      }
+      word = word.slice(64 - 2 * Number(to), 64 - 2 * Number(from));
+    }
+    if (format === 'Dec') return BigInt(`0x${word}`).toString();
+    if (format === 'Num') return formatAmount(BigInt(`0x${word}`));
+    if (format === 'Fmt') return formatAmount(BigInt(`0x${word}`), hint);
+    if (format === 'Addr') return getAddress(`0x${word.padStart(40, '0').slice(-40)}`);
+    return `0x${word}`;
+  });
+}
@@ -0,0 +1,60 @@
+import type { SimulatedAt } from './types/index';
+
+/**
+ * How old a validation file may get before validation warns that it should be regenerated.
+ * State the simulation relied on can change once other transactions land, so an old file may
+ * no longer describe what the transaction will do.
+ */
+export type StalenessLimits = {
+  maxAgeHours: number;
+  // Blocks the chain head may have advanced past the simulated block
+  maxBlocksBehind: number;
+};
+
+export const STALENESS_ENV = {
+  maxAgeHours: 'VALIDATION_MAX_AGE_HOURS',
+  maxBlocksBehind: 'VALIDATION_MAX_BLOCKS_BEHIND',
+} as const;
+
+// Three days, and three days of 12-second L1 blocks
+export const DEFAULT_STALENESS_LIMITS: StalenessLimits = {
+  maxAgeHours: 72,
+  maxBlocksBehind: 21600,
+};
+
+export function stalenessLimitsFromEnv(env: NodeJS.ProcessEnv = process.env): StalenessLimits {
+  const read = (key: keyof StalenessLimits): number => {
+    const value = env[STALENESS_ENV[key]];
+    if (!value) return DEFAULT_STALENESS_LIMITS[key];
+    const n = Number(value);
+    if (!Number.isFinite(n) || n <= 0) {
+      throw new Error(`${STALENESS_ENV[key]} must be a positive number`);
+    }
+    return n;
+  };
+  return { maxAgeHours: read('maxAgeHours'), maxBlocksBehind: read('maxBlocksBehind') };
+}
+
+/**
+ * Warnings for a file generated too long ago or too many blocks behind the current head.
+ */
+export function findStaleness(
+  recorded: SimulatedAt,
+  current: { now: Date; blockNumber: number },
+  limits: StalenessLimits
+): string[] {
+  const warnings: string[] = [];
+  const ageHours = (current.now.getTime() - Date.parse(recorded.generatedAt)) / (3600 * 1000);
+  if (ageHours > limits.maxAgeHours) {
+    warnings.push(
+      `The validation file was generated ${Math.floor(ageHours)} hours ago (limit ${limits.maxAgeHours}); it may be stale and should be regenerated`
+    );
+  }
+  const blocksBehind = current.blockNumber - recorded.blockNumber;
+  if (blocksBehind > limits.maxBlocksBehind) {
+    warnings.push(
+      `The chain has advanced ${blocksBehind} blocks since the simulated block ${recorded.blockNumber} (limit ${limits.maxBlocksBehind}); the validation file may be stale and should be regenerated`
+    );
+  }
+  return warnings;
+}
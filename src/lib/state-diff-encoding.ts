@@ -0,0 +1,114 @@
+import { Address, decodeAbiParameters, Hex } from 'viem';
+import { VmSafeAccountAccess } from './vm-safe';
+
+// ABI decoders for the blobs a forge script writes to stateDiff.json.
+
+export type StorageOverrideDecoded = { key: Hex; value: Hex };
+export type StateOverrideDecoded = {
+  contractAddress: string;
+  overrides: readonly StorageOverrideDecoded[];
+};
+export type PayloadDecoded = {
+  from: Address;
+  to: Address;
+  data: Hex;
+  stateOverrides: readonly StateOverrideDecoded[];
+};
+
+export type ParentPreimage = { slot: Hex; parent: Hex; key: Hex };
+
+// Parameter trees are built once at module load rather than on every decode, which adds up
+// when the server or a batch run decodes many payloads.
+export const OVERRIDES_PARAMS = [
+  {
+    type: 'tuple',
+    components: [
+      { name: 'from', type: 'address' },
+      { name: 'to', type: 'address' },
+      { name: 'data', type: 'bytes' },
+      {
+        name: 'stateOverrides',
+        type: 'tuple[]',
+        components: [
+          { name: 'contractAddress', type: 'address' },
+          {
+            name: 'overrides',
+            type: 'tuple[]',
+            components: [
+              { name: 'key', type: 'bytes32' },
+              { name: 'value', type: 'bytes32' },
+            ],
+          },
+        ],
+      },
+    ],
+  },
+] as const;
+
+export const STATE_DIFF_PARAMS = [
+  {
+    type: 'tuple[]',
+    components: [
+      {
+        name: 'chainInfo',
+        type: 'tuple',
+        components: [
+          { name: 'forkId', type: 'uint256' },
+          { name: 'chainId', type: 'uint256' },
+        ],
+      },
+      { name: 'kind', type: 'uint8' },
+      { name: 'account', type: 'address' },
+      { name: 'accessor', type: 'address' },
+      { name: 'initialized', type: 'bool' },
+      { name: 'oldBalance', type: 'uint256' },
+      { name: 'newBalance', type: 'uint256' },
+      { name: 'deployedCode', type: 'bytes' },
+      { name: 'value', type: 'uint256' },
+      { name: 'data', type: 'bytes' },
+      { name: 'reverted', type: 'bool' },
+      {
+        name: 'storageAccesses',
+        type: 'tuple[]',
+        components: [
+          { name: 'account', type: 'address' },
+          { name: 'slot', type: 'bytes32' },
+          { name: 'isWrite', type: 'bool' },
+          { name: 'previousValue', type: 'bytes32' },
+          { name: 'newValue', type: 'bytes32' },
+          { name: 'reverted', type: 'bool' },
+        ],
+      },
+      { name: 'depth', type: 'uint64' },
+      { name: 'oldNonce', type: 'uint64' },
+      { name: 'newNonce', type: 'uint64' },
+    ],
+  },
+] as const;
+
+export const PREIMAGES_PARAMS = [
+  {
+    type: 'tuple[]',
+    components: [
+      { name: 'slot', type: 'bytes32' },
+      { name: 'parent', type: 'bytes32' },
+      { name: 'key', type: 'bytes32' },
+    ],
+  },
+] as const;
+
+export function decodeOverrides(encoded: string): PayloadDecoded {
+  const [tuple] = decodeAbiParameters(OVERRIDES_PARAMS, encoded as Hex);
+  return tuple;
+}
+
+export function decodeStateDiff(encoded: string): readonly VmSafeAccountAccess[] {
+  const [accesses] = decodeAbiParameters(STATE_DIFF_PARAMS, encoded as Hex);
+
+  return accesses;
+}
+
+export function decodePreimages(encoded: string): readonly ParentPreimage[] {
+  const [arr] = decodeAbiParameters(PREIMAGES_PARAMS, encoded as Hex);
+  return arr;
+}
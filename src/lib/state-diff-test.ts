@@ -0,0 +1,247 @@
+import {
+  Address,
+  concat,
+  custom,
+  encodeAbiParameters,
+  getAddress,
+  Hex,
+  keccak256,
+  numberToHex,
+  pad,
+  toHex,
+  Transport,
+  zeroAddress,
+} from 'viem';
+import { SimulationArtifact } from './simulation-artifact';
+import {
+  OVERRIDES_PARAMS,
+  ParentPreimage,
+  PayloadDecoded,
+  PREIMAGES_PARAMS,
+  STATE_DIFF_PARAMS,
+} from './state-diff-encoding';
+import { AccountAccessKind, VmSafeAccountAccess, VmSafeStorageAccess } from './vm-safe';
+
+/**
+ * Builders and a fake RPC backend for testing code built on StateDiffClient without forge,
+ * fixtures, or a node. Accesses, preimages, and payloads are built in the decoded form and
+ * encoded into a SimulationArtifact the way a forge run writes stateDiff.json, so
+ * fromSimulationArtifact exercises the same decoding as a real run:
+ *
+ *   const rpc = fakeRpc({ code: { [PORTAL]: '0x6001' } });
+ *   const client = new StateDiffClient(0, undefined, { transport: rpc.transport });
+ *   const write = storageWrite(PORTAL, 1, 0, 2);
+ *   const artifact = simulationArtifact({
+ *     accesses: [accountAccess({ account: PORTAL, storageAccesses: [write] })],
+ *   });
+ *   const { result } = await client.fromSimulationArtifact(FAKE_RPC_URL, artifact);
+ *
+ * Nothing here is used outside tests.
+ */
+
+// Used where a report needs an RPC URL; the fake transport never sends anything to it
+export const FAKE_RPC_URL = 'http://fake-rpc.invalid';
+
+// A checksummed address derived from `n`, for accounts a test only needs to tell apart
+export function syntheticAddress(n: number): Address {
+  return getAddress(pad(toHex(n), { size: 20 }));
+}
+
+// A 32-byte word holding `value`
+export function word(value: number | bigint | Hex): Hex {
+  return pad(typeof value === 'string' ? value : toHex(value));
+}
+
+export function accountAccess(overrides: Partial<VmSafeAccountAccess> = {}): VmSafeAccountAccess {
+  return {
+    chainInfo: { forkId: BigInt(0), chainId: BigInt(1) },
+    kind: AccountAccessKind.Call,
+    account: zeroAddress,
+    accessor: zeroAddress,
+    initialized: true,
+    oldBalance: BigInt(0),
+    newBalance: BigInt(0),
+    deployedCode: '0x',
+    value: BigInt(0),
+    data: '0x',
+    reverted: false,
+    storageAccesses: [],
+    depth: BigInt(1),
+    oldNonce: BigInt(0),
+    newNonce: BigInt(0),
+    ...overrides,
+  };
+}
+
+export function storageWrite(
+  account: string,
+  slot: number | bigint | Hex,
+  before: number | bigint | Hex,
+  after: number | bigint | Hex,
+  reverted = false
+): VmSafeStorageAccess {
+  return {
+    account,
+    slot: word(slot),
+    isWrite: true,
+    previousValue: word(before),
+    newValue: word(after),
+    reverted,
+  };
+}
+
+export function storageRead(
+  account: string,
+  slot: number | bigint | Hex,
+  value: number | bigint | Hex
+): VmSafeStorageAccess {
+  return { ...storageWrite(account, slot, value, value), isWrite: false };
+}
+
+// The slot of `key` in the mapping declared at `parent`, with the preimage that resolves it
+export function mappingPreimage(
+  parent: number | bigint | Hex,
+  key: number | bigint | Hex
+): ParentPreimage {
+  const p = word(parent);
+  const k = word(key);
+  return { slot: keccak256(concat([k, p])), parent: p, key: k };
+}
+
+export function payload(overrides: Partial<PayloadDecoded> = {}): PayloadDecoded {
+  return { from: zeroAddress, to: zeroAddress, data: '0x', stateOverrides: [], ...overrides };
+}
+
+export type SyntheticRun = {
+  targetSafe?: Address;
+  domainHash?: Hex;
+  messageHash?: Hex;
+  accesses?: readonly VmSafeAccountAccess[];
+  preimages?: readonly ParentPreimage[];
+  // With a zero `to`, the report skips replaying the call
+  payload?: PayloadDecoded;
+  cmd?: string;
+  forgeOutput?: string;
+};
+
+/**
+ * A SimulationArtifact with the blobs a forge run would write for `run`. The hashes default to
+ * keccak256 of "domain" and "message", which only need to be consistent within a test.
+ */
+export function simulationArtifact(run: SyntheticRun = {}): SimulationArtifact {
+  const domainHash = run.domainHash ?? keccak256(toHex('domain'));
+  const messageHash = run.messageHash ?? keccak256(toHex('message'));
+  const accesses = (run.accesses ?? []).map(a => ({
+    ...a,
+    account: a.account as Address,
+    accessor: a.accessor as Address,
+    storageAccesses: a.storageAccesses.map(s => ({ ...s, account: s.account as Address })),
+  }));
+  const { stateOverrides, ...call } = run.payload ?? payload();
+  const overrides = {
+    ...call,
+    stateOverrides: stateOverrides.map(o => ({
+      ...o,
+      contractAddress: o.contractAddress as Address,
+    })),
+  };
+  return {
+    version: 1,
+    cmd: run.cmd ?? 'forge script Synthetic.s.sol',
+    forgeOutput: run.forgeOutput ?? '',
+    stateDiff: {
+      targetSafe: run.targetSafe ?? syntheticAddress(0x5afe),
+      dataToSign: concat(['0x1901', domainHash, messageHash]),
+      stateDiff: encodeAbiParameters(STATE_DIFF_PARAMS, [accesses]),
+      preimages: encodeAbiParameters(PREIMAGES_PARAMS, [run.preimages ?? []]),
+      overrides: encodeAbiParameters(OVERRIDES_PARAMS, [overrides]),
+    },
+  };
+}
+
+/**
+ * State the fake RPC backend serves. Addresses are matched case-insensitively and slots in any
+ * width; accounts and slots that are not listed are empty.
+ */
+export type FakeChain = {
+  chainId?: number;
+  blockNumber?: number;
+  // Unix seconds
+  blockTimestamp?: number;
+  // Hash of each block by number, for reorgs; others hash to their number
+  blockHashes?: Record<number, Hex>;
+  code?: Record<string, Hex>;
+  // Address to slot to value
+  storage?: Record<string, Record<string, Hex>>;
+  balances?: Record<string, bigint>;
+  // Answers eth_call; returning undefined reverts the call
+  call?: (request: { from?: Address; to: Address; data: Hex }) => Hex | undefined;
+  // Answers methods the fake does not model, such as eth_simulateV1 or debug_traceCall
+  methods?: Record<string, (params: unknown[]) => unknown>;
+};
+
+export type FakeRpcCall = { method: string; params: unknown[] };
+
+/**
+ * A viem transport answering the reads the report stage makes from `chain`. Every request is
+ * recorded in `calls`, and a method it does not serve throws, so a test notices new RPC use.
+ */
+export function fakeRpc(chain: FakeChain = {}): { transport: Transport; calls: FakeRpcCall[] } {
+  const byAddress = <T>(entries: Record<string, T> = {}) =>
+    new Map(Object.entries(entries).map(([address, value]) => [address.toLowerCase(), value]));
+  const code = byAddress(chain.code);
+  const balances = byAddress(chain.balances);
+  const slotKey = (slot: string) => word(slot.toLowerCase() as Hex);
+  const storage = new Map(
+    Object.entries(chain.storage ?? {}).map(([address, slots]) => [
+      address.toLowerCase(),
+      new Map(Object.entries(slots).map(([slot, value]) => [slotKey(slot), word(value)])),
+    ])
+  );
+  const blockNumber = chain.blockNumber ?? 1;
+  const calls: FakeRpcCall[] = [];
+
+  const transport = custom({
+    async request({ method, params = [] }: { method: string; params?: unknown[] }) {
+      calls.push({ method, params });
+      const address = String(params[0]).toLowerCase();
+      switch (method) {
+        case 'eth_chainId':
+          return toHex(chain.chainId ?? 1);
+        case 'eth_blockNumber':
+          return toHex(blockNumber);
+        case 'eth_getBlockByNumber': {
+          const tag = String(params[0]);
+          const number = tag.startsWith('0x') ? Number(tag) : blockNumber;
+          if (number > blockNumber) return null;
+          return {
+            number: toHex(number),
+            hash: chain.blockHashes?.[number] ?? word(number),
+            parentHash: word(number - 1),
+            timestamp: toHex(chain.blockTimestamp ?? 1700000000),
+            transactions: [],
+            uncles: [],
+          };
+        }
+        case 'eth_getCode':
+          return code.get(address) ?? '0x';
+        case 'eth_getStorageAt':
+          return storage.get(address)?.get(slotKey(String(params[1]))) ?? word(0);
+        case 'eth_getBalance':
+          return numberToHex(balances.get(address) ?? BigInt(0));
+        case 'eth_call': {
+          const request = params[0] as { from?: Address; to: Address; data?: Hex };
+          const result = chain.call?.({ ...request, data: request.data ?? '0x' });
+          if (result === undefined) throw new Error('execution reverted');
+          return result;
+        }
+        default: {
+          const answer = chain.methods?.[method];
+          if (answer) return answer(params);
+          throw new Error(`fake RPC: unexpected call ${method}`);
+        }
+      }
+    },
+  });
+  return { transport, calls };
+}
@@ -1,160 +1,1107 @@
 import { spawn } from 'child_process';
 import { promises as fs } from 'fs';
 import path from 'path';
-import { createPublicClient, http, decodeAbiParameters, Hex, Address, getAddress } from 'viem';
-import { BalanceChange, StateChange, StateOverride, TaskConfig } from './types/index';
+import {
+  createPublicClient,
+  http,
+  Hex,
+  Address,
+  getAddress,
+  isAddressEqual,
+  keccak256,
+  PublicClient,
+  Transport,
+  zeroAddress,
+} from 'viem';
+import {
+  AccountDeletion,
+  BalanceChange,
+  CodeChange,
+  CriticalRead,
+  EthTransfer,
+  ExecutionCheck,
+  IntermediateWrite,
+  PayloadSignature,
+  ReportWarning,
+  SafeConfigurationChange,
+  SimulatedAt,
+  StateChange,
+  StateOverride,
+  TaskConfig,
+} from './types/index';
 import contractsCfg from './config/contracts.json';
-import { assertWithinDir } from './path-validation';
-
-type ParsedInput = {
-  targetSafe: string;
-  dataToSign: string; // concatenated 0x + domain(32B) + message(32B)
-  stateDiff: string; // hex-encoded ABI tuple[]
-  preimages: string; // hex-encoded ABI tuple[]
-  overrides: string; // hex-encoded ABI tuple
-};
+import { aggregateAccountAccesses, AccountChange, StorageDiff } from './account-aggregation';
+import { findSuspiciousWrites } from './account-checks';
+import { DeletedAccount, describeAccountDeletion, findAccountDeletions } from './account-deletions';
+import { getChainInfo } from './chains';
+import { explorerAddressUrl } from './explorers';
+import { computeSafeTxHash } from './safe-hash';
+import { describeNonceMismatch, recoverSafeTxNonce, SAFE_NONCE_ABI } from './safe-nonce';
+import { decodePayloadSignatures, describeSafeSignature } from './safe-signatures';
+import { checkExecution, describeExecutionCheck } from './execution-check';
+import { CodeDiff, describeCodeChange, findCodeChanges } from './code-changes';
+import { describeEthTransfer, findEthTransfers, ValueTransfer } from './eth-transfers';
+import {
+  describeSafeConfigurationChange,
+  findSafeConfigurationChanges,
+  SafeConfigDiff,
+  safeConfigurationWarning,
+} from './safe-configuration';
+import { normalizeAddressKeys } from './config-addresses';
+import {
+  buildSandboxArgs,
+  hostSandboxContext,
+  prepareSandboxWorkdir,
+  SandboxConfig,
+} from './sandbox';
+import { redactSecrets, sanitizeCommand } from './simulation-env';
+import { erc7201Slot } from './erc7201';
+import { DecodeError, PolicyViolationError, RpcError, SimulationFailedError } from './errors';
+import {
+  checkDiffFileSize,
+  checkEncodedStateDiff,
+  DecodeLimits,
+  DEFAULT_DECODE_LIMITS,
+} from './decode-limits';
+import { DataToSignForm, hashTypedDataParts, normalizeDataToSign } from './data-to-sign';
+import {
+  allowanceSlot,
+  describePermit,
+  noncesSlot,
+  parsePermit,
+  PERMIT_TOKEN_ABI,
+  permitAccountAccesses,
+} from './permit';
+import { canonicalHash } from './canonical-json';
+import {
+  describeSafeVersion,
+  knownSafeVersion,
+  readSafeSingleton,
+  safeDomainHash,
+} from './safe-singleton';
+import { usedPreimages } from './preimage-database';
+import {
+  learnPreimages,
+  loadPreimageStore,
+  PreimageStore,
+  savePreimageStore,
+  storedPreimages,
+} from './preimage-store';
+import { withKeyedLock } from './keyed-lock';
+import { forkBlockPin } from './simulation-cache';
+import { reportProgress } from './progress';
+import { assertRealPathWithinDir } from './path-validation';
+import { checkCommandAllowed } from './command-policy';
+import { inspectContract, KnownArtifact, KnownPattern, matchArtifact } from './slot-knowledge';
+import {
+  cachedMetadata,
+  ContractMetadata,
+  loadMetadataCache,
+  MetadataCacheOptions,
+  saveMetadataCache,
+} from './metadata-cache';
+import { categoryOf, describeReportGroups } from './report-groups';
+import { describeReportSummary, summarizeReport } from './report-summary';
+import { reportWarning, unknownEntryWarnings } from './report-warnings';
+import {
+  newRetryLog,
+  retryingTransport,
+  RetryLog,
+  RetryPolicy,
+  retryPolicyFromEnv,
+  retryWarnings,
+} from './rpc-retry';
+import {
+  applyUnknownMode,
+  UNKNOWN_CONTRACT_NAME,
+  UNKNOWN_OVERRIDE_MEANING,
+  UNKNOWN_SLOT_SUMMARY,
+  UnknownMode,
+} from './unknown-entries';
+import { mappingKey, splitMappingPatterns } from './mapping-patterns';
+import { renderSlotTemplate, slotTemplateValues } from './slot-templates';
+import { hasFormatHint, SlotFormat, validateFormatHint } from './number-format';
+import { resolveValueAliases, valueAlias, ValueAliases } from './value-aliases';
+import { parsePrestateTrace, prestateTraceToAccountAccesses } from './prestate-trace';
+import {
+  parseTenderlyExport,
+  tenderlyToAccountAccesses,
+  tenderlyToPayload,
+} from './tenderly-export';
+import {
+  buildPrestateTraceParams,
+  buildSimulateV1Params,
+  parseSimulateV1Result,
+} from './simulate-v1';
+import { AccountAccessKind, VmSafeAccountAccess } from './vm-safe';
+import {
+  decodeOverrides,
+  decodePreimages,
+  decodeStateDiff,
+  ParentPreimage,
+  PayloadDecoded,
+  StateOverrideDecoded,
+} from './state-diff-encoding';
+import { incrementCounter, withSpan } from './telemetry';
+import { EncodedStateDiff, SimulationArtifact } from './simulation-artifact';
 
-type StorageOverrideDecoded = { key: Hex; value: Hex };
-type StateOverrideDecoded = {
-  contractAddress: string;
-  overrides: readonly StorageOverrideDecoded[];
-};
-type PayloadDecoded = {
-  from: Address;
-  to: Address;
-  data: Hex;
-  stateOverrides: readonly StateOverrideDecoded[];
-};
+// Storage reads to report besides writes; only reads of critical slots are supported
+export const READS_MODES = ['critical'] as const;
+export type ReadsMode = (typeof READS_MODES)[number];
 
-type VmSafeStorageAccess = {
-  account: string;
-  slot: Hex;
-  isWrite: boolean;
-  previousValue: Hex;
-  newValue: Hex;
-  reverted: boolean;
+export type SimulationResult = {
+  result: TaskConfig;
+  output: string;
+  transactionTo: Address;
+  transactionData: Hex;
+  forgeOutput: string;
+  // The blobs forge wrote, for embedding with --include-raw
+  encoded: EncodedStateDiff;
+  warnings: ReportWarning[];
 };
 
-type VmSafeAccountAccess = {
-  chainInfo: { forkId: bigint; chainId: bigint };
-  kind: number;
-  account: string;
-  accessor: string;
-  initialized: boolean;
-  oldBalance: bigint;
-  newBalance: bigint;
-  deployedCode: Hex;
-  value: bigint;
-  data: Hex;
-  reverted: boolean;
-  storageAccesses: readonly VmSafeStorageAccess[];
-  depth: bigint;
-  oldNonce: bigint;
-  newNonce: bigint;
+export type SlotExplanation = {
+  chainId: string;
+  address: Address;
+  // Name in contracts.json; null when the contract is not configured
+  contract: string | null;
+  // Built-in patterns the contract's code matched, when it is not configured
+  patterns: string[];
+  slot: Hex;
+  // Preimages from the slot up to the slot its outermost mapping is declared at
+  derivation: { slot: Hex; parent: Hex; key: Hex }[];
+  // The entry describing the slot, with its summary rendered for the current value
+  entry: { type: string; description: string; overrideMeaning: string; critical: boolean } | null;
+  // Current on-chain value; null without an RPC endpoint
+  value: Hex | null;
 };
 
-type ParentPreimage = { slot: Hex; parent: Hex; key: Hex };
+// Validation files built without a forge run must have their cmd filled in by hand
+const PLACEHOLDER_CMD = '<<ForgeCommand>>';
 
 type SlotCfg = {
   type: string;
+  // summary and overrideMeaning may refer to the slot's values, e.g. {{afterDec}}; see
+  // slot-templates.ts
   summary: string;
   overrideMeaning: string;
   allowDifference: boolean;
   allowOverrideDifference: boolean;
+  // Only on mapping patterns such as `balances[*]`: the slot the mapping is declared at
+  baseSlot?: string;
+  // Reads of the slot are reported with --include-reads critical
+  critical?: boolean;
+  // Bookkeeping (timestamps, counters): changes are left out of stateChanges and counted under
+  // summary.noiseFiltered. Overrides of the slot are still listed
+  noise?: boolean;
+  // How the word is read for display: token decimals, or a unit (gwei, ether); see
+  // number-format.ts
+  decimals?: number;
+  format?: SlotFormat;
+};
+type ContractCfg = {
+  name: string;
+  slots: Record<string, SlotCfg>;
+  // Mapping patterns keyed by mappingKey(baseSlot, depth)
+  mappings?: Record<string, SlotCfg>;
+  // Built-in patterns an unconfigured contract was identified by
+  patterns?: string[];
+  tags?: string[];
+};
+type RawContractCfg = {
+  name: string;
+  // Categories such as `safe` or `proxy` the report groups the contract by; see report-groups.ts
+  tags?: string[];
+  slots?: string | Record<string, SlotCfg>;
+  // ERC-7201 namespace id -> struct member slot offset -> slot config
+  namespaces?: Record<string, Record<string, SlotCfg>>;
+};
+type RawKnownPattern = Omit<KnownPattern<SlotCfg>, 'slots'> & { slots: string };
+// A build of a contract, for contracts the task itself deploys: the new address is not in
+// contracts.json, so the deployed code is matched against the build's hashes instead
+type RawArtifact = RawContractCfg & { codeHashes?: string[]; metadataHashes?: string[] };
+type RawConfig = {
+  contracts: Record<string, Record<string, RawContractCfg>>;
+  storageLayouts: Record<string, Record<string, SlotCfg>>;
+  knownPatterns?: RawKnownPattern[];
+  artifacts?: RawArtifact[];
+  // Balance changes smaller than this, in decimal wei, are left out of balanceChanges
+  noise?: { minBalanceDeltaWei?: string };
+  // Named values shown instead of bare words in before/after values; see value-aliases.ts
+  constants?: Record<string, string>;
+};
+type ResolvedConfig = {
+  contracts: Record<string, Record<string, ContractCfg>>;
+  knownPatterns: KnownPattern<SlotCfg>[];
+  artifacts: KnownArtifact<ContractCfg>[];
+  minBalanceDelta: bigint;
+  constants: ValueAliases;
+};
+// Slot to parent slot and slot to mapping key, from the recorded and supplied preimages
+type PreimageMaps = { parentMap: Map<Hex, Hex>; preimageKeys: Map<Hex, Hex> };
+
+// Contracts and storage layouts laid over contracts.json, e.g. one team's additions on a shared
+// server. An overlay contract replaces the embedded entry for the same address; overlay
+// artifacts are added to the embedded ones, and overlay constants to the embedded constants.
+export type ConfigOverlay = {
+  contracts?: Record<string, Record<string, unknown>>;
+  storageLayouts?: Record<string, Record<string, unknown>>;
+  artifacts?: Record<string, unknown>[];
+  constants?: Record<string, string>;
 };
-type ContractCfg = { name: string; slots: Record<string, SlotCfg> };
-type RawContractCfg = { name: string; slots?: string | Record<string, SlotCfg> };
 
 export class StateDiffClient {
   private readonly ledgerId: number;
   private readonly allowedDir: string;
+  private readonly strictHashFormat: boolean;
+  private readonly verbose: boolean;
+  private readonly sandbox: SandboxConfig | null;
+  private readonly limits: DecodeLimits | null;
+  private readonly includeReads: ReadsMode | null;
+  private readonly unknowns: UnknownMode;
+  private readonly metadataCache: MetadataCacheOptions | null;
+  private readonly transport: Transport | null;
+  private readonly rpcRetry: RetryPolicy | null;
+  private readonly env: Record<string, string>;
+  private readonly preimages: readonly ParentPreimage[];
+  private readonly preimageStore: string | null;
+  private readonly configOverlay: ConfigOverlay | null;
+  private readonly configKey: string;
+  private readonly allowedCommands: readonly string[] | null;
 
-  constructor(ledgerId: number = 0, allowedDir?: string) {
+  constructor(
+    ledgerId: number = 0,
+    allowedDir?: string,
+    options: {
+      strictHashFormat?: boolean;
+      verbose?: boolean;
+      sandbox?: SandboxConfig | null;
+      // null turns the size limits off
+      limits?: DecodeLimits | null;
+      includeReads?: ReadsMode | null;
+      // How contracts and slots missing from contracts.json are reported (default placeholder)
+      unknowns?: UnknownMode;
+      // Per-chain cache of what RPC lookups found out about unconfigured contracts
+      metadataCache?: MetadataCacheOptions | null;
+      // Used instead of an HTTP transport to rpcUrl, e.g. by the offline selftest
+      transport?: Transport;
+      // Retries for failed RPC lookups (default from the STATE_DIFF_RPC_* variables); null
+      // turns them off
+      rpcRetry?: RetryPolicy | null;
+      // Passed to forge without appearing in cmd (secrets from --env); masked in logs
+      env?: Record<string, string>;
+      // Mapping preimages from outside the simulation (--preimages); forge's own take precedence
+      preimages?: readonly ParentPreimage[];
+      // Directory of mapping preimages learned in earlier runs (--preimage-store); runs start
+      // from the stored preimages of the contracts they touch and add the ones they resolve
+      preimageStore?: string | null;
+      // Laid over contracts.json, e.g. a tenant's overlay on a shared server
+      configOverlay?: ConfigOverlay | null;
+      // Binaries the forge command may start (--allowed-cmds); null allows any
+      allowedCommands?: readonly string[] | null;
+    } = {}
+  ) {
     this.ledgerId = ledgerId;
     // Default to current working directory if no root is specified
     this.allowedDir = allowedDir ? path.resolve(allowedDir) : process.cwd();
+    this.strictHashFormat = options.strictHashFormat ?? false;
+    this.verbose = options.verbose ?? false;
+    this.sandbox = options.sandbox ?? null;
+    this.limits = options.limits === undefined ? DEFAULT_DECODE_LIMITS : options.limits;
+    this.includeReads = options.includeReads ?? null;
+    this.unknowns = options.unknowns ?? 'placeholder';
+    this.metadataCache = options.metadataCache ?? null;
+    this.transport = options.transport ?? null;
+    this.rpcRetry = options.rpcRetry === undefined ? retryPolicyFromEnv() : options.rpcRetry;
+    this.env = options.env ?? {};
+    this.preimages = options.preimages ?? [];
+    this.preimageStore = options.preimageStore ?? null;
+    this.configOverlay = options.configOverlay ?? null;
+    this.configKey = this.configOverlay ? canonicalHash(this.configOverlay) : '';
+    this.allowedCommands = options.allowedCommands ?? null;
   }
 
   async simulate(
     rpcUrl: string,
     forgeCmdParts: string[],
-    workdir: string
-  ): Promise<{
-    result: TaskConfig;
-    output: string;
-    transactionTo: Address;
-    transactionData: Hex;
-    forgeOutput: string;
-  }> {
-    // Validate workdir to prevent path traversal attacks
-    const normalizedWorkdir = assertWithinDir(workdir, this.allowedDir);
+    workdir: string,
+    options: { preimages?: readonly ParentPreimage[] } = {}
+  ): Promise<SimulationResult> {
+    const artifact = await this.simulateOnly(forgeCmdParts, workdir, rpcUrl);
+    return this.fromSimulationArtifact(rpcUrl, artifact, options);
+  }
+
+  /**
+   * Runs forge and returns its encoded state diff without decoding it, so the report can be
+   * generated later (or elsewhere) with fromSimulationArtifact. With `rpcUrl`, the block forge
+   * forks from is recorded in the run's simulatedAt.
+   */
+  async simulateOnly(
+    forgeCmdParts: string[],
+    workdir: string,
+    rpcUrl?: string
+  ): Promise<SimulationArtifact> {
+    // Validate workdir to prevent path traversal attacks, including through symlinks
+    const normalizedWorkdir = assertRealPathWithinDir(workdir, this.allowedDir);
+
+    // Looked up before forge runs, since a run that is not pinned forks the head as it starts
+    const simulatedAt = rpcUrl
+      ? await forkBlock(this.rpcClient(rpcUrl).client, forgeCmdParts)
+      : undefined;
+    // forge writes stateDiff.json to a fixed path inside the workdir, so concurrent server
+    // requests against the same workdir must not overlap.
+    const artifact = await withKeyedLock(normalizedWorkdir, () =>
+      this.runForge(forgeCmdParts, normalizedWorkdir)
+    );
+    return simulatedAt ? { ...artifact, simulatedAt } : artifact;
+  }
 
+  private async runForge(
+    forgeCmdParts: string[],
+    normalizedWorkdir: string
+  ): Promise<SimulationArtifact> {
     const cmd = forgeCmdParts.join(' ');
-    console.log(`🔧 Running forge in ${normalizedWorkdir}: ${cmd}`);
+    const where = this.sandbox ? `a ${this.sandbox.image} sandbox` : normalizedWorkdir;
+    console.log(`🔧 Running forge in ${where}: ${sanitizeCommand(forgeCmdParts)}`);
+    const mask = (text: string) => redactSecrets(text, Object.values(this.env));
 
     const { command, args, env: envAssignments } = this.extractCommandDetails(forgeCmdParts);
-    const spawnEnv = { ...process.env, ...envAssignments, RECORD_STATE_DIFF: 'true' };
-
-    const { stdout, stderr, code } = await this.runCommand(
-      command,
-      args,
-      normalizedWorkdir,
-      120000,
-      spawnEnv
+    checkCommandAllowed(command, this.allowedCommands);
+    const stateDiffPath = this.stateDiffFilePath(normalizedWorkdir);
+
+    let run: { command: string; args: string[]; env: NodeJS.ProcessEnv };
+    if (this.sandbox) {
+      prepareSandboxWorkdir(normalizedWorkdir, stateDiffPath);
+      const sandboxArgs = buildSandboxArgs({
+        config: this.sandbox,
+        workdir: normalizedWorkdir,
+        stateDiffPath,
+        command,
+        args,
+        env: { ...envAssignments, RECORD_STATE_DIFF: 'true' },
+        inheritEnv: Object.keys(this.env),
+        ...hostSandboxContext(),
+      });
+      run = {
+        command: this.sandbox.runtime,
+        args: sandboxArgs,
+        env: { ...process.env, ...this.env },
+      };
+    } else {
+      const env = { ...process.env, ...this.env, ...envAssignments, RECORD_STATE_DIFF: 'true' };
+      run = { command, args, env };
+    }
+
+    const { stdout, stderr, code } = await withSpan('simulate', { cmd: command }, () =>
+      this.runCommand(run.command, run.args, normalizedWorkdir, 120000, run.env)
     );
     if (code !== 0) {
-      throw new Error(
-        `StateDiffClient::simulate: forge command failed with exit code ${code}.\nStdout: ${stdout}\nStderr: ${stderr}`
+      if (this.sandbox) await this.deleteFile(stateDiffPath);
+      throw new SimulationFailedError(
+        `StateDiffClient::simulate: forge command failed with exit code ${code}.\nStdout: ${mask(stdout)}\nStderr: ${mask(stderr)}`
       );
     }
 
     if (stderr) {
-      console.warn('⚠️ forge stderr:', stderr);
+      console.warn('⚠️ forge stderr:', mask(stderr));
     }
 
-    const client = createPublicClient({ transport: http(rpcUrl) });
-    const chainIdHex = (await client.request({ method: 'eth_chainId' })) as string;
+    const stateDiff = await this.readEncodedStateDiff(stateDiffPath, normalizedWorkdir);
+    await this.deleteFile(stateDiffPath);
+
+    return { version: 1, cmd, forgeOutput: mask(stdout), stateDiff };
+  }
+
+  /**
+   * Decodes a forge run captured by simulateOnly and builds the validation result from it.
+   * `preimages` adds to the ones the client was created with, e.g. those a validation file
+   * recorded.
+   */
+  async fromSimulationArtifact(
+    rpcUrl: string,
+    artifact: SimulationArtifact,
+    options: { preimages?: readonly ParentPreimage[] } = {}
+  ): Promise<SimulationResult> {
+    const { cmd, forgeOutput, stateDiff: parsed } = artifact;
+    if (this.limits) checkEncodedStateDiff(parsed, this.limits);
+
+    const { client, retries } = this.rpcClient(rpcUrl);
+    const chainIdHex = await withSpan(
+      'rpc.enrichment',
+      { method: 'eth_chainId' },
+      async () => (await client.request({ method: 'eth_chainId' })) as string
+    );
     const chainIdStr = BigInt(chainIdHex).toString();
 
-    const stateDiffPath = this.stateDiffFilePath(normalizedWorkdir);
-    const parsed = await this.readEncodedStateDiff(stateDiffPath);
+    const { domainHash, messageHash, form, payload, decodedDiff, recorded } = await withSpan(
+      'decode',
+      { chainId: chainIdStr },
+      async () => {
+        try {
+          const hashes = normalizeDataToSign(parsed.dataToSign, { strict: this.strictHashFormat });
+          return {
+            ...hashes,
+            payload: decodeOverrides(parsed.overrides),
+            decodedDiff: decodeStateDiff(parsed.stateDiff),
+            recorded: decodePreimages(parsed.preimages),
+          };
+        } catch (err) {
+          const message = err instanceof Error ? err.message : String(err);
+          throw new DecodeError(`Failed to decode stateDiff.json: ${message}`, { cause: err });
+        }
+      }
+    );
+    const recordedSlots = new Set(recorded.map(p => normalize32(p.slot)));
+    const extraPreimages = [...this.preimages, ...(options.preimages ?? [])].filter(
+      p => !recordedSlots.has(normalize32(p.slot))
+    );
+    // A run saved without its block is dated by the block its command pins, or by the head
+    const simulatedAt = artifact.simulatedAt ?? (await forkBlock(client, cmd.split(/\s+/)));
+    const { result, output, warnings } = await this.transform({
+      cmd,
+      rpcUrl,
+      client,
+      chainIdStr,
+      simulatedAt,
+      targetSafe: parsed.targetSafe,
+      domainHash,
+      messageHash,
+      dataToSignForm: form,
+      payload,
+      decodedDiff,
+      ...this.buildPreimageMaps(recorded, extraPreimages),
+      extraPreimages,
+      retries,
+    });
+    return {
+      result,
+      output,
+      transactionTo: payload.to,
+      transactionData: payload.data,
+      forgeOutput,
+      encoded: parsed,
+      warnings,
+    };
+  }
 
-    try {
-      const { domainHash, messageHash } = this.getDomainAndMessageHashes(parsed.dataToSign);
-      const payload = this.decodeOverrides(parsed.overrides);
-      const decodedDiff = this.decodeStateDiff(parsed.stateDiff);
-      const decodedPreimages = this.decodePreimages(parsed.preimages);
-      const parentMap = this.buildParentMap(decodedPreimages);
-      const config = this.loadAndResolveConfig();
-      const diffsMap = this.buildDiffsMap(decodedDiff);
-      const balanceChanges = this.extractBalanceChanges(config, chainIdStr, decodedDiff);
-
-      const result = this.buildTaskConfig({
-        cmd,
-        rpcUrl,
-        parsed,
-        domainHash,
-        messageHash,
+  /**
+   * Builds the validation result from a prestateTracer diff (debug_traceCall with
+   * { tracer: 'prestateTracer', tracerConfig: { diffMode: true } }) instead of a forge run.
+   * The trace carries no Safe context, so the target Safe and dataToSign are supplied by the
+   * caller and no state overrides are emitted.
+   */
+  async fromPrestateTrace(
+    rpcUrl: string,
+    trace: unknown,
+    opts: { targetSafe: string; dataToSign: string }
+  ): Promise<{ result: TaskConfig; output: string; warnings: ReportWarning[] }> {
+    const { client, retries } = this.rpcClient(rpcUrl);
+    const chainIdHex = await withSpan(
+      'rpc.enrichment',
+      { method: 'eth_chainId' },
+      async () => (await client.request({ method: 'eth_chainId' })) as string
+    );
+    const chainIdStr = BigInt(chainIdHex).toString();
+
+    const { domainHash, messageHash, form, decodedDiff } = await withSpan(
+      'decode',
+      { chainId: chainIdStr, source: 'prestateTracer' },
+      async () => ({
+        ...normalizeDataToSign(opts.dataToSign, { strict: this.strictHashFormat }),
+        decodedDiff: prestateTraceToAccountAccesses(parsePrestateTrace(trace)),
+      })
+    );
+
+    return this.transform({
+      cmd: PLACEHOLDER_CMD,
+      rpcUrl,
+      client,
+      chainIdStr,
+      targetSafe: opts.targetSafe,
+      domainHash,
+      messageHash,
+      dataToSignForm: form,
+      payload: { from: zeroAddress, to: zeroAddress, data: '0x', stateOverrides: [] },
+      decodedDiff,
+      ...this.buildPreimageMaps([], this.preimages),
+      extraPreimages: this.preimages,
+      retries,
+    });
+  }
+
+  /**
+   * Builds the validation result from a simulation exported from the Tenderly dashboard. The
+   * export carries the simulated call and its storage overrides, so overrides are emitted as
+   * usual; the Safe context is supplied by the caller as for prestateTracer input.
+   */
+  async fromTenderlyExport(
+    rpcUrl: string,
+    exported: unknown,
+    opts: { targetSafe: string; dataToSign: string }
+  ): Promise<{ result: TaskConfig; output: string; warnings: ReportWarning[] }> {
+    const { client, retries } = this.rpcClient(rpcUrl);
+    const chainIdHex = await withSpan(
+      'rpc.enrichment',
+      { method: 'eth_chainId' },
+      async () => (await client.request({ method: 'eth_chainId' })) as string
+    );
+    const chainIdStr = BigInt(chainIdHex).toString();
+
+    const { domainHash, messageHash, form, tenderly, decodedDiff } = await withSpan(
+      'decode',
+      { chainId: chainIdStr, source: 'tenderly' },
+      async () => {
+        const tenderly = parseTenderlyExport(exported);
+        return {
+          ...normalizeDataToSign(opts.dataToSign, { strict: this.strictHashFormat }),
+          tenderly,
+          decodedDiff: tenderlyToAccountAccesses(tenderly),
+        };
+      }
+    );
+
+    const { result, output, warnings } = await this.transform({
+      cmd: PLACEHOLDER_CMD,
+      rpcUrl,
+      client,
+      chainIdStr,
+      targetSafe: opts.targetSafe,
+      domainHash,
+      messageHash,
+      dataToSignForm: form,
+      payload: tenderlyToPayload(tenderly),
+      decodedDiff,
+      ...this.buildPreimageMaps([], this.preimages),
+      extraPreimages: this.preimages,
+      retries,
+    });
+    const networkId = tenderly.simulation.network_id;
+    if (networkId !== undefined && networkId !== chainIdStr) {
+      const warning = reportWarning(
+        'CHAIN_MISMATCH',
+        `The Tenderly simulation ran on network ${networkId} but --rpc-url is for chain ${chainIdStr}`,
+        { networkId, chainId: chainIdStr }
+      );
+      return withWarnings({ result, output, warnings }, [warning]);
+    }
+    return { result, output, warnings };
+  }
+
+  /**
+   * Builds the validation result by running the payload through eth_simulateV1 on the RPC
+   * node instead of forge. eth_simulateV1 does not report storage writes, so once it shows the
+   * call executes, the same call is traced with the prestateTracer in diffMode and the diff is
+   * built from the trace. The payload's storage overrides are applied to both and emitted as
+   * usual. A node that cannot trace the call fails the run rather than produce a file without
+   * storage changes.
+   */
+  async fromSimulateV1(
+    rpcUrl: string,
+    payload: PayloadDecoded,
+    opts: { targetSafe: string; dataToSign: string }
+  ): Promise<{ result: TaskConfig; output: string; warnings: ReportWarning[] }> {
+    const { client, retries } = this.rpcClient(rpcUrl);
+    // eth_simulateV1 and debug_traceCall params are built by hand, so bypass viem's typed
+    // request schema
+    const request = client.request as (args: {
+      method: string;
+      params?: unknown;
+    }) => Promise<unknown>;
+    const chainIdHex = await withSpan(
+      'rpc.enrichment',
+      { method: 'eth_chainId' },
+      async () => (await request({ method: 'eth_chainId' })) as string
+    );
+    const chainIdStr = BigInt(chainIdHex).toString();
+
+    const call = async (method: string, params: unknown[]) => {
+      try {
+        return await request({ method, params });
+      } catch (err) {
+        // Many nodes do not implement these yet; say so rather than surface a bare -32601
+        const message = err instanceof Error ? err.message : String(err);
+        throw new RpcError(`${method} request failed: ${message}`, { cause: err });
+      }
+    };
+    await withSpan('simulate', { method: 'eth_simulateV1' }, async () =>
+      parseSimulateV1Result(await call('eth_simulateV1', buildSimulateV1Params(payload)))
+    );
+    const trace = await withSpan('simulate', { method: 'debug_traceCall' }, async () =>
+      parsePrestateTrace(await call('debug_traceCall', buildPrestateTraceParams(payload)))
+    );
+
+    const { domainHash, messageHash, form } = normalizeDataToSign(opts.dataToSign, {
+      strict: this.strictHashFormat,
+    });
+    return this.transform({
+      cmd: PLACEHOLDER_CMD,
+      rpcUrl,
+      client,
+      chainIdStr,
+      targetSafe: opts.targetSafe,
+      domainHash,
+      messageHash,
+      dataToSignForm: form,
+      payload,
+      decodedDiff: prestateTraceToAccountAccesses(trace),
+      ...this.buildPreimageMaps([], this.preimages),
+      extraPreimages: this.preimages,
+      retries,
+    });
+  }
+
+  /**
+   * Builds the validation result for an EIP-2612 permit instead of a transaction. The hashes are
+   * the permit's typed-data hashes, and stateChanges holds the writes the permit makes once
+   * submitted: the allowance, and the owner's nonce when `noncesSlot` is given. Both slots are
+   * derived from the token's storage layout and checked against allowance() and nonces().
+   */
+  async fromPermit(
+    rpcUrl: string,
+    typedData: unknown,
+    opts: { allowanceSlot: Hex; noncesSlot?: Hex }
+  ): Promise<{ result: TaskConfig; output: string; warnings: ReportWarning[] }> {
+    const { client, retries } = this.rpcClient(rpcUrl);
+    const chainIdHex = await withSpan(
+      'rpc.enrichment',
+      { method: 'eth_chainId' },
+      async () => (await client.request({ method: 'eth_chainId' })) as string
+    );
+    const chainIdStr = BigInt(chainIdHex).toString();
+
+    const permit = parsePermit(typedData);
+    if (permit.chainId !== null && String(permit.chainId) !== chainIdStr) {
+      throw new PolicyViolationError(
+        `The permit is for chain ${permit.chainId}, but the RPC endpoint is on chain ${chainIdStr}`
+      );
+    }
+    const { domainHash, messageHash } = hashTypedDataParts(typedData);
+    console.log(`🎫 ${describePermit(permit)}`);
+
+    const warnings: ReportWarning[] = [];
+    if (permit.deadline < BigInt(Math.floor(Date.now() / 1000))) {
+      warnings.push(
+        reportWarning(
+          'PERMIT_EXPIRED',
+          `The permit deadline ${permit.deadline} has passed; submitting it would revert`,
+          { deadline: String(permit.deadline) }
+        )
+      );
+    }
+    // Reads a slot and the getter that should return it, so a wrong layout is caught
+    const readChecked = async (slot: Hex, expected: Promise<bigint>, flag: string) => {
+      const [stored, reported] = await Promise.all([
+        withSpan('rpc.enrichment', { method: 'eth_getStorageAt' }, () =>
+          client.getStorageAt({ address: permit.token, slot })
+        ),
+        expected,
+      ]);
+      const before = normalize32(stored ?? '0x0');
+      if (BigInt(before) !== reported) {
+        throw new Error(
+          `Slot ${slot} of ${permit.token} holds ${BigInt(before)}, but the token reports ${reported}; pass the slot the mapping is declared at with ${flag}`
+        );
+      }
+      return { before, value: reported };
+    };
+
+    const preimages = allowanceSlot(permit, opts.allowanceSlot);
+    const allowance = preimages[1].slot;
+    const { before: allowanceBefore } = await readChecked(
+      allowance,
+      client.readContract({
+        address: permit.token,
+        abi: PERMIT_TOKEN_ABI,
+        functionName: 'allowance',
+        args: [permit.owner, permit.spender],
+      }),
+      '--allowance-slot'
+    );
+    const writes = [{ slot: allowance, before: allowanceBefore, after: bigintToHex(permit.value) }];
+
+    if (opts.noncesSlot) {
+      const nonce = noncesSlot(permit, opts.noncesSlot);
+      const { before, value } = await readChecked(
+        nonce.slot,
+        client.readContract({
+          address: permit.token,
+          abi: PERMIT_TOKEN_ABI,
+          functionName: 'nonces',
+          args: [permit.owner],
+        }),
+        '--nonces-slot'
+      );
+      if (value !== permit.nonce) {
+        warnings.push(
+          reportWarning(
+            'PERMIT_NONCE_MISMATCH',
+            `The permit is signed for nonce ${permit.nonce}, but the owner's nonce is ${value}; submitting it would revert`,
+            { signed: String(permit.nonce), current: String(value) }
+          )
+        );
+      }
+      preimages.push(nonce);
+      writes.push({ slot: nonce.slot, before, after: bigintToHex(value + BigInt(1)) });
+    } else {
+      warnings.push(
+        reportWarning(
+          'PERMIT_NONCE_UNPREDICTED',
+          'The nonce the permit uses up is not listed; pass --nonces-slot to predict it'
+        )
+      );
+    }
+    for (const warning of warnings) console.warn(`⚠️ ${warning.message}`);
+
+    const built = await this.transform({
+      cmd: PLACEHOLDER_CMD,
+      rpcUrl,
+      client,
+      chainIdStr,
+      targetSafe: permit.token,
+      signedBySafe: false,
+      domainHash,
+      messageHash,
+      dataToSignForm: 'typed-data',
+      payload: { from: zeroAddress, to: zeroAddress, data: '0x', stateOverrides: [] },
+      decodedDiff: permitAccountAccesses(permit, writes),
+      ...this.buildPreimageMaps(preimages, this.preimages),
+      extraPreimages: this.preimages,
+      retries,
+    });
+    return withWarnings(built, warnings);
+  }
+
+  // A client for one run, with a fresh retry budget
+  private rpcClient(rpcUrl: string): { client: PublicClient; retries: RetryLog } {
+    const retries = newRetryLog();
+    const transport = this.transport ?? http(rpcUrl);
+    return {
+      client: createPublicClient({
+        transport: this.rpcRetry ? retryingTransport(transport, this.rpcRetry, retries) : transport,
+      }) as PublicClient,
+      retries,
+    };
+  }
+
+  /**
+   * Everything the tool knows about one storage slot, for ad-hoc investigation: the
+   * contracts.json or known-pattern entry that describes it, the mapping keys it is derived
+   * from (the client's preimages), and, with an RPC endpoint, its current value.
+   */
+  async explainSlot(params: {
+    rpcUrl?: string;
+    chainId?: number;
+    address: string;
+    slot: Hex;
+  }): Promise<SlotExplanation> {
+    const addr = params.address.toLowerCase();
+    const address = getAddress(addr);
+    const slot = normalize32(params.slot);
+    const transport = this.transport ?? (params.rpcUrl ? http(params.rpcUrl) : null);
+    const client = transport ? createPublicClient({ transport }) : null;
+
+    let chainIdStr = params.chainId !== undefined ? String(params.chainId) : null;
+    if (client) {
+      const chainIdHex = (await client.request({ method: 'eth_chainId' })) as string;
+      const actual = BigInt(chainIdHex).toString();
+      if (chainIdStr && chainIdStr !== actual) {
+        throw new Error(`The RPC endpoint is on chain ${actual}, not chain ${chainIdStr}`);
+      }
+      chainIdStr = actual;
+    }
+    if (!chainIdStr) throw new Error('Explaining a slot needs an RPC URL or a chain ID');
+
+    const configured = this.loadAndResolveConfig().contracts[chainIdStr]?.[addr];
+    // Unconfigured contracts are matched against the known patterns, which needs their code
+    const config = client
+      ? await this.withKnownPatterns(client, chainIdStr, [], [addr])
+      : this.loadAndResolveConfig();
+    const contract = config.contracts[chainIdStr]?.[addr];
+
+    const stored = this.preimageStore
+      ? storedPreimages(await loadPreimageStore(this.preimageStore, chainIdStr), [addr])
+      : [];
+    const preimages = this.buildPreimageMaps([], [...this.preimages, ...stored]);
+    const derivation: SlotExplanation['derivation'] = [];
+    for (let current = slot; preimages.parentMap.has(current); ) {
+      const parent = preimages.parentMap.get(current)!;
+      derivation.push({ slot: current, parent, key: preimages.preimageKeys.get(current)! });
+      current = parent;
+    }
+
+    const value = client
+      ? normalize32((await client.getStorageAt({ address, slot })) ?? '0x0')
+      : null;
+    const slotCfg = this.findSlot(contract, slot, preimages.parentMap);
+    let entry: SlotExplanation['entry'] = null;
+    if (slotCfg) {
+      const word = value ?? undefined;
+      const values = slotTemplateValues(
+        { before: word, after: word, value: word },
+        this.mappingKeysOf(slot, preimages)
+      );
+      // Without a value, descriptions that refer to it are shown as written
+      const render = (template: string, where: string) => {
+        try {
+          return renderSlotTemplate(template, values, where, slotCfg);
+        } catch {
+          return template;
+        }
+      };
+      entry = {
+        type: slotCfg.type,
+        description: render(slotCfg.summary, `Summary of slot ${slot}`),
+        overrideMeaning: render(slotCfg.overrideMeaning, `Override meaning of slot ${slot}`),
+        critical: slotCfg.critical ?? false,
+      };
+    }
+
+    return {
+      chainId: chainIdStr,
+      address,
+      contract: configured ? configured.name : null,
+      patterns: contract?.patterns ?? [],
+      slot,
+      derivation,
+      entry,
+      value,
+    };
+  }
+
+  private async transform(input: {
+    cmd: string;
+    rpcUrl: string;
+    client: PublicClient;
+    chainIdStr: string;
+    targetSafe: string;
+    // False when the signer is not a Safe, as for a permit signed for a token
+    signedBySafe?: boolean;
+    domainHash: Hex;
+    messageHash: Hex;
+    dataToSignForm: DataToSignForm;
+    payload: PayloadDecoded;
+    decodedDiff: readonly VmSafeAccountAccess[];
+    parentMap: Map<Hex, Hex>;
+    preimageKeys: Map<Hex, Hex>;
+    extraPreimages: readonly ParentPreimage[];
+    retries: RetryLog;
+    // The block the diff was simulated on; the head for sources that simulate against it
+    simulatedAt?: SimulatedAt;
+  }): Promise<{ result: TaskConfig; output: string; warnings: ReportWarning[] }> {
+    const store = this.preimageStore
+      ? await loadPreimageStore(this.preimageStore, input.chainIdStr)
+      : null;
+    const params = store ? withStoredPreimages(input, store) : input;
+    const { client, chainIdStr, decodedDiff } = params;
+    const { storage: diffsMap, accounts, newAccounts: firstTouched } =
+      aggregateAccountAccesses(decodedDiff);
+    // Only accounts the report lists; calls to empty accounts such as precompiles are left out
+    const newAccounts = new Set(
+      [...firstTouched].filter(addr => diffsMap.has(addr) || accounts.has(addr))
+    );
+    for (const addr of newAccounts) {
+      console.log(`🌱 ${getAddress(addr)} did not exist before the transaction; it starts empty`);
+    }
+    const touchedAccounts = new Set([
+      ...diffsMap.keys(),
+      ...params.payload.stateOverrides.map(o => o.contractAddress.toLowerCase()),
+    ]);
+    const config = await withSpan('rpc.enrichment', { method: 'eth_getCode' }, () =>
+      this.withKnownPatterns(client, chainIdStr, decodedDiff, Array.from(touchedAccounts))
+    );
+
+    const createdAccounts = new Set(
+      decodedDiff
+        .filter(a => a.kind === AccountAccessKind.Create)
+        .map(a => a.account.toLowerCase())
+    );
+    const suspicious = await withSpan('rpc.enrichment', { method: 'eth_getCode' }, () =>
+      findSuspiciousWrites(client, Array.from(diffsMap.keys()), createdAccounts)
+    );
+    const warnings = suspicious.map(w => reportWarning('SUSPICIOUS_WRITE', w));
+    const simulatedAt = params.simulatedAt ?? (await simulatedBlock(client));
+    const { safeNonce, nonceWarning } = await this.checkSafeNonce(params);
+    if (nonceWarning) warnings.push(reportWarning('SAFE_NONCE_MISMATCH', nonceWarning));
+    const deletions = findAccountDeletions(decodedDiff);
+    for (const deletion of deletions) {
+      warnings.push(
+        reportWarning('ACCOUNT_DELETED', describeAccountDeletion(deletion), {
+          address: getAddress(deletion.address),
+        })
+      );
+    }
+    const transfers = findEthTransfers(decodedDiff, accounts);
+    for (const transfer of transfers) console.log(`💸 ${describeEthTransfer(transfer)}`);
+    const codeDiffs = await withSpan('rpc.enrichment', { method: 'eth_getCode' }, () =>
+      findCodeChanges(client, decodedDiff, diffsMap)
+    );
+    for (const change of codeDiffs) console.log(`🧬 ${describeCodeChange(change)}`);
+    const safeConfigDiffs = await withSpan('rpc.enrichment', { method: 'eth_getCode' }, () =>
+      findSafeConfigurationChanges(client, decodedDiff, diffsMap)
+    );
+    for (const change of safeConfigDiffs) {
+      console.log(`🛡️  ${describeSafeConfigurationChange(change)}`);
+      const warning = safeConfigurationWarning(change);
+      if (warning) {
+        warnings.push(
+          reportWarning('SAFE_CONFIGURATION_CHANGED', warning, {
+            address: getAddress(change.address),
+            setting: change.setting,
+          })
+        );
+      }
+    }
+    const safe = getAddress(params.targetSafe);
+    const singleton =
+      params.signedBySafe === false
+        ? null
+        : await withSpan('rpc.enrichment', { method: 'eth_getStorageAt' }, () =>
+            readSafeSingleton(client, safe)
+          );
+    const safeVersion = singleton ? (knownSafeVersion(singleton) ?? undefined) : undefined;
+    if (singleton) {
+      console.log(`🏛️  ${safe} runs ${describeSafeVersion(singleton, safeVersion)}`);
+    }
+    if (singleton && !safeVersion) {
+      warnings.push(
+        reportWarning(
+          'UNKNOWN_SAFE_SINGLETON',
+          `${safe} delegates to ${singleton}, which is not a known Safe deployment; check the hashes against its domainSeparator() and getTransactionHash()`,
+          { address: safe, singleton }
+        )
+      );
+    }
+    // The version decides the domain, so a hash built for another version would not verify
+    if (
+      safeVersion &&
+      safeDomainHash(safeVersion, Number(chainIdStr), safe) !== params.domainHash.toLowerCase()
+    ) {
+      warnings.push(
+        reportWarning(
+          'SAFE_DOMAIN_MISMATCH',
+          `The domain hash ${params.domainHash} is not the one ${safe} signs under as a Safe ${safeVersion} on chain ${chainIdStr}`,
+          { address: safe, safeVersion }
+        )
+      );
+    }
+    // Prestate traces carry no call to replay
+    const execution = isAddressEqual(params.payload.to, zeroAddress)
+      ? undefined
+      : await withSpan('rpc.enrichment', { method: 'eth_call' }, () =>
+          checkExecution(
+            client,
+            params.payload,
+            getAddress(params.targetSafe),
+            BigInt(simulatedAt.blockNumber)
+          )
+        );
+    if (execution && execution.status !== 'success') {
+      warnings.push(
+        reportWarning('EXECUTION_FAILED', describeExecutionCheck(execution), {
+          status: execution.status,
+        })
+      );
+    } else if (execution) {
+      console.log(`⛽ ${describeExecutionCheck(execution)}`);
+    }
+    for (const warning of warnings) console.warn(`⚠️ ${warning.message}`);
+
+    const payloadSignatures = await decodePayloadSignatures({
+      to: params.payload.to,
+      data: params.payload.data,
+      targetSafe: getAddress(params.targetSafe),
+      safeTxHash: computeSafeTxHash(params.domainHash, params.messageHash),
+    });
+    if (payloadSignatures.length > 0) {
+      console.log(
+        `✍️  The simulation assumes these signatures: ${payloadSignatures.map(describeSafeSignature).join(', ')}`
+      );
+    }
+
+    // Every lookup has been made by now, so the retries cover the whole run
+    for (const warning of retryWarnings(params.retries)) {
+      console.warn(`⚠️ ${warning.message}`);
+      warnings.push(warning);
+    }
+
+    const result = await withSpan('report', { chainId: chainIdStr }, async () => {
+      const balances = this.extractBalanceChanges(config, chainIdStr, accounts, newAccounts);
+      const ethTransfers = this.convertTransfersToJSON(config, chainIdStr, transfers);
+      const accountDeletions = this.convertDeletionsToJSON(config, chainIdStr, deletions);
+      const codeChanges = this.convertCodeChangesToJSON(config, chainIdStr, codeDiffs);
+      const safeConfigurationChanges = this.convertSafeConfigChangesToJSON(
+        config,
+        chainIdStr,
+        safeConfigDiffs
+      );
+      const built = this.buildTaskConfig({
+        cmd: params.cmd,
+        rpcUrl: params.rpcUrl,
+        targetSafe: params.targetSafe,
+        ...(singleton && { singleton, safeVersion }),
+        domainHash: params.domainHash,
+        messageHash: params.messageHash,
+        dataToSignForm: params.dataToSignForm,
         config,
         chainIdStr,
-        payload,
+        payload: params.payload,
         diffs: Array.from(diffsMap.values()),
-        balanceChanges,
-        parentMap,
+        balanceChanges: balances.balanceChanges,
+        balanceNoise: balances.noise,
+        newAccounts,
+        ethTransfers,
+        parentMap: params.parentMap,
+        preimageKeys: params.preimageKeys,
+        extraPreimages: params.extraPreimages,
+        safeNonce,
+        accountDeletions,
+        codeChanges,
+        safeConfigurationChanges,
+        execution,
+        simulatedAt,
+        criticalReads:
+          this.includeReads === 'critical'
+            ? this.extractCriticalReads(config, chainIdStr, decodedDiff, params)
+            : [],
+        payloadSignatures: payloadSignatures.map(sig => ({
+          type: sig.type,
+          ...(sig.signer ? { signer: sig.signer } : {}),
+        })),
       });
+      // Once per changed slot, however many times the slot was looked up
+      incrementCounter('task_signing.unknown_slots', built.summary?.unknownSlots ?? 0);
+      const report = applyUnknownMode(built, this.unknowns);
+      warnings.push(...unknownEntryWarnings(report.summary));
+      return warnings.length > 0 ? { ...report, warnings } : report;
+    });
 
-      const output = `<<<RESULT>>>\n${JSON.stringify(result, null, 2)}`;
-      console.log('✅ State-diff transformation completed');
-      return {
-        result,
-        output,
-        transactionTo: payload.to,
-        transactionData: payload.data,
-        forgeOutput: stdout,
-      };
-    } finally {
-      await this.deleteFile(stateDiffPath);
+    // Keep what resolved each contract's slots for later runs that lack the preimages
+    if (store) {
+      const slotsByContract = new Map<string, Hex[]>();
+      for (const [addr, diff] of diffsMap) {
+        slotsByContract.set(addr, [...diff.storageDiffs.keys()] as Hex[]);
+      }
+      for (const o of params.payload.stateOverrides) {
+        const addr = o.contractAddress.toLowerCase();
+        const slots = o.overrides.map(v => v.key);
+        slotsByContract.set(addr, [...(slotsByContract.get(addr) ?? []), ...slots]);
+      }
+      const learned = await savePreimageStore(
+        store,
+        learnPreimages(slotsByContract, params.parentMap, params.preimageKeys)
+      );
+      if (learned > 0) console.log(`🧠 Learned ${learned} new mapping preimage(s)`);
     }
+
+    const output = resultOutput(result);
+    console.log('✅ State-diff transformation completed');
+    console.log(`📊 ${describeReportSummary(result.summary!)}`);
+    const groups = describeReportGroups(result.summary!.groups ?? []);
+    for (const line of groups) console.log(`   ${line}`);
+    console.log(
+      `🔑 safeTxHash: ${result.expectedDomainAndMessageHashes.safeTxHash} (should match the transaction hash shown in the Safe UI)`
+    );
+    return { result, output, warnings };
   }
 
   private runCommand(
@@ -216,17 +1163,34 @@ export class StateDiffClient {
   private stateDiffFilePath(workdir: string): string {
     const normalizedWorkdir = path.resolve(workdir);
     const filePath = path.resolve(normalizedWorkdir, 'stateDiff.json');
-    assertWithinDir(filePath, normalizedWorkdir);
+    // A stateDiff.json symlink committed to the task repo would otherwise be written through
+    assertRealPathWithinDir(filePath, normalizedWorkdir);
     return filePath;
   }
 
-  private async readEncodedStateDiff(filePath: string): Promise<ParsedInput> {
+  private async readEncodedStateDiff(filePath: string, workdir: string): Promise<EncodedStateDiff> {
     try {
+      // The forge command can write anything into the workdir, including a symlink in place of
+      // stateDiff.json, so only a file that really is inside the workdir is read
+      assertRealPathWithinDir(filePath, workdir);
+      if (this.limits) {
+        const { size } = await fs.stat(filePath);
+        try {
+          checkDiffFileSize('stateDiff.json', size, this.limits);
+        } catch (err) {
+          await this.deleteFile(filePath);
+          throw err;
+        }
+      }
       const raw = await fs.readFile(filePath, 'utf-8');
-      return JSON.parse(raw) as ParsedInput;
+      if (!raw.trim()) {
+        // The sandbox creates the file before forge runs; empty means forge never wrote it
+        throw new SimulationFailedError(`forge did not write stateDiff.json at ${filePath}`);
+      }
+      return JSON.parse(raw) as EncodedStateDiff;
     } catch (err: unknown) {
       if (err instanceof Error && 'code' in err && err.code === 'ENOENT') {
-        throw new Error(`stateDiff.json not found at ${filePath}`);
+        throw new SimulationFailedError(`stateDiff.json not found at ${filePath}`);
       }
       throw err;
     }
@@ -243,133 +1207,33 @@ export class StateDiffClient {
     }
   }
 
-  private getDomainAndMessageHashes(dataToSign: string): { domainHash: Hex; messageHash: Hex } {
-    const v = dataToSign.trim();
-    if (!v.startsWith('0x')) throw new Error('dataToSign must be 0x-prefixed hex');
-    const hex = v.slice(6);
-    if (hex.length !== 128) {
-      throw new Error(`expected 64 bytes (domain + message), got ${hex.length / 2} bytes`);
-    }
-    const domainHash = ('0x' + hex.slice(0, 64)) as Hex;
-    const messageHash = ('0x' + hex.slice(64, 128)) as Hex;
-    return { domainHash, messageHash };
-  }
-
-  private decodeOverrides(encoded: string): PayloadDecoded {
-    const [tuple] = decodeAbiParameters(
-      [
-        {
-          type: 'tuple',
-          components: [
-            { name: 'from', type: 'address' },
-            { name: 'to', type: 'address' },
-            { name: 'data', type: 'bytes' },
-            {
-              name: 'stateOverrides',
-              type: 'tuple[]',
-              components: [
-                { name: 'contractAddress', type: 'address' },
-                {
-                  name: 'overrides',
-                  type: 'tuple[]',
-                  components: [
-                    { name: 'key', type: 'bytes32' },
-                    { name: 'value', type: 'bytes32' },
-                  ],
-                },
-              ],
-            },
-          ],
-        },
-      ],
-      encoded as Hex
-    );
-    return tuple;
-  }
-
-  private decodeStateDiff(encoded: string): readonly VmSafeAccountAccess[] {
-    const [accesses] = decodeAbiParameters(
-      [
-        {
-          type: 'tuple[]',
-          components: [
-            {
-              name: 'chainInfo',
-              type: 'tuple',
-              components: [
-                { name: 'forkId', type: 'uint256' },
-                { name: 'chainId', type: 'uint256' },
-              ],
-            },
-            { name: 'kind', type: 'uint8' },
-            { name: 'account', type: 'address' },
-            { name: 'accessor', type: 'address' },
-            { name: 'initialized', type: 'bool' },
-            { name: 'oldBalance', type: 'uint256' },
-            { name: 'newBalance', type: 'uint256' },
-            { name: 'deployedCode', type: 'bytes' },
-            { name: 'value', type: 'uint256' },
-            { name: 'data', type: 'bytes' },
-            { name: 'reverted', type: 'bool' },
-            {
-              name: 'storageAccesses',
-              type: 'tuple[]',
-              components: [
-                { name: 'account', type: 'address' },
-                { name: 'slot', type: 'bytes32' },
-                { name: 'isWrite', type: 'bool' },
-                { name: 'previousValue', type: 'bytes32' },
-                { name: 'newValue', type: 'bytes32' },
-                { name: 'reverted', type: 'bool' },
-              ],
-            },
-            { name: 'depth', type: 'uint64' },
-            { name: 'oldNonce', type: 'uint64' },
-            { name: 'newNonce', type: 'uint64' },
-          ],
-        },
-      ],
-      encoded as Hex
-    );
-
-    return accesses;
-  }
-
-  private decodePreimages(encoded: string): readonly ParentPreimage[] {
-    const [arr] = decodeAbiParameters(
-      [
-        {
-          type: 'tuple[]',
-          components: [
-            { name: 'slot', type: 'bytes32' },
-            { name: 'parent', type: 'bytes32' },
-            { name: 'key', type: 'bytes32' },
-          ],
-        },
-      ],
-      encoded as Hex
-    );
-    return arr;
-  }
+  // Keyed by the overlay's hash; '' is contracts.json alone
+  private static configCache = new Map<string, ResolvedConfig>();
 
-  private static configCache: { contracts: Record<string, Record<string, ContractCfg>> } | null =
-    null;
+  private loadAndResolveConfig(): ResolvedConfig {
+    const cached = StateDiffClient.configCache.get(this.configKey);
+    if (cached) return cached;
 
-  private loadAndResolveConfig(): { contracts: Record<string, Record<string, ContractCfg>> } {
-    if (StateDiffClient.configCache) return StateDiffClient.configCache;
+    const parsed = withOverlay(contractsCfg as unknown as RawConfig, this.configOverlay);
 
-    const parsed = contractsCfg as unknown as {
-      contracts: Record<string, Record<string, RawContractCfg>>;
-      storageLayouts: Record<string, Record<string, SlotCfg>>;
+    const minBalanceDelta = parsed.noise?.minBalanceDeltaWei ?? '0';
+    if (!/^\d+$/.test(minBalanceDelta)) {
+      throw new Error(`Invalid noise.minBalanceDeltaWei: ${minBalanceDelta}`);
+    }
+    const out: ResolvedConfig = {
+      contracts: {},
+      knownPatterns: [],
+      artifacts: [],
+      minBalanceDelta: BigInt(minBalanceDelta),
+      constants: resolveValueAliases(parsed.constants),
     };
 
-    const out: { contracts: Record<string, Record<string, ContractCfg>> } = { contracts: {} };
-
     // Normalize storage layouts: ensure lowercase slot keys
     const normalizedLayouts: Record<string, Record<string, SlotCfg>> = {};
     for (const [layoutName, slots] of Object.entries(parsed.storageLayouts || {})) {
       const layoutSlots: Record<string, SlotCfg> = {};
       for (const [slotKey, slotVal] of Object.entries(slots || {})) {
+        validateFormatHint(slotVal, `Slot ${slotKey} of storageLayouts.${layoutName}`);
         layoutSlots[slotKey.toLowerCase()] = slotVal;
       }
       normalizedLayouts[layoutName] = layoutSlots;
@@ -378,85 +1242,158 @@ export class StateDiffClient {
     for (const [chainId, contracts] of Object.entries(parsed.contracts || {})) {
       const lowerChain = chainId.trim();
       out.contracts[lowerChain] = {};
-      for (const [addr, def] of Object.entries(contracts || {})) {
-        const lowerAddr = addr.toLowerCase();
-        const rawSlots = def.slots;
-        let slots: Record<string, SlotCfg> = {};
-
-        if (typeof rawSlots === 'string') {
-          // Expect pattern: "{{storageLayouts.NAME}}"
-          const m = rawSlots.match(/^\{\{storageLayouts\.(.+)\}\}$/);
-          if (!m) {
-            throw new Error(`Invalid slots reference for ${addr} on chain ${chainId}: ${rawSlots}`);
-          }
-          const layout = normalizedLayouts[m[1]];
-          if (!layout) {
-            throw new Error(`Missing storageLayouts.${m[1]} for ${addr} on ${chainId}`);
-          }
-          slots = layout;
-        } else if (rawSlots && typeof rawSlots === 'object') {
-          // Inline slots, normalize keys and field names
-          const inline: Record<string, SlotCfg> = {};
-          for (const [k, v] of Object.entries(rawSlots as Record<string, SlotCfg>)) {
-            inline[k.toLowerCase()] = v;
-          }
-          slots = inline;
-        } else {
-          slots = {};
-        }
+      const byAddress = normalizeAddressKeys(contracts || {}, `contracts.${chainId}`);
+      for (const [lowerAddr, def] of Object.entries(byAddress)) {
+        out.contracts[lowerChain][lowerAddr] = resolveContractCfg(
+          def,
+          normalizedLayouts,
+          `${getAddress(lowerAddr)} on ${chainId}`
+        );
+      }
+    }
 
-        const normalizedSlots: Record<string, SlotCfg> = {};
-        for (const [k, v] of Object.entries(slots)) normalizedSlots[k.toLowerCase()] = v;
-        out.contracts[lowerChain][lowerAddr] = { name: def.name, slots: normalizedSlots };
+    for (const pattern of parsed.knownPatterns || []) {
+      const m = pattern.slots.match(/^\{\{storageLayouts\.(.+)\}\}$/);
+      const layout = m ? normalizedLayouts[m[1]] : undefined;
+      if (!layout) {
+        throw new Error(
+          `Invalid slots reference for known pattern ${pattern.name}: ${pattern.slots}`
+        );
       }
+      out.knownPatterns.push({ ...pattern, slots: layout });
+    }
+
+    for (const artifact of parsed.artifacts || []) {
+      out.artifacts.push({
+        codeHashes: artifact.codeHashes ?? [],
+        metadataHashes: artifact.metadataHashes ?? [],
+        contract: resolveContractCfg(artifact, normalizedLayouts, `artifact ${artifact.name}`),
+      });
     }
 
-    StateDiffClient.configCache = out;
+    StateDiffClient.configCache.set(this.configKey, out);
     return out;
   }
 
-  private buildDiffsMap(
-    decoded: readonly VmSafeAccountAccess[]
-  ): Map<
-    string,
-    { address: string; storageDiffs: Map<string, { key: Hex; before: Hex; after: Hex }> }
-  > {
-    const diffs = new Map<
-      string,
-      { address: string; storageDiffs: Map<string, { key: Hex; before: Hex; after: Hex }> }
-    >();
-    for (const d of decoded) {
-      for (const a of d.storageAccesses) {
-        if (!a.isWrite) continue;
-        const addr = a.account.toLowerCase();
-        let acct = diffs.get(addr);
-        if (!acct) {
-          acct = { address: addr, storageDiffs: new Map() };
-          diffs.set(addr, acct);
-        }
-        const slot = this.n(a.slot);
-        const existing = acct.storageDiffs.get(slot) || {
-          key: slot,
-          before: this.n(a.previousValue),
-          after: this.n(a.previousValue),
-        };
-        existing.after = this.n(a.newValue);
-        if (equalHex(existing.before, existing.after)) {
-          acct.storageDiffs.delete(slot);
-        } else {
-          acct.storageDiffs.set(slot, existing);
+  // Fills in slot descriptions for unconfigured contracts. Contracts the simulation creates are
+  // matched to `artifacts` by code or metadata hash and take the artifact's name and layout;
+  // others that match a built-in pattern (OpenZeppelin Ownable, AccessControl, ...) get its
+  // slots, with the contract name left as a placeholder.
+  private async withKnownPatterns(
+    client: Pick<PublicClient, 'getCode' | 'getStorageAt'>,
+    chainId: string,
+    decoded: readonly VmSafeAccountAccess[],
+    accounts: string[]
+  ): Promise<ResolvedConfig> {
+    const config = this.loadAndResolveConfig();
+    const chainContracts = config.contracts[chainId] || {};
+    const deployedCode = new Map(
+      decoded
+        .filter(a => a.kind === AccountAccessKind.Create)
+        .map(a => [a.account.toLowerCase(), a.deployedCode])
+    );
+
+    // Contracts the task deploys get the layout of the artifact their code was built from
+    const deployed: Record<string, ContractCfg> = {};
+    for (const addr of accounts) {
+      if (chainContracts[addr] || !deployedCode.has(addr)) continue;
+      const artifact = matchArtifact(deployedCode.get(addr), config.artifacts);
+      if (!artifact) continue;
+      console.log(
+        `🏗️  ${getAddress(addr)} is deployed by the task from ${artifact.contract.name}`
+      );
+      deployed[addr] = artifact.contract;
+    }
+    const known = { ...chainContracts, ...deployed };
+    const withContracts = (identified: Record<string, ContractCfg>): ResolvedConfig => ({
+      ...config,
+      contracts: { ...config.contracts, [chainId]: { ...known, ...identified } },
+    });
+
+    const unknown = accounts.filter(addr => !known[addr]);
+    if (unknown.length === 0 || config.knownPatterns.length === 0) return withContracts({});
+
+    const cacheOptions = this.metadataCache;
+    const cache = cacheOptions
+      ? await loadMetadataCache(cacheOptions.dir, chainId, canonicalHash(config.knownPatterns))
+      : null;
+    const updates: Record<string, ContractMetadata> = {};
+    const byName = new Map(config.knownPatterns.map(p => [p.name, p]));
+
+    const identified: Record<string, ContractCfg> = {};
+    for (const [i, addr] of unknown.entries()) {
+      reportProgress(i, unknown.length);
+      const address = getAddress(addr);
+      const created = deployedCode.has(addr);
+      // Contracts created by the simulation are not on chain, so they are never cached
+      const cached =
+        cache && cacheOptions && !created
+          ? cachedMetadata(cache, addr, cacheOptions.ttlHours)
+          : null;
+      let patterns: string[];
+      let pinned: string[];
+      let implementation = cached?.metadata.implementation ?? null;
+      if (cached && cached.fresh && !cacheOptions?.refresh) {
+        patterns = cached.metadata.patterns;
+        pinned = cached.metadata.pinned ?? [];
+      } else {
+        try {
+          const inspection = await inspectContract(
+            client,
+            address,
+            config.knownPatterns,
+            deployedCode.get(addr)
+          );
+          patterns = inspection.matches.map(m => m.name);
+          pinned = inspection.pinned;
+          implementation = inspection.implementation;
+          if (cache && !created) {
+            updates[addr] = {
+              codeHash: inspection.codeHash,
+              implementation: inspection.implementation,
+              patterns,
+              pinned,
+              fetchedAt: new Date().toISOString(),
+            };
+          }
+        } catch (err) {
+          if (!cached) throw err;
+          console.warn(
+            `⚠️  Could not inspect ${address} (${err instanceof Error ? err.message : String(err)}); using metadata cached at ${cached.metadata.fetchedAt}`
+          );
+          patterns = cached.metadata.patterns;
+          pinned = cached.metadata.pinned ?? [];
         }
-        if (acct.storageDiffs.size === 0) diffs.delete(addr);
       }
+      const matches = patterns.flatMap(name => byName.get(name) ?? []);
+      if (matches.length === 0) continue;
+      const names = matches.map(m => m.name).join(', ');
+      if (matches.every(m => pinned.includes(m.name))) {
+        console.log(`🔎 ${address} matches ${names} by code hash`);
+      } else {
+        console.log(
+          `🔎 ${address} may be ${names}: only its function selectors match, so the slot descriptions are hints. Once the code at ${implementation ?? address} is reviewed, add its code hash to the pattern's codeHashes`
+        );
+      }
+      identified[addr] = {
+        name: UNKNOWN_CONTRACT_NAME,
+        slots: Object.assign(
+          {},
+          ...matches.map(m => (pinned.includes(m.name) ? m.slots : selectorHintSlots(m)))
+        ),
+        patterns: matches.map(m => m.name),
+      };
     }
-    return diffs;
+    if (cache) await saveMetadataCache(cache, updates);
+
+    return withContracts(identified);
   }
 
   private convertOverridesToJSON(
     cfg: { contracts: Record<string, Record<string, ContractCfg>> },
     chainId: string,
     overrides: readonly StateOverrideDecoded[],
-    parentMap: Map<Hex, Hex>
+    preimages: PreimageMaps
   ): StateOverride[] {
     const result: StateOverride[] = [];
     const chainContracts = cfg.contracts[chainId] || {};
@@ -479,7 +1416,7 @@ export class StateDiffClient {
       let entry = aggregated.get(addrLower);
       if (!entry) {
         const contract = chainContracts[addrLower];
-        entry = { contract, name: contract?.name ?? '<<ContractName>>', storageMap: new Map() };
+        entry = { contract, name: contract?.name ?? UNKNOWN_CONTRACT_NAME, storageMap: new Map() };
         aggregated.set(addrLower, entry);
       }
       for (const s of o.overrides) {
@@ -493,93 +1430,134 @@ export class StateDiffClient {
         a.key.localeCompare(b.key)
       );
       const jsonOverrides = sortedStorage.map(s => {
-        const slotCfg = this.getSlot(contract, s.key, parentMap);
+        const slotCfg = this.getSlot(contract, s.key, preimages.parentMap);
+        const values = slotTemplateValues({ value: s.value }, this.mappingKeysOf(s.key, preimages));
         return {
           key: s.key,
           value: s.value,
-          description: slotCfg.overrideMeaning,
+          description: renderSlotTemplate(
+            slotCfg.overrideMeaning,
+            values,
+            `Override meaning of ${name} slot ${s.key}`,
+            slotCfg
+          ),
           allowDifference: slotCfg.allowOverrideDifference,
         };
       });
-      result.push({ name, address: getAddress(addrLower), overrides: jsonOverrides });
+      const address = getAddress(addrLower);
+      result.push({
+        name,
+        address,
+        explorerUrl: explorerAddressUrl(chainId, address),
+        overrides: jsonOverrides,
+      });
     }
     return result;
   }
 
   private convertDiffsToJSON(
-    cfg: { contracts: Record<string, Record<string, ContractCfg>> },
+    cfg: Pick<ResolvedConfig, 'contracts' | 'constants'>,
     chainId: string,
-    diffs: Array<{
-      address: string;
-      storageDiffs: Map<string, { key: Hex; before: Hex; after: Hex }>;
-    }>,
-    parentMap: Map<Hex, Hex>
-  ): StateChange[] {
+    diffs: StorageDiff[],
+    preimages: PreimageMaps,
+    newAccounts: ReadonlySet<string>,
+    overrides: readonly StateOverrideDecoded[]
+  ): { stateChanges: StateChange[]; noise: number } {
     const result: StateChange[] = [];
+    let noise = 0;
+    // Slots the simulation overrode, whose before value is the override rather than the chain's
+    const overridden = new Set(
+      overrides.flatMap(o =>
+        o.overrides.map(s => `${o.contractAddress.toLowerCase()}:${normalize32(s.key)}`)
+      )
+    );
     const chainContracts = cfg.contracts[chainId] || {};
     const sortedDiffs = [...diffs].sort((a, b) => a.address.localeCompare(b.address));
     for (const d of sortedDiffs) {
       const contract = chainContracts[d.address];
-      const name = contract?.name ?? '<<ContractName>>';
+      const name = contract?.name ?? UNKNOWN_CONTRACT_NAME;
       const storageArray = Array.from(d.storageDiffs.values());
       storageArray.sort((a, b) => a.key.localeCompare(b.key));
-      const changes = storageArray.map(s => {
-        const slotCfg = this.getSlot(contract, s.key, parentMap);
-        return {
-          key: s.key,
-          before: this.n(s.before),
-          after: this.n(s.after),
-          description: slotCfg.summary,
-          allowDifference: slotCfg.allowDifference,
-        };
+      const changes = storageArray.flatMap(s => {
+        const slotCfg = this.getSlot(contract, s.key, preimages.parentMap);
+        if (slotCfg.noise) {
+          noise++;
+          return [];
+        }
+        const before = this.n(s.before);
+        const after = this.n(s.after);
+        const values = slotTemplateValues({ before, after }, this.mappingKeysOf(s.key, preimages));
+        // Amounts are never named after a constant that happens to share their value
+        const named = !hasFormatHint(slotCfg);
+        const beforeAlias = named ? valueAlias(cfg.constants, before) : undefined;
+        const afterAlias = named ? valueAlias(cfg.constants, after) : undefined;
+        return [
+          {
+            key: s.key,
+            before,
+            after,
+            description: renderSlotTemplate(
+              slotCfg.summary,
+              values,
+              `Summary of ${name} slot ${s.key}`,
+              slotCfg
+            ),
+            allowDifference: slotCfg.allowDifference,
+            ...(slotCfg.decimals !== undefined && { decimals: slotCfg.decimals }),
+            ...(slotCfg.format && { format: slotCfg.format }),
+            ...(beforeAlias && { beforeAlias }),
+            ...(afterAlias && { afterAlias }),
+            ...(overridden.has(`${d.address}:${normalize32(s.key)}`) && {
+              beforeFromOverride: true,
+            }),
+            ...(s.writes.length > 1 && {
+              history: s.writes.map(w => ({ ...w, writer: getAddress(w.writer) })),
+            }),
+          },
+        ];
       });
-      if (changes.length > 0) result.push({ name, address: getAddress(d.address), changes });
+      if (changes.length > 0) {
+        const address = getAddress(d.address);
+        result.push({
+          name,
+          address,
+          explorerUrl: explorerAddressUrl(chainId, address),
+          ...(newAccounts.has(d.address) && { newAccount: true }),
+          changes,
+        });
+      }
     }
-    return result;
+    return { stateChanges: result, noise };
   }
 
+  // Changes smaller than cfg.minBalanceDelta are left out and counted as noise
   private extractBalanceChanges(
-    cfg: { contracts: Record<string, Record<string, ContractCfg>> },
+    cfg: Pick<ResolvedConfig, 'contracts' | 'minBalanceDelta'>,
     chainId: string,
-    decoded: readonly VmSafeAccountAccess[]
-  ): BalanceChange[] {
+    accounts: Map<string, AccountChange>,
+    newAccounts: ReadonlySet<string>
+  ): { balanceChanges: BalanceChange[]; noise: number } {
     const chainContracts = cfg.contracts[chainId] || {};
-    type BalanceAccumulator = {
-      delta: bigint;
-      lastNew: bigint;
-    };
-
-    const accMap = new Map<string, BalanceAccumulator>();
-
-    for (const access of decoded) {
-      const delta = access.newBalance - access.oldBalance;
-      if (delta === BigInt(0) || access.kind === 1) continue;
-      const addr = access.account.toLowerCase();
-      const existing = accMap.get(addr);
-      if (!existing) {
-        accMap.set(addr, { delta, lastNew: access.newBalance });
-      } else {
-        existing.delta += delta;
-        existing.lastNew = access.newBalance;
-      }
-    }
-
     const result: BalanceChange[] = [];
+    let noise = 0;
 
-    for (const [addr, value] of accMap) {
-      if (value.delta === BigInt(0)) continue;
-      const contract = chainContracts[addr];
-      const name = contract?.name ?? '<<ContractName>>';
-      const after = value.lastNew;
-      const before = after - value.delta;
-      if (before < BigInt(0)) {
-        throw new Error(`Negative balance calculated for ${addr}`);
+    for (const [addr, { balance }] of accounts) {
+      if (balance.before === balance.after) continue;
+      const delta = balance.after - balance.before;
+      if ((delta < BigInt(0) ? -delta : delta) < cfg.minBalanceDelta) {
+        noise++;
+        continue;
       }
-      const beforeHex = normalize32(bigintToHex(before));
-      const afterHex = normalize32(bigintToHex(after));
+      const contract = chainContracts[addr];
+      const name = contract?.name ?? UNKNOWN_CONTRACT_NAME;
+      const beforeHex = normalize32(bigintToHex(balance.before));
+      const afterHex = normalize32(bigintToHex(balance.after));
+      const address = getAddress(addr);
       result.push({
         name,
-        address: getAddress(addr),
+        address,
+        explorerUrl: explorerAddressUrl(chainId, address),
+        ...(newAccounts.has(addr) && { newAccount: true }),
         field: 'ETH Balance (wei)',
         before: beforeHex,
         after: afterHex,
@@ -589,25 +1567,187 @@ export class StateDiffClient {
     }
 
     result.sort((a, b) => a.address.localeCompare(b.address));
+    return { balanceChanges: result, noise };
+  }
+
+  private convertDeletionsToJSON(
+    cfg: { contracts: Record<string, Record<string, ContractCfg>> },
+    chainId: string,
+    deletions: DeletedAccount[]
+  ): AccountDeletion[] {
+    const chainContracts = cfg.contracts[chainId] || {};
+    return deletions.map(d => {
+      const address = getAddress(d.address);
+      return {
+        name: chainContracts[d.address]?.name ?? UNKNOWN_CONTRACT_NAME,
+        address,
+        explorerUrl: explorerAddressUrl(chainId, address),
+        codeRemoved: d.codeRemoved,
+        ...(d.removedCode ? { codeHash: keccak256(d.removedCode) } : {}),
+        ...(d.beneficiary ? { beneficiary: getAddress(d.beneficiary) } : {}),
+        sweptBalance: normalize32(bigintToHex(d.sweptBalance)),
+      };
+    });
+  }
+
+  private convertTransfersToJSON(
+    cfg: { contracts: Record<string, Record<string, ContractCfg>> },
+    chainId: string,
+    transfers: ValueTransfer[]
+  ): EthTransfer[] {
+    const chainContracts = cfg.contracts[chainId] || {};
+    return transfers.map(t => {
+      const fromName = t.from && chainContracts[t.from]?.name;
+      const toName = t.to && chainContracts[t.to]?.name;
+      return {
+        ...(t.from ? { from: getAddress(t.from) } : {}),
+        ...(fromName ? { fromName } : {}),
+        ...(t.to ? { to: getAddress(t.to) } : {}),
+        ...(toName ? { toName } : {}),
+        amount: normalize32(bigintToHex(t.amount)),
+      };
+    });
+  }
+
+  private convertCodeChangesToJSON(
+    cfg: { contracts: Record<string, Record<string, ContractCfg>> },
+    chainId: string,
+    changes: CodeDiff[]
+  ): CodeChange[] {
+    const chainContracts = cfg.contracts[chainId] || {};
+    return changes.map(c => {
+      const address = getAddress(c.address);
+      return {
+        name: chainContracts[c.address]?.name ?? UNKNOWN_CONTRACT_NAME,
+        address,
+        explorerUrl: explorerAddressUrl(chainId, address),
+        kind: c.kind,
+        ...(c.implementationBefore ? { implementationBefore: c.implementationBefore } : {}),
+        ...(c.implementationAfter ? { implementationAfter: c.implementationAfter } : {}),
+        ...(c.before ? { before: c.before } : {}),
+        ...(c.after ? { after: c.after } : {}),
+        selectorsAdded: c.selectorsAdded,
+        selectorsRemoved: c.selectorsRemoved,
+      };
+    });
+  }
+
+  private convertSafeConfigChangesToJSON(
+    cfg: { contracts: Record<string, Record<string, ContractCfg>> },
+    chainId: string,
+    changes: SafeConfigDiff[]
+  ): SafeConfigurationChange[] {
+    const chainContracts = cfg.contracts[chainId] || {};
+    return changes.map(c => {
+      const address = getAddress(c.address);
+      const afterName = c.after && chainContracts[c.after.toLowerCase()]?.name;
+      return {
+        name: chainContracts[c.address]?.name ?? UNKNOWN_CONTRACT_NAME,
+        address,
+        explorerUrl: explorerAddressUrl(chainId, address),
+        setting: c.setting,
+        ...(c.before ? { before: c.before } : {}),
+        ...(c.after ? { after: c.after } : {}),
+        ...(afterName ? { afterName } : {}),
+        ...(c.afterCode ? { afterCode: c.afterCode } : {}),
+      };
+    });
+  }
+
+  private extractIntermediateWrites(diffs: StorageDiff[]): IntermediateWrite[] {
+    const result: IntermediateWrite[] = [];
+    const sortedDiffs = [...diffs].sort((a, b) => a.address.localeCompare(b.address));
+    for (const d of sortedDiffs) {
+      const storageArray = Array.from(d.storageDiffs.values());
+      storageArray.sort((a, b) => a.key.localeCompare(b.key));
+      for (const s of storageArray) {
+        if (s.intermediate.length === 0) continue;
+        result.push({ address: getAddress(d.address), key: s.key, values: s.intermediate });
+      }
+    }
     return result;
   }
 
   private getSlot(contract: ContractCfg | undefined, slot: Hex, parentMap: Map<Hex, Hex>): SlotCfg {
-    const DEFAULT: SlotCfg = {
+    const found = this.findSlot(contract, slot, parentMap);
+    if (found) return found;
+    return {
       type: '<<DecodedKind>>',
-      summary: '<<Summary>>',
-      overrideMeaning: '<<OverrideMeaning>>',
+      summary: UNKNOWN_SLOT_SUMMARY,
+      overrideMeaning: UNKNOWN_OVERRIDE_MEANING,
       allowDifference: false,
       allowOverrideDifference: false,
     };
+  }
+
+  private findSlot(
+    contract: ContractCfg | undefined,
+    slot: Hex,
+    parentMap: Map<Hex, Hex>
+  ): SlotCfg | null {
+    // Walk up the preimage chain. A mapping pattern for an ancestor at the same depth takes
+    // precedence over the ancestor's own entry, which describes the mapping as a whole.
     let current = slot;
+    let depth = 0;
     while (true) {
+      const pattern = depth > 0 ? contract?.mappings?.[mappingKey(current, depth)] : undefined;
+      if (pattern) return pattern;
       const found = contract?.slots?.[current];
       if (found) return found;
       const parent = parentMap.get(current);
-      if (!parent) return DEFAULT;
+      if (!parent) return null;
       current = parent;
+      depth += 1;
+    }
+  }
+
+  /**
+   * Reads of slots marked critical in contracts.json, such as a Safe's guard, with the value
+   * each slot held at its first read. Slots that were also written still appear, since the
+   * read shows the value the transaction acted on.
+   */
+  private extractCriticalReads(
+    cfg: { contracts: Record<string, Record<string, ContractCfg>> },
+    chainId: string,
+    decodedDiff: readonly VmSafeAccountAccess[],
+    preimages: PreimageMaps
+  ): CriticalRead[] {
+    const chainContracts = cfg.contracts[chainId] || {};
+    const reads = new Map<string, CriticalRead>();
+    for (const access of decodedDiff) {
+      for (const s of access.storageAccesses) {
+        if (s.isWrite) continue;
+        const addr = s.account.toLowerCase();
+        const key = normalize32(s.slot);
+        const id = `${addr}:${key}`;
+        if (reads.has(id)) continue;
+        const contract = chainContracts[addr];
+        const slotCfg = this.findSlot(contract, key, preimages.parentMap);
+        if (!slotCfg?.critical) continue;
+        const name = contract?.name ?? UNKNOWN_CONTRACT_NAME;
+        const value = normalize32(s.previousValue);
+        // A read leaves the slot as it was, so the summary sees the value on both sides
+        const values = slotTemplateValues(
+          { before: value, after: value, value },
+          this.mappingKeysOf(key, preimages)
+        );
+        reads.set(id, {
+          name,
+          address: getAddress(addr),
+          key,
+          value,
+          description: renderSlotTemplate(
+            slotCfg.summary,
+            values,
+            `Summary of ${name} slot ${key}`,
+            slotCfg
+          ),
+        });
+      }
     }
+    return Array.from(reads.values()).sort(
+      (a, b) => a.address.localeCompare(b.address) || a.key.localeCompare(b.key)
+    );
   }
 
   private n(hex: string): Hex {
@@ -616,69 +1756,334 @@ export class StateDiffClient {
     return ('0x' + h.slice(2)) as Hex;
   }
 
-  private buildParentMap(decodedPreimages: readonly ParentPreimage[]): Map<Hex, Hex> {
+  // Mapping keys on the slot's preimage chain, outermost first
+  private mappingKeysOf(slot: Hex, { parentMap, preimageKeys }: PreimageMaps): Hex[] {
+    const keys: Hex[] = [];
+    let current = slot;
+    for (let parent = parentMap.get(current); parent; parent = parentMap.get(current)) {
+      const key = preimageKeys.get(current);
+      if (key) keys.unshift(key);
+      current = parent;
+    }
+    return keys;
+  }
+
+  private buildPreimageMaps(
+    decodedPreimages: readonly ParentPreimage[],
+    extraPreimages: readonly ParentPreimage[] = []
+  ): PreimageMaps {
     const parentMap = new Map<Hex, Hex>();
-    for (const p of decodedPreimages) {
-      parentMap.set(normalize32(p.slot), normalize32(p.parent));
+    const preimageKeys = new Map<Hex, Hex>();
+    for (const p of [...decodedPreimages, ...extraPreimages]) {
+      const slot = normalize32(p.slot);
+      if (parentMap.has(slot)) continue;
+      parentMap.set(slot, normalize32(p.parent));
+      preimageKeys.set(slot, normalize32(p.key));
+    }
+    return { parentMap, preimageKeys };
+  }
+
+  /**
+   * Recovers the SafeTx nonce from the simulated execTransaction call and compares it with the
+   * Safe's on-chain nonce. Calls that are not execTransaction on the target Safe are skipped.
+   */
+  private async checkSafeNonce(params: {
+    client: PublicClient;
+    targetSafe: string;
+    messageHash: Hex;
+    payload: PayloadDecoded;
+  }): Promise<{ safeNonce?: number; nonceWarning?: string }> {
+    const safe = getAddress(params.targetSafe);
+    if (!isAddressEqual(params.payload.to, safe)) return {};
+
+    let onChainNonce: bigint;
+    try {
+      onChainNonce = await withSpan('rpc.enrichment', { method: 'nonce' }, () =>
+        params.client.readContract({ address: safe, abi: SAFE_NONCE_ABI, functionName: 'nonce' })
+      );
+    } catch {
+      return {};
     }
-    return parentMap;
+    const txNonce = recoverSafeTxNonce({
+      safe,
+      to: params.payload.to,
+      data: params.payload.data,
+      messageHash: params.messageHash,
+      around: onChainNonce,
+    });
+    if (txNonce === null) return {};
+    return {
+      safeNonce: Number(txNonce),
+      nonceWarning: describeNonceMismatch(txNonce, onChainNonce) ?? undefined,
+    };
   }
 
   private buildTaskConfig(params: {
     cmd: string;
     rpcUrl: string;
-    parsed: ParsedInput;
+    targetSafe: string;
+    // Implementation in the target Safe's slot 0 and its version when it is a known deployment
+    singleton?: Address;
+    safeVersion?: string;
     domainHash: Hex;
     messageHash: Hex;
+    dataToSignForm: DataToSignForm;
     config: { contracts: Record<string, Record<string, ContractCfg>> };
     chainIdStr: string;
     payload: PayloadDecoded;
-    diffs: Array<{
-      address: string;
-      storageDiffs: Map<string, { key: Hex; before: Hex; after: Hex }>;
-    }>;
+    diffs: StorageDiff[];
     balanceChanges: BalanceChange[];
+    // Balance changes extractBalanceChanges left out as noise
+    balanceNoise: number;
+    // Lowercased accounts the transaction creates
+    newAccounts: ReadonlySet<string>;
+    ethTransfers: EthTransfer[];
     parentMap: Map<Hex, Hex>;
+    preimageKeys: Map<Hex, Hex>;
+    extraPreimages: readonly ParentPreimage[];
+    safeNonce?: number;
+    accountDeletions: AccountDeletion[];
+    codeChanges: CodeChange[];
+    safeConfigurationChanges: SafeConfigurationChange[];
+    execution?: ExecutionCheck;
+    simulatedAt: SimulatedAt;
+    criticalReads: CriticalRead[];
+    payloadSignatures: PayloadSignature[];
   }): TaskConfig {
     const {
       cmd,
       rpcUrl,
-      parsed,
+      targetSafe,
+      singleton,
+      safeVersion,
       domainHash,
       messageHash,
+      dataToSignForm,
       config,
       chainIdStr,
       payload,
       diffs,
       balanceChanges,
+      balanceNoise,
+      newAccounts,
+      ethTransfers,
       parentMap,
+      preimageKeys,
+      extraPreimages,
+      safeNonce,
+      accountDeletions,
+      codeChanges,
+      safeConfigurationChanges,
+      execution,
+      simulatedAt,
+      criticalReads,
+      payloadSignatures,
     } = params;
 
+    const intermediateWrites = this.verbose ? this.extractIntermediateWrites(diffs) : [];
+    const stateOverrides = this.convertOverridesToJSON(
+      config,
+      chainIdStr,
+      payload.stateOverrides,
+      { parentMap, preimageKeys }
+    );
+    const { stateChanges, noise } = this.convertDiffsToJSON(
+      config,
+      chainIdStr,
+      diffs,
+      { parentMap, preimageKeys },
+      newAccounts,
+      payload.stateOverrides
+    );
+    const fromOverrides = stateChanges.flatMap(sc => sc.changes.filter(c => c.beforeFromOverride));
+    if (fromOverrides.length > 0) {
+      console.log(
+        `🧪 ${fromOverrides.length} state change(s) start from an overridden value, not live chain state`
+      );
+    }
+    const usedExtraPreimages = usedPreimages(
+      [
+        ...diffs.flatMap(d => [...d.storageDiffs.keys()] as Hex[]),
+        ...payload.stateOverrides.flatMap(o => o.overrides.map(v => v.key)),
+        ...criticalReads.map(r => r.key as Hex),
+      ],
+      parentMap,
+      extraPreimages
+    );
+
     return {
       cmd,
       ledgerId: this.ledgerId,
       rpcUrl,
+      chainId: Number(chainIdStr),
+      chainName: getChainInfo(chainIdStr).name,
       expectedDomainAndMessageHashes: {
-        address: getAddress(parsed.targetSafe),
+        address: getAddress(targetSafe),
         domainHash,
         messageHash,
+        safeTxHash: computeSafeTxHash(domainHash, messageHash),
+        ...(singleton && { singleton }),
+        ...(safeVersion && { safeVersion }),
       },
-      stateOverrides: this.convertOverridesToJSON(
-        config,
-        chainIdStr,
-        payload.stateOverrides,
-        parentMap
-      ),
-      stateChanges: this.convertDiffsToJSON(config, chainIdStr, diffs, parentMap),
+      dataToSignForm,
+      ...(safeNonce !== undefined && { safeNonce }),
+      simulatedAt,
+      summary: {
+        ...summarizeReport(
+          { stateOverrides, stateChanges, balanceChanges },
+          new Map(
+            Object.entries(config.contracts[chainIdStr] ?? {}).map(([addr, c]) => [
+              addr,
+              categoryOf(c.name, c.tags),
+            ])
+          )
+        ),
+        ...(noise + balanceNoise > 0 && {
+          noiseFiltered: { stateChanges: noise, balanceChanges: balanceNoise },
+        }),
+      },
+      stateOverrides,
+      stateChanges,
       balanceChanges,
+      ...(ethTransfers.length > 0 && { ethTransfers }),
+      ...(accountDeletions.length > 0 && { accountDeletions }),
+      ...(codeChanges.length > 0 && { codeChanges }),
+      ...(safeConfigurationChanges.length > 0 && { safeConfigurationChanges }),
+      ...(usedExtraPreimages.length > 0 && { extraPreimages: usedExtraPreimages }),
+      ...(payloadSignatures.length > 0 && { payloadSignatures }),
+      ...(execution && { execution }),
+      ...(criticalReads.length > 0 && { criticalReads }),
+      ...(intermediateWrites.length > 0 && { intermediateWrites }),
     };
   }
 }
 
-function equalHex(a: string, b: string): boolean {
-  const na = normalize32(a);
-  const nb = normalize32(b);
-  return na === nb;
+// The block `blockNumber`, or the head, as a report's simulatedAt
+async function simulatedBlock(client: PublicClient, blockNumber?: bigint): Promise<SimulatedAt> {
+  const block = await withSpan('rpc.enrichment', { method: 'eth_getBlockByNumber' }, () =>
+    blockNumber === undefined
+      ? client.getBlock({ blockTag: 'latest' })
+      : client.getBlock({ blockNumber })
+  );
+  return {
+    blockNumber: Number(block.number),
+    ...(block.hash && { blockHash: block.hash }),
+    blockTimestamp: Number(block.timestamp),
+    generatedAt: new Date().toISOString(),
+  };
+}
+
+// The block a forge command forks from: the one --fork-block-number pins, otherwise the head
+function forkBlock(client: PublicClient, forgeCmdParts: readonly string[]): Promise<SimulatedAt> {
+  const pin = forkBlockPin(forgeCmdParts);
+  return simulatedBlock(client, pin === null ? undefined : BigInt(pin));
+}
+
+// Adds the stored preimages of the run's contracts; those the run has itself take precedence
+function withStoredPreimages<
+  P extends {
+    decodedDiff: readonly VmSafeAccountAccess[];
+    payload: PayloadDecoded;
+    parentMap: Map<Hex, Hex>;
+    preimageKeys: Map<Hex, Hex>;
+    extraPreimages: readonly ParentPreimage[];
+  },
+>(params: P, store: PreimageStore): P {
+  const accounts = new Set([
+    ...params.decodedDiff.flatMap(a => [
+      a.account.toLowerCase(),
+      ...a.storageAccesses.map(s => s.account.toLowerCase()),
+    ]),
+    ...params.payload.stateOverrides.map(o => o.contractAddress.toLowerCase()),
+  ]);
+  const stored = storedPreimages(store, accounts).filter(p => !params.parentMap.has(p.slot));
+  if (stored.length === 0) return params;
+  console.log(`🧠 Using ${stored.length} mapping preimage(s) learned in earlier runs`);
+  const parentMap = new Map(params.parentMap);
+  const preimageKeys = new Map(params.preimageKeys);
+  for (const p of stored) {
+    parentMap.set(p.slot, p.parent);
+    preimageKeys.set(p.slot, p.key);
+  }
+  const extraPreimages = [...params.extraPreimages, ...stored];
+  return { ...params, parentMap, preimageKeys, extraPreimages };
+}
+
+// A pattern matched by selectors alone may be another contract with the same functions, so its
+// slot descriptions say they are a guess
+function selectorHintSlots(pattern: KnownPattern<SlotCfg>): Record<string, SlotCfg> {
+  return Object.fromEntries(
+    Object.entries(pattern.slots).map(([slot, cfg]) => [
+      slot,
+      { ...cfg, summary: `Unverified, ${pattern.name} by function selectors only: ${cfg.summary}` },
+    ])
+  );
+}
+
+// Resolves a contracts.json entry's slots reference, mapping patterns, and ERC-7201 namespaces
+function resolveContractCfg(
+  def: RawContractCfg,
+  layouts: Record<string, Record<string, SlotCfg>>,
+  where: string
+): ContractCfg {
+  let slots: Record<string, SlotCfg> = {};
+  if (typeof def.slots === 'string') {
+    // Expect pattern: "{{storageLayouts.NAME}}"
+    const m = def.slots.match(/^\{\{storageLayouts\.(.+)\}\}$/);
+    if (!m) throw new Error(`Invalid slots reference for ${where}: ${def.slots}`);
+    const layout = layouts[m[1]];
+    if (!layout) throw new Error(`Missing storageLayouts.${m[1]} for ${where}`);
+    slots = layout;
+  } else if (def.slots && typeof def.slots === 'object') {
+    // Inline slots, normalize keys
+    for (const [k, v] of Object.entries(def.slots)) slots[k.toLowerCase()] = v;
+  }
+
+  const split = splitMappingPatterns(slots, where);
+  const normalizedSlots: Record<string, SlotCfg> = {};
+  for (const [k, v] of Object.entries(split.slots)) normalizedSlots[k.toLowerCase()] = v;
+  for (const [namespace, members] of Object.entries(def.namespaces || {})) {
+    for (const [offset, v] of Object.entries(members)) {
+      if (!/^\d+$/.test(offset)) {
+        throw new Error(`Invalid member offset "${offset}" in namespace ${namespace} for ${where}`);
+      }
+      normalizedSlots[erc7201Slot(namespace, BigInt(offset))] = v;
+    }
+  }
+  for (const [k, v] of [...Object.entries(normalizedSlots), ...Object.entries(split.mappings)]) {
+    validateFormatHint(v, `Slot ${k} of ${where}`);
+  }
+  return {
+    name: def.name,
+    slots: normalizedSlots,
+    ...(Object.keys(split.mappings).length > 0 && { mappings: split.mappings }),
+    ...(def.tags && def.tags.length > 0 && { tags: def.tags }),
+  };
+}
+
+function withOverlay(base: RawConfig, overlay: ConfigOverlay | null): RawConfig {
+  if (!overlay) return base;
+  const contracts = { ...base.contracts };
+  for (const [chainId, entries] of Object.entries(overlay.contracts ?? {})) {
+    // Keys are normalized first so a differently cased address still replaces the entry
+    contracts[chainId] = {
+      ...normalizeAddressKeys(base.contracts[chainId] ?? {}, `contracts.${chainId}`),
+      ...normalizeAddressKeys(
+        entries as Record<string, RawContractCfg>,
+        `overlay contracts.${chainId}`
+      ),
+    };
+  }
+  return {
+    ...base,
+    contracts,
+    storageLayouts: {
+      ...base.storageLayouts,
+      ...(overlay.storageLayouts as Record<string, Record<string, SlotCfg>>),
+    },
+    artifacts: [...(base.artifacts ?? []), ...((overlay.artifacts ?? []) as RawArtifact[])],
+    constants: { ...base.constants, ...overlay.constants },
+  };
 }
 
 function normalize32(h: string): Hex {
@@ -691,3 +2096,18 @@ function bigintToHex(value: bigint): Hex {
   if (value < BigInt(0)) throw new Error('Negative bigint cannot be converted to hex');
   return ('0x' + value.toString(16)) as Hex;
 }
+
+function resultOutput(result: TaskConfig): string {
+  return `<<<RESULT>>>\n${JSON.stringify(result, null, 2)}`;
+}
+
+// Puts warnings a source found before the report was built ahead of the report's own
+function withWarnings(
+  built: { result: TaskConfig; output: string; warnings: ReportWarning[] },
+  found: ReportWarning[]
+): { result: TaskConfig; output: string; warnings: ReportWarning[] } {
+  const warnings = [...found, ...built.warnings];
+  if (warnings.length === 0) return built;
+  const result = { ...built.result, warnings };
+  return { result, output: resultOutput(result), warnings };
+}
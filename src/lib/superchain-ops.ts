@@ -0,0 +1,262 @@
+import { canonicalJson } from './canonical-json';
+import type { Change, TaskConfig } from './types/index';
+import {
+  DEFAULT_REPORT_LOCALE,
+  localeAmount,
+  localeDateTime,
+  localeDecimal,
+  localeDuration,
+} from './locale-format';
+import { hasFormatHint, SlotFormatHint } from './number-format';
+import { DEFAULT_STALENESS_LIMITS } from './staleness';
+
+export type SignerValidation = {
+  // Heading for this signer's hashes, e.g. the validation file name ("base-sc")
+  label: string;
+  config: TaskConfig;
+};
+
+export type RenderOptions = {
+  // BCP 47 locale for numbers, dates, and durations in prose; see locale-format.ts
+  locale?: string;
+};
+
+const HEADER = `# Validation
+
+This document can be used to validate the inputs and result of the execution of the upgrade transaction which you are signing.
+
+The steps are:
+
+1. [Validate the Domain and Message Hashes](#expected-domain-and-message-hashes)
+2. [Verifying the state changes](#state-changes)`;
+
+// The version decides how the Safe computes the domain hash below it
+function renderSingleton(singleton: string, safeVersion?: string): string {
+  return safeVersion
+    ? `> - Safe Version: ${safeVersion} (singleton \`${singleton}\`)`
+    : `> - Singleton: \`${singleton}\` (not a known Safe deployment)`;
+}
+
+function renderHashes(signers: SignerValidation[]): string {
+  const blocks = signers.map(({ label, config }) => {
+    const { address, domainHash, messageHash, singleton, safeVersion } =
+      config.expectedDomainAndMessageHashes;
+    return [
+      `> ### ${label} (\`${address}\`)`,
+      '>',
+      ...(singleton ? [renderSingleton(singleton, safeVersion)] : []),
+      `> - Domain Hash: \`${domainHash}\``,
+      `> - Message Hash: \`${messageHash}\``,
+    ].join('\n');
+  });
+
+  return [
+    '## Expected Domain and Message Hashes',
+    '',
+    'First, we need to validate the domain and message hashes. These values should match both the values on your ledger and the values printed to the terminal when you run the task.',
+    '',
+    '> [!CAUTION]',
+    '>',
+    '> Before signing, ensure the below hashes match what is on your ledger.',
+    '>',
+    blocks.join('\n>\n'),
+  ].join('\n');
+}
+
+// When the simulation ran and when validation starts calling the files stale
+function renderSimulation(config: TaskConfig, locale: string): string | null {
+  const simulatedAt = config.simulatedAt;
+  if (!simulatedAt) return null;
+  const generatedAt = new Date(simulatedAt.generatedAt);
+  const maxAgeMs = DEFAULT_STALENESS_LIMITS.maxAgeHours * 3600 * 1000;
+  const block = localeDecimal(String(simulatedAt.blockNumber), locale);
+  const blockTime = localeDateTime(new Date(simulatedAt.blockTimestamp * 1000), locale);
+  const generated = localeDateTime(generatedAt, locale);
+  const staleFrom = localeDateTime(new Date(generatedAt.getTime() + maxAgeMs), locale);
+  return [
+    '## Simulation',
+    '',
+    `The transaction was simulated against block ${block}, produced on ${blockTime}, and the validation file was generated on ${generated}.`,
+    '',
+    `By default, validation warns that the file is stale ${localeDuration(maxAgeMs, locale)} after it was generated, from ${staleFrom}. Regenerate the validation files if signing happens later.`,
+  ].join('\n');
+}
+
+// Contract entries are headed one level below their section, or two when each file has its own
+type Heading = '###' | '####';
+
+function renderOverrides(config: TaskConfig, heading: Heading): string | null {
+  if (config.stateOverrides.length === 0) return null;
+
+  const sections = config.stateOverrides.map(o => {
+    const entries = o.overrides.map(ov =>
+      [
+        `- **Key**: \`${ov.key}\``,
+        `  - **Override**: \`${ov.value}\``,
+        `  - **Meaning**: ${ov.description}`,
+      ].join('\n')
+    );
+    return [`${heading} \`${o.address}\` (${o.name})`, '', ...entries].join('\n');
+  });
+  return sections.join('\n\n');
+}
+
+const OVERRIDDEN = 'from a state override, not live chain state';
+
+// Balance changes are in wei
+const ETHER: SlotFormatHint = { format: 'ether' };
+
+// A value's constant name and notes, in parentheses after it
+function annotate(...notes: (string | false | undefined)[]): string {
+  return notes
+    .filter(Boolean)
+    .map(note => ` (${note})`)
+    .join('');
+}
+
+// Every write to a slot written more than once, so intermediate values are reviewed too
+function renderHistory(history: Change['history']): string[] {
+  if (!history) return [];
+  return [
+    `  - **Writes**:`,
+    ...history.map(
+      (w, i) =>
+        `    ${i + 1}. \`${w.value}\` by \`${w.writer}\` at depth ${w.depth}${annotate(w.reverted && 'reverted')}`
+    ),
+  ];
+}
+
+// A slot word as the amount its hints describe, for slots that have them
+function amountOf(word: string, hint: SlotFormatHint, locale: string): string | undefined {
+  return hasFormatHint(hint) ? localeAmount(BigInt(word), locale, hint) : undefined;
+}
+
+function renderChanges(config: TaskConfig, locale: string, heading: Heading): string | null {
+  if (config.stateChanges.length === 0) return null;
+
+  const sections = config.stateChanges.map(sc => {
+    const entries = sc.changes.map(c =>
+      [
+        `- **Key**: \`${c.key}\``,
+        `  - **Before**: \`${c.before}\`${annotate(amountOf(c.before, c, locale), c.beforeAlias, c.beforeFromOverride && OVERRIDDEN)}`,
+        `  - **After**: \`${c.after}\`${annotate(amountOf(c.after, c, locale), c.afterAlias)}`,
+        ...renderHistory(c.history),
+        `  - **Summary**: ${c.description}`,
+      ].join('\n')
+    );
+    return [`${heading} \`${sc.address}\` (${sc.name})`, '', ...entries].join('\n');
+  });
+  return sections.join('\n\n');
+}
+
+function renderBalanceChanges(config: TaskConfig, locale: string, heading: Heading): string | null {
+  const balanceChanges = config.balanceChanges ?? [];
+  if (balanceChanges.length === 0) return null;
+
+  const entries = balanceChanges.map(b =>
+    [
+      `${heading} \`${b.address}\` (${b.name})`,
+      '',
+      `- **${b.field}**`,
+      `  - **Before**: \`${BigInt(b.before).toString()}\`${annotate(localeAmount(BigInt(b.before), locale, ETHER))}`,
+      `  - **After**: \`${BigInt(b.after).toString()}\`${annotate(localeAmount(BigInt(b.after), locale, ETHER))}`,
+      `  - **Summary**: ${b.description}`,
+    ].join('\n')
+  );
+  return entries.join('\n\n');
+}
+
+// Whether every file simulates the same overrides, state changes, and balance changes
+function sameSimulation(signers: SignerValidation[]): boolean {
+  const [first, ...rest] = signers.map(({ config }) =>
+    canonicalJson([config.stateOverrides, config.stateChanges, config.balanceChanges ?? []])
+  );
+  return rest.every(other => other === first);
+}
+
+/**
+ * One section of entries. When the files agree it lists the entries once; otherwise each file
+ * gets a subsection under its label, with "None." for a file that has no entries, so no file's
+ * entries stand in for another's. Null when no file has entries.
+ */
+function renderSection(
+  title: string,
+  intro: string | null,
+  signers: SignerValidation[],
+  shared: boolean,
+  render: (config: TaskConfig, heading: Heading) => string | null
+): string | null {
+  let body: string;
+  if (shared) {
+    const entries = render(signers[0].config, '###');
+    if (entries === null) return null;
+    body = entries;
+  } else {
+    const blocks = signers.map(({ label, config }) => ({ label, entries: render(config, '####') }));
+    if (blocks.every(({ entries }) => entries === null)) return null;
+    body = blocks
+      .map(({ label, entries }) => [`### ${label}`, '', entries ?? 'None.'].join('\n'))
+      .join('\n\n');
+  }
+  return [`## ${title}`, '', ...(intro ? [intro, ''] : []), body].join('\n');
+}
+
+function renderSignerInstructions(signers: SignerValidation[]): string | null {
+  const blocks = signers.flatMap(({ label, config }) => {
+    const instructions = config.signerInstructions;
+    if (!instructions) return [];
+    const sections = [
+      ['```bash', ...instructions.commands, '```'].join('\n'),
+      ...instructions.devices.map(d =>
+        [`#### ${d.device}`, '', ...d.steps.map((step, i) => `${i + 1}. ${step}`)].join('\n')
+      ),
+      ...(instructions.notes?.length ? [instructions.notes.map(n => `- ${n}`).join('\n')] : []),
+    ];
+    return [[`### ${label}`, ...sections].join('\n\n')];
+  });
+  if (blocks.length === 0) return null;
+
+  return ['## Signing Instructions', '', blocks.join('\n\n')].join('\n');
+}
+
+/**
+ * Renders validation files in the superchain-ops VALIDATION.md layout. Each signer gets its own
+ * hash block. Overrides, state changes, and balance changes are listed once when every file has
+ * the same ones, and per file when they differ, as they can for nested Safes. Amounts and dates
+ * in the prose follow `options.locale`.
+ */
+export function renderSuperchainOpsValidation(
+  signers: SignerValidation[],
+  options: RenderOptions = {}
+): string {
+  if (signers.length === 0) {
+    throw new Error('renderSuperchainOpsValidation: at least one validation file is required');
+  }
+  const [primary] = signers;
+  const locale = options.locale ?? DEFAULT_REPORT_LOCALE;
+  const shared = sameSimulation(signers);
+
+  return (
+    [
+      HEADER,
+      renderHashes(signers),
+      renderSignerInstructions(signers),
+      renderSimulation(primary.config, locale),
+      renderSection(
+        'State Overrides',
+        'The following state overrides are applied during simulation only; they are not part of the transaction.',
+        signers,
+        shared,
+        renderOverrides
+      ),
+      renderSection('State Changes', null, signers, shared, (config, heading) =>
+        renderChanges(config, locale, heading)
+      ),
+      renderSection('Balance Changes', null, signers, shared, (config, heading) =>
+        renderBalanceChanges(config, locale, heading)
+      ),
+    ]
+      .filter((section): section is string => section !== null)
+      .join('\n\n') + '\n'
+  );
+}
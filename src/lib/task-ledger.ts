@@ -0,0 +1,171 @@
+import { promises as fs } from 'fs';
+import path from 'path';
+import { Hex, keccak256 } from 'viem';
+import { canonicalHash } from './canonical-json';
+import { hashArtifact } from './ceremony-log';
+import { withKeyedLock } from './keyed-lock';
+import { owningTaskDir } from './recent-modifications';
+import type { TaskConfig } from './types/index';
+
+export const LEDGER_ENTRY_KINDS = ['validation', 'signature'] as const;
+export type LedgerEntryKind = (typeof LEDGER_ENTRY_KINDS)[number];
+
+export type LedgerDetails = Record<string, string | number>;
+
+/**
+ * One line of a task ledger: a generated validation file or a verified task origin signature.
+ * Lines are hash-chained like the ceremony log, so an edited or dropped line is detectable.
+ */
+export type LedgerEntry = {
+  seq: number;
+  timestamp: string;
+  kind: LedgerEntryKind;
+  // Task directory name, e.g. 2025-06-04-upgrade-system-config
+  task: string;
+  details: LedgerDetails;
+  prevHash: Hex;
+  entryHash: Hex;
+};
+
+export type LedgerTaskSummary = {
+  task: string;
+  validations: number;
+  signatures: number;
+  chains: number[];
+  lastActivity: string;
+};
+
+const ZERO_HASH = ('0x' + '0'.repeat(64)) as Hex;
+
+/**
+ * Name a file is filed under: its task directory when it sits under `tasks/`, otherwise the
+ * directory holding it.
+ */
+export function ledgerTaskName(file: string): string {
+  return path.basename(owningTaskDir(file) ?? path.dirname(path.resolve(file)));
+}
+
+function hashEntry(entry: Omit<LedgerEntry, 'entryHash'>): Hex {
+  return canonicalHash(entry);
+}
+
+export async function readLedger(file: string): Promise<LedgerEntry[]> {
+  let content: string;
+  try {
+    content = await fs.readFile(file, 'utf-8');
+  } catch (error) {
+    if (error instanceof Error && 'code' in error && error.code === 'ENOENT') return [];
+    throw error;
+  }
+  return content
+    .split('\n')
+    .filter(line => line.trim().length > 0)
+    .map(line => JSON.parse(line) as LedgerEntry);
+}
+
+export async function appendLedgerEntry(
+  file: string,
+  input: { kind: LedgerEntryKind; task: string; details: LedgerDetails }
+): Promise<LedgerEntry> {
+  return withKeyedLock(file, async () => {
+    const entries = await readLedger(file);
+    const unsigned: Omit<LedgerEntry, 'entryHash'> = {
+      seq: entries.length,
+      timestamp: new Date().toISOString(),
+      kind: input.kind,
+      task: input.task,
+      details: input.details,
+      prevHash: entries[entries.length - 1]?.entryHash ?? ZERO_HASH,
+    };
+    const entry: LedgerEntry = { ...unsigned, entryHash: hashEntry(unsigned) };
+
+    await fs.mkdir(path.dirname(file), { recursive: true });
+    await fs.appendFile(file, JSON.stringify(entry) + '\n', { flag: 'a' });
+    return entry;
+  });
+}
+
+/**
+ * Records a generated validation file. `artifact` is what was written, so the hash matches the
+ * file even when it was redacted or attested.
+ */
+export function recordValidation(
+  file: string,
+  input: { task: string; outFile: string; config: TaskConfig; artifact: unknown }
+): Promise<LedgerEntry> {
+  const { config } = input;
+  return appendLedgerEntry(file, {
+    kind: 'validation',
+    task: input.task,
+    details: {
+      file: input.outFile,
+      ...(config.chainId !== undefined ? { chainId: config.chainId } : {}),
+      safe: config.expectedDomainAndMessageHashes.address,
+      domainHash: config.expectedDomainAndMessageHashes.domainHash,
+      messageHash: config.expectedDomainAndMessageHashes.messageHash,
+      ...(config.expectedDomainAndMessageHashes.safeTxHash
+        ? { safeTxHash: config.expectedDomainAndMessageHashes.safeTxHash }
+        : {}),
+      artifactHash: hashArtifact(input.artifact),
+    },
+  });
+}
+
+/**
+ * Records a task origin signature that passed verification, with the hash of its file.
+ */
+export async function recordSignature(
+  file: string,
+  input: { task: string; role: string; signer: string; signatureFile: string }
+): Promise<LedgerEntry> {
+  const contents = await fs.readFile(input.signatureFile);
+  return appendLedgerEntry(file, {
+    kind: 'signature',
+    task: input.task,
+    details: {
+      role: input.role,
+      signer: input.signer,
+      file: input.signatureFile,
+      fileHash: keccak256(contents),
+    },
+  });
+}
+
+/**
+ * Checks the hash chain of a ledger. Returns a list of problems; empty means it is intact.
+ */
+export function verifyLedger(entries: LedgerEntry[]): string[] {
+  const problems: string[] = [];
+  let prevHash = ZERO_HASH;
+  for (const [index, entry] of entries.entries()) {
+    const line = `line ${index + 1}`;
+    const { entryHash, ...unsigned } = entry;
+    if (entry.seq !== index) problems.push(`${line}: expected seq ${index}`);
+    if (entry.prevHash !== prevHash) problems.push(`${line}: broken hash chain`);
+    if (hashEntry(unsigned) !== entryHash) problems.push(`${line}: entry hash mismatch`);
+    prevHash = entryHash;
+  }
+  return problems;
+}
+
+/**
+ * Per-task counts, most recently active task first.
+ */
+export function summarizeLedger(entries: LedgerEntry[]): LedgerTaskSummary[] {
+  const tasks = new Map<string, LedgerTaskSummary>();
+  for (const entry of entries) {
+    let summary = tasks.get(entry.task);
+    if (!summary) {
+      summary = { task: entry.task, validations: 0, signatures: 0, chains: [], lastActivity: '' };
+      tasks.set(entry.task, summary);
+    }
+    if (entry.kind === 'validation') summary.validations++;
+    if (entry.kind === 'signature') summary.signatures++;
+    const chainId = entry.details.chainId;
+    if (typeof chainId === 'number' && !summary.chains.includes(chainId)) {
+      summary.chains.push(chainId);
+    }
+    summary.lastActivity = entry.timestamp;
+  }
+  return Array.from(tasks.values()).sort((a, b) => b.lastActivity.localeCompare(a.lastActivity));
+}
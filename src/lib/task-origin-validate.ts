@@ -1,6 +1,7 @@
 import * as tar from 'tar';
 import path from 'path';
 import fs from 'fs/promises';
+import os from 'os';
 import { X509Certificate } from 'crypto';
 import { Verifier, toTrustMaterial, toSignedEntity } from '@sigstore/verify';
 import { bundleFromJSON } from '@sigstore/bundle';
@@ -50,7 +51,8 @@ async function getAllFilesRecursively(
 
 export async function createDeterministicTarball(
   taskFolderPath: string,
-  allowedDir?: string
+  allowedDir?: string,
+  outputDir: string = process.cwd()
 ): Promise<string> {
   // If an allowed directory is specified, resolve symlinks and validate the real path
   let resolvedTaskFolderPath = taskFolderPath;
@@ -60,7 +62,7 @@ export async function createDeterministicTarball(
   }
 
   const folderName = path.basename(resolvedTaskFolderPath);
-  const tarballPath = path.resolve(process.cwd(), `${folderName}.tar`);
+  const tarballPath = path.resolve(outputDir, `${folderName}.tar`);
 
   // Check if lib/ folder exists for reproducibility
   const libPath = path.join(resolvedTaskFolderPath, 'lib');
@@ -119,9 +121,16 @@ export async function buildAndValidateSignature(options: TaskOriginVerifyOptions
   const bundleSigJSON = JSON.parse(await fs.readFile(signatureFile, 'utf8'));
   const bundleSig = bundleFromJSON(bundleSigJSON);
 
-  // Regenerate the tarball from the provided task folder
-  const tarballPath = await createDeterministicTarball(taskFolderPath, allowedDir);
-  const tarball = await fs.readFile(tarballPath); // Read as binary Buffer
+  // Regenerate the tarball from the provided task folder. Each call gets its own scratch dir
+  // so concurrent server requests for the same task never read each other's partial tarball.
+  const scratchDir = await fs.mkdtemp(path.join(os.tmpdir(), 'task-origin-'));
+  let tarball: Buffer;
+  try {
+    const tarballPath = await createDeterministicTarball(taskFolderPath, allowedDir, scratchDir);
+    tarball = await fs.readFile(tarballPath); // Read as binary Buffer
+  } finally {
+    await fs.rm(scratchDir, { recursive: true, force: true });
+  }
 
   // Extract the deployment-specific intermediate CA from bundle
   // Bundle structure: [0]=leaf, [1]=runtime intermediate, [2]=static intermediate, [3]=root
@@ -0,0 +1,253 @@
+import { AsyncLocalStorage } from 'async_hooks';
+import { randomBytes } from 'crypto';
+import { withPhase } from './progress';
+
+/**
+ * Minimal OpenTelemetry exporter for the signing pipeline.
+ *
+ * Spans and counters are buffered in memory and shipped as OTLP/JSON over HTTP when
+ * OTEL_EXPORTER_OTLP_ENDPOINT is set. When it is not set every call is a cheap no-op, so
+ * local signers never emit anything. A run started with withTelemetryRun, such as one
+ * validation on the server, buffers into its own buffer, so concurrent runs never export or
+ * drop each other's spans and counters; anything else, such as a CLI invocation, shares the
+ * process buffer.
+ */
+
+type AttributeValue = string | number | boolean;
+export type TelemetryAttributes = Record<string, AttributeValue>;
+
+type SpanRecord = {
+  traceId: string;
+  spanId: string;
+  parentSpanId?: string;
+  name: string;
+  startTimeUnixNano: string;
+  endTimeUnixNano?: string;
+  attributes: TelemetryAttributes;
+  status: { code: number; message?: string };
+};
+
+type CounterRecord = {
+  name: string;
+  value: number;
+  attributes: TelemetryAttributes;
+};
+
+const STATUS_OK = 1;
+const STATUS_ERROR = 2;
+const AGGREGATION_TEMPORALITY_DELTA = 1;
+
+type TelemetryBuffer = {
+  spans: SpanRecord[];
+  counters: Map<string, CounterRecord>;
+};
+
+const spanContext = new AsyncLocalStorage<SpanRecord>();
+const runBuffer = new AsyncLocalStorage<TelemetryBuffer>();
+const processBuffer: TelemetryBuffer = { spans: [], counters: new Map() };
+
+function currentBuffer(): TelemetryBuffer {
+  return runBuffer.getStore() ?? processBuffer;
+}
+
+function endpoint(): string | undefined {
+  const value = process.env.OTEL_EXPORTER_OTLP_ENDPOINT?.trim();
+  return value ? value.replace(/\/+$/, '') : undefined;
+}
+
+export function isTelemetryEnabled(): boolean {
+  return endpoint() !== undefined;
+}
+
+function serviceName(): string {
+  return process.env.OTEL_SERVICE_NAME?.trim() || 'task-signing-tool';
+}
+
+function nowUnixNano(): string {
+  return (BigInt(Date.now()) * BigInt(1000000)).toString();
+}
+
+function toOtlpAttributes(attributes: TelemetryAttributes) {
+  return Object.entries(attributes).map(([key, value]) => {
+    if (typeof value === 'boolean') return { key, value: { boolValue: value } };
+    if (typeof value === 'number') {
+      return Number.isInteger(value)
+        ? { key, value: { intValue: value.toString() } }
+        : { key, value: { doubleValue: value } };
+    }
+    return { key, value: { stringValue: value } };
+  });
+}
+
+/**
+ * Runs fn inside a span. Nested calls are parented to the enclosing span. The span is also a
+ * phase on the CLI progress line.
+ */
+export async function withSpan<T>(
+  name: string,
+  attributes: TelemetryAttributes,
+  fn: () => Promise<T>
+): Promise<T> {
+  const detail = attributes.method ?? attributes.source;
+  return withPhase(detail === undefined ? name : `${name} ${detail}`, () =>
+    recordSpan(name, attributes, fn)
+  );
+}
+
+async function recordSpan<T>(
+  name: string,
+  attributes: TelemetryAttributes,
+  fn: () => Promise<T>
+): Promise<T> {
+  if (!isTelemetryEnabled()) return fn();
+
+  const parent = spanContext.getStore();
+  const span: SpanRecord = {
+    traceId: parent?.traceId ?? randomBytes(16).toString('hex'),
+    spanId: randomBytes(8).toString('hex'),
+    parentSpanId: parent?.spanId,
+    name,
+    startTimeUnixNano: nowUnixNano(),
+    attributes: { ...attributes },
+    status: { code: STATUS_OK },
+  };
+
+  try {
+    return await spanContext.run(span, fn);
+  } catch (error) {
+    span.status = {
+      code: STATUS_ERROR,
+      message: error instanceof Error ? error.message : String(error),
+    };
+    throw error;
+  } finally {
+    span.endTimeUnixNano = nowUnixNano();
+    currentBuffer().spans.push(span);
+  }
+}
+
+/**
+ * Adds value to a monotonic counter. Counters with the same name and attributes are summed
+ * until the next flush.
+ */
+export function incrementCounter(
+  name: string,
+  value: number = 1,
+  attributes: TelemetryAttributes = {}
+): void {
+  if (!isTelemetryEnabled() || value === 0) return;
+
+  const counters = currentBuffer().counters;
+  const key = `${name}|${JSON.stringify(attributes)}`;
+  const existing = counters.get(key);
+  if (existing) {
+    existing.value += value;
+  } else {
+    counters.set(key, { name, value, attributes: { ...attributes } });
+  }
+}
+
+async function post(url: string, body: unknown): Promise<void> {
+  const response = await fetch(url, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(body),
+  });
+  if (!response.ok) {
+    throw new Error(`OTLP export to ${url} failed with status ${response.status}`);
+  }
+}
+
+/**
+ * Runs fn with a telemetry buffer of its own and exports it once fn settles, whether it
+ * returned or threw.
+ */
+export async function withTelemetryRun<T>(fn: () => Promise<T>): Promise<T> {
+  const buffer: TelemetryBuffer = { spans: [], counters: new Map() };
+  try {
+    return await runBuffer.run(buffer, fn);
+  } finally {
+    await exportBuffer(buffer);
+  }
+}
+
+/**
+ * Exports the spans and counters buffered by the current run, or by the process outside one.
+ * Export failures are logged and never thrown so that telemetry can not break a signing
+ * ceremony.
+ */
+export async function flushTelemetry(): Promise<void> {
+  await exportBuffer(currentBuffer());
+}
+
+async function exportBuffer(buffer: TelemetryBuffer): Promise<void> {
+  const base = endpoint();
+  if (!base) return;
+
+  const spans = buffer.spans.splice(0, buffer.spans.length);
+  const metrics = Array.from(buffer.counters.values());
+  buffer.counters.clear();
+
+  const resource = { attributes: toOtlpAttributes({ 'service.name': serviceName() }) };
+  const scope = { name: 'task-signing-tool' };
+  const timeUnixNano = nowUnixNano();
+
+  try {
+    if (spans.length > 0) {
+      await post(`${base}/v1/traces`, {
+        resourceSpans: [
+          {
+            resource,
+            scopeSpans: [
+              {
+                scope,
+                spans: spans.map(span => ({
+                  traceId: span.traceId,
+                  spanId: span.spanId,
+                  parentSpanId: span.parentSpanId,
+                  name: span.name,
+                  kind: 1,
+                  startTimeUnixNano: span.startTimeUnixNano,
+                  endTimeUnixNano: span.endTimeUnixNano,
+                  attributes: toOtlpAttributes(span.attributes),
+                  status: span.status,
+                })),
+              },
+            ],
+          },
+        ],
+      });
+    }
+
+    if (metrics.length > 0) {
+      await post(`${base}/v1/metrics`, {
+        resourceMetrics: [
+          {
+            resource,
+            scopeMetrics: [
+              {
+                scope,
+                metrics: metrics.map(metric => ({
+                  name: metric.name,
+                  sum: {
+                    aggregationTemporality: AGGREGATION_TEMPORALITY_DELTA,
+                    isMonotonic: true,
+                    dataPoints: [
+                      {
+                        asInt: metric.value.toString(),
+                        timeUnixNano,
+                        attributes: toOtlpAttributes(metric.attributes),
+                      },
+                    ],
+                  },
+                })),
+              },
+            ],
+          },
+        ],
+      });
+    }
+  } catch (error) {
+    console.warn('⚠️ Telemetry export failed:', error);
+  }
+}
@@ -0,0 +1,143 @@
+import { readFileSync } from 'fs';
+import path from 'path';
+import { parse as parseYaml } from 'yaml';
+import { z } from 'zod';
+import { describeZodIssues } from './config-schemas';
+import { PolicyViolationError } from './errors';
+import type { StalenessLimits } from './staleness';
+import type { ConfigOverlay } from './state-diff';
+import { UNKNOWN_MODES } from './unknown-entries';
+
+/**
+ * Teams sharing one validation server. The registry lists, per tenant, the chains it validates
+ * on, named contracts.json overlays, and named policy sets. A request picks these by name, so no
+ * configuration is taken from the request itself, and each combination gets its own
+ * StateDiffClient with its own resolved contracts config and metadata cache.
+ */
+
+// Path of the registry (YAML or JSON); without it the server serves a single team
+export const TENANTS_ENV = 'VALIDATION_TENANTS_FILE';
+
+const PolicySetSchema = z
+  .object({
+    // How contracts and slots missing from contracts.json are reported
+    unknowns: z.enum(UNKNOWN_MODES).optional(),
+    strictHashFormat: z.boolean().optional(),
+    // Replace the server's VALIDATION_MAX_AGE_HOURS and VALIDATION_MAX_BLOCKS_BEHIND
+    maxAgeHours: z.number().positive().optional(),
+    maxBlocksBehind: z.number().positive().optional(),
+  })
+  .strict();
+
+const TenantSchema = z
+  .object({
+    chainIds: z.array(z.number().int().positive()).min(1),
+    // Overlay name to a file shaped like contracts.json, relative to the registry
+    overlays: z.record(z.string().min(1)).default({}),
+    policies: z.record(PolicySetSchema).default({}),
+    // Directory of the tenant's metadata cache, relative to the registry; none when unset
+    metadataCache: z.string().min(1).optional(),
+  })
+  .strict();
+
+const TenantRegistrySchema = z.object({ tenants: z.record(TenantSchema) }).strict();
+
+const ConfigOverlaySchema = z
+  .object({
+    contracts: z.record(z.record(z.unknown())).optional(),
+    storageLayouts: z.record(z.record(z.unknown())).optional(),
+    artifacts: z.array(z.record(z.unknown())).optional(),
+    constants: z.record(z.string()).optional(),
+  })
+  .strict();
+
+export type PolicySet = z.infer<typeof PolicySetSchema>;
+export type Tenant = z.infer<typeof TenantSchema>;
+export type TenantRegistry = { dir: string; tenants: Record<string, Tenant> };
+
+export type TenantRequest = {
+  tenant: string;
+  chainId?: number;
+  overlay?: string;
+  policy?: string;
+};
+
+export type TenantContext = {
+  // Identifies the client and caches the request is served with
+  key: string;
+  tenant: string;
+  // Chains the request may validate on: the requested one, or all of the tenant's
+  chainIds: number[];
+  overlayFile: string | null;
+  policy: PolicySet;
+  metadataCacheDir: string | null;
+};
+
+export function parseTenantRegistry(text: string, dir: string): TenantRegistry {
+  const parsed = TenantRegistrySchema.safeParse(parseYaml(text));
+  if (!parsed.success) {
+    throw new Error(`Invalid tenant registry: ${describeZodIssues(parsed.error)}`);
+  }
+  return { dir, tenants: parsed.data.tenants };
+}
+
+export function loadTenantRegistry(file: string): TenantRegistry {
+  return parseTenantRegistry(readFileSync(file, 'utf-8'), path.dirname(path.resolve(file)));
+}
+
+export function tenantRegistryFromEnv(
+  env: NodeJS.ProcessEnv = process.env
+): TenantRegistry | null {
+  const file = env[TENANTS_ENV];
+  return file ? loadTenantRegistry(file) : null;
+}
+
+/**
+ * Checks a request's tenant, chain, overlay, and policy set against the registry. Anything the
+ * registry does not list for the tenant is refused.
+ */
+export function resolveTenant(registry: TenantRegistry, request: TenantRequest): TenantContext {
+  const tenant = Object.hasOwn(registry.tenants, request.tenant)
+    ? registry.tenants[request.tenant]
+    : undefined;
+  if (!tenant) throw new PolicyViolationError(`Unknown tenant ${request.tenant}`);
+  if (request.chainId !== undefined && !tenant.chainIds.includes(request.chainId)) {
+    throw new PolicyViolationError(
+      `Tenant ${request.tenant} does not validate on chain ${request.chainId}`
+    );
+  }
+  if (request.overlay !== undefined && !Object.hasOwn(tenant.overlays, request.overlay)) {
+    throw new PolicyViolationError(
+      `Tenant ${request.tenant} has no config overlay ${request.overlay}`
+    );
+  }
+  if (request.policy !== undefined && !Object.hasOwn(tenant.policies, request.policy)) {
+    throw new PolicyViolationError(`Tenant ${request.tenant} has no policy set ${request.policy}`);
+  }
+
+  const resolve = (file: string) => path.resolve(registry.dir, file);
+  return {
+    key: JSON.stringify([request.tenant, request.overlay ?? null, request.policy ?? null]),
+    tenant: request.tenant,
+    chainIds: request.chainId !== undefined ? [request.chainId] : tenant.chainIds,
+    overlayFile: request.overlay !== undefined ? resolve(tenant.overlays[request.overlay]) : null,
+    policy: request.policy !== undefined ? tenant.policies[request.policy] : {},
+    metadataCacheDir: tenant.metadataCache ? resolve(tenant.metadataCache) : null,
+  };
+}
+
+export function loadConfigOverlay(file: string): ConfigOverlay {
+  const parsed = ConfigOverlaySchema.safeParse(parseYaml(readFileSync(file, 'utf-8')));
+  if (!parsed.success) {
+    throw new Error(`Invalid config overlay ${file}: ${describeZodIssues(parsed.error)}`);
+  }
+  return parsed.data;
+}
+
+// The policy set's staleness limits take the place of the server's
+export function tenantStalenessLimits(policy: PolicySet, base: StalenessLimits): StalenessLimits {
+  return {
+    maxAgeHours: policy.maxAgeHours ?? base.maxAgeHours,
+    maxBlocksBehind: policy.maxBlocksBehind ?? base.maxBlocksBehind,
+  };
+}
@@ -0,0 +1,145 @@
+import { z } from 'zod';
+import { getAddress, Hex, zeroAddress } from 'viem';
+import { AddressSchema, describeZodIssues, HexValueSchema } from './config-schemas';
+import { DecodeError } from './errors';
+import { PayloadDecoded } from './state-diff-encoding';
+import { AccountAccessKind, VmSafeAccountAccess, VmSafeStorageAccess } from './vm-safe';
+
+// Tenderly reports balances as decimal strings
+const DecimalSchema = z.string().regex(/^\d+$/, 'Invalid decimal value');
+
+const RawStorageChangeSchema = z.object({
+  address: AddressSchema,
+  key: HexValueSchema,
+  original: HexValueSchema,
+  dirty: HexValueSchema,
+});
+
+const StateDiffEntrySchema = z.object({
+  // Decoded entries (soltype, original, dirty) are ignored; only the raw slot writes are used
+  raw: z.array(RawStorageChangeSchema).nullable().optional(),
+});
+
+const BalanceDiffSchema = z.object({
+  address: AddressSchema,
+  original: DecimalSchema,
+  dirty: DecimalSchema,
+});
+
+const StateObjectSchema = z.object({
+  storage: z.record(HexValueSchema).optional(),
+});
+
+const TenderlyExportSchema = z.object({
+  simulation: z.object({
+    network_id: z.string().optional(),
+    from: AddressSchema,
+    to: AddressSchema,
+    input: HexValueSchema,
+    state_objects: z.record(StateObjectSchema).nullable().optional(),
+  }),
+  transaction: z.object({
+    transaction_info: z.object({
+      state_diff: z.array(StateDiffEntrySchema).nullable().optional(),
+      balance_diff: z.array(BalanceDiffSchema).nullable().optional(),
+    }),
+  }),
+  // Older dashboard exports put the overrides next to the simulation instead of inside it
+  state_overrides: z.record(StateObjectSchema).nullable().optional(),
+});
+
+export type TenderlyExport = z.infer<typeof TenderlyExportSchema>;
+
+/**
+ * Parses a simulation exported from the Tenderly dashboard or returned by its simulate API.
+ */
+export function parseTenderlyExport(raw: unknown): TenderlyExport {
+  const parsed = TenderlyExportSchema.safeParse(raw);
+  if (!parsed.success) {
+    const issues = describeZodIssues(parsed.error);
+    throw new DecodeError(`Invalid Tenderly simulation export: ${issues}`);
+  }
+  return parsed.data;
+}
+
+function toWord(value: string): Hex {
+  return ('0x' + value.toLowerCase().replace(/^0x/, '').padStart(64, '0')) as Hex;
+}
+
+/**
+ * The simulated call and its storage overrides, in the shape forge writes to stateDiff.json.
+ */
+export function tenderlyToPayload(exp: TenderlyExport): PayloadDecoded {
+  const stateObjects = exp.simulation.state_objects ?? exp.state_overrides ?? {};
+  return {
+    from: getAddress(exp.simulation.from),
+    to: getAddress(exp.simulation.to),
+    data: exp.simulation.input as Hex,
+    stateOverrides: Object.entries(stateObjects)
+      .filter(([, object]) => Object.keys(object.storage ?? {}).length > 0)
+      .map(([contractAddress, object]) => ({
+        contractAddress: getAddress(contractAddress),
+        overrides: Object.entries(object.storage ?? {}).map(([key, value]) => ({
+          key: toWord(key),
+          value: toWord(value),
+        })),
+      })),
+  };
+}
+
+/**
+ * Converts Tenderly's raw storage and balance diffs into synthetic VmSafe account accesses so
+ * the regular state-diff pipeline can consume them. Tenderly reports each slot's value before
+ * and after the whole transaction, so intermediate writes are not visible.
+ */
+export function tenderlyToAccountAccesses(exp: TenderlyExport): VmSafeAccountAccess[] {
+  const info = exp.transaction.transaction_info;
+  const storage = new Map<string, VmSafeStorageAccess[]>();
+  for (const entry of info.state_diff ?? []) {
+    for (const change of entry.raw ?? []) {
+      const account = change.address.toLowerCase();
+      const writes = storage.get(account) ?? [];
+      writes.push({
+        account,
+        slot: toWord(change.key),
+        isWrite: true,
+        previousValue: toWord(change.original),
+        newValue: toWord(change.dirty),
+        reverted: false,
+      });
+      storage.set(account, writes);
+    }
+  }
+
+  const balances = new Map<string, { original: bigint; dirty: bigint }>();
+  for (const diff of info.balance_diff ?? []) {
+    balances.set(diff.address.toLowerCase(), {
+      original: BigInt(diff.original),
+      dirty: BigInt(diff.dirty),
+    });
+  }
+
+  const accounts = new Set([...storage.keys(), ...balances.keys()]);
+  return Array.from(accounts)
+    .sort()
+    .map((account): VmSafeAccountAccess => {
+      const balance = balances.get(account);
+      return {
+        chainInfo: { forkId: BigInt(0), chainId: BigInt(0) },
+        kind: AccountAccessKind.Call,
+        account,
+        accessor: zeroAddress,
+        initialized: true,
+        oldBalance: balance?.original ?? BigInt(0),
+        newBalance: balance?.dirty ?? BigInt(0),
+        deployedCode: '0x',
+        value: BigInt(0),
+        data: '0x',
+        reverted: false,
+        storageAccesses: (storage.get(account) ?? []).sort((a, b) => a.slot.localeCompare(b.slot)),
+        depth: BigInt(0),
+        oldNonce: BigInt(0),
+        newNonce: BigInt(0),
+      };
+    });
+}
@@ -10,11 +10,15 @@ export interface TaskOriginComparison {
 export interface SigningDataComparison {
   contractName: string;
   contractAddress?: string;
+  contractExplorerUrl?: string;
   expected: {
     dataToSign: string;
     address: string;
     domainHash: string;
     messageHash: string;
+    safeTxHash: string;
+    singleton?: string;
+    safeVersion?: string;
     description?: string;
   };
   actual?: {
@@ -22,6 +26,7 @@ export interface SigningDataComparison {
     address: string;
     domainHash: string;
     messageHash: string;
+    safeTxHash: string;
     description?: string;
   };
 }
@@ -29,6 +34,7 @@ export interface SigningDataComparison {
 export interface OverrideComparison {
   contractName: string;
   contractAddress?: string;
+  contractExplorerUrl?: string;
   expected: Override;
   actual?: Override;
 }
@@ -36,6 +42,9 @@ export interface OverrideComparison {
 export interface StateChangeComparison {
   contractName: string;
   contractAddress?: string;
+  contractExplorerUrl?: string;
+  // The contract did not exist before the transaction
+  contractNewAccount?: boolean;
   expected: Change;
   actual?: Change;
 }
@@ -43,6 +52,8 @@ export interface StateChangeComparison {
 export interface BalanceChangeComparison {
   contractName: string;
   contractAddress?: string;
+  contractExplorerUrl?: string;
+  contractNewAccount?: boolean;
   expected: BalanceChange;
   actual?: BalanceChange;
 }
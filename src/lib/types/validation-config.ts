@@ -1,9 +1,28 @@
 import { z } from 'zod';
 import {
+  AccountDeletionSchema,
   BalanceChangeSchema,
   ChangeSchema,
+  CodeChangeSchema,
+  CriticalReadSchema,
+  ExecutionCheckSchema,
+  EthTransferSchema,
   ExpectedHashesSchema,
+  GeneratedBySchema,
+  IntermediateWriteSchema,
+  NestedHashesSchema,
   OverrideSchema,
+  PayloadSignatureSchema,
+  PrestateDependencySchema,
+  RecentlyModifiedSchema,
+  ReportScopeSchema,
+  ReportWarningSchema,
+  ReportSummarySchema,
+  SafeConfigurationChangeSchema,
+  SignerInstructionsSchema,
+  SimulatedAtSchema,
+  SimulationEnvSchema,
+  SlotWriteSchema,
   StateChangeSchema,
   StateOverrideSchema,
   TaskConfigSchema,
@@ -16,7 +35,26 @@ export type StateOverride = z.infer<typeof StateOverrideSchema>;
 export type Change = z.infer<typeof ChangeSchema>;
 export type StateChange = z.infer<typeof StateChangeSchema>;
 export type BalanceChange = z.infer<typeof BalanceChangeSchema>;
+export type AccountDeletion = z.infer<typeof AccountDeletionSchema>;
+export type EthTransfer = z.infer<typeof EthTransferSchema>;
+export type CodeChange = z.infer<typeof CodeChangeSchema>;
+export type SafeConfigurationChange = z.infer<typeof SafeConfigurationChangeSchema>;
+export type PayloadSignature = z.infer<typeof PayloadSignatureSchema>;
+export type CriticalRead = z.infer<typeof CriticalReadSchema>;
+export type ExecutionCheck = z.infer<typeof ExecutionCheckSchema>;
+export type IntermediateWrite = z.infer<typeof IntermediateWriteSchema>;
+export type SlotWrite = z.infer<typeof SlotWriteSchema>;
 export type TaskConfig = z.infer<typeof TaskConfigSchema>;
+export type PrestateDependency = z.infer<typeof PrestateDependencySchema>;
+export type RecentlyModified = z.infer<typeof RecentlyModifiedSchema>;
+export type ReportScope = z.infer<typeof ReportScopeSchema>;
+export type SimulatedAt = z.infer<typeof SimulatedAtSchema>;
+export type ReportSummary = z.infer<typeof ReportSummarySchema>;
+export type GeneratedBy = z.infer<typeof GeneratedBySchema>;
+export type SignerInstructions = z.infer<typeof SignerInstructionsSchema>;
+export type SimulationEnv = z.infer<typeof SimulationEnvSchema>;
+export type NestedHashes = z.infer<typeof NestedHashesSchema>;
+export type ReportWarning = z.infer<typeof ReportWarningSchema>;
 
 // Task Origin Validation Types
 export type TaskOriginValidationConfig = z.infer<typeof TaskOriginValidationConfigSchema>;
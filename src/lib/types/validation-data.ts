@@ -1,6 +1,8 @@
+import type { SafeQuorum } from '../safe-quorum';
 import type {
   BalanceChange,
   ExpectedHashes,
+  ReportWarning,
   StateChange,
   StateOverride,
 } from './validation-config';
@@ -34,4 +36,11 @@ export interface ValidationData {
     domainAndMessageHashes?: ExpectedHashes;
   };
   taskOriginValidation?: TaskOriginValidation;
+  warnings?: ReportWarning[];
+  // Network the simulation ran against, so readers know which chain is affected
+  chainId?: number;
+  chainName?: string;
+  rpcUsed?: string;
+  // On-chain approval status of the Safe transaction being signed
+  quorum?: SafeQuorum;
 }
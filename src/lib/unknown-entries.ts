@@ -0,0 +1,110 @@
+import { PolicyViolationError } from './errors';
+import type { TaskConfig } from './types/index';
+
+// Placeholders for contracts and slots contracts.json does not describe, meant to be filled in
+// by the task author before the file is published
+export const UNKNOWN_CONTRACT_NAME = '<<ContractName>>';
+export const UNKNOWN_SLOT_SUMMARY = '<<Summary>>';
+export const UNKNOWN_OVERRIDE_MEANING = '<<OverrideMeaning>>';
+// Description written instead of the slot placeholders with --unknowns label
+export const UNKNOWN_LABEL = 'unknown';
+
+// placeholder: write the placeholders; label: write "unknown (0x...)"; error: refuse the report
+export const UNKNOWN_MODES = ['placeholder', 'label', 'error'] as const;
+export type UnknownMode = (typeof UNKNOWN_MODES)[number];
+
+const UNKNOWN_NAME_LABEL = /^unknown \(0x[0-9a-fA-F]{40}\)$/;
+
+export function isUnknownName(name: string): boolean {
+  return name === UNKNOWN_CONTRACT_NAME || UNKNOWN_NAME_LABEL.test(name);
+}
+
+export function isUnknownDescription(description: string): boolean {
+  return (
+    description === UNKNOWN_SLOT_SUMMARY ||
+    description === UNKNOWN_OVERRIDE_MEANING ||
+    description === UNKNOWN_LABEL
+  );
+}
+
+type Named = { name: string; address: string };
+
+function namedEntries(config: TaskConfig): Named[] {
+  return [
+    ...config.stateOverrides,
+    ...config.stateChanges,
+    ...(config.balanceChanges ?? []),
+    ...(config.accountDeletions ?? []),
+    ...(config.codeChanges ?? []),
+    ...(config.safeConfigurationChanges ?? []),
+    ...(config.criticalReads ?? []),
+  ];
+}
+
+/**
+ * Contracts without a name and slots without a description, one line each.
+ */
+export function findUnknownEntries(config: TaskConfig): string[] {
+  const contracts = new Set(
+    namedEntries(config)
+      .filter(e => isUnknownName(e.name))
+      .map(e => e.address)
+  );
+  const slots = new Set<string>();
+  for (const o of config.stateOverrides) {
+    for (const override of o.overrides) {
+      if (isUnknownDescription(override.description)) slots.add(`${o.address} ${override.key}`);
+    }
+  }
+  for (const sc of config.stateChanges) {
+    for (const change of sc.changes) {
+      if (isUnknownDescription(change.description)) slots.add(`${sc.address} ${change.key}`);
+    }
+  }
+  return [
+    ...Array.from(contracts, address => `contract ${address}`),
+    ...Array.from(slots, slot => `slot ${slot}`),
+  ];
+}
+
+/**
+ * Applies an UnknownMode to a report built with placeholders. With `error` the report is
+ * refused when anything is unknown, so placeholders cannot leak into a published file.
+ */
+export function applyUnknownMode(config: TaskConfig, mode: UnknownMode): TaskConfig {
+  if (mode === 'placeholder') return config;
+  if (mode === 'error') {
+    const unknowns = findUnknownEntries(config);
+    if (unknowns.length > 0) {
+      throw new PolicyViolationError(
+        `contracts.json does not describe ${unknowns.length} entr${unknowns.length === 1 ? 'y' : 'ies'}: ${unknowns.join(', ')}. Add them to contracts.json, or pass --unknowns placeholder or label`
+      );
+    }
+    return config;
+  }
+
+  const name = <T extends Named>(e: T): T =>
+    e.name === UNKNOWN_CONTRACT_NAME ? { ...e, name: `unknown (${e.address})` } : e;
+  const description = (d: string) => (isUnknownDescription(d) ? UNKNOWN_LABEL : d);
+  return {
+    ...config,
+    stateOverrides: config.stateOverrides.map(o => ({
+      ...name(o),
+      overrides: o.overrides.map(v => ({ ...v, description: description(v.description) })),
+    })),
+    stateChanges: config.stateChanges.map(sc => ({
+      ...name(sc),
+      changes: sc.changes.map(c => ({ ...c, description: description(c.description) })),
+    })),
+    ...(config.balanceChanges && { balanceChanges: config.balanceChanges.map(name) }),
+    ...(config.accountDeletions && { accountDeletions: config.accountDeletions.map(name) }),
+    ...(config.codeChanges && { codeChanges: config.codeChanges.map(name) }),
+    ...(config.safeConfigurationChanges && {
+      safeConfigurationChanges: config.safeConfigurationChanges.map(name),
+    }),
+    ...(config.criticalReads && { criticalReads: config.criticalReads.map(name) }),
+    ...(config.summary && {
+      summary: { ...config.summary, contracts: config.summary.contracts.map(name) },
+    }),
+  };
+}
@@ -1,8 +1,17 @@
 import { formatEther } from 'viem';
+import {
+  formatAmount,
+  formatSlotWord,
+  groupThousands,
+  hasFormatHint,
+  SlotFormatHint,
+} from './number-format';
 
 import {
   BalanceChangeComparison,
+  Change,
   OverrideComparison,
+  ReportWarning,
   SigningDataComparison,
   StateChangeComparison,
   StringDiff,
@@ -11,8 +20,25 @@ import {
   ValidationData,
   ValidationItemsByStep,
 } from '@/lib/types';
+import { computeSafeTxHash } from '@/lib/safe-hash';
+import { isBlockingWarning } from '@/lib/report-warnings';
+import { explorerAddressUrl } from '@/lib/explorers';
 
 const NOT_FOUND_TEXT = 'Not found';
+const OVERRIDDEN_BEFORE_TEXT =
+  'The before value comes from a state override applied for the simulation, not from live chain state, so it cannot be checked against the chain.';
+
+// The writes to a slot written more than once, so temporary values are not missed
+function historyText(history: Change['history']): string | undefined {
+  if (!history) return undefined;
+  const writes = history.map(
+    (w, i) =>
+      `${i + 1}. ${w.value} by ${w.writer} at depth ${w.depth}${w.reverted ? ' (reverted)' : ''}`
+  );
+  return [`This slot was written ${history.length} times during the transaction:`, ...writes].join(
+    '\n'
+  );
+}
 
 export const TASK_ORIGIN_ROLE_LABELS: Record<TaskOriginRole, string> = {
   taskCreator: 'Task Creator',
@@ -51,6 +77,9 @@ export interface ValidationDescription {
 export interface ComparisonCardContent {
   contractName: string;
   contractAddress: string;
+  contractExplorerUrl?: string;
+  // Marks an account the transaction creates, whose before values are all zero
+  newAccount?: boolean;
   storageKey: string;
   storageKeyDiffs?: StringDiff[];
   beforeValue?: string;
@@ -114,8 +143,8 @@ export const STEP_LABELS: Record<ValidationNavEntry['kind'], string> = STEP_DEFI
 export const formatBalanceValue = (hex: string): string => {
   try {
     const value = BigInt(hex);
-    const wei = value.toString();
-    const eth = formatEther(value);
+    const wei = formatAmount(value);
+    const eth = groupThousands(formatEther(value));
     const normalizedHex = hex.startsWith('0x') ? hex : `0x${hex}`;
     return `${eth} ETH (${wei} wei)\nHex: ${normalizedHex}`;
   } catch {
@@ -133,14 +162,21 @@ export const formatBalanceDelta = (beforeHex: string, afterHex: string): string
     }
     const abs = delta >= BigInt(0) ? delta : -delta;
     const sign = delta >= BigInt(0) ? '+' : '-';
-    const wei = abs.toString();
-    const eth = formatEther(abs);
+    const wei = formatAmount(abs);
+    const eth = groupThousands(formatEther(abs));
     return `${sign}${eth} ETH (${sign}${wei} wei)`;
   } catch {
     return `${afterHex} - ${beforeHex}`;
   }
 };
 
+// A slot word with a display hint or a constant's name, readable value first; other words are
+// shown as they are
+export const formatSlotValue = (hex: string, hint: SlotFormatHint, alias?: string): string => {
+  if (hasFormatHint(hint)) return `${formatSlotWord(hex, hint)}\nHex: ${hex}`;
+  return alias ? `${alias}\nHex: ${hex}` : hex;
+};
+
 export const getFieldDiffs = (expected: string, actual: string): StringDiff[] => {
   if (expected === actual) {
     return [{ type: 'unchanged', value: expected }];
@@ -216,6 +252,24 @@ const buildTaskOriginComparisons = (
   return [{ results, allPassed, isDisabled: false }];
 };
 
+// Links come from the explorers configured for the chain the validation ran on, never from the
+// validation file, whose author controls every field in it
+const explorerLink = (validationResult: ValidationData, address: string) =>
+  validationResult.chainId !== undefined
+    ? explorerAddressUrl(String(validationResult.chainId), address)
+    : undefined;
+
+// The target Safe's implementation, which decides how its hashes are computed
+const singletonText = ({
+  singleton,
+  safeVersion,
+}: SigningDataComparison['expected']): string => {
+  if (!singleton) return '';
+  return safeVersion
+    ? ` The Safe runs version ${safeVersion} (singleton ${singleton}).`
+    : ` The Safe runs ${singleton}, which is not a known Safe deployment.`;
+};
+
 const buildSigningComparisons = (
   validationResult: ValidationData | null
 ): SigningDataComparison[] => {
@@ -239,17 +293,22 @@ const buildSigningComparisons = (
     {
       contractName: 'EIP-712 Signing Data',
       contractAddress: expectedHashes.address,
+      contractExplorerUrl: explorerLink(validationResult, expectedHashes.address),
       expected: {
         dataToSign: expectedDataToSign,
         address: expectedHashes.address,
         domainHash: expectedHashes.domainHash,
         messageHash: expectedHashes.messageHash,
+        safeTxHash: computeSafeTxHash(expectedHashes.domainHash, expectedHashes.messageHash),
+        ...(expectedHashes.singleton && { singleton: expectedHashes.singleton }),
+        ...(expectedHashes.safeVersion && { safeVersion: expectedHashes.safeVersion }),
       },
       actual: {
         dataToSign: actualDataToSign,
         address: actualHashes.address,
         domainHash: actualHashes.domainHash,
         messageHash: actualHashes.messageHash,
+        safeTxHash: computeSafeTxHash(actualHashes.domainHash, actualHashes.messageHash),
       },
     },
   ];
@@ -267,6 +326,7 @@ const buildOverrideComparisons = (
     stateOverride.overrides.map((override, oIndex) => ({
       contractName: stateOverride.name,
       contractAddress: stateOverride.address,
+      contractExplorerUrl: explorerLink(validationResult, stateOverride.address),
       expected: override,
       actual: actualOverrides[soIndex]?.overrides?.[oIndex],
     }))
@@ -285,6 +345,8 @@ const buildChangeComparisons = (
     stateChange.changes.map((change, cIndex) => ({
       contractName: stateChange.name,
       contractAddress: stateChange.address,
+      contractExplorerUrl: explorerLink(validationResult, stateChange.address),
+      ...(stateChange.newAccount && { contractNewAccount: true }),
       expected: change,
       actual: actualChanges[scIndex]?.changes?.[cIndex],
     }))
@@ -302,6 +364,8 @@ const buildBalanceComparisons = (
   return expectedBalances.map((balanceChange, bcIndex) => ({
     contractName: balanceChange.name,
     contractAddress: balanceChange.address,
+    contractExplorerUrl: explorerLink(validationResult, balanceChange.address),
+    ...(balanceChange.newAccount && { contractNewAccount: true }),
     expected: balanceChange,
     actual: actualBalances[bcIndex],
   }));
@@ -350,7 +414,14 @@ const matchesBalance = (comparison: BalanceChangeComparison) =>
   comparison.expected.before === comparison.actual.before &&
   comparison.expected.after === comparison.actual.after;
 
-export const hasBlockingErrors = (items: ValidationItemsByStep): boolean => {
+export const hasBlockingErrors = (
+  items: ValidationItemsByStep,
+  warnings: readonly ReportWarning[] = []
+): boolean => {
+  // The re-run found something the file does not match, outside the compared entries, or a
+  // transaction that does not execute
+  if (warnings.some(isBlockingWarning)) return true;
+
   // Task origin validation failures are blocking (but disabled validation is not a blocking error)
   const taskOriginFailed = items.taskOrigin.some(item => !item.isDisabled && !item.allPassed);
   if (taskOriginFailed) return true;
@@ -498,7 +569,12 @@ export const evaluateValidationEntry = (
               title: 'What this does',
               text: item.expected.description,
             } satisfies ValidationDescription)
-          : undefined;
+          : ({
+              variant: 'info',
+              icon: 'lightbulb',
+              title: 'Safe transaction hash',
+              text: `${item.expected.safeTxHash} - this should match the transaction hash shown in the Safe web interface.${singletonText(item.expected)}`,
+            } satisfies ValidationDescription);
 
       return {
         matchStatus,
@@ -509,6 +585,7 @@ export const evaluateValidationEntry = (
           expected: {
             contractName: item.contractName,
             contractAddress: defaultContractAddress(item.contractAddress),
+            contractExplorerUrl: item.contractExplorerUrl,
             storageKey: 'EIP-712 Data to Sign',
             afterValue: expectedData,
             shouldWrap: true,
@@ -516,6 +593,7 @@ export const evaluateValidationEntry = (
           actual: {
             contractName: item.contractName,
             contractAddress: defaultContractAddress(item.contractAddress),
+            contractExplorerUrl: item.contractExplorerUrl,
             storageKey: 'EIP-712 Data to Sign',
             afterValue: actualData,
             afterValueDiffs: getFieldDiffs(expectedData, actualData),
@@ -567,12 +645,14 @@ export const evaluateValidationEntry = (
           expected: {
             contractName: item.contractName,
             contractAddress: defaultContractAddress(item.contractAddress),
+            contractExplorerUrl: item.contractExplorerUrl,
             storageKey: item.expected.key,
             afterValue: item.expected.value,
           },
           actual: {
             contractName: item.contractName,
             contractAddress: defaultContractAddress(item.contractAddress),
+            contractExplorerUrl: item.contractExplorerUrl,
             storageKey: actualKey,
             storageKeyDiffs: getFieldDiffs(item.expected.key, actualKey),
             afterValue: actualValue,
@@ -585,8 +665,24 @@ export const evaluateValidationEntry = (
     case 'change': {
       const item = items.changes[entry.index]!;
       const actualKey = item.actual?.key ?? NOT_FOUND_TEXT;
-      const actualBefore = item.actual?.before ?? NOT_FOUND_TEXT;
-      const actualAfter = item.actual?.after ?? NOT_FOUND_TEXT;
+      // Both sides are read with the expected file's hints, so they can be compared; each side
+      // names its own values, since a differing actual value is not the expected constant
+      const expectedBefore = formatSlotValue(
+        item.expected.before,
+        item.expected,
+        item.expected.beforeAlias
+      );
+      const expectedAfter = formatSlotValue(
+        item.expected.after,
+        item.expected,
+        item.expected.afterAlias
+      );
+      const actualBefore = item.actual
+        ? formatSlotValue(item.actual.before, item.expected, item.actual.beforeAlias)
+        : NOT_FOUND_TEXT;
+      const actualAfter = item.actual
+        ? formatSlotValue(item.actual.after, item.expected, item.actual.afterAlias)
+        : NOT_FOUND_TEXT;
       const match = matchesChange(item);
       const expectedDifference = item.expected.allowDifference;
 
@@ -607,13 +703,18 @@ export const evaluateValidationEntry = (
         matchStatus = createMatchStatus('missing', 'Missing - Not found in actual results');
       }
 
+      const descriptionParts = [
+        item.expected.description?.trim(),
+        item.expected.beforeFromOverride ? OVERRIDDEN_BEFORE_TEXT : undefined,
+        historyText(item.expected.history),
+      ].filter((part): part is string => Boolean(part));
       const description =
-        item.expected.description && item.expected.description.trim().length > 0
+        descriptionParts.length > 0
           ? ({
               variant: expectedDifference ? 'expected-difference' : 'info',
               icon: expectedDifference ? 'check' : 'lightbulb',
               title: expectedDifference ? 'Expected Difference - This is Fine' : 'What this does',
-              text: item.expected.description,
+              text: descriptionParts.join('\n\n'),
             } satisfies ValidationDescription)
           : undefined;
 
@@ -626,19 +727,23 @@ export const evaluateValidationEntry = (
           expected: {
             contractName: item.contractName,
             contractAddress: defaultContractAddress(item.contractAddress),
+            contractExplorerUrl: item.contractExplorerUrl,
+            newAccount: item.contractNewAccount,
             storageKey: item.expected.key,
-            beforeValue: item.expected.before,
-            afterValue: item.expected.after,
+            beforeValue: expectedBefore,
+            afterValue: expectedAfter,
           },
           actual: {
             contractName: item.contractName,
             contractAddress: defaultContractAddress(item.contractAddress),
+            contractExplorerUrl: item.contractExplorerUrl,
+            newAccount: item.contractNewAccount,
             storageKey: actualKey,
             storageKeyDiffs: getFieldDiffs(item.expected.key, actualKey),
             beforeValue: actualBefore,
-            beforeValueDiffs: getFieldDiffs(item.expected.before, actualBefore),
+            beforeValueDiffs: getFieldDiffs(expectedBefore, actualBefore),
             afterValue: actualAfter,
-            afterValueDiffs: getFieldDiffs(item.expected.after, actualAfter),
+            afterValueDiffs: getFieldDiffs(expectedAfter, actualAfter),
             shouldWrap: !match,
           },
         },
@@ -706,6 +811,8 @@ export const evaluateValidationEntry = (
           expected: {
             contractName: item.contractName,
             contractAddress: defaultContractAddress(item.contractAddress),
+            contractExplorerUrl: item.contractExplorerUrl,
+            newAccount: item.contractNewAccount,
             storageKey: item.expected.field,
             beforeValue: expectedBefore,
             afterValue: expectedAfter,
@@ -713,6 +820,8 @@ export const evaluateValidationEntry = (
           actual: {
             contractName: item.contractName,
             contractAddress: defaultContractAddress(item.contractAddress),
+            contractExplorerUrl: item.contractExplorerUrl,
+            newAccount: item.contractNewAccount,
             storageKey: actualField,
             storageKeyDiffs: getFieldDiffs(item.expected.field, actualField),
             beforeValue: actualBefore,
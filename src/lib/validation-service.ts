@@ -1,15 +1,60 @@
 import { promises as fs } from 'fs';
 import path from 'path';
+import { Address, createPublicClient, http } from 'viem';
+import { ChainInfo, getChainInfo } from './chains';
 import { TASK_ORIGIN_COMMON_NAMES, TASK_ORIGIN_SIGNATURE_FILE_NAMES } from './constants';
 import { findContractDeploymentsRoot } from './deployments';
 import { getValidationSummary, parseFromString } from './parser';
+import { readValidationFile } from './artifact-split';
+import { checkSimulatedBlock } from './block-reorg';
+import {
+  describePrestateMismatch,
+  parsePrestateOverrides,
+  PRESTATE_FILE_ENV,
+  prestateFileFromCmd,
+  PrestateOverride,
+  unappliedPrestate,
+} from './chained-tasks';
 import { assertWithinDir } from './path-validation';
+import { describeExecutionCheck } from './execution-check';
+import {
+  checkExpectedOverrides,
+  describeOverrideDiscrepancy,
+  EXPECTED_OVERRIDES_FILE,
+  readExpectedOverrides,
+} from './expected-overrides';
+import { computeSafeTxHash } from './safe-hash';
+import { findUnlistedEntries } from './report-scope';
+import { assertCompleteView } from './report-audience';
+import { checkSafeNonce } from './safe-nonce';
+import {
+  describeContractOwners,
+  describeQuorum,
+  readSafeQuorum,
+  SafeQuorum,
+} from './safe-quorum';
+import { compareNestedHashes, deriveNestedHashes } from './nested-safes';
+import { recordedPreimages } from './preimage-database';
+import { reportWarning } from './report-warnings';
+import { sandboxFromEnv } from './sandbox';
+import { allowedCommandsFromEnv } from './command-policy';
+import { checkRpcAgreement, compareRpcFromEnv, compareRpcs } from './rpc-agreement';
+import { missingSecretEnv } from './simulation-env';
 import { StateDiffClient } from './state-diff';
+import { findStaleness, stalenessLimitsFromEnv } from './staleness';
 import { verifyTaskOrigin } from './task-origin-validate';
+import { incrementCounter, withSpan, withTelemetryRun } from './telemetry';
+import { loadConfigOverlay, TenantContext, tenantStalenessLimits } from './tenants';
+import { PolicyViolationError } from './errors';
+import { DEFAULT_METADATA_CACHE_TTL_HOURS } from './metadata-cache';
 import {
   BalanceChange,
+  CodeChange,
+  EthTransfer,
   ExpectedHashes,
   NetworkType,
+  ReportWarning,
+  SafeConfigurationChange,
   StateChange,
   StateOverride,
   TaskConfig,
@@ -19,16 +64,59 @@ import {
   TaskOriginSignerResult,
   TaskOriginValidation,
 } from './types/index';
-import { TASK_ORIGIN_ROLE_LABELS } from './validation-results-utils';
+import {
+  buildValidationItems,
+  hasBlockingErrors,
+  TASK_ORIGIN_ROLE_LABELS,
+} from './validation-results-utils';
 
 export type ValidationServiceOpts = {
   upgradeId: string;
   network: NetworkType;
   taskConfigFileName: string;
+  // Set when the request named a tenant of a shared server
+  tenant?: TenantContext;
 };
 
 const CONTRACT_DEPLOYMENTS_ROOT = findContractDeploymentsRoot();
-const stateDiffClient = new StateDiffClient(0, CONTRACT_DEPLOYMENTS_ROOT);
+const stateDiffClient = new StateDiffClient(0, CONTRACT_DEPLOYMENTS_ROOT, {
+  sandbox: sandboxFromEnv(),
+  allowedCommands: allowedCommandsFromEnv(),
+});
+// One client per tenant, overlay, and policy set, so their configs and caches stay apart
+const tenantClients = new Map<string, StateDiffClient>();
+
+function clientFor(tenant: TenantContext | undefined): StateDiffClient {
+  if (!tenant) return stateDiffClient;
+  let client = tenantClients.get(tenant.key);
+  if (!client) {
+    client = new StateDiffClient(0, CONTRACT_DEPLOYMENTS_ROOT, {
+      sandbox: sandboxFromEnv(),
+      allowedCommands: allowedCommandsFromEnv(),
+      strictHashFormat: tenant.policy.strictHashFormat,
+      unknowns: tenant.policy.unknowns,
+      configOverlay: tenant.overlayFile ? loadConfigOverlay(tenant.overlayFile) : null,
+      metadataCache: tenant.metadataCacheDir
+        ? {
+            dir: tenant.metadataCacheDir,
+            ttlHours: DEFAULT_METADATA_CACHE_TTL_HOURS,
+            refresh: false,
+          }
+        : null,
+    });
+    tenantClients.set(tenant.key, client);
+  }
+  return client;
+}
+
+// A tenant may only validate on its own chains
+function checkTenantChain(tenant: TenantContext | undefined, chainId: number): void {
+  if (tenant && !tenant.chainIds.includes(chainId)) {
+    throw new PolicyViolationError(
+      `Tenant ${tenant.tenant} may not validate on chain ${chainId} (allowed: ${tenant.chainIds.join(', ')})`
+    );
+  }
+}
 
 async function getConfigData(opts: ValidationServiceOpts): Promise<{
   cfg: TaskConfig;
@@ -58,7 +146,7 @@ async function getConfigData(opts: ValidationServiceOpts): Promise<{
 
   let configContent: string;
   try {
-    configContent = await fs.readFile(configPath, 'utf-8');
+    configContent = await readValidationFile(configPath);
   } catch (error: unknown) {
     if (error instanceof Error && 'code' in error && error.code === 'ENOENT') {
       throw new Error(`ValidationService::getConfigData: Config file not found: ${configPath}`);
@@ -104,33 +192,223 @@ function getExpectedData(parsedConfig: TaskConfig): {
   };
 }
 
+/**
+ * The forge script applies the previous task's post-state from the file `cmd` names, so the
+ * re-run is only on top of that task when every overridden slot it changed started at the
+ * override. A file whose command names no overrides file cannot have applied them.
+ */
+async function checkPrestateApplied(
+  scriptPath: string,
+  cfg: TaskConfig,
+  stateChanges: StateChange[]
+): Promise<ReportWarning[]> {
+  const previous = cfg.prestateFrom!.file;
+  const file = prestateFileFromCmd(cfg.cmd.trim().split(/\s+/));
+  if (!file) {
+    return [
+      reportWarning(
+        'PRESTATE_NOT_APPLIED',
+        `The task depends on ${previous}, but its command sets no ${PRESTATE_FILE_ENV}, so the re-run did not start from that task's post-state`
+      ),
+    ];
+  }
+  let overrides: PrestateOverride[];
+  try {
+    const overridesPath = assertWithinDir(path.join(scriptPath, file), scriptPath);
+    overrides = parsePrestateOverrides(JSON.parse(await fs.readFile(overridesPath, 'utf-8')));
+  } catch (err) {
+    return [
+      reportWarning(
+        'PRESTATE_NOT_APPLIED',
+        `Could not read the overrides from ${file} (${err instanceof Error ? err.message : String(err)}), so the re-run cannot be checked against ${previous}`,
+        { file }
+      ),
+    ];
+  }
+  return unappliedPrestate(overrides, stateChanges).map(mismatch =>
+    reportWarning('PRESTATE_NOT_APPLIED', describePrestateMismatch(mismatch), {
+      address: mismatch.address,
+      key: mismatch.key,
+    })
+  );
+}
+
 async function runStateDiffSimulation(
   scriptPath: string,
-  cfg: TaskConfig
+  cfg: TaskConfig,
+  tenant: TenantContext | undefined
 ): Promise<{
   stateOverrides: StateOverride[];
   stateChanges: StateChange[];
   balanceChanges: BalanceChange[];
   domainAndMessageHashes: ExpectedHashes;
+  warnings: ReportWarning[];
+  chain: ChainInfo;
 }> {
   try {
     console.log('Running state-diff simulation...');
     const forgeCmd = cfg.cmd.trim().split(/\s+/);
-    const stateDiffResult = await stateDiffClient.simulate(cfg.rpcUrl, forgeCmd, scriptPath);
+    // Preimages the file recorded from --preimages, so the re-run describes the same slots
+    const stateDiffResult = await clientFor(tenant).simulate(cfg.rpcUrl, forgeCmd, scriptPath, {
+      preimages: recordedPreimages(cfg),
+    });
+    const warnings = [...stateDiffResult.warnings];
+    const result = stateDiffResult.result;
+    const chain = getChainInfo(result.chainId!);
+    checkTenantChain(tenant, chain.chainId);
+    const compareRpc = compareRpcFromEnv();
+    if (compareRpc) {
+      const agreement = await compareRpcs(result, http(cfg.rpcUrl), http(compareRpc));
+      checkRpcAgreement(agreement);
+    }
+    if (cfg.chainId !== undefined && cfg.chainId !== chain.chainId) {
+      warnings.push(
+        reportWarning(
+          'CHAIN_MISMATCH',
+          `The validation file was generated on chain ${cfg.chainId} but the simulation ran on ${chain.name} (${chain.chainId})`,
+          { expected: cfg.chainId, actual: chain.chainId }
+        )
+      );
+    }
+    if (cfg.simulatedAt) {
+      // Against the head: a re-run pinned to the file's block is as far behind as the file
+      const head = await createPublicClient({ transport: http(cfg.rpcUrl) }).getBlockNumber();
+      const current = { now: new Date(), blockNumber: Number(head) };
+      const limits = tenant
+        ? tenantStalenessLimits(tenant.policy, stalenessLimitsFromEnv())
+        : stalenessLimitsFromEnv();
+      const stale = findStaleness(cfg.simulatedAt, current, limits);
+      warnings.push(
+        ...stale.map(w =>
+          reportWarning('STALE_PRESTATE', w, { simulatedBlock: cfg.simulatedAt!.blockNumber })
+        )
+      );
+    }
+    if (cfg.simulatedAt) {
+      const reorg = await checkSimulatedBlock(http(cfg.rpcUrl), cfg.simulatedAt);
+      if (reorg) {
+        warnings.push(
+          reportWarning('PRESTATE_REORGED', reorg, { simulatedBlock: cfg.simulatedAt.blockNumber })
+        );
+      }
+    }
+    if (cfg.safeNonce !== undefined && result.safeNonce === undefined) {
+      // The nonce could not be recovered from the calldata, so check the one the file declares
+      const nonceWarning = await checkSafeNonce(
+        cfg.rpcUrl,
+        cfg.expectedDomainAndMessageHashes.address,
+        cfg.safeNonce
+      );
+      if (nonceWarning) warnings.push(reportWarning('SAFE_NONCE_MISMATCH', nonceWarning));
+    }
+    if (cfg.nestedHashes) {
+      // Owner Safes sign these instead of the target's hashes, so they are checked the same way
+      const { address, safeTxHash } = result.expectedDomainAndMessageHashes;
+      const nested = await deriveNestedHashes(http(cfg.rpcUrl), address, safeTxHash!);
+      const mismatches = compareNestedHashes(cfg.nestedHashes, nested);
+      warnings.push(...mismatches.map(w => reportWarning('NESTED_HASH_MISMATCH', w)));
+    }
+    // The file's scope and audience only shape what it shows; the whole re-run is compared, so a
+    // change the file leaves out cannot pass as out of scope
+    const unlisted = findUnlistedEntries(cfg, result);
+    const unlistedCount = unlisted.stateOverrides + unlisted.stateChanges + unlisted.balanceChanges;
+    assertCompleteView(cfg, unlistedCount);
+    if (unlistedCount > 0) {
+      warnings.push(
+        reportWarning(
+          'UNLISTED_ENTRIES',
+          `The simulation found ${unlisted.stateChanges} state change(s), ${unlisted.stateOverrides} override(s) and ${unlisted.balanceChanges} balance change(s) the validation file does not list, on ${unlisted.contracts.join(', ')}`,
+          { count: unlistedCount }
+        )
+      );
+    }
+
+    const transferKey = (t: EthTransfer) =>
+      `${t.from ?? 'unknown'}->${t.to ?? 'unknown'}:${BigInt(t.amount)}`;
+    const expectedTransfers = (cfg.ethTransfers ?? []).map(transferKey).sort();
+    const actualTransfers = (result.ethTransfers ?? []).map(transferKey).sort();
+    if (expectedTransfers.join() !== actualTransfers.join()) {
+      warnings.push(
+        reportWarning(
+          'ETH_TRANSFERS_DIFFER',
+          `ETH transfers differ from the validation file: expected [${expectedTransfers.join(', ')}], simulation found [${actualTransfers.join(', ')}]`
+        )
+      );
+    }
+
+    const expectedDeletions = (cfg.accountDeletions ?? []).map(d => d.address).sort();
+    const actualDeletions = (result.accountDeletions ?? []).map(d => d.address).sort();
+    if (expectedDeletions.join() !== actualDeletions.join()) {
+      warnings.push(
+        reportWarning(
+          'ACCOUNT_DELETIONS_DIFFER',
+          `Self-destructed contracts differ from the validation file: expected [${expectedDeletions.join(', ')}], simulation found [${actualDeletions.join(', ')}]`
+        )
+      );
+    }
+
+    // Code hashes are compared so a changed deployment or implementation is not missed
+    const codeKey = (c: CodeChange) => `${c.address}:${c.after?.codeHash ?? 'none'}`;
+    const expectedCode = (cfg.codeChanges ?? []).map(codeKey).sort();
+    const actualCode = (result.codeChanges ?? []).map(codeKey).sort();
+    if (expectedCode.join() !== actualCode.join()) {
+      warnings.push(
+        reportWarning(
+          'CODE_CHANGES_DIFFER',
+          `Code changes differ from the validation file: expected [${expectedCode.join(', ')}], simulation found [${actualCode.join(', ')}]`
+        )
+      );
+    }
+
+    // A new guard or handler changes what the Safe accepts
+    const safeConfigKey = (c: SafeConfigurationChange) =>
+      `${c.address}:${c.setting}:${c.after ?? 'unset'}:${c.afterCode?.codeHash ?? 'none'}`;
+    const expectedSafeConfig = (cfg.safeConfigurationChanges ?? []).map(safeConfigKey).sort();
+    const actualSafeConfig = (result.safeConfigurationChanges ?? []).map(safeConfigKey).sort();
+    if (expectedSafeConfig.join() !== actualSafeConfig.join()) {
+      warnings.push(
+        reportWarning(
+          'SAFE_CONFIGURATION_DIFFERS',
+          `Safe configuration changes differ from the validation file: expected [${expectedSafeConfig.join(', ')}], simulation found [${actualSafeConfig.join(', ')}]`
+        )
+      );
+    }
+
+    if (cfg.execution && cfg.execution.status !== 'success') {
+      warnings.push(
+        reportWarning(
+          'EXECUTION_FAILED',
+          `The validation file was generated from a transaction that does not execute: ${describeExecutionCheck(cfg.execution)}`,
+          { status: cfg.execution.status }
+        )
+      );
+    }
+
+    if (result.payloadSignatures) {
+      const signers = result.payloadSignatures.map(
+        s => `${s.signer ?? 'unknown signer'} (${s.type})`
+      );
+      warnings.push(
+        reportWarning(
+          'PAYLOAD_SIGNATURES',
+          `The simulation assumes these signatures: ${signers.join(', ')}`
+        )
+      );
+    }
 
     console.log(
-      `✅ State-diff simulation completed: ${
-        stateDiffResult.result.stateOverrides.length
-      } state overrides, ${stateDiffResult.result.stateChanges.length} state changes, ${
-        stateDiffResult.result.balanceChanges?.length ?? 0
-      } balance changes found`
+      `✅ State-diff simulation completed: ${result.stateOverrides.length} state overrides, ${
+        result.stateChanges.length
+      } state changes, ${result.balanceChanges?.length ?? 0} balance changes found`
     );
 
     return {
-      stateOverrides: stateDiffResult.result.stateOverrides,
-      stateChanges: stateDiffResult.result.stateChanges,
-      balanceChanges: stateDiffResult.result.balanceChanges ?? [],
-      domainAndMessageHashes: stateDiffResult.result.expectedDomainAndMessageHashes,
+      stateOverrides: result.stateOverrides,
+      stateChanges: result.stateChanges,
+      balanceChanges: result.balanceChanges ?? [],
+      domainAndMessageHashes: result.expectedDomainAndMessageHashes,
+      warnings,
+      chain,
     };
   } catch (error) {
     console.error('❌ State-diff simulation failed:', error);
@@ -253,6 +531,30 @@ async function runTaskOriginValidation(
  * Main validation flow that orchestrates script extraction, simulation, and config parsing.
  */
 export async function validateUpgrade(opts: ValidationServiceOpts): Promise<ValidationData> {
+  // Requests run concurrently on the server, so each exports only its own spans and counters
+  return withTelemetryRun(() =>
+    withSpan(
+      'validate',
+      {
+        upgradeId: opts.upgradeId,
+        network: opts.network,
+        ...(opts.tenant && { tenant: opts.tenant.tenant }),
+      },
+      async () => {
+        const result = await runValidation(opts);
+        if (hasBlockingErrors(buildValidationItems(result), result.warnings)) {
+          incrementCounter('task_signing.policy_violations', 1, {
+            network: opts.network,
+            ...(opts.tenant && { tenant: opts.tenant.tenant }),
+          });
+        }
+        return result;
+      }
+    )
+  );
+}
+
+async function runValidation(opts: ValidationServiceOpts): Promise<ValidationData> {
   console.log(`🚀 Starting validation for ${opts.upgradeId} on ${opts.network}`);
 
   const { cfg, scriptPath, networkConfigDir, signatureDir } = await getConfigData(opts);
@@ -302,11 +604,84 @@ export async function validateUpgrade(opts: ValidationServiceOpts): Promise<Vali
 
   // Run the task simulation
   const expected = getExpectedData(cfg);
-  const actual = await runStateDiffSimulation(scriptPath, cfg);
+  // Checked before forge runs; the simulated chain is checked again afterwards
+  if (cfg.chainId !== undefined) checkTenantChain(opts.tenant, cfg.chainId);
+  const { warnings, chain, ...actual } = await runStateDiffSimulation(
+    scriptPath,
+    cfg,
+    opts.tenant
+  );
+  if (cfg.prestateFrom) {
+    warnings.unshift(
+      reportWarning(
+        'PRESTATE_DEPENDENCY',
+        `This task was simulated on top of ${cfg.prestateFrom.file} (safeTxHash ${cfg.prestateFrom.safeTxHash}) and assumes that task has already executed`,
+        { file: cfg.prestateFrom.file, safeTxHash: cfg.prestateFrom.safeTxHash }
+      )
+    );
+    warnings.push(...(await checkPrestateApplied(scriptPath, cfg, actual.stateChanges)));
+  }
+  const missingSecrets = missingSecretEnv(cfg.simulationEnv ?? []);
+  if (missingSecrets.length > 0) {
+    warnings.push(
+      reportWarning(
+        'MISSING_SECRETS',
+        `The task was simulated with ${missingSecrets.join(', ')} set, which are secrets not recorded in the file and not set here; results may differ`,
+        { variables: missingSecrets.join(',') }
+      )
+    );
+  }
+
+  const expectedOverrides = await readExpectedOverrides(
+    path.join(networkConfigDir, EXPECTED_OVERRIDES_FILE)
+  );
+  if (expectedOverrides) {
+    const discrepancies = checkExpectedOverrides(actual.stateOverrides, expectedOverrides);
+    warnings.push(
+      ...discrepancies.map(d => reportWarning('OVERRIDE_MISMATCH', describeOverrideDiscrepancy(d)))
+    );
+  }
+
+  const quorum = await readQuorum(cfg.rpcUrl, actual.domainAndMessageHashes, warnings);
 
   return {
     expected,
     actual,
     taskOriginValidation,
+    warnings,
+    ...(quorum ? { quorum } : {}),
+    chainId: chain.chainId,
+    chainName: chain.name,
+    rpcUsed: cfg.rpcUrl,
   };
 }
+
+/**
+ * Reads how many owners already approved the transaction. Failing to read it (e.g. the target
+ * is not a Safe) only adds a warning, since it does not affect what is being signed.
+ */
+async function readQuorum(
+  rpcUrl: string,
+  hashes: ExpectedHashes,
+  warnings: ReportWarning[]
+): Promise<SafeQuorum | undefined> {
+  const safeTxHash = computeSafeTxHash(hashes.domainHash, hashes.messageHash);
+  try {
+    const quorum = await withSpan('rpc.enrichment', { method: 'approvedHashes' }, () =>
+      readSafeQuorum(rpcUrl, hashes.address as Address, safeTxHash)
+    );
+    console.log(`🗳️  ${describeQuorum(quorum)}`);
+    for (const line of describeContractOwners(quorum)) console.log(`   ${line}`);
+    return quorum;
+  } catch (error) {
+    const message = error instanceof Error ? error.message : String(error);
+    warnings.push(
+      reportWarning(
+        'QUORUM_UNAVAILABLE',
+        `Could not read the approval status of ${hashes.address}: ${message}`,
+        { address: hashes.address }
+      )
+    );
+    return undefined;
+  }
+}
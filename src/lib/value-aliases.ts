@@ -0,0 +1,52 @@
+import { Hex, isHex } from 'viem';
+
+/**
+ * Names for values reviewers know by heart, declared under `constants` in contracts.json, e.g.
+ * `"SENTINEL_OWNERS": "0x0000000000000000000000000000000000000001"` or a known implementation
+ * address. A state change whose before or after word equals a constant carries the constant's
+ * name, and the report shows `SENTINEL_OWNERS` next to the raw word instead of a bare hash.
+ * Slots with a decimals or format hint hold amounts and are never named, so a threshold of 1
+ * is not shown as SENTINEL_OWNERS.
+ */
+
+// Keyed by the value as a lowercase 32-byte word
+export type ValueAliases = ReadonlyMap<Hex, string>;
+
+const ALIAS_NAME = /^[A-Z][A-Z0-9_]*$/;
+
+// An address or other value of up to 32 bytes, as the slot word holding it
+function aliasWord(value: string, where: string): Hex {
+  const body = value.trim().toLowerCase();
+  if (!isHex(body) || body.length < 3 || body.length > 66) {
+    throw new Error(`${where}: "${value}" is not a hex value of up to 32 bytes`);
+  }
+  return `0x${body.slice(2).padStart(64, '0')}`;
+}
+
+/**
+ * Checks the `constants` of contracts.json. Names are upper snake case, and two names for the
+ * same value are refused, since the report could only show one of them.
+ */
+export function resolveValueAliases(
+  constants: Record<string, string> | undefined,
+  where = 'constants'
+): ValueAliases {
+  const aliases = new Map<Hex, string>();
+  for (const [name, value] of Object.entries(constants ?? {})) {
+    if (!ALIAS_NAME.test(name)) {
+      throw new Error(`${where}: ${name} is not an upper snake case name such as SENTINEL_OWNERS`);
+    }
+    if (typeof value !== 'string') throw new Error(`${where}.${name}: the value must be a string`);
+    const word = aliasWord(value, `${where}.${name}`);
+    const existing = aliases.get(word);
+    if (existing) throw new Error(`${where}: ${existing} and ${name} name the same value`);
+    aliases.set(word, name);
+  }
+  return aliases;
+}
+
+// The constant a slot word equals, if any
+export function valueAlias(aliases: ValueAliases, word: string): string | undefined {
+  if (aliases.size === 0 || !isHex(word) || word.length > 66) return undefined;
+  return aliases.get(`0x${word.slice(2).toLowerCase().padStart(64, '0')}`);
+}
@@ -0,0 +1,101 @@
+import { deflateRawSync, inflateRawSync } from 'zlib';
+import { canonicalHash, canonicalJson } from './canonical-json';
+
+/**
+ * Links to a static web viewer of a validation file (genValidationFile.ts --viewer-link), so
+ * signers on a phone, or without the tool installed, can browse the decoded changes and compare
+ * hashes. The file travels in the link's fragment, which browsers never send to the viewer's
+ * server: `#v=1&hash=<content hash>&data=<deflate-raw, base64url canonical JSON>`. A file too
+ * large for a link is pinned to IPFS instead and referred to as `&cid=<CID>`.
+ *
+ * The viewer recomputes the content hash before showing anything, and signers compare that hash
+ * with the one the facilitator announced (`state-diff hash`), so a link that was edited in
+ * transit shows a different hash. An attestation in the file is carried along for the viewer to
+ * verify.
+ */
+
+export const VIEWER_LINK_VERSION = 1;
+
+// Longer links are cut off by chat apps and QR codes; such files are shared by CID
+export const MAX_VIEWER_PAYLOAD = 32 * 1024;
+
+// CIDv0 (base58btc, Qm...) or CIDv1 in base32 (b...)
+const CID = /^(Qm[1-9A-HJ-NP-Za-km-z]{44}|b[a-z2-7]{58,})$/;
+
+export function parseViewerUrl(value: string): string {
+  let url: URL;
+  try {
+    url = new URL(value);
+  } catch {
+    throw new Error('--viewer-link must be an http(s) URL');
+  }
+  if (url.protocol !== 'https:' && url.protocol !== 'http:') {
+    throw new Error('--viewer-link must be an http(s) URL');
+  }
+  if (url.hash) throw new Error('--viewer-link must not have a fragment; the link adds its own');
+  return url.toString();
+}
+
+export function parseCid(value: string): string {
+  if (!CID.test(value)) throw new Error(`--viewer-cid is not an IPFS CID: ${value}`);
+  return value;
+}
+
+export function encodeViewerPayload(value: unknown): string {
+  return deflateRawSync(Buffer.from(canonicalJson(value), 'utf-8'), { level: 9 }).toString(
+    'base64url'
+  );
+}
+
+/**
+ * Builds the link for `value`, the file as written. With `cid` the link refers to the pinned
+ * file instead of embedding it; without one, a file whose payload is over MAX_VIEWER_PAYLOAD is
+ * refused.
+ */
+export function buildViewerLink(viewerUrl: string, value: unknown, cid?: string): string {
+  const params = new URLSearchParams({
+    v: String(VIEWER_LINK_VERSION),
+    hash: canonicalHash(value),
+  });
+  if (cid) {
+    params.set('cid', cid);
+  } else {
+    const data = encodeViewerPayload(value);
+    if (data.length > MAX_VIEWER_PAYLOAD) {
+      throw new Error(
+        `The file is ${data.length} characters compressed, over the ${MAX_VIEWER_PAYLOAD} a link can carry; pin it to IPFS and pass its CID with --viewer-cid`
+      );
+    }
+    params.set('data', data);
+  }
+  return `${viewerUrl}#${params.toString()}`;
+}
+
+export type ViewerLink =
+  | { hash: string; kind: 'data'; value: unknown }
+  | { hash: string; kind: 'cid'; cid: string };
+
+/**
+ * Reads a viewer link back, as the viewer does. Embedded files must match the link's hash; a
+ * file fetched by CID is checked against it by the caller.
+ */
+export function decodeViewerLink(link: string): ViewerLink {
+  const params = new URLSearchParams(new URL(link).hash.slice(1));
+  if (params.get('v') !== String(VIEWER_LINK_VERSION)) {
+    throw new Error(`Not a version ${VIEWER_LINK_VERSION} viewer link`);
+  }
+  const hash = params.get('hash');
+  if (!hash || !/^0x[0-9a-f]{64}$/.test(hash)) throw new Error('The link has no content hash');
+  const cid = params.get('cid');
+  if (cid) return { hash, kind: 'cid', cid: parseCid(cid) };
+
+  const data = params.get('data');
+  if (!data) throw new Error('The link has neither data nor a CID');
+  // Bounded, so a small link cannot expand into gigabytes
+  const inflated = inflateRawSync(Buffer.from(data, 'base64url'), { maxOutputLength: 1 << 26 });
+  const value: unknown = JSON.parse(inflated.toString('utf-8'));
+  if (canonicalHash(value) !== hash) {
+    throw new Error('The file in the link does not match its content hash');
+  }
+  return { hash, kind: 'data', value };
+}
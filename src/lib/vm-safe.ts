@@ -0,0 +1,43 @@
+import type { Hex } from 'viem';
+
+// Mirrors forge-std VmSafe.AccountAccessKind
+export enum AccountAccessKind {
+  Call = 0,
+  DelegateCall = 1,
+  CallCode = 2,
+  StaticCall = 3,
+  Create = 4,
+  SelfDestruct = 5,
+  Resume = 6,
+  Balance = 7,
+  Extcodesize = 8,
+  Extcodehash = 9,
+  Extcodecopy = 10,
+}
+
+export type VmSafeStorageAccess = {
+  account: string;
+  slot: Hex;
+  isWrite: boolean;
+  previousValue: Hex;
+  newValue: Hex;
+  reverted: boolean;
+};
+
+export type VmSafeAccountAccess = {
+  chainInfo: { forkId: bigint; chainId: bigint };
+  kind: number;
+  account: string;
+  accessor: string;
+  initialized: boolean;
+  oldBalance: bigint;
+  newBalance: bigint;
+  deployedCode: Hex;
+  value: bigint;
+  data: Hex;
+  reverted: boolean;
+  storageAccesses: readonly VmSafeStorageAccess[];
+  depth: bigint;
+  oldNonce: bigint;
+  newNonce: bigint;
+};
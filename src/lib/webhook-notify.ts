@@ -0,0 +1,112 @@
+import { expandEnvVars, getChainInfo } from './chains';
+import { summarizeReport } from './report-summary';
+import type { TaskConfig } from './types/index';
+
+/**
+ * Compact completion message for the signing channel. Facilitators paste the task, chain, and
+ * hashes into Slack or Discord by hand after every generation and verification; with
+ * --notify-webhook the tool posts them itself. The message only repeats what the file already
+ * says, so signers still compare hashes on their devices, not from the channel.
+ */
+
+export type NotifyEvent = 'generated' | 'verified' | 'verification-failed';
+
+export type NotifySummary = {
+  event: NotifyEvent;
+  task: string;
+  config: Pick<
+    TaskConfig,
+    | 'chainId'
+    | 'chainName'
+    | 'expectedDomainAndMessageHashes'
+    | 'stateOverrides'
+    | 'stateChanges'
+    | 'balanceChanges'
+    | 'summary'
+    | 'warnings'
+  >;
+  // Where reviewers find the file: a URL, or the path it was written to
+  artifact?: string;
+  // Why verification failed, or who it found attested the file
+  detail?: string;
+};
+
+type WebhookKind = 'slack' | 'discord';
+
+const HEADLINES: Record<NotifyEvent, string> = {
+  generated: '✅ Validation file generated',
+  verified: '✅ Validation file verified',
+  'verification-failed': '❌ Validation file failed verification',
+};
+
+/**
+ * The webhook URL from --notify-webhook. The URL carries the webhook's secret, so it can be
+ * given as `${VAR}` to keep it out of shell history, as for rpcUrl in the chain registry.
+ */
+export function parseWebhookUrl(value: string, env: NodeJS.ProcessEnv = process.env): string {
+  const expanded = expandEnvVars(value, env);
+  let url: URL;
+  try {
+    url = new URL(expanded);
+  } catch {
+    throw new Error('--notify-webhook must be an http(s) URL');
+  }
+  if (url.protocol !== 'https:' && url.protocol !== 'http:') {
+    throw new Error('--notify-webhook must be an http(s) URL');
+  }
+  return expanded;
+}
+
+// Discord webhooks take `content`; Slack and compatible ones (Mattermost, ...) take `text`
+export function webhookKind(url: string): WebhookKind {
+  const host = new URL(url).hostname;
+  return /(^|\.)discord(app)?\.com$/.test(host) ? 'discord' : 'slack';
+}
+
+export function formatNotification(summary: NotifySummary, kind: WebhookKind): string {
+  const { config } = summary;
+  const bold = (s: string) => (kind === 'discord' ? `**${s}**` : `*${s}*`);
+  const hashes = config.expectedDomainAndMessageHashes;
+  const counts = config.summary ?? summarizeReport(config);
+  const chain =
+    config.chainId !== undefined
+      ? `${config.chainName ?? getChainInfo(config.chainId).name} (${config.chainId})`
+      : 'unknown chain';
+  const warnings = config.warnings?.length ?? 0;
+
+  const lines = [
+    `${HEADLINES[summary.event]}: ${bold(summary.task)}`,
+    `Chain: ${chain} · Safe \`${hashes.address}\``,
+    `Domain hash: \`${hashes.domainHash}\``,
+    `Message hash: \`${hashes.messageHash}\``,
+    ...(hashes.safeTxHash ? [`SafeTx hash: \`${hashes.safeTxHash}\``] : []),
+    `Changes: ${counts.contractsTouched} contract(s), ${counts.slotsChanged} slot(s), ${counts.overridesApplied} override(s), ${config.balanceChanges?.length ?? 0} balance change(s), ${warnings} warning(s)`,
+    ...(summary.detail ? [summary.detail] : []),
+    ...(summary.artifact ? [`Artifact: ${summary.artifact}`] : []),
+  ];
+  return lines.join('\n');
+}
+
+/**
+ * Posts the summary. Throws when the webhook refuses it; callers report that as a warning,
+ * since the file is already written and the message is only a convenience.
+ */
+export async function postNotification(
+  url: string,
+  summary: NotifySummary,
+  fetchFn: typeof fetch = fetch
+): Promise<void> {
+  const kind = webhookKind(url);
+  const text = formatNotification(summary, kind);
+  const response = await fetchFn(url, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(kind === 'discord' ? { content: text } : { text }),
+  });
+  if (!response.ok) {
+    // The URL is a secret, so only its host is named
+    throw new Error(
+      `The webhook at ${new URL(url).host} refused the message with status ${response.status}`
+    );
+  }
+}